@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/config"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Used for flags
+	auditTailLines    int64
+	auditTailFollow   bool
+	auditTailInterval time.Duration
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the audit log of mutations",
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Print the latest audit events, optionally following new ones",
+	RunE:  runAuditTailCmd,
+}
+
+func init() {
+	auditTailCmd.Flags().Int64VarP(&auditTailLines, "lines", "n", 20, "number of latest events to print")
+	auditTailCmd.Flags().BoolVarP(&auditTailFollow, "follow", "f", false, "keep printing events as they are recorded")
+	auditTailCmd.Flags().DurationVar(&auditTailInterval, "interval", time.Second, "how often to poll for new events when following")
+	auditCmd.AddCommand(auditTailCmd)
+	rootCmd.AddCommand(auditCmd)
+}
+
+func runAuditTailCmd(cmd *cobra.Command, args []string) error {
+	// Initialize logger
+	logger := log.NewLogger()
+	if verboseMode {
+		logger.Info("Verbose mode enabled")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		return err
+	}
+
+	// Initialize database connection
+	sqlDB, err := repo.OpenDB(cfg.Database)
+	if err != nil {
+		logger.Error("Failed to connect to database", "error", err)
+		return err
+	}
+	defer func() {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error("Failed to close database connection", "error", err)
+		}
+	}()
+	auditRepo := repo.NewAuditRepo(sqlDB)
+
+	// Print the latest events oldest first, like tail
+	ctx := cmd.Context()
+	events, err := auditRepo.ListLatestAuditEvents(ctx, sqlDB, auditTailLines)
+	if err != nil {
+		logger.Error("Failed to list audit events", "error", err)
+		return err
+	}
+	slices.Reverse(events)
+	lastID, err := printAuditEvents(cmd.OutOrStdout(), events, 0)
+	if err != nil || !auditTailFollow {
+		return err
+	}
+
+	// Poll for the events recorded since the last one printed
+	ticker := time.NewTicker(auditTailInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		events, err := auditRepo.ListAuditEventsAfter(ctx, sqlDB, lastID, 100)
+		if err != nil {
+			logger.Error("Failed to list audit events", "error", err)
+			return err
+		}
+		if lastID, err = printAuditEvents(cmd.OutOrStdout(), events, lastID); err != nil {
+			return err
+		}
+	}
+}
+
+// printAuditEvents writes one line per audit event and returns the ID of the
+// last one, or lastID if there were none
+func printAuditEvents(w io.Writer, events []db.AuditEvent, lastID int64) (int64, error) {
+	for _, event := range events {
+		actor := event.Actor
+		if actor == "" {
+			actor = "-"
+		}
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s/%s\tworkspace=%s\tactor=%s\trequest_id=%s\n",
+			event.OccurredAt.UTC().Format(time.RFC3339), event.Action, event.ResourceType, event.ResourceID, event.WorkspaceID, actor, event.RequestID)
+		if err != nil {
+			return lastID, err
+		}
+		lastID = event.ID
+	}
+	return lastID, nil
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/atreya2011/expense-manager/db/migrations"
+	pgmigrations "github.com/atreya2011/expense-manager/db/postgres/migrations"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/config"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/migrate"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Used for flags
+	migrateUpSteps   int
+	migrateDownSteps int
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply, revert and inspect the database migrations embedded in the binary",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply pending migrations",
+	RunE:  runMigrateUpCmd,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert applied migrations, dropping the data of the tables and columns they added",
+	RunE:  runMigrateDownCmd,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the applied and pending migrations",
+	RunE:  runMigrateStatusCmd,
+}
+
+func init() {
+	migrateUpCmd.Flags().IntVarP(&migrateUpSteps, "steps", "n", 0, "number of migrations to apply (0 applies all)")
+	migrateDownCmd.Flags().IntVarP(&migrateDownSteps, "steps", "n", 1, "number of migrations to revert")
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrateUpCmd(cmd *cobra.Command, args []string) error {
+	return withMigrator(func(migrator *migrate.Migrator) error {
+		applied, err := migrator.Up(cmd.Context(), migrateUpSteps)
+		for _, migration := range applied {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "applied  %s\n", migration.Name); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), "no pending migrations")
+		}
+		return err
+	})
+}
+
+func runMigrateDownCmd(cmd *cobra.Command, args []string) error {
+	if migrateDownSteps < 1 {
+		return fmt.Errorf("--steps must be at least 1")
+	}
+	return withMigrator(func(migrator *migrate.Migrator) error {
+		reverted, err := migrator.Down(cmd.Context(), migrateDownSteps)
+		if err != nil {
+			return err
+		}
+		for _, migration := range reverted {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "reverted %s\n", migration.Name); err != nil {
+				return err
+			}
+		}
+		if len(reverted) == 0 {
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), "no applied migrations")
+		}
+		return err
+	})
+}
+
+func runMigrateStatusCmd(cmd *cobra.Command, args []string) error {
+	return withMigrator(func(migrator *migrate.Migrator) error {
+		status, err := migrator.Status(cmd.Context())
+		if err != nil {
+			return err
+		}
+		w := cmd.OutOrStdout()
+		state := "OK"
+		switch {
+		case len(status.Unknown) > 0:
+			state = "NEWER THAN BINARY"
+		case len(status.Pending) > 0:
+			state = "PENDING"
+		}
+		current := status.Current()
+		if current == "" {
+			current = "none"
+		}
+		if _, err := fmt.Fprintf(w, "status:   %s\ncurrent:  %s\napplied:  %d\npending:  %d\n", state, current, len(status.Applied), len(status.Pending)); err != nil {
+			return err
+		}
+		for _, migration := range status.Pending {
+			if _, err := fmt.Fprintf(w, "  pending %s\n", migration.Name); err != nil {
+				return err
+			}
+		}
+		for _, revision := range status.Unknown {
+			if _, err := fmt.Fprintf(w, "  unknown %s_%s\n", revision.Version, revision.Description); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrationsFS returns the embedded migration directory of a database driver
+func migrationsFS(driver string) fs.FS {
+	if driver == repo.DriverPostgres {
+		return pgmigrations.FS
+	}
+	return migrations.FS
+}
+
+// withMigrator runs fn with a Migrator of the embedded migrations on the
+// configured database
+func withMigrator(fn func(*migrate.Migrator) error) error {
+	// Initialize logger
+	logger := log.NewLogger()
+	if verboseMode {
+		logger.Info("Verbose mode enabled")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		return err
+	}
+
+	// Initialize database connection
+	sqlDB, err := repo.OpenDB(cfg.Database)
+	if err != nil {
+		logger.Error("Failed to connect to database", "error", err)
+		return err
+	}
+	defer func() {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error("Failed to close database connection", "error", err)
+		}
+	}()
+
+	// Load the embedded migrations, refusing them if they do not match atlas.sum
+	migrator, err := migrate.New(sqlDB, migrationsFS(cfg.Database.Driver), clock.NewRealClock(), logger)
+	if err != nil {
+		logger.Error("Failed to load migrations", "error", err)
+		return err
+	}
+	if err := fn(migrator); err != nil {
+		logger.Error("Failed to migrate database", "error", err)
+		return err
+	}
+	return nil
+}
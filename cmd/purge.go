@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/config"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Permanently delete master data that has been in the trash longer than the retention",
+	RunE:  runPurgeCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(purgeCmd)
+}
+
+func runPurgeCmd(cmd *cobra.Command, args []string) error {
+	// Initialize logger
+	logger := log.NewLogger()
+	if verboseMode {
+		logger.Info("Verbose mode enabled")
+	}
+	logger.Info("Starting trash purge...")
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		return err
+	}
+
+	// Initialize database connection
+	logger.Info("Connecting to database...", "driver", cfg.Database.Driver)
+	sqlDB, err := repo.OpenDB(cfg.Database)
+	if err != nil {
+		logger.Error("Failed to connect to database", "error", err)
+		return err
+	}
+	defer func() {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error("Failed to close database connection", "error", err)
+		}
+	}()
+	logger.Info("Database connection established")
+
+	// Rows deleted at or before the cutoff have outlived the retention
+	deletedBefore := clock.NewRealClock().Now().UTC().Add(-cfg.Trash.Retention)
+	logger.Info("Purging trash", "retention", cfg.Trash.Retention, "deleted_before", deletedBefore)
+
+	// Purge every soft-deletable table in one transaction. Rows still
+	// referenced by accounts, transactions, ledger entries or audit events
+	// stay in the trash.
+	userRepo := repo.NewUserRepo(sqlDB)
+	instrumentRepo := repo.NewInstrumentRepo(sqlDB)
+	institutionRepo := repo.NewInstitutionRepo(sqlDB)
+	currencyRepo := repo.NewCurrencyRepo(sqlDB)
+	purges := []struct {
+		table string
+		purge func(context.Context, db.DBTX, time.Time) (int64, error)
+	}{
+		{"users", userRepo.PurgeUsers},
+		{"instruments", instrumentRepo.PurgeInstruments},
+		{"institutions", institutionRepo.PurgeInstitutions},
+		{"currencies", currencyRepo.PurgeCurrencies},
+	}
+
+	ctx := cmd.Context()
+	tx, err := sqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+
+	for _, p := range purges {
+		purged, err := p.purge(ctx, tx, deletedBefore)
+		if err != nil {
+			logger.Error("Failed to purge trash", "table", p.table, "error", err)
+
+			// Attempt to rollback transaction on error
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logger.Error("Failed to rollback transaction", "error", rbErr)
+			}
+
+			return err
+		}
+		logger.Info("Purged trash", "table", p.table, "rows", purged)
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		logger.Error("Failed to commit transaction", "error", err)
+		return err
+	}
+
+	logger.Info("Trash purge completed successfully")
+	return nil
+}
@@ -38,8 +38,8 @@ func runSeedCmd(cmd *cobra.Command, args []string) error {
 	}
 
 	// Initialize database connection
-	logger.Info("Connecting to database...", "path", cfg.Database.Path)
-	db, err := repo.OpenDB(cfg.Database.Path)
+	logger.Info("Connecting to database...", "driver", cfg.Database.Driver)
+	db, err := repo.OpenDB(cfg.Database)
 	if err != nil {
 		logger.Error("Failed to connect to database", "error", err)
 		return err
@@ -10,12 +10,22 @@ import (
 	"syscall"
 	"time"
 
+	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/changes"
 	"github.com/atreya2011/expense-manager/internal/clock"
 	"github.com/atreya2011/expense-manager/internal/config"
+	"github.com/atreya2011/expense-manager/internal/etag"
+	"github.com/atreya2011/expense-manager/internal/idempotency"
 	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/migrate"
+	"github.com/atreya2011/expense-manager/internal/pagination"
 	"github.com/atreya2011/expense-manager/internal/repo"
 	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
 	"github.com/atreya2011/expense-manager/internal/rpc/services"
+	"github.com/atreya2011/expense-manager/internal/validate"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/spf13/cobra"
 	"golang.org/x/net/http2"
@@ -28,7 +38,13 @@ var serveCmd = &cobra.Command{
 	RunE:  runServeCmd,
 }
 
+var (
+	// Used for flags
+	serveMigrate bool
+)
+
 func init() {
+	serveCmd.Flags().BoolVar(&serveMigrate, "migrate", false, "apply pending migrations before serving")
 	rootCmd.AddCommand(serveCmd)
 }
 
@@ -49,66 +65,192 @@ func runServeCmd(cmd *cobra.Command, args []string) error {
 	}
 
 	// Initialize database connection
-	logger.Info("Connecting to database...", "path", cfg.Database.Path)
-	db, err := repo.OpenDB(cfg.Database.Path)
+	logger.Info("Connecting to database...", "driver", cfg.Database.Driver)
+	db, err := repo.OpenDB(cfg.Database)
 	if err != nil {
 		logger.Error("Failed to connect to database", "error", err)
 		return err
 	}
 	logger.Info("Database connection established")
 
-	// Check if the database schema is correct by looking for one of our tables
-	var count int
-	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='account_types'").Scan(&count)
+	// Initialize clock
+	clk := clock.NewRealClock()
+	logger.Info("Clock initialized")
+
+	// Load the embedded migrations, refusing them if they do not match
+	// atlas.sum, apply the pending ones if asked to, and refuse to serve a
+	// database that is behind or ahead of this binary
+	migrator, err := migrate.New(db, migrationsFS(cfg.Database.Driver), clk, logger)
 	if err != nil {
-		logger.Error("Failed to check database schema", "error", err)
+		logger.Error("Failed to load migrations", "error", err)
 		return err
 	}
-	if count == 0 {
-		logger.Error("Database schema is incorrect. Make sure migrations have been applied.", "error", "account_types table not found")
-		logger.Info("Run 'make migrate' to apply Atlas migrations")
-		return fmt.Errorf("account_types table not found")
+	if serveMigrate {
+		if _, err := migrator.Up(cmd.Context(), 0); err != nil {
+			logger.Error("Failed to apply migrations", "error", err)
+			return err
+		}
+	}
+	if err := migrator.Check(cmd.Context()); err != nil {
+		logger.Error("Database schema does not match this binary", "error", err)
+		switch {
+		case errors.Is(err, migrate.ErrPending):
+			logger.Info("Run 'expense-manager migrate up' or 'expense-manager serve --migrate' to apply the migrations")
+		case errors.Is(err, migrate.ErrSchemaNewer):
+			logger.Info("Upgrade expense-manager, or run 'expense-manager migrate down' with the newer binary")
+		}
+		return err
 	}
 	logger.Info("Database schema verified")
 
 	// Initialize repositories
 	userRepo := repo.NewUserRepo(db)
 	instrumentRepo := repo.NewInstrumentRepo(db)
+	institutionRepo := repo.NewInstitutionRepo(db)
+	currencyRepo := repo.NewCurrencyRepo(db)
+	accountRepo := repo.NewAccountRepo(db)
+	categoryRepo := repo.NewCategoryRepo(db)
+	transactionRepo := repo.NewTransactionRepo(db)
+	reportingRepo := repo.NewReportingRepo(db)
+	reconciliationRepo := repo.NewReconciliationRepo(db)
+	idempotencyRepo := repo.NewIdempotencyRepo(db)
+	auditRepo := repo.NewAuditRepo(db)
+	authRepo := repo.NewAuthRepo(db)
+	authzRepo := repo.NewAuthzRepo(db)
+	workspaceRepo := repo.NewWorkspaceRepo(db)
+	changeRepo := repo.NewChangeRepo(db)
 	logger.Info("Repositories initialized")
 
-	// Initialize clock
-	clk := clock.NewRealClock()
-	logger.Info("Clock initialized")
+	// Initialize page token codec
+	if cfg.Pagination.TokenSecret == "" {
+		logger.Warn("PAGE_TOKEN_SECRET is not set; page tokens will not survive a restart")
+	}
+	pages, err := pagination.NewCodec([]byte(cfg.Pagination.TokenSecret), cfg.Pagination.TokenTTL, clk)
+	if err != nil {
+		logger.Error("Failed to initialize page tokens", "error", err)
+		return err
+	}
+
+	// Start the change feed, which polls the change log for the streams
+	// watching it and prunes the log of the changes older than the retention
+	feedCtx, stopFeed := context.WithCancel(cmd.Context())
+	defer stopFeed()
+	feed, err := changes.NewFeed(feedCtx, changeRepo, clk, cfg.Changes.PollInterval, cfg.Changes.Retention, cfg.Changes.Buffer, logger)
+	if err != nil {
+		logger.Error("Failed to initialize change feed", "error", err)
+		return err
+	}
+	go feed.Run(feedCtx)
+	logger.Info("Change feed started", "poll_interval", cfg.Changes.PollInterval, "retention", cfg.Changes.Retention)
 
 	// Initialize Connect RPC services
-	userService := services.NewUserService(userRepo, clk, logger)
-	instrumentService := services.NewInstrumentService(instrumentRepo, clk, logger)
+	userService := services.NewUserService(userRepo, auditRepo, pages, clk, logger)
+	instrumentService := services.NewInstrumentService(instrumentRepo, auditRepo, pages, clk, logger)
+	accountService := services.NewAccountService(accountRepo, userRepo, instrumentRepo, institutionRepo, currencyRepo, auditRepo, pages, clk, logger)
+	institutionService := services.NewInstitutionService(institutionRepo, auditRepo, pages, clk, logger)
+	currencyService := services.NewCurrencyService(currencyRepo, auditRepo, pages, clk, logger)
+	categoryService := services.NewCategoryService(categoryRepo, transactionRepo, auditRepo, pages, clk, logger)
+	transactionService := services.NewTransactionService(transactionRepo, accountRepo, categoryRepo, instrumentRepo, currencyRepo, auditRepo, pages, clk, logger)
+	reportingService := services.NewReportingService(reportingRepo, clk, logger)
+	reconciliationService := services.NewReconciliationService(reconciliationRepo, accountRepo, categoryRepo, transactionRepo, auditRepo, pages, clk, logger)
+	auditService := services.NewAuditService(auditRepo, pages, clk, logger)
+	authService := services.NewAuthService(authRepo, auditRepo, pages, cfg.Auth.SessionTTL, cfg.Auth.SecureCookies, clk, logger)
+	workspaceService := services.NewWorkspaceService(workspaceRepo, userRepo, auditRepo, clk, logger)
+	changeService := services.NewChangeService(changeRepo, authzRepo, feed, logger)
 	logger.Info("Services initialized")
 
+	// Initialize interceptors. Every request is tagged with an X-Request-Id
+	// for the audit events it writes, must authenticate with an API token or
+	// session, must satisfy the protovalidate rules of its message, and must
+	// be allowed by the authorization policy in its workspace, which resolves
+	// accounts and workspaces from the validated message. Retried requests
+	// carrying an Idempotency-Key header replay the outcome of the first
+	// attempt.
+	handlerOptions := connect.WithInterceptors(
+		audit.NewInterceptor(),
+		auth.NewInterceptor(authRepo, clk, logger),
+		validate.NewInterceptor(logger),
+		authz.NewInterceptor(authzRepo, auditRepo, clk, logger),
+		idempotency.NewInterceptor(idempotencyRepo, clk, cfg.Idempotency.KeyTTL, logger,
+			userService, instrumentService, accountService, institutionService, currencyService,
+			categoryService, transactionService, reportingService, reconciliationService, auditService,
+			authService, workspaceService),
+	)
+
 	// Create router
 	mux := http.NewServeMux()
 
 	// Register Connect RPC services
-	userPath, userHandler := expensesv1connect.NewUserServiceHandler(userService)
+	userPath, userHandler := expensesv1connect.NewUserServiceHandler(userService, handlerOptions)
 	mux.Handle(userPath, userHandler)
 	logger.Info("User service registered", "path", userPath)
 
-	instrumentPath, instrumentHandler := expensesv1connect.NewInstrumentServiceHandler(instrumentService)
+	instrumentPath, instrumentHandler := expensesv1connect.NewInstrumentServiceHandler(instrumentService, handlerOptions)
 	mux.Handle(instrumentPath, instrumentHandler)
 	logger.Info("Instrument service registered", "path", instrumentPath)
 
+	accountPath, accountHandler := expensesv1connect.NewAccountServiceHandler(accountService, handlerOptions)
+	mux.Handle(accountPath, accountHandler)
+	logger.Info("Account service registered", "path", accountPath)
+
+	institutionPath, institutionHandler := expensesv1connect.NewInstitutionServiceHandler(institutionService, handlerOptions)
+	mux.Handle(institutionPath, institutionHandler)
+	logger.Info("Institution service registered", "path", institutionPath)
+
+	currencyPath, currencyHandler := expensesv1connect.NewCurrencyServiceHandler(currencyService, handlerOptions)
+	mux.Handle(currencyPath, currencyHandler)
+	logger.Info("Currency service registered", "path", currencyPath)
+
+	categoryPath, categoryHandler := expensesv1connect.NewCategoryServiceHandler(categoryService, handlerOptions)
+	mux.Handle(categoryPath, categoryHandler)
+	logger.Info("Category service registered", "path", categoryPath)
+
+	transactionPath, transactionHandler := expensesv1connect.NewTransactionServiceHandler(transactionService, handlerOptions)
+	mux.Handle(transactionPath, transactionHandler)
+	logger.Info("Transaction service registered", "path", transactionPath)
+
+	reportingPath, reportingHandler := expensesv1connect.NewReportingServiceHandler(reportingService, handlerOptions)
+	mux.Handle(reportingPath, reportingHandler)
+	logger.Info("Reporting service registered", "path", reportingPath)
+
+	reconciliationPath, reconciliationHandler := expensesv1connect.NewReconciliationServiceHandler(reconciliationService, handlerOptions)
+	mux.Handle(reconciliationPath, reconciliationHandler)
+	logger.Info("Reconciliation service registered", "path", reconciliationPath)
+
+	auditPath, auditHandler := expensesv1connect.NewAuditServiceHandler(auditService, handlerOptions)
+	mux.Handle(auditPath, auditHandler)
+	logger.Info("Audit service registered", "path", auditPath)
+
+	authPath, authHandler := expensesv1connect.NewAuthServiceHandler(authService, handlerOptions)
+	mux.Handle(authPath, authHandler)
+	logger.Info("Auth service registered", "path", authPath)
+
+	workspacePath, workspaceHandler := expensesv1connect.NewWorkspaceServiceHandler(workspaceService, handlerOptions)
+	mux.Handle(workspacePath, workspaceHandler)
+	logger.Info("Workspace service registered", "path", workspacePath)
+
+	// Streams outlive the server's timeouts, and end when it shuts down
+	streams, stopStreams := context.WithCancel(context.Background())
+	defer stopStreams()
+
+	changePath, changeHandler := expensesv1connect.NewChangeServiceHandler(changeService, handlerOptions)
+	mux.Handle(changePath, streaming(changeHandler, streams))
+	logger.Info("Change service registered", "path", changePath)
+
 	// Configure server
 	addr := fmt.Sprintf(":%s", cfg.Server.Port)
 	logger.Info("Server listening", "address", addr)
 
-	// Create server with h2c for HTTP/2 without TLS
+	// Create server with h2c for HTTP/2 without TLS, answering conditional
+	// GETs of unchanged resources with 304 Not Modified
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      h2c.NewHandler(mux, &http2.Server{}),
+		Handler:      h2c.NewHandler(etag.ConditionalGET(mux), &http2.Server{}),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
+	server.RegisterOnShutdown(stopStreams)
 
 	// Start server in a goroutine
 	go func() {
@@ -128,6 +270,9 @@ func runServeCmd(cmd *cobra.Command, args []string) error {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
+	// Stop polling the change log before closing the database
+	stopFeed()
+
 	// Close database connection
 	if err := db.Close(); err != nil {
 		logger.Error("Failed to close database connection", "error", err)
@@ -142,3 +287,20 @@ func runServeCmd(cmd *cobra.Command, args []string) error {
 	logger.Info("Server shutdown gracefully")
 	return nil
 }
+
+// streaming lifts the server's read and write timeouts off the long-lived
+// streams of handler, which would otherwise cut them off, and ends the streams
+// once shutdown is done so the server does not wait for them
+func streaming(handler http.Handler, shutdown context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		controller := http.NewResponseController(w)
+		_ = controller.SetReadDeadline(time.Time{})
+		_ = controller.SetWriteDeadline(time.Time{})
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		stop := context.AfterFunc(shutdown, cancel)
+		defer stop()
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
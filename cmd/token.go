@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/config"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Used for flags
+	tokenEmail     string
+	tokenName      string
+	tokenUserName  string
+	tokenExpiresIn time.Duration
+	tokenAdmin     bool
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage personal API tokens",
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a personal API token for a user and print it",
+	RunE:  runTokenCreateCmd,
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <token-id>",
+	Short: "Revoke a personal API token of a user",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTokenRevokeCmd,
+}
+
+func init() {
+	tokenCmd.PersistentFlags().StringVar(&tokenEmail, "email", "", "email of the user owning the token")
+	if err := tokenCmd.MarkPersistentFlagRequired("email"); err != nil {
+		panic(err)
+	}
+	tokenCreateCmd.Flags().StringVar(&tokenName, "name", "cli", "name describing what the token is for")
+	tokenCreateCmd.Flags().StringVar(&tokenUserName, "user-name", "", "create the user with this name if no user has the email")
+	tokenCreateCmd.Flags().DurationVar(&tokenExpiresIn, "expires-in", 0, "how long the token is valid for (0 never expires)")
+	tokenCreateCmd.Flags().BoolVar(&tokenAdmin, "admin", false, "make the user a server admin, who manages users, and an admin of the default workspace")
+	tokenCmd.AddCommand(tokenCreateCmd, tokenRevokeCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
+
+func runTokenCreateCmd(cmd *cobra.Command, args []string) error {
+	if tokenExpiresIn < 0 {
+		return fmt.Errorf("--expires-in must not be negative")
+	}
+	return withTokenDB(func(sqlDB *sqlx.DB, tx *sqlx.Tx) error {
+		ctx := cmd.Context()
+
+		// Look up the owner, creating it on request so the first token can be
+		// issued to an empty database
+		userRepo := repo.NewUserRepo(sqlDB)
+		user, err := userRepo.GetUserByEmail(ctx, tx, tokenEmail)
+		if stderrors.Is(err, errors.ErrNotFound) && tokenUserName != "" {
+			user, err = userRepo.CreateUser(ctx, tx, db.CreateUserParams{Name: tokenUserName, Email: tokenEmail, IsAdmin: tokenAdmin})
+		}
+		if err != nil {
+			return err
+		}
+		if tokenAdmin && !user.IsAdmin {
+			if err := userRepo.SetUserAdmin(ctx, tx, user.ID, true); err != nil {
+				return err
+			}
+		}
+
+		// Make the owner a user of the default workspace, so the token can be
+		// used before any other workspace exists
+		workspaceRepo := repo.NewWorkspaceRepo(sqlDB)
+		member, err := workspaceRepo.GetWorkspaceUser(ctx, tx, repo.DefaultWorkspaceID, user.ID)
+		switch {
+		case stderrors.Is(err, errors.ErrNotFound):
+			_, err = workspaceRepo.AddWorkspaceUser(ctx, tx, db.AddWorkspaceUserParams{WorkspaceID: repo.DefaultWorkspaceID, UserID: user.ID, IsAdmin: tokenAdmin})
+		case err == nil && tokenAdmin && !member.IsAdmin:
+			err = workspaceRepo.SetWorkspaceUserAdmin(ctx, tx, repo.DefaultWorkspaceID, user.ID, true)
+		}
+		if err != nil {
+			return err
+		}
+
+		token, err := auth.NewAPIToken()
+		if err != nil {
+			return err
+		}
+		now := clock.NewRealClock().Now().UTC()
+		var expiresAt *time.Time
+		if tokenExpiresIn > 0 {
+			t := now.Add(tokenExpiresIn)
+			expiresAt = &t
+		}
+		apiToken, err := repo.NewAuthRepo(sqlDB).CreateAPIToken(ctx, tx, db.CreateApiTokenParams{
+			UserID:    user.ID,
+			Name:      tokenName,
+			TokenHash: auth.HashToken(token),
+			CreatedAt: now,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The token is only ever shown here; the database keeps its hash
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "id:    %s\nuser:  %s\ntoken: %s\n", apiToken.ID, user.ID, token)
+		return err
+	})
+}
+
+func runTokenRevokeCmd(cmd *cobra.Command, args []string) error {
+	return withTokenDB(func(sqlDB *sqlx.DB, tx *sqlx.Tx) error {
+		ctx := cmd.Context()
+		user, err := repo.NewUserRepo(sqlDB).GetUserByEmail(ctx, tx, tokenEmail)
+		if err != nil {
+			return err
+		}
+		if err := repo.NewAuthRepo(sqlDB).RevokeAPIToken(ctx, tx, args[0], user.ID, clock.NewRealClock().Now().UTC()); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "revoked %s\n", args[0])
+		return err
+	})
+}
+
+// withTokenDB runs fn in a transaction of the configured database, committing
+// it if fn succeeds
+func withTokenDB(fn func(*sqlx.DB, *sqlx.Tx) error) error {
+	// Initialize logger
+	logger := log.NewLogger()
+	if verboseMode {
+		logger.Info("Verbose mode enabled")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		return err
+	}
+
+	// Initialize database connection
+	sqlDB, err := repo.OpenDB(cfg.Database)
+	if err != nil {
+		logger.Error("Failed to connect to database", "error", err)
+		return err
+	}
+	defer func() {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error("Failed to close database connection", "error", err)
+		}
+	}()
+
+	tx, err := sqlDB.Beginx()
+	if err != nil {
+		logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	if err := fn(sqlDB, tx); err != nil {
+		logger.Error("Failed to update api tokens", "error", err)
+
+		// Attempt to rollback transaction on error
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logger.Error("Failed to rollback transaction", "error", rbErr)
+		}
+
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		logger.Error("Failed to commit transaction", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+-- Add column "minor_units" to table: "currencies"
+ALTER TABLE `currencies` ADD COLUMN `minor_units` integer NOT NULL DEFAULT 2;
+-- Add column "symbol" to table: "currencies"
+ALTER TABLE `currencies` ADD COLUMN `symbol` text NOT NULL DEFAULT '';
+-- Yen has no minor unit
+UPDATE `currencies` SET `minor_units` = 0, `symbol` = '¥' WHERE `code` = 'JPY';
@@ -0,0 +1,17 @@
+-- Disable the enforcement of foreign-keys constraints
+PRAGMA foreign_keys = off;
+-- Create "new_institutions" table
+CREATE TABLE `new_institutions` (`id` text NULL, `name` text NOT NULL, `type` text NOT NULL, `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (`id`), CHECK (
+    type IN ('BANK', 'CARD_ISSUER', 'BROKER', 'WALLET', 'OTHER')
+  ));
+-- Copy rows from old table "institutions" to new temporary table "new_institutions",
+-- mapping free-text types onto the known kinds
+INSERT INTO `new_institutions` (`id`, `name`, `type`, `created_at`, `updated_at`) SELECT `id`, `name`, CASE WHEN upper(`type`) IN ('BANK', 'CARD_ISSUER', 'BROKER', 'WALLET') THEN upper(`type`) ELSE 'OTHER' END, `created_at`, `updated_at` FROM `institutions`;
+-- Drop "institutions" table after copying rows
+DROP TABLE `institutions`;
+-- Rename temporary table "new_institutions" to "institutions"
+ALTER TABLE `new_institutions` RENAME TO `institutions`;
+-- Create index "institutions_name" to table: "institutions"
+CREATE UNIQUE INDEX `institutions_name` ON `institutions` (`name`);
+-- Enable back the enforcement of foreign-keys constraints
+PRAGMA foreign_keys = on;
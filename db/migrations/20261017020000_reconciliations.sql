@@ -0,0 +1,4 @@
+-- Create "reconciliations" table
+CREATE TABLE `reconciliations` (`id` text NULL, `account_id` text NOT NULL, `counted_at` timestamp NOT NULL, `counted_amount` integer NOT NULL, `ledger_balance` integer NOT NULL, `discrepancy` integer NOT NULL, `currency_id` text NOT NULL, `adjustment_transaction_id` text NULL, `notes` text NULL, `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (`id`), CONSTRAINT `0` FOREIGN KEY (`adjustment_transaction_id`) REFERENCES `transactions` (`id`) ON UPDATE NO ACTION ON DELETE SET NULL, CONSTRAINT `1` FOREIGN KEY (`currency_id`) REFERENCES `currencies` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CONSTRAINT `2` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION);
+-- Create index "reconciliations_account_id_counted_at" to table: "reconciliations"
+CREATE INDEX `reconciliations_account_id_counted_at` ON `reconciliations` (`account_id`, `counted_at`);
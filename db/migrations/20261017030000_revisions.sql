@@ -0,0 +1,14 @@
+-- Add column "revision" to table: "users"
+ALTER TABLE `users` ADD COLUMN `revision` integer NOT NULL DEFAULT 1;
+-- Add column "revision" to table: "instruments"
+ALTER TABLE `instruments` ADD COLUMN `revision` integer NOT NULL DEFAULT 1;
+-- Add column "revision" to table: "currencies"
+ALTER TABLE `currencies` ADD COLUMN `revision` integer NOT NULL DEFAULT 1;
+-- Add column "revision" to table: "institutions"
+ALTER TABLE `institutions` ADD COLUMN `revision` integer NOT NULL DEFAULT 1;
+-- Add column "revision" to table: "accounts"
+ALTER TABLE `accounts` ADD COLUMN `revision` integer NOT NULL DEFAULT 1;
+-- Add column "revision" to table: "categories"
+ALTER TABLE `categories` ADD COLUMN `revision` integer NOT NULL DEFAULT 1;
+-- Add column "revision" to table: "transactions"
+ALTER TABLE `transactions` ADD COLUMN `revision` integer NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+-- Create "idempotency_keys" table
+CREATE TABLE `idempotency_keys` (`idempotency_key` text NULL, `method` text NOT NULL, `request_hash` text NOT NULL, `response` blob NULL, `code` integer NOT NULL DEFAULT 0, `error_message` text NOT NULL DEFAULT '', `completed_at` timestamp NULL, `created_at` timestamp NOT NULL, `expires_at` timestamp NOT NULL, PRIMARY KEY (`idempotency_key`));
+-- Create index "idempotency_keys_expires_at" to table: "idempotency_keys"
+CREATE INDEX `idempotency_keys_expires_at` ON `idempotency_keys` (`expires_at`);
@@ -0,0 +1,8 @@
+-- Add column "deleted_at" to table: "users"
+ALTER TABLE `users` ADD COLUMN `deleted_at` timestamp NULL;
+-- Add column "deleted_at" to table: "instruments"
+ALTER TABLE `instruments` ADD COLUMN `deleted_at` timestamp NULL;
+-- Add column "deleted_at" to table: "currencies"
+ALTER TABLE `currencies` ADD COLUMN `deleted_at` timestamp NULL;
+-- Add column "deleted_at" to table: "institutions"
+ALTER TABLE `institutions` ADD COLUMN `deleted_at` timestamp NULL;
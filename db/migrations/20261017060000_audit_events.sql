@@ -0,0 +1,8 @@
+-- Create "audit_events" table
+CREATE TABLE `audit_events` (`id` integer NULL PRIMARY KEY AUTOINCREMENT, `resource_type` text NOT NULL, `resource_id` text NOT NULL, `action` text NOT NULL, `before_state` text NULL, `after_state` text NULL, `actor` text NOT NULL DEFAULT '', `request_id` text NOT NULL DEFAULT '', `occurred_at` timestamp NOT NULL);
+-- Create index "audit_events_resource" to table: "audit_events"
+CREATE INDEX `audit_events_resource` ON `audit_events` (`resource_type`, `resource_id`);
+-- Create index "audit_events_actor" to table: "audit_events"
+CREATE INDEX `audit_events_actor` ON `audit_events` (`actor`);
+-- Create index "audit_events_occurred_at" to table: "audit_events"
+CREATE INDEX `audit_events_occurred_at` ON `audit_events` (`occurred_at`);
@@ -0,0 +1,12 @@
+-- Create "api_tokens" table
+CREATE TABLE `api_tokens` (`id` text NULL, `user_id` text NOT NULL, `name` text NOT NULL, `token_hash` text NOT NULL, `created_at` timestamp NOT NULL, `expires_at` timestamp NULL, `revoked_at` timestamp NULL, PRIMARY KEY (`id`), CONSTRAINT `0` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE);
+-- Create index "api_tokens_token_hash" to table: "api_tokens"
+CREATE UNIQUE INDEX `api_tokens_token_hash` ON `api_tokens` (`token_hash`);
+-- Create index "api_tokens_user_id" to table: "api_tokens"
+CREATE INDEX `api_tokens_user_id` ON `api_tokens` (`user_id`);
+-- Create "sessions" table
+CREATE TABLE `sessions` (`id` text NULL, `user_id` text NOT NULL, `token_hash` text NOT NULL, `created_at` timestamp NOT NULL, `expires_at` timestamp NOT NULL, `revoked_at` timestamp NULL, PRIMARY KEY (`id`), CONSTRAINT `0` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE);
+-- Create index "sessions_token_hash" to table: "sessions"
+CREATE UNIQUE INDEX `sessions_token_hash` ON `sessions` (`token_hash`);
+-- Create index "sessions_user_id" to table: "sessions"
+CREATE INDEX `sessions_user_id` ON `sessions` (`user_id`);
@@ -0,0 +1,4 @@
+-- Add column "is_admin" to table: "users"
+ALTER TABLE `users` ADD COLUMN `is_admin` boolean NOT NULL DEFAULT false;
+-- Create index "account_users_user_id" to table: "account_users"
+CREATE INDEX `account_users_user_id` ON `account_users` (`user_id`);
@@ -0,0 +1,91 @@
+-- Create "workspaces" table
+CREATE TABLE `workspaces` (`id` text NULL, `name` text NOT NULL, `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (`id`));
+-- Create "workspace_users" table
+CREATE TABLE `workspace_users` (`workspace_id` text NOT NULL, `user_id` text NOT NULL, `is_admin` boolean NOT NULL DEFAULT false, `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (`workspace_id`, `user_id`), CONSTRAINT `0` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE, CONSTRAINT `1` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE);
+-- Create index "workspace_users_user_id" to table: "workspace_users"
+CREATE INDEX `workspace_users_user_id` ON `workspace_users` (`user_id`);
+-- Move existing data into a default workspace shared by every existing user,
+-- keeping admins admins of it
+INSERT INTO `workspaces` (`id`, `name`) VALUES ('wsp_default', 'Default');
+INSERT INTO `workspace_users` (`workspace_id`, `user_id`, `is_admin`) SELECT 'wsp_default', `id`, `is_admin` FROM `users`;
+-- Disable the enforcement of foreign-keys constraints
+PRAGMA foreign_keys = off;
+-- Create "new_instruments" table
+CREATE TABLE `new_instruments` (`id` text NULL, `workspace_id` text NOT NULL, `name` text NOT NULL, `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `revision` integer NOT NULL DEFAULT 1, `deleted_at` timestamp NULL, PRIMARY KEY (`id`), CONSTRAINT `0` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION);
+-- Copy rows from old table "instruments" to new temporary table "new_instruments"
+INSERT INTO `new_instruments` (`id`, `workspace_id`, `name`, `created_at`, `updated_at`, `revision`, `deleted_at`) SELECT `id`, 'wsp_default', `name`, `created_at`, `updated_at`, `revision`, `deleted_at` FROM `instruments`;
+-- Drop "instruments" table after copying rows
+DROP TABLE `instruments`;
+-- Rename temporary table "new_instruments" to "instruments"
+ALTER TABLE `new_instruments` RENAME TO `instruments`;
+-- Create index "instruments_workspace_id_name" to table: "instruments"
+CREATE UNIQUE INDEX `instruments_workspace_id_name` ON `instruments` (`workspace_id`, `name`);
+-- Create "new_currencies" table
+CREATE TABLE `new_currencies` (`id` text NULL, `workspace_id` text NOT NULL, `code` text NOT NULL, `name` text NOT NULL, `minor_units` integer NOT NULL DEFAULT 2, `symbol` text NOT NULL DEFAULT '', `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `revision` integer NOT NULL DEFAULT 1, `deleted_at` timestamp NULL, PRIMARY KEY (`id`), CONSTRAINT `0` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION);
+-- Copy rows from old table "currencies" to new temporary table "new_currencies"
+INSERT INTO `new_currencies` (`id`, `workspace_id`, `code`, `name`, `minor_units`, `symbol`, `created_at`, `updated_at`, `revision`, `deleted_at`) SELECT `id`, 'wsp_default', `code`, `name`, `minor_units`, `symbol`, `created_at`, `updated_at`, `revision`, `deleted_at` FROM `currencies`;
+-- Drop "currencies" table after copying rows
+DROP TABLE `currencies`;
+-- Rename temporary table "new_currencies" to "currencies"
+ALTER TABLE `new_currencies` RENAME TO `currencies`;
+-- Create index "currencies_workspace_id_code" to table: "currencies"
+CREATE UNIQUE INDEX `currencies_workspace_id_code` ON `currencies` (`workspace_id`, `code`);
+-- Create "new_institutions" table
+CREATE TABLE `new_institutions` (`id` text NULL, `workspace_id` text NOT NULL, `name` text NOT NULL, `type` text NOT NULL, `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `revision` integer NOT NULL DEFAULT 1, `deleted_at` timestamp NULL, PRIMARY KEY (`id`), CONSTRAINT `0` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CHECK (
+    type IN ('BANK', 'CARD_ISSUER', 'BROKER', 'WALLET', 'OTHER')
+  ));
+-- Copy rows from old table "institutions" to new temporary table "new_institutions"
+INSERT INTO `new_institutions` (`id`, `workspace_id`, `name`, `type`, `created_at`, `updated_at`, `revision`, `deleted_at`) SELECT `id`, 'wsp_default', `name`, `type`, `created_at`, `updated_at`, `revision`, `deleted_at` FROM `institutions`;
+-- Drop "institutions" table after copying rows
+DROP TABLE `institutions`;
+-- Rename temporary table "new_institutions" to "institutions"
+ALTER TABLE `new_institutions` RENAME TO `institutions`;
+-- Create index "institutions_workspace_id_name" to table: "institutions"
+CREATE UNIQUE INDEX `institutions_workspace_id_name` ON `institutions` (`workspace_id`, `name`);
+-- Create "new_accounts" table
+CREATE TABLE `new_accounts` (`id` text NULL, `workspace_id` text NOT NULL, `name` text NOT NULL, `description` text NULL, `account_type_id` text NOT NULL, `instrument_id` text NULL, `institution_id` text NULL, `currency_id` text NULL, `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `revision` integer NOT NULL DEFAULT 1, PRIMARY KEY (`id`), CONSTRAINT `0` FOREIGN KEY (`currency_id`) REFERENCES `currencies` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CONSTRAINT `1` FOREIGN KEY (`institution_id`) REFERENCES `institutions` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CONSTRAINT `2` FOREIGN KEY (`instrument_id`) REFERENCES `instruments` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CONSTRAINT `3` FOREIGN KEY (`account_type_id`) REFERENCES `account_types` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CONSTRAINT `4` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION);
+-- Copy rows from old table "accounts" to new temporary table "new_accounts"
+INSERT INTO `new_accounts` (`id`, `workspace_id`, `name`, `description`, `account_type_id`, `instrument_id`, `institution_id`, `currency_id`, `created_at`, `updated_at`, `revision`) SELECT `id`, 'wsp_default', `name`, `description`, `account_type_id`, `instrument_id`, `institution_id`, `currency_id`, `created_at`, `updated_at`, `revision` FROM `accounts`;
+-- Drop "accounts" table after copying rows
+DROP TABLE `accounts`;
+-- Rename temporary table "new_accounts" to "accounts"
+ALTER TABLE `new_accounts` RENAME TO `accounts`;
+-- Create index "accounts_workspace_id_name" to table: "accounts"
+CREATE UNIQUE INDEX `accounts_workspace_id_name` ON `accounts` (`workspace_id`, `name`);
+-- Create "new_categories" table
+CREATE TABLE `new_categories` (`id` text NULL, `workspace_id` text NOT NULL, `parent_id` text NULL, `name` text NOT NULL, `description` text NULL, `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `revision` integer NOT NULL DEFAULT 1, PRIMARY KEY (`id`), CONSTRAINT `0` FOREIGN KEY (`parent_id`) REFERENCES `categories` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CONSTRAINT `1` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION);
+-- Copy rows from old table "categories" to new temporary table "new_categories"
+INSERT INTO `new_categories` (`id`, `workspace_id`, `parent_id`, `name`, `description`, `created_at`, `updated_at`, `revision`) SELECT `id`, 'wsp_default', `parent_id`, `name`, `description`, `created_at`, `updated_at`, `revision` FROM `categories`;
+-- Drop "categories" table after copying rows
+DROP TABLE `categories`;
+-- Rename temporary table "new_categories" to "categories"
+ALTER TABLE `new_categories` RENAME TO `categories`;
+-- Create index "categories_workspace_id_name" to table: "categories"
+CREATE UNIQUE INDEX `categories_workspace_id_name` ON `categories` (`workspace_id`, `name`);
+-- Create "new_transactions" table
+CREATE TABLE `new_transactions` (`id` text NULL, `workspace_id` text NOT NULL, `date` timestamp NOT NULL, `description` text NOT NULL, `notes` text NULL, `category_id` text NULL, `instrument_id` text NULL, `allocation_tag` text NULL, `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `revision` integer NOT NULL DEFAULT 1, PRIMARY KEY (`id`), CONSTRAINT `0` FOREIGN KEY (`instrument_id`) REFERENCES `instruments` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CONSTRAINT `1` FOREIGN KEY (`category_id`) REFERENCES `categories` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CONSTRAINT `2` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION);
+-- Copy rows from old table "transactions" to new temporary table "new_transactions"
+INSERT INTO `new_transactions` (`id`, `workspace_id`, `date`, `description`, `notes`, `category_id`, `instrument_id`, `allocation_tag`, `created_at`, `updated_at`, `revision`) SELECT `id`, 'wsp_default', `date`, `description`, `notes`, `category_id`, `instrument_id`, `allocation_tag`, `created_at`, `updated_at`, `revision` FROM `transactions`;
+-- Drop "transactions" table after copying rows
+DROP TABLE `transactions`;
+-- Rename temporary table "new_transactions" to "transactions"
+ALTER TABLE `new_transactions` RENAME TO `transactions`;
+-- Create index "transactions_workspace_id_date" to table: "transactions"
+CREATE INDEX `transactions_workspace_id_date` ON `transactions` (`workspace_id`, `date`);
+-- Create "new_reconciliations" table
+CREATE TABLE `new_reconciliations` (`id` text NULL, `workspace_id` text NOT NULL, `account_id` text NOT NULL, `counted_at` timestamp NOT NULL, `counted_amount` integer NOT NULL, `ledger_balance` integer NOT NULL, `discrepancy` integer NOT NULL, `currency_id` text NOT NULL, `adjustment_transaction_id` text NULL, `notes` text NULL, `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (`id`), CONSTRAINT `0` FOREIGN KEY (`adjustment_transaction_id`) REFERENCES `transactions` (`id`) ON UPDATE NO ACTION ON DELETE SET NULL, CONSTRAINT `1` FOREIGN KEY (`currency_id`) REFERENCES `currencies` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CONSTRAINT `2` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION, CONSTRAINT `3` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION);
+-- Copy rows from old table "reconciliations" to new temporary table "new_reconciliations"
+INSERT INTO `new_reconciliations` (`id`, `workspace_id`, `account_id`, `counted_at`, `counted_amount`, `ledger_balance`, `discrepancy`, `currency_id`, `adjustment_transaction_id`, `notes`, `created_at`, `updated_at`) SELECT `id`, 'wsp_default', `account_id`, `counted_at`, `counted_amount`, `ledger_balance`, `discrepancy`, `currency_id`, `adjustment_transaction_id`, `notes`, `created_at`, `updated_at` FROM `reconciliations`;
+-- Drop "reconciliations" table after copying rows
+DROP TABLE `reconciliations`;
+-- Rename temporary table "new_reconciliations" to "reconciliations"
+ALTER TABLE `new_reconciliations` RENAME TO `reconciliations`;
+-- Create index "reconciliations_account_id_counted_at" to table: "reconciliations"
+CREATE INDEX `reconciliations_account_id_counted_at` ON `reconciliations` (`account_id`, `counted_at`);
+-- Enable back the enforcement of foreign-keys constraints
+PRAGMA foreign_keys = on;
+-- Add column "workspace_id" to table: "audit_events"
+ALTER TABLE `audit_events` ADD COLUMN `workspace_id` text NOT NULL DEFAULT '';
+UPDATE `audit_events` SET `workspace_id` = 'wsp_default';
+-- Create index "audit_events_workspace_id" to table: "audit_events"
+CREATE INDEX `audit_events_workspace_id` ON `audit_events` (`workspace_id`);
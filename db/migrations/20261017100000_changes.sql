@@ -0,0 +1,4 @@
+-- Create "changes" table
+CREATE TABLE `changes` (`seq` integer NULL PRIMARY KEY AUTOINCREMENT, `workspace_id` text NOT NULL, `resource_type` text NOT NULL, `resource_id` text NOT NULL, `action` text NOT NULL, `account_ids` text NOT NULL DEFAULT '', `occurred_at` timestamp NOT NULL);
+-- Create index "changes_occurred_at" to table: "changes"
+CREATE INDEX `changes_occurred_at` ON `changes` (`occurred_at`);
@@ -0,0 +1,16 @@
+-- Drop index "users_email" from table: "users"
+DROP INDEX `users_email`;
+-- Create index "users_email" to table: "users"
+CREATE UNIQUE INDEX `users_email` ON `users` (`email`) WHERE deleted_at IS NULL;
+-- Drop index "instruments_workspace_id_name" from table: "instruments"
+DROP INDEX `instruments_workspace_id_name`;
+-- Create index "instruments_workspace_id_name" to table: "instruments"
+CREATE UNIQUE INDEX `instruments_workspace_id_name` ON `instruments` (`workspace_id`, `name`) WHERE deleted_at IS NULL;
+-- Drop index "currencies_workspace_id_code" from table: "currencies"
+DROP INDEX `currencies_workspace_id_code`;
+-- Create index "currencies_workspace_id_code" to table: "currencies"
+CREATE UNIQUE INDEX `currencies_workspace_id_code` ON `currencies` (`workspace_id`, `code`) WHERE deleted_at IS NULL;
+-- Drop index "institutions_workspace_id_name" from table: "institutions"
+DROP INDEX `institutions_workspace_id_name`;
+-- Create index "institutions_workspace_id_name" to table: "institutions"
+CREATE UNIQUE INDEX `institutions_workspace_id_name` ON `institutions` (`workspace_id`, `name`) WHERE deleted_at IS NULL;
@@ -0,0 +1,11 @@
+// Package migrations embeds the Atlas migration directory, so the binary can
+// migrate a database without the atlas CLI. Atlas only reads the .sql files
+// of the directory, so this file does not affect atlas.sum.
+package migrations
+
+import "embed"
+
+// FS holds the migration files and their atlas.sum
+//
+//go:embed *.sql atlas.sum
+var FS embed.FS
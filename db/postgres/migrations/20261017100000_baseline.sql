@@ -0,0 +1,58 @@
+-- Create "account_types" table
+CREATE TABLE account_types (id text PRIMARY KEY, name text NOT NULL, code text NOT NULL CHECK (code IN ('A', 'L', 'E')), created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, UNIQUE (name), UNIQUE (code));
+-- Create "users" table
+CREATE TABLE users (id text PRIMARY KEY, name text NOT NULL, email text NOT NULL, created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, revision bigint NOT NULL DEFAULT 1, deleted_at timestamptz, is_admin boolean NOT NULL DEFAULT FALSE, UNIQUE (email));
+-- Create "workspaces" table
+CREATE TABLE workspaces (id text PRIMARY KEY, name text NOT NULL, created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP);
+-- Create "workspace_users" table
+CREATE TABLE workspace_users (workspace_id text NOT NULL, user_id text NOT NULL, is_admin boolean NOT NULL DEFAULT FALSE, created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (workspace_id, user_id), FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE, FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE);
+-- Create index "workspace_users_user_id" to table: "workspace_users"
+CREATE INDEX workspace_users_user_id ON workspace_users (user_id);
+-- Create "instruments" table
+CREATE TABLE instruments (id text PRIMARY KEY, workspace_id text NOT NULL, name text NOT NULL, created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, revision bigint NOT NULL DEFAULT 1, deleted_at timestamptz, UNIQUE (workspace_id, name), FOREIGN KEY (workspace_id) REFERENCES workspaces (id));
+-- Create "currencies" table
+CREATE TABLE currencies (id text PRIMARY KEY, workspace_id text NOT NULL, code text NOT NULL, name text NOT NULL, minor_units bigint NOT NULL DEFAULT 2, symbol text NOT NULL DEFAULT '', created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, revision bigint NOT NULL DEFAULT 1, deleted_at timestamptz, UNIQUE (workspace_id, code), FOREIGN KEY (workspace_id) REFERENCES workspaces (id));
+-- Create "institutions" table
+CREATE TABLE institutions (id text PRIMARY KEY, workspace_id text NOT NULL, name text NOT NULL, type text NOT NULL CHECK (type IN ('BANK', 'CARD_ISSUER', 'BROKER', 'WALLET', 'OTHER')), created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, revision bigint NOT NULL DEFAULT 1, deleted_at timestamptz, UNIQUE (workspace_id, name), FOREIGN KEY (workspace_id) REFERENCES workspaces (id));
+-- Create "accounts" table
+CREATE TABLE accounts (id text PRIMARY KEY, workspace_id text NOT NULL, name text NOT NULL, description text, account_type_id text NOT NULL, instrument_id text, institution_id text, currency_id text, created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, revision bigint NOT NULL DEFAULT 1, UNIQUE (workspace_id, name), FOREIGN KEY (workspace_id) REFERENCES workspaces (id), FOREIGN KEY (account_type_id) REFERENCES account_types (id), FOREIGN KEY (instrument_id) REFERENCES instruments (id), FOREIGN KEY (institution_id) REFERENCES institutions (id), FOREIGN KEY (currency_id) REFERENCES currencies (id));
+-- Create "account_users" table
+CREATE TABLE account_users (account_id text NOT NULL, user_id text NOT NULL, created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (account_id, user_id), FOREIGN KEY (account_id) REFERENCES accounts (id) ON DELETE CASCADE, FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE);
+-- Create index "account_users_user_id" to table: "account_users"
+CREATE INDEX account_users_user_id ON account_users (user_id);
+-- Create "categories" table
+CREATE TABLE categories (id text PRIMARY KEY, workspace_id text NOT NULL, parent_id text, name text NOT NULL, description text, created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, revision bigint NOT NULL DEFAULT 1, UNIQUE (workspace_id, name), FOREIGN KEY (workspace_id) REFERENCES workspaces (id), FOREIGN KEY (parent_id) REFERENCES categories (id));
+-- Create "transactions" table
+CREATE TABLE transactions (id text PRIMARY KEY, workspace_id text NOT NULL, date timestamptz NOT NULL, description text NOT NULL, notes text, category_id text, instrument_id text, allocation_tag text, created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, revision bigint NOT NULL DEFAULT 1, FOREIGN KEY (workspace_id) REFERENCES workspaces (id), FOREIGN KEY (category_id) REFERENCES categories (id), FOREIGN KEY (instrument_id) REFERENCES instruments (id));
+-- Create index "transactions_workspace_id_date" to table: "transactions"
+CREATE INDEX transactions_workspace_id_date ON transactions (workspace_id, date);
+-- Create "ledger_entries" table
+CREATE TABLE ledger_entries (id text PRIMARY KEY, transaction_id text NOT NULL, account_id text NOT NULL, category_id text, memo text NOT NULL, debit bigint NOT NULL DEFAULT 0, credit bigint NOT NULL DEFAULT 0, currency_id text, created_at timestamptz NOT NULL DEFAULT clock_timestamp(), updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY (transaction_id) REFERENCES transactions (id) ON DELETE CASCADE, FOREIGN KEY (account_id) REFERENCES accounts (id), FOREIGN KEY (category_id) REFERENCES categories (id), FOREIGN KEY (currency_id) REFERENCES currencies (id), CHECK (debit >= 0 AND credit >= 0 AND (debit = 0 OR credit = 0)));
+-- Create "reconciliations" table
+CREATE TABLE reconciliations (id text PRIMARY KEY, workspace_id text NOT NULL, account_id text NOT NULL, counted_at timestamptz NOT NULL, counted_amount bigint NOT NULL, ledger_balance bigint NOT NULL, discrepancy bigint NOT NULL, currency_id text NOT NULL, adjustment_transaction_id text, notes text, created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY (workspace_id) REFERENCES workspaces (id), FOREIGN KEY (account_id) REFERENCES accounts (id), FOREIGN KEY (currency_id) REFERENCES currencies (id), FOREIGN KEY (adjustment_transaction_id) REFERENCES transactions (id) ON DELETE SET NULL);
+-- Create index "reconciliations_account_id_counted_at" to table: "reconciliations"
+CREATE INDEX reconciliations_account_id_counted_at ON reconciliations (account_id, counted_at);
+-- Create "idempotency_keys" table
+CREATE TABLE idempotency_keys (idempotency_key text PRIMARY KEY, method text NOT NULL, request_hash text NOT NULL, response bytea, code bigint NOT NULL DEFAULT 0, error_message text NOT NULL DEFAULT '', completed_at timestamptz, created_at timestamptz NOT NULL, expires_at timestamptz NOT NULL);
+-- Create index "idempotency_keys_expires_at" to table: "idempotency_keys"
+CREATE INDEX idempotency_keys_expires_at ON idempotency_keys (expires_at);
+-- Create "audit_events" table
+CREATE TABLE audit_events (id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY, resource_type text NOT NULL, resource_id text NOT NULL, action text NOT NULL, before_state text, after_state text, actor text NOT NULL DEFAULT '', request_id text NOT NULL DEFAULT '', occurred_at timestamptz NOT NULL, workspace_id text NOT NULL DEFAULT '');
+-- Create index "audit_events_resource" to table: "audit_events"
+CREATE INDEX audit_events_resource ON audit_events (resource_type, resource_id);
+-- Create index "audit_events_actor" to table: "audit_events"
+CREATE INDEX audit_events_actor ON audit_events (actor);
+-- Create index "audit_events_occurred_at" to table: "audit_events"
+CREATE INDEX audit_events_occurred_at ON audit_events (occurred_at);
+-- Create index "audit_events_workspace_id" to table: "audit_events"
+CREATE INDEX audit_events_workspace_id ON audit_events (workspace_id);
+-- Create "api_tokens" table
+CREATE TABLE api_tokens (id text PRIMARY KEY, user_id text NOT NULL, name text NOT NULL, token_hash text NOT NULL, created_at timestamptz NOT NULL, expires_at timestamptz, revoked_at timestamptz, UNIQUE (token_hash), FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE);
+-- Create index "api_tokens_user_id" to table: "api_tokens"
+CREATE INDEX api_tokens_user_id ON api_tokens (user_id);
+-- Create "sessions" table
+CREATE TABLE sessions (id text PRIMARY KEY, user_id text NOT NULL, token_hash text NOT NULL, created_at timestamptz NOT NULL, expires_at timestamptz NOT NULL, revoked_at timestamptz, UNIQUE (token_hash), FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE);
+-- Create index "sessions_user_id" to table: "sessions"
+CREATE INDEX sessions_user_id ON sessions (user_id);
+-- Create the default workspace of requests made without one
+INSERT INTO workspaces (id, name) VALUES ('wsp_default', 'Default');
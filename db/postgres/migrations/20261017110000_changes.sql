@@ -0,0 +1,4 @@
+-- Create "changes" table
+CREATE TABLE changes (seq bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY, workspace_id text NOT NULL, resource_type text NOT NULL, resource_id text NOT NULL, action text NOT NULL, account_ids text NOT NULL DEFAULT '', occurred_at timestamptz NOT NULL);
+-- Create index "changes_occurred_at" to table: "changes"
+CREATE INDEX changes_occurred_at ON changes (occurred_at);
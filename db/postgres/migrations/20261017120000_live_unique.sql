@@ -0,0 +1,16 @@
+-- Modify "users" table
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+-- Create index "users_email" to table: "users"
+CREATE UNIQUE INDEX users_email ON users (email) WHERE (deleted_at IS NULL);
+-- Modify "instruments" table
+ALTER TABLE instruments DROP CONSTRAINT instruments_workspace_id_name_key;
+-- Create index "instruments_workspace_id_name" to table: "instruments"
+CREATE UNIQUE INDEX instruments_workspace_id_name ON instruments (workspace_id, name) WHERE (deleted_at IS NULL);
+-- Modify "currencies" table
+ALTER TABLE currencies DROP CONSTRAINT currencies_workspace_id_code_key;
+-- Create index "currencies_workspace_id_code" to table: "currencies"
+CREATE UNIQUE INDEX currencies_workspace_id_code ON currencies (workspace_id, code) WHERE (deleted_at IS NULL);
+-- Modify "institutions" table
+ALTER TABLE institutions DROP CONSTRAINT institutions_workspace_id_name_key;
+-- Create index "institutions_workspace_id_name" to table: "institutions"
+CREATE UNIQUE INDEX institutions_workspace_id_name ON institutions (workspace_id, name) WHERE (deleted_at IS NULL);
@@ -0,0 +1,11 @@
+// Package migrations embeds the Atlas migration directory of the PostgreSQL
+// schema, the counterpart of db/migrations for SQLite. Atlas only reads the
+// .sql files of the directory, so this file does not affect atlas.sum.
+package migrations
+
+import "embed"
+
+// FS holds the migration files and their atlas.sum
+//
+//go:embed *.sql atlas.sum
+var FS embed.FS
@@ -0,0 +1,23 @@
+-- name: CreateAccount :one
+INSERT INTO accounts (
+  id, workspace_id, name, description, account_type_id, instrument_id, institution_id, currency_id
+) VALUES (
+  'acc_' || replace(gen_random_uuid()::text, '-', ''), $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetAccount :one
+SELECT * FROM accounts
+WHERE id = $1 AND workspace_id = $2 LIMIT 1;
+
+-- name: DeleteAccount :execrows
+DELETE FROM accounts
+WHERE id = $1 AND revision = $2;
+
+-- name: CountLedgerEntriesForAccount :one
+SELECT COUNT(*) FROM ledger_entries
+WHERE account_id = $1;
+
+-- name: GetAccountType :one
+SELECT * FROM account_types
+WHERE id = $1 LIMIT 1;
@@ -0,0 +1,21 @@
+-- name: AddAccountUser :one
+INSERT INTO account_users (
+  account_id, user_id
+) VALUES (
+  $1, $2
+)
+RETURNING *;
+
+-- name: RemoveAccountUser :execrows
+DELETE FROM account_users
+WHERE account_id = $1 AND user_id = $2;
+
+-- name: RemoveAllAccountUsers :exec
+DELETE FROM account_users
+WHERE account_id = $1;
+
+-- name: ListAccountUsers :many
+SELECT users.* FROM users
+JOIN account_users ON account_users.user_id = users.id
+WHERE account_users.account_id = $1 AND users.deleted_at IS NULL
+ORDER BY users.name;
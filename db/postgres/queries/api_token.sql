@@ -0,0 +1,25 @@
+-- name: CreateApiToken :one
+INSERT INTO api_tokens (
+  id, user_id, name, token_hash, created_at, expires_at
+) VALUES (
+  'tok_' || replace(gen_random_uuid()::text, '-', ''), $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetApiToken :one
+SELECT * FROM api_tokens
+WHERE id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: AuthenticateApiToken :one
+-- Finds the unrevoked, unexpired token with the hash, if its user is live
+SELECT api_tokens.* FROM api_tokens
+JOIN users ON users.id = api_tokens.user_id AND users.deleted_at IS NULL
+WHERE api_tokens.token_hash = sqlc.arg(token_hash)
+  AND api_tokens.revoked_at IS NULL
+  AND (api_tokens.expires_at IS NULL OR api_tokens.expires_at > sqlc.arg(now))
+LIMIT 1;
+
+-- name: RevokeApiToken :execrows
+UPDATE api_tokens
+SET revoked_at = $1
+WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL;
@@ -0,0 +1,18 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (
+  workspace_id, resource_type, resource_id, action, before_state, after_state, actor, request_id, occurred_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: ListAuditEventsAfter :many
+SELECT * FROM audit_events
+WHERE id > sqlc.arg(id)
+ORDER BY id
+LIMIT sqlc.arg('limit')::bigint;
+
+-- name: ListLatestAuditEvents :many
+SELECT * FROM audit_events
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')::bigint;
@@ -0,0 +1,29 @@
+-- name: GetUserAdmin :one
+SELECT is_admin FROM users
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: ResolveWorkspaceUser :one
+-- Returns the membership of a user in the workspace a request names, or in the
+-- workspace they joined first if it names none, by workspace ID among those
+-- joined in the same instant
+SELECT * FROM workspace_users
+WHERE user_id = sqlc.arg('user_id')
+  AND (workspace_id = sqlc.arg('workspace_id') OR sqlc.arg('workspace_id') = '')
+ORDER BY created_at, workspace_id
+LIMIT 1;
+
+-- name: ListMemberAccountIDs :many
+SELECT account_users.account_id FROM account_users
+JOIN accounts ON accounts.id = account_users.account_id
+WHERE account_users.user_id = $1 AND accounts.workspace_id = $2
+ORDER BY account_users.account_id;
+
+-- name: ListTransactionAccountIDs :many
+SELECT DISTINCT ledger_entries.account_id FROM ledger_entries
+JOIN transactions ON transactions.id = ledger_entries.transaction_id
+WHERE ledger_entries.transaction_id = $1 AND transactions.workspace_id = $2
+ORDER BY ledger_entries.account_id;
+
+-- name: ListReconciliationAccountIDs :many
+SELECT account_id FROM reconciliations
+WHERE id = $1 AND workspace_id = $2;
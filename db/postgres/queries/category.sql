@@ -0,0 +1,106 @@
+-- name: CreateCategory :one
+INSERT INTO categories (
+  id, workspace_id, parent_id, name, description
+) VALUES (
+  'cat_' || replace(gen_random_uuid()::text, '-', ''), $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetCategory :one
+SELECT * FROM categories
+WHERE id = $1 AND workspace_id = $2 LIMIT 1;
+
+-- name: MoveCategory :one
+UPDATE categories
+SET
+  parent_id = $1,
+  updated_at = CURRENT_TIMESTAMP,
+  revision = revision + 1
+WHERE id = $2 AND revision = $3
+RETURNING *;
+
+-- name: DeleteCategory :execrows
+DELETE FROM categories
+WHERE id = $1 AND revision = $2;
+
+-- name: ListCategorySubtree :many
+-- Returns the category and all of its descendants, the root at depth 0
+WITH RECURSIVE subtree (id, depth) AS (
+  SELECT categories.id, 0
+  FROM categories
+  WHERE categories.id = sqlc.arg('id')
+  UNION ALL
+  SELECT c.id, s.depth + 1
+  FROM categories c
+  JOIN subtree s ON c.parent_id = s.id
+)
+SELECT
+  c.id, c.parent_id, c.name, c.description, c.created_at, c.updated_at, c.revision,
+  CAST(s.depth AS BIGINT) AS depth
+FROM subtree s
+JOIN categories c ON c.id = s.id
+ORDER BY s.depth, c.name;
+
+-- name: ListCategoryForest :many
+-- Returns every category of a workspace reachable from a top-level category
+-- with its depth
+WITH RECURSIVE forest (id, depth) AS (
+  SELECT categories.id, 0
+  FROM categories
+  WHERE categories.parent_id IS NULL
+    AND categories.workspace_id = sqlc.arg('workspace_id')
+  UNION ALL
+  SELECT c.id, f.depth + 1
+  FROM categories c
+  JOIN forest f ON c.parent_id = f.id
+)
+SELECT
+  c.id, c.parent_id, c.name, c.description, c.created_at, c.updated_at, c.revision,
+  CAST(f.depth AS BIGINT) AS depth
+FROM forest f
+JOIN categories c ON c.id = f.id
+ORDER BY f.depth, c.name;
+
+-- name: CountCategoryChildren :one
+SELECT COUNT(*) FROM categories
+WHERE parent_id = $1;
+
+-- name: CountCategoryReferences :one
+SELECT
+  CAST((SELECT COUNT(*) FROM transactions WHERE transactions.category_id = sqlc.arg('id'))
+  + (SELECT COUNT(*) FROM ledger_entries WHERE ledger_entries.category_id = sqlc.arg('id')) AS BIGINT) AS count;
+
+-- name: ReassignCategoryChildren :exec
+UPDATE categories
+SET
+  parent_id = sqlc.narg('new_parent_id'),
+  updated_at = CURRENT_TIMESTAMP,
+  revision = revision + 1
+WHERE parent_id = sqlc.arg('parent_id');
+
+-- name: ListCategoryTransactions :many
+-- Returns the transactions referring to a category themselves or through one
+-- of their ledger entries
+SELECT * FROM transactions
+WHERE transactions.category_id = sqlc.arg('category_id')
+  OR transactions.id IN (SELECT transaction_id FROM ledger_entries WHERE ledger_entries.category_id = sqlc.arg('category_id'))
+ORDER BY id;
+
+-- name: ReassignTransactionCategory :exec
+-- Re-points the transactions referring to a category, bumping the revision of
+-- those referring to it through a ledger entry too. Runs before
+-- ReassignLedgerEntryCategory, which it relies on to find them.
+UPDATE transactions
+SET
+  category_id = CASE WHEN transactions.category_id = sqlc.arg('category_id') THEN sqlc.narg('new_category_id') ELSE transactions.category_id END,
+  updated_at = CURRENT_TIMESTAMP,
+  revision = revision + 1
+WHERE transactions.category_id = sqlc.arg('category_id')
+  OR transactions.id IN (SELECT transaction_id FROM ledger_entries WHERE ledger_entries.category_id = sqlc.arg('category_id'));
+
+-- name: ReassignLedgerEntryCategory :exec
+UPDATE ledger_entries
+SET
+  category_id = sqlc.narg('new_category_id'),
+  updated_at = CURRENT_TIMESTAMP
+WHERE category_id = sqlc.arg('category_id');
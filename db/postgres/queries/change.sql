@@ -0,0 +1,31 @@
+-- name: CreateChange :exec
+-- Takes a transaction-level advisory lock first, so concurrent transactions
+-- take their seq in the order they commit and a stream reading the log after
+-- a seq never misses a change committed later with a lower one
+INSERT INTO changes (
+  workspace_id, resource_type, resource_id, action, account_ids, occurred_at
+)
+SELECT
+  sqlc.arg(workspace_id)::text, sqlc.arg(resource_type)::text, sqlc.arg(resource_id)::text,
+  sqlc.arg(action)::text, sqlc.arg(account_ids)::text, sqlc.arg(occurred_at)::timestamptz
+FROM (SELECT pg_advisory_xact_lock(hashtext('changes'))) AS change_lock;
+
+-- name: ListChangesAfter :many
+SELECT * FROM changes
+WHERE seq > sqlc.arg(seq)
+ORDER BY seq
+LIMIT sqlc.arg('limit')::bigint;
+
+-- name: GetChangeBounds :one
+-- Returns the first and last seq of the change log, 0 when it is empty
+SELECT
+  COALESCE(MIN(seq), 0)::bigint AS first_seq,
+  COALESCE(MAX(seq), 0)::bigint AS last_seq
+FROM changes;
+
+-- name: PruneChanges :execrows
+-- Deletes the changes up to the last one recorded before the cutoff, always
+-- keeping the newest so the log still tells how far it reached
+DELETE FROM changes
+WHERE seq <= (SELECT MAX(cutoff.seq) FROM changes AS cutoff WHERE cutoff.occurred_at < $1)
+  AND seq < (SELECT MAX(newest.seq) FROM changes AS newest);
@@ -0,0 +1,29 @@
+-- name: ClaimIdempotencyKey :execrows
+-- Claims a key for a request in progress, leaving an existing claim untouched
+INSERT INTO idempotency_keys (
+  idempotency_key, method, request_hash, created_at, expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (idempotency_key) DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE idempotency_key = $1 LIMIT 1;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET
+  response = $1,
+  code = $2,
+  error_message = $3,
+  completed_at = $4
+WHERE idempotency_key = $5;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE idempotency_key = $1;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at <= $1;
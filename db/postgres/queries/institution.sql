@@ -0,0 +1,36 @@
+-- name: CreateInstitution :one
+INSERT INTO institutions (
+  id, workspace_id, name, type
+) VALUES (
+  'fi_' || replace(gen_random_uuid()::text, '-', ''), $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetInstitution :one
+SELECT * FROM institutions
+WHERE id = $1 AND workspace_id = $2 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetDeletedInstitution :one
+SELECT * FROM institutions
+WHERE id = $1 AND workspace_id = $2 AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: DeleteInstitution :execrows
+UPDATE institutions
+SET deleted_at = $1, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = $2 AND revision = $3 AND deleted_at IS NULL;
+
+-- name: UndeleteInstitution :one
+UPDATE institutions
+SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = $1 AND revision = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeInstitutions :execrows
+DELETE FROM institutions
+WHERE deleted_at <= $1
+  AND NOT EXISTS (SELECT 1 FROM accounts WHERE accounts.institution_id = institutions.id);
+
+-- name: ListAccountsForInstitution :many
+SELECT * FROM accounts
+WHERE institution_id = $1
+ORDER BY name;
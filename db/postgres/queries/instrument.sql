@@ -0,0 +1,72 @@
+-- name: CreateInstrument :one
+INSERT INTO
+  instruments (id, workspace_id, name)
+VALUES
+  (replace(gen_random_uuid()::text, '-', ''), $1, $2) RETURNING *;
+
+-- name: GetInstrument :one
+SELECT
+  *
+FROM
+  instruments
+WHERE
+  id = $1
+  AND workspace_id = $2
+  AND deleted_at IS NULL
+LIMIT
+  1;
+
+-- name: GetDeletedInstrument :one
+SELECT
+  *
+FROM
+  instruments
+WHERE
+  id = $1
+  AND workspace_id = $2
+  AND deleted_at IS NOT NULL
+LIMIT
+  1;
+
+-- name: DeleteInstrument :execrows
+UPDATE instruments
+SET
+  deleted_at = $1,
+  updated_at = CURRENT_TIMESTAMP,
+  revision = revision + 1
+WHERE
+  id = $2
+  AND revision = $3
+  AND deleted_at IS NULL;
+
+-- name: UndeleteInstrument :one
+UPDATE instruments
+SET
+  deleted_at = NULL,
+  updated_at = CURRENT_TIMESTAMP,
+  revision = revision + 1
+WHERE
+  id = $1
+  AND revision = $2
+  AND deleted_at IS NOT NULL RETURNING *;
+
+-- name: PurgeInstruments :execrows
+DELETE FROM instruments
+WHERE
+  deleted_at <= $1
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      accounts
+    WHERE
+      accounts.instrument_id = instruments.id
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      transactions
+    WHERE
+      transactions.instrument_id = instruments.id
+  );
@@ -0,0 +1,24 @@
+-- name: CreateLedgerEntry :one
+INSERT INTO ledger_entries (
+  id, transaction_id, account_id, category_id, memo, debit, credit, currency_id
+) VALUES (
+  'le_' || replace(gen_random_uuid()::text, '-', ''), $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: ListLedgerEntriesForTransactions :many
+-- Lists the entries of the transactions in a comma-separated list of IDs, in
+-- the order they were written
+SELECT
+  sqlc.embed(ledger_entries),
+  COALESCE(currencies.code, '') AS currency_code,
+  CAST(COALESCE(currencies.minor_units, 0) AS BIGINT) AS currency_minor_units,
+  COALESCE(currencies.symbol, '') AS currency_symbol
+FROM ledger_entries
+LEFT JOIN currencies ON currencies.id = ledger_entries.currency_id
+WHERE ledger_entries.transaction_id = ANY(string_to_array(sqlc.arg('transaction_ids')::text, ','))
+ORDER BY ledger_entries.transaction_id, ledger_entries.created_at, ledger_entries.id;
+
+-- name: DeleteLedgerEntriesForTransaction :exec
+DELETE FROM ledger_entries
+WHERE transaction_id = $1;
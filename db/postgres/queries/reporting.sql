@@ -0,0 +1,65 @@
+-- name: ListLedgerBalances :many
+-- Sums the ledger entries of a workspace dated within [from_date, to_date] per
+-- account, category and currency. Unless member_id is empty, only the
+-- transactions whose entries are all on accounts the member belongs to are
+-- summed, so every transaction counts in full or not at all.
+SELECT
+  accounts.id AS account_id,
+  accounts.name AS account_name,
+  account_types.code AS account_type_code,
+  ledger_entries.category_id,
+  COALESCE(categories.name, '') AS category_name,
+  COALESCE(currencies.code, '') AS currency_code,
+  CAST(COALESCE(currencies.minor_units, 0) AS BIGINT) AS currency_minor_units,
+  COALESCE(currencies.symbol, '') AS currency_symbol,
+  CAST(SUM(ledger_entries.debit) AS BIGINT) AS total_debit,
+  CAST(SUM(ledger_entries.credit) AS BIGINT) AS total_credit
+FROM ledger_entries
+JOIN transactions ON transactions.id = ledger_entries.transaction_id
+CROSS JOIN (SELECT sqlc.arg('member_id')::text AS id) AS report_member
+JOIN accounts ON accounts.id = ledger_entries.account_id
+JOIN account_types ON account_types.id = accounts.account_type_id
+LEFT JOIN categories ON categories.id = ledger_entries.category_id
+LEFT JOIN currencies ON currencies.id = ledger_entries.currency_id
+WHERE transactions.workspace_id = sqlc.arg('workspace_id')
+  AND transactions.date >= sqlc.arg('from_date')
+  AND transactions.date <= sqlc.arg('to_date')
+  AND (report_member.id = '' OR NOT EXISTS (
+    SELECT 1 FROM ledger_entries AS member_entries
+    WHERE member_entries.transaction_id = transactions.id
+      AND member_entries.account_id NOT IN (SELECT account_id FROM account_users WHERE user_id = report_member.id)
+  ))
+GROUP BY accounts.id, account_types.id, ledger_entries.category_id, categories.id, ledger_entries.currency_id, currencies.id
+ORDER BY
+  CASE account_types.code WHEN 'A' THEN 0 WHEN 'L' THEN 1 ELSE 2 END,
+  accounts.name,
+  category_name,
+  currency_code;
+
+-- name: ListUnbalancedTransactions :many
+-- Finds the transactions of a workspace dated on or before as_of whose debits
+-- and credits differ in some currency, narrowed like ListLedgerBalances to the
+-- transactions of member_id unless it is empty
+SELECT
+  transactions.id AS transaction_id,
+  transactions.date,
+  transactions.description,
+  COALESCE(currencies.code, '') AS currency_code,
+  CAST(COALESCE(currencies.minor_units, 0) AS BIGINT) AS currency_minor_units,
+  COALESCE(currencies.symbol, '') AS currency_symbol,
+  CAST(SUM(ledger_entries.debit) AS BIGINT) AS total_debit,
+  CAST(SUM(ledger_entries.credit) AS BIGINT) AS total_credit
+FROM ledger_entries
+JOIN transactions ON transactions.id = ledger_entries.transaction_id
+CROSS JOIN (SELECT sqlc.arg('member_id')::text AS id) AS report_member
+LEFT JOIN currencies ON currencies.id = ledger_entries.currency_id
+WHERE transactions.workspace_id = sqlc.arg('workspace_id')
+  AND transactions.date <= sqlc.arg('as_of')
+  AND (report_member.id = '' OR NOT EXISTS (
+    SELECT 1 FROM ledger_entries AS member_entries
+    WHERE member_entries.transaction_id = transactions.id
+      AND member_entries.account_id NOT IN (SELECT account_id FROM account_users WHERE user_id = report_member.id)
+  ))
+GROUP BY transactions.id, ledger_entries.currency_id, currencies.id
+HAVING SUM(ledger_entries.debit) <> SUM(ledger_entries.credit)
+ORDER BY transactions.date, transactions.id, currency_code;
@@ -0,0 +1,21 @@
+-- name: CreateSession :one
+INSERT INTO sessions (
+  id, user_id, token_hash, created_at, expires_at
+) VALUES (
+  'ses_' || replace(gen_random_uuid()::text, '-', ''), $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: AuthenticateSession :one
+-- Finds the unrevoked, unexpired session with the hash, if its user is live
+SELECT sessions.* FROM sessions
+JOIN users ON users.id = sessions.user_id AND users.deleted_at IS NULL
+WHERE sessions.token_hash = sqlc.arg(token_hash)
+  AND sessions.revoked_at IS NULL
+  AND sessions.expires_at > sqlc.arg(now)
+LIMIT 1;
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = $1
+WHERE id = $2 AND revoked_at IS NULL;
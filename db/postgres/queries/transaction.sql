@@ -0,0 +1,22 @@
+-- name: CreateTransaction :one
+INSERT INTO transactions (
+  id, workspace_id, date, description, notes, category_id, instrument_id, allocation_tag
+) VALUES (
+  'txn_' || replace(gen_random_uuid()::text, '-', ''), $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetTransaction :one
+SELECT * FROM transactions
+WHERE id = $1 AND workspace_id = $2 LIMIT 1;
+
+-- name: DeleteTransaction :execrows
+DELETE FROM transactions
+WHERE id = $1 AND revision = $2;
+
+-- name: ClearReconciliationAdjustments :exec
+UPDATE reconciliations
+SET
+  adjustment_transaction_id = NULL,
+  updated_at = CURRENT_TIMESTAMP
+WHERE adjustment_transaction_id = $1;
@@ -0,0 +1,46 @@
+-- name: CreateUser :one
+INSERT INTO users (
+  id, name, email, is_admin
+) VALUES (
+  'usr_' || replace(gen_random_uuid()::text, '-', ''), $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetUserInWorkspace :one
+SELECT users.* FROM users
+JOIN workspace_users ON workspace_users.user_id = users.id
+WHERE users.id = $1 AND workspace_users.workspace_id = $2 AND users.deleted_at IS NULL LIMIT 1;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: SetUserAdmin :execrows
+UPDATE users
+SET is_admin = $1, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = $2 AND deleted_at IS NULL;
+
+-- name: GetDeletedUser :one
+SELECT * FROM users
+WHERE id = $1 AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: DeleteUser :execrows
+UPDATE users
+SET deleted_at = $1, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = $2 AND revision = $3 AND deleted_at IS NULL;
+
+-- name: UndeleteUser :one
+UPDATE users
+SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = $1 AND revision = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeUsers :execrows
+DELETE FROM users
+WHERE deleted_at <= $1
+  AND NOT EXISTS (SELECT 1 FROM account_users WHERE account_users.user_id = users.id)
+  AND NOT EXISTS (SELECT 1 FROM audit_events WHERE audit_events.actor = users.id);
@@ -0,0 +1,56 @@
+-- name: CreateWorkspace :one
+INSERT INTO workspaces (
+  id, name
+) VALUES (
+  'wsp_' || replace(gen_random_uuid()::text, '-', ''), $1
+)
+RETURNING *;
+
+-- name: GetWorkspace :one
+SELECT * FROM workspaces
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWorkspacesForUser :many
+SELECT sqlc.embed(workspaces), workspace_users.is_admin
+FROM workspaces
+JOIN workspace_users ON workspace_users.workspace_id = workspaces.id
+WHERE workspace_users.user_id = $1
+ORDER BY workspaces.name, workspaces.id;
+
+-- name: AddWorkspaceUser :one
+INSERT INTO workspace_users (
+  workspace_id, user_id, is_admin
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetWorkspaceUser :one
+SELECT * FROM workspace_users
+WHERE workspace_id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: RemoveWorkspaceUser :execrows
+DELETE FROM workspace_users
+WHERE workspace_id = $1 AND user_id = $2;
+
+-- name: RemoveWorkspaceAccountUsers :exec
+-- Removes a user from every account of a workspace
+DELETE FROM account_users
+WHERE user_id = sqlc.arg('user_id')
+  AND account_id IN (SELECT id FROM accounts WHERE workspace_id = sqlc.arg('workspace_id'));
+
+-- name: CountWorkspaceAdmins :one
+SELECT COUNT(*) FROM workspace_users
+WHERE workspace_id = $1 AND is_admin;
+
+-- name: ListWorkspaceUsers :many
+SELECT sqlc.embed(users), workspace_users.is_admin, workspace_users.created_at AS joined_at
+FROM users
+JOIN workspace_users ON workspace_users.user_id = users.id
+WHERE workspace_users.workspace_id = $1 AND users.deleted_at IS NULL
+ORDER BY users.name;
+
+-- name: SetWorkspaceUserAdmin :execrows
+UPDATE workspace_users
+SET is_admin = $1
+WHERE workspace_id = $2 AND user_id = $3;
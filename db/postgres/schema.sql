@@ -0,0 +1,298 @@
+-- The PostgreSQL schema, table for table and column for column the SQLite
+-- schema in db/schema.sql, which the generated queries of both share.
+
+-- Account Types (Asset, Liability, Equity)
+CREATE TABLE account_types (
+  id text PRIMARY KEY,
+  name text NOT NULL,
+  code text NOT NULL CHECK (code IN ('A', 'L', 'E')),
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (name),
+  UNIQUE (code)
+);
+
+-- Users
+CREATE TABLE users (
+  id text PRIMARY KEY,
+  name text NOT NULL,
+  email text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revision bigint NOT NULL DEFAULT 1,
+  deleted_at timestamptz,
+  is_admin boolean NOT NULL DEFAULT FALSE
+);
+
+CREATE UNIQUE INDEX users_email ON users (email) WHERE deleted_at IS NULL;
+
+-- Workspaces (households sharing a server, each with its own data)
+CREATE TABLE workspaces (
+  id text PRIMARY KEY,
+  name text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Workspace Users (Many-to-Many, with the role of each user in a workspace)
+CREATE TABLE workspace_users (
+  workspace_id text NOT NULL,
+  user_id text NOT NULL,
+  is_admin boolean NOT NULL DEFAULT FALSE,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (workspace_id, user_id),
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX workspace_users_user_id ON workspace_users (user_id);
+
+-- Instruments (Cash, Bank Account, Credit Card, etc.)
+CREATE TABLE instruments (
+  id text PRIMARY KEY,
+  workspace_id text NOT NULL,
+  name text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revision bigint NOT NULL DEFAULT 1,
+  deleted_at timestamptz,
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id)
+);
+
+CREATE UNIQUE INDEX instruments_workspace_id_name ON instruments (workspace_id, name) WHERE deleted_at IS NULL;
+
+-- Currencies
+CREATE TABLE currencies (
+  id text PRIMARY KEY,
+  workspace_id text NOT NULL,
+  code text NOT NULL,
+  name text NOT NULL,
+  minor_units bigint NOT NULL DEFAULT 2,
+  symbol text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revision bigint NOT NULL DEFAULT 1,
+  deleted_at timestamptz,
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id)
+);
+
+CREATE UNIQUE INDEX currencies_workspace_id_code ON currencies (workspace_id, code) WHERE deleted_at IS NULL;
+
+-- Institutions (Banks, Credit Card Companies, etc.)
+CREATE TABLE institutions (
+  id text PRIMARY KEY,
+  workspace_id text NOT NULL,
+  name text NOT NULL,
+  type text NOT NULL CHECK (
+    type IN ('BANK', 'CARD_ISSUER', 'BROKER', 'WALLET', 'OTHER')
+  ),
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revision bigint NOT NULL DEFAULT 1,
+  deleted_at timestamptz,
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id)
+);
+
+CREATE UNIQUE INDEX institutions_workspace_id_name ON institutions (workspace_id, name) WHERE deleted_at IS NULL;
+
+-- Accounts
+CREATE TABLE accounts (
+  id text PRIMARY KEY,
+  workspace_id text NOT NULL,
+  name text NOT NULL,
+  description text,
+  account_type_id text NOT NULL,
+  instrument_id text,
+  institution_id text,
+  currency_id text,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revision bigint NOT NULL DEFAULT 1,
+  UNIQUE (workspace_id, name),
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
+  FOREIGN KEY (account_type_id) REFERENCES account_types (id),
+  FOREIGN KEY (instrument_id) REFERENCES instruments (id),
+  FOREIGN KEY (institution_id) REFERENCES institutions (id),
+  FOREIGN KEY (currency_id) REFERENCES currencies (id)
+);
+
+-- Account Users (Many-to-Many)
+CREATE TABLE account_users (
+  account_id text NOT NULL,
+  user_id text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (account_id, user_id),
+  FOREIGN KEY (account_id) REFERENCES accounts (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX account_users_user_id ON account_users (user_id);
+
+-- Categories (Income/Expense types, optional parent_id for hierarchy)
+CREATE TABLE categories (
+  id text PRIMARY KEY,
+  workspace_id text NOT NULL,
+  parent_id text,
+  name text NOT NULL,
+  description text,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revision bigint NOT NULL DEFAULT 1,
+  UNIQUE (workspace_id, name),
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
+  FOREIGN KEY (parent_id) REFERENCES categories (id)
+);
+
+-- Transactions (Journal)
+CREATE TABLE transactions (
+  id text PRIMARY KEY,
+  workspace_id text NOT NULL,
+  date timestamptz NOT NULL,
+  description text NOT NULL,
+  notes text,
+  category_id text,
+  instrument_id text,
+  allocation_tag text,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revision bigint NOT NULL DEFAULT 1,
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
+  FOREIGN KEY (category_id) REFERENCES categories (id),
+  FOREIGN KEY (instrument_id) REFERENCES instruments (id)
+);
+
+CREATE INDEX transactions_workspace_id_date ON transactions (workspace_id, date);
+
+-- Ledger Entries, in the workspace of their transaction. Entries are listed in
+-- the order they were written, which created_at keeps by taking the time of
+-- each insert rather than of its database transaction.
+CREATE TABLE ledger_entries (
+  id text PRIMARY KEY,
+  transaction_id text NOT NULL,
+  account_id text NOT NULL,
+  category_id text,
+  memo text NOT NULL,
+  debit bigint NOT NULL DEFAULT 0,
+  credit bigint NOT NULL DEFAULT 0,
+  currency_id text,
+  created_at timestamptz NOT NULL DEFAULT clock_timestamp(),
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (transaction_id) REFERENCES transactions (id) ON DELETE CASCADE,
+  FOREIGN KEY (account_id) REFERENCES accounts (id),
+  FOREIGN KEY (category_id) REFERENCES categories (id),
+  FOREIGN KEY (currency_id) REFERENCES currencies (id),
+  CHECK (
+    debit >= 0
+    AND credit >= 0
+    AND (
+      debit = 0
+      OR credit = 0
+    )
+  )
+);
+
+-- Reconciliations (counted cash balances compared with the ledger)
+CREATE TABLE reconciliations (
+  id text PRIMARY KEY,
+  workspace_id text NOT NULL,
+  account_id text NOT NULL,
+  counted_at timestamptz NOT NULL,
+  counted_amount bigint NOT NULL,
+  ledger_balance bigint NOT NULL,
+  discrepancy bigint NOT NULL,
+  currency_id text NOT NULL,
+  adjustment_transaction_id text,
+  notes text,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
+  FOREIGN KEY (account_id) REFERENCES accounts (id),
+  FOREIGN KEY (currency_id) REFERENCES currencies (id),
+  FOREIGN KEY (adjustment_transaction_id) REFERENCES transactions (id) ON DELETE SET NULL
+);
+
+CREATE INDEX reconciliations_account_id_counted_at ON reconciliations (account_id, counted_at);
+
+-- Idempotency keys (outcomes of mutating requests, replayed on retry)
+CREATE TABLE idempotency_keys (
+  idempotency_key text PRIMARY KEY,
+  method text NOT NULL,
+  request_hash text NOT NULL,
+  response bytea,
+  code bigint NOT NULL DEFAULT 0,
+  error_message text NOT NULL DEFAULT '',
+  completed_at timestamptz,
+  created_at timestamptz NOT NULL,
+  expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+-- Audit events, one per mutation, written in the transaction making it. Events
+-- outlive their workspace, and denials of requests outside any workspace have
+-- none.
+CREATE TABLE audit_events (
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  resource_type text NOT NULL,
+  resource_id text NOT NULL,
+  action text NOT NULL,
+  before_state text,
+  after_state text,
+  actor text NOT NULL DEFAULT '',
+  request_id text NOT NULL DEFAULT '',
+  occurred_at timestamptz NOT NULL,
+  workspace_id text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_events_resource ON audit_events (resource_type, resource_id);
+
+CREATE INDEX audit_events_actor ON audit_events (actor);
+
+CREATE INDEX audit_events_occurred_at ON audit_events (occurred_at);
+
+CREATE INDEX audit_events_workspace_id ON audit_events (workspace_id);
+
+-- Change log of the resources WatchChanges reports, one row per mutation
+-- written in the transaction making it. seq orders the changes as they were
+-- committed, and account_ids lists the accounts of account and transaction
+-- changes, comma separated, so streams only show them to their members.
+CREATE TABLE changes (
+  seq bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  workspace_id text NOT NULL,
+  resource_type text NOT NULL,
+  resource_id text NOT NULL,
+  action text NOT NULL,
+  account_ids text NOT NULL DEFAULT '',
+  occurred_at timestamptz NOT NULL
+);
+
+CREATE INDEX changes_occurred_at ON changes (occurred_at);
+
+-- API tokens (personal tokens authenticating a user, stored hashed)
+CREATE TABLE api_tokens (
+  id text PRIMARY KEY,
+  user_id text NOT NULL,
+  name text NOT NULL,
+  token_hash text NOT NULL,
+  created_at timestamptz NOT NULL,
+  expires_at timestamptz,
+  revoked_at timestamptz,
+  UNIQUE (token_hash),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX api_tokens_user_id ON api_tokens (user_id);
+
+-- Sessions (cookie sessions of the web frontend, stored hashed)
+CREATE TABLE sessions (
+  id text PRIMARY KEY,
+  user_id text NOT NULL,
+  token_hash text NOT NULL,
+  created_at timestamptz NOT NULL,
+  expires_at timestamptz NOT NULL,
+  revoked_at timestamptz,
+  UNIQUE (token_hash),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX sessions_user_id ON sessions (user_id);
@@ -0,0 +1,23 @@
+-- name: CreateAccount :one
+INSERT INTO accounts (
+  id, workspace_id, name, description, account_type_id, instrument_id, institution_id, currency_id
+) VALUES (
+  'acc_' || lower(hex(randomblob(16))), ?, ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetAccount :one
+SELECT * FROM accounts
+WHERE id = ? AND workspace_id = ? LIMIT 1;
+
+-- name: DeleteAccount :execrows
+DELETE FROM accounts
+WHERE id = ? AND revision = ?;
+
+-- name: CountLedgerEntriesForAccount :one
+SELECT COUNT(*) FROM ledger_entries
+WHERE account_id = ?;
+
+-- name: GetAccountType :one
+SELECT * FROM account_types
+WHERE id = ? LIMIT 1;
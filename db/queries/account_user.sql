@@ -0,0 +1,21 @@
+-- name: AddAccountUser :one
+INSERT INTO account_users (
+  account_id, user_id
+) VALUES (
+  ?, ?
+)
+RETURNING *;
+
+-- name: RemoveAccountUser :execrows
+DELETE FROM account_users
+WHERE account_id = ? AND user_id = ?;
+
+-- name: RemoveAllAccountUsers :exec
+DELETE FROM account_users
+WHERE account_id = ?;
+
+-- name: ListAccountUsers :many
+SELECT users.* FROM users
+JOIN account_users ON account_users.user_id = users.id
+WHERE account_users.account_id = ? AND users.deleted_at IS NULL
+ORDER BY users.name;
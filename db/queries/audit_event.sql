@@ -0,0 +1,18 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (
+  workspace_id, resource_type, resource_id, action, before_state, after_state, actor, request_id, occurred_at
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: ListAuditEventsAfter :many
+SELECT * FROM audit_events
+WHERE id > ?
+ORDER BY id
+LIMIT ?;
+
+-- name: ListLatestAuditEvents :many
+SELECT * FROM audit_events
+ORDER BY id DESC
+LIMIT ?;
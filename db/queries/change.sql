@@ -0,0 +1,26 @@
+-- name: CreateChange :exec
+INSERT INTO changes (
+  workspace_id, resource_type, resource_id, action, account_ids, occurred_at
+) VALUES (
+  ?, ?, ?, ?, ?, ?
+);
+
+-- name: ListChangesAfter :many
+SELECT * FROM changes
+WHERE seq > ?
+ORDER BY seq
+LIMIT ?;
+
+-- name: GetChangeBounds :one
+-- Returns the first and last seq of the change log, 0 when it is empty
+SELECT
+  CAST(COALESCE(MIN(seq), 0) AS INTEGER) AS first_seq,
+  CAST(COALESCE(MAX(seq), 0) AS INTEGER) AS last_seq
+FROM changes;
+
+-- name: PruneChanges :execrows
+-- Deletes the changes up to the last one recorded before the cutoff, always
+-- keeping the newest so the log still tells how far it reached
+DELETE FROM changes
+WHERE seq <= (SELECT MAX(cutoff.seq) FROM changes AS cutoff WHERE cutoff.occurred_at < ?)
+  AND seq < (SELECT MAX(newest.seq) FROM changes AS newest);
@@ -0,0 +1,49 @@
+-- name: CreateCurrency :one
+INSERT INTO currencies (
+  id, workspace_id, code, name, minor_units, symbol
+) VALUES (
+  'cur_' || lower(hex(randomblob(16))), ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetCurrency :one
+SELECT * FROM currencies
+WHERE id = ? AND workspace_id = ? AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetCurrencyByCode :one
+SELECT * FROM currencies
+WHERE code = ? AND workspace_id = ? AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetDeletedCurrency :one
+SELECT * FROM currencies
+WHERE id = ? AND workspace_id = ? AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: DeleteCurrency :execrows
+UPDATE currencies
+SET deleted_at = ?, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = ? AND revision = ? AND deleted_at IS NULL;
+
+-- name: UndeleteCurrency :one
+UPDATE currencies
+SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = ? AND revision = ? AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeCurrencies :execrows
+DELETE FROM currencies
+WHERE deleted_at <= ?
+  AND NOT EXISTS (SELECT 1 FROM accounts WHERE accounts.currency_id = currencies.id)
+  AND NOT EXISTS (SELECT 1 FROM ledger_entries WHERE ledger_entries.currency_id = currencies.id)
+  AND NOT EXISTS (SELECT 1 FROM reconciliations WHERE reconciliations.currency_id = currencies.id);
+
+-- name: CountAccountsForCurrency :one
+SELECT COUNT(*) FROM accounts
+WHERE currency_id = ?;
+
+-- name: CountLedgerEntriesForCurrency :one
+SELECT COUNT(*) FROM ledger_entries
+WHERE currency_id = ?;
+
+-- name: CountReconciliationsForCurrency :one
+SELECT COUNT(*) FROM reconciliations
+WHERE currency_id = ?;
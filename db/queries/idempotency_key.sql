@@ -0,0 +1,29 @@
+-- name: ClaimIdempotencyKey :execrows
+-- Claims a key for a request in progress, leaving an existing claim untouched
+INSERT INTO idempotency_keys (
+  idempotency_key, method, request_hash, created_at, expires_at
+) VALUES (
+  ?, ?, ?, ?, ?
+)
+ON CONFLICT (idempotency_key) DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE idempotency_key = ? LIMIT 1;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET
+  response = ?,
+  code = ?,
+  error_message = ?,
+  completed_at = ?
+WHERE idempotency_key = ?;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE idempotency_key = ?;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at <= ?;
@@ -0,0 +1,36 @@
+-- name: CreateInstitution :one
+INSERT INTO institutions (
+  id, workspace_id, name, type
+) VALUES (
+  'fi_' || lower(hex(randomblob(16))), ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetInstitution :one
+SELECT * FROM institutions
+WHERE id = ? AND workspace_id = ? AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetDeletedInstitution :one
+SELECT * FROM institutions
+WHERE id = ? AND workspace_id = ? AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: DeleteInstitution :execrows
+UPDATE institutions
+SET deleted_at = ?, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = ? AND revision = ? AND deleted_at IS NULL;
+
+-- name: UndeleteInstitution :one
+UPDATE institutions
+SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = ? AND revision = ? AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeInstitutions :execrows
+DELETE FROM institutions
+WHERE deleted_at <= ?
+  AND NOT EXISTS (SELECT 1 FROM accounts WHERE accounts.institution_id = institutions.id);
+
+-- name: ListAccountsForInstitution :many
+SELECT * FROM accounts
+WHERE institution_id = ?
+ORDER BY name;
@@ -1,8 +1,8 @@
 -- name: CreateInstrument :one
 INSERT INTO
-  instruments (id, name)
+  instruments (id, workspace_id, name)
 VALUES
-  (lower(hex (randomblob (16))), ?) RETURNING *;
+  (lower(hex (randomblob (16))), ?, ?) RETURNING *;
 
 -- name: GetInstrument :one
 SELECT
@@ -11,30 +11,62 @@ FROM
   instruments
 WHERE
   id = ?
+  AND workspace_id = ?
+  AND deleted_at IS NULL
 LIMIT
   1;
 
--- name: ListInstruments :many
+-- name: GetDeletedInstrument :one
 SELECT
   *
 FROM
   instruments
-ORDER BY
-  name
+WHERE
+  id = ?
+  AND workspace_id = ?
+  AND deleted_at IS NOT NULL
 LIMIT
-  ?
-OFFSET
-  ?;
+  1;
 
--- name: UpdateInstrument :one
+-- name: DeleteInstrument :execrows
 UPDATE instruments
 SET
-  name = ?,
-  updated_at = CURRENT_TIMESTAMP
+  deleted_at = ?,
+  updated_at = CURRENT_TIMESTAMP,
+  revision = revision + 1
 WHERE
-  id = ? RETURNING *;
+  id = ?
+  AND revision = ?
+  AND deleted_at IS NULL;
+
+-- name: UndeleteInstrument :one
+UPDATE instruments
+SET
+  deleted_at = NULL,
+  updated_at = CURRENT_TIMESTAMP,
+  revision = revision + 1
+WHERE
+  id = ?
+  AND revision = ?
+  AND deleted_at IS NOT NULL RETURNING *;
 
--- name: DeleteInstrument :exec
+-- name: PurgeInstruments :execrows
 DELETE FROM instruments
 WHERE
-  id = ?;
+  deleted_at <= ?
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      accounts
+    WHERE
+      accounts.instrument_id = instruments.id
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      transactions
+    WHERE
+      transactions.instrument_id = instruments.id
+  );
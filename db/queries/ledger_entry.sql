@@ -0,0 +1,24 @@
+-- name: CreateLedgerEntry :one
+INSERT INTO ledger_entries (
+  id, transaction_id, account_id, category_id, memo, debit, credit, currency_id
+) VALUES (
+  'le_' || lower(hex(randomblob(16))), ?, ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: ListLedgerEntriesForTransactions :many
+-- Lists the entries of the transactions in a comma-separated list of IDs, in
+-- the order they were written
+SELECT
+  sqlc.embed(ledger_entries),
+  COALESCE(currencies.code, '') AS currency_code,
+  CAST(COALESCE(currencies.minor_units, 0) AS INTEGER) AS currency_minor_units,
+  COALESCE(currencies.symbol, '') AS currency_symbol
+FROM ledger_entries
+LEFT JOIN currencies ON currencies.id = ledger_entries.currency_id
+WHERE instr(',' || CAST(sqlc.arg('transaction_ids') AS TEXT) || ',', ',' || ledger_entries.transaction_id || ',') > 0
+ORDER BY ledger_entries.transaction_id, ledger_entries.rowid;
+
+-- name: DeleteLedgerEntriesForTransaction :exec
+DELETE FROM ledger_entries
+WHERE transaction_id = ?;
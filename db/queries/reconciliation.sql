@@ -0,0 +1,61 @@
+-- name: CreateReconciliation :one
+INSERT INTO reconciliations (
+  id, workspace_id, account_id, counted_at, counted_amount, ledger_balance,
+  discrepancy, currency_id, adjustment_transaction_id, notes
+) VALUES (
+  'rec_' || lower(hex(randomblob(16))), ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetReconciliation :one
+SELECT
+  sqlc.embed(reconciliations),
+  COALESCE(currencies.code, '') AS currency_code,
+  CAST(COALESCE(currencies.minor_units, 0) AS INTEGER) AS currency_minor_units,
+  COALESCE(currencies.symbol, '') AS currency_symbol
+FROM reconciliations
+LEFT JOIN currencies ON currencies.id = reconciliations.currency_id
+WHERE reconciliations.id = ? AND reconciliations.workspace_id = ? LIMIT 1;
+
+-- name: ListReconciliationsForAccount :many
+-- Lists the counts of an account on or before as_of, oldest first
+SELECT * FROM reconciliations
+WHERE account_id = sqlc.arg('account_id')
+  AND counted_at <= sqlc.arg('as_of')
+ORDER BY counted_at, id;
+
+-- name: ListCashAccounts :many
+-- Cash accounts are Asset accounts of a workspace with a default currency
+SELECT
+  accounts.id AS account_id,
+  accounts.name AS account_name,
+  currencies.id AS currency_id,
+  currencies.code AS currency_code,
+  currencies.minor_units AS currency_minor_units,
+  currencies.symbol AS currency_symbol
+FROM accounts
+JOIN account_types ON account_types.id = accounts.account_type_id
+JOIN currencies ON currencies.id = accounts.currency_id
+WHERE account_types.code = 'A'
+  AND accounts.workspace_id = sqlc.arg('workspace_id')
+  AND accounts.id = COALESCE(sqlc.narg('account_id'), accounts.id)
+ORDER BY accounts.name;
+
+-- name: GetLedgerBalance :one
+-- Sums debits minus credits of an account in one currency for transactions
+-- dated on or before as_of
+SELECT CAST(COALESCE(SUM(ledger_entries.debit - ledger_entries.credit), 0) AS INTEGER) AS balance
+FROM ledger_entries
+JOIN transactions ON transactions.id = ledger_entries.transaction_id
+WHERE ledger_entries.account_id = sqlc.arg('account_id')
+  AND ledger_entries.currency_id = sqlc.arg('currency_id')
+  AND transactions.date <= sqlc.arg('as_of');
+
+-- name: CountLedgerEntriesBetween :one
+-- Counts the entries of an account for transactions dated after after_date and
+-- on or before until_date
+SELECT COUNT(*) FROM ledger_entries
+JOIN transactions ON transactions.id = ledger_entries.transaction_id
+WHERE ledger_entries.account_id = sqlc.arg('account_id')
+  AND transactions.date > sqlc.arg('after_date')
+  AND transactions.date <= sqlc.arg('until_date');
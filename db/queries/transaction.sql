@@ -0,0 +1,22 @@
+-- name: CreateTransaction :one
+INSERT INTO transactions (
+  id, workspace_id, date, description, notes, category_id, instrument_id, allocation_tag
+) VALUES (
+  'txn_' || lower(hex(randomblob(16))), ?, ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetTransaction :one
+SELECT * FROM transactions
+WHERE id = ? AND workspace_id = ? LIMIT 1;
+
+-- name: DeleteTransaction :execrows
+DELETE FROM transactions
+WHERE id = ? AND revision = ?;
+
+-- name: ClearReconciliationAdjustments :exec
+UPDATE reconciliations
+SET
+  adjustment_transaction_id = NULL,
+  updated_at = CURRENT_TIMESTAMP
+WHERE adjustment_transaction_id = ?;
@@ -1,30 +1,46 @@
 -- name: CreateUser :one
 INSERT INTO users (
-  id, name, email
+  id, name, email, is_admin
 ) VALUES (
-  'usr_' || lower(hex(randomblob(16))), ?, ?
+  'usr_' || lower(hex(randomblob(16))), ?, ?, ?
 )
 RETURNING *;
 
 -- name: GetUser :one
 SELECT * FROM users
-WHERE id = ? LIMIT 1;
+WHERE id = ? AND deleted_at IS NULL LIMIT 1;
 
--- name: ListUsers :many
-/* 
-ListUsers returns a paginated list of users.
-*/
+-- name: GetUserInWorkspace :one
+SELECT users.* FROM users
+JOIN workspace_users ON workspace_users.user_id = users.id
+WHERE users.id = ? AND workspace_users.workspace_id = ? AND users.deleted_at IS NULL LIMIT 1;
+
+-- name: GetUserByEmail :one
 SELECT * FROM users
-ORDER BY name
-LIMIT COALESCE(sqlc.narg('limit'), 50)
-OFFSET COALESCE(sqlc.narg('offset'), 0);
+WHERE email = ? AND deleted_at IS NULL LIMIT 1;
+
+-- name: SetUserAdmin :execrows
+UPDATE users
+SET is_admin = ?, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = ? AND deleted_at IS NULL;
+
+-- name: GetDeletedUser :one
+SELECT * FROM users
+WHERE id = ? AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: DeleteUser :execrows
+UPDATE users
+SET deleted_at = ?, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = ? AND revision = ? AND deleted_at IS NULL;
 
--- name: UpdateUser :one
+-- name: UndeleteUser :one
 UPDATE users
-SET name = ?, email = ?, updated_at = CURRENT_TIMESTAMP
-WHERE id = ?
+SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP, revision = revision + 1
+WHERE id = ? AND revision = ? AND deleted_at IS NOT NULL
 RETURNING *;
 
--- name: DeleteUser :exec
+-- name: PurgeUsers :execrows
 DELETE FROM users
-WHERE id = ?;
+WHERE deleted_at <= ?
+  AND NOT EXISTS (SELECT 1 FROM account_users WHERE account_users.user_id = users.id)
+  AND NOT EXISTS (SELECT 1 FROM audit_events WHERE audit_events.actor = users.id);
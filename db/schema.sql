@@ -16,41 +16,86 @@ CREATE TABLE users (
   email TEXT NOT NULL,
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  UNIQUE (email)
+  revision INTEGER NOT NULL DEFAULT 1,
+  deleted_at TIMESTAMP,
+  is_admin BOOLEAN NOT NULL DEFAULT FALSE
 );
 
+CREATE UNIQUE INDEX users_email ON users (email) WHERE deleted_at IS NULL;
+
+-- Workspaces (households sharing a server, each with its own data)
+CREATE TABLE workspaces (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Workspace Users (Many-to-Many, with the role of each user in a workspace)
+CREATE TABLE workspace_users (
+  workspace_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (workspace_id, user_id),
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX workspace_users_user_id ON workspace_users (user_id);
+
 -- Instruments (Cash, Bank Account, Credit Card, etc.)
 CREATE TABLE instruments (
   id TEXT PRIMARY KEY,
+  workspace_id TEXT NOT NULL,
   name TEXT NOT NULL,
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  UNIQUE (name)
+  revision INTEGER NOT NULL DEFAULT 1,
+  deleted_at TIMESTAMP,
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id)
 );
 
+CREATE UNIQUE INDEX instruments_workspace_id_name ON instruments (workspace_id, name) WHERE deleted_at IS NULL;
+
 -- Currencies
 CREATE TABLE currencies (
   id TEXT PRIMARY KEY,
+  workspace_id TEXT NOT NULL,
   code TEXT NOT NULL,
   name TEXT NOT NULL,
+  minor_units INTEGER NOT NULL DEFAULT 2,
+  symbol TEXT NOT NULL DEFAULT '',
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  UNIQUE (code)
+  revision INTEGER NOT NULL DEFAULT 1,
+  deleted_at TIMESTAMP,
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id)
 );
 
+CREATE UNIQUE INDEX currencies_workspace_id_code ON currencies (workspace_id, code) WHERE deleted_at IS NULL;
+
 -- Institutions (Banks, Credit Card Companies, etc.)
 CREATE TABLE institutions (
   id TEXT PRIMARY KEY,
+  workspace_id TEXT NOT NULL,
   name TEXT NOT NULL,
-  type TEXT NOT NULL,
+  type TEXT NOT NULL CHECK (
+    type IN ('BANK', 'CARD_ISSUER', 'BROKER', 'WALLET', 'OTHER')
+  ),
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  UNIQUE (name)
+  revision INTEGER NOT NULL DEFAULT 1,
+  deleted_at TIMESTAMP,
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id)
 );
 
+CREATE UNIQUE INDEX institutions_workspace_id_name ON institutions (workspace_id, name) WHERE deleted_at IS NULL;
+
 -- Accounts
 CREATE TABLE accounts (
   id TEXT PRIMARY KEY,
+  workspace_id TEXT NOT NULL,
   name TEXT NOT NULL,
   description TEXT,
   account_type_id TEXT NOT NULL,
@@ -59,7 +104,9 @@ CREATE TABLE accounts (
   currency_id TEXT,
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  UNIQUE (name),
+  revision INTEGER NOT NULL DEFAULT 1,
+  UNIQUE (workspace_id, name),
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
   FOREIGN KEY (account_type_id) REFERENCES account_types (id),
   FOREIGN KEY (instrument_id) REFERENCES instruments (id),
   FOREIGN KEY (institution_id) REFERENCES institutions (id),
@@ -76,21 +123,27 @@ CREATE TABLE account_users (
   FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
 );
 
+CREATE INDEX account_users_user_id ON account_users (user_id);
+
 -- Categories (Income/Expense types, optional parent_id for hierarchy)
 CREATE TABLE categories (
   id TEXT PRIMARY KEY,
+  workspace_id TEXT NOT NULL,
   parent_id TEXT,
   name TEXT NOT NULL,
   description TEXT,
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  UNIQUE (name),
+  revision INTEGER NOT NULL DEFAULT 1,
+  UNIQUE (workspace_id, name),
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
   FOREIGN KEY (parent_id) REFERENCES categories (id)
 );
 
 -- Transactions (Journal)
 CREATE TABLE transactions (
   id TEXT PRIMARY KEY,
+  workspace_id TEXT NOT NULL,
   date TIMESTAMP NOT NULL,
   description TEXT NOT NULL,
   notes TEXT,
@@ -99,11 +152,15 @@ CREATE TABLE transactions (
   allocation_tag TEXT,
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revision INTEGER NOT NULL DEFAULT 1,
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
   FOREIGN KEY (category_id) REFERENCES categories (id),
   FOREIGN KEY (instrument_id) REFERENCES instruments (id)
 );
 
--- Ledger Entries
+CREATE INDEX transactions_workspace_id_date ON transactions (workspace_id, date);
+
+-- Ledger Entries, in the workspace of their transaction
 CREATE TABLE ledger_entries (
   id TEXT PRIMARY KEY,
   transaction_id TEXT NOT NULL,
@@ -128,3 +185,109 @@ CREATE TABLE ledger_entries (
     )
   )
 );
+
+-- Reconciliations (counted cash balances compared with the ledger)
+CREATE TABLE reconciliations (
+  id TEXT PRIMARY KEY,
+  workspace_id TEXT NOT NULL,
+  account_id TEXT NOT NULL,
+  counted_at TIMESTAMP NOT NULL,
+  counted_amount INTEGER NOT NULL,
+  ledger_balance INTEGER NOT NULL,
+  discrepancy INTEGER NOT NULL,
+  currency_id TEXT NOT NULL,
+  adjustment_transaction_id TEXT,
+  notes TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
+  FOREIGN KEY (account_id) REFERENCES accounts (id),
+  FOREIGN KEY (currency_id) REFERENCES currencies (id),
+  FOREIGN KEY (adjustment_transaction_id) REFERENCES transactions (id) ON DELETE SET NULL
+);
+
+CREATE INDEX reconciliations_account_id_counted_at ON reconciliations (account_id, counted_at);
+
+-- Idempotency keys (outcomes of mutating requests, replayed on retry)
+CREATE TABLE idempotency_keys (
+  idempotency_key TEXT PRIMARY KEY,
+  method TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  response BLOB,
+  code INTEGER NOT NULL DEFAULT 0,
+  error_message TEXT NOT NULL DEFAULT '',
+  completed_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+-- Audit events, one per mutation, written in the transaction making it. Events
+-- outlive their workspace, and denials of requests outside any workspace have
+-- none.
+CREATE TABLE audit_events (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  resource_type TEXT NOT NULL,
+  resource_id TEXT NOT NULL,
+  action TEXT NOT NULL,
+  before_state TEXT,
+  after_state TEXT,
+  actor TEXT NOT NULL DEFAULT '',
+  request_id TEXT NOT NULL DEFAULT '',
+  occurred_at TIMESTAMP NOT NULL,
+  workspace_id TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_events_resource ON audit_events (resource_type, resource_id);
+
+CREATE INDEX audit_events_actor ON audit_events (actor);
+
+CREATE INDEX audit_events_occurred_at ON audit_events (occurred_at);
+
+CREATE INDEX audit_events_workspace_id ON audit_events (workspace_id);
+
+-- Change log of the resources WatchChanges reports, one row per mutation
+-- written in the transaction making it. seq orders the changes as they were
+-- committed, and account_ids lists the accounts of account and transaction
+-- changes, comma separated, so streams only show them to their members.
+CREATE TABLE changes (
+  seq INTEGER PRIMARY KEY AUTOINCREMENT,
+  workspace_id TEXT NOT NULL,
+  resource_type TEXT NOT NULL,
+  resource_id TEXT NOT NULL,
+  action TEXT NOT NULL,
+  account_ids TEXT NOT NULL DEFAULT '',
+  occurred_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX changes_occurred_at ON changes (occurred_at);
+
+-- API tokens (personal tokens authenticating a user, stored hashed)
+CREATE TABLE api_tokens (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  name TEXT NOT NULL,
+  token_hash TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  UNIQUE (token_hash),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX api_tokens_user_id ON api_tokens (user_id);
+
+-- Sessions (cookie sessions of the web frontend, stored hashed)
+CREATE TABLE sessions (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  token_hash TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  revoked_at TIMESTAMP,
+  UNIQUE (token_hash),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX sessions_user_id ON sessions (user_id);
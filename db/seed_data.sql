@@ -6,26 +6,37 @@ VALUES
   ('at_liability', 'Liability', 'L'),
   ('at_equity', 'Equity', 'E');
 
--- Insert default currency (JPY)
+-- Insert the default workspace the data below belongs to, which the
+-- workspaces migration already creates in a migrated database
 INSERT INTO
-  currencies (id, code, name)
+  workspaces (id, name)
 VALUES
-  ('cur_jpy', 'JPY', 'Japanese Yen');
+  ('wsp_default', 'Default')
+ON CONFLICT DO NOTHING;
+
+-- Insert default currency (JPY) and the other currencies we hold accounts in
+INSERT INTO
+  currencies (id, workspace_id, code, name, minor_units, symbol)
+VALUES
+  ('cur_jpy', 'wsp_default', 'JPY', 'Japanese Yen', 0, '¥'),
+  ('cur_usd', 'wsp_default', 'USD', 'US Dollar', 2, '$'),
+  ('cur_eur', 'wsp_default', 'EUR', 'Euro', 2, '€');
 
 -- Insert basic instruments
 INSERT INTO
-  instruments (id, name)
+  instruments (id, workspace_id, name)
 VALUES
-  ('inst_cash', 'Cash'),
-  ('inst_bank', 'Bank Account'),
-  ('inst_credit', 'Credit Card');
+  ('inst_cash', 'wsp_default', 'Cash'),
+  ('inst_bank', 'wsp_default', 'Bank Account'),
+  ('inst_credit', 'wsp_default', 'Credit Card');
 
 -- Insert essential Equity account for tracking earnings
 INSERT INTO
-  accounts (id, name, description, account_type_id)
+  accounts (id, workspace_id, name, description, account_type_id)
 VALUES
   (
     'acc_earnings',
+    'wsp_default',
     'Current Year Earnings',
     'Account for tracking current year earnings',
     'at_equity'
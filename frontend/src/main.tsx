@@ -14,6 +14,8 @@ import "./styles.css"
 const queryClient = new QueryClient()
 const transport = createConnectTransport({
   baseUrl: "http://localhost:8080", // TODO: Make this configurable
+  // Send the session cookie along with every request
+  fetch: (input, init) => fetch(input, { ...init, credentials: "include" }),
 })
 
 // Create a new router instance
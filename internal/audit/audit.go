@@ -0,0 +1,146 @@
+// Package audit carries the metadata of a request that audit events record,
+// and encodes the snapshots of resources before and after a mutation
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Actions recorded by audit events
+const (
+	ActionCreate   = "CREATE"
+	ActionUpdate   = "UPDATE"
+	ActionDelete   = "DELETE"
+	ActionUndelete = "UNDELETE"
+	// ActionDeny records a request refused by authorization
+	ActionDeny = "DENY"
+)
+
+const (
+	// RequestIDHeader is the header carrying the ID of a request. A request
+	// without one is assigned a random ID, returned in the same header.
+	RequestIDHeader = "X-Request-Id"
+	// maxRequestIDLength is the longest request ID accepted from a client
+	maxRequestIDLength = 128
+)
+
+// Request is the metadata of the request making a mutation
+type Request struct {
+	ID    string
+	Actor string // Empty for unauthenticated requests
+}
+
+type requestKey struct{}
+
+// WithRequest returns a copy of ctx carrying the request metadata
+func WithRequest(ctx context.Context, request Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, request)
+}
+
+// RequestFrom returns the request metadata carried by ctx, if any
+func RequestFrom(ctx context.Context) Request {
+	request, _ := ctx.Value(requestKey{}).(Request)
+	return request
+}
+
+// WithActor returns a copy of ctx whose request metadata names actor as the
+// one making the request
+func WithActor(ctx context.Context, actor string) context.Context {
+	request := RequestFrom(ctx)
+	request.Actor = actor
+	return WithRequest(ctx, request)
+}
+
+// Snapshot encodes a resource as JSON for an audit event. A nil message, for
+// the missing side of a create or delete, encodes to nil.
+func Snapshot(msg proto.Message) (*string, error) {
+	if msg == nil || !msg.ProtoReflect().IsValid() {
+		return nil, nil
+	}
+	encoded, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	// protojson varies its whitespace on purpose; store compact JSON so
+	// snapshots are stable across builds
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, encoded); err != nil {
+		return nil, err
+	}
+	snapshot := compact.String()
+	return &snapshot, nil
+}
+
+// Interceptor tags every request with an ID for the audit events it writes,
+// taken from the X-Request-Id header or generated, and returns it in the same
+// response header
+type Interceptor struct{}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// NewInterceptor creates a new Interceptor
+func NewInterceptor() *Interceptor {
+	return &Interceptor{}
+}
+
+// WrapUnary implements connect.Interceptor
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		requestID := requestID(req.Header().Get(RequestIDHeader))
+		res, err := next(i.withRequestID(ctx, requestID), req)
+		if err != nil {
+			var connectErr *connect.Error
+			if errors.As(err, &connectErr) {
+				connectErr.Meta().Set(RequestIDHeader, requestID)
+			}
+			return nil, err
+		}
+		res.Header().Set(RequestIDHeader, requestID)
+		return res, nil
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		requestID := requestID(conn.RequestHeader().Get(RequestIDHeader))
+		conn.ResponseHeader().Set(RequestIDHeader, requestID)
+		return next(i.withRequestID(ctx, requestID), conn)
+	}
+}
+
+// withRequestID sets the request ID of the request metadata in ctx, keeping
+// an actor already identified
+func (i *Interceptor) withRequestID(ctx context.Context, requestID string) context.Context {
+	request := RequestFrom(ctx)
+	request.ID = requestID
+	return WithRequest(ctx, request)
+}
+
+// requestID returns the request ID a client sent, or a random one if it sent
+// none or one too long to record
+func requestID(header string) string {
+	if header != "" && len(header) <= maxRequestIDLength {
+		return header
+	}
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
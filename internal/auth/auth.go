@@ -0,0 +1,129 @@
+// Package auth authenticates requests with personal API tokens and cookie
+// sessions, and carries the authenticated user in the request context
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// SessionCookie is the name of the cookie carrying a session token
+	SessionCookie = "expense_session"
+	// apiTokenPrefix and sessionTokenPrefix mark the kind of a token, so a
+	// leaked one is easy to recognize
+	apiTokenPrefix     = "emt_"
+	sessionTokenPrefix = "ems_"
+)
+
+// Identity is the authenticated user of a request and the credential it
+// authenticated with. Exactly one of APITokenID and SessionID is set.
+type Identity struct {
+	UserID     string
+	APITokenID string
+	SessionID  string
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the authenticated identity
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFrom returns the authenticated identity carried by ctx, if any
+func IdentityFrom(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// UserIDFrom returns the ID of the authenticated user carried by ctx, or "" if
+// the request is not authenticated
+func UserIDFrom(ctx context.Context) string {
+	identity, _ := IdentityFrom(ctx)
+	return identity.UserID
+}
+
+// NewAPIToken generates a new personal API token
+func NewAPIToken() (string, error) {
+	return newToken(apiTokenPrefix)
+}
+
+// NewSessionToken generates a new session token
+func NewSessionToken() (string, error) {
+	return newToken(sessionTokenPrefix)
+}
+
+// newToken generates a random token with the given prefix
+func newToken(prefix string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// HashToken returns the hash a token is stored and looked up by. Tokens are
+// long and random, so a fast unsalted hash is enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or ""
+func bearerToken(header http.Header) string {
+	scheme, token, ok := strings.Cut(header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// sessionToken returns the token of the session cookie, or ""
+func sessionToken(header http.Header) string {
+	for _, line := range header.Values("Cookie") {
+		cookies, err := http.ParseCookie(line)
+		if err != nil {
+			continue
+		}
+		for _, cookie := range cookies {
+			if cookie.Name == SessionCookie {
+				return cookie.Value
+			}
+		}
+	}
+	return ""
+}
+
+// NewSessionCookie returns the cookie handing a session token to a browser.
+// The cookie is unreadable by scripts and withheld from cross-site requests.
+func NewSessionCookie(token string, expiresAt time.Time, secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// ClearSessionCookie returns the cookie removing the session cookie from a
+// browser
+func ClearSessionCookie(secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     SessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
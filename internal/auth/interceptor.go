@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"connectrpc.com/connect"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/repo"
+)
+
+// Interceptor rejects requests without a valid API token or session, and puts
+// the authenticated user into the context of the others. An API token is sent
+// as "Authorization: Bearer <token>", a session as the session cookie.
+type Interceptor struct {
+	repo   *repo.AuthRepo
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// NewInterceptor creates a new Interceptor
+func NewInterceptor(authRepo *repo.AuthRepo, clk clock.Clock, logger *slog.Logger) *Interceptor {
+	return &Interceptor{
+		repo:   authRepo,
+		clock:  clk,
+		logger: logger,
+	}
+}
+
+// WrapUnary implements connect.Interceptor
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		ctx, err := i.authenticate(ctx, req.Spec().Procedure, req.Header())
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := i.authenticate(ctx, conn.Spec().Procedure, conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// authenticate resolves the credentials of a request to its identity, and
+// returns a copy of ctx carrying it for services, logs and audit events
+func (i *Interceptor) authenticate(ctx context.Context, procedure string, header http.Header) (context.Context, error) {
+	now := i.clock.Now().UTC()
+	var identity Identity
+	if token := bearerToken(header); token != "" {
+		apiToken, err := i.repo.AuthenticateAPIToken(ctx, i.repo.GetDB(), HashToken(token), now)
+		if err != nil {
+			return nil, i.credentialError(ctx, procedure, "api token", err)
+		}
+		identity = Identity{UserID: apiToken.UserID, APITokenID: apiToken.ID}
+	} else if token := sessionToken(header); token != "" {
+		session, err := i.repo.AuthenticateSession(ctx, i.repo.GetDB(), HashToken(token), now)
+		if err != nil {
+			return nil, i.credentialError(ctx, procedure, "session", err)
+		}
+		identity = Identity{UserID: session.UserID, SessionID: session.ID}
+	} else {
+		log.ErrorContext(ctx, i.logger, "Request without credentials", "procedure", procedure)
+		return nil, unauthenticated(fmt.Errorf("%w: an api token or session is required", errors.ErrUnauthenticated))
+	}
+
+	ctx = WithIdentity(ctx, identity)
+	ctx = audit.WithActor(ctx, identity.UserID)
+	return log.WithAttrs(ctx, "user_id", identity.UserID), nil
+}
+
+// credentialError converts a failed lookup of a credential into a connect
+// error, hiding whether it never existed, expired or was revoked
+func (i *Interceptor) credentialError(ctx context.Context, procedure, kind string, err error) error {
+	if stderrors.Is(err, errors.ErrNotFound) {
+		log.ErrorContext(ctx, i.logger, "Invalid credentials", "procedure", procedure, "kind", kind)
+		return unauthenticated(fmt.Errorf("%w: invalid or expired %s", errors.ErrUnauthenticated, kind))
+	}
+	log.ErrorContext(ctx, i.logger, "Failed to authenticate request", "procedure", procedure, "kind", kind, "error", err)
+	return errors.Internal(err)
+}
+
+// unauthenticated returns an Unauthenticated error telling the client which
+// scheme to authenticate with
+func unauthenticated(err error) error {
+	connectErr := errors.Unauthenticated(err)
+	connectErr.Meta().Set("WWW-Authenticate", "Bearer")
+	return connectErr
+}
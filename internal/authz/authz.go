@@ -0,0 +1,67 @@
+// Package authz authorizes authenticated requests. Every request is made in a
+// workspace, named by the X-Workspace-Id header or else the first workspace
+// its user joined. A policy table decides who may call each procedure: master
+// data is managed by the admins of the workspace only, ledger entries are read
+// and posted by the members of their accounts, as recorded in account_users,
+// and users are managed by server admins.
+package authz
+
+import (
+	"context"
+
+	"github.com/atreya2011/expense-manager/internal/repo"
+)
+
+// WorkspaceHeader is the request header naming the workspace of a request
+const WorkspaceHeader = "X-Workspace-Id"
+
+// Subject is the authenticated user a request is authorized for
+type Subject struct {
+	UserID      string
+	ServerAdmin bool
+	// WorkspaceID is the workspace of the request, empty if the user does not
+	// belong to the workspace the request names or to any
+	WorkspaceID string
+	Admin       bool            // Whether the user is an admin of the workspace
+	Accounts    map[string]bool // IDs of the accounts of the workspace the user belongs to
+}
+
+// NewSubject creates the Subject of a user of a workspace belonging to
+// accountIDs
+func NewSubject(userID, workspaceID string, admin bool, accountIDs []string) Subject {
+	accounts := make(map[string]bool, len(accountIDs))
+	for _, id := range accountIDs {
+		accounts[id] = true
+	}
+	return Subject{UserID: userID, WorkspaceID: workspaceID, Admin: admin, Accounts: accounts}
+}
+
+// Member reports whether the subject belongs to an account
+func (s Subject) Member(accountID string) bool {
+	return s.Accounts[accountID]
+}
+
+type subjectKey struct{}
+
+// WithSubject returns a copy of ctx carrying the authorized subject
+func WithSubject(ctx context.Context, subject Subject) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// SubjectFrom returns the authorized subject carried by ctx, if any. Services
+// narrow the ledger entries they list to the subject's accounts; requests
+// without a subject, such as in-process calls, are not narrowed.
+func SubjectFrom(ctx context.Context) (Subject, bool) {
+	subject, ok := ctx.Value(subjectKey{}).(Subject)
+	return subject, ok
+}
+
+// WorkspaceFrom returns the workspace of the request carried by ctx. Requests
+// without a subject, such as in-process calls, are made in the default
+// workspace.
+func WorkspaceFrom(ctx context.Context) string {
+	if subject, ok := SubjectFrom(ctx); ok {
+		return subject.WorkspaceID
+	}
+	return repo.DefaultWorkspaceID
+}
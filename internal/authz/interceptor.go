@@ -0,0 +1,165 @@
+package authz
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+
+	"connectrpc.com/connect"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+)
+
+// Interceptor resolves the workspace of every request and authorizes it
+// against the Policy, denying those it does not allow with PermissionDenied
+// and recording each denial in the audit log. Allowed requests carry their
+// Subject in the context. It must run after the auth interceptor.
+type Interceptor struct {
+	repo      *repo.AuthzRepo
+	auditRepo *repo.AuditRepo
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// NewInterceptor creates a new Interceptor
+func NewInterceptor(authzRepo *repo.AuthzRepo, auditRepo *repo.AuditRepo, clk clock.Clock, logger *slog.Logger) *Interceptor {
+	return &Interceptor{
+		repo:      authzRepo,
+		auditRepo: auditRepo,
+		clock:     clk,
+		logger:    logger,
+	}
+}
+
+// WrapUnary implements connect.Interceptor
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		ctx, err := i.authorize(ctx, req.Spec().Procedure, req.Header().Get(WorkspaceHeader), req.Any())
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor. The messages of a
+// stream arrive after it is authorized, so streaming procedures cannot be
+// Member procedures.
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := i.authorize(ctx, conn.Spec().Procedure, conn.RequestHeader().Get(WorkspaceHeader), nil)
+		if err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// authorize decides whether the authenticated user may call procedure with
+// msg in the named workspace, or their first one if empty, and returns a copy
+// of ctx carrying their Subject if so
+func (i *Interceptor) authorize(ctx context.Context, procedure, workspaceID string, msg any) (context.Context, error) {
+	identity, ok := auth.IdentityFrom(ctx)
+	if !ok {
+		log.ErrorContext(ctx, i.logger, "Request reached authorization unauthenticated", "procedure", procedure)
+		return nil, errors.Unauthenticated(fmt.Errorf("%w: an api token or session is required", errors.ErrUnauthenticated))
+	}
+
+	subject, err := i.subject(ctx, identity.UserID, workspaceID)
+	if err == nil {
+		err = Authorize(ctx, procedure, msg, subject, resolver{repo: i.repo, workspaceID: subject.WorkspaceID})
+	}
+	if err != nil {
+		if stderrors.Is(err, errors.ErrPermissionDenied) {
+			i.recordDenial(ctx, procedure, subject.WorkspaceID, err)
+			return nil, errors.PermissionDenied(err)
+		}
+		log.ErrorContext(ctx, i.logger, "Failed to authorize request", "procedure", procedure, "error", err)
+		return nil, errors.Internal(err)
+	}
+	return WithSubject(ctx, subject), nil
+}
+
+// subject loads the roles of a user and their accounts in a workspace, or in
+// their first workspace if workspaceID is empty. A user outside the workspace
+// gets a Subject without one.
+func (i *Interceptor) subject(ctx context.Context, userID, workspaceID string) (Subject, error) {
+	serverAdmin, err := i.repo.GetUserAdmin(ctx, i.repo.GetDB(), userID)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			return Subject{}, fmt.Errorf("%w: user %s not found", errors.ErrPermissionDenied, userID)
+		}
+		return Subject{}, err
+	}
+
+	member, err := i.repo.ResolveWorkspaceUser(ctx, i.repo.GetDB(), userID, workspaceID)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			return Subject{UserID: userID, ServerAdmin: serverAdmin}, nil
+		}
+		return Subject{}, err
+	}
+
+	accounts, err := i.repo.ListMemberAccountIDs(ctx, i.repo.GetDB(), member.WorkspaceID, userID)
+	if err != nil {
+		return Subject{}, err
+	}
+	subject := NewSubject(userID, member.WorkspaceID, member.IsAdmin, accounts)
+	subject.ServerAdmin = serverAdmin
+	return subject, nil
+}
+
+// recordDenial logs a denied request and records it in the audit log of the
+// workspace it was made in. A failure to record is logged; the request is
+// denied either way.
+func (i *Interceptor) recordDenial(ctx context.Context, procedure, workspaceID string, reason error) {
+	log.WarnContext(ctx, i.logger, "Permission denied", "procedure", procedure, "reason", reason)
+
+	request := audit.RequestFrom(ctx)
+	_, err := i.auditRepo.CreateAuditEvent(ctx, i.auditRepo.GetDB(), db.CreateAuditEventParams{
+		WorkspaceID:  workspaceID,
+		ResourceType: "procedure",
+		ResourceID:   procedure,
+		Action:       audit.ActionDeny,
+		Actor:        request.Actor,
+		RequestID:    request.ID,
+		OccurredAt:   i.clock.Now().UTC(),
+	})
+	if err != nil {
+		log.ErrorContext(ctx, i.logger, "Failed to record denial", "procedure", procedure, "error", err)
+	}
+}
+
+// resolver resolves the accounts of the stored resources of a workspace with
+// an AuthzRepo
+type resolver struct {
+	repo        *repo.AuthzRepo
+	workspaceID string
+}
+
+// TransactionAccounts implements Resolver
+func (r resolver) TransactionAccounts(ctx context.Context, id string) ([]string, error) {
+	return r.repo.ListTransactionAccountIDs(ctx, r.repo.GetDB(), r.workspaceID, id)
+}
+
+// ReconciliationAccounts implements Resolver
+func (r resolver) ReconciliationAccounts(ctx context.Context, id string) ([]string, error) {
+	return r.repo.ListReconciliationAccountIDs(ctx, r.repo.GetDB(), r.workspaceID, id)
+}
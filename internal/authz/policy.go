@@ -0,0 +1,259 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// Access is who may call a procedure
+type Access int
+
+const (
+	// Authenticated procedures may be called by every authenticated user,
+	// whether or not they belong to a workspace
+	Authenticated Access = iota + 1
+	// Workspace procedures may be called by every user of the request's
+	// workspace. Those listing ledger entries narrow their results to the
+	// caller's accounts.
+	Workspace
+	// Admin procedures manage the master data of the request's workspace and
+	// may only be called by its admins
+	Admin
+	// Member procedures read or post ledger entries on the accounts a request
+	// names, and may only be called by members of every one of them in the
+	// request's workspace. Being an admin grants no access to the ledger.
+	Member
+	// ServerAdmin procedures manage the users shared by every workspace and may
+	// only be called by server admins
+	ServerAdmin
+)
+
+// Resolver looks up the accounts of the stored resources a request names by ID.
+// Missing resources have no accounts, leaving the service to report them.
+type Resolver interface {
+	TransactionAccounts(ctx context.Context, id string) ([]string, error)
+	ReconciliationAccounts(ctx context.Context, id string) ([]string, error)
+}
+
+// Rule is the policy of a procedure
+type Rule struct {
+	Access Access
+	// Accounts returns the IDs of the accounts a request to a Member
+	// procedure touches
+	Accounts func(ctx context.Context, msg any, resolver Resolver) ([]string, error)
+}
+
+// Policy is the rule of every procedure. Procedures missing from it are denied
+// to everyone.
+var Policy = map[string]Rule{
+	// Users, which are shared by every workspace. Users of a workspace see
+	// its users only.
+	expensesv1connect.UserServiceCreateUserProcedure:       {Access: ServerAdmin},
+	expensesv1connect.UserServiceGetUserProcedure:          {Access: Workspace},
+	expensesv1connect.UserServiceListUsersProcedure:        {Access: Workspace},
+	expensesv1connect.UserServiceUpdateUserProcedure:       {Access: ServerAdmin},
+	expensesv1connect.UserServiceDeleteUserProcedure:       {Access: ServerAdmin},
+	expensesv1connect.UserServiceListDeletedUsersProcedure: {Access: ServerAdmin},
+	expensesv1connect.UserServiceUndeleteUserProcedure:     {Access: ServerAdmin},
+	expensesv1connect.UserServiceBatchCreateUsersProcedure: {Access: ServerAdmin},
+	expensesv1connect.UserServiceBatchUpdateUsersProcedure: {Access: ServerAdmin},
+	expensesv1connect.UserServiceBatchDeleteUsersProcedure: {Access: ServerAdmin},
+
+	// Instruments
+	expensesv1connect.InstrumentServiceCreateInstrumentProcedure:       {Access: Admin},
+	expensesv1connect.InstrumentServiceGetInstrumentProcedure:          {Access: Workspace},
+	expensesv1connect.InstrumentServiceListInstrumentsProcedure:        {Access: Workspace},
+	expensesv1connect.InstrumentServiceUpdateInstrumentProcedure:       {Access: Admin},
+	expensesv1connect.InstrumentServiceDeleteInstrumentProcedure:       {Access: Admin},
+	expensesv1connect.InstrumentServiceListDeletedInstrumentsProcedure: {Access: Admin},
+	expensesv1connect.InstrumentServiceUndeleteInstrumentProcedure:     {Access: Admin},
+	expensesv1connect.InstrumentServiceBatchCreateInstrumentsProcedure: {Access: Admin},
+	expensesv1connect.InstrumentServiceBatchUpdateInstrumentsProcedure: {Access: Admin},
+	expensesv1connect.InstrumentServiceBatchDeleteInstrumentsProcedure: {Access: Admin},
+
+	// Currencies
+	expensesv1connect.CurrencyServiceCreateCurrencyProcedure:        {Access: Admin},
+	expensesv1connect.CurrencyServiceGetCurrencyProcedure:           {Access: Workspace},
+	expensesv1connect.CurrencyServiceListCurrenciesProcedure:        {Access: Workspace},
+	expensesv1connect.CurrencyServiceUpdateCurrencyProcedure:        {Access: Admin},
+	expensesv1connect.CurrencyServiceDeleteCurrencyProcedure:        {Access: Admin},
+	expensesv1connect.CurrencyServiceListDeletedCurrenciesProcedure: {Access: Admin},
+	expensesv1connect.CurrencyServiceUndeleteCurrencyProcedure:      {Access: Admin},
+
+	// Institutions
+	expensesv1connect.InstitutionServiceCreateInstitutionProcedure:       {Access: Admin},
+	expensesv1connect.InstitutionServiceGetInstitutionProcedure:          {Access: Workspace},
+	expensesv1connect.InstitutionServiceListInstitutionsProcedure:        {Access: Workspace},
+	expensesv1connect.InstitutionServiceUpdateInstitutionProcedure:       {Access: Admin},
+	expensesv1connect.InstitutionServiceDeleteInstitutionProcedure:       {Access: Admin},
+	expensesv1connect.InstitutionServiceListDeletedInstitutionsProcedure: {Access: Admin},
+	expensesv1connect.InstitutionServiceUndeleteInstitutionProcedure:     {Access: Admin},
+	expensesv1connect.InstitutionServiceListInstitutionAccountsProcedure: {Access: Workspace},
+
+	// Categories
+	expensesv1connect.CategoryServiceCreateCategoryProcedure:  {Access: Admin},
+	expensesv1connect.CategoryServiceGetCategoryProcedure:     {Access: Workspace},
+	expensesv1connect.CategoryServiceListCategoriesProcedure:  {Access: Workspace},
+	expensesv1connect.CategoryServiceUpdateCategoryProcedure:  {Access: Admin},
+	expensesv1connect.CategoryServiceDeleteCategoryProcedure:  {Access: Admin},
+	expensesv1connect.CategoryServiceMoveCategoryProcedure:    {Access: Admin},
+	expensesv1connect.CategoryServiceGetCategoryTreeProcedure: {Access: Workspace},
+	expensesv1connect.CategoryServiceListDescendantsProcedure: {Access: Workspace},
+
+	// Accounts. Creating an account makes the caller its first member.
+	expensesv1connect.AccountServiceCreateAccountProcedure: {Access: Workspace},
+	expensesv1connect.AccountServiceGetAccountProcedure: {Access: Member, Accounts: request(func(_ context.Context, req *expensesv1.GetAccountRequest, _ Resolver) ([]string, error) {
+		return []string{req.GetId()}, nil
+	})},
+	expensesv1connect.AccountServiceListAccountsProcedure: {Access: Workspace},
+	expensesv1connect.AccountServiceUpdateAccountProcedure: {Access: Member, Accounts: request(func(_ context.Context, req *expensesv1.UpdateAccountRequest, _ Resolver) ([]string, error) {
+		return []string{req.GetId()}, nil
+	})},
+	expensesv1connect.AccountServiceDeleteAccountProcedure: {Access: Member, Accounts: request(func(_ context.Context, req *expensesv1.DeleteAccountRequest, _ Resolver) ([]string, error) {
+		return []string{req.GetId()}, nil
+	})},
+	expensesv1connect.AccountServiceAddAccountUserProcedure: {Access: Member, Accounts: request(func(_ context.Context, req *expensesv1.AddAccountUserRequest, _ Resolver) ([]string, error) {
+		return []string{req.GetAccountId()}, nil
+	})},
+	expensesv1connect.AccountServiceRemoveAccountUserProcedure: {Access: Member, Accounts: request(func(_ context.Context, req *expensesv1.RemoveAccountUserRequest, _ Resolver) ([]string, error) {
+		return []string{req.GetAccountId()}, nil
+	})},
+	expensesv1connect.AccountServiceListAccountUsersProcedure: {Access: Member, Accounts: request(func(_ context.Context, req *expensesv1.ListAccountUsersRequest, _ Resolver) ([]string, error) {
+		return []string{req.GetAccountId()}, nil
+	})},
+
+	// Transactions
+	expensesv1connect.TransactionServiceCreateTransactionProcedure: {Access: Member, Accounts: request(func(_ context.Context, req *expensesv1.CreateTransactionRequest, _ Resolver) ([]string, error) {
+		return lineAccounts(req.GetLines()), nil
+	})},
+	expensesv1connect.TransactionServiceGetTransactionProcedure: {Access: Member, Accounts: request(func(ctx context.Context, req *expensesv1.GetTransactionRequest, resolver Resolver) ([]string, error) {
+		return resolver.TransactionAccounts(ctx, req.GetId())
+	})},
+	expensesv1connect.TransactionServiceListTransactionsProcedure: {Access: Workspace},
+	expensesv1connect.TransactionServiceUpdateTransactionProcedure: {Access: Member, Accounts: request(func(ctx context.Context, req *expensesv1.UpdateTransactionRequest, resolver Resolver) ([]string, error) {
+		accounts, err := resolver.TransactionAccounts(ctx, req.GetId())
+		return append(accounts, lineAccounts(req.GetLines())...), err
+	})},
+	expensesv1connect.TransactionServiceDeleteTransactionProcedure: {Access: Member, Accounts: request(func(ctx context.Context, req *expensesv1.DeleteTransactionRequest, resolver Resolver) ([]string, error) {
+		return resolver.TransactionAccounts(ctx, req.GetId())
+	})},
+
+	// Reconciliations
+	expensesv1connect.ReconciliationServiceReconcileCashProcedure: {Access: Member, Accounts: request(func(_ context.Context, req *expensesv1.ReconcileCashRequest, _ Resolver) ([]string, error) {
+		return []string{req.GetAccountId(), req.GetAdjustmentAccountId()}, nil
+	})},
+	expensesv1connect.ReconciliationServiceGetReconciliationProcedure: {Access: Member, Accounts: request(func(ctx context.Context, req *expensesv1.GetReconciliationRequest, resolver Resolver) ([]string, error) {
+		return resolver.ReconciliationAccounts(ctx, req.GetId())
+	})},
+	expensesv1connect.ReconciliationServiceListReconciliationsProcedure: {Access: Member, Accounts: request(func(_ context.Context, req *expensesv1.ListReconciliationsRequest, _ Resolver) ([]string, error) {
+		return []string{req.GetAccountId()}, nil
+	})},
+	expensesv1connect.ReconciliationServiceListUnreconciledPeriodsProcedure: {Access: Member, Accounts: request(func(_ context.Context, req *expensesv1.ListUnreconciledPeriodsRequest, _ Resolver) ([]string, error) {
+		return []string{req.GetAccountId()}, nil
+	})},
+
+	// Reports
+	expensesv1connect.ReportingServiceGetTrialBalanceProcedure:    {Access: Workspace},
+	expensesv1connect.ReportingServiceGetBalanceSheetProcedure:    {Access: Workspace},
+	expensesv1connect.ReportingServiceGetIncomeStatementProcedure: {Access: Workspace},
+
+	// Change feed, narrowed to the accounts and transactions of the caller's
+	// accounts by the stream itself
+	expensesv1connect.ChangeServiceWatchChangesProcedure: {Access: Workspace},
+
+	// Audit log, which holds snapshots of every resource of the workspace
+	expensesv1connect.AuditServiceListAuditEventsProcedure: {Access: Admin},
+
+	// Workspaces. Creating a workspace makes the caller its first admin.
+	expensesv1connect.WorkspaceServiceCreateWorkspaceProcedure:     {Access: Authenticated},
+	expensesv1connect.WorkspaceServiceListWorkspacesProcedure:      {Access: Authenticated},
+	expensesv1connect.WorkspaceServiceInviteUserProcedure:          {Access: Admin},
+	expensesv1connect.WorkspaceServiceRemoveWorkspaceUserProcedure: {Access: Admin},
+	expensesv1connect.WorkspaceServiceListWorkspaceUsersProcedure:  {Access: Workspace},
+
+	// API tokens and sessions, each scoped to the caller
+	expensesv1connect.AuthServiceCreateApiTokenProcedure: {Access: Authenticated},
+	expensesv1connect.AuthServiceListApiTokensProcedure:  {Access: Authenticated},
+	expensesv1connect.AuthServiceRevokeApiTokenProcedure: {Access: Authenticated},
+	expensesv1connect.AuthServiceCreateSessionProcedure:  {Access: Authenticated},
+	expensesv1connect.AuthServiceDeleteSessionProcedure:  {Access: Authenticated},
+}
+
+// request adapts a function of a typed request to Rule.Accounts
+func request[T any](accounts func(context.Context, T, Resolver) ([]string, error)) func(context.Context, any, Resolver) ([]string, error) {
+	return func(ctx context.Context, msg any, resolver Resolver) ([]string, error) {
+		req, ok := msg.(T)
+		if !ok {
+			return nil, fmt.Errorf("unexpected request %T", msg)
+		}
+		return accounts(ctx, req, resolver)
+	}
+}
+
+// lineAccounts returns the accounts ledger lines are posted on
+func lineAccounts(lines []*expensesv1.LedgerLine) []string {
+	accounts := make([]string, len(lines))
+	for i, line := range lines {
+		accounts[i] = line.GetAccountId()
+	}
+	return accounts
+}
+
+// Authorize decides whether subject may call procedure with the request msg.
+// It returns an error wrapping errors.ErrPermissionDenied if not, and any
+// other error if the accounts of the request could not be resolved.
+func Authorize(ctx context.Context, procedure string, msg any, subject Subject, resolver Resolver) error {
+	rule, ok := Policy[procedure]
+	if !ok {
+		return fmt.Errorf("%w: %s has no policy", errors.ErrPermissionDenied, procedure)
+	}
+	var accounts []string
+	if rule.Access == Member && rule.Accounts != nil {
+		var err error
+		if accounts, err = rule.Accounts(ctx, msg, resolver); err != nil {
+			return fmt.Errorf("failed to resolve accounts of %s: %w", procedure, err)
+		}
+	}
+	return Decide(rule, subject, accounts)
+}
+
+// Decide applies a rule to the subject of a request touching accountIDs. Empty
+// account IDs, of optional fields left unset, are ignored.
+func Decide(rule Rule, subject Subject, accountIDs []string) error {
+	switch rule.Access {
+	case Authenticated:
+		return nil
+	case ServerAdmin:
+		if !subject.ServerAdmin {
+			return fmt.Errorf("%w: only server admins may manage users", errors.ErrPermissionDenied)
+		}
+		return nil
+	}
+
+	// Every other procedure is called in a workspace
+	if subject.WorkspaceID == "" {
+		return fmt.Errorf("%w: not a user of the workspace", errors.ErrPermissionDenied)
+	}
+	switch rule.Access {
+	case Workspace:
+		return nil
+	case Admin:
+		if !subject.Admin {
+			return fmt.Errorf("%w: only workspace admins may manage master data", errors.ErrPermissionDenied)
+		}
+		return nil
+	case Member:
+		for _, id := range accountIDs {
+			if id != "" && !subject.Member(id) {
+				return fmt.Errorf("%w: not a member of account %s", errors.ErrPermissionDenied, id)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown access %d", errors.ErrPermissionDenied, rule.Access)
+	}
+}
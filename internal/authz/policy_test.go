@@ -0,0 +1,238 @@
+package authz
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// fakeResolver resolves stored resources from maps of their accounts
+type fakeResolver struct {
+	transactions    map[string][]string
+	reconciliations map[string][]string
+}
+
+func (r fakeResolver) TransactionAccounts(_ context.Context, id string) ([]string, error) {
+	return r.transactions[id], nil
+}
+
+func (r fakeResolver) ReconciliationAccounts(_ context.Context, id string) ([]string, error) {
+	return r.reconciliations[id], nil
+}
+
+// TestAuthorize tests the policy table against admins, members and others
+func TestAuthorize(t *testing.T) {
+	admin := NewSubject("usr_admin", "wsp_home", true, nil)
+	member := NewSubject("usr_member", "wsp_home", false, []string{"acc_cash", "acc_food"})
+	outsider := Subject{UserID: "usr_outsider"}
+	serverAdmin := Subject{UserID: "usr_root", ServerAdmin: true}
+	resolver := fakeResolver{
+		transactions:    map[string][]string{"txn_mine": {"acc_cash", "acc_food"}, "txn_shared": {"acc_cash", "acc_rent"}},
+		reconciliations: map[string][]string{"rec_mine": {"acc_cash"}, "rec_other": {"acc_rent"}},
+	}
+	lines := func(accounts ...string) []*expensesv1.LedgerLine {
+		result := make([]*expensesv1.LedgerLine, len(accounts))
+		for i, account := range accounts {
+			result[i] = &expensesv1.LedgerLine{AccountId: account}
+		}
+		return result
+	}
+
+	// Define test cases
+	tests := []struct {
+		name         string
+		procedure    string
+		msg          proto.Message
+		subject      Subject
+		expectDenied bool
+	}{
+		{
+			name:      "Admin manages master data",
+			procedure: expensesv1connect.InstrumentServiceCreateInstrumentProcedure,
+			msg:       &expensesv1.CreateInstrumentRequest{Name: "Cash"},
+			subject:   admin,
+		},
+		{
+			name:         "Member cannot manage master data",
+			procedure:    expensesv1connect.CurrencyServiceDeleteCurrencyProcedure,
+			msg:          &expensesv1.DeleteCurrencyRequest{Id: "cur_jpy"},
+			subject:      member,
+			expectDenied: true,
+		},
+		{
+			name:      "Member reads master data",
+			procedure: expensesv1connect.CurrencyServiceListCurrenciesProcedure,
+			msg:       &expensesv1.ListCurrenciesRequest{},
+			subject:   member,
+		},
+		{
+			name:         "Audit log is for admins",
+			procedure:    expensesv1connect.AuditServiceListAuditEventsProcedure,
+			msg:          &expensesv1.ListAuditEventsRequest{},
+			subject:      member,
+			expectDenied: true,
+		},
+		{
+			name:      "Member reads own account",
+			procedure: expensesv1connect.AccountServiceGetAccountProcedure,
+			msg:       &expensesv1.GetAccountRequest{Id: "acc_cash"},
+			subject:   member,
+		},
+		{
+			name:         "Member cannot read other account",
+			procedure:    expensesv1connect.AccountServiceGetAccountProcedure,
+			msg:          &expensesv1.GetAccountRequest{Id: "acc_rent"},
+			subject:      member,
+			expectDenied: true,
+		},
+		{
+			name:         "Admin cannot read other account",
+			procedure:    expensesv1connect.AccountServiceListAccountUsersProcedure,
+			msg:          &expensesv1.ListAccountUsersRequest{AccountId: "acc_rent"},
+			subject:      admin,
+			expectDenied: true,
+		},
+		{
+			name:      "Member posts on own accounts",
+			procedure: expensesv1connect.TransactionServiceCreateTransactionProcedure,
+			msg:       &expensesv1.CreateTransactionRequest{Lines: lines("acc_cash", "acc_food")},
+			subject:   member,
+		},
+		{
+			name:         "Member cannot post on other account",
+			procedure:    expensesv1connect.TransactionServiceCreateTransactionProcedure,
+			msg:          &expensesv1.CreateTransactionRequest{Lines: lines("acc_cash", "acc_rent")},
+			subject:      member,
+			expectDenied: true,
+		},
+		{
+			name:      "Member reads own transaction",
+			procedure: expensesv1connect.TransactionServiceGetTransactionProcedure,
+			msg:       &expensesv1.GetTransactionRequest{Id: "txn_mine"},
+			subject:   member,
+		},
+		{
+			name:         "Member cannot delete transaction crossing other account",
+			procedure:    expensesv1connect.TransactionServiceDeleteTransactionProcedure,
+			msg:          &expensesv1.DeleteTransactionRequest{Id: "txn_shared"},
+			subject:      member,
+			expectDenied: true,
+		},
+		{
+			name:         "Member cannot move own transaction to other account",
+			procedure:    expensesv1connect.TransactionServiceUpdateTransactionProcedure,
+			msg:          &expensesv1.UpdateTransactionRequest{Id: "txn_mine", Lines: lines("acc_cash", "acc_rent")},
+			subject:      member,
+			expectDenied: true,
+		},
+		{
+			name:      "Missing transaction is left to the service",
+			procedure: expensesv1connect.TransactionServiceGetTransactionProcedure,
+			msg:       &expensesv1.GetTransactionRequest{Id: "txn_missing"},
+			subject:   member,
+		},
+		{
+			name:         "Member cannot read other reconciliation",
+			procedure:    expensesv1connect.ReconciliationServiceGetReconciliationProcedure,
+			msg:          &expensesv1.GetReconciliationRequest{Id: "rec_other"},
+			subject:      member,
+			expectDenied: true,
+		},
+		{
+			name:      "Unset optional account is ignored",
+			procedure: expensesv1connect.ReconciliationServiceListReconciliationsProcedure,
+			msg:       &expensesv1.ListReconciliationsRequest{},
+			subject:   member,
+		},
+		{
+			name:         "Outsider cannot read master data",
+			procedure:    expensesv1connect.CurrencyServiceListCurrenciesProcedure,
+			msg:          &expensesv1.ListCurrenciesRequest{},
+			subject:      outsider,
+			expectDenied: true,
+		},
+		{
+			name:      "Outsider creates a workspace",
+			procedure: expensesv1connect.WorkspaceServiceCreateWorkspaceProcedure,
+			msg:       &expensesv1.CreateWorkspaceRequest{Name: "Home"},
+			subject:   outsider,
+		},
+		{
+			name:      "Admin invites users",
+			procedure: expensesv1connect.WorkspaceServiceInviteUserProcedure,
+			msg:       &expensesv1.InviteUserRequest{Email: "ann@example.com"},
+			subject:   admin,
+		},
+		{
+			name:         "Member cannot invite users",
+			procedure:    expensesv1connect.WorkspaceServiceInviteUserProcedure,
+			msg:          &expensesv1.InviteUserRequest{Email: "ann@example.com"},
+			subject:      member,
+			expectDenied: true,
+		},
+		{
+			name:      "Server admin manages users",
+			procedure: expensesv1connect.UserServiceDeleteUserProcedure,
+			msg:       &expensesv1.DeleteUserRequest{Id: "usr_member"},
+			subject:   serverAdmin,
+		},
+		{
+			name:         "Workspace admin cannot manage users",
+			procedure:    expensesv1connect.UserServiceDeleteUserProcedure,
+			msg:          &expensesv1.DeleteUserRequest{Id: "usr_member"},
+			subject:      admin,
+			expectDenied: true,
+		},
+		{
+			name:         "Unknown procedure",
+			procedure:    "/expenses.v1.ExpenseService/Unknown",
+			msg:          &expensesv1.GetAccountRequest{},
+			subject:      admin,
+			expectDenied: true,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Authorize(context.Background(), tc.procedure, tc.msg, tc.subject, resolver)
+			denied := stderrors.Is(err, errors.ErrPermissionDenied)
+			if err != nil && !denied {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if denied != tc.expectDenied {
+				t.Errorf("Expected denied=%v, got %v", tc.expectDenied, err)
+			}
+		})
+	}
+}
+
+// TestPolicyCoversEveryProcedure tests that every procedure of the API has a
+// rule, and that Member rules can resolve accounts
+func TestPolicyCoversEveryProcedure(t *testing.T) {
+	protoregistry.GlobalFiles.RangeFilesByPackage("expenses.v1", func(file protoreflect.FileDescriptor) bool {
+		for i := 0; i < file.Services().Len(); i++ {
+			service := file.Services().Get(i)
+			for j := 0; j < service.Methods().Len(); j++ {
+				procedure := "/" + string(service.FullName()) + "/" + string(service.Methods().Get(j).Name())
+				rule, ok := Policy[procedure]
+				if !ok {
+					t.Errorf("%s has no policy", procedure)
+					continue
+				}
+				if rule.Access == Member && rule.Accounts == nil {
+					t.Errorf("%s is a Member procedure without Accounts", procedure)
+				}
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,200 @@
+// Package changes fans the change log out to the streams watching it.
+//
+// Every mutation of a watched resource records a change in the same database
+// transaction, numbered by a seq that grows with each commit. SQLite commits
+// one writer at a time, and on PostgreSQL recording a change takes a
+// transaction-level advisory lock before its seq, so a transaction can never
+// commit a lower seq than one already read from the log. A Feed polls the
+// log for the changes committed since its last poll and hands each of them to
+// every subscriber through a bounded buffer. A subscriber too slow to keep its
+// buffer from filling up is dropped rather than holding up the others or
+// buffering without bound: its channel is closed, and it catches up from the
+// log itself before subscribing again.
+package changes
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+)
+
+const (
+	// PageSize is the number of changes read from the log at a time
+	PageSize = 100
+
+	// pruneInterval is how often the log is pruned of the changes older than
+	// the retention
+	pruneInterval = time.Hour
+)
+
+// ErrInvalidToken is returned for a resume token Token did not create
+var ErrInvalidToken = errors.New("invalid resume token")
+
+// Token encodes the seq of the last change a client has seen as an opaque
+// resume token
+func Token(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
+}
+
+// ParseToken decodes a resume token created by Token into its seq
+func ParseToken(token string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	seq, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || seq < 0 {
+		return 0, ErrInvalidToken
+	}
+	return seq, nil
+}
+
+// Subscription receives the changes a Feed publishes after it was subscribed.
+// C is closed when the subscription is dropped for falling behind or
+// unsubscribed.
+type Subscription struct {
+	C <-chan db.Change
+	c chan db.Change
+}
+
+// Feed polls the change log and publishes the new changes to its subscribers
+type Feed struct {
+	repo      *repo.ChangeRepo
+	clock     clock.Clock
+	interval  time.Duration
+	retention time.Duration
+	buffer    int
+	logger    *slog.Logger
+
+	mu          sync.Mutex
+	last        int64 // seq of the last change published
+	subscribers map[*Subscription]struct{}
+}
+
+// NewFeed creates a Feed publishing the changes recorded from now on, polling
+// for them every interval and buffering up to buffer changes per subscriber.
+// Run prunes the changes older than retention from the log.
+func NewFeed(ctx context.Context, repo *repo.ChangeRepo, clock clock.Clock, interval, retention time.Duration, buffer int, logger *slog.Logger) (*Feed, error) {
+	_, last, err := repo.GetChangeBounds(ctx, repo.GetDB())
+	if err != nil {
+		return nil, err
+	}
+	return &Feed{
+		repo:        repo,
+		clock:       clock,
+		interval:    interval,
+		retention:   retention,
+		buffer:      buffer,
+		logger:      logger,
+		last:        last,
+		subscribers: make(map[*Subscription]struct{}),
+	}, nil
+}
+
+// Run polls the change log and prunes it until ctx is done
+func (f *Feed) Run(ctx context.Context) {
+	f.prune(ctx)
+	pruned := f.clock.Now()
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := f.Poll(ctx); err != nil {
+			f.logger.Error("Failed to poll changes", "error", err)
+		}
+		if now := f.clock.Now(); now.Sub(pruned) >= pruneInterval {
+			f.prune(ctx)
+			pruned = now
+		}
+	}
+}
+
+// Poll publishes the changes recorded since the last poll
+func (f *Feed) Poll(ctx context.Context) error {
+	for {
+		f.mu.Lock()
+		last := f.last
+		f.mu.Unlock()
+
+		changes, err := f.repo.ListChangesAfter(ctx, f.repo.GetDB(), last, PageSize)
+		if err != nil {
+			return err
+		}
+		f.publish(changes)
+		if len(changes) < PageSize {
+			return nil
+		}
+	}
+}
+
+// publish hands changes to every subscriber, dropping those whose buffer is
+// full
+func (f *Feed) publish(changes []db.Change) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, change := range changes {
+		if change.Seq <= f.last {
+			continue
+		}
+		for sub := range f.subscribers {
+			select {
+			case sub.c <- change:
+			default:
+				f.logger.Warn("Dropping change subscriber that fell behind", "buffer", f.buffer, "seq", change.Seq)
+				delete(f.subscribers, sub)
+				close(sub.c)
+			}
+		}
+		f.last = change.Seq
+	}
+}
+
+// prune deletes the changes older than the retention from the log
+func (f *Feed) prune(ctx context.Context) {
+	before := f.clock.Now().UTC().Add(-f.retention)
+	pruned, err := f.repo.PruneChanges(ctx, f.repo.GetDB(), before)
+	if err != nil {
+		f.logger.Error("Failed to prune changes", "error", err)
+		return
+	}
+	if pruned > 0 {
+		f.logger.Info("Pruned changes", "rows", pruned, "before", before)
+	}
+}
+
+// Subscribe subscribes to the changes published from now on. Subscribers
+// catch up on the changes recorded before from the log, skipping those they
+// have already seen when the subscription delivers them again.
+func (f *Feed) Subscribe() *Subscription {
+	c := make(chan db.Change, f.buffer)
+	sub := &Subscription{C: c, c: c}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe stops delivering changes to a subscription and closes it, unless
+// it has already been dropped
+func (f *Feed) Unsubscribe(sub *Subscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subscribers[sub]; ok {
+		delete(f.subscribers, sub)
+		close(sub.c)
+	}
+}
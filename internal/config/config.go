@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
@@ -10,8 +11,13 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Pagination  PaginationConfig
+	Idempotency IdempotencyConfig
+	Trash       TrashConfig
+	Auth        AuthConfig
+	Changes     ChangesConfig
 }
 
 // ServerConfig holds server-specific configuration
@@ -19,9 +25,54 @@ type ServerConfig struct {
 	Port string `env:"SERVER_PORT" envDefault:"8080"`
 }
 
-// DatabaseConfig holds database-specific configuration
+// DatabaseConfig holds database-specific configuration. The driver is sqlite3
+// or postgres; SQLite opens DSN as a file path, falling back to Path, and
+// PostgreSQL takes a URL or key=value connection string.
 type DatabaseConfig struct {
-	Path string `env:"DATABASE_PATH" envDefault:"db/expenses.db"`
+	Driver string `env:"DATABASE_DRIVER" envDefault:"sqlite3"`
+	DSN    string `env:"DATABASE_DSN"`
+	Path   string `env:"DATABASE_PATH" envDefault:"db/expenses.db"`
+}
+
+// Source returns the data source name the driver opens
+func (c DatabaseConfig) Source() string {
+	if c.DSN == "" {
+		return c.Path
+	}
+	return c.DSN
+}
+
+// PaginationConfig holds page token configuration. Without a secret a random
+// one is generated at startup, so page tokens do not survive a restart.
+type PaginationConfig struct {
+	TokenSecret string        `env:"PAGE_TOKEN_SECRET"`
+	TokenTTL    time.Duration `env:"PAGE_TOKEN_TTL" envDefault:"24h"`
+}
+
+// IdempotencyConfig holds idempotency key configuration
+type IdempotencyConfig struct {
+	KeyTTL time.Duration `env:"IDEMPOTENCY_KEY_TTL" envDefault:"24h"`
+}
+
+// TrashConfig holds soft delete configuration
+type TrashConfig struct {
+	Retention time.Duration `env:"TRASH_RETENTION" envDefault:"720h"`
+}
+
+// AuthConfig holds authentication configuration. Session cookies are only
+// sent over HTTPS unless secure cookies are turned off for local development.
+type AuthConfig struct {
+	SessionTTL    time.Duration `env:"AUTH_SESSION_TTL" envDefault:"168h"`
+	SecureCookies bool          `env:"AUTH_SECURE_COOKIES" envDefault:"true"`
+}
+
+// ChangesConfig holds change feed configuration. Changes are kept in the log
+// for the retention, which bounds how long a resume token stays usable, and
+// each stream buffers up to Buffer changes before it is dropped as too slow.
+type ChangesConfig struct {
+	Retention    time.Duration `env:"CHANGES_RETENTION" envDefault:"168h"`
+	PollInterval time.Duration `env:"CHANGES_POLL_INTERVAL" envDefault:"1s"`
+	Buffer       int           `env:"CHANGES_BUFFER" envDefault:"256"`
 }
 
 // Load loads configuration from environment variables and .env file
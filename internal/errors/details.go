@@ -0,0 +1,171 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+)
+
+// Domain is the ErrorInfo domain of the reasons below
+const Domain = "expense-manager"
+
+// Reasons are the stable ErrorInfo reasons of API errors, and of the field
+// violations of InvalidArgument errors, for clients to localize
+const (
+	ReasonRequiredField       = "REQUIRED_FIELD"
+	ReasonInvalidField        = "INVALID_FIELD"
+	ReasonReferenceNotFound   = "REFERENCE_NOT_FOUND"
+	ReasonNotFound            = "RESOURCE_NOT_FOUND"
+	ReasonAlreadyExists       = "RESOURCE_ALREADY_EXISTS"
+	ReasonInUse               = "RESOURCE_IN_USE"
+	ReasonLastAdmin           = "LAST_WORKSPACE_ADMIN"
+	ReasonSessionRequired     = "SESSION_REQUIRED"
+	ReasonAPITokenRequired    = "API_TOKEN_REQUIRED"
+	ReasonForeignKey          = "FOREIGN_KEY_VIOLATION"
+	ReasonCheckViolation      = "CHECK_VIOLATION"
+	ReasonNotNull             = "NOT_NULL_VIOLATION"
+	ReasonOutOfRange          = "AMOUNT_OUT_OF_RANGE"
+	ReasonEtagMismatch        = "ETAG_MISMATCH"
+	ReasonRequestInProgress   = "REQUEST_IN_PROGRESS"
+	ReasonIdempotencyKeyReuse = "IDEMPOTENCY_KEY_REUSED"
+	ReasonBatchFailed         = "BATCH_FAILED"
+	ReasonResumeTokenExpired  = "RESUME_TOKEN_EXPIRED"
+	ReasonUnauthenticated     = "UNAUTHENTICATED"
+	ReasonPermissionDenied    = "PERMISSION_DENIED"
+	ReasonUnavailable         = "DATABASE_UNAVAILABLE"
+	ReasonInternal            = "INTERNAL"
+)
+
+// Error creates a connect error with an ErrorInfo of reason followed by the
+// given details, such as a BadRequest or a ResourceInfo
+func Error(code connect.Code, reason string, err error, details ...proto.Message) *connect.Error {
+	connectErr := connect.NewError(code, err)
+	for _, msg := range append([]proto.Message{&errdetails.ErrorInfo{Reason: reason, Domain: Domain}}, details...) {
+		// Details are well-known messages, which always marshal
+		if detail, err := connect.NewErrorDetail(msg); err == nil {
+			connectErr.AddDetail(detail)
+		}
+	}
+	return connectErr
+}
+
+// BadRequest creates a BadRequest detail of field violations
+func BadRequest(violations ...*errdetails.BadRequest_FieldViolation) *errdetails.BadRequest {
+	return &errdetails.BadRequest{FieldViolations: violations}
+}
+
+// FieldViolation creates a violation of a request field, named by its path
+// such as lines[1].account_id
+func FieldViolation(field, reason, description string) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{Field: field, Reason: reason, Description: description}
+}
+
+// Resource creates a ResourceInfo detail of a resource of a type such as
+// account, named by its ID
+func Resource(resourceType, name string) *errdetails.ResourceInfo {
+	return &errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: name}
+}
+
+// Required creates the InvalidArgument error of a request missing required
+// fields, with a violation per field
+func Required(fields ...string) *connect.Error {
+	verb := "is"
+	if len(fields) > 1 {
+		verb = "are"
+	}
+	list := strings.Join(fields, ", ")
+	if n := len(fields); n > 1 {
+		list = strings.Join(fields[:n-1], ", ") + " and " + fields[n-1]
+	}
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(fields))
+	for i, field := range fields {
+		violations[i] = FieldViolation(field, ReasonRequiredField, field+" is required")
+	}
+	return Error(connect.CodeInvalidArgument, ReasonRequiredField, fmt.Errorf("%w: %s %s required", ErrInvalidInput, list, verb), BadRequest(violations...))
+}
+
+// InvalidField creates the InvalidArgument error of a field with an invalid
+// value, described by format and args
+func InvalidField(field, format string, args ...any) *connect.Error {
+	description := fmt.Sprintf(format, args...)
+	return Error(connect.CodeInvalidArgument, ReasonInvalidField, fmt.Errorf("%w: %s", ErrInvalidInput, description),
+		BadRequest(FieldViolation(field, ReasonInvalidField, description)))
+}
+
+// NotFound creates the NotFound error of a resource missing by ID
+func NotFound(resourceType, id string) *connect.Error {
+	return Error(connect.CodeNotFound, ReasonNotFound, fmt.Errorf("%w: %s with id %s not found", ErrNotFound, strings.ReplaceAll(resourceType, "_", " "), id), Resource(resourceType, id))
+}
+
+// AlreadyExists creates the AlreadyExists error of a resource whose field
+// duplicates that of another, described by format and args
+func AlreadyExists(resourceType, name, field, format string, args ...any) *connect.Error {
+	description := fmt.Sprintf(format, args...)
+	return Error(connect.CodeAlreadyExists, ReasonAlreadyExists, fmt.Errorf("%w: %s", ErrDuplicate, description),
+		Resource(resourceType, name), BadRequest(FieldViolation(field, ReasonAlreadyExists, description)))
+}
+
+// InUse creates the FailedPrecondition error of a resource that cannot be
+// changed or deleted while other records use it, described by format and args
+func InUse(resourceType, id, format string, args ...any) *connect.Error {
+	return Error(connect.CodeFailedPrecondition, ReasonInUse, fmt.Errorf("%w: %s", ErrInvalidInput, fmt.Sprintf(format, args...)), Resource(resourceType, id))
+}
+
+// Unauthenticated creates the Unauthenticated error of a request without
+// valid credentials
+func Unauthenticated(err error) *connect.Error {
+	return Error(connect.CodeUnauthenticated, ReasonUnauthenticated, err)
+}
+
+// PermissionDenied creates the PermissionDenied error of a request its user
+// may not make
+func PermissionDenied(err error) *connect.Error {
+	return Error(connect.CodePermissionDenied, ReasonPermissionDenied, err)
+}
+
+// Internal creates the Internal error of an unexpected failure
+func Internal(err error) *connect.Error {
+	return Error(connect.CodeInternal, ReasonInternal, fmt.Errorf("%w: %v", ErrInternal, err))
+}
+
+// Database creates the connect error of a failed database operation:
+// AlreadyExists for a duplicate, FailedPrecondition for a write breaking a
+// foreign key, check or not null constraint, Unavailable for a busy database,
+// each naming the offending fields, Aborted for a write that lost a race with
+// a concurrent one, and Internal for anything else
+func Database(err error) *connect.Error {
+	if errors.Is(err, ErrConflict) {
+		return Error(connect.CodeAborted, ReasonEtagMismatch, err)
+	}
+	var dbErr *DatabaseError
+	if !errors.As(err, &dbErr) {
+		return Internal(err)
+	}
+
+	code, reason := connect.CodeFailedPrecondition, ""
+	switch {
+	case errors.Is(dbErr.Kind, ErrDuplicate):
+		code, reason = connect.CodeAlreadyExists, ReasonAlreadyExists
+	case errors.Is(dbErr.Kind, ErrForeignKey):
+		reason = ReasonForeignKey
+	case errors.Is(dbErr.Kind, ErrCheckViolation):
+		reason = ReasonCheckViolation
+	case errors.Is(dbErr.Kind, ErrNotNull):
+		reason = ReasonNotNull
+	default:
+		return Error(connect.CodeUnavailable, ReasonUnavailable, dbErr)
+	}
+	var details []proto.Message
+	if len(dbErr.Columns) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(dbErr.Columns))
+		for i, column := range dbErr.Columns {
+			violations[i] = FieldViolation(column, reason, dbErr.Error())
+		}
+		details = append(details, BadRequest(violations...))
+	}
+	return Error(code, reason, dbErr, details...)
+}
@@ -1,7 +1,10 @@
 // Package errors provides common error definitions
 package errors
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 // Common error definitions shared across packages
 var (
@@ -16,4 +19,64 @@ var (
 
 	// ErrInternal is returned when an internal error occurs
 	ErrInternal = errors.New("internal error")
+
+	// ErrConflict is returned when a write is based on a stale version of a
+	// resource
+	ErrConflict = errors.New("conflict")
+
+	// ErrUnauthenticated is returned when a request carries no valid
+	// credentials
+	ErrUnauthenticated = errors.New("unauthenticated")
+
+	// ErrPermissionDenied is returned when an authenticated user may not make
+	// a request
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrForeignKey is returned when a write references a row that does not
+	// exist, or removes one that is still referenced
+	ErrForeignKey = errors.New("foreign key violation")
+
+	// ErrCheckViolation is returned when a write fails a CHECK constraint
+	ErrCheckViolation = errors.New("check constraint violation")
+
+	// ErrNotNull is returned when a write leaves a required column empty
+	ErrNotNull = errors.New("not null violation")
+
+	// ErrUnavailable is returned when the database is busy or locked and the
+	// request may succeed if retried
+	ErrUnavailable = errors.New("database unavailable")
 )
+
+// DatabaseError is a database error classified as one of ErrDuplicate,
+// ErrForeignKey, ErrCheckViolation, ErrNotNull and ErrUnavailable, with the
+// table, columns or constraint the database named
+type DatabaseError struct {
+	Kind       error    // Sentinel error the database error is classified as
+	Table      string   // Table of the offending columns, if known
+	Columns    []string // Offending columns, if known
+	Constraint string   // Offending constraint, if known and not a column list
+	Err        error    // Driver error
+}
+
+// Field returns the offending columns separated by commas, or the constraint
+// if the database did not name any column
+func (e *DatabaseError) Field() string {
+	if len(e.Columns) > 0 {
+		return strings.Join(e.Columns, ", ")
+	}
+	return e.Constraint
+}
+
+// Error implements the error interface
+func (e *DatabaseError) Error() string {
+	if field := e.Field(); field != "" {
+		return e.Kind.Error() + ": " + field
+	}
+	return e.Kind.Error()
+}
+
+// Unwrap returns the sentinel and the driver error, so errors.Is matches
+// both
+func (e *DatabaseError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
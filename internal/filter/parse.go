@@ -0,0 +1,371 @@
+// Package filter parses AIP-160 filter expressions and AIP-132 order_by
+// clauses of List RPCs and compiles them to parameterized SQLite or PostgreSQL.
+// Only fields declared in a resource's Schema may be referenced, and every
+// value is bound as a query argument, so requests never contribute raw SQL.
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxLength is the longest filter or order_by accepted, in bytes
+const MaxLength = 2048
+
+// maxDepth bounds the nesting of parentheses and negations
+const maxDepth = 32
+
+// Error reports an invalid filter or order_by at a 1-based byte position
+type Error struct {
+	Pos int
+	Msg string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// errorf creates an Error at pos
+func errorf(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Expr is a node of a parsed filter expression
+type Expr interface {
+	// Position returns the 1-based byte position where the node starts
+	Position() int
+}
+
+// And matches rows matching both operands
+type And struct {
+	Left, Right Expr
+}
+
+// Or matches rows matching either operand
+type Or struct {
+	Left, Right Expr
+}
+
+// Not matches rows not matching its operand
+type Not struct {
+	Pos  int
+	Expr Expr
+}
+
+// Restriction compares a field with a value, e.g. name:"Cash*"
+type Restriction struct {
+	Pos      int    // position of the field name
+	Field    string // field name as written
+	OpPos    int    // position of the comparator
+	Op       string // one of = != < <= > >= :
+	ValuePos int    // position of the value
+	Value    string // unquoted value
+}
+
+// Position implements Expr
+func (e *And) Position() int { return e.Left.Position() }
+
+// Position implements Expr
+func (e *Or) Position() int { return e.Left.Position() }
+
+// Position implements Expr
+func (e *Not) Position() int { return e.Pos }
+
+// Position implements Expr
+func (e *Restriction) Position() int { return e.Pos }
+
+// tokenKind classifies the tokens of a filter
+type tokenKind int
+
+const (
+	tokenEOF        tokenKind = iota
+	tokenText                 // field name, keyword or unquoted value
+	tokenString               // quoted value
+	tokenComparator           // = != < <= > >= :
+	tokenLParen
+	tokenRParen
+	tokenMinus // negation prefix, e.g. -name:"Cash*"
+)
+
+// token is a lexeme of a filter with its 1-based byte position
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// Parse parses an AIP-160 filter expression. An empty filter returns a nil
+// Expr. Juxtaposed terms are combined with AND, and OR binds tighter than AND,
+// so `a AND b OR c` means `a AND (b OR c)`.
+func Parse(filter string) (Expr, error) {
+	if len(filter) > MaxLength {
+		return nil, errorf(MaxLength+1, "filter is longer than %d bytes", MaxLength)
+	}
+	tokens, err := lex(filter)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, nil
+	}
+	expr, err := p.expression(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errorf(t.pos, "unexpected %s", describe(t))
+	}
+	return expr, nil
+}
+
+// lex splits a filter into tokens
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i + 1})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i + 1})
+			i++
+		case c == '"' || c == '\'':
+			value, end, err := lexString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value, pos: i + 1})
+			i = end
+		case c == '=' || c == ':':
+			tokens = append(tokens, token{kind: tokenComparator, text: string(c), pos: i + 1})
+			i++
+		case c == '!':
+			if i+1 >= len(input) || input[i+1] != '=' {
+				return nil, errorf(i+1, "unexpected character '!'")
+			}
+			tokens = append(tokens, token{kind: tokenComparator, text: "!=", pos: i + 1})
+			i += 2
+		case c == '<' || c == '>':
+			op := string(c)
+			if i+1 < len(input) && input[i+1] == '=' {
+				op += "="
+			}
+			tokens = append(tokens, token{kind: tokenComparator, text: op, pos: i + 1})
+			i += len(op)
+		case c == '-' && i+1 < len(input) && (isLetter(input[i+1]) || input[i+1] == '('):
+			tokens = append(tokens, token{kind: tokenMinus, text: "-", pos: i + 1})
+			i++
+		default:
+			end := i
+			for end < len(input) && isTextChar(input[end]) {
+				end++
+			}
+			if end == i {
+				return nil, errorf(i+1, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind: tokenText, text: input[i:end], pos: i + 1})
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(input) + 1}), nil
+}
+
+// lexString reads the quoted string starting at input[start], returning its
+// unescaped value and the index after the closing quote
+func lexString(input string, start int) (string, int, error) {
+	quote := input[start]
+	var b strings.Builder
+	for i := start + 1; i < len(input); i++ {
+		switch input[i] {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(input) {
+				return "", 0, errorf(i+1, "unterminated escape sequence")
+			}
+			i++
+			b.WriteByte(input[i])
+		default:
+			b.WriteByte(input[i])
+		}
+	}
+	return "", 0, errorf(start+1, "unterminated string")
+}
+
+// isLetter reports whether c is an ASCII letter
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isTextChar reports whether c may appear in an unquoted token
+func isTextChar(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '(', ')', '"', '\'', '=', '!', '<', '>', ':':
+		return false
+	}
+	return true
+}
+
+// describe names a token for error messages
+func describe(t token) string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// parser is a recursive descent parser over the AIP-160 grammar:
+//
+//	expression  = sequence { "AND" sequence }
+//	sequence    = factor { factor }
+//	factor      = term { "OR" term }
+//	term        = [ "NOT" | "-" ] simple
+//	simple      = restriction | "(" expression ")"
+//	restriction = field comparator value
+type parser struct {
+	tokens []token
+	next   int
+}
+
+// peek returns the next token without consuming it
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+// advance consumes and returns the next token
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenText && t.text == word
+}
+
+// startsTerm reports whether the next token can begin a term
+func (p *parser) startsTerm() bool {
+	t := p.peek()
+	switch t.kind {
+	case tokenText:
+		return t.text != "AND" && t.text != "OR"
+	case tokenMinus, tokenLParen:
+		return true
+	}
+	return false
+}
+
+func (p *parser) expression(depth int) (Expr, error) {
+	if depth > maxDepth {
+		return nil, errorf(p.peek().pos, "filter is nested more than %d levels deep", maxDepth)
+	}
+	left, err := p.sequence(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		p.advance()
+		right, err := p.sequence(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) sequence(depth int) (Expr, error) {
+	left, err := p.factor(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.startsTerm() {
+		right, err := p.factor(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) factor(depth int) (Expr, error) {
+	left, err := p.term(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		p.advance()
+		right, err := p.term(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) term(depth int) (Expr, error) {
+	if t := p.peek(); t.kind == tokenMinus || p.keyword("NOT") {
+		p.advance()
+		expr, err := p.simple(depth)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Pos: t.pos, Expr: expr}, nil
+	}
+	return p.simple(depth)
+}
+
+func (p *parser) simple(depth int) (Expr, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokenLParen:
+		expr, err := p.expression(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenRParen {
+			return nil, errorf(closing.pos, "expected ')' but found %s", describe(closing))
+		}
+		return expr, nil
+	case tokenText:
+		if t.text == "AND" || t.text == "OR" || t.text == "NOT" {
+			return nil, errorf(t.pos, "expected a field name but found %s", describe(t))
+		}
+		return p.restriction(t)
+	}
+	return nil, errorf(t.pos, "expected a field name but found %s", describe(t))
+}
+
+func (p *parser) restriction(field token) (Expr, error) {
+	op := p.advance()
+	if op.kind != tokenComparator {
+		return nil, errorf(op.pos, "expected a comparison operator after %q but found %s", field.text, describe(op))
+	}
+	value := p.advance()
+	if value.kind != tokenText && value.kind != tokenString {
+		return nil, errorf(value.pos, "expected a value after %q but found %s", op.text, describe(value))
+	}
+	return &Restriction{
+		Pos:      field.pos,
+		Field:    field.text,
+		OpPos:    op.pos,
+		Op:       op.text,
+		ValuePos: value.pos,
+		Value:    value.text,
+	}, nil
+}
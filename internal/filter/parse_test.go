@@ -0,0 +1,246 @@
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestParse tests parsing filters into expressions, with the precedence of
+// their operators and the positions of their errors
+func TestParse(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name        string
+		filter      string
+		expectExpr  string
+		expectPos   int
+		expectError string
+	}{
+		{
+			name:   "Empty filter",
+			filter: "  ",
+		},
+		{
+			name:       "Restriction",
+			filter:     `name = "Cash"`,
+			expectExpr: `name="Cash"`,
+		},
+		{
+			name:       "Juxtaposed terms are combined with AND",
+			filter:     `name:Cash amount>5`,
+			expectExpr: `(name:"Cash" AND amount>"5")`,
+		},
+		{
+			name:       "AND is left-associative",
+			filter:     `a=1 AND b=2 AND c=3`,
+			expectExpr: `((a="1" AND b="2") AND c="3")`,
+		},
+		{
+			name:       "OR binds tighter than AND",
+			filter:     `a=1 AND b=2 OR c=3`,
+			expectExpr: `(a="1" AND (b="2" OR c="3"))`,
+		},
+		{
+			name:       "OR binds tighter than juxtaposition",
+			filter:     `a=1 b=2 OR c=3`,
+			expectExpr: `(a="1" AND (b="2" OR c="3"))`,
+		},
+		{
+			name:       "Parentheses group terms",
+			filter:     `(a=1 AND b=2) OR c=3`,
+			expectExpr: `((a="1" AND b="2") OR c="3")`,
+		},
+		{
+			name:       "NOT negates a restriction",
+			filter:     `NOT a=1 AND b=2`,
+			expectExpr: `(NOT a="1" AND b="2")`,
+		},
+		{
+			name:       "Minus negates a group",
+			filter:     `-(a=1 OR b=2)`,
+			expectExpr: `NOT (a="1" OR b="2")`,
+		},
+		{
+			name:       "Minus before a digit is part of the value",
+			filter:     `amount<-5`,
+			expectExpr: `amount<"-5"`,
+		},
+		{
+			name:       "Two-character comparators",
+			filter:     `a!=1 b<=2 c>=3`,
+			expectExpr: `((a!="1" AND b<="2") AND c>="3")`,
+		},
+		{
+			name:       "Escaped double quotes",
+			filter:     `name="say \"hi\""`,
+			expectExpr: `name="say \"hi\""`,
+		},
+		{
+			name:       "Single-quoted string with escapes",
+			filter:     `name='it\'s a \\ path'`,
+			expectExpr: `name="it's a \\ path"`,
+		},
+		{
+			name:       "Quoted keywords are values",
+			filter:     `name="AND"`,
+			expectExpr: `name="AND"`,
+		},
+		{
+			name:        "Missing value",
+			filter:      `name=`,
+			expectPos:   6,
+			expectError: `expected a value after "=" but found end of filter`,
+		},
+		{
+			name:        "Missing comparator",
+			filter:      `name "Cash"`,
+			expectPos:   6,
+			expectError: `expected a comparison operator after "name" but found string "Cash"`,
+		},
+		{
+			name:        "Unclosed parenthesis",
+			filter:      `(a=1 OR b=2`,
+			expectPos:   12,
+			expectError: `expected ')' but found end of filter`,
+		},
+		{
+			name:        "Unopened parenthesis",
+			filter:      `a=1)`,
+			expectPos:   4,
+			expectError: `unexpected ")"`,
+		},
+		{
+			name:        "Keyword instead of a field",
+			filter:      `a=1 AND OR b=2`,
+			expectPos:   9,
+			expectError: `expected a field name but found "OR"`,
+		},
+		{
+			name:        "Leading keyword",
+			filter:      `AND a=1`,
+			expectPos:   1,
+			expectError: `expected a field name but found "AND"`,
+		},
+		{
+			name:        "Lone exclamation mark",
+			filter:      `a ! 1`,
+			expectPos:   3,
+			expectError: `unexpected character '!'`,
+		},
+		{
+			name:        "Unterminated string",
+			filter:      `name="Cash`,
+			expectPos:   6,
+			expectError: "unterminated string",
+		},
+		{
+			name:        "Unterminated escape sequence",
+			filter:      `name="Cash\`,
+			expectPos:   11,
+			expectError: "unterminated escape sequence",
+		},
+		{
+			name:        "Nested too deeply",
+			filter:      strings.Repeat("(", maxDepth+1) + "a=1" + strings.Repeat(")", maxDepth+1),
+			expectPos:   maxDepth + 2,
+			expectError: "filter is nested more than 32 levels deep",
+		},
+		{
+			name:        "Too long",
+			filter:      "a=" + strings.Repeat("x", MaxLength),
+			expectPos:   MaxLength + 1,
+			expectError: "filter is longer than 2048 bytes",
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := Parse(tc.filter)
+
+			if tc.expectError != "" {
+				var filterErr *Error
+				if !errors.As(err, &filterErr) {
+					t.Fatalf("Expected a filter error, got %v", err)
+				}
+				if filterErr.Pos != tc.expectPos || filterErr.Msg != tc.expectError {
+					t.Errorf("Expected %q at position %d, got %q at position %d", tc.expectError, tc.expectPos, filterErr.Msg, filterErr.Pos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := formatExpr(expr); got != tc.expectExpr {
+				t.Errorf("Expected expression %s, got %s", tc.expectExpr, got)
+			}
+		})
+	}
+}
+
+// TestParsePositions tests the positions parsed expressions record for
+// reporting errors while compiling them
+func TestParsePositions(t *testing.T) {
+	expr, err := Parse(`a=1 AND -b != "x"`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	and, ok := expr.(*And)
+	if !ok {
+		t.Fatalf("Expected an And, got %T", expr)
+	}
+	not, ok := and.Right.(*Not)
+	if !ok {
+		t.Fatalf("Expected a Not, got %T", and.Right)
+	}
+	restriction, ok := not.Expr.(*Restriction)
+	if !ok {
+		t.Fatalf("Expected a Restriction, got %T", not.Expr)
+	}
+
+	// Define test cases
+	tests := []struct {
+		name      string
+		pos       int
+		expectPos int
+	}{
+		{name: "And starts at its left operand", pos: and.Position(), expectPos: 1},
+		{name: "Not starts at its prefix", pos: not.Position(), expectPos: 9},
+		{name: "Field", pos: restriction.Pos, expectPos: 10},
+		{name: "Comparator", pos: restriction.OpPos, expectPos: 12},
+		{name: "Value", pos: restriction.ValuePos, expectPos: 15},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.pos != tc.expectPos {
+				t.Errorf("Expected position %d, got %d", tc.expectPos, tc.pos)
+			}
+		})
+	}
+}
+
+// formatExpr renders an expression with every operation parenthesized and
+// every value quoted
+func formatExpr(expr Expr) string {
+	switch e := expr.(type) {
+	case nil:
+		return ""
+	case *And:
+		return "(" + formatExpr(e.Left) + " AND " + formatExpr(e.Right) + ")"
+	case *Or:
+		return "(" + formatExpr(e.Left) + " OR " + formatExpr(e.Right) + ")"
+	case *Not:
+		return "NOT " + formatExpr(e.Expr)
+	case *Restriction:
+		return e.Field + e.Op + quoteValue(e.Value)
+	}
+	return "?"
+}
+
+// quoteValue quotes a value with its double quotes and backslashes escaped
+func quoteValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,445 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the type of a field, which decides how its values are parsed and
+// compared
+type Type int
+
+const (
+	// String fields compare text. = and != accept * wildcards, and : matches
+	// case-insensitively with * wildcards.
+	String Type = iota
+	// Integer fields compare whole numbers
+	Integer
+	// Timestamp fields compare RFC 3339 timestamps or YYYY-MM-DD dates (UTC)
+	// at millisecond precision
+	Timestamp
+)
+
+// String returns the name of the type for error messages
+func (t Type) String() string {
+	switch t {
+	case Integer:
+		return "integer"
+	case Timestamp:
+		return "timestamp"
+	}
+	return "string"
+}
+
+// Dialect is the SQL dialect a Query is rendered in
+type Dialect int
+
+const (
+	// SQLite renders queries for SQLite
+	SQLite Dialect = iota
+	// PostgreSQL renders queries for PostgreSQL
+	PostgreSQL
+)
+
+// timestampLayout is the layout timestamps are normalized to before they are
+// compared, matching SQLite's strftime('%Y-%m-%d %H:%M:%f') and PostgreSQL's
+// to_char(..., 'YYYY-MM-DD HH24:MI:SS.MS') in UTC
+const timestampLayout = "2006-01-02 15:04:05.000"
+
+// parse converts a filter or cursor value to a query argument
+func (t Type) parse(value string) (any, error) {
+	switch t {
+	case Integer:
+		return strconv.ParseInt(value, 10, 64)
+	case Timestamp:
+		if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return ts.UTC().Format(timestampLayout), nil
+		}
+		ts, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, err
+		}
+		return ts.Format(timestampLayout), nil
+	}
+	return value, nil
+}
+
+// Field declares a field of T that may be referenced in filters
+type Field[T any] struct {
+	Column string // SQL column, qualified when the list joins tables
+	Type   Type
+	// Key returns the value of the field for page cursors. Only fields with a
+	// Key may appear in order_by, so it must be nil for nullable columns.
+	Key func(T) any
+}
+
+// expr returns the SQL expression a field is compared and sorted by.
+// Timestamps are normalized because SQLite stores them as text in the layout
+// of whoever wrote them, and PostgreSQL's are rendered in the same layout.
+func (f Field[T]) expr(d Dialect) string {
+	if f.Type != Timestamp {
+		return f.Column
+	}
+	if d == PostgreSQL {
+		return "to_char(" + f.Column + " AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS.MS')"
+	}
+	return "strftime('%Y-%m-%d %H:%M:%f', " + f.Column + ")"
+}
+
+// Schema is the allowlist of fields a list of T may be filtered and ordered by
+type Schema[T any] struct {
+	Fields       map[string]Field[T]
+	IDColumn     string // unique column breaking ties between equal sort keys
+	DefaultOrder string // order_by applied when a request has none
+}
+
+// Clause is a parameterized SQL condition
+type Clause struct {
+	SQL  string
+	Args []any
+}
+
+// condition renders a restriction of a Query in a dialect
+type condition func(Dialect) Clause
+
+// fixed is a condition that reads the same in every dialect
+func fixed(clause Clause) condition {
+	return func(Dialect) Clause { return clause }
+}
+
+// orderTerm is a field of an order_by clause
+type orderTerm[T any] struct {
+	field Field[T]
+	desc  bool
+}
+
+// Query is a filter and ordering compiled against a Schema
+type Query[T any] struct {
+	schema *Schema[T]
+	where  []condition
+	order  []orderTerm[T]
+	after  condition
+}
+
+// Compile parses a filter and an order_by clause against the schema. An empty
+// orderBy applies the schema's default order.
+func (s *Schema[T]) Compile(filter, orderBy string) (*Query[T], error) {
+	q := &Query[T]{schema: s}
+	expr, err := Parse(filter)
+	if err != nil {
+		return nil, err
+	}
+	if expr != nil {
+		cond, err := s.compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		q.where = append(q.where, cond)
+	}
+	if strings.TrimSpace(orderBy) == "" {
+		orderBy = s.DefaultOrder
+	}
+	if q.order, err = s.parseOrderBy(orderBy); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// compile translates an expression to SQL
+func (s *Schema[T]) compile(expr Expr) (condition, error) {
+	switch e := expr.(type) {
+	case *And:
+		return s.compileBinary("AND", e.Left, e.Right)
+	case *Or:
+		return s.compileBinary("OR", e.Left, e.Right)
+	case *Not:
+		operand, err := s.compile(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return func(d Dialect) Clause {
+			clause := operand(d)
+			return Clause{SQL: "NOT " + clause.SQL, Args: clause.Args}
+		}, nil
+	case *Restriction:
+		return s.compileRestriction(e)
+	}
+	return nil, errorf(expr.Position(), "unsupported expression")
+}
+
+func (s *Schema[T]) compileBinary(op string, left, right Expr) (condition, error) {
+	l, err := s.compile(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.compile(right)
+	if err != nil {
+		return nil, err
+	}
+	return func(d Dialect) Clause {
+		lc, rc := l(d), r(d)
+		return Clause{
+			SQL:  "(" + lc.SQL + " " + op + " " + rc.SQL + ")",
+			Args: append(append([]any{}, lc.Args...), rc.Args...),
+		}
+	}, nil
+}
+
+func (s *Schema[T]) compileRestriction(r *Restriction) (condition, error) {
+	field, ok := s.Fields[r.Field]
+	if !ok {
+		return nil, errorf(r.Pos, "unknown field %q", r.Field)
+	}
+
+	if field.Type == String {
+		switch {
+		case r.Op == ":":
+			return func(d Dialect) Clause {
+				op := "LIKE"
+				if d == PostgreSQL {
+					op = "ILIKE"
+				}
+				return Clause{SQL: "(" + field.Column + " " + op + ` ? ESCAPE '\')`, Args: []any{likePattern(r.Value)}}
+			}, nil
+		case (r.Op == "=" || r.Op == "!=") && strings.Contains(r.Value, "*"):
+			not := ""
+			if r.Op == "!=" {
+				not = "NOT "
+			}
+			return func(d Dialect) Clause {
+				// PostgreSQL's LIKE is case-sensitive like SQLite's GLOB
+				if d == PostgreSQL {
+					return Clause{SQL: "(" + field.Column + " " + not + `LIKE ? ESCAPE '\')`, Args: []any{likePattern(r.Value)}}
+				}
+				return Clause{SQL: "(" + field.Column + " " + not + "GLOB ?)", Args: []any{globPattern(r.Value)}}
+			}, nil
+		}
+	} else if r.Op == ":" {
+		return nil, errorf(r.OpPos, "operator ':' only applies to string fields, not %s field %q", field.Type, r.Field)
+	}
+
+	value, err := field.Type.parse(r.Value)
+	if err != nil {
+		return nil, errorf(r.ValuePos, "invalid %s value %q for field %q", field.Type, r.Value, r.Field)
+	}
+	return compare(field, r.Op, value), nil
+}
+
+// compare is the condition that a field compares to value with op
+func compare[T any](field Field[T], op string, value any) condition {
+	return func(d Dialect) Clause {
+		return Clause{SQL: "(" + field.expr(d) + " " + op + " ?)", Args: []any{value}}
+	}
+}
+
+// likePattern converts a value with * wildcards to a LIKE pattern escaped with
+// a backslash
+func likePattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`)
+	return replacer.Replace(value)
+}
+
+// globPattern converts a value with * wildcards to a GLOB pattern, escaping
+// GLOB's other metacharacters
+func globPattern(value string) string {
+	replacer := strings.NewReplacer(`?`, `[?]`, `[`, `[[]`)
+	return replacer.Replace(value)
+}
+
+// parseOrderBy parses a comma-separated list of fields, each optionally
+// followed by asc or desc
+func (s *Schema[T]) parseOrderBy(orderBy string) ([]orderTerm[T], error) {
+	if len(orderBy) > MaxLength {
+		return nil, errorf(MaxLength+1, "order_by is longer than %d bytes", MaxLength)
+	}
+
+	var terms []orderTerm[T]
+	seen := make(map[string]bool)
+	offset := 0
+	for _, part := range strings.Split(orderBy, ",") {
+		words, positions := splitWords(part, offset)
+		offset += len(part) + 1
+		if len(words) == 0 {
+			return nil, errorf(offset, "expected a field name in order_by")
+		}
+
+		name := words[0]
+		field, ok := s.Fields[name]
+		if !ok {
+			return nil, errorf(positions[0], "unknown field %q", name)
+		}
+		if field.Key == nil {
+			return nil, errorf(positions[0], "field %q cannot be used in order_by", name)
+		}
+		if seen[name] {
+			return nil, errorf(positions[0], "field %q appears more than once in order_by", name)
+		}
+		seen[name] = true
+
+		term := orderTerm[T]{field: field}
+		if len(words) > 1 {
+			switch strings.ToLower(words[1]) {
+			case "asc":
+			case "desc":
+				term.desc = true
+			default:
+				return nil, errorf(positions[1], "expected asc or desc after %q but found %q", name, words[1])
+			}
+		}
+		if len(words) > 2 {
+			return nil, errorf(positions[2], "expected ',' but found %q", words[2])
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// splitWords splits s into whitespace-separated words and their 1-based
+// positions, with s starting at byte offset in the input
+func splitWords(s string, offset int) ([]string, []int) {
+	var words []string
+	var positions []int
+	start := -1
+	for i := 0; i <= len(s); i++ {
+		space := i == len(s) || s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r'
+		switch {
+		case !space && start < 0:
+			start = i
+		case space && start >= 0:
+			words = append(words, s[start:i])
+			positions = append(positions, offset+start+1)
+			start = -1
+		}
+	}
+	return words, positions
+}
+
+// Equal narrows the query to rows whose field equals value, for list
+// parameters passed outside the filter expression
+func (q *Query[T]) Equal(name string, value any) error {
+	return q.Compare(name, "=", value)
+}
+
+// Compare narrows the query to rows whose field compares to value with op, one
+// of =, !=, <, <=, > and >=, for list parameters passed outside the filter
+// expression. Values of timestamp fields may be given as time.Time.
+func (q *Query[T]) Compare(name, op string, value any) error {
+	field, ok := q.schema.Fields[name]
+	if !ok {
+		return fmt.Errorf("unknown field %q", name)
+	}
+	switch op {
+	case "=", "!=", "<", "<=", ">", ">=":
+	default:
+		return fmt.Errorf("unknown operator %q", op)
+	}
+	if ts, ok := value.(time.Time); ok && field.Type == Timestamp {
+		value = ts.UTC().Format(timestampLayout)
+	}
+	q.where = append(q.where, compare(field, op, value))
+	return nil
+}
+
+// Where narrows the query with a clause built by the caller, for restrictions
+// the filter language cannot express such as authorization. The SQL of the
+// clause must not come from the request.
+func (q *Query[T]) Where(clause Clause) {
+	q.where = append(q.where, fixed(Clause{SQL: "(" + clause.SQL + ")", Args: clause.Args}))
+}
+
+// After narrows the query to the rows following the row with the given sort
+// keys and ID, as returned by Keys, in the query's order
+func (q *Query[T]) After(keys []string, id string) error {
+	if len(keys) != len(q.order) {
+		return fmt.Errorf("cursor has %d sort keys, expected %d", len(keys), len(q.order))
+	}
+	values := make([]any, len(keys))
+	for i, term := range q.order {
+		value, err := term.field.Type.parse(keys[i])
+		if err != nil {
+			return fmt.Errorf("invalid cursor sort key %q: %w", keys[i], err)
+		}
+		values[i] = value
+	}
+	q.after = func(d Dialect) Clause {
+		after := Clause{SQL: q.schema.IDColumn + " > ?", Args: []any{id}}
+		for i := len(q.order) - 1; i >= 0; i-- {
+			term := q.order[i]
+			op := ">"
+			if term.desc {
+				op = "<"
+			}
+			expr := term.field.expr(d)
+			after = Clause{
+				SQL:  fmt.Sprintf("(%s %s ? OR (%s = ? AND %s))", expr, op, expr, after.SQL),
+				Args: append([]any{values[i], values[i]}, after.Args...),
+			}
+		}
+		return after
+	}
+	return nil
+}
+
+// Keys returns the sort keys of a row for a page cursor
+func (q *Query[T]) Keys(row T) []string {
+	keys := make([]string, len(q.order))
+	for i, term := range q.order {
+		switch value := term.field.Key(row).(type) {
+		case time.Time:
+			keys[i] = value.UTC().Format(time.RFC3339Nano)
+		case string:
+			keys[i] = value
+		default:
+			keys[i] = fmt.Sprint(value)
+		}
+	}
+	return keys
+}
+
+// Filter returns the restriction of the query without the page cursor in a
+// dialect, for counting every matching row
+func (q *Query[T]) Filter(d Dialect) Clause {
+	return join(q.where, d)
+}
+
+// Page returns the restriction of the query after the page cursor in a
+// dialect
+func (q *Query[T]) Page(d Dialect) Clause {
+	if q.after == nil {
+		return q.Filter(d)
+	}
+	return join(append(q.where[:len(q.where):len(q.where)], q.after), d)
+}
+
+// OrderBy returns the SQL ordering of the query in a dialect, ending with the
+// ID column
+func (q *Query[T]) OrderBy(d Dialect) string {
+	columns := make([]string, 0, len(q.order)+1)
+	for _, term := range q.order {
+		if term.desc {
+			columns = append(columns, term.field.expr(d)+" DESC")
+		} else {
+			columns = append(columns, term.field.expr(d))
+		}
+	}
+	return strings.Join(append(columns, q.schema.IDColumn), ", ")
+}
+
+// join renders conditions in a dialect and combines them with AND, matching
+// every row when there are none
+func join(conditions []condition, d Dialect) Clause {
+	if len(conditions) == 0 {
+		return Clause{SQL: "TRUE"}
+	}
+	joined := Clause{}
+	sqls := make([]string, len(conditions))
+	for i, cond := range conditions {
+		clause := cond(d)
+		sqls[i] = clause.SQL
+		joined.Args = append(joined.Args, clause.Args...)
+	}
+	joined.SQL = strings.Join(sqls, " AND ")
+	return joined
+}
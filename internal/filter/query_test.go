@@ -0,0 +1,383 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testRow is a row of the list testSchema describes
+type testRow struct {
+	ID     string
+	Name   string
+	Amount int64
+	Date   time.Time
+}
+
+// testSchema is a schema with a field of each type, and a note field that may
+// only be filtered by
+var testSchema = &Schema[testRow]{
+	Fields: map[string]Field[testRow]{
+		"name":   {Column: "rows.name", Type: String, Key: func(r testRow) any { return r.Name }},
+		"amount": {Column: "rows.amount", Type: Integer, Key: func(r testRow) any { return r.Amount }},
+		"date":   {Column: "rows.date", Type: Timestamp, Key: func(r testRow) any { return r.Date }},
+		"note":   {Column: "rows.note", Type: String},
+	},
+	IDColumn:     "rows.id",
+	DefaultOrder: "name",
+}
+
+// TestCompile tests compiling filters and order_by clauses to SQLite and
+// PostgreSQL
+func TestCompile(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name           string
+		filter         string
+		orderBy        string
+		expectSQLite   Clause
+		expectPostgres Clause
+		expectOrder    [2]string // SQLite and PostgreSQL
+		expectPos      int
+		expectError    string
+	}{
+		{
+			name:           "No filter matches every row in the default order",
+			expectSQLite:   Clause{SQL: "TRUE"},
+			expectPostgres: Clause{SQL: "TRUE"},
+			expectOrder:    [2]string{"rows.name, rows.id", "rows.name, rows.id"},
+		},
+		{
+			name:           "Precedence of AND and OR",
+			filter:         `name="Cash" AND amount>1 OR amount<-5`,
+			expectSQLite:   Clause{SQL: "((rows.name = ?) AND ((rows.amount > ?) OR (rows.amount < ?)))", Args: []any{"Cash", int64(1), int64(-5)}},
+			expectPostgres: Clause{SQL: "((rows.name = ?) AND ((rows.amount > ?) OR (rows.amount < ?)))", Args: []any{"Cash", int64(1), int64(-5)}},
+			expectOrder:    [2]string{"rows.name, rows.id", "rows.name, rows.id"},
+		},
+		{
+			name:           "Parentheses and negation",
+			filter:         `-(name="Cash" OR NOT amount>=10)`,
+			expectSQLite:   Clause{SQL: "NOT ((rows.name = ?) OR NOT (rows.amount >= ?))", Args: []any{"Cash", int64(10)}},
+			expectPostgres: Clause{SQL: "NOT ((rows.name = ?) OR NOT (rows.amount >= ?))", Args: []any{"Cash", int64(10)}},
+			expectOrder:    [2]string{"rows.name, rows.id", "rows.name, rows.id"},
+		},
+		{
+			name:           "Has matches case-insensitively with LIKE metacharacters escaped",
+			filter:         `note:"100%_off\\*"`,
+			expectSQLite:   Clause{SQL: `(rows.note LIKE ? ESCAPE '\')`, Args: []any{`100\%\_off\\%`}},
+			expectPostgres: Clause{SQL: `(rows.note ILIKE ? ESCAPE '\')`, Args: []any{`100\%\_off\\%`}},
+			expectOrder:    [2]string{"rows.name, rows.id", "rows.name, rows.id"},
+		},
+		{
+			name:           "Equality with wildcards matches case-sensitively",
+			filter:         `name="Ca?h[*"`,
+			expectSQLite:   Clause{SQL: "(rows.name GLOB ?)", Args: []any{"Ca[?]h[[]*"}},
+			expectPostgres: Clause{SQL: `(rows.name LIKE ? ESCAPE '\')`, Args: []any{"Ca?h[%"}},
+			expectOrder:    [2]string{"rows.name, rows.id", "rows.name, rows.id"},
+		},
+		{
+			name:           "Inequality with wildcards",
+			filter:         `name!="Cash_*"`,
+			expectSQLite:   Clause{SQL: "(rows.name NOT GLOB ?)", Args: []any{"Cash_*"}},
+			expectPostgres: Clause{SQL: `(rows.name NOT LIKE ? ESCAPE '\')`, Args: []any{`Cash\_%`}},
+			expectOrder:    [2]string{"rows.name, rows.id", "rows.name, rows.id"},
+		},
+		{
+			name:           "Timestamps are normalized to UTC",
+			filter:         `date>=2025-04-01 date<"2025-04-02T09:00:00+09:00"`,
+			expectSQLite:   Clause{SQL: "((strftime('%Y-%m-%d %H:%M:%f', rows.date) >= ?) AND (strftime('%Y-%m-%d %H:%M:%f', rows.date) < ?))", Args: []any{"2025-04-01 00:00:00.000", "2025-04-02 00:00:00.000"}},
+			expectPostgres: Clause{SQL: "((to_char(rows.date AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS.MS') >= ?) AND (to_char(rows.date AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS.MS') < ?))", Args: []any{"2025-04-01 00:00:00.000", "2025-04-02 00:00:00.000"}},
+			expectOrder:    [2]string{"rows.name, rows.id", "rows.name, rows.id"},
+		},
+		{
+			name:           "Order by several fields",
+			orderBy:        "amount desc, date ASC",
+			expectSQLite:   Clause{SQL: "TRUE"},
+			expectPostgres: Clause{SQL: "TRUE"},
+			expectOrder: [2]string{
+				"rows.amount DESC, strftime('%Y-%m-%d %H:%M:%f', rows.date), rows.id",
+				"rows.amount DESC, to_char(rows.date AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS.MS'), rows.id",
+			},
+		},
+		{
+			name:        "Unknown field",
+			filter:      `amount>1 AND colour="red"`,
+			expectPos:   14,
+			expectError: `unknown field "colour"`,
+		},
+		{
+			name:        "Integer field with a text value",
+			filter:      `amount="ten"`,
+			expectPos:   8,
+			expectError: `invalid integer value "ten" for field "amount"`,
+		},
+		{
+			name:        "Timestamp field with an invalid date",
+			filter:      `date>2025-13-01`,
+			expectPos:   6,
+			expectError: `invalid timestamp value "2025-13-01" for field "date"`,
+		},
+		{
+			name:        "Has on an integer field",
+			filter:      `amount:5`,
+			expectPos:   7,
+			expectError: `operator ':' only applies to string fields, not integer field "amount"`,
+		},
+		{
+			name:        "Syntax error",
+			filter:      `name=`,
+			expectPos:   6,
+			expectError: `expected a value after "=" but found end of filter`,
+		},
+		{
+			name:        "Order by an unknown field",
+			orderBy:     "name, colour",
+			expectPos:   7,
+			expectError: `unknown field "colour"`,
+		},
+		{
+			name:        "Order by a field without a key",
+			orderBy:     "note",
+			expectPos:   1,
+			expectError: `field "note" cannot be used in order_by`,
+		},
+		{
+			name:        "Order by a field twice",
+			orderBy:     "name, name desc",
+			expectPos:   7,
+			expectError: `field "name" appears more than once in order_by`,
+		},
+		{
+			name:        "Order by an unknown direction",
+			orderBy:     "name sideways",
+			expectPos:   6,
+			expectError: `expected asc or desc after "name" but found "sideways"`,
+		},
+		{
+			name:        "Order by with a missing comma",
+			orderBy:     "name asc amount",
+			expectPos:   10,
+			expectError: `expected ',' but found "amount"`,
+		},
+		{
+			name:        "Order by with a trailing comma",
+			orderBy:     "name,",
+			expectPos:   6,
+			expectError: "expected a field name in order_by",
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := testSchema.Compile(tc.filter, tc.orderBy)
+
+			if tc.expectError != "" {
+				var filterErr *Error
+				if !errors.As(err, &filterErr) {
+					t.Fatalf("Expected a filter error, got %v", err)
+				}
+				if filterErr.Pos != tc.expectPos || filterErr.Msg != tc.expectError {
+					t.Errorf("Expected %q at position %d, got %q at position %d", tc.expectError, tc.expectPos, filterErr.Msg, filterErr.Pos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for i, d := range []Dialect{SQLite, PostgreSQL} {
+				expect := []Clause{tc.expectSQLite, tc.expectPostgres}[i]
+				if got := q.Filter(d); !equalClauses(got, expect) {
+					t.Errorf("Expected dialect %d filter %s %v, got %s %v", d, expect.SQL, expect.Args, got.SQL, got.Args)
+				}
+				if got := q.OrderBy(d); got != tc.expectOrder[i] {
+					t.Errorf("Expected dialect %d order %q, got %q", d, tc.expectOrder[i], got)
+				}
+			}
+		})
+	}
+}
+
+// TestAfter tests the keyset predicates narrowing queries to the rows after a
+// page cursor
+func TestAfter(t *testing.T) {
+	row := testRow{
+		ID:     "row_1",
+		Name:   "Cash",
+		Amount: 5,
+		Date:   time.Date(2025, 4, 1, 9, 30, 0, 0, time.FixedZone("JST", 9*60*60)),
+	}
+
+	// Define test cases
+	tests := []struct {
+		name           string
+		filter         string
+		orderBy        string
+		keys           []string // the keys of row if nil
+		expectKeys     []string
+		expectSQLite   Clause
+		expectPostgres Clause
+		expectError    bool
+	}{
+		{
+			name:           "Default order",
+			expectKeys:     []string{"Cash"},
+			expectSQLite:   Clause{SQL: "(rows.name > ? OR (rows.name = ? AND rows.id > ?))", Args: []any{"Cash", "Cash", "row_1"}},
+			expectPostgres: Clause{SQL: "(rows.name > ? OR (rows.name = ? AND rows.id > ?))", Args: []any{"Cash", "Cash", "row_1"}},
+		},
+		{
+			name:       "Descending and ascending fields after a filter",
+			filter:     `name:"C*"`,
+			orderBy:    "amount desc, name",
+			expectKeys: []string{"5", "Cash"},
+			expectSQLite: Clause{
+				SQL:  `(rows.name LIKE ? ESCAPE '\') AND (rows.amount < ? OR (rows.amount = ? AND (rows.name > ? OR (rows.name = ? AND rows.id > ?))))`,
+				Args: []any{"C%", int64(5), int64(5), "Cash", "Cash", "row_1"},
+			},
+			expectPostgres: Clause{
+				SQL:  `(rows.name ILIKE ? ESCAPE '\') AND (rows.amount < ? OR (rows.amount = ? AND (rows.name > ? OR (rows.name = ? AND rows.id > ?))))`,
+				Args: []any{"C%", int64(5), int64(5), "Cash", "Cash", "row_1"},
+			},
+		},
+		{
+			name:       "Timestamp field",
+			orderBy:    "date desc",
+			expectKeys: []string{"2025-04-01T00:30:00Z"},
+			expectSQLite: Clause{
+				SQL:  "(strftime('%Y-%m-%d %H:%M:%f', rows.date) < ? OR (strftime('%Y-%m-%d %H:%M:%f', rows.date) = ? AND rows.id > ?))",
+				Args: []any{"2025-04-01 00:30:00.000", "2025-04-01 00:30:00.000", "row_1"},
+			},
+			expectPostgres: Clause{
+				SQL:  "(to_char(rows.date AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS.MS') < ? OR (to_char(rows.date AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS.MS') = ? AND rows.id > ?))",
+				Args: []any{"2025-04-01 00:30:00.000", "2025-04-01 00:30:00.000", "row_1"},
+			},
+		},
+		{
+			name:        "Cursor with too few keys",
+			orderBy:     "amount, name",
+			keys:        []string{"5"},
+			expectError: true,
+		},
+		{
+			name:        "Cursor with an invalid key",
+			orderBy:     "amount",
+			keys:        []string{"five"},
+			expectError: true,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := testSchema.Compile(tc.filter, tc.orderBy)
+			if err != nil {
+				t.Fatalf("Failed to compile query: %v", err)
+			}
+			keys := tc.keys
+			if keys == nil {
+				keys = q.Keys(row)
+				if !slices.Equal(keys, tc.expectKeys) {
+					t.Errorf("Expected keys %v, got %v", tc.expectKeys, keys)
+				}
+			}
+
+			filter := q.Filter(SQLite)
+			err = q.After(keys, row.ID)
+
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for i, d := range []Dialect{SQLite, PostgreSQL} {
+				expect := []Clause{tc.expectSQLite, tc.expectPostgres}[i]
+				if got := q.Page(d); !equalClauses(got, expect) {
+					t.Errorf("Expected dialect %d page %s %v, got %s %v", d, expect.SQL, expect.Args, got.SQL, got.Args)
+				}
+			}
+			// Counting the rows of a list ignores the cursor
+			if got := q.Filter(SQLite); !equalClauses(got, filter) {
+				t.Errorf("Expected filter %s %v without the cursor, got %s %v", filter.SQL, filter.Args, got.SQL, got.Args)
+			}
+		})
+	}
+}
+
+// TestNarrow tests narrowing queries with list parameters passed outside the
+// filter
+func TestNarrow(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name         string
+		narrow       func(q *Query[testRow]) error
+		expectSQLite Clause
+		expectError  bool
+	}{
+		{
+			name:         "Equal",
+			narrow:       func(q *Query[testRow]) error { return q.Equal("name", "Cash") },
+			expectSQLite: Clause{SQL: "(rows.amount > ?) AND (rows.name = ?)", Args: []any{int64(0), "Cash"}},
+		},
+		{
+			name: "Compare with a time",
+			narrow: func(q *Query[testRow]) error {
+				return q.Compare("date", "<", time.Date(2025, 4, 2, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60)))
+			},
+			expectSQLite: Clause{SQL: "(rows.amount > ?) AND (strftime('%Y-%m-%d %H:%M:%f', rows.date) < ?)", Args: []any{int64(0), "2025-04-02 00:00:00.000"}},
+		},
+		{
+			name: "Where",
+			narrow: func(q *Query[testRow]) error {
+				q.Where(Clause{SQL: "rows.id IN (SELECT row_id FROM members WHERE user_id = ?)", Args: []any{"usr_1"}})
+				return nil
+			},
+			expectSQLite: Clause{SQL: "(rows.amount > ?) AND (rows.id IN (SELECT row_id FROM members WHERE user_id = ?))", Args: []any{int64(0), "usr_1"}},
+		},
+		{
+			name:        "Unknown field",
+			narrow:      func(q *Query[testRow]) error { return q.Equal("colour", "red") },
+			expectError: true,
+		},
+		{
+			name:        "Unknown operator",
+			narrow:      func(q *Query[testRow]) error { return q.Compare("amount", "LIKE", 1) },
+			expectError: true,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := testSchema.Compile("amount>0", "")
+			if err != nil {
+				t.Fatalf("Failed to compile query: %v", err)
+			}
+
+			err = tc.narrow(q)
+
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := q.Filter(SQLite); !equalClauses(got, tc.expectSQLite) {
+				t.Errorf("Expected filter %s %v, got %s %v", tc.expectSQLite.SQL, tc.expectSQLite.Args, got.SQL, got.Args)
+			}
+		})
+	}
+}
+
+// equalClauses reports whether two clauses have the same SQL and arguments
+func equalClauses(a, b Clause) bool {
+	return a.SQL == b.SQL && (len(a.Args) == 0 && len(b.Args) == 0 || reflect.DeepEqual(a.Args, b.Args))
+}
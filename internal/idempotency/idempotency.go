@@ -0,0 +1,354 @@
+// Package idempotency implements a Connect interceptor honoring the
+// Idempotency-Key header. The first request with a key records the hash of
+// its payload and, once it completes, its response or error. A retry with the
+// same key and payload replays that outcome instead of running again, and a
+// request reusing the key with a different payload is rejected. Keys expire
+// after a TTL measured with a clock.Clock. Only the response message is
+// recorded, so responses that also set headers, such as the session cookie of
+// CreateSession, are never replayed: their key is released and a retry runs
+// again.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"connectrpc.com/connect"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+)
+
+const (
+	// Header is the request header carrying the idempotency key
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from a previous request
+	ReplayedHeader = "Idempotent-Replayed"
+	// MaxKeyLength is the longest idempotency key accepted
+	MaxKeyLength = 255
+)
+
+// Interceptor replays the outcome of retried requests that carry an
+// Idempotency-Key header. Requests without the header, and methods without
+// side effects, pass through untouched.
+type Interceptor struct {
+	repo      *repo.IdempotencyRepo
+	clock     clock.Clock
+	ttl       time.Duration
+	logger    *slog.Logger
+	responses map[protoreflect.FullName]reflect.Type
+}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// NewInterceptor creates a new Interceptor keeping keys for ttl. Replayed
+// responses are rebuilt as the *connect.Response types returned by the methods
+// of handlers, the service implementations the interceptor is installed on.
+func NewInterceptor(idempotencyRepo *repo.IdempotencyRepo, clk clock.Clock, ttl time.Duration, logger *slog.Logger, handlers ...any) *Interceptor {
+	return &Interceptor{
+		repo:      idempotencyRepo,
+		clock:     clk,
+		ttl:       ttl,
+		logger:    logger,
+		responses: responseTypes(handlers),
+	}
+}
+
+// WrapUnary implements connect.Interceptor
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		key := req.Header().Get(Header)
+		if key == "" || req.Spec().IsClient || req.Spec().IdempotencyLevel == connect.IdempotencyNoSideEffects {
+			return next(ctx, req)
+		}
+		if len(key) > MaxKeyLength {
+			log.ErrorContext(ctx, i.logger, "Invalid idempotency key", "procedure", req.Spec().Procedure, "length", len(key))
+			return nil, errors.InvalidField(Header, "%s is longer than %d bytes", Header, MaxKeyLength)
+		}
+		// Keys are scoped to the authenticated user and their workspace, so
+		// that one user cannot replay the responses of another, nor a response
+		// of one workspace in another
+		if userID := auth.UserIDFrom(ctx); userID != "" {
+			key = userID + "/" + authz.WorkspaceFrom(ctx) + "/" + key
+		}
+
+		hash, err := requestHash(req)
+		if err != nil {
+			log.ErrorContext(ctx, i.logger, "Failed to hash request", "procedure", req.Spec().Procedure, "error", err)
+			return nil, errors.Internal(err)
+		}
+
+		record, claimed, err := i.claim(ctx, key, req.Spec().Procedure, hash)
+		if err != nil {
+			log.ErrorContext(ctx, i.logger, "Failed to claim idempotency key", "procedure", req.Spec().Procedure, "error", err)
+			return nil, errors.Internal(err)
+		}
+		if !claimed {
+			return i.replay(ctx, req.Spec(), record, hash)
+		}
+
+		log.InfoContext(ctx, i.logger, "Idempotency key claimed", "procedure", req.Spec().Procedure, "key", key)
+		res, err := next(ctx, req)
+		i.complete(ctx, key, res, err)
+		return res, err
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// claim purges expired keys and claims key for a new request. If the key is
+// already claimed it returns the existing record instead.
+func (i *Interceptor) claim(ctx context.Context, key, procedure, hash string) (db.IdempotencyKey, bool, error) {
+	tx, err := i.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		return db.IdempotencyKey{}, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, i.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	now := i.clock.Now().UTC()
+	if _, err := i.repo.DeleteExpiredIdempotencyKeys(ctx, tx, now); err != nil {
+		return db.IdempotencyKey{}, false, err
+	}
+	claimed, err := i.repo.ClaimIdempotencyKey(ctx, tx, db.ClaimIdempotencyKeyParams{
+		IdempotencyKey: key,
+		Method:         procedure,
+		RequestHash:    hash,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(i.ttl),
+	})
+	if err != nil {
+		return db.IdempotencyKey{}, false, err
+	}
+
+	var record db.IdempotencyKey
+	if !claimed {
+		if record, err = i.repo.GetIdempotencyKey(ctx, tx, key); err != nil {
+			return db.IdempotencyKey{}, false, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return db.IdempotencyKey{}, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return record, claimed, nil
+}
+
+// replay returns the recorded outcome of a previous request with the same key
+func (i *Interceptor) replay(ctx context.Context, spec connect.Spec, record db.IdempotencyKey, hash string) (connect.AnyResponse, error) {
+	if record.Method != spec.Procedure || record.RequestHash != hash {
+		log.ErrorContext(ctx, i.logger, "Idempotency key reused with a different request", "procedure", spec.Procedure, "key", record.IdempotencyKey)
+		return nil, errors.Error(connect.CodeInvalidArgument, errors.ReasonIdempotencyKeyReuse, fmt.Errorf("%w: %s %s was already used for a different request", errors.ErrInvalidInput, Header, record.IdempotencyKey))
+	}
+	if record.CompletedAt == nil {
+		log.ErrorContext(ctx, i.logger, "Idempotency key still in progress", "procedure", spec.Procedure, "key", record.IdempotencyKey)
+		return nil, errors.Error(connect.CodeAborted, errors.ReasonRequestInProgress, fmt.Errorf("%w: the request with %s %s is still in progress", errors.ErrConflict, Header, record.IdempotencyKey))
+	}
+
+	log.InfoContext(ctx, i.logger, "Replaying idempotent request", "procedure", spec.Procedure, "key", record.IdempotencyKey, "code", record.Code)
+	if record.Code != 0 {
+		connectErr := connect.NewError(connect.Code(record.Code), stderrors.New(record.ErrorMessage))
+		if err := addDetails(connectErr, record.Response); err != nil {
+			log.ErrorContext(ctx, i.logger, "Failed to decode recorded error details", "procedure", spec.Procedure, "error", err)
+		}
+		connectErr.Meta().Set(ReplayedHeader, "true")
+		return nil, connectErr
+	}
+
+	res, err := i.newResponse(spec, record.Response)
+	if err != nil {
+		log.ErrorContext(ctx, i.logger, "Failed to decode recorded response", "procedure", spec.Procedure, "error", err)
+		return nil, errors.Internal(err)
+	}
+	res.Header().Set(ReplayedHeader, "true")
+	return res, nil
+}
+
+// complete records the outcome of the request holding key. Outcomes a retry
+// could change, such as internal or unavailable errors, release the key
+// instead so the retry runs again. So do responses carrying headers, which a
+// replay could not reproduce; a Set-Cookie header holds a session token that
+// must not be stored either.
+func (i *Interceptor) complete(ctx context.Context, key string, res connect.AnyResponse, err error) {
+	if err != nil && !final(connect.CodeOf(err)) || err == nil && len(res.Header()) > 0 {
+		i.release(ctx, key)
+		return
+	}
+
+	now := i.clock.Now().UTC()
+	arg := db.CompleteIdempotencyKeyParams{
+		IdempotencyKey: key,
+		CompletedAt:    &now,
+	}
+	if err != nil {
+		arg.Code = int64(connect.CodeOf(err))
+		arg.ErrorMessage = err.Error()
+		var connectErr *connect.Error
+		if stderrors.As(err, &connectErr) {
+			arg.ErrorMessage = connectErr.Message()
+			details, marshalErr := marshalDetails(connectErr)
+			if marshalErr != nil {
+				log.ErrorContext(ctx, i.logger, "Failed to record error details", "key", key, "error", marshalErr)
+				return
+			}
+			arg.Response = details
+		}
+	} else if msg, ok := res.Any().(proto.Message); ok {
+		response, marshalErr := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if marshalErr != nil {
+			log.ErrorContext(ctx, i.logger, "Failed to record response", "key", key, "error", marshalErr)
+			return
+		}
+		arg.Response = response
+	}
+	if err := i.repo.CompleteIdempotencyKey(ctx, i.repo.GetDB(), arg); err != nil {
+		log.ErrorContext(ctx, i.logger, "Failed to complete idempotency key", "key", key, "error", err)
+	}
+}
+
+// release deletes key so that a retry of the request runs again
+func (i *Interceptor) release(ctx context.Context, key string) {
+	if err := i.repo.DeleteIdempotencyKey(ctx, i.repo.GetDB(), key); err != nil {
+		log.ErrorContext(ctx, i.logger, "Failed to release idempotency key", "key", key, "error", err)
+	}
+}
+
+// marshalDetails encodes the details of a connect error, such as its
+// ErrorInfo reason, as a google.rpc.Status to record in place of a response.
+// An error without details records nothing.
+func marshalDetails(connectErr *connect.Error) ([]byte, error) {
+	details := connectErr.Details()
+	if len(details) == 0 {
+		return nil, nil
+	}
+	status := &spb.Status{Code: int32(connectErr.Code()), Message: connectErr.Message()}
+	for _, detail := range details {
+		status.Details = append(status.Details, &anypb.Any{
+			TypeUrl: "type.googleapis.com/" + detail.Type(),
+			Value:   detail.Bytes(),
+		})
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(status)
+}
+
+// addDetails adds the details marshalDetails recorded to a replayed error
+func addDetails(connectErr *connect.Error, recorded []byte) error {
+	if len(recorded) == 0 {
+		return nil
+	}
+	var status spb.Status
+	if err := proto.Unmarshal(recorded, &status); err != nil {
+		return err
+	}
+	for _, detail := range status.Details {
+		errorDetail, err := connect.NewErrorDetail(detail)
+		if err != nil {
+			return err
+		}
+		connectErr.AddDetail(errorDetail)
+	}
+	return nil
+}
+
+// final reports whether retrying a request that failed with code would fail
+// the same way
+func final(code connect.Code) bool {
+	switch code {
+	case connect.CodeInvalidArgument, connect.CodeNotFound, connect.CodeAlreadyExists,
+		connect.CodePermissionDenied, connect.CodeFailedPrecondition, connect.CodeOutOfRange,
+		connect.CodeUnimplemented, connect.CodeUnauthenticated:
+		return true
+	}
+	return false
+}
+
+// requestHash hashes the procedure and the deterministic encoding of the
+// request message
+func requestHash(req connect.AnyRequest) (string, error) {
+	msg, ok := req.Any().(proto.Message)
+	if !ok {
+		return "", fmt.Errorf("request of %s is not a protobuf message", req.Spec().Procedure)
+	}
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(req.Spec().Procedure+"\x00"), payload...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// newResponse decodes a recorded response of the method of spec into the
+// *connect.Response type its handler returns
+func (i *Interceptor) newResponse(spec connect.Spec, payload []byte) (connect.AnyResponse, error) {
+	method, ok := spec.Schema.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("no schema for %s", spec.Procedure)
+	}
+	responseType, ok := i.responses[method.Output().FullName()]
+	if !ok {
+		return nil, fmt.Errorf("no handler returns %s", method.Output().FullName())
+	}
+
+	res := reflect.New(responseType)
+	msgField := res.Elem().FieldByName("Msg")
+	msg := reflect.New(msgField.Type().Elem())
+	if err := proto.Unmarshal(payload, msg.Interface().(proto.Message)); err != nil {
+		return nil, err
+	}
+	msgField.Set(msg)
+	return res.Interface().(connect.AnyResponse), nil
+}
+
+// responseTypes maps response message names to the connect.Response types
+// returned by the unary methods of handlers. Connect only accepts the
+// handler's own generic response type, which cannot be named from here.
+func responseTypes(handlers []any) map[protoreflect.FullName]reflect.Type {
+	responses := make(map[protoreflect.FullName]reflect.Type)
+	anyResponse := reflect.TypeOf((*connect.AnyResponse)(nil)).Elem()
+	for _, handler := range handlers {
+		handlerType := reflect.TypeOf(handler)
+		for m := range handlerType.NumMethod() {
+			method := handlerType.Method(m).Type
+			if method.NumOut() != 2 || !method.Out(0).Implements(anyResponse) || method.Out(0).Kind() != reflect.Pointer {
+				continue
+			}
+			responseType := method.Out(0).Elem()
+			msgField, ok := responseType.FieldByName("Msg")
+			if !ok || msgField.Type.Kind() != reflect.Pointer {
+				continue
+			}
+			msg, ok := reflect.New(msgField.Type.Elem()).Interface().(proto.Message)
+			if !ok {
+				continue
+			}
+			responses[msg.ProtoReflect().Descriptor().FullName()] = responseType
+		}
+	}
+	return responses
+}
@@ -19,8 +19,23 @@ func NewLogger() *slog.Logger {
 	return logger
 }
 
-// LogWithContext logs with trace context information automatically extracted
+type attrsKey struct{}
+
+// WithAttrs returns a copy of ctx carrying key-value pairs that every log
+// written with it includes, such as the ID of the authenticated user
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	attrs, _ := ctx.Value(attrsKey{}).([]any)
+	return context.WithValue(ctx, attrsKey{}, append(attrs[:len(attrs):len(attrs)], args...))
+}
+
+// LogWithContext logs with trace context information and the attributes added
+// by WithAttrs automatically extracted
 func LogWithContext(ctx context.Context, logger *slog.Logger, level slog.Level, msg string, args ...any) {
+	// Add the attributes carried by the context
+	if attrs, ok := ctx.Value(attrsKey{}).([]any); ok {
+		args = append(args, attrs...)
+	}
+
 	// Extract trace IDs if present in context
 	spanCtx := trace.SpanContextFromContext(ctx)
 	if spanCtx.IsValid() {
@@ -0,0 +1,148 @@
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// SumFile is the name of the integrity file Atlas keeps in a migration
+// directory
+const SumFile = "atlas.sum"
+
+// Migration is one file of a migration directory
+type Migration struct {
+	Version     string // Leading timestamp of the file name, such as 20250428085758
+	Description string // Rest of the file name, such as baseline
+	Name        string // File name
+	SQL         string
+	Hash        string // Sum of the file in atlas.sum, chained with the files before it
+}
+
+// Load reads the migrations of a directory in version order, verifying them
+// against its atlas.sum the way `atlas migrate validate` does, so that a
+// directory edited without rehashing is refused
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration directory: %w", err)
+	}
+	var migrations []Migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		version, description, _ := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+		migrations = append(migrations, Migration{Version: version, Description: description, Name: name, SQL: string(content)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Name < migrations[j].Name })
+
+	// Each file is hashed together with the files before it, and the whole
+	// directory by the names and hashes of its files
+	chain, total := sha256.New(), sha256.New()
+	for i := range migrations {
+		chain.Write([]byte(migrations[i].Name))
+		chain.Write([]byte(migrations[i].SQL))
+		migrations[i].Hash = base64.StdEncoding.EncodeToString(chain.Sum(nil))
+		total.Write([]byte(migrations[i].Name))
+		total.Write([]byte(migrations[i].Hash))
+	}
+	if err := verifySum(fsys, migrations, base64.StdEncoding.EncodeToString(total.Sum(nil))); err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+// verifySum compares the hashes of the migrations to those recorded in
+// atlas.sum
+func verifySum(fsys fs.FS, migrations []Migration, total string) error {
+	content, err := fs.ReadFile(fsys, SumFile)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", SumFile, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	if !scanner.Scan() {
+		return fmt.Errorf("%w: %s is empty", ErrChecksumMismatch, SumFile)
+	}
+	recorded := map[string]string{}
+	for scanner.Scan() {
+		if name, hash, ok := strings.Cut(scanner.Text(), " h1:"); ok {
+			recorded[name] = hash
+		}
+	}
+	for _, m := range migrations {
+		if recorded[m.Name] != m.Hash {
+			return fmt.Errorf("%w: %s does not match %s", ErrChecksumMismatch, m.Name, SumFile)
+		}
+	}
+	if strings.TrimPrefix(strings.TrimSpace(strings.SplitN(string(content), "\n", 2)[0]), "h1:") != total || len(recorded) != len(migrations) {
+		return fmt.Errorf("%w: the migration directory does not match %s", ErrChecksumMismatch, SumFile)
+	}
+	return nil
+}
+
+// statements splits a migration file into its SQL statements, the way Atlas
+// counts them, ignoring semicolons within quotes and comments
+func statements(sql string) []string {
+	var (
+		result []string
+		start  int
+	)
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// Skip a quoted string or identifier, where a doubled quote escapes it
+			for i++; i < len(sql); i++ {
+				if sql[i] == c {
+					if i+1 < len(sql) && sql[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(sql)
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(sql)
+			}
+		case c == ';':
+			if stmt := strings.TrimSpace(sql[start : i+1]); stmt != ";" {
+				result = append(result, stmt)
+			}
+			start = i + 1
+		}
+	}
+	if stmt := strings.TrimSpace(sql[start:]); stmt != "" && !onlyComments(stmt) {
+		result = append(result, stmt)
+	}
+	return result
+}
+
+// onlyComments reports whether a trailing piece of a file holds nothing but
+// comments
+func onlyComments(sql string) bool {
+	for _, line := range strings.Split(sql, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,377 @@
+// Package migrate applies the Atlas migration directory embedded in the binary
+// to a SQLite or PostgreSQL database. Applied migrations are recorded in Atlas's own
+// atlas_schema_revisions table, so a database keeps working with both the
+// atlas CLI and the migrate command. Migrations are reverted by rebuilding the
+// schema the earlier migrations describe and copying the data over, the way
+// Atlas plans a down migration from the desired state, which is only
+// supported on SQLite.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrChecksumMismatch is returned for a migration directory that does not
+	// match its atlas.sum
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrSchemaNewer is returned for a database migrated by a newer binary,
+	// with migrations this binary does not know
+	ErrSchemaNewer = errors.New("database schema is newer than this binary")
+	// ErrPending is returned by Check for a database with migrations to apply
+	ErrPending = errors.New("database has pending migrations")
+	// ErrDirty is returned for a database a migration failed part way through
+	ErrDirty = errors.New("database has a partially applied migration")
+	// ErrUnsupported is returned by Down on PostgreSQL
+	ErrUnsupported = errors.New("reverting migrations is only supported on SQLite")
+)
+
+// revisionsTable is the table Atlas records applied migrations in
+const revisionsTable = "atlas_schema_revisions"
+
+// createRevisionsTable creates revisionsTable with the columns Atlas uses
+const createRevisionsTable = "CREATE TABLE IF NOT EXISTS `" + revisionsTable + "` (" +
+	"`version` text NOT NULL, `description` text NOT NULL, `type` integer NOT NULL DEFAULT 2, " +
+	"`applied` integer NOT NULL DEFAULT 0, `total` integer NOT NULL DEFAULT 0, `executed_at` datetime NOT NULL, " +
+	"`execution_time` integer NOT NULL, `error` text NULL, `error_stmt` text NULL, `hash` text NOT NULL, " +
+	"`partial_hashes` json NULL, `operator_version` text NOT NULL, PRIMARY KEY (`version`))"
+
+// createPostgresRevisionsTable is createRevisionsTable in PostgreSQL
+const createPostgresRevisionsTable = `CREATE TABLE IF NOT EXISTS "` + revisionsTable + `" (` +
+	`"version" text NOT NULL, "description" text NOT NULL, "type" bigint NOT NULL DEFAULT 2, ` +
+	`"applied" bigint NOT NULL DEFAULT 0, "total" bigint NOT NULL DEFAULT 0, "executed_at" timestamptz NOT NULL, ` +
+	`"execution_time" bigint NOT NULL, "error" text NULL, "error_stmt" text NULL, "hash" text NOT NULL, ` +
+	`"partial_hashes" jsonb NULL, "operator_version" text NOT NULL, PRIMARY KEY ("version"))`
+
+// operatorVersion identifies this binary as the operator of the revisions it
+// records
+const operatorVersion = "expense-manager"
+
+// revisionTypeExecute marks a revision of an executed migration file
+const revisionTypeExecute = 2
+
+// Revision is a migration recorded as applied to the database
+type Revision struct {
+	Version     string    `db:"version"`
+	Description string    `db:"description"`
+	Applied     int       `db:"applied"`
+	Total       int       `db:"total"`
+	ExecutedAt  time.Time `db:"executed_at"`
+}
+
+// Status describes how far the database is migrated
+type Status struct {
+	Applied []Revision
+	Pending []Migration
+	// Unknown are applied revisions missing from the migration directory,
+	// such as those of a newer binary
+	Unknown []Revision
+}
+
+// Current returns the version of the last applied migration, or "" if none is
+func (s Status) Current() string {
+	if len(s.Applied) == 0 {
+		return ""
+	}
+	return s.Applied[len(s.Applied)-1].Version
+}
+
+// Migrator applies and reverts the migrations of a directory
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+	clock      clock.Clock
+	logger     *slog.Logger
+}
+
+// New creates a Migrator for the migration directory fsys, verifying it
+// against its atlas.sum
+func New(db *sqlx.DB, fsys fs.FS, clk clock.Clock, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, clock: clk, logger: logger}, nil
+}
+
+// postgres reports whether the database is PostgreSQL
+func (m *Migrator) postgres() bool {
+	return m.db.DriverName() == repo.DriverPostgres
+}
+
+// Status reports the applied, pending and unknown migrations of the database
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	create := createRevisionsTable
+	if m.postgres() {
+		create = createPostgresRevisionsTable
+	}
+	if _, err := m.db.ExecContext(ctx, create); err != nil {
+		return Status{}, fmt.Errorf("failed to create revisions table: %w", err)
+	}
+	var revisions []Revision
+	if err := m.db.SelectContext(ctx, &revisions, "SELECT version, description, applied, total, executed_at FROM "+revisionsTable+" ORDER BY version"); err != nil {
+		return Status{}, fmt.Errorf("failed to list revisions: %w", err)
+	}
+
+	var status Status
+	known := make(map[string]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+	applied := make(map[string]bool, len(revisions))
+	for _, revision := range revisions {
+		if !known[revision.Version] {
+			status.Unknown = append(status.Unknown, revision)
+			continue
+		}
+		if revision.Applied < revision.Total {
+			return Status{}, fmt.Errorf("%w: %s applied %d of %d statements", ErrDirty, revision.Version, revision.Applied, revision.Total)
+		}
+		applied[revision.Version] = true
+		status.Applied = append(status.Applied, revision)
+	}
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+	return status, nil
+}
+
+// Check fails with ErrSchemaNewer if the database has migrations this binary
+// does not know, and with ErrPending if it has migrations to apply
+func (m *Migrator) Check(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if len(status.Unknown) > 0 {
+		return fmt.Errorf("%w: %s is not in the embedded migrations", ErrSchemaNewer, status.Unknown[len(status.Unknown)-1].Version)
+	}
+	if len(status.Pending) > 0 {
+		return fmt.Errorf("%w: %d to apply, starting with %s", ErrPending, len(status.Pending), status.Pending[0].Name)
+	}
+	return nil
+}
+
+// Up applies up to n pending migrations in version order, or all of them if
+// n is 0, each in its own transaction. It returns the migrations applied.
+func (m *Migrator) Up(ctx context.Context, n int) ([]Migration, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(status.Unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s is not in the embedded migrations", ErrSchemaNewer, status.Unknown[len(status.Unknown)-1].Version)
+	}
+	pending := status.Pending
+	if n > 0 && n < len(pending) {
+		pending = pending[:n]
+	}
+	if len(pending) > 0 && status.Current() != "" && pending[0].Version < status.Current() {
+		return nil, fmt.Errorf("migration %s is older than the applied %s; it must be renamed after it", pending[0].Name, status.Current())
+	}
+
+	var applied []Migration
+	err = m.withoutForeignKeys(ctx, func(conn *sqlx.Conn) error {
+		for _, migration := range pending {
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// apply executes the statements of a migration and records its revision in a
+// single transaction
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, migration Migration) error {
+	log.InfoContext(ctx, m.logger, "Applying migration", "version", migration.Version, "name", migration.Name)
+	start := m.clock.Now()
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.ErrorContext(ctx, m.logger, "Failed to rollback transaction", "error", err)
+		}
+	}()
+
+	stmts := statements(migration.SQL)
+	hashes := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to apply %s, statement %d: %w", migration.Name, i+1, err)
+		}
+		sum := sha256.Sum256([]byte(stmt))
+		hashes[i] = "h1:" + base64.StdEncoding.EncodeToString(sum[:])
+	}
+	partialHashes, err := json.Marshal(hashes)
+	if err != nil {
+		return fmt.Errorf("failed to encode statement hashes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		tx.Rebind("INSERT INTO "+revisionsTable+" (version, description, type, applied, total, executed_at, execution_time, hash, partial_hashes, operator_version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		migration.Version, migration.Description, revisionTypeExecute, len(stmts), len(stmts), start.UTC(), m.clock.Now().Sub(start).Nanoseconds(),
+		migration.Hash, string(partialHashes), operatorVersion,
+	); err != nil {
+		return fmt.Errorf("failed to record revision %s: %w", migration.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", migration.Name, err)
+	}
+	return nil
+}
+
+// Down reverts the last n applied migrations, or the last one if n is 0. It
+// rebuilds every table in the shape the remaining migrations give it, keeping
+// the data of the columns both shapes share, and returns the migrations
+// reverted, last first. It fails with ErrUnsupported on PostgreSQL.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	if m.postgres() {
+		return nil, ErrUnsupported
+	}
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(status.Unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s is not in the embedded migrations", ErrSchemaNewer, status.Unknown[len(status.Unknown)-1].Version)
+	}
+	if n <= 0 {
+		n = 1
+	}
+	if n > len(status.Applied) {
+		n = len(status.Applied)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	keep := status.Applied[:len(status.Applied)-n]
+	reverted := make([]Migration, 0, n)
+	for i := len(status.Applied) - 1; i >= len(keep); i-- {
+		reverted = append(reverted, m.migration(status.Applied[i].Version))
+	}
+
+	// Plan the target schema on a scratch database
+	target, err := m.plan(ctx, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.withoutForeignKeys(ctx, func(conn *sqlx.Conn) error {
+		for _, migration := range reverted {
+			log.InfoContext(ctx, m.logger, "Reverting migration", "version", migration.Version, "name", migration.Name)
+		}
+		tx, err := conn.BeginTxx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer func() {
+			if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+				log.ErrorContext(ctx, m.logger, "Failed to rollback transaction", "error", err)
+			}
+		}()
+		if err := rebuild(ctx, tx, target); err != nil {
+			return err
+		}
+		for _, migration := range reverted {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+revisionsTable+" WHERE version = ?", migration.Version); err != nil {
+				return fmt.Errorf("failed to delete revision %s: %w", migration.Version, err)
+			}
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
+
+// migration returns the migration of a version known to be in the directory
+func (m *Migrator) migration(version string) Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return Migration{Version: version}
+}
+
+// plan applies the migrations of revisions to an empty scratch database and
+// returns the schema they produce
+func (m *Migrator) plan(ctx context.Context, revisions []Revision) ([]object, error) {
+	scratch, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scratch database: %w", err)
+	}
+	defer func() {
+		if err := scratch.Close(); err != nil {
+			log.ErrorContext(ctx, m.logger, "Failed to close scratch database", "error", err)
+		}
+	}()
+	// Every connection to :memory: is a database of its own
+	scratch.SetMaxOpenConns(1)
+
+	for _, revision := range revisions {
+		for i, stmt := range statements(m.migration(revision.Version).SQL) {
+			if _, err := scratch.ExecContext(ctx, stmt); err != nil {
+				return nil, fmt.Errorf("failed to plan %s, statement %d: %w", revision.Version, i+1, err)
+			}
+		}
+	}
+	return schema(ctx, scratch)
+}
+
+// withoutForeignKeys runs fn on a connection with foreign key enforcement off,
+// as SQLite requires for rebuilding tables, and restores it afterwards.
+// PostgreSQL connections are passed as they are.
+func (m *Migrator) withoutForeignKeys(ctx context.Context, fn func(*sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.ErrorContext(ctx, m.logger, "Failed to release connection", "error", err)
+		}
+	}()
+
+	if m.postgres() {
+		return fn(conn)
+	}
+	var enabled bool
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enabled); err != nil {
+		return fmt.Errorf("failed to read foreign_keys: %w", err)
+	}
+	if enabled {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = off"); err != nil {
+			return fmt.Errorf("failed to disable foreign keys: %w", err)
+		}
+		defer func() {
+			if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = on"); err != nil {
+				log.ErrorContext(ctx, m.logger, "Failed to enable foreign keys", "error", err)
+			}
+		}()
+	}
+	return fn(conn)
+}
@@ -0,0 +1,179 @@
+package migrate
+
+import (
+	"context"
+	stderrors "errors"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/atreya2011/expense-manager/db/migrations"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/jmoiron/sqlx"
+)
+
+// openTestDB opens an empty in-memory database
+func openTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close database: %v", err)
+		}
+	})
+	return db
+}
+
+// newTestMigrator creates a Migrator of the embedded migrations on an empty
+// database
+func newTestMigrator(t *testing.T) (*Migrator, *sqlx.DB) {
+	t.Helper()
+
+	db := openTestDB(t)
+	migrator, err := New(db, migrations.FS, clock.NewMockClock(time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)), slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+	return migrator, db
+}
+
+// tableExists reports whether the database has a table
+func tableExists(t *testing.T, db *sqlx.DB, name string) bool {
+	t.Helper()
+
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name); err != nil {
+		t.Fatalf("Failed to look up table %s: %v", name, err)
+	}
+	return count > 0
+}
+
+// TestUpDown tests applying every migration, reverting the last ones while
+// keeping the data of the remaining columns, and applying them again
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	migrator, db := newTestMigrator(t)
+
+	// A new database has every migration pending
+	if err := migrator.Check(ctx); !stderrors.Is(err, ErrPending) {
+		t.Fatalf("Expected ErrPending, got %v", err)
+	}
+	applied, err := migrator.Up(ctx, 0)
+	if err != nil {
+		t.Fatalf("Failed to apply migrations: %v", err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Errorf("Expected %d migrations applied, got %d", len(migrator.migrations), len(applied))
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatalf("Expected an up to date database, got %v", err)
+	}
+
+	// Reverting the migrations down through the workspaces one keeps the rows
+	// of the tables they changed
+	reverting := migrator.migrations[slices.IndexFunc(migrator.migrations, func(m Migration) bool {
+		return m.Description == "workspaces"
+	}):]
+	if _, err := db.Exec("INSERT INTO instruments (id, workspace_id, name) VALUES ('inst_cash', 'wsp_default', 'Cash')"); err != nil {
+		t.Fatalf("Failed to insert instrument: %v", err)
+	}
+	reverted, err := migrator.Down(ctx, len(reverting))
+	if err != nil {
+		t.Fatalf("Failed to revert migrations: %v", err)
+	}
+	if len(reverted) != len(reverting) || reverted[len(reverted)-1].Version != reverting[0].Version {
+		t.Fatalf("Expected %s and later reverted, got %v", reverting[0].Version, reverted)
+	}
+	for _, table := range []string{"workspaces", "changes"} {
+		if tableExists(t, db, table) {
+			t.Errorf("Expected the %s table to be dropped", table)
+		}
+	}
+	var name string
+	if err := db.Get(&name, "SELECT name FROM instruments WHERE id = 'inst_cash'"); err != nil || name != "Cash" {
+		t.Errorf("Expected the instrument to be kept, got %q, %v", name, err)
+	}
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if len(status.Pending) != len(reverting) || status.Pending[0].Version != reverting[0].Version {
+		t.Errorf("Expected %s and later pending, got %v", reverting[0].Version, status.Pending)
+	}
+
+	// Applying them again moves the instrument into the default workspace
+	// Applying it again moves the instrument into the default workspace
+	if _, err := migrator.Up(ctx, 0); err != nil {
+		t.Fatalf("Failed to reapply migrations: %v", err)
+	}
+	var workspaceID string
+	if err := db.Get(&workspaceID, "SELECT workspace_id FROM instruments WHERE id = 'inst_cash'"); err != nil || workspaceID != "wsp_default" {
+		t.Errorf("Expected the instrument in wsp_default, got %q, %v", workspaceID, err)
+	}
+}
+
+// TestRefusals tests refusing a tampered migration directory and a database
+// migrated by a newer binary
+func TestRefusals(t *testing.T) {
+	ctx := context.Background()
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		run         func(t *testing.T) error
+		expectError error
+	}{
+		{
+			name: "Tampered migration",
+			run: func(t *testing.T) error {
+				tampered := fstest.MapFS{}
+				err := fs.WalkDir(migrations.FS, ".", func(path string, d fs.DirEntry, err error) error {
+					if err != nil || d.IsDir() {
+						return err
+					}
+					content, err := fs.ReadFile(migrations.FS, path)
+					tampered[path] = &fstest.MapFile{Data: content}
+					return err
+				})
+				if err != nil {
+					t.Fatalf("Failed to copy migrations: %v", err)
+				}
+				tampered["20250428085758_baseline.sql"].Data = append(tampered["20250428085758_baseline.sql"].Data, "DROP TABLE users;\n"...)
+				_, err = New(openTestDB(t), tampered, clock.NewRealClock(), slog.New(slog.DiscardHandler))
+				return err
+			},
+			expectError: ErrChecksumMismatch,
+		},
+		{
+			name: "Newer database",
+			run: func(t *testing.T) error {
+				migrator, db := newTestMigrator(t)
+				if _, err := migrator.Up(ctx, 0); err != nil {
+					t.Fatalf("Failed to apply migrations: %v", err)
+				}
+				if _, err := db.Exec("INSERT INTO atlas_schema_revisions (version, description, executed_at, execution_time, hash, operator_version) VALUES ('29991231000000', 'future', CURRENT_TIMESTAMP, 0, '', '')"); err != nil {
+					t.Fatalf("Failed to record revision: %v", err)
+				}
+				return migrator.Check(ctx)
+			},
+			expectError: ErrSchemaNewer,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.run(t); !stderrors.Is(err, tc.expectError) {
+				t.Errorf("Expected %v, got %v", tc.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// object is a table, index, view or trigger of a schema
+type object struct {
+	Type    string         `db:"type"`
+	Name    string         `db:"name"`
+	Table   string         `db:"tbl_name"`
+	SQL     sql.NullString `db:"sql"`
+	Columns []string       `db:"-"`
+}
+
+// schema lists the objects of a database other than SQLite's own and the
+// revisions table, tables first
+func schema(ctx context.Context, db sqlx.QueryerContext) ([]object, error) {
+	var objects []object
+	err := sqlx.SelectContext(ctx, db, &objects, `SELECT type, name, tbl_name, sql FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' AND name != ? AND sql IS NOT NULL
+		ORDER BY type != 'table', rowid`, revisionsTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	for i := range objects {
+		if objects[i].Type != "table" {
+			continue
+		}
+		if err := sqlx.SelectContext(ctx, db, &objects[i].Columns, "SELECT name FROM pragma_table_info(?)", objects[i].Name); err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", objects[i].Name, err)
+		}
+	}
+	return objects, nil
+}
+
+// createTableName matches the name of the table a CREATE TABLE statement
+// creates, quoted or not
+var createTableName = regexp.MustCompile("(?i)^(CREATE\\s+TABLE\\s+)(?:`[^`]+`|\"[^\"]+\"|\\[[^\\]]+\\]|\\S+?)(\\s*\\()")
+
+// rebuild changes the schema of the database tx runs on to target, following
+// SQLite's procedure for altering tables: each table of the target is created
+// under a new name, filled with the columns it shares with the table it
+// replaces, and renamed over it. Tables the target lacks are dropped.
+func rebuild(ctx context.Context, tx *sqlx.Tx, target []object) error {
+	current, err := schema(ctx, tx)
+	if err != nil {
+		return err
+	}
+	tables := map[string]object{}
+	for _, obj := range current {
+		switch obj.Type {
+		case "table":
+			tables[obj.Name] = obj
+		case "index", "view", "trigger":
+			// Recreated from the target below
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("DROP %s IF EXISTS %s", strings.ToUpper(obj.Type), quote(obj.Name))); err != nil {
+				return fmt.Errorf("failed to drop %s %s: %w", obj.Type, obj.Name, err)
+			}
+		}
+	}
+
+	for _, obj := range target {
+		if obj.Type != "table" {
+			continue
+		}
+		old, exists := tables[obj.Name]
+		delete(tables, obj.Name)
+		if !exists {
+			if _, err := tx.ExecContext(ctx, obj.SQL.String); err != nil {
+				return fmt.Errorf("failed to create table %s: %w", obj.Name, err)
+			}
+			continue
+		}
+		if old.SQL.String == obj.SQL.String {
+			continue
+		}
+
+		temporary := "new_" + obj.Name
+		create := createTableName.ReplaceAllString(obj.SQL.String, "${1}"+quote(temporary)+"${2}")
+		if _, err := tx.ExecContext(ctx, create); err != nil {
+			return fmt.Errorf("failed to create table %s: %w", obj.Name, err)
+		}
+		columns := shared(old.Columns, obj.Columns)
+		if len(columns) > 0 {
+			list := strings.Join(columns, ", ")
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", quote(temporary), list, list, quote(obj.Name))); err != nil {
+				return fmt.Errorf("failed to copy table %s: %w", obj.Name, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "DROP TABLE "+quote(obj.Name)); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", obj.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quote(temporary), quote(obj.Name))); err != nil {
+			return fmt.Errorf("failed to rename table %s: %w", obj.Name, err)
+		}
+	}
+
+	// Drop the tables the target does not have
+	for name := range tables {
+		if _, err := tx.ExecContext(ctx, "DROP TABLE "+quote(name)); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", name, err)
+		}
+	}
+
+	for _, obj := range target {
+		if obj.Type == "table" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, obj.SQL.String); err != nil {
+			return fmt.Errorf("failed to create %s %s: %w", obj.Type, obj.Name, err)
+		}
+	}
+	return nil
+}
+
+// shared returns the quoted columns of to that from also has
+func shared(from, to []string) []string {
+	has := make(map[string]bool, len(from))
+	for _, column := range from {
+		has[column] = true
+	}
+	var columns []string
+	for _, column := range to {
+		if has[column] {
+			columns = append(columns, quote(column))
+		}
+	}
+	return columns
+}
+
+// quote quotes an identifier
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,128 @@
+// Package money converts between integer minor-unit amounts and their decimal
+// representation using ISO 4217 minor-unit metadata.
+package money
+
+import (
+	stderrors "errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MaxMinorUnits is the largest number of minor units a currency may have
+const MaxMinorUnits = 4
+
+// MaxAmount is the largest amount in minor units a ledger line may carry. It
+// leaves room for summing thousands of lines without leaving the int64 range.
+const MaxAmount int64 = 1_000_000_000_000_000
+
+// ErrNotRepresentable is returned when a decimal amount has more fractional
+// digits than the currency allows
+var ErrNotRepresentable = stderrors.New("amount is not representable in currency")
+
+// ErrOutOfRange is returned when a sum of amounts does not fit in an int64
+var ErrOutOfRange = stderrors.New("amount is out of range")
+
+// Add returns the sum of amounts, or ErrOutOfRange when it overflows an int64
+// instead of wrapping around
+func Add(amounts ...int64) (int64, error) {
+	var sum int64
+	for _, amount := range amounts {
+		if amount > 0 && sum > math.MaxInt64-amount || amount < 0 && sum < math.MinInt64-amount {
+			return 0, ErrOutOfRange
+		}
+		sum += amount
+	}
+	return sum, nil
+}
+
+// FormatDecimal renders an amount in minor units as a plain decimal string,
+// e.g. 1234 with 2 minor units becomes "12.34"
+func FormatDecimal(amount int64, minorUnits int) string {
+	sign := ""
+	magnitude := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		magnitude = uint64(-amount)
+	}
+
+	digits := fmt.Sprintf("%0*d", minorUnits+1, magnitude)
+	if minorUnits == 0 {
+		return sign + digits
+	}
+	split := len(digits) - minorUnits
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// Format renders an amount for display with thousands separators, prefixed by
+// the currency symbol when one is known and suffixed by the code otherwise
+func Format(amount int64, minorUnits int, symbol, code string) string {
+	decimal := FormatDecimal(amount, minorUnits)
+	sign := ""
+	if strings.HasPrefix(decimal, "-") {
+		sign, decimal = "-", decimal[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(decimal, ".")
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	number := grouped.String()
+	if fraction != "" {
+		number += "." + fraction
+	}
+
+	if symbol != "" {
+		return sign + symbol + number
+	}
+	return strings.TrimSpace(sign + number + " " + code)
+}
+
+// ParseDecimal converts a decimal string such as "12.34" into minor units. It
+// returns ErrNotRepresentable when the value needs more fractional digits than
+// minorUnits; trailing zeros beyond the allowed precision are accepted.
+func ParseDecimal(value string, minorUnits int) (int64, error) {
+	s := strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	whole, fraction, hasPoint := strings.Cut(s, ".")
+	if whole == "" && fraction == "" || !isDigits(whole) || !isDigits(fraction) || hasPoint && fraction == "" {
+		return 0, fmt.Errorf("invalid decimal amount %q", value)
+	}
+
+	trimmed := strings.TrimRight(fraction, "0")
+	if len(trimmed) > minorUnits {
+		return 0, fmt.Errorf("%w: %q has more than %d decimal places", ErrNotRepresentable, value, minorUnits)
+	}
+	fraction = trimmed + strings.Repeat("0", minorUnits-len(trimmed))
+
+	var amount int64
+	for _, digit := range whole + fraction {
+		if amount > (math.MaxInt64-9)/10 {
+			return 0, fmt.Errorf("decimal amount %q is out of range", value)
+		}
+		amount = amount*10 + int64(digit-'0')
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// isDigits reports whether s consists only of ASCII digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+// Package pagination implements opaque keyset page tokens. A token records the
+// sort keys and ID of the last row of a page, a hash of the filters the page was
+// listed with and an expiry, and is signed with HMAC-SHA256 so clients cannot
+// forge or alter it.
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/clock"
+)
+
+const (
+	// DefaultPageSize is the page size used when a request does not specify one
+	DefaultPageSize = 50
+	// MaxPageSize is the largest page returned; larger page sizes are coerced
+	// down to it
+	MaxPageSize = 1000
+)
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed or carry a bad signature
+	ErrInvalidToken = errors.New("invalid page token")
+	// ErrExpiredToken is returned for tokens past their expiry
+	ErrExpiredToken = errors.New("page token has expired")
+	// ErrFilterMismatch is returned for tokens reused with different filters
+	ErrFilterMismatch = errors.New("page token was issued for different filters")
+)
+
+// Cursor identifies the last row of a page in a list ordered by one or more
+// sort keys, with the row ID breaking ties between equal keys
+type Cursor struct {
+	Keys []string `json:"k"`
+	ID   string   `json:"i"`
+}
+
+// IsZero reports whether the cursor points before the first row
+func (c Cursor) IsZero() bool {
+	return len(c.Keys) == 0 && c.ID == ""
+}
+
+// payload is the signed content of a page token
+type payload struct {
+	Cursor
+	Filter string `json:"f"` // hash of the filters the token was issued for
+	Expiry int64  `json:"e"` // Unix seconds
+}
+
+// Page holds the parsed pagination parameters of a List request
+type Page struct {
+	Size      int64  // maximum number of rows to return
+	After     Cursor // zero for the first page
+	Filter    string // hash of the request filters
+	SkipTotal bool   // whether the caller opted out of counting every matching row
+}
+
+// Codec issues and verifies page tokens
+type Codec struct {
+	secret []byte
+	ttl    time.Duration
+	clock  clock.Clock
+}
+
+// NewCodec creates a new Codec signing tokens with secret that expire after
+// ttl. An empty secret is replaced by a random one, so tokens do not survive a
+// restart.
+func NewCodec(secret []byte, ttl time.Duration, clock clock.Clock) (*Codec, error) {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate page token secret: %w", err)
+		}
+	}
+	return &Codec{
+		secret: secret,
+		ttl:    ttl,
+		clock:  clock,
+	}, nil
+}
+
+// Filter hashes the scope of a List RPC and its filter values, so a token
+// issued for one list cannot be replayed against another
+func Filter(scope string, values ...string) string {
+	h := sha256.New()
+	h.Write([]byte(scope))
+	for _, value := range values {
+		h.Write([]byte{0})
+		h.Write([]byte(value))
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:12])
+}
+
+// Parse validates a page size and token for the given filter hash. A page size
+// of 0 selects DefaultPageSize and one above MaxPageSize is clamped to it.
+func (c *Codec) Parse(pageSize int32, token, filter string) (Page, error) {
+	page := Page{Size: DefaultPageSize, Filter: filter}
+	if pageSize < 0 {
+		return Page{}, fmt.Errorf("page size must not be negative")
+	}
+	if pageSize > 0 {
+		page.Size = min(int64(pageSize), MaxPageSize)
+	}
+	if token != "" {
+		after, err := c.Decode(token, filter)
+		if err != nil {
+			return Page{}, err
+		}
+		page.After = after
+	}
+	return page, nil
+}
+
+// Encode issues a token for the page after cursor
+func (c *Codec) Encode(cursor Cursor, filter string) (string, error) {
+	data, err := json.Marshal(payload{
+		Cursor: cursor,
+		Filter: filter,
+		Expiry: c.clock.Now().Add(c.ttl).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode page token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(c.sign(data)), nil
+}
+
+// Decode verifies a token and returns its cursor
+func (c *Codec) Decode(token, filter string) (Cursor, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Cursor{}, ErrInvalidToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.sign(data)) {
+		return Cursor{}, ErrInvalidToken
+	}
+
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Cursor{}, ErrInvalidToken
+	}
+	if p.Filter != filter {
+		return Cursor{}, ErrFilterMismatch
+	}
+	if c.clock.Now().Unix() > p.Expiry {
+		return Cursor{}, ErrExpiredToken
+	}
+	return p.Cursor, nil
+}
+
+// sign computes the HMAC-SHA256 of a token payload
+func (c *Codec) sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
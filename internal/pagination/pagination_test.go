@@ -0,0 +1,131 @@
+package pagination
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/clock"
+)
+
+// TestParse tests validating page sizes and tokens
+func TestParse(t *testing.T) {
+	testClock := clock.NewDefaultMockClock()
+	codec, err := NewCodec([]byte("test-secret"), time.Hour, testClock)
+	if err != nil {
+		t.Fatalf("Failed to create codec: %v", err)
+	}
+	cursor := Cursor{Keys: []string{"Cash"}, ID: "inst_cash"}
+	token, err := codec.Encode(cursor, "instruments")
+	if err != nil {
+		t.Fatalf("Failed to encode token: %v", err)
+	}
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		pageSize    int32
+		token       string
+		filter      string
+		advance     time.Duration
+		expectSize  int64
+		expectAfter Cursor
+		expectError error
+		expectFail  bool
+	}{
+		{
+			name:       "Default page size",
+			filter:     "instruments",
+			expectSize: DefaultPageSize,
+		},
+		{
+			name:       "Requested page size",
+			pageSize:   10,
+			filter:     "instruments",
+			expectSize: 10,
+		},
+		{
+			name:       "Maximum page size",
+			pageSize:   MaxPageSize,
+			filter:     "instruments",
+			expectSize: MaxPageSize,
+		},
+		{
+			name:       "Page size above the maximum is clamped",
+			pageSize:   MaxPageSize + 1,
+			filter:     "instruments",
+			expectSize: MaxPageSize,
+		},
+		{
+			name:       "Largest page size is clamped",
+			pageSize:   1<<31 - 1,
+			filter:     "instruments",
+			expectSize: MaxPageSize,
+		},
+		{
+			name:       "Negative page size",
+			pageSize:   -1,
+			filter:     "instruments",
+			expectFail: true,
+		},
+		{
+			name:        "Token for the same filter",
+			pageSize:    10,
+			token:       token,
+			filter:      "instruments",
+			expectSize:  10,
+			expectAfter: cursor,
+		},
+		{
+			name:        "Token for other filters",
+			token:       token,
+			filter:      "users",
+			expectError: ErrFilterMismatch,
+		},
+		{
+			name:        "Tampered token",
+			token:       "x" + token,
+			filter:      "instruments",
+			expectError: ErrInvalidToken,
+		},
+		{
+			name:        "Expired token",
+			token:       token,
+			filter:      "instruments",
+			advance:     2 * time.Hour,
+			expectError: ErrExpiredToken,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			testClock.SetTime(clock.NewDefaultMockClock().Now().Add(tc.advance))
+
+			page, err := codec.Parse(tc.pageSize, tc.token, tc.filter)
+
+			if tc.expectError != nil || tc.expectFail {
+				if err == nil {
+					t.Fatalf("Expected an error, got page %+v", page)
+				}
+				if tc.expectError != nil && !errors.Is(err, tc.expectError) {
+					t.Errorf("Expected %v, got %v", tc.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if page.Size != tc.expectSize {
+				t.Errorf("Expected page size %d, got %d", tc.expectSize, page.Size)
+			}
+			if page.After.ID != tc.expectAfter.ID || !slices.Equal(page.After.Keys, tc.expectAfter.Keys) {
+				t.Errorf("Expected cursor %+v, got %+v", tc.expectAfter, page.After)
+			}
+			if page.Filter != tc.filter {
+				t.Errorf("Expected filter %q, got %q", tc.filter, page.Filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// AccountRepo provides direct access to account-related database operations
+type AccountRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewAccountRepo creates a new AccountRepo
+func NewAccountRepo(dbConn *sqlx.DB) *AccountRepo {
+	return &AccountRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *AccountRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// CreateAccount creates a new account within the provided DBTX
+func (r *AccountRepo) CreateAccount(ctx context.Context, dbtx db.DBTX, arg db.CreateAccountParams) (db.Account, error) {
+	queries := r.queries(dbtx)
+	account, err := queries.CreateAccount(ctx, arg)
+	if err != nil {
+		return db.Account{}, fmt.Errorf("failed to create account: %w", TranslateError(err))
+	}
+	return account, nil
+}
+
+// GetAccount retrieves an account by ID in a workspace within the provided DBTX
+func (r *AccountRepo) GetAccount(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.Account, error) {
+	queries := r.queries(dbtx)
+	account, err := queries.GetAccount(ctx, db.GetAccountParams{ID: id, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Account{}, fmt.Errorf("account not found: %w", errors.ErrNotFound)
+		}
+		return db.Account{}, fmt.Errorf("failed to get account: %w", TranslateError(err))
+	}
+	return account, nil
+}
+
+// AccountFields is the allowlist of account fields ListAccounts may filter and
+// order by
+var AccountFields = &filter.Schema[db.Account]{
+	Fields: map[string]filter.Field[db.Account]{
+		"name":            {Column: "name", Type: filter.String, Key: func(row db.Account) any { return row.Name }},
+		"description":     {Column: "description", Type: filter.String},
+		"account_type_id": {Column: "account_type_id", Type: filter.String, Key: func(row db.Account) any { return row.AccountTypeID }},
+		"instrument_id":   {Column: "instrument_id", Type: filter.String},
+		"institution_id":  {Column: "institution_id", Type: filter.String},
+		"currency_id":     {Column: "currency_id", Type: filter.String},
+		"created_at":      {Column: "created_at", Type: filter.Timestamp, Key: func(row db.Account) any { return row.CreatedAt }},
+		"updated_at":      {Column: "updated_at", Type: filter.Timestamp, Key: func(row db.Account) any { return row.UpdatedAt }},
+	},
+	IDColumn:     "id",
+	DefaultOrder: "name",
+}
+
+// ListAccounts retrieves up to limit accounts matching q in its order, starting
+// after its page cursor, within the provided DBTX
+func (r *AccountRepo) ListAccounts(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Account], limit int64) ([]db.Account, error) {
+	accounts, err := listPage(ctx, dbtx, "*", "accounts", q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", TranslateError(err))
+	}
+	return accounts, nil
+}
+
+// CountAccounts counts the accounts matching the filter of q within the
+// provided DBTX
+func (r *AccountRepo) CountAccounts(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Account]) (int64, error) {
+	count, err := countRows(ctx, dbtx, "accounts", q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count accounts: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// accountColumns are the columns of accounts UpdateAccount may change
+var accountColumns = []string{"name", "description", "account_type_id", "instrument_id", "institution_id", "currency_id"}
+
+// UpdateAccount sets the changed columns of an account at a revision within the
+// provided DBTX. It returns errors.ErrConflict if the account is no longer at that
+// revision.
+func (r *AccountRepo) UpdateAccount(ctx context.Context, dbtx db.DBTX, id string, revision int64, changes map[string]any) (db.Account, error) {
+	account, err := updateRow[db.Account](ctx, dbtx, "accounts", accountColumns, id, revision, changes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Account{}, fmt.Errorf("account was modified concurrently: %w", errors.ErrConflict)
+		}
+		return db.Account{}, fmt.Errorf("failed to update account: %w", TranslateError(err))
+	}
+	return account, nil
+}
+
+// DeleteAccount deletes an account at a revision and its user links within the
+// provided DBTX. It returns errors.ErrConflict if the account is no longer at
+// that revision.
+func (r *AccountRepo) DeleteAccount(ctx context.Context, dbtx db.DBTX, id string, revision int64) error {
+	queries := r.queries(dbtx)
+	// Foreign keys are not enforced on the connection, so the ON DELETE CASCADE
+	// on account_users has to be applied by hand
+	if err := queries.RemoveAllAccountUsers(ctx, id); err != nil {
+		return fmt.Errorf("failed to remove account users: %w", TranslateError(err))
+	}
+	rows, err := queries.DeleteAccount(ctx, db.DeleteAccountParams{ID: id, Revision: revision})
+	if err != nil {
+		return fmt.Errorf("failed to delete account: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("account was modified concurrently: %w", errors.ErrConflict)
+	}
+	return nil
+}
+
+// CountLedgerEntries returns the number of ledger entries posted to an account within the provided DBTX
+func (r *AccountRepo) CountLedgerEntries(ctx context.Context, dbtx db.DBTX, id string) (int64, error) {
+	queries := r.queries(dbtx)
+	count, err := queries.CountLedgerEntriesForAccount(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count ledger entries: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// GetAccountType retrieves an account type by ID within the provided DBTX
+func (r *AccountRepo) GetAccountType(ctx context.Context, dbtx db.DBTX, id string) (db.AccountType, error) {
+	queries := r.queries(dbtx)
+	accountType, err := queries.GetAccountType(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.AccountType{}, fmt.Errorf("account type not found: %w", errors.ErrNotFound)
+		}
+		return db.AccountType{}, fmt.Errorf("failed to get account type: %w", TranslateError(err))
+	}
+	return accountType, nil
+}
+
+// AddAccountUser links a user to an account within the provided DBTX
+func (r *AccountRepo) AddAccountUser(ctx context.Context, dbtx db.DBTX, accountID, userID string) (db.AccountUser, error) {
+	queries := r.queries(dbtx)
+	accountUser, err := queries.AddAccountUser(ctx, db.AddAccountUserParams{
+		AccountID: accountID,
+		UserID:    userID,
+	})
+	if err != nil {
+		return db.AccountUser{}, fmt.Errorf("failed to add account user: %w", TranslateError(err))
+	}
+	return accountUser, nil
+}
+
+// RemoveAccountUser unlinks a user from an account within the provided DBTX
+func (r *AccountRepo) RemoveAccountUser(ctx context.Context, dbtx db.DBTX, accountID, userID string) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.RemoveAccountUser(ctx, db.RemoveAccountUserParams{
+		AccountID: accountID,
+		UserID:    userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove account user: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("account user not found: %w", errors.ErrNotFound)
+	}
+	return nil
+}
+
+// ListAccountUsers retrieves the users linked to an account within the provided DBTX
+func (r *AccountRepo) ListAccountUsers(ctx context.Context, dbtx db.DBTX, accountID string) ([]db.User, error) {
+	queries := r.queries(dbtx)
+	users, err := queries.ListAccountUsers(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account users: %w", TranslateError(err))
+	}
+	return users, nil
+}
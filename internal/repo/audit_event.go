@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditRepo provides direct access to audit event database operations
+type AuditRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewAuditRepo creates a new AuditRepo
+func NewAuditRepo(dbConn *sqlx.DB) *AuditRepo {
+	return &AuditRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *AuditRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// CreateAuditEvent records an audit event within the provided DBTX, which
+// should be the transaction making the audited mutation
+func (r *AuditRepo) CreateAuditEvent(ctx context.Context, dbtx db.DBTX, arg db.CreateAuditEventParams) (db.AuditEvent, error) {
+	queries := r.queries(dbtx)
+	event, err := queries.CreateAuditEvent(ctx, arg)
+	if err != nil {
+		return db.AuditEvent{}, fmt.Errorf("failed to create audit event: %w", TranslateError(err))
+	}
+	return event, nil
+}
+
+// AuditEventFields is the allowlist of audit event fields ListAuditEvents may
+// filter and order by
+var AuditEventFields = &filter.Schema[db.AuditEvent]{
+	Fields: map[string]filter.Field[db.AuditEvent]{
+		"resource_type": {Column: "resource_type", Type: filter.String, Key: func(row db.AuditEvent) any { return row.ResourceType }},
+		"resource_id":   {Column: "resource_id", Type: filter.String, Key: func(row db.AuditEvent) any { return row.ResourceID }},
+		"action":        {Column: "action", Type: filter.String, Key: func(row db.AuditEvent) any { return row.Action }},
+		"actor":         {Column: "actor", Type: filter.String, Key: func(row db.AuditEvent) any { return row.Actor }},
+		"request_id":    {Column: "request_id", Type: filter.String, Key: func(row db.AuditEvent) any { return row.RequestID }},
+		"occurred_at":   {Column: "occurred_at", Type: filter.Timestamp, Key: func(row db.AuditEvent) any { return row.OccurredAt }},
+	},
+	IDColumn:     "id",
+	DefaultOrder: "occurred_at desc",
+}
+
+// ListAuditEvents retrieves up to limit audit events matching q in its order,
+// starting after its page cursor, within the provided DBTX
+func (r *AuditRepo) ListAuditEvents(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.AuditEvent], limit int64) ([]db.AuditEvent, error) {
+	events, err := listPage(ctx, dbtx, "*", "audit_events", q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", TranslateError(err))
+	}
+	return events, nil
+}
+
+// CountAuditEvents counts the audit events matching the filter of q within the
+// provided DBTX
+func (r *AuditRepo) CountAuditEvents(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.AuditEvent]) (int64, error) {
+	count, err := countRows(ctx, dbtx, "audit_events", q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit events: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// ListAuditEventsAfter retrieves up to limit audit events recorded after the
+// event with the given ID, oldest first, within the provided DBTX
+func (r *AuditRepo) ListAuditEventsAfter(ctx context.Context, dbtx db.DBTX, id, limit int64) ([]db.AuditEvent, error) {
+	queries := r.queries(dbtx)
+	events, err := queries.ListAuditEventsAfter(ctx, db.ListAuditEventsAfterParams{ID: id, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", TranslateError(err))
+	}
+	return events, nil
+}
+
+// ListLatestAuditEvents retrieves the limit most recently recorded audit
+// events, newest first, within the provided DBTX
+func (r *AuditRepo) ListLatestAuditEvents(ctx context.Context, dbtx db.DBTX, limit int64) ([]db.AuditEvent, error) {
+	queries := r.queries(dbtx)
+	events, err := queries.ListLatestAuditEvents(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list latest audit events: %w", TranslateError(err))
+	}
+	return events, nil
+}
@@ -0,0 +1,174 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// AuthRepo provides direct access to API token and session database operations
+type AuthRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewAuthRepo creates a new AuthRepo
+func NewAuthRepo(dbConn *sqlx.DB) *AuthRepo {
+	return &AuthRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *AuthRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// CreateAPIToken creates a new API token within the provided DBTX
+func (r *AuthRepo) CreateAPIToken(ctx context.Context, dbtx db.DBTX, arg db.CreateApiTokenParams) (db.ApiToken, error) {
+	queries := r.queries(dbtx)
+	token, err := queries.CreateApiToken(ctx, arg)
+	if err != nil {
+		return db.ApiToken{}, fmt.Errorf("failed to create api token: %w", TranslateError(err))
+	}
+	return token, nil
+}
+
+// GetAPIToken retrieves an API token of a user by ID within the provided DBTX
+func (r *AuthRepo) GetAPIToken(ctx context.Context, dbtx db.DBTX, id, userID string) (db.ApiToken, error) {
+	queries := r.queries(dbtx)
+	token, err := queries.GetApiToken(ctx, db.GetApiTokenParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.ApiToken{}, fmt.Errorf("api token not found: %w", errors.ErrNotFound)
+		}
+		return db.ApiToken{}, fmt.Errorf("failed to get api token: %w", TranslateError(err))
+	}
+	return token, nil
+}
+
+// AuthenticateAPIToken retrieves the API token with the given hash if it is
+// neither revoked nor expired at now and its user is not in the trash, within
+// the provided DBTX
+func (r *AuthRepo) AuthenticateAPIToken(ctx context.Context, dbtx db.DBTX, tokenHash string, now time.Time) (db.ApiToken, error) {
+	queries := r.queries(dbtx)
+	token, err := queries.AuthenticateApiToken(ctx, db.AuthenticateApiTokenParams{
+		TokenHash: tokenHash,
+		Now:       &now,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.ApiToken{}, fmt.Errorf("api token not found: %w", errors.ErrNotFound)
+		}
+		return db.ApiToken{}, fmt.Errorf("failed to authenticate api token: %w", TranslateError(err))
+	}
+	return token, nil
+}
+
+// APITokenFields is the allowlist of API token fields ListAPITokens may filter
+// and order by
+var APITokenFields = &filter.Schema[db.ApiToken]{
+	Fields: map[string]filter.Field[db.ApiToken]{
+		"user_id":    {Column: "user_id", Type: filter.String, Key: func(row db.ApiToken) any { return row.UserID }},
+		"name":       {Column: "name", Type: filter.String, Key: func(row db.ApiToken) any { return row.Name }},
+		"created_at": {Column: "created_at", Type: filter.Timestamp, Key: func(row db.ApiToken) any { return row.CreatedAt }},
+		"expires_at": {Column: "expires_at", Type: filter.Timestamp},
+	},
+	IDColumn:     "id",
+	DefaultOrder: "created_at desc",
+}
+
+// activeAPITokens is the table expression of the API tokens not revoked
+const activeAPITokens = "(SELECT * FROM api_tokens WHERE revoked_at IS NULL) AS api_tokens"
+
+// ListAPITokens retrieves up to limit unrevoked API tokens matching q in its
+// order, starting after its page cursor, within the provided DBTX
+func (r *AuthRepo) ListAPITokens(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.ApiToken], limit int64) ([]db.ApiToken, error) {
+	tokens, err := listPage(ctx, dbtx, "*", activeAPITokens, q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", TranslateError(err))
+	}
+	return tokens, nil
+}
+
+// CountAPITokens counts the unrevoked API tokens matching the filter of q
+// within the provided DBTX
+func (r *AuthRepo) CountAPITokens(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.ApiToken]) (int64, error) {
+	count, err := countRows(ctx, dbtx, activeAPITokens, q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count api tokens: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// RevokeAPIToken revokes an unrevoked API token of a user within the provided
+// DBTX
+func (r *AuthRepo) RevokeAPIToken(ctx context.Context, dbtx db.DBTX, id, userID string, revokedAt time.Time) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.RevokeApiToken(ctx, db.RevokeApiTokenParams{
+		RevokedAt: &revokedAt,
+		ID:        id,
+		UserID:    userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke api token: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("api token not found: %w", errors.ErrNotFound)
+	}
+	return nil
+}
+
+// CreateSession creates a new session within the provided DBTX
+func (r *AuthRepo) CreateSession(ctx context.Context, dbtx db.DBTX, arg db.CreateSessionParams) (db.Session, error) {
+	queries := r.queries(dbtx)
+	session, err := queries.CreateSession(ctx, arg)
+	if err != nil {
+		return db.Session{}, fmt.Errorf("failed to create session: %w", TranslateError(err))
+	}
+	return session, nil
+}
+
+// AuthenticateSession retrieves the session with the given hash if it is
+// neither revoked nor expired at now and its user is not in the trash, within
+// the provided DBTX
+func (r *AuthRepo) AuthenticateSession(ctx context.Context, dbtx db.DBTX, tokenHash string, now time.Time) (db.Session, error) {
+	queries := r.queries(dbtx)
+	session, err := queries.AuthenticateSession(ctx, db.AuthenticateSessionParams{
+		TokenHash: tokenHash,
+		Now:       now,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Session{}, fmt.Errorf("session not found: %w", errors.ErrNotFound)
+		}
+		return db.Session{}, fmt.Errorf("failed to authenticate session: %w", TranslateError(err))
+	}
+	return session, nil
+}
+
+// RevokeSession revokes an unrevoked session within the provided DBTX
+func (r *AuthRepo) RevokeSession(ctx context.Context, dbtx db.DBTX, id string, revokedAt time.Time) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.RevokeSession(ctx, db.RevokeSessionParams{
+		RevokedAt: &revokedAt,
+		ID:        id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("session not found: %w", errors.ErrNotFound)
+	}
+	return nil
+}
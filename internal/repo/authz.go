@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// AuthzRepo provides direct access to the database operations authorizing
+// requests: the roles of users, their workspaces and the accounts resources
+// are posted on
+type AuthzRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewAuthzRepo creates a new AuthzRepo
+func NewAuthzRepo(dbConn *sqlx.DB) *AuthzRepo {
+	return &AuthzRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *AuthzRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// GetUserAdmin reports whether a user not in the trash is a server admin within
+// the provided DBTX
+func (r *AuthzRepo) GetUserAdmin(ctx context.Context, dbtx db.DBTX, userID string) (bool, error) {
+	queries := r.queries(dbtx)
+	admin, err := queries.GetUserAdmin(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, fmt.Errorf("user not found: %w", errors.ErrNotFound)
+		}
+		return false, fmt.Errorf("failed to get user admin: %w", TranslateError(err))
+	}
+	return admin, nil
+}
+
+// ResolveWorkspaceUser retrieves the membership of a user in a workspace, or in
+// the workspace they joined first if workspaceID is empty, within the provided
+// DBTX
+func (r *AuthzRepo) ResolveWorkspaceUser(ctx context.Context, dbtx db.DBTX, userID, workspaceID string) (db.WorkspaceUser, error) {
+	queries := r.queries(dbtx)
+	member, err := queries.ResolveWorkspaceUser(ctx, db.ResolveWorkspaceUserParams{UserID: userID, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.WorkspaceUser{}, fmt.Errorf("user does not belong to the workspace: %w", errors.ErrNotFound)
+		}
+		return db.WorkspaceUser{}, fmt.Errorf("failed to resolve workspace: %w", TranslateError(err))
+	}
+	return member, nil
+}
+
+// ListMemberAccountIDs retrieves the IDs of the accounts of a workspace a user
+// belongs to within the provided DBTX
+func (r *AuthzRepo) ListMemberAccountIDs(ctx context.Context, dbtx db.DBTX, workspaceID, userID string) ([]string, error) {
+	queries := r.queries(dbtx)
+	ids, err := queries.ListMemberAccountIDs(ctx, db.ListMemberAccountIDsParams{UserID: userID, WorkspaceID: workspaceID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member accounts: %w", TranslateError(err))
+	}
+	return ids, nil
+}
+
+// ListTransactionAccountIDs retrieves the IDs of the accounts the ledger
+// entries of a transaction of a workspace are posted on within the provided
+// DBTX. A missing transaction has none.
+func (r *AuthzRepo) ListTransactionAccountIDs(ctx context.Context, dbtx db.DBTX, workspaceID, transactionID string) ([]string, error) {
+	queries := r.queries(dbtx)
+	ids, err := queries.ListTransactionAccountIDs(ctx, db.ListTransactionAccountIDsParams{TransactionID: transactionID, WorkspaceID: workspaceID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transaction accounts: %w", TranslateError(err))
+	}
+	return ids, nil
+}
+
+// ListReconciliationAccountIDs retrieves the ID of the account a
+// reconciliation of a workspace counted within the provided DBTX. A missing
+// reconciliation has none.
+func (r *AuthzRepo) ListReconciliationAccountIDs(ctx context.Context, dbtx db.DBTX, workspaceID, reconciliationID string) ([]string, error) {
+	queries := r.queries(dbtx)
+	ids, err := queries.ListReconciliationAccountIDs(ctx, db.ListReconciliationAccountIDsParams{ID: reconciliationID, WorkspaceID: workspaceID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reconciliation accounts: %w", TranslateError(err))
+	}
+	return ids, nil
+}
+
+// memberAccounts selects the IDs of the accounts a user belongs to
+const memberAccounts = "SELECT account_id FROM account_users WHERE user_id = ?"
+
+// AccountsOfMember narrows ListAccounts and CountAccounts to the accounts
+// userID belongs to
+func AccountsOfMember(userID string) filter.Clause {
+	return filter.Clause{SQL: "id IN (" + memberAccounts + ")", Args: []any{userID}}
+}
+
+// TransactionsOfMember narrows ListTransactions and CountTransactions to the
+// transactions whose ledger entries are all posted on accounts userID belongs
+// to
+func TransactionsOfMember(userID string) filter.Clause {
+	return filter.Clause{
+		SQL:  "NOT EXISTS (SELECT 1 FROM ledger_entries WHERE ledger_entries.transaction_id = transactions.id AND ledger_entries.account_id NOT IN (" + memberAccounts + "))",
+		Args: []any{userID},
+	}
+}
+
+// ReconciliationsOfMember narrows ListReconciliations and
+// CountReconciliations to the reconciliations of accounts userID belongs to
+func ReconciliationsOfMember(userID string) filter.Clause {
+	return filter.Clause{SQL: "reconciliations.account_id IN (" + memberAccounts + ")", Args: []any{userID}}
+}
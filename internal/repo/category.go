@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// CategoryRepo provides direct access to category-related database operations
+type CategoryRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewCategoryRepo creates a new CategoryRepo
+func NewCategoryRepo(dbConn *sqlx.DB) *CategoryRepo {
+	return &CategoryRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *CategoryRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// CreateCategory creates a new category within the provided DBTX
+func (r *CategoryRepo) CreateCategory(ctx context.Context, dbtx db.DBTX, arg db.CreateCategoryParams) (db.Category, error) {
+	queries := r.queries(dbtx)
+	category, err := queries.CreateCategory(ctx, arg)
+	if err != nil {
+		return db.Category{}, fmt.Errorf("failed to create category: %w", TranslateError(err))
+	}
+	return category, nil
+}
+
+// GetCategory retrieves a category by ID in a workspace within the provided DBTX
+func (r *CategoryRepo) GetCategory(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.Category, error) {
+	queries := r.queries(dbtx)
+	category, err := queries.GetCategory(ctx, db.GetCategoryParams{ID: id, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Category{}, fmt.Errorf("category not found: %w", errors.ErrNotFound)
+		}
+		return db.Category{}, fmt.Errorf("failed to get category: %w", TranslateError(err))
+	}
+	return category, nil
+}
+
+// CategoryFields is the allowlist of category fields ListCategories may filter
+// and order by
+var CategoryFields = &filter.Schema[db.Category]{
+	Fields: map[string]filter.Field[db.Category]{
+		"name":        {Column: "name", Type: filter.String, Key: func(row db.Category) any { return row.Name }},
+		"description": {Column: "description", Type: filter.String},
+		"parent_id":   {Column: "parent_id", Type: filter.String},
+		"created_at":  {Column: "created_at", Type: filter.Timestamp, Key: func(row db.Category) any { return row.CreatedAt }},
+		"updated_at":  {Column: "updated_at", Type: filter.Timestamp, Key: func(row db.Category) any { return row.UpdatedAt }},
+	},
+	IDColumn:     "id",
+	DefaultOrder: "name",
+}
+
+// ListCategories retrieves up to limit categories matching q in its order,
+// starting after its page cursor, within the provided DBTX
+func (r *CategoryRepo) ListCategories(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Category], limit int64) ([]db.Category, error) {
+	categories, err := listPage(ctx, dbtx, "*", "categories", q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", TranslateError(err))
+	}
+	return categories, nil
+}
+
+// CountCategories counts the categories matching the filter of q within the
+// provided DBTX
+func (r *CategoryRepo) CountCategories(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Category]) (int64, error) {
+	count, err := countRows(ctx, dbtx, "categories", q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count categories: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// categoryColumns are the columns of categories UpdateCategory may change
+var categoryColumns = []string{"parent_id", "name", "description"}
+
+// UpdateCategory sets the changed columns of a category at a revision within the
+// provided DBTX. It returns errors.ErrConflict if the category is no longer at that
+// revision.
+func (r *CategoryRepo) UpdateCategory(ctx context.Context, dbtx db.DBTX, id string, revision int64, changes map[string]any) (db.Category, error) {
+	category, err := updateRow[db.Category](ctx, dbtx, "categories", categoryColumns, id, revision, changes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Category{}, fmt.Errorf("category was modified concurrently: %w", errors.ErrConflict)
+		}
+		return db.Category{}, fmt.Errorf("failed to update category: %w", TranslateError(err))
+	}
+	return category, nil
+}
+
+// MoveCategory changes the parent of a category at a revision within the
+// provided DBTX. It returns errors.ErrConflict if the category is no longer at
+// that revision.
+func (r *CategoryRepo) MoveCategory(ctx context.Context, dbtx db.DBTX, id string, revision int64, parentID *string) (db.Category, error) {
+	queries := r.queries(dbtx)
+	category, err := queries.MoveCategory(ctx, db.MoveCategoryParams{
+		ParentID: parentID,
+		ID:       id,
+		Revision: revision,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Category{}, fmt.Errorf("category was modified concurrently: %w", errors.ErrConflict)
+		}
+		return db.Category{}, fmt.Errorf("failed to move category: %w", TranslateError(err))
+	}
+	return category, nil
+}
+
+// DeleteCategory deletes a category at a revision within the provided DBTX. It
+// returns errors.ErrConflict if the category is no longer at that revision.
+func (r *CategoryRepo) DeleteCategory(ctx context.Context, dbtx db.DBTX, id string, revision int64) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.DeleteCategory(ctx, db.DeleteCategoryParams{ID: id, Revision: revision})
+	if err != nil {
+		return fmt.Errorf("failed to delete category: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("category was modified concurrently: %w", errors.ErrConflict)
+	}
+	return nil
+}
+
+// ListSubtree retrieves a category and all of its descendants within the
+// provided DBTX, ordered by depth with the category itself at depth 0
+func (r *CategoryRepo) ListSubtree(ctx context.Context, dbtx db.DBTX, id string) ([]db.ListCategorySubtreeRow, error) {
+	queries := r.queries(dbtx)
+	rows, err := queries.ListCategorySubtree(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list category subtree: %w", TranslateError(err))
+	}
+	return rows, nil
+}
+
+// ListForest retrieves every category of a workspace reachable from a
+// top-level category within the provided DBTX, ordered by depth
+func (r *CategoryRepo) ListForest(ctx context.Context, dbtx db.DBTX, workspaceID string) ([]db.ListCategoryForestRow, error) {
+	queries := r.queries(dbtx)
+	rows, err := queries.ListCategoryForest(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list category tree: %w", TranslateError(err))
+	}
+	return rows, nil
+}
+
+// CountChildren counts the direct children of a category within the provided DBTX
+func (r *CategoryRepo) CountChildren(ctx context.Context, dbtx db.DBTX, id string) (int64, error) {
+	queries := r.queries(dbtx)
+	count, err := queries.CountCategoryChildren(ctx, &id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count child categories: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// CountReferences counts the transactions and ledger entries that reference a
+// category within the provided DBTX
+func (r *CategoryRepo) CountReferences(ctx context.Context, dbtx db.DBTX, id string) (int64, error) {
+	queries := r.queries(dbtx)
+	count, err := queries.CountCategoryReferences(ctx, &id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count category references: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// ReassignChildren moves the direct children of a category under a new parent
+// within the provided DBTX. A nil parent makes them top-level categories.
+func (r *CategoryRepo) ReassignChildren(ctx context.Context, dbtx db.DBTX, id string, newParentID *string) error {
+	queries := r.queries(dbtx)
+	err := queries.ReassignCategoryChildren(ctx, db.ReassignCategoryChildrenParams{
+		NewParentID: newParentID,
+		ParentID:    &id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reassign child categories: %w", TranslateError(err))
+	}
+	return nil
+}
+
+// ListTransactions retrieves the transactions that reference a category
+// themselves or through one of their ledger entries within the provided DBTX
+func (r *CategoryRepo) ListTransactions(ctx context.Context, dbtx db.DBTX, id string) ([]db.Transaction, error) {
+	queries := r.queries(dbtx)
+	transactions, err := queries.ListCategoryTransactions(ctx, &id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list category transactions: %w", TranslateError(err))
+	}
+	return transactions, nil
+}
+
+// ReassignReferences points the transactions and ledger entries of a category
+// at another category within the provided DBTX, bumping the revision of every
+// transaction ListTransactions returns for it. A nil category clears them.
+func (r *CategoryRepo) ReassignReferences(ctx context.Context, dbtx db.DBTX, id string, newCategoryID *string) error {
+	queries := r.queries(dbtx)
+	err := queries.ReassignTransactionCategory(ctx, db.ReassignTransactionCategoryParams{
+		NewCategoryID: newCategoryID,
+		CategoryID:    &id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reassign transaction categories: %w", TranslateError(err))
+	}
+	err = queries.ReassignLedgerEntryCategory(ctx, db.ReassignLedgerEntryCategoryParams{
+		NewCategoryID: newCategoryID,
+		CategoryID:    &id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reassign ledger entry categories: %w", TranslateError(err))
+	}
+	return nil
+}
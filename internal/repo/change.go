@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// ChangeRepo provides direct access to change log database operations
+type ChangeRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewChangeRepo creates a new ChangeRepo
+func NewChangeRepo(dbConn *sqlx.DB) *ChangeRepo {
+	return &ChangeRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *ChangeRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// CreateChange records a change within the provided DBTX, which should be the
+// transaction making the change
+func (r *ChangeRepo) CreateChange(ctx context.Context, dbtx db.DBTX, arg db.CreateChangeParams) error {
+	queries := r.queries(dbtx)
+	if err := queries.CreateChange(ctx, arg); err != nil {
+		return fmt.Errorf("failed to create change: %w", TranslateError(err))
+	}
+	return nil
+}
+
+// ListChangesAfter retrieves up to limit changes recorded after the change
+// with the given seq, oldest first, within the provided DBTX
+func (r *ChangeRepo) ListChangesAfter(ctx context.Context, dbtx db.DBTX, seq, limit int64) ([]db.Change, error) {
+	queries := r.queries(dbtx)
+	changes, err := queries.ListChangesAfter(ctx, db.ListChangesAfterParams{Seq: seq, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", TranslateError(err))
+	}
+	return changes, nil
+}
+
+// GetChangeBounds retrieves the seq of the first and last change in the log,
+// both 0 if it is empty, within the provided DBTX
+func (r *ChangeRepo) GetChangeBounds(ctx context.Context, dbtx db.DBTX) (first, last int64, err error) {
+	queries := r.queries(dbtx)
+	bounds, err := queries.GetChangeBounds(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get change bounds: %w", TranslateError(err))
+	}
+	return bounds.FirstSeq, bounds.LastSeq, nil
+}
+
+// PruneChanges deletes the changes recorded before a cutoff, keeping the
+// newest change, within the provided DBTX and returns how many were deleted
+func (r *ChangeRepo) PruneChanges(ctx context.Context, dbtx db.DBTX, before time.Time) (int64, error) {
+	queries := r.queries(dbtx)
+	pruned, err := queries.PruneChanges(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune changes: %w", TranslateError(err))
+	}
+	return pruned, nil
+}
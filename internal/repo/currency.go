@@ -0,0 +1,223 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// CurrencyRepo provides direct access to currency-related database operations
+type CurrencyRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewCurrencyRepo creates a new CurrencyRepo
+func NewCurrencyRepo(dbConn *sqlx.DB) *CurrencyRepo {
+	return &CurrencyRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *CurrencyRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// CreateCurrency creates a new currency within the provided DBTX
+func (r *CurrencyRepo) CreateCurrency(ctx context.Context, dbtx db.DBTX, arg db.CreateCurrencyParams) (db.Currency, error) {
+	queries := r.queries(dbtx)
+	currency, err := queries.CreateCurrency(ctx, arg)
+	if err != nil {
+		return db.Currency{}, fmt.Errorf("failed to create currency: %w", TranslateError(err))
+	}
+	return currency, nil
+}
+
+// GetCurrency retrieves a currency by ID in a workspace within the provided DBTX
+func (r *CurrencyRepo) GetCurrency(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.Currency, error) {
+	queries := r.queries(dbtx)
+	currency, err := queries.GetCurrency(ctx, db.GetCurrencyParams{ID: id, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Currency{}, fmt.Errorf("currency not found: %w", errors.ErrNotFound)
+		}
+		return db.Currency{}, fmt.Errorf("failed to get currency: %w", TranslateError(err))
+	}
+	return currency, nil
+}
+
+// GetCurrencyByCode retrieves a currency by its code in a workspace within the
+// provided DBTX
+func (r *CurrencyRepo) GetCurrencyByCode(ctx context.Context, dbtx db.DBTX, workspaceID, code string) (db.Currency, error) {
+	queries := r.queries(dbtx)
+	currency, err := queries.GetCurrencyByCode(ctx, db.GetCurrencyByCodeParams{Code: code, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Currency{}, fmt.Errorf("currency not found: %w", errors.ErrNotFound)
+		}
+		return db.Currency{}, fmt.Errorf("failed to get currency: %w", TranslateError(err))
+	}
+	return currency, nil
+}
+
+// CurrencyFields is the allowlist of currency fields ListCurrencies may filter
+// and order by
+var CurrencyFields = &filter.Schema[db.Currency]{
+	Fields: map[string]filter.Field[db.Currency]{
+		"code":        {Column: "code", Type: filter.String, Key: func(row db.Currency) any { return row.Code }},
+		"name":        {Column: "name", Type: filter.String, Key: func(row db.Currency) any { return row.Name }},
+		"minor_units": {Column: "minor_units", Type: filter.Integer, Key: func(row db.Currency) any { return row.MinorUnits }},
+		"symbol":      {Column: "symbol", Type: filter.String, Key: func(row db.Currency) any { return row.Symbol }},
+		"created_at":  {Column: "created_at", Type: filter.Timestamp, Key: func(row db.Currency) any { return row.CreatedAt }},
+		"updated_at":  {Column: "updated_at", Type: filter.Timestamp, Key: func(row db.Currency) any { return row.UpdatedAt }},
+	},
+	IDColumn:     "id",
+	DefaultOrder: "code",
+}
+
+// ListCurrencies retrieves up to limit currencies matching q in its order,
+// starting after its page cursor, within the provided DBTX
+func (r *CurrencyRepo) ListCurrencies(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Currency], limit int64) ([]db.Currency, error) {
+	currencies, err := listPage(ctx, dbtx, "*", liveRows("currencies"), q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list currencies: %w", TranslateError(err))
+	}
+	return currencies, nil
+}
+
+// CountCurrencies counts the currencies matching the filter of q within the
+// provided DBTX
+func (r *CurrencyRepo) CountCurrencies(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Currency]) (int64, error) {
+	count, err := countRows(ctx, dbtx, liveRows("currencies"), q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count currencies: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// currencyColumns are the columns of currencies UpdateCurrency may change
+var currencyColumns = []string{"name", "minor_units", "symbol"}
+
+// UpdateCurrency sets the changed columns of a currency at a revision within the
+// provided DBTX. It returns errors.ErrConflict if the currency is no longer at that
+// revision.
+func (r *CurrencyRepo) UpdateCurrency(ctx context.Context, dbtx db.DBTX, id string, revision int64, changes map[string]any) (db.Currency, error) {
+	currency, err := updateRow[db.Currency](ctx, dbtx, "currencies", currencyColumns, id, revision, changes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Currency{}, fmt.Errorf("currency was modified concurrently: %w", errors.ErrConflict)
+		}
+		return db.Currency{}, fmt.Errorf("failed to update currency: %w", TranslateError(err))
+	}
+	return currency, nil
+}
+
+// DeleteCurrency moves a currency at a revision to the trash within the provided DBTX.
+// It returns errors.ErrConflict if the currency is no longer at that revision.
+func (r *CurrencyRepo) DeleteCurrency(ctx context.Context, dbtx db.DBTX, id string, revision int64, deletedAt time.Time) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.DeleteCurrency(ctx, db.DeleteCurrencyParams{DeletedAt: &deletedAt, ID: id, Revision: revision})
+	if err != nil {
+		return fmt.Errorf("failed to delete currency: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("currency was modified concurrently: %w", errors.ErrConflict)
+	}
+	return nil
+}
+
+// GetDeletedCurrency retrieves a currency in the trash by ID in a workspace within the provided DBTX
+func (r *CurrencyRepo) GetDeletedCurrency(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.Currency, error) {
+	queries := r.queries(dbtx)
+	currency, err := queries.GetDeletedCurrency(ctx, db.GetDeletedCurrencyParams{ID: id, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Currency{}, fmt.Errorf("deleted currency not found: %w", errors.ErrNotFound)
+		}
+		return db.Currency{}, fmt.Errorf("failed to get deleted currency: %w", TranslateError(err))
+	}
+	return currency, nil
+}
+
+// ListDeletedCurrencies retrieves up to limit currencies in the trash matching q in its
+// order, starting after its page cursor, within the provided DBTX
+func (r *CurrencyRepo) ListDeletedCurrencies(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Currency], limit int64) ([]db.Currency, error) {
+	currencies, err := listPage(ctx, dbtx, "*", trashedRows("currencies"), q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted currencies: %w", TranslateError(err))
+	}
+	return currencies, nil
+}
+
+// CountDeletedCurrencies counts the currencies in the trash matching the filter of q
+// within the provided DBTX
+func (r *CurrencyRepo) CountDeletedCurrencies(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Currency]) (int64, error) {
+	count, err := countRows(ctx, dbtx, trashedRows("currencies"), q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted currencies: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// UndeleteCurrency restores a currency at a revision from the trash within the provided
+// DBTX. It returns errors.ErrConflict if the currency is no longer at that revision.
+func (r *CurrencyRepo) UndeleteCurrency(ctx context.Context, dbtx db.DBTX, id string, revision int64) (db.Currency, error) {
+	queries := r.queries(dbtx)
+	currency, err := queries.UndeleteCurrency(ctx, db.UndeleteCurrencyParams{ID: id, Revision: revision})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Currency{}, fmt.Errorf("deleted currency was modified concurrently: %w", errors.ErrConflict)
+		}
+		return db.Currency{}, fmt.Errorf("failed to undelete currency: %w", TranslateError(err))
+	}
+	return currency, nil
+}
+
+// PurgeCurrencies permanently deletes the currencies moved to the trash at or before
+// deletedBefore within the provided DBTX and returns how many were deleted
+func (r *CurrencyRepo) PurgeCurrencies(ctx context.Context, dbtx db.DBTX, deletedBefore time.Time) (int64, error) {
+	queries := r.queries(dbtx)
+	purged, err := queries.PurgeCurrencies(ctx, &deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge currencies: %w", TranslateError(err))
+	}
+	return purged, nil
+}
+
+// CountAccounts counts the accounts denominated in a currency within the provided DBTX
+func (r *CurrencyRepo) CountAccounts(ctx context.Context, dbtx db.DBTX, id string) (int64, error) {
+	queries := r.queries(dbtx)
+	count, err := queries.CountAccountsForCurrency(ctx, &id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count accounts for currency: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// CountLedgerEntries counts the ledger entries posted in a currency within the provided DBTX
+func (r *CurrencyRepo) CountLedgerEntries(ctx context.Context, dbtx db.DBTX, id string) (int64, error) {
+	queries := r.queries(dbtx)
+	count, err := queries.CountLedgerEntriesForCurrency(ctx, &id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count ledger entries for currency: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// CountReconciliations counts the reconciliations recorded in a currency within the provided DBTX
+func (r *CurrencyRepo) CountReconciliations(ctx context.Context, dbtx db.DBTX, id string) (int64, error) {
+	queries := r.queries(dbtx)
+	count, err := queries.CountReconciliationsForCurrency(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reconciliations for currency: %w", TranslateError(err))
+	}
+	return count, nil
+}
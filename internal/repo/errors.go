@@ -0,0 +1,113 @@
+package repo
+
+import (
+	stderrors "errors"
+	"strings"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLSTATEs PostgreSQL reports the errors TranslateError classifies with
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgCheckViolation       = "23514"
+	pgNotNullViolation     = "23502"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgLockNotAvailable     = "55P03"
+)
+
+// TranslateError classifies a SQLite or PostgreSQL error as an
+// *errors.DatabaseError naming the offending table and columns or constraint.
+// Errors it does not classify, and those it already has, are returned as they
+// are.
+func TranslateError(err error) error {
+	var dbErr *errors.DatabaseError
+	if err == nil || stderrors.As(err, &dbErr) {
+		return err
+	}
+	var sqliteErr sqlite3.Error
+	if stderrors.As(err, &sqliteErr) {
+		return translateSQLite(sqliteErr, err)
+	}
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) {
+		return translatePostgres(pgErr, err)
+	}
+	return err
+}
+
+// translateSQLite classifies a SQLite error by its extended code. SQLite only
+// names the offending columns or constraint in the message, as in "UNIQUE
+// constraint failed: users.email".
+func translateSQLite(sqliteErr sqlite3.Error, err error) error {
+	var kind error
+	switch {
+	case sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked:
+		return &errors.DatabaseError{Kind: errors.ErrUnavailable, Err: err}
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		kind = errors.ErrDuplicate
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+		kind = errors.ErrForeignKey
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintCheck:
+		kind = errors.ErrCheckViolation
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintNotNull:
+		kind = errors.ErrNotNull
+	default:
+		return err
+	}
+
+	translated := &errors.DatabaseError{Kind: kind, Err: err}
+	_, detail, ok := strings.Cut(sqliteErr.Error(), "constraint failed: ")
+	if !ok {
+		return translated
+	}
+	if kind == errors.ErrCheckViolation {
+		translated.Constraint = detail
+		return translated
+	}
+	for _, qualified := range strings.Split(detail, ", ") {
+		table, column, ok := strings.Cut(qualified, ".")
+		if !ok {
+			column = table
+			table = ""
+		}
+		translated.Table = table
+		translated.Columns = append(translated.Columns, column)
+	}
+	return translated
+}
+
+// translatePostgres classifies a PostgreSQL error by its SQLSTATE, taking the
+// columns of key violations from the detail, as in "Key (email)=(a@b.c)
+// already exists."
+func translatePostgres(pgErr *pgconn.PgError, err error) error {
+	translated := &errors.DatabaseError{Table: pgErr.TableName, Constraint: pgErr.ConstraintName, Err: err}
+	switch pgErr.Code {
+	case pgSerializationFailure, pgDeadlockDetected, pgLockNotAvailable:
+		return &errors.DatabaseError{Kind: errors.ErrUnavailable, Err: err}
+	case pgUniqueViolation:
+		translated.Kind = errors.ErrDuplicate
+	case pgForeignKeyViolation:
+		translated.Kind = errors.ErrForeignKey
+	case pgCheckViolation:
+		translated.Kind = errors.ErrCheckViolation
+		return translated
+	case pgNotNullViolation:
+		translated.Kind = errors.ErrNotNull
+		translated.Columns = []string{pgErr.ColumnName}
+		return translated
+	default:
+		return err
+	}
+
+	if rest, ok := strings.CutPrefix(pgErr.Detail, "Key ("); ok {
+		if columns, _, ok := strings.Cut(rest, ")="); ok {
+			translated.Columns = strings.Split(columns, ", ")
+		}
+	}
+	return translated
+}
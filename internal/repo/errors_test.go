@@ -0,0 +1,170 @@
+package repo
+
+import (
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// TestTranslateError tests the classification of database errors
+func TestTranslateError(t *testing.T) {
+	sqliteDB, err := sqlx.Open(DriverSQLite, ":memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		if err := sqliteDB.Close(); err != nil {
+			t.Errorf("Failed to close database: %v", err)
+		}
+	}()
+	// Every connection to :memory: is a database of its own
+	sqliteDB.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"CREATE TABLE parents (id TEXT PRIMARY KEY)",
+		"CREATE TABLE children (id TEXT PRIMARY KEY, parent_id TEXT REFERENCES parents (id), name TEXT NOT NULL, code TEXT CHECK (code IN ('A', 'B')), UNIQUE (parent_id, name))",
+		"INSERT INTO parents (id) VALUES ('p1')",
+		"INSERT INTO children (id, parent_id, name, code) VALUES ('c1', 'p1', 'Alice', 'A')",
+	} {
+		if _, err := sqliteDB.Exec(stmt); err != nil {
+			t.Fatalf("Failed to create schema: %v", err)
+		}
+	}
+	sqliteErr := func(query string) error {
+		_, err := sqliteDB.Exec(query)
+		if err == nil {
+			t.Fatalf("Expected %q to fail", query)
+		}
+		return fmt.Errorf("failed to insert child: %w", err)
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		kind       error
+		table      string
+		columns    []string
+		constraint string
+	}{
+		{
+			name:    "SQLite unique",
+			err:     sqliteErr("INSERT INTO children (id, parent_id, name) VALUES ('c2', 'p1', 'Alice')"),
+			kind:    errors.ErrDuplicate,
+			table:   "children",
+			columns: []string{"parent_id", "name"},
+		},
+		{
+			name:    "SQLite primary key",
+			err:     sqliteErr("INSERT INTO parents (id) VALUES ('p1')"),
+			kind:    errors.ErrDuplicate,
+			table:   "parents",
+			columns: []string{"id"},
+		},
+		{
+			name: "SQLite foreign key",
+			err:  sqliteErr("INSERT INTO children (id, parent_id, name) VALUES ('c2', 'p2', 'Bob')"),
+			kind: errors.ErrForeignKey,
+		},
+		{
+			name:       "SQLite check",
+			err:        sqliteErr("INSERT INTO children (id, parent_id, name, code) VALUES ('c2', 'p1', 'Bob', 'C')"),
+			kind:       errors.ErrCheckViolation,
+			constraint: "code IN ('A', 'B')",
+		},
+		{
+			name:    "SQLite not null",
+			err:     sqliteErr("INSERT INTO children (id, parent_id) VALUES ('c2', 'p1')"),
+			kind:    errors.ErrNotNull,
+			table:   "children",
+			columns: []string{"name"},
+		},
+		{
+			name: "SQLite busy",
+			err:  sqlite3.Error{Code: sqlite3.ErrBusy},
+			kind: errors.ErrUnavailable,
+		},
+		{
+			name: "SQLite locked",
+			err:  sqlite3.Error{Code: sqlite3.ErrLocked},
+			kind: errors.ErrUnavailable,
+		},
+		{
+			name:       "PostgreSQL unique",
+			err:        &pgconn.PgError{Code: "23505", TableName: "children", ConstraintName: "children_parent_id_name_key", Detail: "Key (parent_id, name)=(p1, Alice) already exists."},
+			kind:       errors.ErrDuplicate,
+			table:      "children",
+			columns:    []string{"parent_id", "name"},
+			constraint: "children_parent_id_name_key",
+		},
+		{
+			name:       "PostgreSQL foreign key",
+			err:        &pgconn.PgError{Code: "23503", TableName: "children", ConstraintName: "children_parent_id_fkey", Detail: `Key (parent_id)=(p2) is not present in table "parents".`},
+			kind:       errors.ErrForeignKey,
+			table:      "children",
+			columns:    []string{"parent_id"},
+			constraint: "children_parent_id_fkey",
+		},
+		{
+			name:       "PostgreSQL check",
+			err:        &pgconn.PgError{Code: "23514", TableName: "children", ConstraintName: "children_code_check"},
+			kind:       errors.ErrCheckViolation,
+			table:      "children",
+			constraint: "children_code_check",
+		},
+		{
+			name:    "PostgreSQL not null",
+			err:     &pgconn.PgError{Code: "23502", TableName: "children", ColumnName: "name"},
+			kind:    errors.ErrNotNull,
+			table:   "children",
+			columns: []string{"name"},
+		},
+		{
+			name: "PostgreSQL serialization failure",
+			err:  &pgconn.PgError{Code: "40001"},
+			kind: errors.ErrUnavailable,
+		},
+		{
+			name: "Unclassified",
+			err:  stderrors.New("disk I/O error"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			translated := TranslateError(tc.err)
+			var dbErr *errors.DatabaseError
+			if !stderrors.As(translated, &dbErr) {
+				if tc.kind != nil {
+					t.Fatalf("Expected a DatabaseError, got %v", translated)
+				}
+				if translated != tc.err {
+					t.Errorf("Expected the error to be returned as it is, got %v", translated)
+				}
+				return
+			}
+			if !stderrors.Is(translated, tc.kind) {
+				t.Errorf("Expected kind %v, got %v", tc.kind, dbErr.Kind)
+			}
+			if !stderrors.Is(translated, tc.err) {
+				t.Errorf("Expected the translated error to wrap %v", tc.err)
+			}
+			if dbErr.Table != tc.table {
+				t.Errorf("Expected table %q, got %q", tc.table, dbErr.Table)
+			}
+			if !slices.Equal(dbErr.Columns, tc.columns) {
+				t.Errorf("Expected columns %q, got %q", tc.columns, dbErr.Columns)
+			}
+			if dbErr.Constraint != tc.constraint {
+				t.Errorf("Expected constraint %q, got %q", tc.constraint, dbErr.Constraint)
+			}
+			if TranslateError(translated) != translated {
+				t.Errorf("Expected a translated error to be returned as it is")
+			}
+		})
+	}
+}
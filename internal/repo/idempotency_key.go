@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// IdempotencyRepo provides direct access to idempotency key database operations
+type IdempotencyRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewIdempotencyRepo creates a new IdempotencyRepo
+func NewIdempotencyRepo(dbConn *sqlx.DB) *IdempotencyRepo {
+	return &IdempotencyRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *IdempotencyRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// ClaimIdempotencyKey records a key for a request in progress within the
+// provided DBTX. It reports false, leaving the existing record untouched, if
+// the key is already claimed.
+func (r *IdempotencyRepo) ClaimIdempotencyKey(ctx context.Context, dbtx db.DBTX, arg db.ClaimIdempotencyKeyParams) (bool, error) {
+	queries := r.queries(dbtx)
+	rows, err := queries.ClaimIdempotencyKey(ctx, arg)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim idempotency key: %w", TranslateError(err))
+	}
+	return rows == 1, nil
+}
+
+// GetIdempotencyKey retrieves the record of a key within the provided DBTX
+func (r *IdempotencyRepo) GetIdempotencyKey(ctx context.Context, dbtx db.DBTX, key string) (db.IdempotencyKey, error) {
+	queries := r.queries(dbtx)
+	record, err := queries.GetIdempotencyKey(ctx, key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.IdempotencyKey{}, fmt.Errorf("idempotency key not found: %w", errors.ErrNotFound)
+		}
+		return db.IdempotencyKey{}, fmt.Errorf("failed to get idempotency key: %w", TranslateError(err))
+	}
+	return record, nil
+}
+
+// CompleteIdempotencyKey stores the outcome of the request holding a key
+// within the provided DBTX
+func (r *IdempotencyRepo) CompleteIdempotencyKey(ctx context.Context, dbtx db.DBTX, arg db.CompleteIdempotencyKeyParams) error {
+	queries := r.queries(dbtx)
+	if err := queries.CompleteIdempotencyKey(ctx, arg); err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", TranslateError(err))
+	}
+	return nil
+}
+
+// DeleteIdempotencyKey releases a key within the provided DBTX
+func (r *IdempotencyRepo) DeleteIdempotencyKey(ctx context.Context, dbtx db.DBTX, key string) error {
+	queries := r.queries(dbtx)
+	if err := queries.DeleteIdempotencyKey(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", TranslateError(err))
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes the keys expired at now within the
+// provided DBTX and returns how many were deleted
+func (r *IdempotencyRepo) DeleteExpiredIdempotencyKeys(ctx context.Context, dbtx db.DBTX, now time.Time) (int64, error) {
+	queries := r.queries(dbtx)
+	deleted, err := queries.DeleteExpiredIdempotencyKeys(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", TranslateError(err))
+	}
+	return deleted, nil
+}
@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// InstitutionRepo provides direct access to institution-related database operations
+type InstitutionRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewInstitutionRepo creates a new InstitutionRepo
+func NewInstitutionRepo(dbConn *sqlx.DB) *InstitutionRepo {
+	return &InstitutionRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *InstitutionRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// CreateInstitution creates a new institution within the provided DBTX
+func (r *InstitutionRepo) CreateInstitution(ctx context.Context, dbtx db.DBTX, arg db.CreateInstitutionParams) (db.Institution, error) {
+	queries := r.queries(dbtx)
+	institution, err := queries.CreateInstitution(ctx, arg)
+	if err != nil {
+		return db.Institution{}, fmt.Errorf("failed to create institution: %w", TranslateError(err))
+	}
+	return institution, nil
+}
+
+// GetInstitution retrieves an institution by ID in a workspace within the provided DBTX
+func (r *InstitutionRepo) GetInstitution(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.Institution, error) {
+	queries := r.queries(dbtx)
+	institution, err := queries.GetInstitution(ctx, db.GetInstitutionParams{ID: id, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Institution{}, fmt.Errorf("institution not found: %w", errors.ErrNotFound)
+		}
+		return db.Institution{}, fmt.Errorf("failed to get institution: %w", TranslateError(err))
+	}
+	return institution, nil
+}
+
+// InstitutionFields is the allowlist of institution fields ListInstitutions may
+// filter and order by
+var InstitutionFields = &filter.Schema[db.Institution]{
+	Fields: map[string]filter.Field[db.Institution]{
+		"name":       {Column: "name", Type: filter.String, Key: func(row db.Institution) any { return row.Name }},
+		"type":       {Column: "type", Type: filter.String, Key: func(row db.Institution) any { return row.Type }},
+		"created_at": {Column: "created_at", Type: filter.Timestamp, Key: func(row db.Institution) any { return row.CreatedAt }},
+		"updated_at": {Column: "updated_at", Type: filter.Timestamp, Key: func(row db.Institution) any { return row.UpdatedAt }},
+	},
+	IDColumn:     "id",
+	DefaultOrder: "name",
+}
+
+// ListInstitutions retrieves up to limit institutions matching q in its order,
+// starting after its page cursor, within the provided DBTX
+func (r *InstitutionRepo) ListInstitutions(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Institution], limit int64) ([]db.Institution, error) {
+	institutions, err := listPage(ctx, dbtx, "*", liveRows("institutions"), q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list institutions: %w", TranslateError(err))
+	}
+	return institutions, nil
+}
+
+// CountInstitutions counts the institutions matching the filter of q within the
+// provided DBTX
+func (r *InstitutionRepo) CountInstitutions(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Institution]) (int64, error) {
+	count, err := countRows(ctx, dbtx, liveRows("institutions"), q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count institutions: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// institutionColumns are the columns of institutions UpdateInstitution may change
+var institutionColumns = []string{"name", "type"}
+
+// UpdateInstitution sets the changed columns of an institution at a revision within the
+// provided DBTX. It returns errors.ErrConflict if the institution is no longer at that
+// revision.
+func (r *InstitutionRepo) UpdateInstitution(ctx context.Context, dbtx db.DBTX, id string, revision int64, changes map[string]any) (db.Institution, error) {
+	institution, err := updateRow[db.Institution](ctx, dbtx, "institutions", institutionColumns, id, revision, changes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Institution{}, fmt.Errorf("institution was modified concurrently: %w", errors.ErrConflict)
+		}
+		return db.Institution{}, fmt.Errorf("failed to update institution: %w", TranslateError(err))
+	}
+	return institution, nil
+}
+
+// DeleteInstitution moves an institution at a revision to the trash within the provided DBTX.
+// It returns errors.ErrConflict if the institution is no longer at that revision.
+func (r *InstitutionRepo) DeleteInstitution(ctx context.Context, dbtx db.DBTX, id string, revision int64, deletedAt time.Time) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.DeleteInstitution(ctx, db.DeleteInstitutionParams{DeletedAt: &deletedAt, ID: id, Revision: revision})
+	if err != nil {
+		return fmt.Errorf("failed to delete institution: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("institution was modified concurrently: %w", errors.ErrConflict)
+	}
+	return nil
+}
+
+// GetDeletedInstitution retrieves an institution in the trash by ID in a workspace within the provided DBTX
+func (r *InstitutionRepo) GetDeletedInstitution(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.Institution, error) {
+	queries := r.queries(dbtx)
+	institution, err := queries.GetDeletedInstitution(ctx, db.GetDeletedInstitutionParams{ID: id, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Institution{}, fmt.Errorf("deleted institution not found: %w", errors.ErrNotFound)
+		}
+		return db.Institution{}, fmt.Errorf("failed to get deleted institution: %w", TranslateError(err))
+	}
+	return institution, nil
+}
+
+// ListDeletedInstitutions retrieves up to limit institutions in the trash matching q in its
+// order, starting after its page cursor, within the provided DBTX
+func (r *InstitutionRepo) ListDeletedInstitutions(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Institution], limit int64) ([]db.Institution, error) {
+	institutions, err := listPage(ctx, dbtx, "*", trashedRows("institutions"), q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted institutions: %w", TranslateError(err))
+	}
+	return institutions, nil
+}
+
+// CountDeletedInstitutions counts the institutions in the trash matching the filter of q
+// within the provided DBTX
+func (r *InstitutionRepo) CountDeletedInstitutions(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Institution]) (int64, error) {
+	count, err := countRows(ctx, dbtx, trashedRows("institutions"), q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted institutions: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// UndeleteInstitution restores an institution at a revision from the trash within the provided
+// DBTX. It returns errors.ErrConflict if the institution is no longer at that revision.
+func (r *InstitutionRepo) UndeleteInstitution(ctx context.Context, dbtx db.DBTX, id string, revision int64) (db.Institution, error) {
+	queries := r.queries(dbtx)
+	institution, err := queries.UndeleteInstitution(ctx, db.UndeleteInstitutionParams{ID: id, Revision: revision})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Institution{}, fmt.Errorf("deleted institution was modified concurrently: %w", errors.ErrConflict)
+		}
+		return db.Institution{}, fmt.Errorf("failed to undelete institution: %w", TranslateError(err))
+	}
+	return institution, nil
+}
+
+// PurgeInstitutions permanently deletes the institutions moved to the trash at or before
+// deletedBefore within the provided DBTX and returns how many were deleted
+func (r *InstitutionRepo) PurgeInstitutions(ctx context.Context, dbtx db.DBTX, deletedBefore time.Time) (int64, error) {
+	queries := r.queries(dbtx)
+	purged, err := queries.PurgeInstitutions(ctx, &deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge institutions: %w", TranslateError(err))
+	}
+	return purged, nil
+}
+
+// ListAccounts retrieves the accounts held at an institution within the provided DBTX
+func (r *InstitutionRepo) ListAccounts(ctx context.Context, dbtx db.DBTX, id string) ([]db.Account, error) {
+	queries := r.queries(dbtx)
+	accounts, err := queries.ListAccountsForInstitution(ctx, &id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list institution accounts: %w", TranslateError(err))
+	}
+	return accounts, nil
+}
@@ -4,22 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
 	db "github.com/atreya2011/expense-manager/internal/repo/gen"
 	"github.com/jmoiron/sqlx"
 )
 
 // InstrumentRepo provides direct access to instrument-related database operations
 type InstrumentRepo struct {
-	db *sqlx.DB // Store the underlying DB pool
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
 }
 
 // NewInstrumentRepo creates a new InstrumentRepo
 func NewInstrumentRepo(dbConn *sqlx.DB) *InstrumentRepo {
 	return &InstrumentRepo{
-		db: dbConn,
+		db:      dbConn,
+		queries: queriesFor(dbConn),
 	}
 }
 
@@ -28,73 +31,147 @@ func (r *InstrumentRepo) GetDB() *sqlx.DB {
 	return r.db
 }
 
-// CreateInstrument creates a new instrument within the provided DBTX
-func (r *InstrumentRepo) CreateInstrument(ctx context.Context, dbtx db.DBTX, name string) (db.Instrument, error) {
-	queries := db.New(dbtx)
-	instrument, err := queries.CreateInstrument(ctx, name)
+// CreateInstrument creates a new instrument in a workspace within the provided
+// DBTX
+func (r *InstrumentRepo) CreateInstrument(ctx context.Context, dbtx db.DBTX, workspaceID, name string) (db.Instrument, error) {
+	queries := r.queries(dbtx)
+	instrument, err := queries.CreateInstrument(ctx, db.CreateInstrumentParams{WorkspaceID: workspaceID, Name: name})
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			return db.Instrument{}, fmt.Errorf("instrument with this name already exists: %w", errors.ErrDuplicate)
-		}
-		return db.Instrument{}, fmt.Errorf("failed to create instrument: %w", err)
+		return db.Instrument{}, fmt.Errorf("failed to create instrument: %w", TranslateError(err))
 	}
 	return instrument, nil
 }
 
-// GetInstrument retrieves a instrument by ID within the provided DBTX
-func (r *InstrumentRepo) GetInstrument(ctx context.Context, dbtx db.DBTX, id string) (db.Instrument, error) {
-	queries := db.New(dbtx)
-	instrument, err := queries.GetInstrument(ctx, id)
+// GetInstrument retrieves a instrument by ID in a workspace within the provided DBTX
+func (r *InstrumentRepo) GetInstrument(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.Instrument, error) {
+	queries := r.queries(dbtx)
+	instrument, err := queries.GetInstrument(ctx, db.GetInstrumentParams{ID: id, WorkspaceID: workspaceID})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return db.Instrument{}, fmt.Errorf("instrument not found: %w", errors.ErrNotFound)
 		}
-		return db.Instrument{}, fmt.Errorf("failed to get instrument: %w", err)
+		return db.Instrument{}, fmt.Errorf("failed to get instrument: %w", TranslateError(err))
 	}
 	return instrument, nil
 }
 
-// ListInstruments retrieves a paginated list of instruments within the provided DBTX
-func (r *InstrumentRepo) ListInstruments(ctx context.Context, dbtx db.DBTX, limit, offset int64) ([]db.Instrument, error) {
-	queries := db.New(dbtx)
-	instruments, err := queries.ListInstruments(ctx, db.ListInstrumentsParams{
-		Limit:  limit,
-		Offset: offset,
-	})
+// InstrumentFields is the allowlist of instrument fields ListInstruments may
+// filter and order by
+var InstrumentFields = &filter.Schema[db.Instrument]{
+	Fields: map[string]filter.Field[db.Instrument]{
+		"name":       {Column: "name", Type: filter.String, Key: func(row db.Instrument) any { return row.Name }},
+		"created_at": {Column: "created_at", Type: filter.Timestamp, Key: func(row db.Instrument) any { return row.CreatedAt }},
+		"updated_at": {Column: "updated_at", Type: filter.Timestamp, Key: func(row db.Instrument) any { return row.UpdatedAt }},
+	},
+	IDColumn:     "id",
+	DefaultOrder: "name",
+}
+
+// ListInstruments retrieves up to limit instruments matching q in its order,
+// starting after its page cursor, within the provided DBTX
+func (r *InstrumentRepo) ListInstruments(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Instrument], limit int64) ([]db.Instrument, error) {
+	instruments, err := listPage(ctx, dbtx, "*", liveRows("instruments"), q, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list instruments: %w", err)
+		return nil, fmt.Errorf("failed to list instruments: %w", TranslateError(err))
 	}
 	return instruments, nil
 }
 
-// UpdateInstrument updates an instrument within the provided DBTX
-func (r *InstrumentRepo) UpdateInstrument(ctx context.Context, dbtx db.DBTX, id, name string) (db.Instrument, error) {
-	queries := db.New(dbtx)
-	instrument, err := queries.UpdateInstrument(ctx, db.UpdateInstrumentParams{
-		ID:   id,
-		Name: name,
-	})
+// CountInstruments counts the instruments matching the filter of q within the
+// provided DBTX
+func (r *InstrumentRepo) CountInstruments(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Instrument]) (int64, error) {
+	count, err := countRows(ctx, dbtx, liveRows("instruments"), q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count instruments: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// instrumentColumns are the columns of instruments UpdateInstrument may change
+var instrumentColumns = []string{"name"}
+
+// UpdateInstrument sets the changed columns of an instrument at a revision within the
+// provided DBTX. It returns errors.ErrConflict if the instrument is no longer at that
+// revision.
+func (r *InstrumentRepo) UpdateInstrument(ctx context.Context, dbtx db.DBTX, id string, revision int64, changes map[string]any) (db.Instrument, error) {
+	instrument, err := updateRow[db.Instrument](ctx, dbtx, "instruments", instrumentColumns, id, revision, changes)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return db.Instrument{}, fmt.Errorf("instrument not found: %w", errors.ErrNotFound)
+			return db.Instrument{}, fmt.Errorf("instrument was modified concurrently: %w", errors.ErrConflict)
 		}
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			return db.Instrument{}, fmt.Errorf("instrument with this name already exists: %w", errors.ErrDuplicate)
+		return db.Instrument{}, fmt.Errorf("failed to update instrument: %w", TranslateError(err))
+	}
+	return instrument, nil
+}
+
+// DeleteInstrument moves an instrument at a revision to the trash within the provided DBTX.
+// It returns errors.ErrConflict if the instrument is no longer at that revision.
+func (r *InstrumentRepo) DeleteInstrument(ctx context.Context, dbtx db.DBTX, id string, revision int64, deletedAt time.Time) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.DeleteInstrument(ctx, db.DeleteInstrumentParams{DeletedAt: &deletedAt, ID: id, Revision: revision})
+	if err != nil {
+		return fmt.Errorf("failed to delete instrument: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("instrument was modified concurrently: %w", errors.ErrConflict)
+	}
+	return nil
+}
+
+// GetDeletedInstrument retrieves an instrument in the trash by ID in a workspace within the provided DBTX
+func (r *InstrumentRepo) GetDeletedInstrument(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.Instrument, error) {
+	queries := r.queries(dbtx)
+	instrument, err := queries.GetDeletedInstrument(ctx, db.GetDeletedInstrumentParams{ID: id, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Instrument{}, fmt.Errorf("deleted instrument not found: %w", errors.ErrNotFound)
 		}
-		return db.Instrument{}, fmt.Errorf("failed to update instrument: %w", err)
+		return db.Instrument{}, fmt.Errorf("failed to get deleted instrument: %w", TranslateError(err))
 	}
 	return instrument, nil
 }
 
-// DeleteInstrument deletes an instrument by ID within the provided DBTX
-func (r *InstrumentRepo) DeleteInstrument(ctx context.Context, dbtx db.DBTX, id string) error {
-	queries := db.New(dbtx)
-	err := queries.DeleteInstrument(ctx, id)
+// ListDeletedInstruments retrieves up to limit instruments in the trash matching q in its
+// order, starting after its page cursor, within the provided DBTX
+func (r *InstrumentRepo) ListDeletedInstruments(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Instrument], limit int64) ([]db.Instrument, error) {
+	instruments, err := listPage(ctx, dbtx, "*", trashedRows("instruments"), q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted instruments: %w", TranslateError(err))
+	}
+	return instruments, nil
+}
+
+// CountDeletedInstruments counts the instruments in the trash matching the filter of q
+// within the provided DBTX
+func (r *InstrumentRepo) CountDeletedInstruments(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Instrument]) (int64, error) {
+	count, err := countRows(ctx, dbtx, trashedRows("instruments"), q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted instruments: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// UndeleteInstrument restores an instrument at a revision from the trash within the provided
+// DBTX. It returns errors.ErrConflict if the instrument is no longer at that revision.
+func (r *InstrumentRepo) UndeleteInstrument(ctx context.Context, dbtx db.DBTX, id string, revision int64) (db.Instrument, error) {
+	queries := r.queries(dbtx)
+	instrument, err := queries.UndeleteInstrument(ctx, db.UndeleteInstrumentParams{ID: id, Revision: revision})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("instrument not found: %w", errors.ErrNotFound)
+			return db.Instrument{}, fmt.Errorf("deleted instrument was modified concurrently: %w", errors.ErrConflict)
 		}
-		return fmt.Errorf("failed to delete instrument: %w", err)
+		return db.Instrument{}, fmt.Errorf("failed to undelete instrument: %w", TranslateError(err))
 	}
-	return nil
+	return instrument, nil
+}
+
+// PurgeInstruments permanently deletes the instruments moved to the trash at or before
+// deletedBefore within the provided DBTX and returns how many were deleted
+func (r *InstrumentRepo) PurgeInstruments(ctx context.Context, dbtx db.DBTX, deletedBefore time.Time) (int64, error) {
+	queries := r.queries(dbtx)
+	purged, err := queries.PurgeInstruments(ctx, &deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge instruments: %w", TranslateError(err))
+	}
+	return purged, nil
 }
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// listPage selects up to limit rows of columns from a table expression,
+// restricted and ordered by q and starting after its page cursor, within the
+// provided DBTX. Table expressions and columns are constants of this package;
+// everything from the request is bound as an argument by q.
+func listPage[T any](ctx context.Context, dbtx db.DBTX, columns, from string, q *filter.Query[T], limit int64) ([]T, error) {
+	d := dialect(dbtx)
+	where := q.Page(d)
+	query := "SELECT " + columns + " FROM " + from + " WHERE " + where.SQL + " ORDER BY " + q.OrderBy(d) + " LIMIT ?"
+	rows, err := dbtx.QueryContext(ctx, rebind(dbtx, query), append(where.Args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []T{}
+	err = sqlx.StructScan(rows, &items)
+	if closeErr := rows.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// countRows counts the rows of a table expression matching the filter of q,
+// ignoring its page cursor, within the provided DBTX
+func countRows[T any](ctx context.Context, dbtx db.DBTX, from string, q *filter.Query[T]) (int64, error) {
+	where := q.Filter(dialect(dbtx))
+	var count int64
+	if err := dbtx.QueryRowContext(ctx, rebind(dbtx, "SELECT COUNT(*) FROM "+from+" WHERE "+where.SQL), where.Args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// dialect returns the filter dialect of the database behind a DBTX, which is
+// SQLite unless it is a PostgreSQL pool or transaction from OpenDB
+func dialect(dbtx db.DBTX) filter.Dialect {
+	if named, ok := dbtx.(interface{ DriverName() string }); ok && named.DriverName() == DriverPostgres {
+		return filter.PostgreSQL
+	}
+	return filter.SQLite
+}
+
+// liveRows is the table expression of the rows of a soft-deletable table that
+// are not in the trash
+func liveRows(table string) string {
+	return "(SELECT * FROM " + table + " WHERE deleted_at IS NULL) AS " + table
+}
+
+// trashedRows is the table expression of the rows of a soft-deletable table
+// that are in the trash
+func trashedRows(table string) string {
+	return "(SELECT * FROM " + table + " WHERE deleted_at IS NOT NULL) AS " + table
+}
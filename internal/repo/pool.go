@@ -4,12 +4,51 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/atreya2011/expense-manager/internal/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3" // Import SQLite driver
 )
 
-// OpenDB creates a new database connection pool
-func OpenDB(path string) (*sqlx.DB, error) {
+// Drivers OpenDB connects with, which are also the driver names of the pools
+// it returns
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+// OpenDB creates a new database connection pool for the configured driver
+func OpenDB(cfg config.DatabaseConfig) (*sqlx.DB, error) {
+	var (
+		db  *sqlx.DB
+		err error
+	)
+	switch cfg.Driver {
+	case DriverSQLite:
+		db, err = openSQLite(cfg.Source())
+	case DriverPostgres:
+		db, err = openPostgres(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q, expected %s or %s", cfg.Driver, DriverSQLite, DriverPostgres)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Test the connection
+	if err := db.Ping(); err != nil {
+		if err := db.Close(); err != nil {
+			return nil, fmt.Errorf("error closing database: %w", err)
+		}
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+
+	return db, nil
+}
+
+// openSQLite opens a SQLite database file
+func openSQLite(path string) (*sqlx.DB, error) {
 	connStr := path
 
 	// Add query parameters if not an in-memory database
@@ -22,7 +61,7 @@ func OpenDB(path string) (*sqlx.DB, error) {
 		connStr = "file::memory:?cache=shared&_busy_timeout=5000"
 	}
 
-	db, err := sqlx.Open("sqlite3", connStr)
+	db, err := sqlx.Open(DriverSQLite, connStr)
 	if err != nil {
 		return nil, fmt.Errorf("error opening database: %w", err)
 	}
@@ -32,13 +71,24 @@ func OpenDB(path string) (*sqlx.DB, error) {
 	db.SetMaxIdleConns(1)
 	db.SetConnMaxLifetime(time.Hour)
 
-	// Test the connection
-	if err := db.Ping(); err != nil {
-		if err := db.Close(); err != nil {
-			return nil, fmt.Errorf("error closing database: %w", err)
-		}
-		return nil, fmt.Errorf("error connecting to database: %w", err)
+	return db, nil
+}
+
+// openPostgres opens a PostgreSQL database through pgx
+func openPostgres(dsn string) (*sqlx.DB, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("error opening database: DATABASE_DSN is required for %s", DriverPostgres)
+	}
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
 	}
+	db := sqlx.NewDb(stdlib.OpenDB(*connConfig), DriverPostgres)
+
+	// Set connection pool settings
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+	db.SetConnMaxLifetime(time.Hour)
 
 	return db, nil
 }
@@ -0,0 +1,616 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	pg "github.com/atreya2011/expense-manager/internal/repo/gen/postgres"
+	"github.com/jmoiron/sqlx"
+)
+
+// The repositories run the queries sqlc generates from db/queries for SQLite,
+// or on PostgreSQL those it generates from db/postgres/queries. The latter
+// take the same arguments and return the same columns, in Go types that only
+// differ from the SQLite ones by package, so postgresQueries runs them behind
+// the SQLite Querier by converting each argument and row. The hand-written
+// queries of this package are written for SQLite and rebound for PostgreSQL.
+
+// rebind rewrites the ? placeholders of a hand-written query to the bind
+// variables of the database behind a DBTX
+func rebind(dbtx db.DBTX, query string) string {
+	if dialect(dbtx) == filter.PostgreSQL {
+		return numberPlaceholders(query)
+	}
+	return query
+}
+
+// numberPlaceholders rewrites the ? and ?NNN placeholders of a query to $NNN,
+// numbering a bare ? one above the largest number so far as SQLite does, and
+// leaving quoted text and comments alone
+func numberPlaceholders(query string) string {
+	var (
+		b    strings.Builder
+		last int
+	)
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"':
+			end := i + 1
+			for end < len(query) && query[end] != c {
+				end++
+			}
+			b.WriteString(query[i:min(end+1, len(query))])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 4
+			}
+			b.WriteString(query[i : i+end+4])
+			i += end + 3
+		case c == '?':
+			end := i + 1
+			n := 0
+			for end < len(query) && query[end] >= '0' && query[end] <= '9' {
+				n = n*10 + int(query[end]-'0')
+				end++
+			}
+			if end == i+1 {
+				n = last + 1
+			}
+			last = max(last, n)
+			fmt.Fprintf(&b, "$%d", n)
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// queriesFor returns the generated queries of the driver of a pool, which its
+// repositories create within the DBTXs they are given
+func queriesFor(dbConn *sqlx.DB) func(db.DBTX) db.Querier {
+	if dbConn.DriverName() == DriverPostgres {
+		return func(dbtx db.DBTX) db.Querier { return postgresQueries{queries: pg.New(dbtx)} }
+	}
+	return func(dbtx db.DBTX) db.Querier { return db.New(dbtx) }
+}
+
+// postgresQueries runs the generated PostgreSQL queries as a db.Querier
+type postgresQueries struct {
+	queries *pg.Queries
+}
+
+var _ db.Querier = postgresQueries{}
+
+// convertRows converts the rows a PostgreSQL query returns to their SQLite
+// types, keeping a nil slice nil
+func convertRows[S, T any](rows []S, convert func(S) T) []T {
+	if rows == nil {
+		return nil
+	}
+	converted := make([]T, len(rows))
+	for i, row := range rows {
+		converted[i] = convert(row)
+	}
+	return converted
+}
+
+// toGetReconciliationRow converts a row embedding a model, which cannot be
+// converted as a whole
+func toGetReconciliationRow(row pg.GetReconciliationRow) db.GetReconciliationRow {
+	return db.GetReconciliationRow{
+		Reconciliation:     db.Reconciliation(row.Reconciliation),
+		CurrencyCode:       row.CurrencyCode,
+		CurrencyMinorUnits: row.CurrencyMinorUnits,
+		CurrencySymbol:     row.CurrencySymbol,
+	}
+}
+
+// toListLedgerEntriesForTransactionsRow converts a row embedding a model
+func toListLedgerEntriesForTransactionsRow(row pg.ListLedgerEntriesForTransactionsRow) db.ListLedgerEntriesForTransactionsRow {
+	return db.ListLedgerEntriesForTransactionsRow{
+		LedgerEntry:        db.LedgerEntry(row.LedgerEntry),
+		CurrencyCode:       row.CurrencyCode,
+		CurrencyMinorUnits: row.CurrencyMinorUnits,
+		CurrencySymbol:     row.CurrencySymbol,
+	}
+}
+
+// toListWorkspaceUsersRow converts a row embedding a model
+func toListWorkspaceUsersRow(row pg.ListWorkspaceUsersRow) db.ListWorkspaceUsersRow {
+	return db.ListWorkspaceUsersRow{
+		User:     db.User(row.User),
+		IsAdmin:  row.IsAdmin,
+		JoinedAt: row.JoinedAt,
+	}
+}
+
+// toListWorkspacesForUserRow converts a row embedding a model
+func toListWorkspacesForUserRow(row pg.ListWorkspacesForUserRow) db.ListWorkspacesForUserRow {
+	return db.ListWorkspacesForUserRow{
+		Workspace: db.Workspace(row.Workspace),
+		IsAdmin:   row.IsAdmin,
+	}
+}
+
+func (q postgresQueries) AddAccountUser(ctx context.Context, arg db.AddAccountUserParams) (db.AccountUser, error) {
+	row, err := q.queries.AddAccountUser(ctx, pg.AddAccountUserParams(arg))
+	return db.AccountUser(row), err
+}
+
+func (q postgresQueries) AddWorkspaceUser(ctx context.Context, arg db.AddWorkspaceUserParams) (db.WorkspaceUser, error) {
+	row, err := q.queries.AddWorkspaceUser(ctx, pg.AddWorkspaceUserParams(arg))
+	return db.WorkspaceUser(row), err
+}
+
+func (q postgresQueries) AuthenticateApiToken(ctx context.Context, arg db.AuthenticateApiTokenParams) (db.ApiToken, error) {
+	row, err := q.queries.AuthenticateApiToken(ctx, pg.AuthenticateApiTokenParams(arg))
+	return db.ApiToken(row), err
+}
+
+func (q postgresQueries) AuthenticateSession(ctx context.Context, arg db.AuthenticateSessionParams) (db.Session, error) {
+	row, err := q.queries.AuthenticateSession(ctx, pg.AuthenticateSessionParams(arg))
+	return db.Session(row), err
+}
+
+func (q postgresQueries) ClaimIdempotencyKey(ctx context.Context, arg db.ClaimIdempotencyKeyParams) (int64, error) {
+	return q.queries.ClaimIdempotencyKey(ctx, pg.ClaimIdempotencyKeyParams(arg))
+}
+
+func (q postgresQueries) ClearReconciliationAdjustments(ctx context.Context, adjustmentTransactionID *string) error {
+	return q.queries.ClearReconciliationAdjustments(ctx, adjustmentTransactionID)
+}
+
+func (q postgresQueries) CompleteIdempotencyKey(ctx context.Context, arg db.CompleteIdempotencyKeyParams) error {
+	return q.queries.CompleteIdempotencyKey(ctx, pg.CompleteIdempotencyKeyParams(arg))
+}
+
+func (q postgresQueries) CountAccountsForCurrency(ctx context.Context, currencyID *string) (int64, error) {
+	return q.queries.CountAccountsForCurrency(ctx, currencyID)
+}
+
+func (q postgresQueries) CountCategoryChildren(ctx context.Context, parentID *string) (int64, error) {
+	return q.queries.CountCategoryChildren(ctx, parentID)
+}
+
+func (q postgresQueries) CountCategoryReferences(ctx context.Context, id *string) (int64, error) {
+	return q.queries.CountCategoryReferences(ctx, id)
+}
+
+func (q postgresQueries) CountLedgerEntriesBetween(ctx context.Context, arg db.CountLedgerEntriesBetweenParams) (int64, error) {
+	return q.queries.CountLedgerEntriesBetween(ctx, pg.CountLedgerEntriesBetweenParams(arg))
+}
+
+func (q postgresQueries) CountLedgerEntriesForAccount(ctx context.Context, accountID string) (int64, error) {
+	return q.queries.CountLedgerEntriesForAccount(ctx, accountID)
+}
+
+func (q postgresQueries) CountLedgerEntriesForCurrency(ctx context.Context, currencyID *string) (int64, error) {
+	return q.queries.CountLedgerEntriesForCurrency(ctx, currencyID)
+}
+
+func (q postgresQueries) CountReconciliationsForCurrency(ctx context.Context, currencyID string) (int64, error) {
+	return q.queries.CountReconciliationsForCurrency(ctx, currencyID)
+}
+
+func (q postgresQueries) CountWorkspaceAdmins(ctx context.Context, workspaceID string) (int64, error) {
+	return q.queries.CountWorkspaceAdmins(ctx, workspaceID)
+}
+
+func (q postgresQueries) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+	row, err := q.queries.CreateAccount(ctx, pg.CreateAccountParams(arg))
+	return db.Account(row), err
+}
+
+func (q postgresQueries) CreateApiToken(ctx context.Context, arg db.CreateApiTokenParams) (db.ApiToken, error) {
+	row, err := q.queries.CreateApiToken(ctx, pg.CreateApiTokenParams(arg))
+	return db.ApiToken(row), err
+}
+
+func (q postgresQueries) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) (db.AuditEvent, error) {
+	row, err := q.queries.CreateAuditEvent(ctx, pg.CreateAuditEventParams(arg))
+	return db.AuditEvent(row), err
+}
+
+func (q postgresQueries) CreateCategory(ctx context.Context, arg db.CreateCategoryParams) (db.Category, error) {
+	row, err := q.queries.CreateCategory(ctx, pg.CreateCategoryParams(arg))
+	return db.Category(row), err
+}
+
+func (q postgresQueries) CreateChange(ctx context.Context, arg db.CreateChangeParams) error {
+	return q.queries.CreateChange(ctx, pg.CreateChangeParams(arg))
+}
+
+func (q postgresQueries) CreateCurrency(ctx context.Context, arg db.CreateCurrencyParams) (db.Currency, error) {
+	row, err := q.queries.CreateCurrency(ctx, pg.CreateCurrencyParams(arg))
+	return db.Currency(row), err
+}
+
+func (q postgresQueries) CreateInstitution(ctx context.Context, arg db.CreateInstitutionParams) (db.Institution, error) {
+	row, err := q.queries.CreateInstitution(ctx, pg.CreateInstitutionParams(arg))
+	return db.Institution(row), err
+}
+
+func (q postgresQueries) CreateInstrument(ctx context.Context, arg db.CreateInstrumentParams) (db.Instrument, error) {
+	row, err := q.queries.CreateInstrument(ctx, pg.CreateInstrumentParams(arg))
+	return db.Instrument(row), err
+}
+
+func (q postgresQueries) CreateLedgerEntry(ctx context.Context, arg db.CreateLedgerEntryParams) (db.LedgerEntry, error) {
+	row, err := q.queries.CreateLedgerEntry(ctx, pg.CreateLedgerEntryParams(arg))
+	return db.LedgerEntry(row), err
+}
+
+func (q postgresQueries) CreateReconciliation(ctx context.Context, arg db.CreateReconciliationParams) (db.Reconciliation, error) {
+	row, err := q.queries.CreateReconciliation(ctx, pg.CreateReconciliationParams(arg))
+	return db.Reconciliation(row), err
+}
+
+func (q postgresQueries) CreateSession(ctx context.Context, arg db.CreateSessionParams) (db.Session, error) {
+	row, err := q.queries.CreateSession(ctx, pg.CreateSessionParams(arg))
+	return db.Session(row), err
+}
+
+func (q postgresQueries) CreateTransaction(ctx context.Context, arg db.CreateTransactionParams) (db.Transaction, error) {
+	row, err := q.queries.CreateTransaction(ctx, pg.CreateTransactionParams(arg))
+	return db.Transaction(row), err
+}
+
+func (q postgresQueries) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	row, err := q.queries.CreateUser(ctx, pg.CreateUserParams(arg))
+	return db.User(row), err
+}
+
+func (q postgresQueries) CreateWorkspace(ctx context.Context, name string) (db.Workspace, error) {
+	row, err := q.queries.CreateWorkspace(ctx, name)
+	return db.Workspace(row), err
+}
+
+func (q postgresQueries) DeleteAccount(ctx context.Context, arg db.DeleteAccountParams) (int64, error) {
+	return q.queries.DeleteAccount(ctx, pg.DeleteAccountParams(arg))
+}
+
+func (q postgresQueries) DeleteCategory(ctx context.Context, arg db.DeleteCategoryParams) (int64, error) {
+	return q.queries.DeleteCategory(ctx, pg.DeleteCategoryParams(arg))
+}
+
+func (q postgresQueries) DeleteCurrency(ctx context.Context, arg db.DeleteCurrencyParams) (int64, error) {
+	return q.queries.DeleteCurrency(ctx, pg.DeleteCurrencyParams(arg))
+}
+
+func (q postgresQueries) DeleteExpiredIdempotencyKeys(ctx context.Context, expiresAt time.Time) (int64, error) {
+	return q.queries.DeleteExpiredIdempotencyKeys(ctx, expiresAt)
+}
+
+func (q postgresQueries) DeleteIdempotencyKey(ctx context.Context, idempotencyKey string) error {
+	return q.queries.DeleteIdempotencyKey(ctx, idempotencyKey)
+}
+
+func (q postgresQueries) DeleteInstitution(ctx context.Context, arg db.DeleteInstitutionParams) (int64, error) {
+	return q.queries.DeleteInstitution(ctx, pg.DeleteInstitutionParams(arg))
+}
+
+func (q postgresQueries) DeleteInstrument(ctx context.Context, arg db.DeleteInstrumentParams) (int64, error) {
+	return q.queries.DeleteInstrument(ctx, pg.DeleteInstrumentParams(arg))
+}
+
+func (q postgresQueries) DeleteLedgerEntriesForTransaction(ctx context.Context, transactionID string) error {
+	return q.queries.DeleteLedgerEntriesForTransaction(ctx, transactionID)
+}
+
+func (q postgresQueries) DeleteTransaction(ctx context.Context, arg db.DeleteTransactionParams) (int64, error) {
+	return q.queries.DeleteTransaction(ctx, pg.DeleteTransactionParams(arg))
+}
+
+func (q postgresQueries) DeleteUser(ctx context.Context, arg db.DeleteUserParams) (int64, error) {
+	return q.queries.DeleteUser(ctx, pg.DeleteUserParams(arg))
+}
+
+func (q postgresQueries) GetAccount(ctx context.Context, arg db.GetAccountParams) (db.Account, error) {
+	row, err := q.queries.GetAccount(ctx, pg.GetAccountParams(arg))
+	return db.Account(row), err
+}
+
+func (q postgresQueries) GetAccountType(ctx context.Context, id string) (db.AccountType, error) {
+	row, err := q.queries.GetAccountType(ctx, id)
+	return db.AccountType(row), err
+}
+
+func (q postgresQueries) GetApiToken(ctx context.Context, arg db.GetApiTokenParams) (db.ApiToken, error) {
+	row, err := q.queries.GetApiToken(ctx, pg.GetApiTokenParams(arg))
+	return db.ApiToken(row), err
+}
+
+func (q postgresQueries) GetCategory(ctx context.Context, arg db.GetCategoryParams) (db.Category, error) {
+	row, err := q.queries.GetCategory(ctx, pg.GetCategoryParams(arg))
+	return db.Category(row), err
+}
+
+func (q postgresQueries) GetChangeBounds(ctx context.Context) (db.GetChangeBoundsRow, error) {
+	row, err := q.queries.GetChangeBounds(ctx)
+	return db.GetChangeBoundsRow(row), err
+}
+
+func (q postgresQueries) GetCurrency(ctx context.Context, arg db.GetCurrencyParams) (db.Currency, error) {
+	row, err := q.queries.GetCurrency(ctx, pg.GetCurrencyParams(arg))
+	return db.Currency(row), err
+}
+
+func (q postgresQueries) GetCurrencyByCode(ctx context.Context, arg db.GetCurrencyByCodeParams) (db.Currency, error) {
+	row, err := q.queries.GetCurrencyByCode(ctx, pg.GetCurrencyByCodeParams(arg))
+	return db.Currency(row), err
+}
+
+func (q postgresQueries) GetDeletedCurrency(ctx context.Context, arg db.GetDeletedCurrencyParams) (db.Currency, error) {
+	row, err := q.queries.GetDeletedCurrency(ctx, pg.GetDeletedCurrencyParams(arg))
+	return db.Currency(row), err
+}
+
+func (q postgresQueries) GetDeletedInstitution(ctx context.Context, arg db.GetDeletedInstitutionParams) (db.Institution, error) {
+	row, err := q.queries.GetDeletedInstitution(ctx, pg.GetDeletedInstitutionParams(arg))
+	return db.Institution(row), err
+}
+
+func (q postgresQueries) GetDeletedInstrument(ctx context.Context, arg db.GetDeletedInstrumentParams) (db.Instrument, error) {
+	row, err := q.queries.GetDeletedInstrument(ctx, pg.GetDeletedInstrumentParams(arg))
+	return db.Instrument(row), err
+}
+
+func (q postgresQueries) GetDeletedUser(ctx context.Context, id string) (db.User, error) {
+	row, err := q.queries.GetDeletedUser(ctx, id)
+	return db.User(row), err
+}
+
+func (q postgresQueries) GetIdempotencyKey(ctx context.Context, idempotencyKey string) (db.IdempotencyKey, error) {
+	row, err := q.queries.GetIdempotencyKey(ctx, idempotencyKey)
+	return db.IdempotencyKey(row), err
+}
+
+func (q postgresQueries) GetInstitution(ctx context.Context, arg db.GetInstitutionParams) (db.Institution, error) {
+	row, err := q.queries.GetInstitution(ctx, pg.GetInstitutionParams(arg))
+	return db.Institution(row), err
+}
+
+func (q postgresQueries) GetInstrument(ctx context.Context, arg db.GetInstrumentParams) (db.Instrument, error) {
+	row, err := q.queries.GetInstrument(ctx, pg.GetInstrumentParams(arg))
+	return db.Instrument(row), err
+}
+
+func (q postgresQueries) GetLedgerBalance(ctx context.Context, arg db.GetLedgerBalanceParams) (int64, error) {
+	return q.queries.GetLedgerBalance(ctx, pg.GetLedgerBalanceParams(arg))
+}
+
+func (q postgresQueries) GetReconciliation(ctx context.Context, arg db.GetReconciliationParams) (db.GetReconciliationRow, error) {
+	row, err := q.queries.GetReconciliation(ctx, pg.GetReconciliationParams(arg))
+	return toGetReconciliationRow(row), err
+}
+
+func (q postgresQueries) GetTransaction(ctx context.Context, arg db.GetTransactionParams) (db.Transaction, error) {
+	row, err := q.queries.GetTransaction(ctx, pg.GetTransactionParams(arg))
+	return db.Transaction(row), err
+}
+
+func (q postgresQueries) GetUser(ctx context.Context, id string) (db.User, error) {
+	row, err := q.queries.GetUser(ctx, id)
+	return db.User(row), err
+}
+
+func (q postgresQueries) GetUserAdmin(ctx context.Context, id string) (bool, error) {
+	return q.queries.GetUserAdmin(ctx, id)
+}
+
+func (q postgresQueries) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	row, err := q.queries.GetUserByEmail(ctx, email)
+	return db.User(row), err
+}
+
+func (q postgresQueries) GetUserInWorkspace(ctx context.Context, arg db.GetUserInWorkspaceParams) (db.User, error) {
+	row, err := q.queries.GetUserInWorkspace(ctx, pg.GetUserInWorkspaceParams(arg))
+	return db.User(row), err
+}
+
+func (q postgresQueries) GetWorkspace(ctx context.Context, id string) (db.Workspace, error) {
+	row, err := q.queries.GetWorkspace(ctx, id)
+	return db.Workspace(row), err
+}
+
+func (q postgresQueries) GetWorkspaceUser(ctx context.Context, arg db.GetWorkspaceUserParams) (db.WorkspaceUser, error) {
+	row, err := q.queries.GetWorkspaceUser(ctx, pg.GetWorkspaceUserParams(arg))
+	return db.WorkspaceUser(row), err
+}
+
+func (q postgresQueries) ListAccountUsers(ctx context.Context, accountID string) ([]db.User, error) {
+	rows, err := q.queries.ListAccountUsers(ctx, accountID)
+	return convertRows(rows, func(row pg.User) db.User { return db.User(row) }), err
+}
+
+func (q postgresQueries) ListAccountsForInstitution(ctx context.Context, institutionID *string) ([]db.Account, error) {
+	rows, err := q.queries.ListAccountsForInstitution(ctx, institutionID)
+	return convertRows(rows, func(row pg.Account) db.Account { return db.Account(row) }), err
+}
+
+func (q postgresQueries) ListAuditEventsAfter(ctx context.Context, arg db.ListAuditEventsAfterParams) ([]db.AuditEvent, error) {
+	rows, err := q.queries.ListAuditEventsAfter(ctx, pg.ListAuditEventsAfterParams(arg))
+	return convertRows(rows, func(row pg.AuditEvent) db.AuditEvent { return db.AuditEvent(row) }), err
+}
+
+func (q postgresQueries) ListCashAccounts(ctx context.Context, arg db.ListCashAccountsParams) ([]db.ListCashAccountsRow, error) {
+	rows, err := q.queries.ListCashAccounts(ctx, pg.ListCashAccountsParams(arg))
+	return convertRows(rows, func(row pg.ListCashAccountsRow) db.ListCashAccountsRow { return db.ListCashAccountsRow(row) }), err
+}
+
+func (q postgresQueries) ListCategoryForest(ctx context.Context, workspaceID string) ([]db.ListCategoryForestRow, error) {
+	rows, err := q.queries.ListCategoryForest(ctx, workspaceID)
+	return convertRows(rows, func(row pg.ListCategoryForestRow) db.ListCategoryForestRow { return db.ListCategoryForestRow(row) }), err
+}
+
+func (q postgresQueries) ListCategorySubtree(ctx context.Context, id string) ([]db.ListCategorySubtreeRow, error) {
+	rows, err := q.queries.ListCategorySubtree(ctx, id)
+	return convertRows(rows, func(row pg.ListCategorySubtreeRow) db.ListCategorySubtreeRow { return db.ListCategorySubtreeRow(row) }), err
+}
+
+func (q postgresQueries) ListCategoryTransactions(ctx context.Context, categoryID *string) ([]db.Transaction, error) {
+	rows, err := q.queries.ListCategoryTransactions(ctx, categoryID)
+	return convertRows(rows, func(row pg.Transaction) db.Transaction { return db.Transaction(row) }), err
+}
+
+func (q postgresQueries) ListChangesAfter(ctx context.Context, arg db.ListChangesAfterParams) ([]db.Change, error) {
+	rows, err := q.queries.ListChangesAfter(ctx, pg.ListChangesAfterParams(arg))
+	return convertRows(rows, func(row pg.Change) db.Change { return db.Change(row) }), err
+}
+
+func (q postgresQueries) ListLatestAuditEvents(ctx context.Context, limit int64) ([]db.AuditEvent, error) {
+	rows, err := q.queries.ListLatestAuditEvents(ctx, limit)
+	return convertRows(rows, func(row pg.AuditEvent) db.AuditEvent { return db.AuditEvent(row) }), err
+}
+
+func (q postgresQueries) ListLedgerBalances(ctx context.Context, arg db.ListLedgerBalancesParams) ([]db.ListLedgerBalancesRow, error) {
+	rows, err := q.queries.ListLedgerBalances(ctx, pg.ListLedgerBalancesParams(arg))
+	return convertRows(rows, func(row pg.ListLedgerBalancesRow) db.ListLedgerBalancesRow { return db.ListLedgerBalancesRow(row) }), err
+}
+
+func (q postgresQueries) ListLedgerEntriesForTransactions(ctx context.Context, transactionIds string) ([]db.ListLedgerEntriesForTransactionsRow, error) {
+	rows, err := q.queries.ListLedgerEntriesForTransactions(ctx, transactionIds)
+	return convertRows(rows, toListLedgerEntriesForTransactionsRow), err
+}
+
+func (q postgresQueries) ListMemberAccountIDs(ctx context.Context, arg db.ListMemberAccountIDsParams) ([]string, error) {
+	return q.queries.ListMemberAccountIDs(ctx, pg.ListMemberAccountIDsParams(arg))
+}
+
+func (q postgresQueries) ListReconciliationAccountIDs(ctx context.Context, arg db.ListReconciliationAccountIDsParams) ([]string, error) {
+	return q.queries.ListReconciliationAccountIDs(ctx, pg.ListReconciliationAccountIDsParams(arg))
+}
+
+func (q postgresQueries) ListReconciliationsForAccount(ctx context.Context, arg db.ListReconciliationsForAccountParams) ([]db.Reconciliation, error) {
+	rows, err := q.queries.ListReconciliationsForAccount(ctx, pg.ListReconciliationsForAccountParams(arg))
+	return convertRows(rows, func(row pg.Reconciliation) db.Reconciliation { return db.Reconciliation(row) }), err
+}
+
+func (q postgresQueries) ListTransactionAccountIDs(ctx context.Context, arg db.ListTransactionAccountIDsParams) ([]string, error) {
+	return q.queries.ListTransactionAccountIDs(ctx, pg.ListTransactionAccountIDsParams(arg))
+}
+
+func (q postgresQueries) ListUnbalancedTransactions(ctx context.Context, arg db.ListUnbalancedTransactionsParams) ([]db.ListUnbalancedTransactionsRow, error) {
+	rows, err := q.queries.ListUnbalancedTransactions(ctx, pg.ListUnbalancedTransactionsParams(arg))
+	return convertRows(rows, func(row pg.ListUnbalancedTransactionsRow) db.ListUnbalancedTransactionsRow {
+		return db.ListUnbalancedTransactionsRow(row)
+	}), err
+}
+
+func (q postgresQueries) ListWorkspaceUsers(ctx context.Context, workspaceID string) ([]db.ListWorkspaceUsersRow, error) {
+	rows, err := q.queries.ListWorkspaceUsers(ctx, workspaceID)
+	return convertRows(rows, toListWorkspaceUsersRow), err
+}
+
+func (q postgresQueries) ListWorkspacesForUser(ctx context.Context, userID string) ([]db.ListWorkspacesForUserRow, error) {
+	rows, err := q.queries.ListWorkspacesForUser(ctx, userID)
+	return convertRows(rows, toListWorkspacesForUserRow), err
+}
+
+func (q postgresQueries) MoveCategory(ctx context.Context, arg db.MoveCategoryParams) (db.Category, error) {
+	row, err := q.queries.MoveCategory(ctx, pg.MoveCategoryParams(arg))
+	return db.Category(row), err
+}
+
+func (q postgresQueries) PruneChanges(ctx context.Context, occurredAt time.Time) (int64, error) {
+	return q.queries.PruneChanges(ctx, occurredAt)
+}
+
+func (q postgresQueries) PurgeCurrencies(ctx context.Context, deletedAt *time.Time) (int64, error) {
+	return q.queries.PurgeCurrencies(ctx, deletedAt)
+}
+
+func (q postgresQueries) PurgeInstitutions(ctx context.Context, deletedAt *time.Time) (int64, error) {
+	return q.queries.PurgeInstitutions(ctx, deletedAt)
+}
+
+func (q postgresQueries) PurgeInstruments(ctx context.Context, deletedAt *time.Time) (int64, error) {
+	return q.queries.PurgeInstruments(ctx, deletedAt)
+}
+
+func (q postgresQueries) PurgeUsers(ctx context.Context, deletedAt *time.Time) (int64, error) {
+	return q.queries.PurgeUsers(ctx, deletedAt)
+}
+
+func (q postgresQueries) ReassignCategoryChildren(ctx context.Context, arg db.ReassignCategoryChildrenParams) error {
+	return q.queries.ReassignCategoryChildren(ctx, pg.ReassignCategoryChildrenParams(arg))
+}
+
+func (q postgresQueries) ReassignLedgerEntryCategory(ctx context.Context, arg db.ReassignLedgerEntryCategoryParams) error {
+	return q.queries.ReassignLedgerEntryCategory(ctx, pg.ReassignLedgerEntryCategoryParams(arg))
+}
+
+func (q postgresQueries) ReassignTransactionCategory(ctx context.Context, arg db.ReassignTransactionCategoryParams) error {
+	return q.queries.ReassignTransactionCategory(ctx, pg.ReassignTransactionCategoryParams(arg))
+}
+
+func (q postgresQueries) RemoveAccountUser(ctx context.Context, arg db.RemoveAccountUserParams) (int64, error) {
+	return q.queries.RemoveAccountUser(ctx, pg.RemoveAccountUserParams(arg))
+}
+
+func (q postgresQueries) RemoveAllAccountUsers(ctx context.Context, accountID string) error {
+	return q.queries.RemoveAllAccountUsers(ctx, accountID)
+}
+
+func (q postgresQueries) RemoveWorkspaceAccountUsers(ctx context.Context, arg db.RemoveWorkspaceAccountUsersParams) error {
+	return q.queries.RemoveWorkspaceAccountUsers(ctx, pg.RemoveWorkspaceAccountUsersParams(arg))
+}
+
+func (q postgresQueries) RemoveWorkspaceUser(ctx context.Context, arg db.RemoveWorkspaceUserParams) (int64, error) {
+	return q.queries.RemoveWorkspaceUser(ctx, pg.RemoveWorkspaceUserParams(arg))
+}
+
+func (q postgresQueries) ResolveWorkspaceUser(ctx context.Context, arg db.ResolveWorkspaceUserParams) (db.WorkspaceUser, error) {
+	row, err := q.queries.ResolveWorkspaceUser(ctx, pg.ResolveWorkspaceUserParams(arg))
+	return db.WorkspaceUser(row), err
+}
+
+func (q postgresQueries) RevokeApiToken(ctx context.Context, arg db.RevokeApiTokenParams) (int64, error) {
+	return q.queries.RevokeApiToken(ctx, pg.RevokeApiTokenParams(arg))
+}
+
+func (q postgresQueries) RevokeSession(ctx context.Context, arg db.RevokeSessionParams) (int64, error) {
+	return q.queries.RevokeSession(ctx, pg.RevokeSessionParams(arg))
+}
+
+func (q postgresQueries) SetUserAdmin(ctx context.Context, arg db.SetUserAdminParams) (int64, error) {
+	return q.queries.SetUserAdmin(ctx, pg.SetUserAdminParams(arg))
+}
+
+func (q postgresQueries) SetWorkspaceUserAdmin(ctx context.Context, arg db.SetWorkspaceUserAdminParams) (int64, error) {
+	return q.queries.SetWorkspaceUserAdmin(ctx, pg.SetWorkspaceUserAdminParams(arg))
+}
+
+func (q postgresQueries) UndeleteCurrency(ctx context.Context, arg db.UndeleteCurrencyParams) (db.Currency, error) {
+	row, err := q.queries.UndeleteCurrency(ctx, pg.UndeleteCurrencyParams(arg))
+	return db.Currency(row), err
+}
+
+func (q postgresQueries) UndeleteInstitution(ctx context.Context, arg db.UndeleteInstitutionParams) (db.Institution, error) {
+	row, err := q.queries.UndeleteInstitution(ctx, pg.UndeleteInstitutionParams(arg))
+	return db.Institution(row), err
+}
+
+func (q postgresQueries) UndeleteInstrument(ctx context.Context, arg db.UndeleteInstrumentParams) (db.Instrument, error) {
+	row, err := q.queries.UndeleteInstrument(ctx, pg.UndeleteInstrumentParams(arg))
+	return db.Instrument(row), err
+}
+
+func (q postgresQueries) UndeleteUser(ctx context.Context, arg db.UndeleteUserParams) (db.User, error) {
+	row, err := q.queries.UndeleteUser(ctx, pg.UndeleteUserParams(arg))
+	return db.User(row), err
+}
@@ -0,0 +1,361 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	pg "github.com/atreya2011/expense-manager/internal/repo/gen/postgres"
+	"github.com/jmoiron/sqlx"
+)
+
+// TestNumberPlaceholders tests the numbering of the placeholders of
+// hand-written queries
+func TestNumberPlaceholders(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "No placeholders",
+			query: "SELECT COUNT(*) FROM accounts",
+			want:  "SELECT COUNT(*) FROM accounts",
+		},
+		{
+			name:  "Bare placeholders",
+			query: "UPDATE accounts SET name = ?, description = ? WHERE id = ? RETURNING *",
+			want:  "UPDATE accounts SET name = $1, description = $2 WHERE id = $3 RETURNING *",
+		},
+		{
+			name:  "Numbered placeholders",
+			query: "SELECT * FROM users WHERE id = ?2 AND email = ?1 OR id = ?2",
+			want:  "SELECT * FROM users WHERE id = $2 AND email = $1 OR id = $2",
+		},
+		{
+			name:  "Bare placeholder after a numbered one",
+			query: "SELECT * FROM api_tokens WHERE token_hash = ?3 AND user_id = ?",
+			want:  "SELECT * FROM api_tokens WHERE token_hash = $3 AND user_id = $4",
+		},
+		{
+			name:  "Quoted text and comments",
+			query: "SELECT '?', \"a?\" FROM t -- why?\nWHERE x = ? /* or ? */ AND y LIKE ? ESCAPE '\\'",
+			want:  "SELECT '?', \"a?\" FROM t -- why?\nWHERE x = $1 /* or ? */ AND y LIKE $2 ESCAPE '\\'",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := numberPlaceholders(tc.query); got != tc.want {
+				t.Errorf("numberPlaceholders() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestPostgresQueries tests that every generated SQLite query has a PostgreSQL
+// counterpart taking the same arguments and returning the same columns in the
+// same order, which postgresQueries relies on
+func TestPostgresQueries(t *testing.T) {
+	sqliteQuerier := reflect.TypeOf((*db.Querier)(nil)).Elem()
+	postgresQuerier := reflect.TypeOf((*pg.Querier)(nil)).Elem()
+	if sqliteQuerier.NumMethod() != postgresQuerier.NumMethod() {
+		t.Errorf("SQLite has %d queries, PostgreSQL %d", sqliteQuerier.NumMethod(), postgresQuerier.NumMethod())
+	}
+
+	for i := 0; i < sqliteQuerier.NumMethod(); i++ {
+		method := sqliteQuerier.Method(i)
+		t.Run(method.Name, func(t *testing.T) {
+			counterpart, ok := postgresQuerier.MethodByName(method.Name)
+			if !ok {
+				t.Fatalf("no PostgreSQL query named %s", method.Name)
+			}
+			if got, want := signature(counterpart.Type), signature(method.Type); !slices.Equal(got, want) {
+				t.Errorf("PostgreSQL signature = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestPostgresQuerier tests that repositories on a PostgreSQL pool run the
+// PostgreSQL queries with their arguments converted and their rows converted
+// back, and rebind their hand-written queries, against a connection recording
+// the statements it is sent
+func TestPostgresQuerier(t *testing.T) {
+	ctx := context.Background()
+	jpy := "cur_jpy"
+	now := time.Date(2025, 4, 26, 12, 0, 0, 0, time.UTC)
+	userColumns := []string{"id", "name", "email", "created_at", "updated_at", "revision", "deleted_at", "is_admin"}
+	user := db.User{ID: "usr_1", Name: "Alice", Email: "alice@example.com", CreatedAt: now, UpdatedAt: now, Revision: 1}
+	listUsers := func(ctx context.Context, dbConn *sqlx.DB) (any, error) {
+		q, err := UserFields.Compile(`name:"A*"`, "")
+		if err != nil {
+			return nil, err
+		}
+		return NewUserRepo(dbConn).ListUsers(ctx, dbConn, q, 10)
+	}
+	listWorkspaces := func(ctx context.Context, dbConn *sqlx.DB) (any, error) {
+		return NewWorkspaceRepo(dbConn).ListWorkspacesForUser(ctx, dbConn, "usr_1")
+	}
+
+	// Define test cases
+	tests := []struct {
+		name         string
+		driver       string
+		columns      []string
+		values       []driver.Value
+		run          func(ctx context.Context, dbConn *sqlx.DB) (any, error)
+		expectQuery  string // part of the statement sent
+		expectArgs   []driver.Value
+		expectResult any
+	}{
+		{
+			name:    "Generated query with nullable arguments and columns",
+			driver:  DriverPostgres,
+			columns: []string{"id", "workspace_id", "name", "description", "account_type_id", "instrument_id", "institution_id", "currency_id", "created_at", "updated_at", "revision"},
+			values:  []driver.Value{"acc_1", "wsp_1", "Cash", nil, "at_asset", nil, nil, "cur_jpy", now, now, int64(1)},
+			run: func(ctx context.Context, dbConn *sqlx.DB) (any, error) {
+				return NewAccountRepo(dbConn).CreateAccount(ctx, dbConn, db.CreateAccountParams{
+					WorkspaceID:   "wsp_1",
+					Name:          "Cash",
+					AccountTypeID: "at_asset",
+					CurrencyID:    &jpy,
+				})
+			},
+			expectQuery: "gen_random_uuid()::text, '-', ''), $1, $2, $3, $4, $5, $6, $7",
+			expectArgs:  []driver.Value{"wsp_1", "Cash", nil, "at_asset", nil, nil, "cur_jpy"},
+			expectResult: db.Account{
+				ID:            "acc_1",
+				WorkspaceID:   "wsp_1",
+				Name:          "Cash",
+				AccountTypeID: "at_asset",
+				CurrencyID:    &jpy,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+				Revision:      1,
+			},
+		},
+		{
+			name:         "Generated query embedding a model",
+			driver:       DriverPostgres,
+			columns:      []string{"id", "name", "created_at", "updated_at", "is_admin"},
+			values:       []driver.Value{"wsp_1", "Home", now, now, true},
+			run:          listWorkspaces,
+			expectQuery:  "WHERE workspace_users.user_id = $1",
+			expectArgs:   []driver.Value{"usr_1"},
+			expectResult: []db.ListWorkspacesForUserRow{{Workspace: db.Workspace{ID: "wsp_1", Name: "Home", CreatedAt: now, UpdatedAt: now}, IsAdmin: true}},
+		},
+		{
+			name:         "Hand-written list query",
+			driver:       DriverPostgres,
+			columns:      userColumns,
+			values:       []driver.Value{"usr_1", "Alice", "alice@example.com", now, now, int64(1), nil, false},
+			run:          listUsers,
+			expectQuery:  `SELECT * FROM (SELECT * FROM users WHERE deleted_at IS NULL) AS users WHERE (name ILIKE $1 ESCAPE '\') ORDER BY name, id LIMIT $2`,
+			expectArgs:   []driver.Value{"A%", int64(10)},
+			expectResult: []db.User{user},
+		},
+		{
+			name:    "Hand-written update",
+			driver:  DriverPostgres,
+			columns: []string{"id", "workspace_id", "name", "created_at", "updated_at", "revision", "deleted_at"},
+			values:  []driver.Value{"inst_1", "wsp_1", "Card", now, now, int64(2), nil},
+			run: func(ctx context.Context, dbConn *sqlx.DB) (any, error) {
+				return NewInstrumentRepo(dbConn).UpdateInstrument(ctx, dbConn, "inst_1", 1, map[string]any{"name": "Card"})
+			},
+			expectQuery:  "UPDATE instruments SET name = $1, updated_at = CURRENT_TIMESTAMP, revision = revision + 1 WHERE id = $2 AND revision = $3 RETURNING *",
+			expectArgs:   []driver.Value{"Card", "inst_1", int64(1)},
+			expectResult: db.Instrument{ID: "inst_1", WorkspaceID: "wsp_1", Name: "Card", CreatedAt: now, UpdatedAt: now, Revision: 2},
+		},
+		{
+			name:   "Change taking the change log lock before its seq",
+			driver: DriverPostgres,
+			run: func(ctx context.Context, dbConn *sqlx.DB) (any, error) {
+				return nil, NewChangeRepo(dbConn).CreateChange(ctx, dbConn, db.CreateChangeParams{
+					WorkspaceID:  "wsp_1",
+					ResourceType: "instrument",
+					ResourceID:   "inst_1",
+					Action:       "update",
+					OccurredAt:   now,
+				})
+			},
+			expectQuery: "FROM (SELECT pg_advisory_xact_lock(hashtext('changes'))) AS change_lock",
+			expectArgs:  []driver.Value{"wsp_1", "instrument", "inst_1", "update", "", now},
+		},
+		{
+			name:         "SQLite pool runs the SQLite queries",
+			driver:       DriverSQLite,
+			columns:      []string{"id", "name", "created_at", "updated_at", "is_admin"},
+			values:       []driver.Value{"wsp_1", "Home", now, now, false},
+			run:          listWorkspaces,
+			expectQuery:  "WHERE workspace_users.user_id = ?",
+			expectArgs:   []driver.Value{"usr_1"},
+			expectResult: []db.ListWorkspacesForUserRow{{Workspace: db.Workspace{ID: "wsp_1", Name: "Home", CreatedAt: now, UpdatedAt: now}}},
+		},
+		{
+			name:         "SQLite pool keeps the hand-written placeholders",
+			driver:       DriverSQLite,
+			columns:      userColumns,
+			values:       []driver.Value{"usr_1", "Alice", "alice@example.com", now, now, int64(1), nil, false},
+			run:          listUsers,
+			expectQuery:  `SELECT * FROM (SELECT * FROM users WHERE deleted_at IS NULL) AS users WHERE (name LIKE ? ESCAPE '\') ORDER BY name, id LIMIT ?`,
+			expectArgs:   []driver.Value{"A%", int64(10)},
+			expectResult: []db.User{user},
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recorder{columns: tc.columns, values: tc.values}
+			dbConn := sqlx.NewDb(sql.OpenDB(rec), tc.driver)
+			defer func() { _ = dbConn.Close() }()
+
+			result, err := tc.run(ctx, dbConn)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !strings.Contains(rec.query, tc.expectQuery) {
+				t.Errorf("Expected a statement containing %q, got %q", tc.expectQuery, rec.query)
+			}
+			if !reflect.DeepEqual(rec.args, tc.expectArgs) {
+				t.Errorf("Expected args %v, got %v", tc.expectArgs, rec.args)
+			}
+			if !reflect.DeepEqual(result, tc.expectResult) {
+				t.Errorf("Expected result %+v, got %+v", tc.expectResult, result)
+			}
+		})
+	}
+}
+
+// recorder is a database/sql connector whose connections record the last
+// statement sent to them and answer it with a single canned row
+type recorder struct {
+	columns []string
+	values  []driver.Value
+	query   string
+	args    []driver.Value
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) {
+	return recorderConn{r}, nil
+}
+
+func (r *recorder) Driver() driver.Driver {
+	return recorderDriver{r}
+}
+
+// recorderDriver opens the connections of a recorder
+type recorderDriver struct {
+	recorder *recorder
+}
+
+func (d recorderDriver) Open(string) (driver.Conn, error) {
+	return recorderConn(d), nil
+}
+
+// recorderConn is a connection of a recorder
+type recorderConn struct {
+	recorder *recorder
+}
+
+func (c recorderConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("recorderConn does not prepare statements")
+}
+
+func (c recorderConn) Close() error {
+	return nil
+}
+
+func (c recorderConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("recorderConn does not begin transactions")
+}
+
+func (c recorderConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query, args)
+	return &recorderRows{columns: c.recorder.columns, values: c.recorder.values}, nil
+}
+
+func (c recorderConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+// record records a statement and its arguments
+func (c recorderConn) record(query string, args []driver.NamedValue) {
+	c.recorder.query = query
+	c.recorder.args = make([]driver.Value, len(args))
+	for i, arg := range args {
+		c.recorder.args[i] = arg.Value
+	}
+}
+
+// recorderRows returns the canned row of a recorder once
+type recorderRows struct {
+	columns []string
+	values  []driver.Value
+	done    bool
+}
+
+func (r *recorderRows) Columns() []string {
+	return r.columns
+}
+
+func (r *recorderRows) Close() error {
+	return nil
+}
+
+func (r *recorderRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	copy(dest, r.values)
+	r.done = true
+	return nil
+}
+
+// signature describes the arguments and results of a query by the field names
+// of generated structs, which must line up between engines
+func signature(method reflect.Type) []string {
+	var parts []string
+	for i := 0; i < method.NumIn(); i++ {
+		parts = append(parts, "in:"+strings.Join(shape(method.In(i)), ","))
+	}
+	for i := 0; i < method.NumOut(); i++ {
+		parts = append(parts, "out:"+strings.Join(shape(method.Out(i)), ","))
+	}
+	return parts
+}
+
+// shape lists the fields of a generated struct, flattening embedded models.
+// Any other type is a single value, since each engine may pick its own Go type
+// for a column, such as *string or sql.NullString.
+func shape(t reflect.Type) []string {
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct {
+		return append([]string{"[]"}, shape(t.Elem())...)
+	}
+	if t.Kind() != reflect.Struct || !strings.Contains(t.PkgPath(), "/internal/repo/gen") {
+		return []string{"value"}
+	}
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type.Kind() == reflect.Struct && strings.Contains(field.Type.PkgPath(), "/internal/repo/gen") {
+			for _, nested := range shape(field.Type) {
+				fields = append(fields, field.Name+"."+nested)
+			}
+			continue
+		}
+		fields = append(fields, field.Name)
+	}
+	return fields
+}
@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// ReconciliationRepo provides direct access to cash reconciliation database operations
+type ReconciliationRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewReconciliationRepo creates a new ReconciliationRepo
+func NewReconciliationRepo(dbConn *sqlx.DB) *ReconciliationRepo {
+	return &ReconciliationRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *ReconciliationRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// CreateReconciliation records a cash count within the provided DBTX
+func (r *ReconciliationRepo) CreateReconciliation(ctx context.Context, dbtx db.DBTX, arg db.CreateReconciliationParams) (db.Reconciliation, error) {
+	queries := r.queries(dbtx)
+	reconciliation, err := queries.CreateReconciliation(ctx, arg)
+	if err != nil {
+		return db.Reconciliation{}, fmt.Errorf("failed to create reconciliation: %w", TranslateError(err))
+	}
+	return reconciliation, nil
+}
+
+// GetReconciliation retrieves a reconciliation and its currency by ID in a workspace within
+// the provided DBTX
+func (r *ReconciliationRepo) GetReconciliation(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.GetReconciliationRow, error) {
+	queries := r.queries(dbtx)
+	reconciliation, err := queries.GetReconciliation(ctx, db.GetReconciliationParams{ID: id, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.GetReconciliationRow{}, fmt.Errorf("reconciliation not found: %w", errors.ErrNotFound)
+		}
+		return db.GetReconciliationRow{}, fmt.Errorf("failed to get reconciliation: %w", TranslateError(err))
+	}
+	return reconciliation, nil
+}
+
+// reconciliationsFrom joins reconciliations with their currency for listing
+const reconciliationsFrom = "reconciliations LEFT JOIN currencies ON currencies.id = reconciliations.currency_id"
+
+// reconciliationColumns selects a db.GetReconciliationRow, aliasing the
+// embedded reconciliation columns the way sqlx maps nested structs
+const reconciliationColumns = `
+  reconciliations.id AS "reconciliation.id",
+  reconciliations.workspace_id AS "reconciliation.workspace_id",
+  reconciliations.account_id AS "reconciliation.account_id",
+  reconciliations.counted_at AS "reconciliation.counted_at",
+  reconciliations.counted_amount AS "reconciliation.counted_amount",
+  reconciliations.ledger_balance AS "reconciliation.ledger_balance",
+  reconciliations.discrepancy AS "reconciliation.discrepancy",
+  reconciliations.currency_id AS "reconciliation.currency_id",
+  reconciliations.adjustment_transaction_id AS "reconciliation.adjustment_transaction_id",
+  reconciliations.notes AS "reconciliation.notes",
+  reconciliations.created_at AS "reconciliation.created_at",
+  reconciliations.updated_at AS "reconciliation.updated_at",
+  COALESCE(currencies.code, '') AS currency_code,
+  CAST(COALESCE(currencies.minor_units, 0) AS INTEGER) AS currency_minor_units,
+  COALESCE(currencies.symbol, '') AS currency_symbol`
+
+// ReconciliationFields is the allowlist of reconciliation fields
+// ListReconciliations may filter and order by
+var ReconciliationFields = &filter.Schema[db.GetReconciliationRow]{
+	Fields: map[string]filter.Field[db.GetReconciliationRow]{
+		"account_id":                {Column: "reconciliations.account_id", Type: filter.String, Key: func(row db.GetReconciliationRow) any { return row.Reconciliation.AccountID }},
+		"counted_at":                {Column: "reconciliations.counted_at", Type: filter.Timestamp, Key: func(row db.GetReconciliationRow) any { return row.Reconciliation.CountedAt }},
+		"counted.amount":            {Column: "reconciliations.counted_amount", Type: filter.Integer, Key: func(row db.GetReconciliationRow) any { return row.Reconciliation.CountedAmount }},
+		"ledger_balance.amount":     {Column: "reconciliations.ledger_balance", Type: filter.Integer, Key: func(row db.GetReconciliationRow) any { return row.Reconciliation.LedgerBalance }},
+		"discrepancy.amount":        {Column: "reconciliations.discrepancy", Type: filter.Integer, Key: func(row db.GetReconciliationRow) any { return row.Reconciliation.Discrepancy }},
+		"currency_id":               {Column: "reconciliations.currency_id", Type: filter.String, Key: func(row db.GetReconciliationRow) any { return row.Reconciliation.CurrencyID }},
+		"adjustment_transaction_id": {Column: "reconciliations.adjustment_transaction_id", Type: filter.String},
+		"notes":                     {Column: "reconciliations.notes", Type: filter.String},
+		"created_at":                {Column: "reconciliations.created_at", Type: filter.Timestamp, Key: func(row db.GetReconciliationRow) any { return row.Reconciliation.CreatedAt }},
+		"updated_at":                {Column: "reconciliations.updated_at", Type: filter.Timestamp, Key: func(row db.GetReconciliationRow) any { return row.Reconciliation.UpdatedAt }},
+	},
+	IDColumn:     "reconciliations.id",
+	DefaultOrder: "counted_at desc",
+}
+
+// ListReconciliations retrieves up to limit reconciliations matching q in its
+// order, starting after its page cursor, within the provided DBTX
+func (r *ReconciliationRepo) ListReconciliations(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.GetReconciliationRow], limit int64) ([]db.GetReconciliationRow, error) {
+	reconciliations, err := listPage(ctx, dbtx, reconciliationColumns, reconciliationsFrom, q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reconciliations: %w", TranslateError(err))
+	}
+	return reconciliations, nil
+}
+
+// CountReconciliations counts the reconciliations matching the filter of q
+// within the provided DBTX
+func (r *ReconciliationRepo) CountReconciliations(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.GetReconciliationRow]) (int64, error) {
+	count, err := countRows(ctx, dbtx, reconciliationsFrom, q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reconciliations: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// ListAccountReconciliations retrieves the counts of an account made on or
+// before asOf, oldest first, within the provided DBTX
+func (r *ReconciliationRepo) ListAccountReconciliations(ctx context.Context, dbtx db.DBTX, accountID string, asOf time.Time) ([]db.Reconciliation, error) {
+	queries := r.queries(dbtx)
+	reconciliations, err := queries.ListReconciliationsForAccount(ctx, db.ListReconciliationsForAccountParams{
+		AccountID: accountID,
+		AsOf:      asOf,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account reconciliations: %w", TranslateError(err))
+	}
+	return reconciliations, nil
+}
+
+// ListCashAccounts retrieves the Asset accounts of a workspace with a default
+// currency within the provided DBTX, optionally restricted to one account
+func (r *ReconciliationRepo) ListCashAccounts(ctx context.Context, dbtx db.DBTX, workspaceID string, accountID *string) ([]db.ListCashAccountsRow, error) {
+	queries := r.queries(dbtx)
+	accounts, err := queries.ListCashAccounts(ctx, db.ListCashAccountsParams{WorkspaceID: workspaceID, AccountID: accountID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cash accounts: %w", TranslateError(err))
+	}
+	return accounts, nil
+}
+
+// GetLedgerBalance computes the debit balance of an account in one currency
+// as of a point in time within the provided DBTX
+func (r *ReconciliationRepo) GetLedgerBalance(ctx context.Context, dbtx db.DBTX, accountID, currencyID string, asOf time.Time) (int64, error) {
+	queries := r.queries(dbtx)
+	balance, err := queries.GetLedgerBalance(ctx, db.GetLedgerBalanceParams{
+		AccountID:  accountID,
+		CurrencyID: &currencyID,
+		AsOf:       asOf,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get ledger balance: %w", TranslateError(err))
+	}
+	return balance, nil
+}
+
+// CountEntriesBetween counts the ledger entries of an account for transactions
+// dated after after and on or before until within the provided DBTX
+func (r *ReconciliationRepo) CountEntriesBetween(ctx context.Context, dbtx db.DBTX, accountID string, after, until time.Time) (int64, error) {
+	queries := r.queries(dbtx)
+	count, err := queries.CountLedgerEntriesBetween(ctx, db.CountLedgerEntriesBetweenParams{
+		AccountID: accountID,
+		AfterDate: after,
+		UntilDate: until,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count ledger entries: %w", TranslateError(err))
+	}
+	return count, nil
+}
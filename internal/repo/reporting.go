@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// ReportingRepo provides read-only aggregate queries over the ledger
+type ReportingRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewReportingRepo creates a new ReportingRepo
+func NewReportingRepo(dbConn *sqlx.DB) *ReportingRepo {
+	return &ReportingRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *ReportingRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// ListLedgerBalances sums the ledger entries of the transactions of a
+// workspace dated between from and to (both inclusive) per account, category
+// and currency within the provided DBTX. Unless memberID is empty, only the
+// transactions whose ledger entries are all posted on accounts memberID
+// belongs to are summed, as TransactionsOfMember lists them.
+func (r *ReportingRepo) ListLedgerBalances(ctx context.Context, dbtx db.DBTX, workspaceID, memberID string, from, to time.Time) ([]db.ListLedgerBalancesRow, error) {
+	queries := r.queries(dbtx)
+	rows, err := queries.ListLedgerBalances(ctx, db.ListLedgerBalancesParams{
+		MemberID:    memberID,
+		WorkspaceID: workspaceID,
+		FromDate:    from,
+		ToDate:      to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ledger balances: %w", TranslateError(err))
+	}
+	return rows, nil
+}
+
+// ListUnbalancedTransactions finds the transactions of a workspace dated on or
+// before asOf whose debits and credits differ in some currency within the
+// provided DBTX, narrowed to the transactions of memberID like
+// ListLedgerBalances
+func (r *ReportingRepo) ListUnbalancedTransactions(ctx context.Context, dbtx db.DBTX, workspaceID, memberID string, asOf time.Time) ([]db.ListUnbalancedTransactionsRow, error) {
+	queries := r.queries(dbtx)
+	rows, err := queries.ListUnbalancedTransactions(ctx, db.ListUnbalancedTransactionsParams{MemberID: memberID, WorkspaceID: workspaceID, AsOf: asOf})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unbalanced transactions: %w", TranslateError(err))
+	}
+	return rows, nil
+}
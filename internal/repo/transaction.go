@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// TransactionRepo provides direct access to transaction and ledger entry database operations
+type TransactionRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewTransactionRepo creates a new TransactionRepo
+func NewTransactionRepo(dbConn *sqlx.DB) *TransactionRepo {
+	return &TransactionRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *TransactionRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// CreateTransaction creates a new transaction header within the provided DBTX
+func (r *TransactionRepo) CreateTransaction(ctx context.Context, dbtx db.DBTX, arg db.CreateTransactionParams) (db.Transaction, error) {
+	queries := r.queries(dbtx)
+	transaction, err := queries.CreateTransaction(ctx, arg)
+	if err != nil {
+		return db.Transaction{}, fmt.Errorf("failed to create transaction: %w", TranslateError(err))
+	}
+	return transaction, nil
+}
+
+// GetTransaction retrieves a transaction header by ID in a workspace within the provided DBTX
+func (r *TransactionRepo) GetTransaction(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.Transaction, error) {
+	queries := r.queries(dbtx)
+	transaction, err := queries.GetTransaction(ctx, db.GetTransactionParams{ID: id, WorkspaceID: workspaceID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Transaction{}, fmt.Errorf("transaction not found: %w", errors.ErrNotFound)
+		}
+		return db.Transaction{}, fmt.Errorf("failed to get transaction: %w", TranslateError(err))
+	}
+	return transaction, nil
+}
+
+// TransactionFields is the allowlist of transaction fields ListTransactions may
+// filter and order by
+var TransactionFields = &filter.Schema[db.Transaction]{
+	Fields: map[string]filter.Field[db.Transaction]{
+		"date":           {Column: "date", Type: filter.Timestamp, Key: func(row db.Transaction) any { return row.Date }},
+		"description":    {Column: "description", Type: filter.String, Key: func(row db.Transaction) any { return row.Description }},
+		"notes":          {Column: "notes", Type: filter.String},
+		"category_id":    {Column: "category_id", Type: filter.String},
+		"instrument_id":  {Column: "instrument_id", Type: filter.String},
+		"allocation_tag": {Column: "allocation_tag", Type: filter.String},
+		"created_at":     {Column: "created_at", Type: filter.Timestamp, Key: func(row db.Transaction) any { return row.CreatedAt }},
+		"updated_at":     {Column: "updated_at", Type: filter.Timestamp, Key: func(row db.Transaction) any { return row.UpdatedAt }},
+	},
+	IDColumn:     "id",
+	DefaultOrder: "date desc",
+}
+
+// ListTransactions retrieves up to limit transactions matching q in its order,
+// starting after its page cursor, within the provided DBTX
+func (r *TransactionRepo) ListTransactions(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Transaction], limit int64) ([]db.Transaction, error) {
+	transactions, err := listPage(ctx, dbtx, "*", "transactions", q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", TranslateError(err))
+	}
+	return transactions, nil
+}
+
+// CountTransactions counts the transactions matching the filter of q within the
+// provided DBTX
+func (r *TransactionRepo) CountTransactions(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.Transaction]) (int64, error) {
+	count, err := countRows(ctx, dbtx, "transactions", q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count transactions: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// transactionColumns are the columns of transactions UpdateTransaction may change
+var transactionColumns = []string{"date", "description", "notes", "category_id", "instrument_id", "allocation_tag"}
+
+// UpdateTransaction sets the changed columns of a transaction header at a revision within the
+// provided DBTX. It returns errors.ErrConflict if the transaction is no longer at that
+// revision.
+func (r *TransactionRepo) UpdateTransaction(ctx context.Context, dbtx db.DBTX, id string, revision int64, changes map[string]any) (db.Transaction, error) {
+	transaction, err := updateRow[db.Transaction](ctx, dbtx, "transactions", transactionColumns, id, revision, changes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Transaction{}, fmt.Errorf("transaction was modified concurrently: %w", errors.ErrConflict)
+		}
+		return db.Transaction{}, fmt.Errorf("failed to update transaction: %w", TranslateError(err))
+	}
+	return transaction, nil
+}
+
+// DeleteTransaction deletes a transaction at a revision and its ledger entries
+// within the provided DBTX. It returns errors.ErrConflict if the transaction is
+// no longer at that revision.
+func (r *TransactionRepo) DeleteTransaction(ctx context.Context, dbtx db.DBTX, id string, revision int64) error {
+	// Foreign keys are not enforced on the connection, so the ON DELETE CASCADE
+	// on ledger_entries and the ON DELETE SET NULL on reconciliations have to
+	// be applied by hand
+	if err := r.DeleteLedgerEntries(ctx, dbtx, id); err != nil {
+		return err
+	}
+	queries := r.queries(dbtx)
+	if err := queries.ClearReconciliationAdjustments(ctx, &id); err != nil {
+		return fmt.Errorf("failed to clear reconciliation adjustments: %w", TranslateError(err))
+	}
+	rows, err := queries.DeleteTransaction(ctx, db.DeleteTransactionParams{ID: id, Revision: revision})
+	if err != nil {
+		return fmt.Errorf("failed to delete transaction: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("transaction was modified concurrently: %w", errors.ErrConflict)
+	}
+	return nil
+}
+
+// CreateLedgerEntry creates a new ledger entry within the provided DBTX
+func (r *TransactionRepo) CreateLedgerEntry(ctx context.Context, dbtx db.DBTX, arg db.CreateLedgerEntryParams) (db.LedgerEntry, error) {
+	queries := r.queries(dbtx)
+	entry, err := queries.CreateLedgerEntry(ctx, arg)
+	if err != nil {
+		return db.LedgerEntry{}, fmt.Errorf("failed to create ledger entry: %w", TranslateError(err))
+	}
+	return entry, nil
+}
+
+// ListLedgerEntries retrieves the ledger entries of the given transactions within the provided DBTX.
+// Transaction IDs never contain commas, so they are passed as one list both
+// SQLite and PostgreSQL can split.
+func (r *TransactionRepo) ListLedgerEntries(ctx context.Context, dbtx db.DBTX, transactionIDs []string) ([]db.ListLedgerEntriesForTransactionsRow, error) {
+	queries := r.queries(dbtx)
+	entries, err := queries.ListLedgerEntriesForTransactions(ctx, strings.Join(transactionIDs, ","))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ledger entries: %w", TranslateError(err))
+	}
+	return entries, nil
+}
+
+// DeleteLedgerEntries deletes all ledger entries of a transaction within the provided DBTX
+func (r *TransactionRepo) DeleteLedgerEntries(ctx context.Context, dbtx db.DBTX, transactionID string) error {
+	queries := r.queries(dbtx)
+	if err := queries.DeleteLedgerEntriesForTransaction(ctx, transactionID); err != nil {
+		return fmt.Errorf("failed to delete ledger entries: %w", TranslateError(err))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// updateRow sets the changed columns of the row of table with the given ID and
+// revision, touches its updated_at and bumps its revision, within the provided
+// DBTX. Tables and allowed columns are constants of this package and fix the
+// order of the SET clause; the changed values are bound as arguments. It
+// returns sql.ErrNoRows if no row has the ID at that revision.
+func updateRow[T any](ctx context.Context, dbtx db.DBTX, table string, allowed []string, id string, revision int64, changes map[string]any) (T, error) {
+	var row T
+	for column := range changes {
+		if !slices.Contains(allowed, column) {
+			return row, fmt.Errorf("column %q of %s cannot be updated", column, table)
+		}
+	}
+
+	set := make([]string, 0, len(changes)+2)
+	args := make([]any, 0, len(changes)+1)
+	for _, column := range allowed {
+		if value, ok := changes[column]; ok {
+			set = append(set, column+" = ?")
+			args = append(args, value)
+		}
+	}
+	set = append(set, "updated_at = CURRENT_TIMESTAMP", "revision = revision + 1")
+
+	query := "UPDATE " + table + " SET " + strings.Join(set, ", ") + " WHERE id = ? AND revision = ? RETURNING *"
+	rows, err := dbtx.QueryContext(ctx, rebind(dbtx, query), append(args, id, revision)...)
+	if err != nil {
+		return row, err
+	}
+
+	var updated []T
+	err = sqlx.StructScan(rows, &updated)
+	if closeErr := rows.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return row, err
+	}
+	if len(updated) == 0 {
+		return row, sql.ErrNoRows
+	}
+	return updated[0], nil
+}
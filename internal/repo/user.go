@@ -4,21 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
 	db "github.com/atreya2011/expense-manager/internal/repo/gen"
 	"github.com/jmoiron/sqlx"
 )
 
 // UserRepo provides direct access to user-related database operations
 type UserRepo struct {
-	db *sqlx.DB // Store the underlying DB pool
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
 }
 
 // NewUserRepo creates a new UserRepo
 func NewUserRepo(dbConn *sqlx.DB) *UserRepo {
 	return &UserRepo{
-		db: dbConn,
+		db:      dbConn,
+		queries: queriesFor(dbConn),
 	}
 }
 
@@ -29,59 +33,186 @@ func (r *UserRepo) GetDB() *sqlx.DB {
 
 // CreateUser creates a new user within the provided DBTX
 func (r *UserRepo) CreateUser(ctx context.Context, dbtx db.DBTX, arg db.CreateUserParams) (db.User, error) {
-	queries := db.New(dbtx)
+	queries := r.queries(dbtx)
 	user, err := queries.CreateUser(ctx, arg)
 	if err != nil {
-		return db.User{}, fmt.Errorf("failed to create user: %w", err)
+		return db.User{}, fmt.Errorf("failed to create user: %w", TranslateError(err))
 	}
 	return user, nil
 }
 
 // GetUser retrieves a user by ID within the provided DBTX
 func (r *UserRepo) GetUser(ctx context.Context, dbtx db.DBTX, id string) (db.User, error) {
-	queries := db.New(dbtx)
+	queries := r.queries(dbtx)
 	user, err := queries.GetUser(ctx, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return db.User{}, fmt.Errorf("user not found: %w", errors.ErrNotFound)
 		}
-		return db.User{}, fmt.Errorf("failed to get user: %w", err)
+		return db.User{}, fmt.Errorf("failed to get user: %w", TranslateError(err))
 	}
 	return user, nil
 }
 
-// ListUsers retrieves a paginated list of users within the provided DBTX
-func (r *UserRepo) ListUsers(ctx context.Context, dbtx db.DBTX, arg db.ListUsersParams) ([]db.User, error) {
-	queries := db.New(dbtx)
-	users, err := queries.ListUsers(ctx, arg)
+// GetUserInWorkspace retrieves a user of a workspace by ID within the provided
+// DBTX
+func (r *UserRepo) GetUserInWorkspace(ctx context.Context, dbtx db.DBTX, workspaceID, id string) (db.User, error) {
+	queries := r.queries(dbtx)
+	user, err := queries.GetUserInWorkspace(ctx, db.GetUserInWorkspaceParams{ID: id, WorkspaceID: workspaceID})
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
+		if err == sql.ErrNoRows {
+			return db.User{}, fmt.Errorf("user not found in workspace: %w", errors.ErrNotFound)
+		}
+		return db.User{}, fmt.Errorf("failed to get user: %w", TranslateError(err))
 	}
-	return users, nil
+	return user, nil
 }
 
-// UpdateUser updates a user within the provided DBTX
-func (r *UserRepo) UpdateUser(ctx context.Context, dbtx db.DBTX, arg db.UpdateUserParams) (db.User, error) {
-	queries := db.New(dbtx)
-	user, err := queries.UpdateUser(ctx, arg)
+// GetUserByEmail retrieves a user by email within the provided DBTX
+func (r *UserRepo) GetUserByEmail(ctx context.Context, dbtx db.DBTX, email string) (db.User, error) {
+	queries := r.queries(dbtx)
+	user, err := queries.GetUserByEmail(ctx, email)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return db.User{}, fmt.Errorf("user not found: %w", errors.ErrNotFound)
 		}
-		return db.User{}, fmt.Errorf("failed to update user: %w", err)
+		return db.User{}, fmt.Errorf("failed to get user by email: %w", TranslateError(err))
 	}
 	return user, nil
 }
 
-// DeleteUser deletes a user by ID within the provided DBTX
-func (r *UserRepo) DeleteUser(ctx context.Context, dbtx db.DBTX, id string) error {
-	queries := db.New(dbtx)
-	err := queries.DeleteUser(ctx, id)
+// UserFields is the allowlist of user fields ListUsers may filter and order by
+var UserFields = &filter.Schema[db.User]{
+	Fields: map[string]filter.Field[db.User]{
+		"name":       {Column: "name", Type: filter.String, Key: func(row db.User) any { return row.Name }},
+		"email":      {Column: "email", Type: filter.String, Key: func(row db.User) any { return row.Email }},
+		"created_at": {Column: "created_at", Type: filter.Timestamp, Key: func(row db.User) any { return row.CreatedAt }},
+		"updated_at": {Column: "updated_at", Type: filter.Timestamp, Key: func(row db.User) any { return row.UpdatedAt }},
+	},
+	IDColumn:     "id",
+	DefaultOrder: "name",
+}
+
+// ListUsers retrieves up to limit users matching q in its order, starting after
+// its page cursor, within the provided DBTX
+func (r *UserRepo) ListUsers(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.User], limit int64) ([]db.User, error) {
+	users, err := listPage(ctx, dbtx, "*", liveRows("users"), q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", TranslateError(err))
+	}
+	return users, nil
+}
+
+// CountUsers counts the users matching the filter of q within the provided DBTX
+func (r *UserRepo) CountUsers(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.User]) (int64, error) {
+	count, err := countRows(ctx, dbtx, liveRows("users"), q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// userColumns are the columns of users UpdateUser may change
+var userColumns = []string{"name", "email"}
+
+// UpdateUser sets the changed columns of a user at a revision within the
+// provided DBTX. It returns errors.ErrConflict if the user is no longer at that
+// revision.
+func (r *UserRepo) UpdateUser(ctx context.Context, dbtx db.DBTX, id string, revision int64, changes map[string]any) (db.User, error) {
+	user, err := updateRow[db.User](ctx, dbtx, "users", userColumns, id, revision, changes)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("user not found: %w", errors.ErrNotFound)
+			return db.User{}, fmt.Errorf("user was modified concurrently: %w", errors.ErrConflict)
 		}
-		return fmt.Errorf("failed to delete user: %w", err)
+		return db.User{}, fmt.Errorf("failed to update user: %w", TranslateError(err))
+	}
+	return user, nil
+}
+
+// DeleteUser moves a user at a revision to the trash within the provided DBTX.
+// It returns errors.ErrConflict if the user is no longer at that revision.
+func (r *UserRepo) DeleteUser(ctx context.Context, dbtx db.DBTX, id string, revision int64, deletedAt time.Time) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.DeleteUser(ctx, db.DeleteUserParams{DeletedAt: &deletedAt, ID: id, Revision: revision})
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("user was modified concurrently: %w", errors.ErrConflict)
+	}
+	return nil
+}
+
+// SetUserAdmin grants or withdraws the admin role of a user not in the trash
+// within the provided DBTX
+func (r *UserRepo) SetUserAdmin(ctx context.Context, dbtx db.DBTX, id string, admin bool) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.SetUserAdmin(ctx, db.SetUserAdminParams{IsAdmin: admin, ID: id})
+	if err != nil {
+		return fmt.Errorf("failed to set user admin: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("user not found: %w", errors.ErrNotFound)
 	}
 	return nil
 }
+
+// GetDeletedUser retrieves a user in the trash by ID within the provided DBTX
+func (r *UserRepo) GetDeletedUser(ctx context.Context, dbtx db.DBTX, id string) (db.User, error) {
+	queries := r.queries(dbtx)
+	user, err := queries.GetDeletedUser(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.User{}, fmt.Errorf("deleted user not found: %w", errors.ErrNotFound)
+		}
+		return db.User{}, fmt.Errorf("failed to get deleted user: %w", TranslateError(err))
+	}
+	return user, nil
+}
+
+// ListDeletedUsers retrieves up to limit users in the trash matching q in its
+// order, starting after its page cursor, within the provided DBTX
+func (r *UserRepo) ListDeletedUsers(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.User], limit int64) ([]db.User, error) {
+	users, err := listPage(ctx, dbtx, "*", trashedRows("users"), q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted users: %w", TranslateError(err))
+	}
+	return users, nil
+}
+
+// CountDeletedUsers counts the users in the trash matching the filter of q
+// within the provided DBTX
+func (r *UserRepo) CountDeletedUsers(ctx context.Context, dbtx db.DBTX, q *filter.Query[db.User]) (int64, error) {
+	count, err := countRows(ctx, dbtx, trashedRows("users"), q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted users: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// UndeleteUser restores a user at a revision from the trash within the provided
+// DBTX. It returns errors.ErrConflict if the user is no longer at that revision.
+func (r *UserRepo) UndeleteUser(ctx context.Context, dbtx db.DBTX, id string, revision int64) (db.User, error) {
+	queries := r.queries(dbtx)
+	user, err := queries.UndeleteUser(ctx, db.UndeleteUserParams{ID: id, Revision: revision})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.User{}, fmt.Errorf("deleted user was modified concurrently: %w", errors.ErrConflict)
+		}
+		return db.User{}, fmt.Errorf("failed to undelete user: %w", TranslateError(err))
+	}
+	return user, nil
+}
+
+// PurgeUsers permanently deletes the users moved to the trash at or before
+// deletedBefore within the provided DBTX and returns how many were deleted.
+// Users still holding accounts or named as the actor of audit events stay in
+// the trash; their memberships, API tokens and sessions go with them.
+func (r *UserRepo) PurgeUsers(ctx context.Context, dbtx db.DBTX, deletedBefore time.Time) (int64, error) {
+	queries := r.queries(dbtx)
+	purged, err := queries.PurgeUsers(ctx, &deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge users: %w", TranslateError(err))
+	}
+	return purged, nil
+}
@@ -0,0 +1,158 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultWorkspaceID is the workspace the data predating workspaces was moved
+// into, and the workspace of requests made without one
+const DefaultWorkspaceID = "wsp_default"
+
+// WorkspaceRepo provides direct access to workspace-related database operations
+type WorkspaceRepo struct {
+	db      *sqlx.DB                 // Store the underlying DB pool
+	queries func(db.DBTX) db.Querier // Generated queries of the pool's driver
+}
+
+// NewWorkspaceRepo creates a new WorkspaceRepo
+func NewWorkspaceRepo(dbConn *sqlx.DB) *WorkspaceRepo {
+	return &WorkspaceRepo{
+		db:      dbConn,
+		queries: queriesFor(dbConn),
+	}
+}
+
+// GetDB returns the underlying database connection pool
+func (r *WorkspaceRepo) GetDB() *sqlx.DB {
+	return r.db
+}
+
+// CreateWorkspace creates a new workspace within the provided DBTX
+func (r *WorkspaceRepo) CreateWorkspace(ctx context.Context, dbtx db.DBTX, name string) (db.Workspace, error) {
+	queries := r.queries(dbtx)
+	workspace, err := queries.CreateWorkspace(ctx, name)
+	if err != nil {
+		return db.Workspace{}, fmt.Errorf("failed to create workspace: %w", TranslateError(err))
+	}
+	return workspace, nil
+}
+
+// GetWorkspace retrieves a workspace by ID within the provided DBTX
+func (r *WorkspaceRepo) GetWorkspace(ctx context.Context, dbtx db.DBTX, id string) (db.Workspace, error) {
+	queries := r.queries(dbtx)
+	workspace, err := queries.GetWorkspace(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.Workspace{}, fmt.Errorf("workspace not found: %w", errors.ErrNotFound)
+		}
+		return db.Workspace{}, fmt.Errorf("failed to get workspace: %w", TranslateError(err))
+	}
+	return workspace, nil
+}
+
+// ListWorkspacesForUser retrieves the workspaces a user belongs to, with their
+// role in each, within the provided DBTX
+func (r *WorkspaceRepo) ListWorkspacesForUser(ctx context.Context, dbtx db.DBTX, userID string) ([]db.ListWorkspacesForUserRow, error) {
+	queries := r.queries(dbtx)
+	workspaces, err := queries.ListWorkspacesForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", TranslateError(err))
+	}
+	return workspaces, nil
+}
+
+// AddWorkspaceUser adds a user to a workspace within the provided DBTX
+func (r *WorkspaceRepo) AddWorkspaceUser(ctx context.Context, dbtx db.DBTX, arg db.AddWorkspaceUserParams) (db.WorkspaceUser, error) {
+	queries := r.queries(dbtx)
+	member, err := queries.AddWorkspaceUser(ctx, arg)
+	if err != nil {
+		return db.WorkspaceUser{}, fmt.Errorf("failed to add workspace user: %w", TranslateError(err))
+	}
+	return member, nil
+}
+
+// GetWorkspaceUser retrieves the membership of a user in a workspace within the
+// provided DBTX
+func (r *WorkspaceRepo) GetWorkspaceUser(ctx context.Context, dbtx db.DBTX, workspaceID, userID string) (db.WorkspaceUser, error) {
+	queries := r.queries(dbtx)
+	member, err := queries.GetWorkspaceUser(ctx, db.GetWorkspaceUserParams{WorkspaceID: workspaceID, UserID: userID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.WorkspaceUser{}, fmt.Errorf("workspace user not found: %w", errors.ErrNotFound)
+		}
+		return db.WorkspaceUser{}, fmt.Errorf("failed to get workspace user: %w", TranslateError(err))
+	}
+	return member, nil
+}
+
+// RemoveWorkspaceUser removes a user from a workspace and from every account of
+// it within the provided DBTX
+func (r *WorkspaceRepo) RemoveWorkspaceUser(ctx context.Context, dbtx db.DBTX, workspaceID, userID string) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.RemoveWorkspaceUser(ctx, db.RemoveWorkspaceUserParams{WorkspaceID: workspaceID, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace user: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("workspace user not found: %w", errors.ErrNotFound)
+	}
+	err = queries.RemoveWorkspaceAccountUsers(ctx, db.RemoveWorkspaceAccountUsersParams{UserID: userID, WorkspaceID: workspaceID})
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace account users: %w", TranslateError(err))
+	}
+	return nil
+}
+
+// SetWorkspaceUserAdmin grants or revokes the admin role of a user in a
+// workspace within the provided DBTX
+func (r *WorkspaceRepo) SetWorkspaceUserAdmin(ctx context.Context, dbtx db.DBTX, workspaceID, userID string, admin bool) error {
+	queries := r.queries(dbtx)
+	rows, err := queries.SetWorkspaceUserAdmin(ctx, db.SetWorkspaceUserAdminParams{IsAdmin: admin, WorkspaceID: workspaceID, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to set workspace user admin: %w", TranslateError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("workspace user not found: %w", errors.ErrNotFound)
+	}
+	return nil
+}
+
+// CountWorkspaceAdmins counts the admins of a workspace within the provided DBTX
+func (r *WorkspaceRepo) CountWorkspaceAdmins(ctx context.Context, dbtx db.DBTX, workspaceID string) (int64, error) {
+	queries := r.queries(dbtx)
+	count, err := queries.CountWorkspaceAdmins(ctx, workspaceID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count workspace admins: %w", TranslateError(err))
+	}
+	return count, nil
+}
+
+// ListWorkspaceUsers retrieves the users of a workspace not in the trash, with
+// their role, within the provided DBTX
+func (r *WorkspaceRepo) ListWorkspaceUsers(ctx context.Context, dbtx db.DBTX, workspaceID string) ([]db.ListWorkspaceUsersRow, error) {
+	queries := r.queries(dbtx)
+	users, err := queries.ListWorkspaceUsers(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace users: %w", TranslateError(err))
+	}
+	return users, nil
+}
+
+// InWorkspace narrows a list of a table with a workspace_id column, such as
+// ListInstruments of "instruments", to the rows of a workspace
+func InWorkspace(table, workspaceID string) filter.Clause {
+	return filter.Clause{SQL: table + ".workspace_id = ?", Args: []any{workspaceID}}
+}
+
+// UsersOfWorkspace narrows ListUsers and CountUsers to the users of a
+// workspace
+func UsersOfWorkspace(workspaceID string) filter.Clause {
+	return filter.Clause{SQL: "id IN (SELECT user_id FROM workspace_users WHERE workspace_id = ?)", Args: []any{workspaceID}}
+}
@@ -0,0 +1,645 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/etag"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// AccountService implements the AccountService interface defined in the proto
+type AccountService struct {
+	expensesv1connect.UnimplementedAccountServiceHandler
+	repo            *repo.AccountRepo
+	userRepo        *repo.UserRepo
+	instrumentRepo  *repo.InstrumentRepo
+	institutionRepo *repo.InstitutionRepo
+	currencyRepo    *repo.CurrencyRepo
+	auditor         auditor
+	pages           *pagination.Codec
+	clock           clock.Clock
+	logger          *slog.Logger
+}
+
+// NewAccountService creates a new AccountService
+func NewAccountService(
+	repo *repo.AccountRepo,
+	userRepo *repo.UserRepo,
+	instrumentRepo *repo.InstrumentRepo,
+	institutionRepo *repo.InstitutionRepo,
+	currencyRepo *repo.CurrencyRepo,
+	auditRepo *repo.AuditRepo,
+	pages *pagination.Codec,
+	clock clock.Clock,
+	logger *slog.Logger,
+) *AccountService {
+	return &AccountService{
+		repo:            repo,
+		userRepo:        userRepo,
+		instrumentRepo:  instrumentRepo,
+		institutionRepo: institutionRepo,
+		currencyRepo:    currencyRepo,
+		auditor:         newAuditor(auditRepo, clock, logger),
+		pages:           pages,
+		clock:           clock,
+		logger:          logger,
+	}
+}
+
+// CreateAccount creates a new account
+func (s *AccountService) CreateAccount(ctx context.Context, req *connect.Request[expensesv1.CreateAccountRequest]) (*connect.Response[expensesv1.CreateAccountResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Creating account", "name", req.Msg.Name, "account_type_id", req.Msg.AccountTypeId)
+
+	// Validate input
+	if req.Msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateAccount", "error", "name is required")
+		return nil, errors.Required("name")
+	}
+	if req.Msg.AccountTypeId == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateAccount", "error", "account_type_id is required")
+		return nil, errors.Required("account_type_id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Validate referenced master data within the transaction
+	params := db.CreateAccountParams{
+		WorkspaceID:   authz.WorkspaceFrom(ctx),
+		Name:          req.Msg.Name,
+		Description:   nullableString(&req.Msg.Description),
+		AccountTypeID: req.Msg.AccountTypeId,
+		InstrumentID:  nullableString(req.Msg.InstrumentId),
+		InstitutionID: nullableString(req.Msg.InstitutionId),
+		CurrencyID:    nullableString(req.Msg.CurrencyId),
+	}
+	if err := s.validateReferences(ctx, tx, params.AccountTypeID, params.InstrumentID, params.InstitutionID, params.CurrencyID); err != nil {
+		return nil, err
+	}
+
+	// Create account in database within the transaction
+	account, err := s.repo.CreateAccount(ctx, tx, params)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "Account already exists", "name", req.Msg.Name)
+			return nil, errors.AlreadyExists("account", req.Msg.Name, "name", "account with name %s already exists", req.Msg.Name)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to create account", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "account", account.ID, audit.ActionCreate, nil, toProtoAccount(account)); err != nil {
+		return nil, err
+	}
+
+	// Make the caller the first member of the account, so they may use it
+	if subject, ok := authz.SubjectFrom(ctx); ok {
+		if _, err := s.repo.AddAccountUser(ctx, tx, account.ID, subject.UserID); err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to add account user", "account_id", account.ID, "user_id", subject.UserID, "error", err)
+			return nil, storageError(err)
+		}
+		member := &expensesv1.AddAccountUserRequest{AccountId: account.ID, UserId: subject.UserID}
+		if err := s.auditor.record(ctx, tx, "account_user", account.ID+"/"+subject.UserID, audit.ActionCreate, nil, member); err != nil {
+			return nil, err
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Account created successfully", "id", account.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.CreateAccountResponse{
+		Account: toProtoAccount(account),
+	}), nil
+}
+
+// GetAccount retrieves an account by ID
+func (s *AccountService) GetAccount(ctx context.Context, req *connect.Request[expensesv1.GetAccountRequest]) (*connect.Response[expensesv1.GetAccountResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Getting account", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for GetAccount", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Get account from database (read operations can use the main DB connection)
+	account, err := s.repo.GetAccount(ctx, s.repo.GetDB(), authz.WorkspaceFrom(ctx), req.Msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Account not found", "id", req.Msg.Id)
+			return nil, errors.NotFound("account", req.Msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get account", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	log.InfoContext(ctx, s.logger, "Account retrieved successfully", "id", account.ID)
+
+	// Prepare response, exposing the etag for conditional HTTP GETs
+	resp := connect.NewResponse(&expensesv1.GetAccountResponse{
+		Account: toProtoAccount(account),
+	})
+	resp.Header().Set(etag.Header, resp.Msg.Account.Etag)
+	return resp, nil
+}
+
+// ListAccounts retrieves a paginated list of accounts
+func (s *AccountService) ListAccounts(ctx context.Context, req *connect.Request[expensesv1.ListAccountsRequest]) (*connect.Response[expensesv1.ListAccountsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing accounts", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("accounts", req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering within the caller's workspace
+	query, err := parseQuery(ctx, s.logger, repo.AccountFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("accounts", authz.WorkspaceFrom(ctx)))
+
+	// Narrow the list to the accounts the caller belongs to
+	if subject, ok := authz.SubjectFrom(ctx); ok {
+		query.Where(repo.AccountsOfMember(subject.UserID))
+	}
+
+	// Get accounts from database (read operations can use the main DB connection)
+	accounts, err := s.repo.ListAccounts(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list accounts", "error", err)
+		return nil, storageError(err)
+	}
+
+	accounts, pageResponse, err := paginate(ctx, s.logger, s.pages, page, accounts, func(row db.Account) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountAccounts(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare response
+	protoAccounts := make([]*expensesv1.Account, len(accounts))
+	for i, account := range accounts {
+		protoAccounts[i] = toProtoAccount(account)
+	}
+
+	log.InfoContext(ctx, s.logger, "Accounts retrieved successfully", "count", len(accounts))
+
+	return connect.NewResponse(&expensesv1.ListAccountsResponse{
+		Accounts:           protoAccounts,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// accountUpdatePaths are the fields of an account UpdateAccount may change
+var accountUpdatePaths = []string{"name", "description", "account_type_id", "instrument_id", "institution_id", "currency_id"}
+
+// UpdateAccount updates the fields of an account selected by the update mask
+func (s *AccountService) UpdateAccount(ctx context.Context, req *connect.Request[expensesv1.UpdateAccountRequest]) (*connect.Response[expensesv1.UpdateAccountResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Updating account", "id", req.Msg.Id, "name", req.Msg.Name, "update_mask", req.Msg.GetUpdateMask().GetPaths())
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateAccount", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+	mask, err := parseUpdateMask(ctx, s.logger, req.Msg.UpdateMask, accountUpdatePaths)
+	if err != nil {
+		return nil, err
+	}
+	if mask.has("name") && req.Msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateAccount", "error", "name is required")
+		return nil, errors.Required("name")
+	}
+	if mask.has("account_type_id") && req.Msg.AccountTypeId == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateAccount", "error", "account_type_id is required")
+		return nil, errors.Required("account_type_id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if account exists within the transaction
+	existing, err := s.repo.GetAccount(ctx, tx, authz.WorkspaceFrom(ctx), req.Msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Account not found", "id", req.Msg.Id)
+			return nil, errors.NotFound("account", req.Msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to check if account exists", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "account", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Validate the master data the updated account references within the
+	// transaction
+	changes := mask.pick(map[string]any{
+		"name":            req.Msg.Name,
+		"description":     nullableString(&req.Msg.Description),
+		"account_type_id": req.Msg.AccountTypeId,
+		"instrument_id":   nullableString(req.Msg.InstrumentId),
+		"institution_id":  nullableString(req.Msg.InstitutionId),
+		"currency_id":     nullableString(req.Msg.CurrencyId),
+	})
+	if mask.has("account_type_id") {
+		existing.AccountTypeID = req.Msg.AccountTypeId
+	}
+	if mask.has("instrument_id") {
+		existing.InstrumentID = nullableString(req.Msg.InstrumentId)
+	}
+	if mask.has("institution_id") {
+		existing.InstitutionID = nullableString(req.Msg.InstitutionId)
+	}
+	if mask.has("currency_id") {
+		existing.CurrencyID = nullableString(req.Msg.CurrencyId)
+	}
+	if err := s.validateReferences(ctx, tx, existing.AccountTypeID, existing.InstrumentID, existing.InstitutionID, existing.CurrencyID); err != nil {
+		return nil, err
+	}
+
+	// Update the masked fields of the account within the transaction
+	account, err := s.repo.UpdateAccount(ctx, tx, req.Msg.Id, existing.Revision, changes)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "Account with name already exists", "name", req.Msg.Name)
+			return nil, errors.AlreadyExists("account", req.Msg.Name, "name", "account with name %s already exists", req.Msg.Name)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to update account", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "account", account.ID, audit.ActionUpdate, toProtoAccount(existing), toProtoAccount(account)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Account updated successfully", "id", account.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.UpdateAccountResponse{
+		Account: toProtoAccount(account),
+	}), nil
+}
+
+// DeleteAccount deletes an account by ID
+func (s *AccountService) DeleteAccount(ctx context.Context, req *connect.Request[expensesv1.DeleteAccountRequest]) (*connect.Response[expensesv1.DeleteAccountResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Deleting account", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for DeleteAccount", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if account exists within the transaction
+	existing, err := s.repo.GetAccount(ctx, tx, authz.WorkspaceFrom(ctx), req.Msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Account not found", "id", req.Msg.Id)
+			return nil, errors.NotFound("account", req.Msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to check if account exists", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "account", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Refuse to delete accounts that still carry ledger entries
+	entryCount, err := s.repo.CountLedgerEntries(ctx, tx, req.Msg.Id)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to count ledger entries", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if entryCount > 0 {
+		log.ErrorContext(ctx, s.logger, "Account has ledger entries", "id", req.Msg.Id, "count", entryCount)
+		return nil, errors.InUse("account", req.Msg.Id, "account with id %s has %d ledger entries", req.Msg.Id, entryCount)
+	}
+
+	// Delete account from database within the transaction
+	err = s.repo.DeleteAccount(ctx, tx, req.Msg.Id, existing.Revision)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to delete account", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "account", existing.ID, audit.ActionDelete, toProtoAccount(existing), nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Account deleted successfully", "id", req.Msg.Id)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.DeleteAccountResponse{
+		Success: true,
+	}), nil
+}
+
+// AddAccountUser links a user to an account
+func (s *AccountService) AddAccountUser(ctx context.Context, req *connect.Request[expensesv1.AddAccountUserRequest]) (*connect.Response[expensesv1.AddAccountUserResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Adding account user", "account_id", req.Msg.AccountId, "user_id", req.Msg.UserId)
+
+	// Validate input
+	if req.Msg.AccountId == "" || req.Msg.UserId == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for AddAccountUser", "error", "account_id and user_id are required")
+		return nil, errors.Required("account_id", "user_id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check that both sides of the link exist within the transaction
+	if err := s.checkAccountAndUser(ctx, tx, req.Msg.AccountId, req.Msg.UserId); err != nil {
+		return nil, err
+	}
+
+	// Link user to account within the transaction
+	_, err = s.repo.AddAccountUser(ctx, tx, req.Msg.AccountId, req.Msg.UserId)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "Account user already exists", "account_id", req.Msg.AccountId, "user_id", req.Msg.UserId)
+			return nil, errors.AlreadyExists("account_user", req.Msg.AccountId+"/"+req.Msg.UserId, "user_id", "user %s is already linked to account %s", req.Msg.UserId, req.Msg.AccountId)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to add account user", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "account_user", req.Msg.AccountId+"/"+req.Msg.UserId, audit.ActionCreate, nil, req.Msg); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Account user added successfully", "account_id", req.Msg.AccountId, "user_id", req.Msg.UserId)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.AddAccountUserResponse{
+		Success: true,
+	}), nil
+}
+
+// RemoveAccountUser unlinks a user from an account
+func (s *AccountService) RemoveAccountUser(ctx context.Context, req *connect.Request[expensesv1.RemoveAccountUserRequest]) (*connect.Response[expensesv1.RemoveAccountUserResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Removing account user", "account_id", req.Msg.AccountId, "user_id", req.Msg.UserId)
+
+	// Validate input
+	if req.Msg.AccountId == "" || req.Msg.UserId == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for RemoveAccountUser", "error", "account_id and user_id are required")
+		return nil, errors.Required("account_id", "user_id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check that the account is in the caller's workspace within the transaction
+	if _, err := s.repo.GetAccount(ctx, tx, authz.WorkspaceFrom(ctx), req.Msg.AccountId); err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Account not found", "id", req.Msg.AccountId)
+			return nil, errors.NotFound("account", req.Msg.AccountId)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get account", "id", req.Msg.AccountId, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Unlink user from account within the transaction
+	err = s.repo.RemoveAccountUser(ctx, tx, req.Msg.AccountId, req.Msg.UserId)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Account user not found", "account_id", req.Msg.AccountId, "user_id", req.Msg.UserId)
+			return nil, errors.Error(connect.CodeNotFound, errors.ReasonNotFound, fmt.Errorf("%w: user %s is not linked to account %s", errors.ErrNotFound, req.Msg.UserId, req.Msg.AccountId),
+				errors.Resource("account_user", req.Msg.AccountId+"/"+req.Msg.UserId))
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to remove account user", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "account_user", req.Msg.AccountId+"/"+req.Msg.UserId, audit.ActionDelete, req.Msg, nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Account user removed successfully", "account_id", req.Msg.AccountId, "user_id", req.Msg.UserId)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.RemoveAccountUserResponse{
+		Success: true,
+	}), nil
+}
+
+// ListAccountUsers retrieves the users linked to an account
+func (s *AccountService) ListAccountUsers(ctx context.Context, req *connect.Request[expensesv1.ListAccountUsersRequest]) (*connect.Response[expensesv1.ListAccountUsersResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing account users", "account_id", req.Msg.AccountId)
+
+	// Validate input
+	if req.Msg.AccountId == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for ListAccountUsers", "error", "account_id is required")
+		return nil, errors.Required("account_id")
+	}
+
+	// Check if account exists (read operations can use the main DB connection)
+	_, err := s.repo.GetAccount(ctx, s.repo.GetDB(), authz.WorkspaceFrom(ctx), req.Msg.AccountId)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Account not found", "id", req.Msg.AccountId)
+			return nil, errors.NotFound("account", req.Msg.AccountId)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get account", "id", req.Msg.AccountId, "error", err)
+		return nil, storageError(err)
+	}
+
+	users, err := s.repo.ListAccountUsers(ctx, s.repo.GetDB(), req.Msg.AccountId)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list account users", "account_id", req.Msg.AccountId, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Prepare response
+	protoUsers := make([]*expensesv1.User, len(users))
+	for i, user := range users {
+		protoUsers[i] = toProtoUser(user)
+	}
+
+	log.InfoContext(ctx, s.logger, "Account users retrieved successfully", "account_id", req.Msg.AccountId, "count", len(users))
+
+	return connect.NewResponse(&expensesv1.ListAccountUsersResponse{
+		Users: protoUsers,
+	}), nil
+}
+
+// validateReferences checks that the master data an account points at exists
+func (s *AccountService) validateReferences(ctx context.Context, dbtx db.DBTX, accountTypeID string, instrumentID, institutionID, currencyID *string) error {
+	if _, err := s.repo.GetAccountType(ctx, dbtx, accountTypeID); err != nil {
+		return referenceError(ctx, s.logger, "account_type_id", accountTypeID, err)
+	}
+	if instrumentID != nil {
+		if _, err := s.instrumentRepo.GetInstrument(ctx, dbtx, authz.WorkspaceFrom(ctx), *instrumentID); err != nil {
+			return referenceError(ctx, s.logger, "instrument_id", *instrumentID, err)
+		}
+	}
+	if institutionID != nil {
+		if _, err := s.institutionRepo.GetInstitution(ctx, dbtx, authz.WorkspaceFrom(ctx), *institutionID); err != nil {
+			return referenceError(ctx, s.logger, "institution_id", *institutionID, err)
+		}
+	}
+	if currencyID != nil {
+		if _, err := s.currencyRepo.GetCurrency(ctx, dbtx, authz.WorkspaceFrom(ctx), *currencyID); err != nil {
+			return referenceError(ctx, s.logger, "currency_id", *currencyID, err)
+		}
+	}
+	return nil
+}
+
+// checkAccountAndUser checks that both the account and the user of a link exist
+func (s *AccountService) checkAccountAndUser(ctx context.Context, dbtx db.DBTX, accountID, userID string) error {
+	if _, err := s.repo.GetAccount(ctx, dbtx, authz.WorkspaceFrom(ctx), accountID); err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Account not found", "id", accountID)
+			return errors.NotFound("account", accountID)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get account", "id", accountID, "error", err)
+		return storageError(err)
+	}
+	if _, err := s.userRepo.GetUserInWorkspace(ctx, dbtx, authz.WorkspaceFrom(ctx), userID); err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "User not found", "id", userID)
+			return errors.NotFound("user", userID)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get user", "id", userID, "error", err)
+		return storageError(err)
+	}
+	return nil
+}
+
+// toProtoAccount converts a db.Account to a expensesv1.Account
+func toProtoAccount(account db.Account) *expensesv1.Account {
+	return &expensesv1.Account{
+		Id:            account.ID,
+		Name:          account.Name,
+		Description:   stringValue(account.Description),
+		AccountTypeId: account.AccountTypeID,
+		InstrumentId:  account.InstrumentID,
+		InstitutionId: account.InstitutionID,
+		CurrencyId:    account.CurrencyID,
+		CreatedAt:     timestamppb.New(account.CreatedAt),
+		UpdatedAt:     timestamppb.New(account.UpdatedAt),
+		Etag:          etag.Compute(account.Revision, account.UpdatedAt),
+	}
+}
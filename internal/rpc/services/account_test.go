@@ -0,0 +1,585 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// newTestAccountService creates an AccountService wired to the test repositories
+func newTestAccountService() *AccountService {
+	return NewAccountService(accountRepo, userRepo, instrumentRepo, institutionRepo, currencyRepo, auditRepo, testPages, testClock, testLogger)
+}
+
+// TestCreateAccount tests the CreateAccount RPC method
+func TestCreateAccount(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new AccountService with the test repositories
+	service := newTestAccountService()
+
+	// Create referenced master data (using the main DB connection for setup)
+	instrument := createTestInstrument(t, testDB, "Cash")
+	createTestInstitution(t, testDB, "fi_test", "Test Bank", "BANK")
+	createTestCurrency(t, testDB, "cur_jpy", "JPY", "Japanese Yen")
+
+	missing := "missing"
+	institutionID := "fi_test"
+	currencyID := "cur_jpy"
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		request     *expensesv1.CreateAccountRequest
+		expectError bool
+		expectCode  connect.Code
+	}{
+		{
+			name: "Valid account creation",
+			request: &expensesv1.CreateAccountRequest{
+				Name:          "Wallet",
+				Description:   "Cash in my wallet",
+				AccountTypeId: "at_asset",
+				InstrumentId:  &instrument.ID,
+				InstitutionId: &institutionID,
+				CurrencyId:    &currencyID,
+			},
+			expectError: false,
+		},
+		{
+			name: "Missing name",
+			request: &expensesv1.CreateAccountRequest{
+				AccountTypeId: "at_asset",
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Missing account type",
+			request: &expensesv1.CreateAccountRequest{
+				Name: "No Type",
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Unknown account type",
+			request: &expensesv1.CreateAccountRequest{
+				Name:          "Bad Type",
+				AccountTypeId: "at_income",
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Unknown instrument",
+			request: &expensesv1.CreateAccountRequest{
+				Name:          "Bad Instrument",
+				AccountTypeId: "at_asset",
+				InstrumentId:  &missing,
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Unknown institution",
+			request: &expensesv1.CreateAccountRequest{
+				Name:          "Bad Institution",
+				AccountTypeId: "at_asset",
+				InstitutionId: &missing,
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Unknown currency",
+			request: &expensesv1.CreateAccountRequest{
+				Name:          "Bad Currency",
+				AccountTypeId: "at_asset",
+				CurrencyId:    &missing,
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Duplicate name (should fail)",
+			request: &expensesv1.CreateAccountRequest{
+				Name:          "Wallet", // Same name as the first case
+				AccountTypeId: "at_asset",
+			},
+			expectError: true,
+			expectCode:  connect.CodeAlreadyExists,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := connect.NewRequest(tc.request)
+			resp, err := service.CreateAccount(ctx, req)
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.expectCode.String())
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+
+			// Verify response for successful cases
+			if resp == nil || resp.Msg == nil || resp.Msg.Account == nil {
+				t.Fatalf("Expected valid response, got nil")
+			}
+
+			account := resp.Msg.Account
+			if account.Id == "" {
+				t.Errorf("Expected account ID to be generated, got empty string")
+			}
+			if account.Name != tc.request.Name {
+				t.Errorf("Expected name=%s, got %s", tc.request.Name, account.Name)
+			}
+			if account.Description != tc.request.Description {
+				t.Errorf("Expected description=%s, got %s", tc.request.Description, account.Description)
+			}
+			if account.GetCurrencyId() != tc.request.GetCurrencyId() {
+				t.Errorf("Expected currency_id=%s, got %s", tc.request.GetCurrencyId(), account.GetCurrencyId())
+			}
+		})
+	}
+}
+
+// TestGetAccount tests the GetAccount RPC method
+func TestGetAccount(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new AccountService with the test repositories
+	service := newTestAccountService()
+
+	// Create a test account (using the main DB connection for setup)
+	testAccount := createTestAccount(t, testDB, "Savings", "at_asset", nil)
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		accountID   string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Valid account retrieval",
+			accountID:   testAccount.ID,
+			expectError: false,
+		},
+		{
+			name:        "Non-existent account",
+			accountID:   "acc_nonexistent",
+			expectError: true,
+			errorMsg:    "not found",
+		},
+		{
+			name:        "Empty account ID",
+			accountID:   "",
+			expectError: true,
+			errorMsg:    "id is required",
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := connect.NewRequest(&expensesv1.GetAccountRequest{
+				Id: tc.accountID,
+			})
+
+			resp, err := service.GetAccount(ctx, req)
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.errorMsg)
+
+			// Verify response for successful cases
+			if !tc.expectError {
+				if resp == nil || resp.Msg == nil || resp.Msg.Account == nil {
+					t.Fatalf("Expected valid response, got nil")
+				}
+
+				if resp.Msg.Account.Id != testAccount.ID {
+					t.Errorf("Expected ID=%s, got %s", testAccount.ID, resp.Msg.Account.Id)
+				}
+
+				if resp.Msg.Account.AccountTypeId != "at_asset" {
+					t.Errorf("Expected account_type_id=at_asset, got %s", resp.Msg.Account.AccountTypeId)
+				}
+			}
+		})
+	}
+}
+
+// TestListAccounts tests the ListAccounts RPC method
+func TestListAccounts(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new AccountService with the test repositories
+	service := newTestAccountService()
+
+	// Create test accounts (using the main DB connection for setup)
+	_ = createTestAccount(t, testDB, "Wallet", "at_asset", nil)
+	_ = createTestAccount(t, testDB, "Credit Card", "at_liability", nil)
+	openingBalance := createTestAccount(t, testDB, "Opening Balance", "at_equity", nil)
+
+	// Define test cases
+	tests := []struct {
+		name      string
+		pageSize  int32
+		pageToken string
+		expectedN int
+	}{
+		{
+			name:      "List all accounts (default page size)",
+			expectedN: 3,
+		},
+		{
+			name:      "List with pagination (page 1)",
+			pageSize:  2,
+			expectedN: 2,
+		},
+		{
+			name:      "List with pagination (page 2)",
+			pageSize:  2,
+			pageToken: pageTokenAfter(t, pagination.Filter("accounts", "", ""), openingBalance.Name, openingBalance.ID),
+			expectedN: 1,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var page *expensesv1.Pagination
+			if tc.pageSize > 0 || tc.pageToken != "" {
+				page = &expensesv1.Pagination{
+					PageSize:  tc.pageSize,
+					PageToken: tc.pageToken,
+				}
+			}
+
+			resp, err := service.ListAccounts(ctx, connect.NewRequest(&expensesv1.ListAccountsRequest{
+				Pagination: page,
+			}))
+			assertError(t, err, false, "")
+			if resp == nil || resp.Msg == nil {
+				t.Fatalf("Expected valid response, got nil")
+			}
+
+			if len(resp.Msg.Accounts) != tc.expectedN {
+				t.Errorf("Expected %d accounts, got %d", tc.expectedN, len(resp.Msg.Accounts))
+			}
+		})
+	}
+}
+
+// TestUpdateAccount tests the UpdateAccount RPC method
+func TestUpdateAccount(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new AccountService with the test repositories
+	service := newTestAccountService()
+
+	// Create test accounts (using the main DB connection for setup)
+	testAccount := createTestAccount(t, testDB, "Original Name", "at_asset", nil)
+	otherAccount := createTestAccount(t, testDB, "Other Account", "at_asset", nil)
+	missing := "missing"
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		request     *expensesv1.UpdateAccountRequest
+		expectError bool
+		expectCode  connect.Code
+	}{
+		{
+			name: "Valid account update",
+			request: &expensesv1.UpdateAccountRequest{
+				Id:            testAccount.ID,
+				Name:          "Updated Name",
+				Description:   "Updated description",
+				AccountTypeId: "at_liability",
+			},
+			expectError: false,
+		},
+		{
+			name: "Non-existent account",
+			request: &expensesv1.UpdateAccountRequest{
+				Id:            "acc_nonexistent",
+				Name:          "Updated Name",
+				AccountTypeId: "at_asset",
+			},
+			expectError: true,
+			expectCode:  connect.CodeNotFound,
+		},
+		{
+			name: "Empty account ID",
+			request: &expensesv1.UpdateAccountRequest{
+				Name:          "Updated Name",
+				AccountTypeId: "at_asset",
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Unknown currency",
+			request: &expensesv1.UpdateAccountRequest{
+				Id:            testAccount.ID,
+				Name:          "Updated Name",
+				AccountTypeId: "at_asset",
+				CurrencyId:    &missing,
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Duplicate name",
+			request: &expensesv1.UpdateAccountRequest{
+				Id:            testAccount.ID,
+				Name:          otherAccount.Name,
+				AccountTypeId: "at_asset",
+			},
+			expectError: true,
+			expectCode:  connect.CodeAlreadyExists,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.UpdateAccount(ctx, connect.NewRequest(tc.request))
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.expectCode.String())
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+
+			// Verify response for successful cases
+			if resp == nil || resp.Msg == nil || resp.Msg.Account == nil {
+				t.Fatalf("Expected valid response, got nil")
+			}
+			if resp.Msg.Account.Name != tc.request.Name {
+				t.Errorf("Expected name=%s, got %s", tc.request.Name, resp.Msg.Account.Name)
+			}
+			if resp.Msg.Account.AccountTypeId != tc.request.AccountTypeId {
+				t.Errorf("Expected account_type_id=%s, got %s", tc.request.AccountTypeId, resp.Msg.Account.AccountTypeId)
+			}
+		})
+	}
+}
+
+// TestDeleteAccount tests the DeleteAccount RPC method
+func TestDeleteAccount(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new AccountService with the test repositories
+	service := newTestAccountService()
+
+	// Create test data (using the main DB connection for setup)
+	testAccount := createTestAccount(t, testDB, "Delete Me", "at_asset", nil)
+	postedAccount := createTestAccount(t, testDB, "Has Entries", "at_asset", nil)
+	testUser := createTestUser(t, testDB, "Owner", "owner@example.com")
+	if _, err := accountRepo.AddAccountUser(context.Background(), testDB, testAccount.ID, testUser.ID); err != nil {
+		t.Fatalf("Failed to link test user: %v", err)
+	}
+	if _, err := testDB.Exec(testDB.Rebind(`INSERT INTO transactions (id, workspace_id, date, description) VALUES ('txn_1', ?, CURRENT_TIMESTAMP, 'Posted')`), repo.DefaultWorkspaceID); err != nil {
+		t.Fatalf("Failed to create test transaction: %v", err)
+	}
+	if _, err := testDB.Exec(testDB.Rebind(`INSERT INTO ledger_entries (id, transaction_id, account_id, memo, debit) VALUES ('le_1', 'txn_1', ?, 'posted', 100)`), postedAccount.ID); err != nil {
+		t.Fatalf("Failed to create test ledger entry: %v", err)
+	}
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		accountID   string
+		expectError bool
+		expectCode  connect.Code
+	}{
+		{
+			name:        "Valid account deletion",
+			accountID:   testAccount.ID,
+			expectError: false,
+		},
+		{
+			name:        "Account with ledger entries",
+			accountID:   postedAccount.ID,
+			expectError: true,
+			expectCode:  connect.CodeFailedPrecondition,
+		},
+		{
+			name:        "Non-existent account",
+			accountID:   "acc_nonexistent",
+			expectError: true,
+			expectCode:  connect.CodeNotFound,
+		},
+		{
+			name:        "Empty account ID",
+			accountID:   "",
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.DeleteAccount(ctx, connect.NewRequest(&expensesv1.DeleteAccountRequest{
+				Id: tc.accountID,
+			}))
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.expectCode.String())
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+
+			if resp == nil || resp.Msg == nil || !resp.Msg.Success {
+				t.Fatalf("Expected success=true")
+			}
+
+			// Verify the account and its user links were actually deleted
+			var count int
+			if err := testDB.QueryRow(testDB.Rebind("SELECT COUNT(*) FROM accounts WHERE id = ?"), tc.accountID).Scan(&count); err != nil {
+				t.Fatalf("Failed to query deleted account: %v", err)
+			}
+			if count != 0 {
+				t.Errorf("Account was not deleted, found %d records", count)
+			}
+			if err := testDB.QueryRow(testDB.Rebind("SELECT COUNT(*) FROM account_users WHERE account_id = ?"), tc.accountID).Scan(&count); err != nil {
+				t.Fatalf("Failed to query account users: %v", err)
+			}
+			if count != 0 {
+				t.Errorf("Account users were not deleted, found %d records", count)
+			}
+		})
+	}
+}
+
+// TestAccountUsers tests the AddAccountUser, ListAccountUsers and RemoveAccountUser RPC methods
+func TestAccountUsers(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new AccountService with the test repositories
+	service := newTestAccountService()
+
+	// Create test data (using the main DB connection for setup)
+	testAccount := createTestAccount(t, testDB, "Joint Account", "at_asset", nil)
+	alice := createTestUser(t, testDB, "Alice", "alice@example.com")
+	bob := createTestUser(t, testDB, "Bob", "bob@example.com")
+
+	ctx := context.Background()
+
+	// Define add test cases
+	addTests := []struct {
+		name       string
+		accountID  string
+		userID     string
+		expectCode connect.Code
+	}{
+		{name: "Link Alice", accountID: testAccount.ID, userID: alice.ID},
+		{name: "Link Bob", accountID: testAccount.ID, userID: bob.ID},
+		{name: "Link Alice again", accountID: testAccount.ID, userID: alice.ID, expectCode: connect.CodeAlreadyExists},
+		{name: "Unknown account", accountID: "acc_nonexistent", userID: alice.ID, expectCode: connect.CodeNotFound},
+		{name: "Unknown user", accountID: testAccount.ID, userID: "usr_nonexistent", expectCode: connect.CodeNotFound},
+		{name: "Missing IDs", expectCode: connect.CodeInvalidArgument},
+	}
+	for _, tc := range addTests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.AddAccountUser(ctx, connect.NewRequest(&expensesv1.AddAccountUserRequest{
+				AccountId: tc.accountID,
+				UserId:    tc.userID,
+			}))
+			expectError := tc.expectCode != 0
+			assertError(t, err, expectError, tc.expectCode.String())
+			if expectError && connect.CodeOf(err) != tc.expectCode {
+				t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+			}
+		})
+	}
+
+	// Both users should now be listed, ordered by name
+	listResp, err := service.ListAccountUsers(ctx, connect.NewRequest(&expensesv1.ListAccountUsersRequest{
+		AccountId: testAccount.ID,
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error listing account users: %v", err)
+	}
+	if len(listResp.Msg.Users) != 2 || listResp.Msg.Users[0].Id != alice.ID || listResp.Msg.Users[1].Id != bob.ID {
+		t.Fatalf("Expected [Alice Bob], got %v", listResp.Msg.Users)
+	}
+
+	// Remove Bob, then removing him again should fail
+	if _, err := service.RemoveAccountUser(ctx, connect.NewRequest(&expensesv1.RemoveAccountUserRequest{
+		AccountId: testAccount.ID,
+		UserId:    bob.ID,
+	})); err != nil {
+		t.Fatalf("Unexpected error removing account user: %v", err)
+	}
+	_, err = service.RemoveAccountUser(ctx, connect.NewRequest(&expensesv1.RemoveAccountUserRequest{
+		AccountId: testAccount.ID,
+		UserId:    bob.ID,
+	}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("Expected code %v, got %v", connect.CodeNotFound, connect.CodeOf(err))
+	}
+
+	// An admin of another workspace cannot unlink Alice from the account
+	other, err := workspaceRepo.CreateWorkspace(ctx, testDB, "Other")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	otherCtx := authz.WithSubject(ctx, authz.NewSubject(bob.ID, other.ID, true, nil))
+	_, err = service.RemoveAccountUser(otherCtx, connect.NewRequest(&expensesv1.RemoveAccountUserRequest{
+		AccountId: testAccount.ID,
+		UserId:    alice.ID,
+	}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("Expected code %v, got %v", connect.CodeNotFound, connect.CodeOf(err))
+	}
+
+	listResp, err = service.ListAccountUsers(ctx, connect.NewRequest(&expensesv1.ListAccountUsersRequest{
+		AccountId: testAccount.ID,
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error listing account users: %v", err)
+	}
+	if len(listResp.Msg.Users) != 1 || listResp.Msg.Users[0].Id != alice.ID {
+		t.Errorf("Expected [Alice], got %v", listResp.Msg.Users)
+	}
+
+	// Listing users for an unknown account should fail
+	_, err = service.ListAccountUsers(ctx, connect.NewRequest(&expensesv1.ListAccountUsersRequest{
+		AccountId: "acc_nonexistent",
+	}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("Expected code %v, got %v", connect.CodeNotFound, connect.CodeOf(err))
+	}
+}
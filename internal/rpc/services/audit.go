@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// AuditService implements the AuditService interface defined in the proto
+type AuditService struct {
+	expensesv1connect.UnimplementedAuditServiceHandler
+	repo   *repo.AuditRepo
+	pages  *pagination.Codec
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(repo *repo.AuditRepo, pages *pagination.Codec, clock clock.Clock, logger *slog.Logger) *AuditService {
+	return &AuditService{
+		repo:   repo,
+		pages:  pages,
+		clock:  clock,
+		logger: logger,
+	}
+}
+
+// ListAuditEvents retrieves a paginated list of audit events, optionally
+// narrowed to a resource, an actor and a time range
+func (s *AuditService) ListAuditEvents(ctx context.Context, req *connect.Request[expensesv1.ListAuditEventsRequest]) (*connect.Response[expensesv1.ListAuditEventsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing audit events", "resource_type", req.Msg.GetResourceType(), "resource_id", req.Msg.GetResourceId(), "actor", req.Msg.GetActor(), "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Validate input
+	var startTime, endTime *time.Time
+	if req.Msg.StartTime != nil {
+		t := req.Msg.StartTime.AsTime().UTC()
+		startTime = &t
+	}
+	if req.Msg.EndTime != nil {
+		t := req.Msg.EndTime.AsTime().UTC()
+		endTime = &t
+	}
+	if startTime != nil && endTime != nil && !startTime.Before(*endTime) {
+		log.ErrorContext(ctx, s.logger, "Invalid input for ListAuditEvents", "error", "start_time must be before end_time")
+		return nil, errors.InvalidField("end_time", "start_time must be before end_time")
+	}
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("audit_events",
+		req.Msg.GetResourceType(), req.Msg.GetResourceId(), req.Msg.GetActor(),
+		formatOptionalTime(startTime), formatOptionalTime(endTime), req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering within the caller's workspace, narrowed
+	// to the requested resource, actor and time range
+	query, err := parseQuery(ctx, s.logger, repo.AuditEventFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("audit_events", authz.WorkspaceFrom(ctx)))
+	type restriction struct {
+		field, op string
+		value     any
+	}
+	var restrictions []restriction
+	if req.Msg.ResourceType != nil {
+		restrictions = append(restrictions, restriction{"resource_type", "=", *req.Msg.ResourceType})
+	}
+	if req.Msg.ResourceId != nil {
+		restrictions = append(restrictions, restriction{"resource_id", "=", *req.Msg.ResourceId})
+	}
+	if req.Msg.Actor != nil {
+		restrictions = append(restrictions, restriction{"actor", "=", *req.Msg.Actor})
+	}
+	if startTime != nil {
+		restrictions = append(restrictions, restriction{"occurred_at", ">=", *startTime})
+	}
+	if endTime != nil {
+		restrictions = append(restrictions, restriction{"occurred_at", "<", *endTime})
+	}
+	for _, r := range restrictions {
+		if err := query.Compare(r.field, r.op, r.value); err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to restrict audit events", "field", r.field, "error", err)
+			return nil, errors.Internal(err)
+		}
+	}
+
+	// Get audit events from database (read operations can use the main DB connection)
+	events, err := s.repo.ListAuditEvents(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list audit events", "error", err)
+		return nil, storageError(err)
+	}
+
+	events, pageResponse, err := paginate(ctx, s.logger, s.pages, page, events, func(row db.AuditEvent) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: strconv.FormatInt(row.ID, 10)}
+	}, func() (int64, error) {
+		return s.repo.CountAuditEvents(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to proto messages
+	protoEvents := make([]*expensesv1.AuditEvent, len(events))
+	for i, event := range events {
+		protoEvents[i] = toProtoAuditEvent(event)
+	}
+
+	log.InfoContext(ctx, s.logger, "Audit events retrieved successfully", "count", len(events))
+
+	return connect.NewResponse(&expensesv1.ListAuditEventsResponse{
+		AuditEvents:        protoEvents,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// formatOptionalTime formats a time for a page token filter, or "" for nil
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// toProtoAuditEvent converts a db.AuditEvent to a expensesv1.AuditEvent
+func toProtoAuditEvent(event db.AuditEvent) *expensesv1.AuditEvent {
+	return &expensesv1.AuditEvent{
+		Id:           event.ID,
+		ResourceType: event.ResourceType,
+		ResourceId:   event.ResourceID,
+		Action:       event.Action,
+		Before:       stringValue(event.BeforeState),
+		After:        stringValue(event.AfterState),
+		Actor:        event.Actor,
+		RequestId:    event.RequestID,
+		OccurredAt:   timestamppb.New(event.OccurredAt),
+	}
+}
+
+// auditor records audit events, and the changes of watched resources, for the
+// mutations of a service
+type auditor struct {
+	repo    *repo.AuditRepo
+	changes *repo.ChangeRepo
+	clock   clock.Clock
+	logger  *slog.Logger
+}
+
+// newAuditor creates an auditor writing to the audit and change logs of the
+// database of auditRepo
+func newAuditor(auditRepo *repo.AuditRepo, clock clock.Clock, logger *slog.Logger) auditor {
+	return auditor{
+		repo:    auditRepo,
+		changes: repo.NewChangeRepo(auditRepo.GetDB()),
+		clock:   clock,
+		logger:  logger,
+	}
+}
+
+// record writes an audit event for a mutation of a resource within the
+// transaction making it, so the event is kept exactly when the mutation is.
+// before and after are the API views of the resource, nil for the missing side
+// of a create or delete. Mutations of watched resources also record a change
+// for WatchChanges.
+func (a auditor) record(ctx context.Context, dbtx db.DBTX, resourceType, resourceID, action string, before, after proto.Message) error {
+	return a.recordIn(ctx, dbtx, authz.WorkspaceFrom(ctx), resourceType, resourceID, action, before, after)
+}
+
+// recordIn writes an audit event like record, into the audit log of a given
+// workspace rather than that of the request
+func (a auditor) recordIn(ctx context.Context, dbtx db.DBTX, workspaceID, resourceType, resourceID, action string, before, after proto.Message) error {
+	beforeState, err := audit.Snapshot(before)
+	if err != nil {
+		log.ErrorContext(ctx, a.logger, "Failed to snapshot resource", "resource_type", resourceType, "resource_id", resourceID, "error", err)
+		return errors.Internal(err)
+	}
+	afterState, err := audit.Snapshot(after)
+	if err != nil {
+		log.ErrorContext(ctx, a.logger, "Failed to snapshot resource", "resource_type", resourceType, "resource_id", resourceID, "error", err)
+		return errors.Internal(err)
+	}
+
+	request := audit.RequestFrom(ctx)
+	occurredAt := a.clock.Now().UTC()
+	_, err = a.repo.CreateAuditEvent(ctx, dbtx, db.CreateAuditEventParams{
+		WorkspaceID:  workspaceID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Action:       action,
+		BeforeState:  beforeState,
+		AfterState:   afterState,
+		Actor:        request.Actor,
+		RequestID:    request.ID,
+		OccurredAt:   occurredAt,
+	})
+	if err != nil {
+		log.ErrorContext(ctx, a.logger, "Failed to record audit event", "resource_type", resourceType, "resource_id", resourceID, "action", action, "error", err)
+		return storageError(err)
+	}
+
+	if !watchedResources[resourceType] {
+		return nil
+	}
+	err = a.changes.CreateChange(ctx, dbtx, db.CreateChangeParams{
+		WorkspaceID:  workspaceID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Action:       action,
+		AccountIds:   strings.Join(changeAccountIDs(before, after), ","),
+		OccurredAt:   occurredAt,
+	})
+	if err != nil {
+		log.ErrorContext(ctx, a.logger, "Failed to record change", "resource_type", resourceType, "resource_id", resourceID, "action", action, "error", err)
+		return storageError(err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// TestListAuditEvents tests the ListAuditEvents RPC method over the events
+// recorded by UserService mutations
+func TestListAuditEvents(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Mutate users with a clock of their own, advanced between mutations so
+	// the events can be told apart by time, on behalf of two actors
+	start := time.Date(2025, 4, 26, 12, 0, 0, 0, time.UTC)
+	auditClock := clock.NewMockClock(start)
+	userService := NewUserService(userRepo, auditRepo, testPages, auditClock, testLogger)
+	service := NewAuditService(auditRepo, testPages, auditClock, testLogger)
+	alice := audit.WithRequest(context.Background(), audit.Request{ID: "req-alice", Actor: "alice"})
+	bob := audit.WithRequest(context.Background(), audit.Request{ID: "req-bob", Actor: "bob"})
+
+	created, err := userService.CreateUser(alice, connect.NewRequest(&expensesv1.CreateUserRequest{Name: "Audited User", Email: "audited@example.com"}))
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	userID := created.Msg.User.Id
+
+	auditClock.SetTime(start.Add(time.Hour))
+	if _, err := userService.UpdateUser(bob, connect.NewRequest(&expensesv1.UpdateUserRequest{Id: userID, Name: "Renamed User", Email: "audited@example.com"})); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+
+	// A failed mutation is rolled back together with its audit event
+	auditClock.SetTime(start.Add(90 * time.Minute))
+	if _, err := userService.UpdateUser(bob, connect.NewRequest(&expensesv1.UpdateUserRequest{Id: userID, Name: "Lost Update", Email: "audited@example.com", Etag: created.Msg.User.Etag})); err == nil {
+		t.Fatalf("Expected update with a stale etag to fail")
+	}
+
+	auditClock.SetTime(start.Add(2 * time.Hour))
+	if _, err := userService.DeleteUser(alice, connect.NewRequest(&expensesv1.DeleteUserRequest{Id: userID})); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+
+	auditClock.SetTime(start.Add(3 * time.Hour))
+	other, err := userService.CreateUser(bob, connect.NewRequest(&expensesv1.CreateUserRequest{Name: "Other User", Email: "other@example.com"}))
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherID := other.Msg.User.Id
+	resourceType, actor := "user", "bob"
+
+	// Define test cases. Events are listed as "ACTION resource_id", newest
+	// first.
+	tests := []struct {
+		name         string
+		req          *expensesv1.ListAuditEventsRequest
+		expectError  bool
+		errorMsg     string
+		expectEvents []string
+	}{
+		{
+			name:         "All events",
+			req:          &expensesv1.ListAuditEventsRequest{},
+			expectEvents: []string{"CREATE " + otherID, "DELETE " + userID, "UPDATE " + userID, "CREATE " + userID},
+		},
+		{
+			name:         "By resource",
+			req:          &expensesv1.ListAuditEventsRequest{ResourceType: &resourceType, ResourceId: &userID},
+			expectEvents: []string{"DELETE " + userID, "UPDATE " + userID, "CREATE " + userID},
+		},
+		{
+			name:         "By actor",
+			req:          &expensesv1.ListAuditEventsRequest{Actor: &actor},
+			expectEvents: []string{"CREATE " + otherID, "UPDATE " + userID},
+		},
+		{
+			name:         "By time range",
+			req:          &expensesv1.ListAuditEventsRequest{StartTime: timestamppb.New(start.Add(time.Hour)), EndTime: timestamppb.New(start.Add(3 * time.Hour))},
+			expectEvents: []string{"DELETE " + userID, "UPDATE " + userID},
+		},
+		{
+			name:         "By filter",
+			req:          &expensesv1.ListAuditEventsRequest{Filter: `action = "DELETE"`},
+			expectEvents: []string{"DELETE " + userID},
+		},
+		{
+			name:         "Oldest first",
+			req:          &expensesv1.ListAuditEventsRequest{ResourceId: &userID, OrderBy: "occurred_at"},
+			expectEvents: []string{"CREATE " + userID, "UPDATE " + userID, "DELETE " + userID},
+		},
+		{
+			name:        "Empty time range",
+			req:         &expensesv1.ListAuditEventsRequest{StartTime: timestamppb.New(start.Add(time.Hour)), EndTime: timestamppb.New(start)},
+			expectError: true,
+			errorMsg:    "start_time must be before end_time",
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.ListAuditEvents(context.Background(), connect.NewRequest(tc.req))
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.errorMsg)
+
+			if !tc.expectError {
+				var got []string
+				for _, event := range resp.Msg.AuditEvents {
+					got = append(got, event.Action+" "+event.ResourceId)
+				}
+				if strings.Join(got, ", ") != strings.Join(tc.expectEvents, ", ") {
+					t.Errorf("Expected events %v, got %v", tc.expectEvents, got)
+				}
+				if resp.Msg.PaginationResponse.TotalCount != int32(len(tc.expectEvents)) {
+					t.Errorf("Expected total count %d, got %d", len(tc.expectEvents), resp.Msg.PaginationResponse.TotalCount)
+				}
+			}
+		})
+	}
+
+	// Verify the snapshots, actor, request ID and timestamp of each mutation
+	resp, err := service.ListAuditEvents(context.Background(), connect.NewRequest(&expensesv1.ListAuditEventsRequest{ResourceId: &userID, OrderBy: "occurred_at"}))
+	if err != nil {
+		t.Fatalf("Failed to list audit events: %v", err)
+	}
+	snapshots := []struct {
+		before, after string
+		actor         string
+		occurredAt    time.Time
+	}{
+		{"", `"name":"Audited User"`, "alice", start},
+		{`"name":"Audited User"`, `"name":"Renamed User"`, "bob", start.Add(time.Hour)},
+		{`"name":"Renamed User"`, "", "alice", start.Add(2 * time.Hour)},
+	}
+	for i, event := range resp.Msg.AuditEvents {
+		want := snapshots[i]
+		if (want.before == "") != (event.Before == "") || !strings.Contains(event.Before, want.before) {
+			t.Errorf("%s: expected before to contain %q, got %q", event.Action, want.before, event.Before)
+		}
+		if (want.after == "") != (event.After == "") || !strings.Contains(event.After, want.after) {
+			t.Errorf("%s: expected after to contain %q, got %q", event.Action, want.after, event.After)
+		}
+		if event.Actor != want.actor || event.RequestId != "req-"+want.actor {
+			t.Errorf("%s: expected actor %s with request ID req-%s, got %s with %s", event.Action, want.actor, want.actor, event.Actor, event.RequestId)
+		}
+		if !event.OccurredAt.AsTime().Equal(want.occurredAt) {
+			t.Errorf("%s: expected occurred_at %v, got %v", event.Action, want.occurredAt, event.OccurredAt.AsTime())
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// AuthService implements the AuthService interface defined in the proto
+type AuthService struct {
+	expensesv1connect.UnimplementedAuthServiceHandler
+	repo          *repo.AuthRepo
+	auditor       auditor
+	pages         *pagination.Codec
+	sessionTTL    time.Duration
+	secureCookies bool
+	clock         clock.Clock
+	logger        *slog.Logger
+}
+
+// NewAuthService creates a new AuthService. Sessions last sessionTTL, and
+// their cookies are marked Secure if secureCookies is set.
+func NewAuthService(
+	repo *repo.AuthRepo,
+	auditRepo *repo.AuditRepo,
+	pages *pagination.Codec,
+	sessionTTL time.Duration,
+	secureCookies bool,
+	clock clock.Clock,
+	logger *slog.Logger,
+) *AuthService {
+	return &AuthService{
+		repo:          repo,
+		auditor:       newAuditor(auditRepo, clock, logger),
+		pages:         pages,
+		sessionTTL:    sessionTTL,
+		secureCookies: secureCookies,
+		clock:         clock,
+		logger:        logger,
+	}
+}
+
+// CreateApiToken creates a personal API token for the authenticated user
+func (s *AuthService) CreateApiToken(ctx context.Context, req *connect.Request[expensesv1.CreateApiTokenRequest]) (*connect.Response[expensesv1.CreateApiTokenResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Creating api token", "name", req.Msg.Name)
+
+	// Validate input
+	identity, err := s.identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateApiToken", "error", "name is required")
+		return nil, errors.Required("name")
+	}
+	now := s.clock.Now().UTC()
+	var expiresAt *time.Time
+	if req.Msg.ExpiresAt != nil {
+		t := req.Msg.ExpiresAt.AsTime().UTC()
+		if !t.After(now) {
+			log.ErrorContext(ctx, s.logger, "Invalid input for CreateApiToken", "error", "expires_at must be in the future")
+			return nil, errors.InvalidField("expires_at", "expires_at must be in the future")
+		}
+		expiresAt = &t
+	}
+
+	// Generate the token, of which only the hash is stored
+	token, err := auth.NewAPIToken()
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to generate api token", "error", err)
+		return nil, errors.Internal(err)
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Create api token in database within the transaction
+	apiToken, err := s.repo.CreateAPIToken(ctx, tx, db.CreateApiTokenParams{
+		UserID:    identity.UserID,
+		Name:      req.Msg.Name,
+		TokenHash: auth.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to create api token", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "api_token", apiToken.ID, audit.ActionCreate, nil, toProtoAPIToken(apiToken)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Api token created successfully", "id", apiToken.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.CreateApiTokenResponse{
+		ApiToken: toProtoAPIToken(apiToken),
+		Token:    token,
+	}), nil
+}
+
+// ListApiTokens retrieves a paginated list of the unrevoked API tokens of the
+// authenticated user
+func (s *AuthService) ListApiTokens(ctx context.Context, req *connect.Request[expensesv1.ListApiTokensRequest]) (*connect.Response[expensesv1.ListApiTokensResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing api tokens", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	identity, err := s.identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("api_tokens", identity.UserID, req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering, narrowed to the authenticated user
+	query, err := parseQuery(ctx, s.logger, repo.APITokenFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	if err := query.Equal("user_id", identity.UserID); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to restrict api tokens by user", "error", err)
+		return nil, errors.Internal(err)
+	}
+
+	// Get api tokens from database (read operations can use the main DB connection)
+	tokens, err := s.repo.ListAPITokens(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list api tokens", "error", err)
+		return nil, storageError(err)
+	}
+
+	tokens, pageResponse, err := paginate(ctx, s.logger, s.pages, page, tokens, func(row db.ApiToken) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountAPITokens(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to proto messages
+	protoTokens := make([]*expensesv1.ApiToken, len(tokens))
+	for i, token := range tokens {
+		protoTokens[i] = toProtoAPIToken(token)
+	}
+
+	log.InfoContext(ctx, s.logger, "Api tokens retrieved successfully", "count", len(tokens))
+
+	return connect.NewResponse(&expensesv1.ListApiTokensResponse{
+		ApiTokens:          protoTokens,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// RevokeApiToken revokes an API token of the authenticated user
+func (s *AuthService) RevokeApiToken(ctx context.Context, req *connect.Request[expensesv1.RevokeApiTokenRequest]) (*connect.Response[expensesv1.RevokeApiTokenResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Revoking api token", "id", req.Msg.Id)
+
+	// Validate input
+	identity, err := s.identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for RevokeApiToken", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Revoke the api token within the transaction. Tokens of other users are
+	// reported as not found.
+	existing, err := s.repo.GetAPIToken(ctx, tx, req.Msg.Id, identity.UserID)
+	if err == nil {
+		err = s.repo.RevokeAPIToken(ctx, tx, req.Msg.Id, identity.UserID, s.clock.Now().UTC())
+	}
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Api token not found", "id", req.Msg.Id)
+			return nil, errors.NotFound("api_token", req.Msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to revoke api token", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "api_token", existing.ID, audit.ActionDelete, toProtoAPIToken(existing), nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Api token revoked successfully", "id", req.Msg.Id)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.RevokeApiTokenResponse{
+		Success: true,
+	}), nil
+}
+
+// CreateSession starts a cookie session for the user of the API token the
+// request is authenticated with, handing the session token to the browser in
+// the session cookie. This is how the web frontend signs in: sessions are only
+// minted from API tokens, so they cannot extend themselves past their TTL.
+func (s *AuthService) CreateSession(ctx context.Context, req *connect.Request[expensesv1.CreateSessionRequest]) (*connect.Response[expensesv1.CreateSessionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Creating session")
+
+	identity, err := s.identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if identity.APITokenID == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateSession", "error", "request is not authenticated by an api token")
+		return nil, errors.Error(connect.CodeFailedPrecondition, errors.ReasonAPITokenRequired, fmt.Errorf("%w: sessions are started with an api token", errors.ErrInvalidInput))
+	}
+
+	// Generate the session token, of which only the hash is stored
+	token, err := auth.NewSessionToken()
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to generate session token", "error", err)
+		return nil, errors.Internal(err)
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Create session in database within the transaction
+	now := s.clock.Now().UTC()
+	session, err := s.repo.CreateSession(ctx, tx, db.CreateSessionParams{
+		UserID:    identity.UserID,
+		TokenHash: auth.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.sessionTTL),
+	})
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to create session", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "session", session.ID, audit.ActionCreate, nil, toProtoSession(session)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Session created successfully", "id", session.ID)
+
+	// Prepare response, handing the token to the browser in the cookie only
+	resp := connect.NewResponse(&expensesv1.CreateSessionResponse{
+		Session: toProtoSession(session),
+	})
+	resp.Header().Add("Set-Cookie", auth.NewSessionCookie(token, session.ExpiresAt, s.secureCookies).String())
+	return resp, nil
+}
+
+// DeleteSession ends the cookie session the request is authenticated with and
+// clears the session cookie
+func (s *AuthService) DeleteSession(ctx context.Context, req *connect.Request[expensesv1.DeleteSessionRequest]) (*connect.Response[expensesv1.DeleteSessionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Deleting session")
+
+	// Validate input
+	identity, err := s.identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if identity.SessionID == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for DeleteSession", "error", "request is not authenticated by a session")
+		return nil, errors.Error(connect.CodeFailedPrecondition, errors.ReasonSessionRequired, fmt.Errorf("%w: request is not authenticated by a session", errors.ErrInvalidInput))
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Revoke the session within the transaction
+	if err := s.repo.RevokeSession(ctx, tx, identity.SessionID, s.clock.Now().UTC()); err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Session not found", "id", identity.SessionID)
+			return nil, errors.NotFound("session", identity.SessionID)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to delete session", "id", identity.SessionID, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "session", identity.SessionID, audit.ActionDelete, nil, nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Session deleted successfully", "id", identity.SessionID)
+
+	// Prepare response, clearing the cookie
+	resp := connect.NewResponse(&expensesv1.DeleteSessionResponse{
+		Success: true,
+	})
+	resp.Header().Add("Set-Cookie", auth.ClearSessionCookie(s.secureCookies).String())
+	return resp, nil
+}
+
+// identity returns the authenticated identity of a request, which the auth
+// interceptor guarantees for every request it lets through
+func (s *AuthService) identity(ctx context.Context) (auth.Identity, error) {
+	identity, ok := auth.IdentityFrom(ctx)
+	if !ok {
+		log.ErrorContext(ctx, s.logger, "Request is not authenticated")
+		return auth.Identity{}, errors.Unauthenticated(fmt.Errorf("%w: an api token or session is required", errors.ErrUnauthenticated))
+	}
+	return identity, nil
+}
+
+// toProtoAPIToken converts a db.ApiToken to a expensesv1.ApiToken
+func toProtoAPIToken(token db.ApiToken) *expensesv1.ApiToken {
+	return &expensesv1.ApiToken{
+		Id:        token.ID,
+		Name:      token.Name,
+		CreatedAt: timestamppb.New(token.CreatedAt),
+		ExpiresAt: timestampOrNil(token.ExpiresAt),
+	}
+}
+
+// toProtoSession converts a db.Session to a expensesv1.Session
+func toProtoSession(session db.Session) *expensesv1.Session {
+	return &expensesv1.Session{
+		Id:        session.ID,
+		CreatedAt: timestamppb.New(session.CreatedAt),
+		ExpiresAt: timestamppb.New(session.ExpiresAt),
+	}
+}
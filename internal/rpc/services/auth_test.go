@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TestAuthentication tests authenticating requests with API tokens and
+// cookie sessions through the auth interceptor
+func TestAuthentication(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+	ctx := context.Background()
+
+	// Serve the UserService and AuthService behind the interceptor
+	interceptor := connect.WithInterceptors(auth.NewInterceptor(authRepo, testClock, testLogger))
+	mux := http.NewServeMux()
+	mux.Handle(expensesv1connect.NewUserServiceHandler(NewUserService(userRepo, auditRepo, testPages, testClock, testLogger), interceptor))
+	mux.Handle(expensesv1connect.NewAuthServiceHandler(NewAuthService(authRepo, auditRepo, testPages, time.Hour, true, testClock, testLogger), interceptor))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	users := expensesv1connect.NewUserServiceClient(server.Client(), server.URL)
+	authClient := expensesv1connect.NewAuthServiceClient(server.Client(), server.URL)
+
+	// Seed a user with a valid, an expired and a revoked API token
+	user := createTestUser(t, testDB, "Token User", "token@example.com")
+	expired := testClock.Now().Add(-time.Minute)
+	_, validToken := createTestAPIToken(t, testDB, user.ID, "valid", nil)
+	_, expiredToken := createTestAPIToken(t, testDB, user.ID, "expired", &expired)
+	revoked, revokedToken := createTestAPIToken(t, testDB, user.ID, "revoked", nil)
+	if err := authRepo.RevokeAPIToken(ctx, testDB, revoked.ID, user.ID, testClock.Now()); err != nil {
+		t.Fatalf("Failed to revoke api token: %v", err)
+	}
+
+	// Start a session with the valid token, and end a second one right away
+	createSession := func() string {
+		req := connect.NewRequest(&expensesv1.CreateSessionRequest{})
+		req.Header().Set("Authorization", "Bearer "+validToken)
+		res, err := authClient.CreateSession(ctx, req)
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		cookie, err := http.ParseSetCookie(res.Header().Get("Set-Cookie"))
+		if err != nil || cookie.Name != auth.SessionCookie || !cookie.HttpOnly || !cookie.Secure {
+			t.Fatalf("Expected a secure session cookie, got %q", res.Header().Get("Set-Cookie"))
+		}
+		return cookie.Value
+	}
+	session := createSession()
+	endedSession := createSession()
+	req := connect.NewRequest(&expensesv1.DeleteSessionRequest{})
+	req.Header().Set("Cookie", auth.SessionCookie+"="+endedSession)
+	res, err := authClient.DeleteSession(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to delete session: %v", err)
+	}
+	if cookie, err := http.ParseSetCookie(res.Header().Get("Set-Cookie")); err != nil || cookie.MaxAge >= 0 {
+		t.Errorf("Expected the session cookie to be cleared, got %q", res.Header().Get("Set-Cookie"))
+	}
+
+	// Define test cases
+	tests := []struct {
+		name          string
+		authorization string
+		cookie        string
+		expectCode    connect.Code
+	}{
+		{
+			name:       "No credentials",
+			expectCode: connect.CodeUnauthenticated,
+		},
+		{
+			name:          "Valid api token",
+			authorization: "Bearer " + validToken,
+		},
+		{
+			name:          "Expired api token",
+			authorization: "Bearer " + expiredToken,
+			expectCode:    connect.CodeUnauthenticated,
+		},
+		{
+			name:          "Revoked api token",
+			authorization: "Bearer " + revokedToken,
+			expectCode:    connect.CodeUnauthenticated,
+		},
+		{
+			name:          "Unknown api token",
+			authorization: "Bearer emt_unknown",
+			expectCode:    connect.CodeUnauthenticated,
+		},
+		{
+			name:          "Other authorization scheme",
+			authorization: "Basic " + validToken,
+			expectCode:    connect.CodeUnauthenticated,
+		},
+		{
+			name:   "Valid session",
+			cookie: session,
+		},
+		{
+			name:       "Deleted session",
+			cookie:     endedSession,
+			expectCode: connect.CodeUnauthenticated,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := connect.NewRequest(&expensesv1.ListApiTokensRequest{})
+			if tc.authorization != "" {
+				req.Header().Set("Authorization", tc.authorization)
+			}
+			if tc.cookie != "" {
+				req.Header().Set("Cookie", auth.SessionCookie+"="+tc.cookie)
+			}
+			res, err := authClient.ListApiTokens(ctx, req)
+
+			if tc.expectCode != 0 {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Fatalf("Expected code %v, got %v", tc.expectCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			// The revoked token is not listed
+			if len(res.Msg.ApiTokens) != 2 {
+				t.Errorf("Expected 2 api tokens, got %d", len(res.Msg.ApiTokens))
+			}
+		})
+	}
+
+	// Other services see the authenticated user too
+	usersReq := connect.NewRequest(&expensesv1.ListUsersRequest{})
+	usersReq.Header().Set("Cookie", auth.SessionCookie+"="+session)
+	if _, err := users.ListUsers(ctx, usersReq); err != nil {
+		t.Errorf("Unexpected error listing users with a session: %v", err)
+	}
+}
+
+// TestSessionSignIn tests the web frontend's sign-in flow: exchanging an API
+// token for a session cookie, using and ending the session, and starting
+// sessions only from API tokens
+func TestSessionSignIn(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+	ctx := context.Background()
+
+	// Serve the AuthService behind the interceptor, on a clock of its own to
+	// let the session expire
+	sessionClock := clock.NewMockClock(testClock.Now())
+	interceptor := connect.WithInterceptors(auth.NewInterceptor(authRepo, sessionClock, testLogger))
+	mux := http.NewServeMux()
+	mux.Handle(expensesv1connect.NewAuthServiceHandler(NewAuthService(authRepo, auditRepo, testPages, time.Hour, true, sessionClock, testLogger), interceptor))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	authClient := expensesv1connect.NewAuthServiceClient(server.Client(), server.URL)
+
+	// Seed a user with an API token, as issued by token create
+	user := createTestUser(t, testDB, "Web User", "web@example.com")
+	_, apiToken := createTestAPIToken(t, testDB, user.ID, "web", nil)
+
+	// Define test cases, in order, as each step continues the session of the
+	// previous ones
+	var session string
+	tests := []struct {
+		name          string
+		call          string
+		authorization string
+		withSession   bool
+		advance       time.Duration
+		expectCode    connect.Code
+		expectReason  string
+		expectCookie  bool
+	}{
+		{
+			name:       "Sign in without credentials",
+			call:       "create",
+			expectCode: connect.CodeUnauthenticated,
+		},
+		{
+			name:          "Sign in with an api token",
+			call:          "create",
+			authorization: "Bearer " + apiToken,
+			expectCookie:  true,
+		},
+		{
+			name:        "Request with the session cookie only",
+			call:        "list",
+			withSession: true,
+		},
+		{
+			name:         "Session cannot start another session",
+			call:         "create",
+			withSession:  true,
+			expectCode:   connect.CodeFailedPrecondition,
+			expectReason: errors.ReasonAPITokenRequired,
+		},
+		{
+			name:        "Sign out",
+			call:        "delete",
+			withSession: true,
+		},
+		{
+			name:        "Request after signing out",
+			call:        "list",
+			withSession: true,
+			expectCode:  connect.CodeUnauthenticated,
+		},
+		{
+			name:          "Sign in again",
+			call:          "create",
+			authorization: "Bearer " + apiToken,
+			expectCookie:  true,
+		},
+		{
+			name:        "Request after the session expired",
+			call:        "list",
+			withSession: true,
+			advance:     2 * time.Hour,
+			expectCode:  connect.CodeUnauthenticated,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sessionClock.SetTime(sessionClock.Now().Add(tc.advance))
+			setCredentials := func(req connect.AnyRequest) {
+				if tc.authorization != "" {
+					req.Header().Set("Authorization", tc.authorization)
+				}
+				if tc.withSession {
+					req.Header().Set("Cookie", auth.SessionCookie+"="+session)
+				}
+			}
+
+			var header http.Header
+			var err error
+			switch tc.call {
+			case "create":
+				req := connect.NewRequest(&expensesv1.CreateSessionRequest{})
+				setCredentials(req)
+				var res *connect.Response[expensesv1.CreateSessionResponse]
+				if res, err = authClient.CreateSession(ctx, req); err == nil {
+					header = res.Header()
+				}
+			case "delete":
+				req := connect.NewRequest(&expensesv1.DeleteSessionRequest{})
+				setCredentials(req)
+				_, err = authClient.DeleteSession(ctx, req)
+			case "list":
+				req := connect.NewRequest(&expensesv1.ListApiTokensRequest{})
+				setCredentials(req)
+				_, err = authClient.ListApiTokens(ctx, req)
+			}
+
+			if tc.expectCode != 0 {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Fatalf("Expected code %v, got %v", tc.expectCode, err)
+				}
+				if tc.expectReason != "" {
+					if reason := errorReason(t, err); reason != tc.expectReason {
+						t.Errorf("Expected reason %s, got %s", tc.expectReason, reason)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.expectCookie {
+				cookie, err := http.ParseSetCookie(header.Get("Set-Cookie"))
+				if err != nil || cookie.Name != auth.SessionCookie || cookie.Value == "" {
+					t.Fatalf("Expected a session cookie, got %q", header.Get("Set-Cookie"))
+				}
+				session = cookie.Value
+			}
+		})
+	}
+}
+
+// TestApiTokens tests creating and revoking API tokens through the AuthService
+func TestApiTokens(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	service := NewAuthService(authRepo, auditRepo, testPages, time.Hour, true, testClock, testLogger)
+	owner := createTestUser(t, testDB, "Owner", "owner@example.com")
+	other := createTestUser(t, testDB, "Other", "other@example.com")
+	ownerCtx := auth.WithIdentity(context.Background(), auth.Identity{UserID: owner.ID, APITokenID: "tok_owner"})
+	otherCtx := auth.WithIdentity(context.Background(), auth.Identity{UserID: other.ID, APITokenID: "tok_other"})
+
+	// Create a token whose secret authenticates its owner
+	created, err := service.CreateApiToken(ownerCtx, connect.NewRequest(&expensesv1.CreateApiTokenRequest{Name: "cli"}))
+	if err != nil {
+		t.Fatalf("Failed to create api token: %v", err)
+	}
+	apiToken, err := authRepo.AuthenticateAPIToken(context.Background(), testDB, auth.HashToken(created.Msg.Token), testClock.Now())
+	if err != nil || apiToken.UserID != owner.ID {
+		t.Fatalf("Expected the token to authenticate %s, got %+v, %v", owner.ID, apiToken, err)
+	}
+
+	// Define test cases
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		create     *expensesv1.CreateApiTokenRequest
+		revokeID   string
+		expectCode connect.Code
+	}{
+		{
+			name:       "Create without name",
+			ctx:        ownerCtx,
+			create:     &expensesv1.CreateApiTokenRequest{},
+			expectCode: connect.CodeInvalidArgument,
+		},
+		{
+			name:       "Create already expired",
+			ctx:        ownerCtx,
+			create:     &expensesv1.CreateApiTokenRequest{Name: "old", ExpiresAt: timestamppb.New(testClock.Now().Add(-time.Hour))},
+			expectCode: connect.CodeInvalidArgument,
+		},
+		{
+			name:       "Create unauthenticated",
+			ctx:        context.Background(),
+			create:     &expensesv1.CreateApiTokenRequest{Name: "anonymous"},
+			expectCode: connect.CodeUnauthenticated,
+		},
+		{
+			name:       "Revoke token of another user",
+			ctx:        otherCtx,
+			revokeID:   created.Msg.ApiToken.Id,
+			expectCode: connect.CodeNotFound,
+		},
+		{
+			name:     "Revoke own token",
+			ctx:      ownerCtx,
+			revokeID: created.Msg.ApiToken.Id,
+		},
+		{
+			name:       "Revoke revoked token",
+			ctx:        ownerCtx,
+			revokeID:   created.Msg.ApiToken.Id,
+			expectCode: connect.CodeNotFound,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			if tc.create != nil {
+				_, err = service.CreateApiToken(tc.ctx, connect.NewRequest(tc.create))
+			} else {
+				_, err = service.RevokeApiToken(tc.ctx, connect.NewRequest(&expensesv1.RevokeApiTokenRequest{Id: tc.revokeID}))
+			}
+			if tc.expectCode != 0 {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Fatalf("Expected code %v, got %v", tc.expectCode, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	// The revoked token no longer authenticates
+	if _, err := authRepo.AuthenticateAPIToken(context.Background(), testDB, auth.HashToken(created.Msg.Token), testClock.Now()); err == nil {
+		t.Error("Expected the revoked token not to authenticate")
+	}
+}
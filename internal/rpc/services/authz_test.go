@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+	"github.com/atreya2011/expense-manager/internal/validate"
+)
+
+// TestAuthorization tests authorizing requests by admin role and account
+// membership through the interceptors, in the order the server chains them.
+// Malformed requests are refused by validation before authorization.
+func TestAuthorization(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+	ctx := context.Background()
+
+	// Serve the AccountService and InstrumentService behind the interceptors
+	interceptors := connect.WithInterceptors(
+		audit.NewInterceptor(),
+		auth.NewInterceptor(authRepo, testClock, testLogger),
+		validate.NewInterceptor(testLogger),
+		authz.NewInterceptor(repo.NewAuthzRepo(testDB), auditRepo, testClock, testLogger),
+	)
+	mux := http.NewServeMux()
+	mux.Handle(expensesv1connect.NewAccountServiceHandler(newTestAccountService(), interceptors))
+	mux.Handle(expensesv1connect.NewInstrumentServiceHandler(NewInstrumentService(instrumentRepo, auditRepo, testPages, testClock, testLogger), interceptors))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	accounts := expensesv1connect.NewAccountServiceClient(server.Client(), server.URL)
+	instruments := expensesv1connect.NewInstrumentServiceClient(server.Client(), server.URL)
+
+	// Seed an admin and a member with an API token each
+	admin := createTestUser(t, testDB, "Admin", "admin@example.com")
+	if err := workspaceRepo.SetWorkspaceUserAdmin(ctx, testDB, repo.DefaultWorkspaceID, admin.ID, true); err != nil {
+		t.Fatalf("Failed to make user admin: %v", err)
+	}
+	member := createTestUser(t, testDB, "Member", "member@example.com")
+	_, adminToken := createTestAPIToken(t, testDB, admin.ID, "admin", nil)
+	_, memberToken := createTestAPIToken(t, testDB, member.ID, "member", nil)
+	withToken := func(req connect.AnyRequest, token string) {
+		req.Header().Set("Authorization", "Bearer "+token)
+	}
+
+	// The member opens an account, becoming its member
+	createReq := connect.NewRequest(&expensesv1.CreateAccountRequest{Name: "Wallet", AccountTypeId: "at_asset"})
+	withToken(createReq, memberToken)
+	created, err := accounts.CreateAccount(ctx, createReq)
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	accountID := created.Msg.Account.Id
+
+	// Define test cases
+	tests := []struct {
+		name       string
+		call       func(token string) error
+		token      string
+		expectCode connect.Code
+	}{
+		{
+			name: "Admin manages master data",
+			call: func(token string) error {
+				req := connect.NewRequest(&expensesv1.CreateInstrumentRequest{Name: "Cash"})
+				withToken(req, token)
+				_, err := instruments.CreateInstrument(ctx, req)
+				return err
+			},
+			token: adminToken,
+		},
+		{
+			name: "Member cannot manage master data",
+			call: func(token string) error {
+				req := connect.NewRequest(&expensesv1.CreateInstrumentRequest{Name: "Card"})
+				withToken(req, token)
+				_, err := instruments.CreateInstrument(ctx, req)
+				return err
+			},
+			token:      memberToken,
+			expectCode: connect.CodePermissionDenied,
+		},
+		{
+			name: "Malformed request is refused before authorization",
+			call: func(token string) error {
+				req := connect.NewRequest(&expensesv1.CreateInstrumentRequest{})
+				withToken(req, token)
+				_, err := instruments.CreateInstrument(ctx, req)
+				return err
+			},
+			token:      memberToken,
+			expectCode: connect.CodeInvalidArgument,
+		},
+		{
+			name: "Member reads own account",
+			call: func(token string) error {
+				req := connect.NewRequest(&expensesv1.GetAccountRequest{Id: accountID})
+				withToken(req, token)
+				_, err := accounts.GetAccount(ctx, req)
+				return err
+			},
+			token: memberToken,
+		},
+		{
+			name: "Admin cannot read other account",
+			call: func(token string) error {
+				req := connect.NewRequest(&expensesv1.GetAccountRequest{Id: accountID})
+				withToken(req, token)
+				_, err := accounts.GetAccount(ctx, req)
+				return err
+			},
+			token:      adminToken,
+			expectCode: connect.CodePermissionDenied,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call(tc.token)
+
+			if tc.expectCode != 0 {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Fatalf("Expected code %v, got %v", tc.expectCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	// Accounts are listed to their members only
+	for token, expected := range map[string]int{memberToken: 1, adminToken: 0} {
+		req := connect.NewRequest(&expensesv1.ListAccountsRequest{})
+		withToken(req, token)
+		res, err := accounts.ListAccounts(ctx, req)
+		if err != nil {
+			t.Fatalf("Failed to list accounts: %v", err)
+		}
+		if len(res.Msg.Accounts) != expected {
+			t.Errorf("Expected %d accounts, got %d", expected, len(res.Msg.Accounts))
+		}
+	}
+
+	// Denials are recorded with the denied user as actor
+	events, err := auditRepo.ListLatestAuditEvents(ctx, testDB, 100)
+	if err != nil {
+		t.Fatalf("Failed to list audit events: %v", err)
+	}
+	denials := map[string]string{}
+	denied := 0
+	for _, event := range events {
+		if event.Action == audit.ActionDeny {
+			denials[event.ResourceID] = event.Actor
+			denied++
+		}
+	}
+	if denied != 2 {
+		t.Errorf("Expected 2 denials, the malformed request not among them, got %d", denied)
+	}
+	if actor := denials[expensesv1connect.InstrumentServiceCreateInstrumentProcedure]; actor != member.ID {
+		t.Errorf("Expected a denial of %s, got actor %q", member.ID, actor)
+	}
+	if actor := denials[expensesv1connect.AccountServiceGetAccountProcedure]; actor != admin.ID {
+		t.Errorf("Expected a denial of %s, got actor %q", admin.ID, actor)
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/validate"
+)
+
+// batchSavepoint is the savepoint each request of a batch runs under, so a
+// failed request can be undone without aborting the transaction
+const batchSavepoint = "batch_request"
+
+// runBatch applies apply to each of the requests of a batch within dbtx,
+// validating each request first, as the validation interceptor only checks
+// the batch itself. Each request runs under a savepoint which a failure rolls
+// back to, and fails with a BatchError naming its index. Unless allowPartial
+// is set any failure fails the whole batch, and the caller rolls the
+// transaction back; otherwise the failures are returned alongside the
+// successes to commit.
+func runBatch[T proto.Message](ctx context.Context, logger *slog.Logger, dbtx db.DBTX, requests []T, allowPartial bool, apply func(T) error) ([]*expensesv1.BatchError, error) {
+	var (
+		failures []*expensesv1.BatchError
+		code     connect.Code
+	)
+	fail := func(index int, err error) {
+		if len(failures) == 0 {
+			code = connect.CodeOf(err)
+		}
+		failures = append(failures, batchError(index, err))
+	}
+	for i, req := range requests {
+		if err := validate.Message(req); err != nil {
+			log.ErrorContext(ctx, logger, "Invalid batch request", "index", i, "error", err)
+			fail(i, err)
+			continue
+		}
+
+		if _, err := dbtx.ExecContext(ctx, "SAVEPOINT "+batchSavepoint); err != nil {
+			log.ErrorContext(ctx, logger, "Failed to create savepoint", "index", i, "error", err)
+			return nil, storageError(fmt.Errorf("failed to create savepoint: %w", err))
+		}
+		if err := apply(req); err != nil {
+			fail(i, err)
+			if _, err := dbtx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+batchSavepoint); err != nil {
+				log.ErrorContext(ctx, logger, "Failed to roll back to savepoint", "index", i, "error", err)
+				return nil, storageError(fmt.Errorf("failed to roll back to savepoint: %w", err))
+			}
+		}
+		if _, err := dbtx.ExecContext(ctx, "RELEASE SAVEPOINT "+batchSavepoint); err != nil {
+			log.ErrorContext(ctx, logger, "Failed to release savepoint", "index", i, "error", err)
+			return nil, storageError(fmt.Errorf("failed to release savepoint: %w", err))
+		}
+	}
+
+	if len(failures) > 0 && !allowPartial {
+		log.ErrorContext(ctx, logger, "Batch failed", "failed", len(failures), "requests", len(requests))
+		return nil, batchFailed(code, failures, len(requests))
+	}
+	return failures, nil
+}
+
+// batchError converts the error of the request at index of a batch into a
+// BatchError carrying its code, message and details
+func batchError(index int, err error) *expensesv1.BatchError {
+	batchErr := &expensesv1.BatchError{
+		Index:   int32(index),
+		Code:    connect.CodeOf(err).String(),
+		Message: err.Error(),
+	}
+	var connectErr *connect.Error
+	if stderrors.As(err, &connectErr) {
+		batchErr.Message = connectErr.Message()
+		for _, detail := range connectErr.Details() {
+			batchErr.Details = append(batchErr.Details, &anypb.Any{
+				TypeUrl: "type.googleapis.com/" + detail.Type(),
+				Value:   detail.Bytes(),
+			})
+		}
+	}
+	return batchErr
+}
+
+// batchFailed creates the error of a batch its failures undid as a whole,
+// with the code of its first failure and a BatchError detail per failure
+func batchFailed(code connect.Code, failures []*expensesv1.BatchError, requests int) *connect.Error {
+	first := failures[0]
+	details := make([]proto.Message, len(failures))
+	for i, failure := range failures {
+		details[i] = failure
+	}
+	return errors.Error(code, errors.ReasonBatchFailed,
+		fmt.Errorf("%d of %d requests failed, requests[%d]: %s", len(failures), requests, first.Index, first.Message), details...)
+}
@@ -0,0 +1,706 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/etag"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// CategoryService implements the CategoryService interface defined in the proto
+type CategoryService struct {
+	expensesv1connect.UnimplementedCategoryServiceHandler
+	repo            *repo.CategoryRepo
+	transactionRepo *repo.TransactionRepo
+	auditor         auditor
+	pages           *pagination.Codec
+	clock           clock.Clock
+	logger          *slog.Logger
+}
+
+// NewCategoryService creates a new CategoryService
+func NewCategoryService(repo *repo.CategoryRepo, transactionRepo *repo.TransactionRepo, auditRepo *repo.AuditRepo, pages *pagination.Codec, clock clock.Clock, logger *slog.Logger) *CategoryService {
+	return &CategoryService{
+		repo:            repo,
+		transactionRepo: transactionRepo,
+		auditor:         newAuditor(auditRepo, clock, logger),
+		pages:           pages,
+		clock:           clock,
+		logger:          logger,
+	}
+}
+
+// CreateCategory creates a new category
+func (s *CategoryService) CreateCategory(ctx context.Context, req *connect.Request[expensesv1.CreateCategoryRequest]) (*connect.Response[expensesv1.CreateCategoryResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Creating category", "name", req.Msg.Name, "parent_id", req.Msg.GetParentId())
+
+	// Validate input
+	if req.Msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateCategory", "error", "name is required")
+		return nil, errors.Required("name")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// A new category has no descendants, so only the parent's existence matters
+	parentID := nullableString(req.Msg.ParentId)
+	if err := s.validateParent(ctx, tx, "", parentID); err != nil {
+		return nil, err
+	}
+
+	// Create category in database within the transaction
+	category, err := s.repo.CreateCategory(ctx, tx, db.CreateCategoryParams{
+		WorkspaceID: authz.WorkspaceFrom(ctx),
+		ParentID:    parentID,
+		Name:        req.Msg.Name,
+		Description: nullableString(&req.Msg.Description),
+	})
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "Category already exists", "name", req.Msg.Name)
+			return nil, errors.AlreadyExists("category", req.Msg.Name, "name", "category with name %s already exists", req.Msg.Name)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to create category", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "category", category.ID, audit.ActionCreate, nil, toProtoCategory(category)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Category created successfully", "id", category.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.CreateCategoryResponse{
+		Category: toProtoCategory(category),
+	}), nil
+}
+
+// GetCategory retrieves a category by ID
+func (s *CategoryService) GetCategory(ctx context.Context, req *connect.Request[expensesv1.GetCategoryRequest]) (*connect.Response[expensesv1.GetCategoryResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Getting category", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for GetCategory", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Get category from database (read operations can use the main DB connection)
+	category, err := s.getCategory(ctx, s.repo.GetDB(), req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	log.InfoContext(ctx, s.logger, "Category retrieved successfully", "id", category.ID)
+
+	// Prepare response, exposing the etag for conditional HTTP GETs
+	resp := connect.NewResponse(&expensesv1.GetCategoryResponse{
+		Category: toProtoCategory(category),
+	})
+	resp.Header().Set(etag.Header, resp.Msg.Category.Etag)
+	return resp, nil
+}
+
+// ListCategories retrieves a list of categories with optional pagination
+func (s *CategoryService) ListCategories(ctx context.Context, req *connect.Request[expensesv1.ListCategoriesRequest]) (*connect.Response[expensesv1.ListCategoriesResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing categories", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("categories", req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering within the caller's workspace
+	query, err := parseQuery(ctx, s.logger, repo.CategoryFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("categories", authz.WorkspaceFrom(ctx)))
+
+	// Get categories from database (read operations can use the main DB connection)
+	categories, err := s.repo.ListCategories(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list categories", "error", err)
+		return nil, storageError(err)
+	}
+
+	categories, pageResponse, err := paginate(ctx, s.logger, s.pages, page, categories, func(row db.Category) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountCategories(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to proto messages
+	protoCategories := make([]*expensesv1.Category, len(categories))
+	for i, category := range categories {
+		protoCategories[i] = toProtoCategory(category)
+	}
+
+	log.InfoContext(ctx, s.logger, "Categories retrieved successfully", "count", len(categories))
+
+	return connect.NewResponse(&expensesv1.ListCategoriesResponse{
+		Categories:         protoCategories,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// categoryUpdatePaths are the fields of a category UpdateCategory may change
+var categoryUpdatePaths = []string{"name", "description", "parent_id"}
+
+// UpdateCategory updates the fields of an existing category selected by the
+// update mask
+func (s *CategoryService) UpdateCategory(ctx context.Context, req *connect.Request[expensesv1.UpdateCategoryRequest]) (*connect.Response[expensesv1.UpdateCategoryResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Updating category", "id", req.Msg.Id, "name", req.Msg.Name, "parent_id", req.Msg.GetParentId(), "update_mask", req.Msg.GetUpdateMask().GetPaths())
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateCategory", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+	mask, err := parseUpdateMask(ctx, s.logger, req.Msg.UpdateMask, categoryUpdatePaths)
+	if err != nil {
+		return nil, err
+	}
+	if mask.has("name") && req.Msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateCategory", "error", "name is required")
+		return nil, errors.Required("name")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if category exists and that the new parent keeps the hierarchy acyclic
+	existing, err := s.getCategory(ctx, tx, req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkETag(ctx, s.logger, "category", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+	parentID := nullableString(req.Msg.ParentId)
+	if mask.has("parent_id") {
+		if err := s.validateParent(ctx, tx, req.Msg.Id, parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Update the masked fields of the category within the transaction
+	category, err := s.repo.UpdateCategory(ctx, tx, req.Msg.Id, existing.Revision, mask.pick(map[string]any{
+		"name":        req.Msg.Name,
+		"description": nullableString(&req.Msg.Description),
+		"parent_id":   parentID,
+	}))
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "Category name already exists", "name", req.Msg.Name)
+			return nil, errors.AlreadyExists("category", req.Msg.Name, "name", "category with name %s already exists", req.Msg.Name)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to update category", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "category", category.ID, audit.ActionUpdate, toProtoCategory(existing), toProtoCategory(category)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Category updated successfully", "id", category.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.UpdateCategoryResponse{
+		Category: toProtoCategory(category),
+	}), nil
+}
+
+// DeleteCategory deletes a category according to the requested mode
+func (s *CategoryService) DeleteCategory(ctx context.Context, req *connect.Request[expensesv1.DeleteCategoryRequest]) (*connect.Response[expensesv1.DeleteCategoryResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Deleting category", "id", req.Msg.Id, "mode", req.Msg.Mode.String())
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for DeleteCategory", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if category exists within the transaction
+	category, err := s.getCategory(ctx, tx, req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkETag(ctx, s.logger, "category", category.ID, req.Msg.Etag, category.Revision, category.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Delete category within the transaction
+	switch req.Msg.Mode {
+	case expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_UNSPECIFIED, expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_RESTRICT:
+		err = s.deleteRestrict(ctx, tx, category)
+	case expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_REASSIGN:
+		err = s.deleteReassign(ctx, tx, category)
+	case expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_CASCADE:
+		err = s.deleteCascade(ctx, tx, category.ID)
+	default:
+		log.ErrorContext(ctx, s.logger, "Invalid input for DeleteCategory", "error", "unknown delete mode", "mode", req.Msg.Mode)
+		return nil, errors.InvalidField("mode", "unknown delete mode %d", req.Msg.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "category", category.ID, audit.ActionDelete, toProtoCategory(category), nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Category deleted successfully", "id", req.Msg.Id)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.DeleteCategoryResponse{
+		Success: true,
+	}), nil
+}
+
+// MoveCategory moves a category under a new parent
+func (s *CategoryService) MoveCategory(ctx context.Context, req *connect.Request[expensesv1.MoveCategoryRequest]) (*connect.Response[expensesv1.MoveCategoryResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Moving category", "id", req.Msg.Id, "new_parent_id", req.Msg.GetNewParentId())
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for MoveCategory", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if category exists and that the new parent keeps the hierarchy acyclic
+	existing, err := s.getCategory(ctx, tx, req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkETag(ctx, s.logger, "category", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+	parentID := nullableString(req.Msg.NewParentId)
+	if err := s.validateParent(ctx, tx, req.Msg.Id, parentID); err != nil {
+		return nil, err
+	}
+
+	// Move category within the transaction
+	category, err := s.repo.MoveCategory(ctx, tx, req.Msg.Id, existing.Revision, parentID)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to move category", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "category", category.ID, audit.ActionUpdate, toProtoCategory(existing), toProtoCategory(category)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Category moved successfully", "id", category.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.MoveCategoryResponse{
+		Category: toProtoCategory(category),
+	}), nil
+}
+
+// GetCategoryTree retrieves the whole category hierarchy, or the subtree below
+// a single category when a root is given
+func (s *CategoryService) GetCategoryTree(ctx context.Context, req *connect.Request[expensesv1.GetCategoryTreeRequest]) (*connect.Response[expensesv1.GetCategoryTreeResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Getting category tree", "root_id", req.Msg.GetRootId())
+
+	// Walk the hierarchy (read operations can use the main DB connection)
+	var rows []db.ListCategorySubtreeRow
+	if rootID := nullableString(req.Msg.RootId); rootID != nil {
+		if _, err := s.getCategory(ctx, s.repo.GetDB(), *rootID); err != nil {
+			return nil, err
+		}
+		subtree, err := s.repo.ListSubtree(ctx, s.repo.GetDB(), *rootID)
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to get category tree", "root_id", *rootID, "error", err)
+			return nil, storageError(err)
+		}
+		rows = subtree
+	} else {
+		forest, err := s.repo.ListForest(ctx, s.repo.GetDB(), authz.WorkspaceFrom(ctx))
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to get category tree", "error", err)
+			return nil, storageError(err)
+		}
+		for _, row := range forest {
+			rows = append(rows, db.ListCategorySubtreeRow(row))
+		}
+	}
+
+	log.InfoContext(ctx, s.logger, "Category tree retrieved successfully", "count", len(rows))
+
+	return connect.NewResponse(&expensesv1.GetCategoryTreeResponse{
+		Roots: buildCategoryTree(rows),
+	}), nil
+}
+
+// ListDescendants retrieves all descendants of a category
+func (s *CategoryService) ListDescendants(ctx context.Context, req *connect.Request[expensesv1.ListDescendantsRequest]) (*connect.Response[expensesv1.ListDescendantsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing category descendants", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for ListDescendants", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Walk the subtree (read operations can use the main DB connection)
+	if _, err := s.getCategory(ctx, s.repo.GetDB(), req.Msg.Id); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListSubtree(ctx, s.repo.GetDB(), req.Msg.Id)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list category descendants", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Skip the category itself, which is the only row at depth 0
+	protoCategories := make([]*expensesv1.Category, 0, len(rows))
+	for _, row := range rows {
+		if row.Depth > 0 {
+			protoCategories = append(protoCategories, toProtoCategory(categoryFromRow(row)))
+		}
+	}
+
+	log.InfoContext(ctx, s.logger, "Category descendants retrieved successfully", "id", req.Msg.Id, "count", len(protoCategories))
+
+	return connect.NewResponse(&expensesv1.ListDescendantsResponse{
+		Categories: protoCategories,
+	}), nil
+}
+
+// getCategory fetches a category and maps a missing row to NotFound
+func (s *CategoryService) getCategory(ctx context.Context, dbtx db.DBTX, id string) (db.Category, error) {
+	category, err := s.repo.GetCategory(ctx, dbtx, authz.WorkspaceFrom(ctx), id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Category not found", "id", id)
+			return db.Category{}, errors.NotFound("category", id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get category", "id", id, "error", err)
+		return db.Category{}, storageError(err)
+	}
+	return category, nil
+}
+
+// validateParent checks that a parent exists and that placing the category
+// under it would not create a cycle. An empty id means the category is new.
+func (s *CategoryService) validateParent(ctx context.Context, dbtx db.DBTX, id string, parentID *string) error {
+	if parentID == nil {
+		return nil
+	}
+	if _, err := s.repo.GetCategory(ctx, dbtx, authz.WorkspaceFrom(ctx), *parentID); err != nil {
+		return referenceError(ctx, s.logger, "parent_id", *parentID, err)
+	}
+	if id == "" {
+		return nil
+	}
+
+	// The parent must not be the category itself or any of its descendants
+	subtree, err := s.repo.ListSubtree(ctx, dbtx, id)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list category subtree", "id", id, "error", err)
+		return storageError(err)
+	}
+	for _, row := range subtree {
+		if row.ID == *parentID {
+			log.ErrorContext(ctx, s.logger, "Category parent would create a cycle", "id", id, "parent_id", *parentID)
+			return errors.InvalidField("parent_id", "moving category %s under %s would create a cycle", id, *parentID)
+		}
+	}
+	return nil
+}
+
+// deleteRestrict deletes a category only if nothing depends on it
+func (s *CategoryService) deleteRestrict(ctx context.Context, dbtx db.DBTX, category db.Category) error {
+	id := category.ID
+	children, err := s.repo.CountChildren(ctx, dbtx, id)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to count child categories", "id", id, "error", err)
+		return storageError(err)
+	}
+	if children > 0 {
+		log.ErrorContext(ctx, s.logger, "Category has children", "id", id, "children", children)
+		return errors.InUse("category", id, "category %s has %d child categories", id, children)
+	}
+
+	references, err := s.repo.CountReferences(ctx, dbtx, id)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to count category references", "id", id, "error", err)
+		return storageError(err)
+	}
+	if references > 0 {
+		log.ErrorContext(ctx, s.logger, "Category is referenced", "id", id, "references", references)
+		return errors.InUse("category", id, "category %s is referenced by %d transactions or ledger entries", id, references)
+	}
+
+	return s.deleteCategory(ctx, dbtx, id, category.Revision)
+}
+
+// deleteReassign hands the children and references of a category to its
+// parent before deleting it, recording an update of each child it moves
+func (s *CategoryService) deleteReassign(ctx context.Context, dbtx db.DBTX, category db.Category) error {
+	subtree, err := s.repo.ListSubtree(ctx, dbtx, category.ID)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list category subtree", "id", category.ID, "error", err)
+		return storageError(err)
+	}
+	if err := s.repo.ReassignChildren(ctx, dbtx, category.ID, category.ParentID); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to reassign child categories", "id", category.ID, "error", err)
+		return storageError(err)
+	}
+	for _, row := range subtree {
+		if row.Depth != 1 {
+			continue
+		}
+		child, err := s.getCategory(ctx, dbtx, row.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.auditor.record(ctx, dbtx, "category", child.ID, audit.ActionUpdate, toProtoCategory(categoryFromRow(row)), toProtoCategory(child)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.reassignReferences(ctx, dbtx, category.ID, category.ParentID); err != nil {
+		return err
+	}
+	return s.deleteCategory(ctx, dbtx, category.ID, category.Revision)
+}
+
+// deleteCascade deletes a category with its whole subtree, clearing any
+// transaction or ledger entry references to the deleted categories. The
+// deletion of the category itself is recorded by the caller, that of each
+// descendant here.
+func (s *CategoryService) deleteCascade(ctx context.Context, dbtx db.DBTX, id string) error {
+	subtree, err := s.repo.ListSubtree(ctx, dbtx, id)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list category subtree", "id", id, "error", err)
+		return storageError(err)
+	}
+
+	// Delete the deepest categories first so no child outlives its parent
+	for i := len(subtree) - 1; i >= 0; i-- {
+		if err := s.reassignReferences(ctx, dbtx, subtree[i].ID, nil); err != nil {
+			return err
+		}
+		if err := s.deleteCategory(ctx, dbtx, subtree[i].ID, subtree[i].Revision); err != nil {
+			return err
+		}
+		if subtree[i].Depth == 0 {
+			continue
+		}
+		if err := s.auditor.record(ctx, dbtx, "category", subtree[i].ID, audit.ActionDelete, toProtoCategory(categoryFromRow(subtree[i])), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reassignReferences points the transactions and ledger entries of a category
+// at another category, or clears them for a nil one, recording an update of
+// each transaction it changes
+func (s *CategoryService) reassignReferences(ctx context.Context, dbtx db.DBTX, id string, newCategoryID *string) error {
+	existing, err := s.repo.ListTransactions(ctx, dbtx, id)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list category transactions", "id", id, "error", err)
+		return storageError(err)
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	before, err := loadTransactions(ctx, s.logger, s.transactionRepo, dbtx, existing)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.ReassignReferences(ctx, dbtx, id, newCategoryID); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to reassign category references", "id", id, "error", err)
+		return storageError(err)
+	}
+
+	// Reload the transactions to record them as they are now
+	updated := make([]db.Transaction, len(existing))
+	for i, transaction := range existing {
+		updated[i], err = s.transactionRepo.GetTransaction(ctx, dbtx, authz.WorkspaceFrom(ctx), transaction.ID)
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to get transaction", "id", transaction.ID, "error", err)
+			return storageError(err)
+		}
+	}
+	after, err := loadTransactions(ctx, s.logger, s.transactionRepo, dbtx, updated)
+	if err != nil {
+		return err
+	}
+	for i := range updated {
+		if err := s.auditor.record(ctx, dbtx, "transaction", updated[i].ID, audit.ActionUpdate, before[i], after[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteCategory removes a single category row at the revision it was read at
+func (s *CategoryService) deleteCategory(ctx context.Context, dbtx db.DBTX, id string, revision int64) error {
+	if err := s.repo.DeleteCategory(ctx, dbtx, id, revision); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to delete category", "id", id, "error", err)
+		return storageError(err)
+	}
+	return nil
+}
+
+// buildCategoryTree assembles depth-ordered rows into nested nodes. Rows at
+// the smallest depth become the roots of the returned forest.
+func buildCategoryTree(rows []db.ListCategorySubtreeRow) []*expensesv1.CategoryNode {
+	roots := []*expensesv1.CategoryNode{}
+	nodes := make(map[string]*expensesv1.CategoryNode, len(rows))
+	for _, row := range rows {
+		node := &expensesv1.CategoryNode{
+			Category: toProtoCategory(categoryFromRow(row)),
+			Depth:    int32(row.Depth),
+		}
+		nodes[row.ID] = node
+
+		parent, ok := nodes[stringValue(row.ParentID)]
+		if row.Depth == 0 || !ok {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return roots
+}
+
+// categoryFromRow converts a hierarchy row back into a db.Category
+func categoryFromRow(row db.ListCategorySubtreeRow) db.Category {
+	return db.Category{
+		ID:          row.ID,
+		ParentID:    row.ParentID,
+		Name:        row.Name,
+		Description: row.Description,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+		Revision:    row.Revision,
+	}
+}
+
+// toProtoCategory converts a db.Category to a expensesv1.Category
+func toProtoCategory(category db.Category) *expensesv1.Category {
+	return &expensesv1.Category{
+		Id:          category.ID,
+		ParentId:    category.ParentID,
+		Name:        category.Name,
+		Description: stringValue(category.Description),
+		CreatedAt:   timestamppb.New(category.CreatedAt),
+		UpdatedAt:   timestamppb.New(category.UpdatedAt),
+		Etag:        etag.Compute(category.Revision, category.UpdatedAt),
+	}
+}
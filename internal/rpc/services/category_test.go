@@ -0,0 +1,504 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/atreya2011/expense-manager/internal/repo"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// setupCategoryTree resets the database and creates the hierarchy
+// Food > Groceries > Produce, Food > Dining, and a separate Transport
+func setupCategoryTree(t *testing.T) {
+	t.Helper()
+
+	resetTestDB(t)
+	food, groceries := "cat_food", "cat_groceries"
+	createTestCategory(t, testDB, "cat_food", "Food", nil)
+	createTestCategory(t, testDB, "cat_groceries", "Groceries", &food)
+	createTestCategory(t, testDB, "cat_produce", "Produce", &groceries)
+	createTestCategory(t, testDB, "cat_dining", "Dining", &food)
+	createTestCategory(t, testDB, "cat_transport", "Transport", nil)
+}
+
+// categoryParent returns the parent_id currently stored for a category
+func categoryParent(t *testing.T, id string) *string {
+	t.Helper()
+
+	var parentID *string
+	if err := testDB.QueryRow(testDB.Rebind("SELECT parent_id FROM categories WHERE id = ?"), id).Scan(&parentID); err != nil {
+		t.Fatalf("Failed to read category %s: %v", id, err)
+	}
+	return parentID
+}
+
+// TestCreateCategory tests the CreateCategory RPC method
+func TestCreateCategory(t *testing.T) {
+	setupCategoryTree(t)
+
+	// Create a new CategoryService with the test repository
+	service := NewCategoryService(categoryRepo, transactionRepo, auditRepo, testPages, testClock, testLogger)
+
+	food, missing := "cat_food", "cat_missing"
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		request     *expensesv1.CreateCategoryRequest
+		expectError bool
+		expectCode  connect.Code
+	}{
+		{
+			name:        "Top-level category",
+			request:     &expensesv1.CreateCategoryRequest{Name: "Utilities"},
+			expectError: false,
+		},
+		{
+			name:        "Subcategory",
+			request:     &expensesv1.CreateCategoryRequest{Name: "Snacks", ParentId: &food},
+			expectError: false,
+		},
+		{
+			name:        "Unknown parent",
+			request:     &expensesv1.CreateCategoryRequest{Name: "Orphan", ParentId: &missing},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name:        "Duplicate name",
+			request:     &expensesv1.CreateCategoryRequest{Name: "Food"},
+			expectError: true,
+			expectCode:  connect.CodeAlreadyExists,
+		},
+		{
+			name:        "Missing name",
+			request:     &expensesv1.CreateCategoryRequest{},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.CreateCategory(ctx, connect.NewRequest(tc.request))
+
+			// Check errors
+			assertError(t, err, tc.expectError, "")
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+
+			// Verify response for successful cases
+			category := resp.Msg.Category
+			if category.Id == "" {
+				t.Errorf("Expected category ID to be generated, got empty string")
+			}
+			if category.GetParentId() != tc.request.GetParentId() {
+				t.Errorf("Expected parent_id=%s, got %s", tc.request.GetParentId(), category.GetParentId())
+			}
+		})
+	}
+}
+
+// TestMoveCategory tests the MoveCategory and UpdateCategory cycle checks
+func TestMoveCategory(t *testing.T) {
+	// Create a new CategoryService with the test repository
+	service := NewCategoryService(categoryRepo, transactionRepo, auditRepo, testPages, testClock, testLogger)
+
+	food, produce, transport, missing := "cat_food", "cat_produce", "cat_transport", "cat_missing"
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		id          string
+		newParentID *string
+		expectError bool
+		expectCode  connect.Code
+	}{
+		{
+			name:        "Move under another tree",
+			id:          "cat_groceries",
+			newParentID: &transport,
+			expectError: false,
+		},
+		{
+			name:        "Move to top level",
+			id:          "cat_dining",
+			newParentID: nil,
+			expectError: false,
+		},
+		{
+			name:        "Move under itself",
+			id:          "cat_food",
+			newParentID: &food,
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name:        "Move under a grandchild",
+			id:          "cat_food",
+			newParentID: &produce,
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name:        "Move under unknown parent",
+			id:          "cat_food",
+			newParentID: &missing,
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name:        "Move unknown category",
+			id:          "cat_missing",
+			newParentID: &food,
+			expectError: true,
+			expectCode:  connect.CodeNotFound,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setupCategoryTree(t)
+
+			resp, err := service.MoveCategory(ctx, connect.NewRequest(&expensesv1.MoveCategoryRequest{
+				Id:          tc.id,
+				NewParentId: tc.newParentID,
+			}))
+
+			// Check errors
+			assertError(t, err, tc.expectError, "")
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+
+			if resp.Msg.Category.ParentId != nil && tc.newParentID == nil {
+				t.Errorf("Expected category to become top-level, got parent %s", *resp.Msg.Category.ParentId)
+			}
+			if got := categoryParent(t, tc.id); stringValue(got) != stringValue(tc.newParentID) {
+				t.Errorf("Expected stored parent %s, got %s", stringValue(tc.newParentID), stringValue(got))
+			}
+		})
+	}
+
+	// UpdateCategory applies the same cycle check
+	t.Run("Update into own subtree", func(t *testing.T) {
+		setupCategoryTree(t)
+
+		_, err := service.UpdateCategory(ctx, connect.NewRequest(&expensesv1.UpdateCategoryRequest{
+			Id:       "cat_food",
+			Name:     "Food",
+			ParentId: &produce,
+		}))
+		if connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Errorf("Expected code %v, got %v", connect.CodeInvalidArgument, connect.CodeOf(err))
+		}
+	})
+}
+
+// TestGetCategoryTree tests the GetCategoryTree and ListDescendants RPC methods
+func TestGetCategoryTree(t *testing.T) {
+	setupCategoryTree(t)
+
+	// Create a new CategoryService with the test repository
+	service := NewCategoryService(categoryRepo, transactionRepo, auditRepo, testPages, testClock, testLogger)
+
+	ctx := context.Background()
+
+	t.Run("Whole forest", func(t *testing.T) {
+		resp, err := service.GetCategoryTree(ctx, connect.NewRequest(&expensesv1.GetCategoryTreeRequest{}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		roots := resp.Msg.Roots
+		if len(roots) != 2 || roots[0].Category.Id != "cat_food" || roots[1].Category.Id != "cat_transport" {
+			t.Fatalf("Expected roots [Food Transport], got %v", roots)
+		}
+		food := roots[0]
+		if len(food.Children) != 2 || food.Children[0].Category.Name != "Dining" || food.Children[1].Category.Name != "Groceries" {
+			t.Fatalf("Expected Food children [Dining Groceries], got %v", food.Children)
+		}
+		produce := food.Children[1].Children
+		if len(produce) != 1 || produce[0].Category.Id != "cat_produce" || produce[0].Depth != 2 {
+			t.Errorf("Expected Produce at depth 2 under Groceries, got %v", produce)
+		}
+	})
+
+	t.Run("Subtree", func(t *testing.T) {
+		rootID := "cat_groceries"
+		resp, err := service.GetCategoryTree(ctx, connect.NewRequest(&expensesv1.GetCategoryTreeRequest{RootId: &rootID}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(resp.Msg.Roots) != 1 || resp.Msg.Roots[0].Depth != 0 || len(resp.Msg.Roots[0].Children) != 1 {
+			t.Errorf("Expected Groceries with one child, got %v", resp.Msg.Roots)
+		}
+	})
+
+	t.Run("Unknown root", func(t *testing.T) {
+		rootID := "cat_missing"
+		_, err := service.GetCategoryTree(ctx, connect.NewRequest(&expensesv1.GetCategoryTreeRequest{RootId: &rootID}))
+		if connect.CodeOf(err) != connect.CodeNotFound {
+			t.Errorf("Expected code %v, got %v", connect.CodeNotFound, connect.CodeOf(err))
+		}
+	})
+
+	t.Run("Descendants", func(t *testing.T) {
+		resp, err := service.ListDescendants(ctx, connect.NewRequest(&expensesv1.ListDescendantsRequest{Id: "cat_food"}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var ids []string
+		for _, category := range resp.Msg.Categories {
+			ids = append(ids, category.Id)
+		}
+		expected := []string{"cat_dining", "cat_groceries", "cat_produce"}
+		if len(ids) != len(expected) {
+			t.Fatalf("Expected descendants %v, got %v", expected, ids)
+		}
+		for i := range expected {
+			if ids[i] != expected[i] {
+				t.Errorf("Expected descendants %v, got %v", expected, ids)
+				break
+			}
+		}
+	})
+
+	t.Run("Etags of tree nodes and descendants are current", func(t *testing.T) {
+		// Describe Dining and Produce, so their etags leave revision 1 behind
+		for _, id := range []string{"cat_dining", "cat_produce"} {
+			if _, err := service.UpdateCategory(ctx, connect.NewRequest(&expensesv1.UpdateCategoryRequest{
+				Id:          id,
+				Description: "Described",
+				UpdateMask:  &fieldmaskpb.FieldMask{Paths: []string{"description"}},
+			})); err != nil {
+				t.Fatalf("Failed to describe category %s: %v", id, err)
+			}
+		}
+
+		tree, err := service.GetCategoryTree(ctx, connect.NewRequest(&expensesv1.GetCategoryTreeRequest{}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		dining := tree.Msg.Roots[0].Children[0].Category
+		descendants, err := service.ListDescendants(ctx, connect.NewRequest(&expensesv1.ListDescendantsRequest{Id: "cat_groceries"}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		produce := descendants.Msg.Categories[0]
+
+		for _, category := range []*expensesv1.Category{dining, produce} {
+			_, err := service.UpdateCategory(ctx, connect.NewRequest(&expensesv1.UpdateCategoryRequest{
+				Id:          category.Id,
+				Description: "Described again",
+				UpdateMask:  &fieldmaskpb.FieldMask{Paths: []string{"description"}},
+				Etag:        category.Etag,
+			}))
+			if err != nil {
+				t.Errorf("Expected the etag of %s to be accepted, got %v", category.Id, err)
+			}
+		}
+	})
+}
+
+// TestDeleteCategory tests the DeleteCategory RPC method in each mode
+func TestDeleteCategory(t *testing.T) {
+	// Create a new CategoryService with the test repository
+	service := NewCategoryService(categoryRepo, transactionRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		id          string
+		mode        expensesv1.CategoryDeleteMode
+		expectError bool
+		expectCode  connect.Code
+		remaining   int                // categories left afterwards
+		audited     []string           // resource/id/action of the audit events recorded
+		revisions   [2]int64           // revisions of txn_dinner and txn_market afterwards
+		verify      func(t *testing.T) // extra checks for successful cases
+	}{
+		{
+			name:        "Restrict refuses category with children",
+			id:          "cat_groceries",
+			mode:        expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_UNSPECIFIED,
+			expectError: true,
+			expectCode:  connect.CodeFailedPrecondition,
+		},
+		{
+			name:        "Restrict refuses referenced category",
+			id:          "cat_dining",
+			mode:        expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_RESTRICT,
+			expectError: true,
+			expectCode:  connect.CodeFailedPrecondition,
+		},
+		{
+			name:      "Restrict deletes unused leaf",
+			id:        "cat_produce",
+			mode:      expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_RESTRICT,
+			remaining: 4,
+			audited:   []string{"category/cat_produce/DELETE"},
+			revisions: [2]int64{1, 1},
+		},
+		{
+			name:      "Reassign moves children and references to the grandparent",
+			id:        "cat_groceries",
+			mode:      expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_REASSIGN,
+			remaining: 4,
+			audited:   []string{"category/cat_groceries/DELETE", "category/cat_produce/UPDATE", "transaction/txn_market/UPDATE"},
+			revisions: [2]int64{1, 2},
+			verify: func(t *testing.T) {
+				if got := categoryParent(t, "cat_produce"); stringValue(got) != "cat_food" {
+					t.Errorf("Expected Produce to move under Food, got %s", stringValue(got))
+				}
+			},
+		},
+		{
+			name:      "Reassign of a top-level category promotes its children",
+			id:        "cat_food",
+			mode:      expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_REASSIGN,
+			remaining: 4,
+			audited:   []string{"category/cat_dining/UPDATE", "category/cat_food/DELETE", "category/cat_groceries/UPDATE"},
+			revisions: [2]int64{1, 1},
+			verify: func(t *testing.T) {
+				if got := categoryParent(t, "cat_dining"); got != nil {
+					t.Errorf("Expected Dining to become top-level, got %s", *got)
+				}
+			},
+		},
+		{
+			name:      "Cascade deletes the subtree and clears references",
+			id:        "cat_food",
+			mode:      expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_CASCADE,
+			remaining: 1,
+			audited: []string{
+				"category/cat_dining/DELETE", "category/cat_food/DELETE", "category/cat_groceries/DELETE", "category/cat_produce/DELETE",
+				"transaction/txn_dinner/UPDATE", "transaction/txn_market/UPDATE",
+			},
+			revisions: [2]int64{2, 2},
+			verify: func(t *testing.T) {
+				var count int
+				if err := testDB.QueryRow("SELECT COUNT(*) FROM transactions WHERE category_id IS NOT NULL").Scan(&count); err != nil {
+					t.Fatalf("Failed to count transactions: %v", err)
+				}
+				if count != 0 {
+					t.Errorf("Expected category references to be cleared, found %d", count)
+				}
+				if err := testDB.QueryRow("SELECT COUNT(*) FROM ledger_entries WHERE category_id IS NOT NULL").Scan(&count); err != nil {
+					t.Fatalf("Failed to count ledger entries: %v", err)
+				}
+				if count != 0 {
+					t.Errorf("Expected ledger entry references to be cleared, found %d", count)
+				}
+			},
+		},
+		{
+			name:        "Unknown category",
+			id:          "cat_missing",
+			mode:        expensesv1.CategoryDeleteMode_CATEGORY_DELETE_MODE_CASCADE,
+			expectError: true,
+			expectCode:  connect.CodeNotFound,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setupCategoryTree(t)
+			_, err := testDB.Exec(testDB.Rebind("INSERT INTO transactions (id, workspace_id, date, description, category_id) VALUES ('txn_dinner', ?, CURRENT_TIMESTAMP, 'Dinner', 'cat_dining')"), repo.DefaultWorkspaceID)
+			if err != nil {
+				t.Fatalf("Failed to create test transaction: %v", err)
+			}
+
+			// txn_market refers to Groceries only through a ledger entry
+			wallet := createTestAccount(t, testDB, "Wallet", "at_asset", nil)
+			_, err = testDB.Exec(testDB.Rebind("INSERT INTO transactions (id, workspace_id, date, description) VALUES ('txn_market', ?, CURRENT_TIMESTAMP, 'Market')"), repo.DefaultWorkspaceID)
+			if err != nil {
+				t.Fatalf("Failed to create test transaction: %v", err)
+			}
+			_, err = testDB.Exec(testDB.Rebind("INSERT INTO ledger_entries (id, transaction_id, account_id, category_id, memo, debit) VALUES ('le_market', 'txn_market', ?, 'cat_groceries', 'Market', 100)"), wallet.ID)
+			if err != nil {
+				t.Fatalf("Failed to create test ledger entry: %v", err)
+			}
+
+			resp, err := service.DeleteCategory(ctx, connect.NewRequest(&expensesv1.DeleteCategoryRequest{
+				Id:   tc.id,
+				Mode: tc.mode,
+			}))
+
+			// Check errors
+			assertError(t, err, tc.expectError, "")
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+
+			if !resp.Msg.Success {
+				t.Fatalf("Expected success=true")
+			}
+			var count int
+			if err := testDB.QueryRow("SELECT COUNT(*) FROM categories").Scan(&count); err != nil {
+				t.Fatalf("Failed to count categories: %v", err)
+			}
+			if count != tc.remaining {
+				t.Errorf("Expected %d categories to remain, got %d", tc.remaining, count)
+			}
+
+			// Every category and transaction changed is in the audit log, and
+			// each changed transaction is in the change log at a new revision
+			var audited []string
+			if err := testDB.Select(&audited, "SELECT resource_type || '/' || resource_id || '/' || action FROM audit_events ORDER BY resource_type, resource_id"); err != nil {
+				t.Fatalf("Failed to list audit events: %v", err)
+			}
+			if !slices.Equal(audited, tc.audited) {
+				t.Errorf("Expected audit events %q, got %q", tc.audited, audited)
+			}
+			var changed []string
+			if err := testDB.Select(&changed, "SELECT resource_id FROM changes WHERE resource_type = 'transaction' ORDER BY resource_id"); err != nil {
+				t.Fatalf("Failed to list changes: %v", err)
+			}
+			var expectChanged []string
+			for _, event := range tc.audited {
+				if id, ok := strings.CutPrefix(event, "transaction/"); ok {
+					expectChanged = append(expectChanged, strings.TrimSuffix(id, "/UPDATE"))
+				}
+			}
+			if !slices.Equal(changed, expectChanged) {
+				t.Errorf("Expected changes to transactions %q, got %q", expectChanged, changed)
+			}
+			for i, id := range []string{"txn_dinner", "txn_market"} {
+				var revision int64
+				if err := testDB.QueryRow(testDB.Rebind("SELECT revision FROM transactions WHERE id = ?"), id).Scan(&revision); err != nil {
+					t.Fatalf("Failed to read transaction %s: %v", id, err)
+				}
+				if revision != tc.revisions[i] {
+					t.Errorf("Expected %s at revision %d, got %d", id, tc.revisions[i], revision)
+				}
+			}
+			if tc.verify != nil {
+				tc.verify(t)
+			}
+		})
+	}
+}
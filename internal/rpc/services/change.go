@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/changes"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// watchedResources are the resource types whose mutations record a change
+var watchedResources = map[string]bool{
+	"user":        true,
+	"instrument":  true,
+	"account":     true,
+	"transaction": true,
+}
+
+// changeTypes maps the audit actions of changes to their change types.
+// Restoring a resource from the trash brings it back into lists, so it is
+// reported as a create.
+var changeTypes = map[string]expensesv1.ChangeType{
+	audit.ActionCreate:   expensesv1.ChangeType_CHANGE_TYPE_CREATED,
+	audit.ActionUndelete: expensesv1.ChangeType_CHANGE_TYPE_CREATED,
+	audit.ActionUpdate:   expensesv1.ChangeType_CHANGE_TYPE_UPDATED,
+	audit.ActionDelete:   expensesv1.ChangeType_CHANGE_TYPE_DELETED,
+}
+
+// changeAccountIDs returns the IDs of the accounts the API views of a mutated
+// resource are on, which a caller must belong to all of to see the change
+func changeAccountIDs(before, after proto.Message) []string {
+	var ids []string
+	for _, msg := range []proto.Message{before, after} {
+		switch msg := msg.(type) {
+		case *expensesv1.Account:
+			ids = append(ids, msg.GetId())
+		case *expensesv1.Transaction:
+			for _, entry := range msg.GetLedgerEntries() {
+				ids = append(ids, entry.GetAccountId())
+			}
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// ChangeService implements the ChangeService interface defined in the proto
+type ChangeService struct {
+	expensesv1connect.UnimplementedChangeServiceHandler
+	repo      *repo.ChangeRepo
+	authzRepo *repo.AuthzRepo
+	feed      *changes.Feed
+	logger    *slog.Logger
+}
+
+// NewChangeService creates a new ChangeService streaming the changes of feed
+func NewChangeService(repo *repo.ChangeRepo, authzRepo *repo.AuthzRepo, feed *changes.Feed, logger *slog.Logger) *ChangeService {
+	return &ChangeService{
+		repo:      repo,
+		authzRepo: authzRepo,
+		feed:      feed,
+		logger:    logger,
+	}
+}
+
+// WatchChanges streams the changes of the caller's workspace after the
+// request's resume token, or from now on without one. The stream first catches
+// up on the recorded changes from the log and then follows the feed, catching
+// up from the log again whenever it falls behind and is dropped by the feed.
+func (s *ChangeService) WatchChanges(ctx context.Context, req *connect.Request[expensesv1.WatchChangesRequest], stream *connect.ServerStream[expensesv1.WatchChangesResponse]) error {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Watching changes", "resume_token", req.Msg.ResumeToken)
+
+	// Subscribe before catching up, so no change committed meanwhile is missed
+	sub := s.feed.Subscribe()
+	defer func() { s.feed.Unsubscribe(sub) }()
+
+	// Resume after the token, or from the end of the log without one
+	var after int64
+	if req.Msg.ResumeToken != "" {
+		var err error
+		if after, err = changes.ParseToken(req.Msg.ResumeToken); err != nil {
+			log.ErrorContext(ctx, s.logger, "Invalid resume token", "error", err)
+			return errors.InvalidField("resume_token", "invalid resume token")
+		}
+	} else {
+		_, last, err := s.repo.GetChangeBounds(ctx, s.repo.GetDB())
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to get change bounds", "error", err)
+			return storageError(err)
+		}
+		after = last
+	}
+
+	watcher := s.newChangeWatcher(ctx)
+	after, err := s.catchUp(ctx, stream, watcher, after, req.Msg.ResumeToken != "", true)
+	if err != nil {
+		return err
+	}
+	log.InfoContext(ctx, s.logger, "Caught up on changes", "seq", after)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.InfoContext(ctx, s.logger, "Stopped watching changes", "seq", after)
+			return nil
+		case change, ok := <-sub.C:
+			if !ok {
+				// Dropped by the feed for falling behind: catch up from the
+				// log and follow the feed again
+				log.WarnContext(ctx, s.logger, "Change stream fell behind, catching up", "seq", after)
+				sub = s.feed.Subscribe()
+				if after, err = s.catchUp(ctx, stream, watcher, after, false, false); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Send the changes already buffered along with it
+			batch := []db.Change{change}
+			for n := len(sub.C); n > 0 && len(batch) < changes.PageSize; n-- {
+				batch = append(batch, <-sub.C)
+			}
+			if after, err = s.send(ctx, stream, watcher, batch, after, false); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// catchUp sends the changes recorded after the change with seq after from the
+// log, a page at a time, and returns the seq of the last one. When resuming
+// from a client's token it fails with OutOfRange if changes after it have been
+// pruned. The first catch-up of a stream sends a response even if there are no
+// changes, so clients learn that they are caught up and get a resume token.
+func (s *ChangeService) catchUp(ctx context.Context, stream *connect.ServerStream[expensesv1.WatchChangesResponse], watcher *changeWatcher, after int64, resumed, first bool) (int64, error) {
+	for {
+		page, err := s.repo.ListChangesAfter(ctx, s.repo.GetDB(), after, changes.PageSize)
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to list changes", "seq", after, "error", err)
+			return 0, storageError(err)
+		}
+
+		// Check the token against the log only after reading from it, so a
+		// prune in between is noticed
+		if resumed {
+			oldest, newest, err := s.repo.GetChangeBounds(ctx, s.repo.GetDB())
+			if err != nil {
+				log.ErrorContext(ctx, s.logger, "Failed to get change bounds", "error", err)
+				return 0, storageError(err)
+			}
+			if after < oldest-1 || after > newest {
+				log.ErrorContext(ctx, s.logger, "Resume token outside the change log", "seq", after, "first", oldest, "last", newest)
+				return 0, errors.Error(connect.CodeOutOfRange, errors.ReasonResumeTokenExpired,
+					stderrors.New("resume token is outside the retained change log; refetch and watch from now on"))
+			}
+			resumed = false
+		}
+
+		last := len(page) < changes.PageSize
+		if after, err = s.send(ctx, stream, watcher, page, after, first && last); err != nil {
+			return 0, err
+		}
+		if last {
+			return after, nil
+		}
+	}
+}
+
+// send sends the changes of a batch after the change with seq after that the
+// stream may see, and returns the seq of the last change of the batch. Batches
+// without such changes are only sent if force is set.
+func (s *ChangeService) send(ctx context.Context, stream *connect.ServerStream[expensesv1.WatchChangesResponse], watcher *changeWatcher, batch []db.Change, after int64, force bool) (int64, error) {
+	var protoChanges []*expensesv1.Change
+	for _, change := range batch {
+		if change.Seq <= after {
+			continue
+		}
+		after = change.Seq
+		visible, err := watcher.visible(ctx, change)
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to list member accounts", "error", err)
+			return 0, storageError(err)
+		}
+		if visible {
+			protoChanges = append(protoChanges, toProtoChange(change))
+		}
+	}
+	if len(protoChanges) == 0 && !force {
+		return after, nil
+	}
+
+	if err := stream.Send(&expensesv1.WatchChangesResponse{
+		Changes:     protoChanges,
+		ResumeToken: changes.Token(after),
+	}); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to send changes", "error", err)
+		return 0, err
+	}
+	return after, nil
+}
+
+// changeWatcher decides which changes a stream may see: those of its
+// workspace, and of accounts and transactions only those on accounts the
+// caller belongs to. Streams without a subject, such as in-process calls, are
+// not narrowed to accounts.
+type changeWatcher struct {
+	authzRepo   *repo.AuthzRepo
+	workspaceID string
+	subject     authz.Subject
+	narrowed    bool
+	checked     map[string]bool // IDs of the accounts looked up since the stream started
+}
+
+// newChangeWatcher creates the changeWatcher of a stream
+func (s *ChangeService) newChangeWatcher(ctx context.Context) *changeWatcher {
+	subject, ok := authz.SubjectFrom(ctx)
+	watcher := &changeWatcher{
+		authzRepo:   s.authzRepo,
+		workspaceID: authz.WorkspaceFrom(ctx),
+		subject:     subject,
+		narrowed:    ok,
+		checked:     make(map[string]bool),
+	}
+	if ok {
+		watcher.subject.Accounts = maps.Clone(subject.Accounts)
+	}
+	return watcher
+}
+
+// visible reports whether the stream may see a change. The memberships of the
+// caller are looked up again when a change is on an account the stream has not
+// met yet, such as one the caller created since the stream started.
+func (w *changeWatcher) visible(ctx context.Context, change db.Change) (bool, error) {
+	if change.WorkspaceID != w.workspaceID {
+		return false, nil
+	}
+	if !w.narrowed || change.AccountIds == "" {
+		return true, nil
+	}
+
+	ids := strings.Split(change.AccountIds, ",")
+	if slices.ContainsFunc(ids, func(id string) bool { return !w.subject.Member(id) && !w.checked[id] }) {
+		accountIDs, err := w.authzRepo.ListMemberAccountIDs(ctx, w.authzRepo.GetDB(), w.workspaceID, w.subject.UserID)
+		if err != nil {
+			return false, err
+		}
+		for _, id := range accountIDs {
+			w.subject.Accounts[id] = true
+		}
+		for _, id := range ids {
+			w.checked[id] = true
+		}
+	}
+	return !slices.ContainsFunc(ids, func(id string) bool { return !w.subject.Member(id) }), nil
+}
+
+// toProtoChange converts a db.Change to a expensesv1.Change
+func toProtoChange(change db.Change) *expensesv1.Change {
+	return &expensesv1.Change{
+		ResourceType: change.ResourceType,
+		ResourceId:   change.ResourceID,
+		Type:         changeTypes[change.Action],
+		OccurredAt:   timestamppb.New(change.OccurredAt),
+	}
+}
@@ -0,0 +1,415 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/changes"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// TestChangeFeed tests publishing changes to subscribers and dropping those
+// that fall behind
+func TestChangeFeed(t *testing.T) {
+	ctx := context.Background()
+	changeRepo := repo.NewChangeRepo(testDB)
+
+	// Define test cases
+	tests := []struct {
+		name           string
+		buffer         int
+		changes        int
+		expectReceived int
+		expectDropped  bool
+	}{
+		{
+			name:           "Subscriber keeping up",
+			buffer:         4,
+			changes:        3,
+			expectReceived: 3,
+		},
+		{
+			name:           "Slow subscriber is dropped",
+			buffer:         2,
+			changes:        3,
+			expectReceived: 2,
+			expectDropped:  true,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resetTestDB(t)
+			feed, err := changes.NewFeed(ctx, changeRepo, testClock, time.Hour, time.Hour, tc.buffer, testLogger)
+			if err != nil {
+				t.Fatalf("Failed to create feed: %v", err)
+			}
+			sub := feed.Subscribe()
+			defer feed.Unsubscribe(sub)
+
+			for range tc.changes {
+				err := changeRepo.CreateChange(ctx, testDB, db.CreateChangeParams{
+					WorkspaceID:  repo.DefaultWorkspaceID,
+					ResourceType: "instrument",
+					ResourceID:   "inst_cash",
+					Action:       audit.ActionUpdate,
+					OccurredAt:   testClock.Now(),
+				})
+				if err != nil {
+					t.Fatalf("Failed to create change: %v", err)
+				}
+			}
+			if err := feed.Poll(ctx); err != nil {
+				t.Fatalf("Failed to poll feed: %v", err)
+			}
+
+			// Drain the buffered changes, after which a dropped subscription
+			// is closed and a live one has nothing to receive
+			received := 0
+			for n := len(sub.C); n > 0; n-- {
+				<-sub.C
+				received++
+			}
+			dropped := false
+			select {
+			case _, ok := <-sub.C:
+				dropped = !ok
+			default:
+			}
+
+			if received != tc.expectReceived {
+				t.Errorf("Expected %d changes received, got %d", tc.expectReceived, received)
+			}
+			if dropped != tc.expectDropped {
+				t.Errorf("Expected dropped %v, got %v", tc.expectDropped, dropped)
+			}
+		})
+	}
+}
+
+// TestChangeFeedOverlappingTransactions tests that a change recorded by a
+// transaction committing after another that started recording later is still
+// published, on PostgreSQL where writers overlap
+func TestChangeFeedOverlappingTransactions(t *testing.T) {
+	if testDB.DriverName() != repo.DriverPostgres {
+		t.Skip("SQLite commits one writer at a time")
+	}
+	resetTestDB(t)
+	ctx := context.Background()
+	changeRepo := repo.NewChangeRepo(testDB)
+	feed, err := changes.NewFeed(ctx, changeRepo, testClock, time.Hour, time.Hour, 4, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create feed: %v", err)
+	}
+	sub := feed.Subscribe()
+	defer feed.Unsubscribe(sub)
+
+	record := func(dbtx db.DBTX, resourceID string) error {
+		return changeRepo.CreateChange(ctx, dbtx, db.CreateChangeParams{
+			WorkspaceID:  repo.DefaultWorkspaceID,
+			ResourceType: "instrument",
+			ResourceID:   resourceID,
+			Action:       audit.ActionUpdate,
+			OccurredAt:   testClock.Now(),
+		})
+	}
+
+	// The first transaction records a change and stays open
+	first, err := testDB.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer func() { _ = first.Rollback() }()
+	if err := record(first, "inst_first"); err != nil {
+		t.Fatalf("Failed to create change: %v", err)
+	}
+
+	// The second records a change and commits as soon as it can
+	done := make(chan error, 1)
+	go func() {
+		second, err := testDB.BeginTxx(ctx, nil)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer func() { _ = second.Rollback() }()
+		if err := record(second, "inst_second"); err != nil {
+			done <- err
+			return
+		}
+		done <- second.Commit()
+	}()
+
+	// Poll once the second transaction has either committed or is waiting
+	// for the first to release the change log
+	var secondErr error
+	finished := false
+	for !finished {
+		var waiting int
+		if err := testDB.GetContext(ctx, &waiting, "SELECT COUNT(*) FROM pg_locks WHERE locktype = 'advisory' AND NOT granted"); err != nil {
+			t.Fatalf("Failed to count waiting locks: %v", err)
+		}
+		if waiting > 0 {
+			break
+		}
+		select {
+		case secondErr = <-done:
+			finished = true
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if err := feed.Poll(ctx); err != nil {
+		t.Fatalf("Failed to poll feed: %v", err)
+	}
+
+	// Commit the first transaction, let the second finish and poll again
+	if err := first.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+	if !finished {
+		secondErr = <-done
+	}
+	if secondErr != nil {
+		t.Fatalf("Failed to record the second change: %v", secondErr)
+	}
+	if err := feed.Poll(ctx); err != nil {
+		t.Fatalf("Failed to poll feed: %v", err)
+	}
+
+	// Both changes are published, in the order they committed
+	var received []string
+	for n := len(sub.C); n > 0; n-- {
+		received = append(received, (<-sub.C).ResourceID)
+	}
+	if expected := []string{"inst_first", "inst_second"}; !slices.Equal(received, expected) {
+		t.Errorf("Expected changes %v, got %v", expected, received)
+	}
+}
+
+// TestWatchChanges tests catching up on and following the changes visible to
+// the caller through the interceptors
+func TestWatchChanges(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+	ctx := context.Background()
+
+	// Serve the change feed alongside the services making changes, behind the
+	// interceptors. The feed is polled by the tests rather than run.
+	changeRepo := repo.NewChangeRepo(testDB)
+	authzRepo := repo.NewAuthzRepo(testDB)
+	feed, err := changes.NewFeed(ctx, changeRepo, testClock, time.Hour, time.Hour, 16, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create feed: %v", err)
+	}
+	interceptors := connect.WithInterceptors(
+		audit.NewInterceptor(),
+		auth.NewInterceptor(authRepo, testClock, testLogger),
+		authz.NewInterceptor(authzRepo, auditRepo, testClock, testLogger),
+	)
+	mux := http.NewServeMux()
+	mux.Handle(expensesv1connect.NewChangeServiceHandler(NewChangeService(changeRepo, authzRepo, feed, testLogger), interceptors))
+	mux.Handle(expensesv1connect.NewAccountServiceHandler(newTestAccountService(), interceptors))
+	mux.Handle(expensesv1connect.NewInstrumentServiceHandler(NewInstrumentService(instrumentRepo, auditRepo, testPages, testClock, testLogger), interceptors))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	changeClient := expensesv1connect.NewChangeServiceClient(server.Client(), server.URL)
+	accounts := expensesv1connect.NewAccountServiceClient(server.Client(), server.URL)
+	instruments := expensesv1connect.NewInstrumentServiceClient(server.Client(), server.URL)
+
+	// Seed an admin and a member with an API token each
+	admin := createTestUser(t, testDB, "Admin", "admin@example.com")
+	if err := workspaceRepo.SetWorkspaceUserAdmin(ctx, testDB, repo.DefaultWorkspaceID, admin.ID, true); err != nil {
+		t.Fatalf("Failed to make user admin: %v", err)
+	}
+	member := createTestUser(t, testDB, "Member", "member@example.com")
+	_, adminToken := createTestAPIToken(t, testDB, admin.ID, "admin", nil)
+	_, memberToken := createTestAPIToken(t, testDB, member.ID, "member", nil)
+	withToken := func(req connect.AnyRequest, token string) {
+		req.Header().Set("Authorization", "Bearer "+token)
+	}
+	createInstrument := func(t *testing.T, token, name string) string {
+		req := connect.NewRequest(&expensesv1.CreateInstrumentRequest{Name: name})
+		withToken(req, token)
+		res, err := instruments.CreateInstrument(ctx, req)
+		if err != nil {
+			t.Fatalf("Failed to create instrument: %v", err)
+		}
+		return res.Msg.Instrument.Id
+	}
+	createAccount := func(t *testing.T, token, name string) string {
+		req := connect.NewRequest(&expensesv1.CreateAccountRequest{Name: name, AccountTypeId: "at_asset"})
+		withToken(req, token)
+		res, err := accounts.CreateAccount(ctx, req)
+		if err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+		return res.Msg.Account.Id
+	}
+
+	// Record the changes to catch up on: master data seen by everyone, and an
+	// account of each user seen only by its member
+	cashID := createInstrument(t, adminToken, "Cash")
+	walletID := createAccount(t, memberToken, "Wallet")
+	createAccount(t, adminToken, "Vault")
+	cardID := createInstrument(t, adminToken, "Card")
+	deleteReq := connect.NewRequest(&expensesv1.DeleteInstrumentRequest{Id: cardID})
+	withToken(deleteReq, adminToken)
+	if _, err := instruments.DeleteInstrument(ctx, deleteReq); err != nil {
+		t.Fatalf("Failed to delete instrument: %v", err)
+	}
+	first, last, err := changeRepo.GetChangeBounds(ctx, testDB)
+	if err != nil {
+		t.Fatalf("Failed to get change bounds: %v", err)
+	}
+
+	// Define test cases, in order, as the last prunes the log
+	tests := []struct {
+		name         string
+		token        string
+		resumeToken  string
+		setup        func(t *testing.T)
+		follow       func(t *testing.T) []string
+		expectCode   connect.Code
+		expectReason string
+		expectFirst  []string
+		expectResume string
+	}{
+		{
+			name:        "Member catches up on the changes it may see",
+			token:       memberToken,
+			resumeToken: changes.Token(first - 1),
+			expectFirst: []string{
+				"CHANGE_TYPE_CREATED instrument/" + cashID,
+				"CHANGE_TYPE_CREATED account/" + walletID,
+				"CHANGE_TYPE_CREATED instrument/" + cardID,
+				"CHANGE_TYPE_DELETED instrument/" + cardID,
+			},
+			expectResume: changes.Token(last),
+		},
+		{
+			name:         "Watching without a token starts from now",
+			token:        adminToken,
+			expectResume: changes.Token(last),
+		},
+		{
+			name:  "Member follows new changes, including of its new accounts",
+			token: memberToken,
+			follow: func(t *testing.T) []string {
+				createAccount(t, adminToken, "Safe")
+				savingsID := createAccount(t, memberToken, "Savings")
+				bankID := createInstrument(t, adminToken, "Bank")
+				return []string{
+					"CHANGE_TYPE_CREATED account/" + savingsID,
+					"CHANGE_TYPE_CREATED instrument/" + bankID,
+				}
+			},
+			expectResume: changes.Token(last),
+		},
+		{
+			name:         "Invalid resume token",
+			token:        memberToken,
+			resumeToken:  "not a token",
+			expectCode:   connect.CodeInvalidArgument,
+			expectReason: errors.ReasonInvalidField,
+		},
+		{
+			name:         "Resume token ahead of the log",
+			token:        memberToken,
+			resumeToken:  changes.Token(last + 100),
+			expectCode:   connect.CodeOutOfRange,
+			expectReason: errors.ReasonResumeTokenExpired,
+		},
+		{
+			name:        "Resume token of pruned changes",
+			token:       memberToken,
+			resumeToken: changes.Token(first - 1),
+			setup: func(t *testing.T) {
+				if _, err := changeRepo.PruneChanges(ctx, testDB, testClock.Now().Add(time.Hour)); err != nil {
+					t.Fatalf("Failed to prune changes: %v", err)
+				}
+			},
+			expectCode:   connect.CodeOutOfRange,
+			expectReason: errors.ReasonResumeTokenExpired,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setup != nil {
+				tc.setup(t)
+			}
+			streamCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+
+			req := connect.NewRequest(&expensesv1.WatchChangesRequest{ResumeToken: tc.resumeToken})
+			withToken(req, tc.token)
+			stream, err := changeClient.WatchChanges(streamCtx, req)
+			if err != nil {
+				t.Fatalf("Failed to watch changes: %v", err)
+			}
+			defer func() { _ = stream.Close() }()
+
+			if !stream.Receive() {
+				if tc.expectCode == 0 {
+					t.Fatalf("Expected a response, got %v", stream.Err())
+				}
+				if connect.CodeOf(stream.Err()) != tc.expectCode {
+					t.Fatalf("Expected code %v, got %v", tc.expectCode, stream.Err())
+				}
+				if reason := errorReason(t, stream.Err()); reason != tc.expectReason {
+					t.Errorf("Expected reason %s, got %s", tc.expectReason, reason)
+				}
+				return
+			}
+			if tc.expectCode != 0 {
+				t.Fatalf("Expected %v, got a response", tc.expectCode)
+			}
+			if got := describeChanges(stream.Msg().Changes); !slices.Equal(got, tc.expectFirst) {
+				t.Errorf("Expected first changes %v, got %v", tc.expectFirst, got)
+			}
+			if stream.Msg().ResumeToken != tc.expectResume {
+				t.Errorf("Expected resume token %q, got %q", tc.expectResume, stream.Msg().ResumeToken)
+			}
+			if tc.follow == nil {
+				return
+			}
+
+			// Make the changes, publish them and collect them from the stream,
+			// which may batch them into one or more responses
+			expected := tc.follow(t)
+			if err := feed.Poll(ctx); err != nil {
+				t.Fatalf("Failed to poll feed: %v", err)
+			}
+			var got []string
+			for len(got) < len(expected) && stream.Receive() {
+				got = append(got, describeChanges(stream.Msg().Changes)...)
+			}
+			if !slices.Equal(got, expected) {
+				t.Errorf("Expected followed changes %v, got %v (%v)", expected, got, stream.Err())
+			}
+		})
+	}
+}
+
+// describeChanges describes changes as their types and resources
+func describeChanges(changes []*expensesv1.Change) []string {
+	var described []string
+	for _, change := range changes {
+		described = append(described, change.Type.String()+" "+change.ResourceType+"/"+change.ResourceId)
+	}
+	return described
+}
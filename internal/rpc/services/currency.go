@@ -0,0 +1,536 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/etag"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/money"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// CurrencyService implements the CurrencyService interface defined in the proto
+type CurrencyService struct {
+	expensesv1connect.UnimplementedCurrencyServiceHandler
+	repo    *repo.CurrencyRepo
+	auditor auditor
+	pages   *pagination.Codec
+	clock   clock.Clock
+	logger  *slog.Logger
+}
+
+// NewCurrencyService creates a new CurrencyService
+func NewCurrencyService(repo *repo.CurrencyRepo, auditRepo *repo.AuditRepo, pages *pagination.Codec, clock clock.Clock, logger *slog.Logger) *CurrencyService {
+	return &CurrencyService{
+		repo:    repo,
+		auditor: newAuditor(auditRepo, clock, logger),
+		pages:   pages,
+		clock:   clock,
+		logger:  logger,
+	}
+}
+
+// CreateCurrency creates a new currency
+func (s *CurrencyService) CreateCurrency(ctx context.Context, req *connect.Request[expensesv1.CreateCurrencyRequest]) (*connect.Response[expensesv1.CreateCurrencyResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Creating currency", "code", req.Msg.Code)
+
+	// Validate input against the bundled ISO 4217 list
+	code := strings.ToUpper(strings.TrimSpace(req.Msg.Code))
+	if code == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateCurrency", "error", "code is required")
+		return nil, errors.Required("code")
+	}
+	iso, ok := money.LookupISO(code)
+	if !ok {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateCurrency", "error", "unknown ISO 4217 code", "code", code)
+		return nil, errors.InvalidField("code", "%s is not an ISO 4217 currency code", code)
+	}
+	name := req.Msg.Name
+	if name == "" {
+		name = iso.Name
+	}
+	minorUnits := int64(iso.MinorUnits)
+	if req.Msg.MinorUnits != nil {
+		if err := s.validateMinorUnits(ctx, *req.Msg.MinorUnits); err != nil {
+			return nil, err
+		}
+		minorUnits = int64(*req.Msg.MinorUnits)
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Create currency in database within the transaction
+	currency, err := s.repo.CreateCurrency(ctx, tx, db.CreateCurrencyParams{
+		WorkspaceID: authz.WorkspaceFrom(ctx),
+		Code:        code,
+		Name:        name,
+		MinorUnits:  minorUnits,
+		Symbol:      req.Msg.Symbol,
+	})
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "Currency already exists", "code", code)
+			return nil, errors.AlreadyExists("currency", code, "code", "currency with code %s already exists", code)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to create currency", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "currency", currency.ID, audit.ActionCreate, nil, toProtoCurrency(currency)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Currency created successfully", "id", currency.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.CreateCurrencyResponse{
+		Currency: toProtoCurrency(currency),
+	}), nil
+}
+
+// GetCurrency retrieves a currency by ID
+func (s *CurrencyService) GetCurrency(ctx context.Context, req *connect.Request[expensesv1.GetCurrencyRequest]) (*connect.Response[expensesv1.GetCurrencyResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Getting currency", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for GetCurrency", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Get currency from database (read operations can use the main DB connection)
+	currency, err := s.getCurrency(ctx, s.repo.GetDB(), req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	log.InfoContext(ctx, s.logger, "Currency retrieved successfully", "id", currency.ID)
+
+	// Prepare response, exposing the etag for conditional HTTP GETs
+	resp := connect.NewResponse(&expensesv1.GetCurrencyResponse{
+		Currency: toProtoCurrency(currency),
+	})
+	resp.Header().Set(etag.Header, resp.Msg.Currency.Etag)
+	return resp, nil
+}
+
+// ListCurrencies retrieves a list of currencies with optional pagination
+func (s *CurrencyService) ListCurrencies(ctx context.Context, req *connect.Request[expensesv1.ListCurrenciesRequest]) (*connect.Response[expensesv1.ListCurrenciesResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing currencies", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("currencies", req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering within the caller's workspace
+	query, err := parseQuery(ctx, s.logger, repo.CurrencyFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("currencies", authz.WorkspaceFrom(ctx)))
+
+	// Get currencies from database (read operations can use the main DB connection)
+	currencies, err := s.repo.ListCurrencies(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list currencies", "error", err)
+		return nil, storageError(err)
+	}
+
+	currencies, pageResponse, err := paginate(ctx, s.logger, s.pages, page, currencies, func(row db.Currency) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountCurrencies(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to proto messages
+	protoCurrencies := make([]*expensesv1.Currency, len(currencies))
+	for i, currency := range currencies {
+		protoCurrencies[i] = toProtoCurrency(currency)
+	}
+
+	log.InfoContext(ctx, s.logger, "Currencies retrieved successfully", "count", len(currencies))
+
+	return connect.NewResponse(&expensesv1.ListCurrenciesResponse{
+		Currencies:         protoCurrencies,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// currencyUpdatePaths are the fields of a currency UpdateCurrency may change
+var currencyUpdatePaths = []string{"name", "minor_units", "symbol"}
+
+// UpdateCurrency updates the fields of an existing currency selected by the
+// update mask
+func (s *CurrencyService) UpdateCurrency(ctx context.Context, req *connect.Request[expensesv1.UpdateCurrencyRequest]) (*connect.Response[expensesv1.UpdateCurrencyResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Updating currency", "id", req.Msg.Id, "name", req.Msg.Name, "update_mask", req.Msg.GetUpdateMask().GetPaths())
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateCurrency", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+	mask, err := parseUpdateMask(ctx, s.logger, req.Msg.UpdateMask, currencyUpdatePaths, "code")
+	if err != nil {
+		return nil, err
+	}
+	if mask.has("name") && req.Msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateCurrency", "error", "name is required")
+		return nil, errors.Required("name")
+	}
+	if mask.has("minor_units") && req.Msg.MinorUnits != nil {
+		if err := s.validateMinorUnits(ctx, *req.Msg.MinorUnits); err != nil {
+			return nil, err
+		}
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if currency exists within the transaction
+	existing, err := s.getCurrency(ctx, tx, req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkETag(ctx, s.logger, "currency", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Posted amounts and reconciled balances are stored in minor units, so
+	// changing their scale would silently change their value. Unset minor
+	// units keep the current scale.
+	minorUnits := existing.MinorUnits
+	if mask.has("minor_units") && req.Msg.MinorUnits != nil && int64(*req.Msg.MinorUnits) != existing.MinorUnits {
+		entries, err := s.repo.CountLedgerEntries(ctx, tx, existing.ID)
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to count ledger entries", "id", existing.ID, "error", err)
+			return nil, storageError(err)
+		}
+		if entries > 0 {
+			log.ErrorContext(ctx, s.logger, "Cannot change minor units of a currency in use", "id", existing.ID, "ledger_entries", entries)
+			return nil, errors.InUse("currency", existing.Code, "currency %s has %d ledger entries, minor units cannot change", existing.Code, entries)
+		}
+		reconciliations, err := s.repo.CountReconciliations(ctx, tx, existing.ID)
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to count reconciliations", "id", existing.ID, "error", err)
+			return nil, storageError(err)
+		}
+		if reconciliations > 0 {
+			log.ErrorContext(ctx, s.logger, "Cannot change minor units of a currency in use", "id", existing.ID, "reconciliations", reconciliations)
+			return nil, errors.InUse("currency", existing.Code, "currency %s has %d reconciliations, minor units cannot change", existing.Code, reconciliations)
+		}
+		minorUnits = int64(*req.Msg.MinorUnits)
+	}
+
+	// Update the masked fields of the currency within the transaction
+	currency, err := s.repo.UpdateCurrency(ctx, tx, req.Msg.Id, existing.Revision, mask.pick(map[string]any{
+		"name":        req.Msg.Name,
+		"minor_units": minorUnits,
+		"symbol":      req.Msg.Symbol,
+	}))
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to update currency", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "currency", currency.ID, audit.ActionUpdate, toProtoCurrency(existing), toProtoCurrency(currency)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Currency updated successfully", "id", currency.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.UpdateCurrencyResponse{
+		Currency: toProtoCurrency(currency),
+	}), nil
+}
+
+// DeleteCurrency moves a currency no account or ledger entry uses to the trash
+func (s *CurrencyService) DeleteCurrency(ctx context.Context, req *connect.Request[expensesv1.DeleteCurrencyRequest]) (*connect.Response[expensesv1.DeleteCurrencyResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Deleting currency", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for DeleteCurrency", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if currency exists within the transaction
+	currency, err := s.getCurrency(ctx, tx, req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkETag(ctx, s.logger, "currency", currency.ID, req.Msg.Etag, currency.Revision, currency.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// A currency still used by accounts or ledger entries cannot be deleted
+	accounts, err := s.repo.CountAccounts(ctx, tx, currency.ID)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to count accounts", "id", currency.ID, "error", err)
+		return nil, storageError(err)
+	}
+	entries, err := s.repo.CountLedgerEntries(ctx, tx, currency.ID)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to count ledger entries", "id", currency.ID, "error", err)
+		return nil, storageError(err)
+	}
+	if accounts > 0 || entries > 0 {
+		log.ErrorContext(ctx, s.logger, "Currency is in use", "id", currency.ID, "accounts", accounts, "ledger_entries", entries)
+		return nil, errors.InUse("currency", currency.Code, "currency %s is used by %d accounts and %d ledger entries", currency.Code, accounts, entries)
+	}
+
+	// Move the currency to the trash within the transaction
+	if err := s.repo.DeleteCurrency(ctx, tx, currency.ID, currency.Revision, s.clock.Now().UTC()); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to delete currency", "id", currency.ID, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "currency", currency.ID, audit.ActionDelete, toProtoCurrency(currency), nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Currency deleted successfully", "id", req.Msg.Id)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.DeleteCurrencyResponse{
+		Success: true,
+	}), nil
+}
+
+// ListDeletedCurrencies retrieves a paginated list of the currencies in the trash
+func (s *CurrencyService) ListDeletedCurrencies(ctx context.Context, req *connect.Request[expensesv1.ListDeletedCurrenciesRequest]) (*connect.Response[expensesv1.ListDeletedCurrenciesResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing deleted currencies", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("deleted_currencies", req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering within the caller's workspace
+	query, err := parseQuery(ctx, s.logger, repo.CurrencyFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("currencies", authz.WorkspaceFrom(ctx)))
+
+	// Get deleted currencies from database (read operations can use the main DB connection)
+	currencies, err := s.repo.ListDeletedCurrencies(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list deleted currencies", "error", err)
+		return nil, storageError(err)
+	}
+
+	currencies, pageResponse, err := paginate(ctx, s.logger, s.pages, page, currencies, func(row db.Currency) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountDeletedCurrencies(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to proto messages
+	protoCurrencies := make([]*expensesv1.Currency, len(currencies))
+	for i, currency := range currencies {
+		protoCurrencies[i] = toProtoCurrency(currency)
+	}
+
+	log.InfoContext(ctx, s.logger, "Deleted currencies retrieved successfully", "count", len(currencies))
+
+	return connect.NewResponse(&expensesv1.ListDeletedCurrenciesResponse{
+		Currencies:         protoCurrencies,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// UndeleteCurrency restores a currency from the trash
+func (s *CurrencyService) UndeleteCurrency(ctx context.Context, req *connect.Request[expensesv1.UndeleteCurrencyRequest]) (*connect.Response[expensesv1.UndeleteCurrencyResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Undeleting currency", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UndeleteCurrency", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if the currency is in the trash within the transaction
+	existing, err := s.repo.GetDeletedCurrency(ctx, tx, authz.WorkspaceFrom(ctx), req.Msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Deleted currency not found", "id", req.Msg.Id)
+			return nil, errors.Error(connect.CodeNotFound, errors.ReasonNotFound, fmt.Errorf("%w: deleted currency with id %s not found", errors.ErrNotFound, req.Msg.Id), errors.Resource("currency", req.Msg.Id))
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to check if deleted currency exists", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "currency", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Restore the currency within the transaction
+	currency, err := s.repo.UndeleteCurrency(ctx, tx, req.Msg.Id, existing.Revision)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "A live currency already has the code of the deleted currency", "id", req.Msg.Id, "code", existing.Code)
+			return nil, errors.AlreadyExists("currency", existing.Code, "code", "currency with code %s already exists", existing.Code)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to undelete currency", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "currency", currency.ID, audit.ActionUndelete, toProtoCurrency(existing), toProtoCurrency(currency)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Currency undeleted successfully", "id", currency.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.UndeleteCurrencyResponse{
+		Currency: toProtoCurrency(currency),
+	}), nil
+}
+
+// getCurrency fetches a currency and maps a missing row to NotFound
+func (s *CurrencyService) getCurrency(ctx context.Context, dbtx db.DBTX, id string) (db.Currency, error) {
+	currency, err := s.repo.GetCurrency(ctx, dbtx, authz.WorkspaceFrom(ctx), id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Currency not found", "id", id)
+			return db.Currency{}, errors.NotFound("currency", id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get currency", "id", id, "error", err)
+		return db.Currency{}, storageError(err)
+	}
+	return currency, nil
+}
+
+// validateMinorUnits checks that a requested number of minor units is supported
+func (s *CurrencyService) validateMinorUnits(ctx context.Context, minorUnits int32) error {
+	if minorUnits < 0 || minorUnits > money.MaxMinorUnits {
+		log.ErrorContext(ctx, s.logger, "Invalid minor units", "minor_units", minorUnits)
+		return errors.InvalidField("minor_units", "minor_units must be between 0 and %d", money.MaxMinorUnits)
+	}
+	return nil
+}
+
+// toProtoCurrency converts a db.Currency to a expensesv1.Currency
+func toProtoCurrency(currency db.Currency) *expensesv1.Currency {
+	return &expensesv1.Currency{
+		Id:         currency.ID,
+		Code:       currency.Code,
+		Name:       currency.Name,
+		MinorUnits: int32(currency.MinorUnits),
+		Symbol:     currency.Symbol,
+		CreatedAt:  timestamppb.New(currency.CreatedAt),
+		UpdatedAt:  timestamppb.New(currency.UpdatedAt),
+		Etag:       etag.Compute(currency.Revision, currency.UpdatedAt),
+		DeletedAt:  timestampOrNil(currency.DeletedAt),
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// TestCreateCurrency tests the CreateCurrency RPC method
+func TestCreateCurrency(t *testing.T) {
+	resetTestDB(t)
+	createTestCurrency(t, testDB, "cur_jpy", "JPY", "Japanese Yen")
+
+	// Create a new CurrencyService with the test repository
+	service := NewCurrencyService(currencyRepo, auditRepo, testPages, testClock, testLogger)
+
+	three, negative := int32(3), int32(-1)
+
+	// Define test cases
+	tests := []struct {
+		name               string
+		request            *expensesv1.CreateCurrencyRequest
+		expectError        bool
+		expectCode         connect.Code
+		expectedCode       string
+		expectedName       string
+		expectedMinorUnits int32
+	}{
+		{
+			name:               "Defaults from ISO 4217",
+			request:            &expensesv1.CreateCurrencyRequest{Code: "USD", Symbol: "$"},
+			expectError:        false,
+			expectedCode:       "USD",
+			expectedName:       "US Dollar",
+			expectedMinorUnits: 2,
+		},
+		{
+			name:               "Lowercase code with explicit name",
+			request:            &expensesv1.CreateCurrencyRequest{Code: "eur", Name: "Euro", Symbol: "€"},
+			expectError:        false,
+			expectedCode:       "EUR",
+			expectedName:       "Euro",
+			expectedMinorUnits: 2,
+		},
+		{
+			name:               "Three decimal currency",
+			request:            &expensesv1.CreateCurrencyRequest{Code: "KWD"},
+			expectError:        false,
+			expectedCode:       "KWD",
+			expectedName:       "Kuwaiti Dinar",
+			expectedMinorUnits: 3,
+		},
+		{
+			name:               "Explicit minor units",
+			request:            &expensesv1.CreateCurrencyRequest{Code: "CHF", MinorUnits: &three},
+			expectError:        false,
+			expectedCode:       "CHF",
+			expectedName:       "Swiss Franc",
+			expectedMinorUnits: 3,
+		},
+		{
+			name:        "Unknown code",
+			request:     &expensesv1.CreateCurrencyRequest{Code: "XYZ"},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name:        "Negative minor units",
+			request:     &expensesv1.CreateCurrencyRequest{Code: "GBP", MinorUnits: &negative},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name:        "Duplicate code",
+			request:     &expensesv1.CreateCurrencyRequest{Code: "JPY"},
+			expectError: true,
+			expectCode:  connect.CodeAlreadyExists,
+		},
+		{
+			name:        "Missing code",
+			request:     &expensesv1.CreateCurrencyRequest{},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.CreateCurrency(ctx, connect.NewRequest(tc.request))
+
+			// Check errors
+			assertError(t, err, tc.expectError, "")
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+
+			// Verify response for successful cases
+			currency := resp.Msg.Currency
+			if currency.Code != tc.expectedCode {
+				t.Errorf("Expected code=%s, got %s", tc.expectedCode, currency.Code)
+			}
+			if currency.Name != tc.expectedName {
+				t.Errorf("Expected name=%s, got %s", tc.expectedName, currency.Name)
+			}
+			if currency.MinorUnits != tc.expectedMinorUnits {
+				t.Errorf("Expected minor_units=%d, got %d", tc.expectedMinorUnits, currency.MinorUnits)
+			}
+		})
+	}
+}
+
+// TestUpdateCurrency tests the UpdateCurrency RPC method
+func TestUpdateCurrency(t *testing.T) {
+	fx := setupTransactionFixture(t)
+
+	// Create a new CurrencyService with the test repository
+	service := NewCurrencyService(currencyRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Post a JPY transaction so that JPY is in use
+	ctx := context.Background()
+	_, err := newTestTransactionService().CreateTransaction(ctx, connect.NewRequest(&expensesv1.CreateTransactionRequest{
+		Description: "Card payment",
+		Lines: []*expensesv1.LedgerLine{
+			debitLine(fx.card.ID, 500, ""),
+			creditLine(fx.cash.ID, 500, ""),
+		},
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create test transaction: %v", err)
+	}
+
+	// Count an empty EUR wallet so that EUR is in use without ledger entries
+	createTestCurrency(t, testDB, "cur_eur", "EUR", "Euro")
+	eur := "cur_eur"
+	wallet := createTestAccount(t, testDB, "EUR Wallet", "at_asset", &eur)
+	if _, err := reconciliationRepo.CreateReconciliation(ctx, testDB, db.CreateReconciliationParams{
+		WorkspaceID:   repo.DefaultWorkspaceID,
+		AccountID:     wallet.ID,
+		CountedAt:     testClock.Now(),
+		CountedAmount: 1500,
+		Discrepancy:   1500,
+		CurrencyID:    eur,
+	}); err != nil {
+		t.Fatalf("Failed to create test reconciliation: %v", err)
+	}
+
+	zero, two := int32(0), int32(2)
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		request     *expensesv1.UpdateCurrencyRequest
+		expectError bool
+		expectCode  connect.Code
+	}{
+		{
+			name:        "Change symbol of a currency in use",
+			request:     &expensesv1.UpdateCurrencyRequest{Id: "cur_jpy", Name: "Japanese Yen", Symbol: "¥", MinorUnits: &zero},
+			expectError: false,
+		},
+		{
+			name:        "Change minor units of a currency in use",
+			request:     &expensesv1.UpdateCurrencyRequest{Id: "cur_jpy", Name: "Japanese Yen", MinorUnits: &two},
+			expectError: true,
+			expectCode:  connect.CodeFailedPrecondition,
+		},
+		{
+			name:        "Change minor units of a reconciled currency",
+			request:     &expensesv1.UpdateCurrencyRequest{Id: "cur_eur", Name: "Euro", MinorUnits: &zero},
+			expectError: true,
+			expectCode:  connect.CodeFailedPrecondition,
+		},
+		{
+			name:        "Change minor units of an unused currency",
+			request:     &expensesv1.UpdateCurrencyRequest{Id: "cur_usd", Name: "US Dollar", MinorUnits: &zero},
+			expectError: false,
+		},
+		{
+			name:        "Non-existent currency",
+			request:     &expensesv1.UpdateCurrencyRequest{Id: "cur_xxx", Name: "Nothing"},
+			expectError: true,
+			expectCode:  connect.CodeNotFound,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.UpdateCurrency(ctx, connect.NewRequest(tc.request))
+
+			// Check errors
+			assertError(t, err, tc.expectError, "")
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+
+			if resp.Msg.Currency.Symbol != tc.request.Symbol {
+				t.Errorf("Expected symbol=%s, got %s", tc.request.Symbol, resp.Msg.Currency.Symbol)
+			}
+			if resp.Msg.Currency.MinorUnits != tc.request.GetMinorUnits() {
+				t.Errorf("Expected minor_units=%d, got %d", tc.request.GetMinorUnits(), resp.Msg.Currency.MinorUnits)
+			}
+		})
+	}
+}
+
+// TestDeleteCurrency tests the DeleteCurrency RPC method
+func TestDeleteCurrency(t *testing.T) {
+	setupTransactionFixture(t)
+	createTestCurrency(t, testDB, "cur_eur", "EUR", "Euro")
+
+	// Create a new CurrencyService with the test repository
+	service := NewCurrencyService(currencyRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		id          string
+		expectError bool
+		expectCode  connect.Code
+	}{
+		{
+			name:        "Unused currency",
+			id:          "cur_eur",
+			expectError: false,
+		},
+		{
+			name:        "Currency used by accounts",
+			id:          "cur_jpy",
+			expectError: true,
+			expectCode:  connect.CodeFailedPrecondition,
+		},
+		{
+			name:        "Non-existent currency",
+			id:          "cur_xxx",
+			expectError: true,
+			expectCode:  connect.CodeNotFound,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.DeleteCurrency(ctx, connect.NewRequest(&expensesv1.DeleteCurrencyRequest{Id: tc.id}))
+
+			// Check errors
+			assertError(t, err, tc.expectError, "")
+			if tc.expectError && connect.CodeOf(err) != tc.expectCode {
+				t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+			}
+		})
+	}
+}
+
+// TestToProtoMoney tests the decimal and display forms of Money
+func TestToProtoMoney(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name              string
+		amount            int64
+		code              string
+		minorUnits        int64
+		symbol            string
+		expectedValue     string
+		expectedFormatted string
+	}{
+		{"Yen", 1234567, "JPY", 0, "¥", "1234567", "¥1,234,567"},
+		{"Dollars and cents", 123456, "USD", 2, "$", "1234.56", "$1,234.56"},
+		{"Less than one unit", 5, "EUR", 2, "€", "0.05", "€0.05"},
+		{"Negative amount", -1050, "USD", 2, "$", "-10.50", "-$10.50"},
+		{"Three decimals without symbol", 1000, "KWD", 3, "", "1.000", "1.000 KWD"},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := toProtoMoney(tc.amount, tc.code, tc.minorUnits, tc.symbol)
+			if m.Value != tc.expectedValue {
+				t.Errorf("Expected value=%s, got %s", tc.expectedValue, m.Value)
+			}
+			if m.Formatted != tc.expectedFormatted {
+				t.Errorf("Expected formatted=%s, got %s", tc.expectedFormatted, m.Formatted)
+			}
+		})
+	}
+}
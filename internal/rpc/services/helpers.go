@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/etag"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/money"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// referenceError converts a failed lookup of a referenced record into a connect
+// error, so callers get InvalidArgument instead of a raw foreign key failure
+func referenceError(ctx context.Context, logger *slog.Logger, field, id string, err error) error {
+	if stderrors.Is(err, errors.ErrNotFound) {
+		log.ErrorContext(ctx, logger, "Referenced record not found", "field", field, "id", id)
+		return errors.Error(connect.CodeInvalidArgument, errors.ReasonReferenceNotFound, fmt.Errorf("%w: %s %s does not exist", errors.ErrInvalidInput, field, id),
+			errors.BadRequest(errors.FieldViolation(field, errors.ReasonReferenceNotFound, fmt.Sprintf("%s %s does not exist", field, id))))
+	}
+	log.ErrorContext(ctx, logger, "Failed to check reference", "field", field, "id", id, "error", err)
+	return storageError(err)
+}
+
+// storageError converts a failed database operation into a connect error,
+// classifying constraint violations and busy databases by the field they name
+func storageError(err error) error {
+	return errors.Database(repo.TranslateError(err))
+}
+
+// nullableString maps nil and empty strings to a SQL NULL
+func nullableString(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
+
+// stringValue returns the value of a nullable string, or "" for NULL
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// timestampOrNil converts a nullable time to a timestamp, or nil for NULL
+func timestampOrNil(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// toProtoMoney converts an amount in minor units to a expensesv1.Money, using
+// the currency's minor units and symbol for the decimal and display forms
+func toProtoMoney(amount int64, code string, minorUnits int64, symbol string) *expensesv1.Money {
+	return &expensesv1.Money{
+		Amount:    amount,
+		Currency:  code,
+		Value:     money.FormatDecimal(amount, int(minorUnits)),
+		Formatted: money.Format(amount, int(minorUnits), symbol, code),
+	}
+}
+
+// checkETag compares the etag a write request was based on with the current
+// etag of the resource. An empty etag skips the check, so clients opt in to
+// optimistic concurrency control.
+func checkETag(ctx context.Context, logger *slog.Logger, resource, id, requested string, revision int64, updatedAt time.Time) error {
+	current := etag.Compute(revision, updatedAt)
+	if requested == "" || requested == current {
+		return nil
+	}
+	log.ErrorContext(ctx, logger, "Stale etag", "resource", resource, "id", id, "etag", requested, "current_etag", current)
+	return errors.Error(connect.CodeAborted, errors.ReasonEtagMismatch, fmt.Errorf("%w: %s %s has been modified since etag %s was read", errors.ErrConflict, resource, id, requested),
+		errors.Resource(resource, id))
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// TestErrorDetails tests the ErrorInfo reasons, BadRequest field violations
+// and ResourceInfo attached to errors
+func TestErrorDetails(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create the services with the test repositories
+	userService := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
+	accountService := newTestAccountService()
+
+	// Create a test user (using the main DB connection for setup)
+	testUser := createTestUser(t, testDB, "Details User", "details@example.com")
+
+	// Define test cases
+	ctx := context.Background()
+	tests := []struct {
+		name         string
+		call         func() error
+		expectCode   connect.Code
+		expectReason string
+		expectField  string
+		expectType   string
+	}{
+		{
+			name: "Missing field",
+			call: func() error {
+				_, err := userService.UpdateUser(ctx, connect.NewRequest(&expensesv1.UpdateUserRequest{
+					Id:         testUser.ID,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+				}))
+				return err
+			},
+			expectCode:   connect.CodeInvalidArgument,
+			expectReason: errors.ReasonRequiredField,
+			expectField:  "name",
+		},
+		{
+			name: "Duplicate email",
+			call: func() error {
+				_, err := userService.CreateUser(ctx, connect.NewRequest(&expensesv1.CreateUserRequest{Name: "Copy", Email: testUser.Email}))
+				return err
+			},
+			expectCode:   connect.CodeAlreadyExists,
+			expectReason: errors.ReasonAlreadyExists,
+			expectField:  "email",
+			expectType:   "user",
+		},
+		{
+			name: "Missing resource",
+			call: func() error {
+				_, err := userService.GetUser(ctx, connect.NewRequest(&expensesv1.GetUserRequest{Id: "missing"}))
+				return err
+			},
+			expectCode:   connect.CodeNotFound,
+			expectReason: errors.ReasonNotFound,
+			expectType:   "user",
+		},
+		{
+			name: "Missing reference",
+			call: func() error {
+				_, err := accountService.CreateAccount(ctx, connect.NewRequest(&expensesv1.CreateAccountRequest{Name: "Orphan", AccountTypeId: "at_missing"}))
+				return err
+			},
+			expectCode:   connect.CodeInvalidArgument,
+			expectReason: errors.ReasonReferenceNotFound,
+			expectField:  "account_type_id",
+		},
+		{
+			name: "Immutable update_mask path",
+			call: func() error {
+				_, err := userService.UpdateUser(ctx, connect.NewRequest(&expensesv1.UpdateUserRequest{
+					Id:         testUser.ID,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"id"}},
+				}))
+				return err
+			},
+			expectCode:   connect.CodeInvalidArgument,
+			expectReason: errors.ReasonInvalidField,
+			expectField:  "update_mask",
+		},
+		{
+			name: "Stale etag",
+			call: func() error {
+				_, err := userService.DeleteUser(ctx, connect.NewRequest(&expensesv1.DeleteUserRequest{Id: testUser.ID, Etag: "stale"}))
+				return err
+			},
+			expectCode:   connect.CodeAborted,
+			expectReason: errors.ReasonEtagMismatch,
+			expectType:   "user",
+		},
+		{
+			name: "Check violation",
+			call: func() error {
+				return storageError(fmt.Errorf("failed to create account: %w", &errors.DatabaseError{
+					Kind:    errors.ErrCheckViolation,
+					Table:   "accounts",
+					Columns: []string{"account_type_id"},
+				}))
+			},
+			expectCode:   connect.CodeFailedPrecondition,
+			expectReason: errors.ReasonCheckViolation,
+			expectField:  "account_type_id",
+		},
+		{
+			name: "Unclassified storage failure",
+			call: func() error {
+				return storageError(fmt.Errorf("failed to create account: disk I/O error"))
+			},
+			expectCode:   connect.CodeInternal,
+			expectReason: errors.ReasonInternal,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			if connect.CodeOf(err) != tc.expectCode {
+				t.Fatalf("Expected code %v, got %v (%v)", tc.expectCode, connect.CodeOf(err), err)
+			}
+			connectErr, ok := err.(*connect.Error)
+			if !ok {
+				t.Fatalf("Expected a *connect.Error, got %T", err)
+			}
+
+			var reason, field, resourceType string
+			for _, detail := range connectErr.Details() {
+				value, err := detail.Value()
+				if err != nil {
+					t.Fatalf("Failed to decode error detail %s: %v", detail.Type(), err)
+				}
+				switch msg := value.(type) {
+				case *errdetails.ErrorInfo:
+					if msg.Domain != errors.Domain {
+						t.Errorf("Expected domain %s, got %s", errors.Domain, msg.Domain)
+					}
+					reason = msg.Reason
+				case *errdetails.BadRequest:
+					if len(msg.FieldViolations) > 0 {
+						field = msg.FieldViolations[0].Field
+					}
+				case *errdetails.ResourceInfo:
+					resourceType = msg.ResourceType
+				}
+			}
+			if reason != tc.expectReason {
+				t.Errorf("Expected reason %s, got %s", tc.expectReason, reason)
+			}
+			if field != tc.expectField {
+				t.Errorf("Expected field violation of %q, got %q", tc.expectField, field)
+			}
+			if resourceType != tc.expectType {
+				t.Errorf("Expected resource type %q, got %q", tc.expectType, resourceType)
+			}
+		})
+	}
+}
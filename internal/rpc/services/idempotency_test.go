@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/idempotency"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// TestIdempotencyKey tests replaying retried requests through the
+// Idempotency-Key interceptor
+func TestIdempotencyKey(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Serve the UserService behind the interceptor, with a clock of its own so
+	// keys can expire without moving the shared test clock
+	keyClock := clock.NewMockClock(time.Date(2025, 4, 26, 12, 0, 0, 0, time.UTC))
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
+	interceptor := idempotency.NewInterceptor(repo.NewIdempotencyRepo(testDB), keyClock, time.Hour, testLogger, service)
+	mux := http.NewServeMux()
+	mux.Handle(expensesv1connect.NewUserServiceHandler(service, connect.WithInterceptors(interceptor)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := expensesv1connect.NewUserServiceClient(server.Client(), server.URL)
+
+	// Define the requests in the order they are sent. Each is compared with the
+	// user created by the request named in sameAs, if any.
+	tests := []struct {
+		name           string
+		key            string
+		userName       string
+		email          string
+		advance        time.Duration
+		expectCode     connect.Code
+		expectReason   string
+		expectReplayed bool
+		sameAs         string
+	}{
+		{
+			name:     "First request",
+			key:      "key-1",
+			userName: "Retried User",
+			email:    "first@example.com",
+		},
+		{
+			name:           "Retry replays the response",
+			key:            "key-1",
+			userName:       "Retried User",
+			email:          "first@example.com",
+			expectReplayed: true,
+			sameAs:         "First request",
+		},
+		{
+			name:         "Key reused with a different payload",
+			key:          "key-1",
+			userName:     "Retried User",
+			email:        "other@example.com",
+			expectCode:   connect.CodeInvalidArgument,
+			expectReason: errors.ReasonIdempotencyKeyReuse,
+		},
+		{
+			name:         "Failed request",
+			key:          "key-2",
+			userName:     "Duplicate User",
+			email:        "first@example.com",
+			expectCode:   connect.CodeAlreadyExists,
+			expectReason: errors.ReasonAlreadyExists,
+		},
+		{
+			name:           "Retry replays the error and its details",
+			key:            "key-2",
+			userName:       "Duplicate User",
+			email:          "first@example.com",
+			expectCode:     connect.CodeAlreadyExists,
+			expectReason:   errors.ReasonAlreadyExists,
+			expectReplayed: true,
+		},
+		{
+			name:       "Retry after the key expired runs again",
+			key:        "key-2",
+			userName:   "Duplicate User",
+			email:      "first@example.com",
+			advance:    2 * time.Hour,
+			expectCode: connect.CodeAlreadyExists,
+		},
+		{
+			name:     "Request without a key",
+			userName: "Retried User",
+			email:    "third@example.com",
+		},
+	}
+
+	// Run tests
+	created := make(map[string]string)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			keyClock.SetTime(keyClock.Now().Add(tc.advance))
+
+			req := connect.NewRequest(&expensesv1.CreateUserRequest{Name: tc.userName, Email: tc.email})
+			if tc.key != "" {
+				req.Header().Set(idempotency.Header, tc.key)
+			}
+			res, err := client.CreateUser(context.Background(), req)
+
+			replayed := ""
+			if tc.expectCode != 0 {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Fatalf("Expected code %v, got %v", tc.expectCode, err)
+				}
+				if connectErr, ok := err.(*connect.Error); ok {
+					replayed = connectErr.Meta().Get(idempotency.ReplayedHeader)
+				}
+				if reason := errorReason(t, err); tc.expectReason != "" && reason != tc.expectReason {
+					t.Errorf("Expected reason %s, got %q", tc.expectReason, reason)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				created[tc.name] = res.Msg.User.Id
+				replayed = res.Header().Get(idempotency.ReplayedHeader)
+			}
+
+			if (replayed == "true") != tc.expectReplayed {
+				t.Errorf("Expected replayed=%v, got %q", tc.expectReplayed, replayed)
+			}
+			if tc.sameAs != "" && created[tc.name] != created[tc.sameAs] {
+				t.Errorf("Expected user ID=%s, got %s", created[tc.sameAs], created[tc.name])
+			}
+		})
+	}
+
+	// Only the requests that ran created users
+	var count int
+	if err := testDB.Get(&count, "SELECT COUNT(*) FROM users"); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 users, got %d", count)
+	}
+}
+
+// TestIdempotencyKeySessionCookie tests that a retried CreateSession runs
+// again instead of replaying a response without its session cookie
+func TestIdempotencyKeySessionCookie(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Serve the AuthService behind the authentication and Idempotency-Key
+	// interceptors
+	service := NewAuthService(authRepo, auditRepo, testPages, time.Hour, true, testClock, testLogger)
+	interceptors := connect.WithInterceptors(
+		auth.NewInterceptor(authRepo, testClock, testLogger),
+		idempotency.NewInterceptor(repo.NewIdempotencyRepo(testDB), testClock, time.Hour, testLogger, service),
+	)
+	mux := http.NewServeMux()
+	mux.Handle(expensesv1connect.NewAuthServiceHandler(service, interceptors))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := expensesv1connect.NewAuthServiceClient(server.Client(), server.URL)
+
+	// Create a test user with an API token (using the main DB connection for setup)
+	user := createTestUser(t, testDB, "Session User", "session@example.com")
+	_, token := createTestAPIToken(t, testDB, user.ID, "session", nil)
+
+	// Define test cases
+	tests := []struct {
+		name string
+	}{
+		{name: "First request"},
+		{name: "Retry"},
+	}
+
+	// Run tests
+	sessions := make(map[string]bool)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := connect.NewRequest(&expensesv1.CreateSessionRequest{})
+			req.Header().Set("Authorization", "Bearer "+token)
+			req.Header().Set(idempotency.Header, "session-key")
+			res, err := client.CreateSession(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if replayed := res.Header().Get(idempotency.ReplayedHeader); replayed != "" {
+				t.Errorf("Expected the request to run, got replayed=%q", replayed)
+			}
+			cookie, err := http.ParseSetCookie(res.Header().Get("Set-Cookie"))
+			if err != nil || cookie.Name != auth.SessionCookie {
+				t.Fatalf("Expected a session cookie, got %q", res.Header().Get("Set-Cookie"))
+			}
+			if sessions[cookie.Value] {
+				t.Errorf("Expected a new session, got a repeated cookie")
+			}
+			sessions[cookie.Value] = true
+		})
+	}
+
+	// No key kept the session token
+	var count int
+	if err := testDB.Get(&count, "SELECT COUNT(*) FROM idempotency_keys"); err != nil {
+		t.Fatalf("Failed to count idempotency keys: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected 0 idempotency keys, got %d", count)
+	}
+}
@@ -0,0 +1,575 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/etag"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// institutionTypePrefix is stripped from enum names to get the stored type
+const institutionTypePrefix = "INSTITUTION_TYPE_"
+
+// InstitutionService implements the InstitutionService interface defined in the proto
+type InstitutionService struct {
+	expensesv1connect.UnimplementedInstitutionServiceHandler
+	repo    *repo.InstitutionRepo
+	auditor auditor
+	pages   *pagination.Codec
+	clock   clock.Clock
+	logger  *slog.Logger
+}
+
+// NewInstitutionService creates a new InstitutionService
+func NewInstitutionService(repo *repo.InstitutionRepo, auditRepo *repo.AuditRepo, pages *pagination.Codec, clock clock.Clock, logger *slog.Logger) *InstitutionService {
+	return &InstitutionService{
+		repo:    repo,
+		auditor: newAuditor(auditRepo, clock, logger),
+		pages:   pages,
+		clock:   clock,
+		logger:  logger,
+	}
+}
+
+// CreateInstitution creates a new institution
+func (s *InstitutionService) CreateInstitution(ctx context.Context, req *connect.Request[expensesv1.CreateInstitutionRequest]) (*connect.Response[expensesv1.CreateInstitutionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Creating institution", "name", req.Msg.Name, "type", req.Msg.Type.String())
+
+	// Validate input
+	if req.Msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateInstitution", "error", "name is required")
+		return nil, errors.Required("name")
+	}
+	if err := s.validateType(ctx, req.Msg.Type); err != nil {
+		return nil, err
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Create institution in database within the transaction
+	institution, err := s.repo.CreateInstitution(ctx, tx, db.CreateInstitutionParams{
+		WorkspaceID: authz.WorkspaceFrom(ctx),
+		Name:        req.Msg.Name,
+		Type:        institutionTypeToDB(req.Msg.Type),
+	})
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "Institution already exists", "name", req.Msg.Name)
+			return nil, errors.AlreadyExists("institution", req.Msg.Name, "name", "institution with name %s already exists", req.Msg.Name)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to create institution", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "institution", institution.ID, audit.ActionCreate, nil, toProtoInstitution(institution)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Institution created successfully", "id", institution.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.CreateInstitutionResponse{
+		Institution: toProtoInstitution(institution),
+	}), nil
+}
+
+// GetInstitution retrieves an institution by ID
+func (s *InstitutionService) GetInstitution(ctx context.Context, req *connect.Request[expensesv1.GetInstitutionRequest]) (*connect.Response[expensesv1.GetInstitutionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Getting institution", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for GetInstitution", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Get institution from database (read operations can use the main DB connection)
+	institution, err := s.getInstitution(ctx, s.repo.GetDB(), req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	log.InfoContext(ctx, s.logger, "Institution retrieved successfully", "id", institution.ID)
+
+	// Prepare response, exposing the etag for conditional HTTP GETs
+	resp := connect.NewResponse(&expensesv1.GetInstitutionResponse{
+		Institution: toProtoInstitution(institution),
+	})
+	resp.Header().Set(etag.Header, resp.Msg.Institution.Etag)
+	return resp, nil
+}
+
+// ListInstitutions retrieves a list of institutions, optionally filtered by type
+func (s *InstitutionService) ListInstitutions(ctx context.Context, req *connect.Request[expensesv1.ListInstitutionsRequest]) (*connect.Response[expensesv1.ListInstitutionsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing institutions", "type", req.Msg.Type.String(), "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Parse the type filter; unspecified lists every type
+	var institutionType *string
+	if req.Msg.Type != expensesv1.InstitutionType_INSTITUTION_TYPE_UNSPECIFIED {
+		if err := s.validateType(ctx, req.Msg.Type); err != nil {
+			return nil, err
+		}
+		stored := institutionTypeToDB(req.Msg.Type)
+		institutionType = &stored
+	}
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("institutions", stringValue(institutionType), req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering within the caller's workspace, narrowed
+	// to the requested type
+	query, err := parseQuery(ctx, s.logger, repo.InstitutionFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("institutions", authz.WorkspaceFrom(ctx)))
+	if institutionType != nil {
+		if err := query.Equal("type", *institutionType); err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to restrict institutions by type", "error", err)
+			return nil, errors.Internal(err)
+		}
+	}
+
+	// Get institutions from database (read operations can use the main DB connection)
+	institutions, err := s.repo.ListInstitutions(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list institutions", "error", err)
+		return nil, storageError(err)
+	}
+
+	institutions, pageResponse, err := paginate(ctx, s.logger, s.pages, page, institutions, func(row db.Institution) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountInstitutions(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to proto messages
+	protoInstitutions := make([]*expensesv1.Institution, len(institutions))
+	for i, institution := range institutions {
+		protoInstitutions[i] = toProtoInstitution(institution)
+	}
+
+	log.InfoContext(ctx, s.logger, "Institutions retrieved successfully", "count", len(institutions))
+
+	return connect.NewResponse(&expensesv1.ListInstitutionsResponse{
+		Institutions:       protoInstitutions,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// institutionUpdatePaths are the fields of an institution UpdateInstitution
+// may change
+var institutionUpdatePaths = []string{"name", "type"}
+
+// UpdateInstitution updates the fields of an existing institution selected by
+// the update mask
+func (s *InstitutionService) UpdateInstitution(ctx context.Context, req *connect.Request[expensesv1.UpdateInstitutionRequest]) (*connect.Response[expensesv1.UpdateInstitutionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Updating institution", "id", req.Msg.Id, "name", req.Msg.Name, "type", req.Msg.Type.String(), "update_mask", req.Msg.GetUpdateMask().GetPaths())
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateInstitution", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+	mask, err := parseUpdateMask(ctx, s.logger, req.Msg.UpdateMask, institutionUpdatePaths)
+	if err != nil {
+		return nil, err
+	}
+	if mask.has("name") && req.Msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateInstitution", "error", "name is required")
+		return nil, errors.Required("name")
+	}
+	if mask.has("type") {
+		if err := s.validateType(ctx, req.Msg.Type); err != nil {
+			return nil, err
+		}
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if institution exists and is unchanged since the client read it
+	existing, err := s.getInstitution(ctx, tx, req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkETag(ctx, s.logger, "institution", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Update the masked fields of the institution within the transaction
+	institution, err := s.repo.UpdateInstitution(ctx, tx, req.Msg.Id, existing.Revision, mask.pick(map[string]any{
+		"name": req.Msg.Name,
+		"type": institutionTypeToDB(req.Msg.Type),
+	}))
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Institution not found", "id", req.Msg.Id)
+			return nil, errors.NotFound("institution", req.Msg.Id)
+		}
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "Institution name already exists", "name", req.Msg.Name)
+			return nil, errors.AlreadyExists("institution", req.Msg.Name, "name", "institution with name %s already exists", req.Msg.Name)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to update institution", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "institution", institution.ID, audit.ActionUpdate, toProtoInstitution(existing), toProtoInstitution(institution)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Institution updated successfully", "id", institution.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.UpdateInstitutionResponse{
+		Institution: toProtoInstitution(institution),
+	}), nil
+}
+
+// DeleteInstitution moves an institution no account references to the trash
+func (s *InstitutionService) DeleteInstitution(ctx context.Context, req *connect.Request[expensesv1.DeleteInstitutionRequest]) (*connect.Response[expensesv1.DeleteInstitutionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Deleting institution", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for DeleteInstitution", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if institution exists within the transaction
+	existing, err := s.getInstitution(ctx, tx, req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkETag(ctx, s.logger, "institution", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Refuse while accounts still point at the institution, naming them so the
+	// caller knows what to move first
+	accounts, err := s.repo.ListAccounts(ctx, tx, req.Msg.Id)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list institution accounts", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if len(accounts) > 0 {
+		blocking := make([]string, len(accounts))
+		for i, account := range accounts {
+			blocking[i] = fmt.Sprintf("%s (%s)", account.ID, account.Name)
+		}
+		log.ErrorContext(ctx, s.logger, "Institution is referenced by accounts", "id", req.Msg.Id, "accounts", blocking)
+		return nil, errors.InUse("institution", req.Msg.Id, "institution %s is referenced by accounts: %s", req.Msg.Id, strings.Join(blocking, ", "))
+	}
+
+	// Move the institution to the trash within the transaction
+	if err := s.repo.DeleteInstitution(ctx, tx, req.Msg.Id, existing.Revision, s.clock.Now().UTC()); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to delete institution", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "institution", existing.ID, audit.ActionDelete, toProtoInstitution(existing), nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Institution deleted successfully", "id", req.Msg.Id)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.DeleteInstitutionResponse{
+		Success: true,
+	}), nil
+}
+
+// ListDeletedInstitutions retrieves a paginated list of the institutions in the trash
+func (s *InstitutionService) ListDeletedInstitutions(ctx context.Context, req *connect.Request[expensesv1.ListDeletedInstitutionsRequest]) (*connect.Response[expensesv1.ListDeletedInstitutionsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing deleted institutions", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("deleted_institutions", req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering within the caller's workspace
+	query, err := parseQuery(ctx, s.logger, repo.InstitutionFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("institutions", authz.WorkspaceFrom(ctx)))
+
+	// Get deleted institutions from database (read operations can use the main DB connection)
+	institutions, err := s.repo.ListDeletedInstitutions(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list deleted institutions", "error", err)
+		return nil, storageError(err)
+	}
+
+	institutions, pageResponse, err := paginate(ctx, s.logger, s.pages, page, institutions, func(row db.Institution) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountDeletedInstitutions(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to proto messages
+	protoInstitutions := make([]*expensesv1.Institution, len(institutions))
+	for i, institution := range institutions {
+		protoInstitutions[i] = toProtoInstitution(institution)
+	}
+
+	log.InfoContext(ctx, s.logger, "Deleted institutions retrieved successfully", "count", len(institutions))
+
+	return connect.NewResponse(&expensesv1.ListDeletedInstitutionsResponse{
+		Institutions:       protoInstitutions,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// UndeleteInstitution restores an institution from the trash
+func (s *InstitutionService) UndeleteInstitution(ctx context.Context, req *connect.Request[expensesv1.UndeleteInstitutionRequest]) (*connect.Response[expensesv1.UndeleteInstitutionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Undeleting institution", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UndeleteInstitution", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if the institution is in the trash within the transaction
+	existing, err := s.repo.GetDeletedInstitution(ctx, tx, authz.WorkspaceFrom(ctx), req.Msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Deleted institution not found", "id", req.Msg.Id)
+			return nil, errors.Error(connect.CodeNotFound, errors.ReasonNotFound, fmt.Errorf("%w: deleted institution with id %s not found", errors.ErrNotFound, req.Msg.Id), errors.Resource("institution", req.Msg.Id))
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to check if deleted institution exists", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "institution", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Restore the institution within the transaction
+	institution, err := s.repo.UndeleteInstitution(ctx, tx, req.Msg.Id, existing.Revision)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "A live institution already has the name of the deleted institution", "id", req.Msg.Id, "name", existing.Name)
+			return nil, errors.AlreadyExists("institution", existing.Name, "name", "institution with name %s already exists", existing.Name)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to undelete institution", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "institution", institution.ID, audit.ActionUndelete, toProtoInstitution(existing), toProtoInstitution(institution)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Institution undeleted successfully", "id", institution.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.UndeleteInstitutionResponse{
+		Institution: toProtoInstitution(institution),
+	}), nil
+}
+
+// ListInstitutionAccounts retrieves the accounts held at an institution
+func (s *InstitutionService) ListInstitutionAccounts(ctx context.Context, req *connect.Request[expensesv1.ListInstitutionAccountsRequest]) (*connect.Response[expensesv1.ListInstitutionAccountsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing institution accounts", "institution_id", req.Msg.InstitutionId)
+
+	// Validate input
+	if req.Msg.InstitutionId == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for ListInstitutionAccounts", "error", "institution_id is required")
+		return nil, errors.Required("institution_id")
+	}
+
+	// Get accounts from database (read operations can use the main DB connection)
+	if _, err := s.getInstitution(ctx, s.repo.GetDB(), req.Msg.InstitutionId); err != nil {
+		return nil, err
+	}
+	accounts, err := s.repo.ListAccounts(ctx, s.repo.GetDB(), req.Msg.InstitutionId)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list institution accounts", "institution_id", req.Msg.InstitutionId, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Narrow the list to the accounts the caller belongs to
+	if subject, ok := authz.SubjectFrom(ctx); ok {
+		accounts = slices.DeleteFunc(accounts, func(account db.Account) bool {
+			return !subject.Member(account.ID)
+		})
+	}
+
+	// Convert to proto messages
+	protoAccounts := make([]*expensesv1.Account, len(accounts))
+	for i, account := range accounts {
+		protoAccounts[i] = toProtoAccount(account)
+	}
+
+	log.InfoContext(ctx, s.logger, "Institution accounts retrieved successfully", "institution_id", req.Msg.InstitutionId, "count", len(accounts))
+
+	return connect.NewResponse(&expensesv1.ListInstitutionAccountsResponse{
+		Accounts: protoAccounts,
+	}), nil
+}
+
+// getInstitution fetches an institution and maps a missing row to NotFound
+func (s *InstitutionService) getInstitution(ctx context.Context, dbtx db.DBTX, id string) (db.Institution, error) {
+	institution, err := s.repo.GetInstitution(ctx, dbtx, authz.WorkspaceFrom(ctx), id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Institution not found", "id", id)
+			return db.Institution{}, errors.NotFound("institution", id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get institution", "id", id, "error", err)
+		return db.Institution{}, storageError(err)
+	}
+	return institution, nil
+}
+
+// validateType checks that an institution type is one of the known kinds
+func (s *InstitutionService) validateType(ctx context.Context, institutionType expensesv1.InstitutionType) error {
+	if _, known := expensesv1.InstitutionType_name[int32(institutionType)]; !known || institutionType == expensesv1.InstitutionType_INSTITUTION_TYPE_UNSPECIFIED {
+		log.ErrorContext(ctx, s.logger, "Invalid institution type", "type", int32(institutionType))
+		return errors.InvalidField("type", "type must be one of BANK, CARD_ISSUER, BROKER, WALLET or OTHER")
+	}
+	return nil
+}
+
+// institutionTypeToDB returns the stored form of an institution type, e.g. BANK
+func institutionTypeToDB(institutionType expensesv1.InstitutionType) string {
+	return strings.TrimPrefix(institutionType.String(), institutionTypePrefix)
+}
+
+// institutionTypeFromDB maps a stored institution type back to the enum,
+// treating anything unrecognised as OTHER
+func institutionTypeFromDB(stored string) expensesv1.InstitutionType {
+	if value, ok := expensesv1.InstitutionType_value[institutionTypePrefix+stored]; ok {
+		return expensesv1.InstitutionType(value)
+	}
+	return expensesv1.InstitutionType_INSTITUTION_TYPE_OTHER
+}
+
+// toProtoInstitution converts a db.Institution to a expensesv1.Institution
+func toProtoInstitution(institution db.Institution) *expensesv1.Institution {
+	return &expensesv1.Institution{
+		Id:        institution.ID,
+		Name:      institution.Name,
+		Type:      institutionTypeFromDB(institution.Type),
+		CreatedAt: timestamppb.New(institution.CreatedAt),
+		UpdatedAt: timestamppb.New(institution.UpdatedAt),
+		Etag:      etag.Compute(institution.Revision, institution.UpdatedAt),
+		DeletedAt: timestampOrNil(institution.DeletedAt),
+	}
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// TestCreateInstitution tests the CreateInstitution RPC method
+func TestCreateInstitution(t *testing.T) {
+	resetTestDB(t)
+	createTestInstitution(t, testDB, "fi_existing", "Existing Bank", "BANK")
+
+	// Create a new InstitutionService with the test repository
+	service := NewInstitutionService(institutionRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		request     *expensesv1.CreateInstitutionRequest
+		expectError bool
+		expectCode  connect.Code
+	}{
+		{
+			name:        "Valid bank",
+			request:     &expensesv1.CreateInstitutionRequest{Name: "MUFG", Type: expensesv1.InstitutionType_INSTITUTION_TYPE_BANK},
+			expectError: false,
+		},
+		{
+			name:        "Valid wallet",
+			request:     &expensesv1.CreateInstitutionRequest{Name: "PayPay", Type: expensesv1.InstitutionType_INSTITUTION_TYPE_WALLET},
+			expectError: false,
+		},
+		{
+			name:        "Unspecified type",
+			request:     &expensesv1.CreateInstitutionRequest{Name: "Mystery"},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name:        "Unknown type value",
+			request:     &expensesv1.CreateInstitutionRequest{Name: "Mystery", Type: expensesv1.InstitutionType(42)},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name:        "Duplicate name",
+			request:     &expensesv1.CreateInstitutionRequest{Name: "Existing Bank", Type: expensesv1.InstitutionType_INSTITUTION_TYPE_BANK},
+			expectError: true,
+			expectCode:  connect.CodeAlreadyExists,
+		},
+		{
+			name:        "Missing name",
+			request:     &expensesv1.CreateInstitutionRequest{Type: expensesv1.InstitutionType_INSTITUTION_TYPE_BANK},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.CreateInstitution(ctx, connect.NewRequest(tc.request))
+
+			// Check errors
+			assertError(t, err, tc.expectError, "")
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+
+			// Verify response for successful cases
+			institution := resp.Msg.Institution
+			if !strings.HasPrefix(institution.Id, "fi_") {
+				t.Errorf("Expected generated fi_ ID, got %s", institution.Id)
+			}
+			if institution.Type != tc.request.Type {
+				t.Errorf("Expected type=%v, got %v", tc.request.Type, institution.Type)
+			}
+		})
+	}
+}
+
+// TestListInstitutions tests the ListInstitutions RPC method
+func TestListInstitutions(t *testing.T) {
+	resetTestDB(t)
+	createTestInstitution(t, testDB, "fi_mufg", "MUFG", "BANK")
+	createTestInstitution(t, testDB, "fi_smbc", "SMBC", "BANK")
+	createTestInstitution(t, testDB, "fi_jcb", "JCB", "CARD_ISSUER")
+	createTestInstitution(t, testDB, "fi_sbi", "SBI Securities", "BROKER")
+
+	// Create a new InstitutionService with the test repository
+	service := NewInstitutionService(institutionRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		request     *expensesv1.ListInstitutionsRequest
+		expectedIDs []string
+		expectTotal int32
+		expectError bool
+	}{
+		{
+			name:        "All types",
+			request:     &expensesv1.ListInstitutionsRequest{},
+			expectedIDs: []string{"fi_jcb", "fi_mufg", "fi_sbi", "fi_smbc"},
+			expectTotal: 4,
+		},
+		{
+			name:        "Banks only",
+			request:     &expensesv1.ListInstitutionsRequest{Type: expensesv1.InstitutionType_INSTITUTION_TYPE_BANK},
+			expectedIDs: []string{"fi_mufg", "fi_smbc"},
+			expectTotal: 2,
+		},
+		{
+			name:        "Type without institutions",
+			request:     &expensesv1.ListInstitutionsRequest{Type: expensesv1.InstitutionType_INSTITUTION_TYPE_WALLET},
+			expectedIDs: []string{},
+		},
+		{
+			name: "Banks with pagination",
+			request: &expensesv1.ListInstitutionsRequest{
+				Type:       expensesv1.InstitutionType_INSTITUTION_TYPE_BANK,
+				Pagination: &expensesv1.Pagination{PageSize: 1, PageToken: pageTokenAfter(t, pagination.Filter("institutions", "BANK", "", ""), "MUFG", "fi_mufg")},
+			},
+			expectedIDs: []string{"fi_smbc"},
+			expectTotal: 2,
+		},
+		{
+			name: "Token reused with another type",
+			request: &expensesv1.ListInstitutionsRequest{
+				Type:       expensesv1.InstitutionType_INSTITUTION_TYPE_BROKER,
+				Pagination: &expensesv1.Pagination{PageSize: 1, PageToken: pageTokenAfter(t, pagination.Filter("institutions", "BANK", "", ""), "MUFG", "fi_mufg")},
+			},
+			expectError: true,
+		},
+		{
+			name:        "Unknown type",
+			request:     &expensesv1.ListInstitutionsRequest{Type: expensesv1.InstitutionType(42)},
+			expectError: true,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.ListInstitutions(ctx, connect.NewRequest(tc.request))
+			assertError(t, err, tc.expectError, "")
+			if tc.expectError {
+				return
+			}
+
+			if len(resp.Msg.Institutions) != len(tc.expectedIDs) {
+				t.Fatalf("Expected %d institutions, got %d", len(tc.expectedIDs), len(resp.Msg.Institutions))
+			}
+			for i, institution := range resp.Msg.Institutions {
+				if institution.Id != tc.expectedIDs[i] {
+					t.Errorf("Expected institution %d to be %s, got %s", i, tc.expectedIDs[i], institution.Id)
+				}
+			}
+			if resp.Msg.PaginationResponse.TotalCount != tc.expectTotal {
+				t.Errorf("Expected total count %d, got %d", tc.expectTotal, resp.Msg.PaginationResponse.TotalCount)
+			}
+		})
+	}
+}
+
+// TestDeleteInstitution tests the DeleteInstitution and ListInstitutionAccounts RPC methods
+func TestDeleteInstitution(t *testing.T) {
+	resetTestDB(t)
+	createTestInstitution(t, testDB, "fi_mufg", "MUFG", "BANK")
+	createTestInstitution(t, testDB, "fi_unused", "Unused Bank", "BANK")
+	checking := createTestAccount(t, testDB, "MUFG Checking", "at_asset", nil)
+	savings := createTestAccount(t, testDB, "MUFG Savings", "at_asset", nil)
+	if _, err := testDB.Exec("UPDATE accounts SET institution_id = 'fi_mufg'"); err != nil {
+		t.Fatalf("Failed to link accounts: %v", err)
+	}
+
+	// Create a new InstitutionService with the test repository
+	service := NewInstitutionService(institutionRepo, auditRepo, testPages, testClock, testLogger)
+
+	ctx := context.Background()
+
+	t.Run("List accounts at institution", func(t *testing.T) {
+		resp, err := service.ListInstitutionAccounts(ctx, connect.NewRequest(&expensesv1.ListInstitutionAccountsRequest{
+			InstitutionId: "fi_mufg",
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(resp.Msg.Accounts) != 2 || resp.Msg.Accounts[0].Id != checking.ID || resp.Msg.Accounts[1].Id != savings.ID {
+			t.Errorf("Expected checking and savings accounts, got %v", resp.Msg.Accounts)
+		}
+	})
+
+	// Define test cases
+	tests := []struct {
+		name          string
+		id            string
+		expectError   bool
+		expectCode    connect.Code
+		expectInError []string
+	}{
+		{
+			name:          "Institution with accounts",
+			id:            "fi_mufg",
+			expectError:   true,
+			expectCode:    connect.CodeFailedPrecondition,
+			expectInError: []string{checking.ID, savings.ID},
+		},
+		{
+			name:        "Institution without accounts",
+			id:          "fi_unused",
+			expectError: false,
+		},
+		{
+			name:        "Non-existent institution",
+			id:          "fi_missing",
+			expectError: true,
+			expectCode:  connect.CodeNotFound,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.DeleteInstitution(ctx, connect.NewRequest(&expensesv1.DeleteInstitutionRequest{Id: tc.id}))
+
+			// Check errors
+			assertError(t, err, tc.expectError, "")
+			if !tc.expectError {
+				return
+			}
+			if connect.CodeOf(err) != tc.expectCode {
+				t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+			}
+			for _, want := range tc.expectInError {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to list blocking account %s, got %v", want, err)
+				}
+			}
+		})
+	}
+}
@@ -9,9 +9,13 @@ import (
 	"connectrpc.com/connect"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/authz"
 	"github.com/atreya2011/expense-manager/internal/clock"
 	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/etag"
 	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/pagination"
 	"github.com/atreya2011/expense-manager/internal/repo"
 	db "github.com/atreya2011/expense-manager/internal/repo/gen"
 	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
@@ -21,17 +25,21 @@ import (
 // InstrumentService implements the InstrumentService interface defined in the proto
 type InstrumentService struct {
 	expensesv1connect.UnimplementedInstrumentServiceHandler
-	repo   *repo.InstrumentRepo
-	clock  clock.Clock
-	logger *slog.Logger
+	repo    *repo.InstrumentRepo
+	auditor auditor
+	pages   *pagination.Codec
+	clock   clock.Clock
+	logger  *slog.Logger
 }
 
 // NewInstrumentService creates a new InstrumentService
-func NewInstrumentService(repo *repo.InstrumentRepo, clock clock.Clock, logger *slog.Logger) *InstrumentService {
+func NewInstrumentService(repo *repo.InstrumentRepo, auditRepo *repo.AuditRepo, pages *pagination.Codec, clock clock.Clock, logger *slog.Logger) *InstrumentService {
 	return &InstrumentService{
-		repo:   repo,
-		clock:  clock,
-		logger: logger,
+		repo:    repo,
+		auditor: newAuditor(auditRepo, clock, logger),
+		pages:   pages,
+		clock:   clock,
+		logger:  logger,
 	}
 }
 
@@ -40,17 +48,11 @@ func (s *InstrumentService) CreateInstrument(ctx context.Context, req *connect.R
 	// Log method entry
 	log.InfoContext(ctx, s.logger, "Creating instrument", "name", req.Msg.Name)
 
-	// Validate input
-	if req.Msg.Name == "" {
-		log.ErrorContext(ctx, s.logger, "Invalid input for CreateInstrument", "error", "name is required")
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: name is required", errors.ErrInvalidInput))
-	}
-
 	// Begin transaction
 	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
 	if err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to begin transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
 	}
 	defer func() {
 		if err := tx.Rollback(); err != nil {
@@ -60,20 +62,15 @@ func (s *InstrumentService) CreateInstrument(ctx context.Context, req *connect.R
 	}() // Rollback if any error occurs
 
 	// Create instrument in database within the transaction
-	instrument, err := s.repo.CreateInstrument(ctx, tx, req.Msg.Name)
+	instrument, err := s.createInstrument(ctx, tx, req.Msg)
 	if err != nil {
-		if stderrors.Is(err, errors.ErrDuplicate) {
-			log.ErrorContext(ctx, s.logger, "Instrument already exists", "name", req.Msg.Name)
-			return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("%w: instrument with name %s already exists", errors.ErrDuplicate, req.Msg.Name))
-		}
-		log.ErrorContext(ctx, s.logger, "Failed to create instrument", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		return nil, err
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to commit transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
 	// Log success
@@ -90,61 +87,59 @@ func (s *InstrumentService) GetInstrument(ctx context.Context, req *connect.Requ
 	// Log method entry
 	log.InfoContext(ctx, s.logger, "Getting instrument", "id", req.Msg.Id)
 
-	// Validate input
-	if req.Msg.Id == "" {
-		log.ErrorContext(ctx, s.logger, "Invalid input for GetInstrument", "error", "id is required")
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: id is required", errors.ErrInvalidInput))
-	}
-
 	// Get instrument from database (read operations can use the main DB connection)
-	instrument, err := s.repo.GetInstrument(ctx, s.repo.GetDB(), req.Msg.Id)
+	instrument, err := s.repo.GetInstrument(ctx, s.repo.GetDB(), authz.WorkspaceFrom(ctx), req.Msg.Id)
 	if err != nil {
 		if stderrors.Is(err, errors.ErrNotFound) {
 			log.ErrorContext(ctx, s.logger, "Instrument not found", "id", req.Msg.Id)
-			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("%w: instrument with id %s not found", errors.ErrNotFound, req.Msg.Id))
+			return nil, errors.NotFound("instrument", req.Msg.Id)
 		}
 		log.ErrorContext(ctx, s.logger, "Failed to get instrument", "id", req.Msg.Id, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		return nil, storageError(err)
 	}
 
 	log.InfoContext(ctx, s.logger, "Instrument retrieved successfully", "id", instrument.ID)
 
-	// Prepare response
-	return connect.NewResponse(&expensesv1.GetInstrumentResponse{
+	// Prepare response, exposing the etag for conditional HTTP GETs
+	resp := connect.NewResponse(&expensesv1.GetInstrumentResponse{
 		Instrument: toProtoInstrument(instrument),
-	}), nil
+	})
+	resp.Header().Set(etag.Header, resp.Msg.Instrument.Etag)
+	return resp, nil
 }
 
 // ListInstruments retrieves a paginated list of instruments
 func (s *InstrumentService) ListInstruments(ctx context.Context, req *connect.Request[expensesv1.ListInstrumentsRequest]) (*connect.Response[expensesv1.ListInstrumentsResponse], error) {
 	// Log method entry
-	log.InfoContext(ctx, s.logger, "Listing instruments")
+	log.InfoContext(ctx, s.logger, "Listing instruments", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
 
 	// Parse pagination parameters
-	limit := int64(50) // default limit
-	offset := int64(0) // default offset
-	if req.Msg.Pagination != nil {
-		if req.Msg.Pagination.PageSize > 0 {
-			limit = int64(req.Msg.Pagination.PageSize)
-		}
-		// Extract offset from page token if provided
-		if req.Msg.Pagination.PageToken != "" {
-			// In a real implementation, we would decode the page token to get the offset
-			// For simplicity, we'll just parse it as an offset
-			if _, err := fmt.Sscanf(req.Msg.Pagination.PageToken, "%d", &offset); err != nil {
-				log.ErrorContext(ctx, s.logger, "Invalid page token", "token", req.Msg.Pagination.PageToken, "error", err)
-				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: invalid page token", errors.ErrInvalidInput))
-			}
-		}
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("instruments", req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
 	}
 
-	log.InfoContext(ctx, s.logger, "Pagination parameters", "limit", limit, "offset", offset)
+	// Compile the filter and ordering within the caller's workspace
+	query, err := parseQuery(ctx, s.logger, repo.InstrumentFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("instruments", authz.WorkspaceFrom(ctx)))
 
 	// Get instruments from database (read operations can use the main DB connection)
-	instruments, err := s.repo.ListInstruments(ctx, s.repo.GetDB(), limit, offset)
+	instruments, err := s.repo.ListInstruments(ctx, s.repo.GetDB(), query, page.Size+1)
 	if err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to list instruments", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		return nil, storageError(err)
+	}
+
+	instruments, pageResponse, err := paginate(ctx, s.logger, s.pages, page, instruments, func(row db.Instrument) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountInstruments(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare response
@@ -153,43 +148,154 @@ func (s *InstrumentService) ListInstruments(ctx context.Context, req *connect.Re
 		protoInstruments[i] = toProtoInstrument(instrument)
 	}
 
-	// Prepare pagination response
-	nextPageToken := ""
-	if len(instruments) == int(limit) {
-		nextPageToken = fmt.Sprintf("%d", offset+limit)
-	}
-
 	log.InfoContext(ctx, s.logger, "Instruments retrieved successfully", "count", len(instruments))
 
 	return connect.NewResponse(&expensesv1.ListInstrumentsResponse{
-		Instruments: protoInstruments,
-		PaginationResponse: &expensesv1.PaginationResponse{
-			NextPageToken: nextPageToken,
-			TotalCount:    int32(len(instruments)), // In a real implementation, we would get the total count from the database
-		},
+		Instruments:        protoInstruments,
+		PaginationResponse: pageResponse,
 	}), nil
 }
 
-// UpdateInstrument updates an instrument
+// instrumentUpdatePaths are the fields of an instrument UpdateInstrument may
+// change
+var instrumentUpdatePaths = []string{"name"}
+
+// UpdateInstrument updates the fields of an instrument selected by the update
+// mask
 func (s *InstrumentService) UpdateInstrument(ctx context.Context, req *connect.Request[expensesv1.UpdateInstrumentRequest]) (*connect.Response[expensesv1.UpdateInstrumentResponse], error) {
 	// Log method entry
-	log.InfoContext(ctx, s.logger, "Updating instrument", "id", req.Msg.Id, "name", req.Msg.Name)
+	log.InfoContext(ctx, s.logger, "Updating instrument", "id", req.Msg.Id, "name", req.Msg.Name, "update_mask", req.Msg.GetUpdateMask().GetPaths())
 
-	// Validate input
-	if req.Msg.Id == "" {
-		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateInstrument", "error", "id is required")
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: id is required", errors.ErrInvalidInput))
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
 	}
-	if req.Msg.Name == "" {
-		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateInstrument", "error", "name is required")
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: name is required", errors.ErrInvalidInput))
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Update instrument within the transaction
+	instrument, err := s.updateInstrument(ctx, tx, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Instrument updated successfully", "id", instrument.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.UpdateInstrumentResponse{
+		Instrument: toProtoInstrument(instrument),
+	}), nil
+}
+
+// DeleteInstrument moves an instrument to the trash
+func (s *InstrumentService) DeleteInstrument(ctx context.Context, req *connect.Request[expensesv1.DeleteInstrumentRequest]) (*connect.Response[expensesv1.DeleteInstrumentResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Deleting instrument", "id", req.Msg.Id)
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Move the instrument to the trash within the transaction
+	if err := s.deleteInstrument(ctx, tx, req.Msg); err != nil {
+		return nil, err
 	}
 
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Instrument deleted successfully", "id", req.Msg.Id)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.DeleteInstrumentResponse{
+		Success: true,
+	}), nil
+}
+
+// ListDeletedInstruments retrieves a paginated list of the instruments in the trash
+func (s *InstrumentService) ListDeletedInstruments(ctx context.Context, req *connect.Request[expensesv1.ListDeletedInstrumentsRequest]) (*connect.Response[expensesv1.ListDeletedInstrumentsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing deleted instruments", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("deleted_instruments", req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering within the caller's workspace
+	query, err := parseQuery(ctx, s.logger, repo.InstrumentFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("instruments", authz.WorkspaceFrom(ctx)))
+
+	// Get deleted instruments from database (read operations can use the main DB connection)
+	instruments, err := s.repo.ListDeletedInstruments(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list deleted instruments", "error", err)
+		return nil, storageError(err)
+	}
+
+	instruments, pageResponse, err := paginate(ctx, s.logger, s.pages, page, instruments, func(row db.Instrument) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountDeletedInstruments(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to proto messages
+	protoInstruments := make([]*expensesv1.Instrument, len(instruments))
+	for i, instrument := range instruments {
+		protoInstruments[i] = toProtoInstrument(instrument)
+	}
+
+	log.InfoContext(ctx, s.logger, "Deleted instruments retrieved successfully", "count", len(instruments))
+
+	return connect.NewResponse(&expensesv1.ListDeletedInstrumentsResponse{
+		Instruments:        protoInstruments,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// UndeleteInstrument restores an instrument from the trash
+func (s *InstrumentService) UndeleteInstrument(ctx context.Context, req *connect.Request[expensesv1.UndeleteInstrumentRequest]) (*connect.Response[expensesv1.UndeleteInstrumentResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Undeleting instrument", "id", req.Msg.Id)
+
 	// Begin transaction
 	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
 	if err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to begin transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
 	}
 	defer func() {
 		if err := tx.Rollback(); err != nil {
@@ -198,59 +304,109 @@ func (s *InstrumentService) UpdateInstrument(ctx context.Context, req *connect.R
 		}
 	}() // Rollback if any error occurs
 
-	// Check if instrument exists within the transaction
-	_, err = s.repo.GetInstrument(ctx, tx, req.Msg.Id)
+	// Check if the instrument is in the trash within the transaction
+	existing, err := s.repo.GetDeletedInstrument(ctx, tx, authz.WorkspaceFrom(ctx), req.Msg.Id)
 	if err != nil {
 		if stderrors.Is(err, errors.ErrNotFound) {
-			log.ErrorContext(ctx, s.logger, "Instrument not found", "id", req.Msg.Id)
-			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("%w: instrument with id %s not found", errors.ErrNotFound, req.Msg.Id))
+			log.ErrorContext(ctx, s.logger, "Deleted instrument not found", "id", req.Msg.Id)
+			return nil, errors.Error(connect.CodeNotFound, errors.ReasonNotFound, fmt.Errorf("%w: deleted instrument with id %s not found", errors.ErrNotFound, req.Msg.Id), errors.Resource("instrument", req.Msg.Id))
 		}
-		log.ErrorContext(ctx, s.logger, "Failed to check if instrument exists", "id", req.Msg.Id, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		log.ErrorContext(ctx, s.logger, "Failed to check if deleted instrument exists", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "instrument", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
 	}
 
-	// Update instrument in database within the transaction
-	instrument, err := s.repo.UpdateInstrument(ctx, tx, req.Msg.Id, req.Msg.Name)
+	// Restore the instrument within the transaction
+	instrument, err := s.repo.UndeleteInstrument(ctx, tx, req.Msg.Id, existing.Revision)
 	if err != nil {
 		if stderrors.Is(err, errors.ErrDuplicate) {
-			log.ErrorContext(ctx, s.logger, "Instrument with name already exists", "name", req.Msg.Name)
-			return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("%w: instrument with name %s already exists", errors.ErrDuplicate, req.Msg.Name))
+			log.ErrorContext(ctx, s.logger, "A live instrument already has the name of the deleted instrument", "id", req.Msg.Id, "name", existing.Name)
+			return nil, errors.AlreadyExists("instrument", existing.Name, "name", "instrument with name %s already exists", existing.Name)
 		}
-		log.ErrorContext(ctx, s.logger, "Failed to update instrument", "id", req.Msg.Id, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		log.ErrorContext(ctx, s.logger, "Failed to undelete instrument", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "instrument", instrument.ID, audit.ActionUndelete, toProtoInstrument(existing), toProtoInstrument(instrument)); err != nil {
+		return nil, err
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to commit transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
 	// Log success
-	log.InfoContext(ctx, s.logger, "Instrument updated successfully", "id", instrument.ID)
+	log.InfoContext(ctx, s.logger, "Instrument undeleted successfully", "id", instrument.ID)
 
 	// Prepare response
-	return connect.NewResponse(&expensesv1.UpdateInstrumentResponse{
+	return connect.NewResponse(&expensesv1.UndeleteInstrumentResponse{
 		Instrument: toProtoInstrument(instrument),
 	}), nil
 }
 
-// DeleteInstrument deletes an instrument by ID
-func (s *InstrumentService) DeleteInstrument(ctx context.Context, req *connect.Request[expensesv1.DeleteInstrumentRequest]) (*connect.Response[expensesv1.DeleteInstrumentResponse], error) {
+// BatchCreateInstruments creates many instruments in one transaction
+func (s *InstrumentService) BatchCreateInstruments(ctx context.Context, req *connect.Request[expensesv1.BatchCreateInstrumentsRequest]) (*connect.Response[expensesv1.BatchCreateInstrumentsResponse], error) {
 	// Log method entry
-	log.InfoContext(ctx, s.logger, "Deleting instrument", "id", req.Msg.Id)
+	log.InfoContext(ctx, s.logger, "Batch creating instruments", "count", len(req.Msg.Requests), "allow_partial", req.Msg.AllowPartial)
 
-	// Validate input
-	if req.Msg.Id == "" {
-		log.ErrorContext(ctx, s.logger, "Invalid input for DeleteInstrument", "error", "id is required")
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: id is required", errors.ErrInvalidInput))
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Create each instrument within the transaction
+	var instruments []*expensesv1.Instrument
+	failures, err := runBatch(ctx, s.logger, tx, req.Msg.Requests, req.Msg.AllowPartial, func(msg *expensesv1.CreateInstrumentRequest) error {
+		instrument, err := s.createInstrument(ctx, tx, msg)
+		if err != nil {
+			return err
+		}
+		instruments = append(instruments, toProtoInstrument(instrument))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Instruments batch created successfully", "created", len(instruments), "failed", len(failures))
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.BatchCreateInstrumentsResponse{
+		Instruments: instruments,
+		Errors:      failures,
+	}), nil
+}
+
+// BatchUpdateInstruments updates many instruments in one transaction
+func (s *InstrumentService) BatchUpdateInstruments(ctx context.Context, req *connect.Request[expensesv1.BatchUpdateInstrumentsRequest]) (*connect.Response[expensesv1.BatchUpdateInstrumentsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Batch updating instruments", "count", len(req.Msg.Requests), "allow_partial", req.Msg.AllowPartial)
+
 	// Begin transaction
 	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
 	if err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to begin transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
 	}
 	defer func() {
 		if err := tx.Rollback(); err != nil {
@@ -259,39 +415,177 @@ func (s *InstrumentService) DeleteInstrument(ctx context.Context, req *connect.R
 		}
 	}() // Rollback if any error occurs
 
-	// Check if instrument exists within the transaction
-	_, err = s.repo.GetInstrument(ctx, tx, req.Msg.Id)
-	if err != nil {
-		if stderrors.Is(err, errors.ErrNotFound) {
-			log.ErrorContext(ctx, s.logger, "Instrument not found", "id", req.Msg.Id)
-			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("%w: instrument with id %s not found", errors.ErrNotFound, req.Msg.Id))
+	// Update each instrument within the transaction
+	var instruments []*expensesv1.Instrument
+	failures, err := runBatch(ctx, s.logger, tx, req.Msg.Requests, req.Msg.AllowPartial, func(msg *expensesv1.UpdateInstrumentRequest) error {
+		instrument, err := s.updateInstrument(ctx, tx, msg)
+		if err != nil {
+			return err
 		}
-		log.ErrorContext(ctx, s.logger, "Failed to check if instrument exists", "id", req.Msg.Id, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		instruments = append(instruments, toProtoInstrument(instrument))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
-	// Delete instrument from database within the transaction
-	err = s.repo.DeleteInstrument(ctx, tx, req.Msg.Id)
+	// Log success
+	log.InfoContext(ctx, s.logger, "Instruments batch updated successfully", "updated", len(instruments), "failed", len(failures))
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.BatchUpdateInstrumentsResponse{
+		Instruments: instruments,
+		Errors:      failures,
+	}), nil
+}
+
+// BatchDeleteInstruments moves many instruments to the trash in one transaction
+func (s *InstrumentService) BatchDeleteInstruments(ctx context.Context, req *connect.Request[expensesv1.BatchDeleteInstrumentsRequest]) (*connect.Response[expensesv1.BatchDeleteInstrumentsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Batch deleting instruments", "count", len(req.Msg.Requests), "allow_partial", req.Msg.AllowPartial)
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
 	if err != nil {
-		log.ErrorContext(ctx, s.logger, "Failed to delete instrument", "id", req.Msg.Id, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Move each instrument to the trash within the transaction
+	var ids []string
+	failures, err := runBatch(ctx, s.logger, tx, req.Msg.Requests, req.Msg.AllowPartial, func(msg *expensesv1.DeleteInstrumentRequest) error {
+		if err := s.deleteInstrument(ctx, tx, msg); err != nil {
+			return err
+		}
+		ids = append(ids, msg.Id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to commit transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
 	// Log success
-	log.InfoContext(ctx, s.logger, "Instrument deleted successfully", "id", req.Msg.Id)
+	log.InfoContext(ctx, s.logger, "Instruments batch deleted successfully", "deleted", len(ids), "failed", len(failures))
 
 	// Prepare response
-	return connect.NewResponse(&expensesv1.DeleteInstrumentResponse{
-		Success: true,
+	return connect.NewResponse(&expensesv1.BatchDeleteInstrumentsResponse{
+		Ids:    ids,
+		Errors: failures,
 	}), nil
 }
 
+// createInstrument creates an instrument, recording it in the audit log
+func (s *InstrumentService) createInstrument(ctx context.Context, dbtx db.DBTX, msg *expensesv1.CreateInstrumentRequest) (db.Instrument, error) {
+	instrument, err := s.repo.CreateInstrument(ctx, dbtx, authz.WorkspaceFrom(ctx), msg.Name)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "Instrument already exists", "name", msg.Name)
+			return db.Instrument{}, errors.AlreadyExists("instrument", msg.Name, "name", "instrument with name %s already exists", msg.Name)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to create instrument", "error", err)
+		return db.Instrument{}, storageError(err)
+	}
+
+	// Record the mutation in the audit log
+	if err := s.auditor.record(ctx, dbtx, "instrument", instrument.ID, audit.ActionCreate, nil, toProtoInstrument(instrument)); err != nil {
+		return db.Instrument{}, err
+	}
+	return instrument, nil
+}
+
+// updateInstrument updates the fields of an instrument selected by the update
+// mask, recording the change in the audit log
+func (s *InstrumentService) updateInstrument(ctx context.Context, dbtx db.DBTX, msg *expensesv1.UpdateInstrumentRequest) (db.Instrument, error) {
+	// Validate the fields the update mask selects, which the validation
+	// interceptor cannot tell are required
+	mask, err := parseUpdateMask(ctx, s.logger, msg.UpdateMask, instrumentUpdatePaths)
+	if err != nil {
+		return db.Instrument{}, err
+	}
+	if mask.has("name") && msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateInstrument", "error", "name is required")
+		return db.Instrument{}, errors.Required("name")
+	}
+
+	// Check if instrument exists
+	existing, err := s.repo.GetInstrument(ctx, dbtx, authz.WorkspaceFrom(ctx), msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Instrument not found", "id", msg.Id)
+			return db.Instrument{}, errors.NotFound("instrument", msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to check if instrument exists", "id", msg.Id, "error", err)
+		return db.Instrument{}, storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "instrument", existing.ID, msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return db.Instrument{}, err
+	}
+
+	// Update the masked fields of the instrument
+	instrument, err := s.repo.UpdateInstrument(ctx, dbtx, msg.Id, existing.Revision, mask.pick(map[string]any{
+		"name": msg.Name,
+	}))
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "Instrument with name already exists", "name", msg.Name)
+			return db.Instrument{}, errors.AlreadyExists("instrument", msg.Name, "name", "instrument with name %s already exists", msg.Name)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to update instrument", "id", msg.Id, "error", err)
+		return db.Instrument{}, storageError(err)
+	}
+
+	// Record the mutation in the audit log
+	if err := s.auditor.record(ctx, dbtx, "instrument", instrument.ID, audit.ActionUpdate, toProtoInstrument(existing), toProtoInstrument(instrument)); err != nil {
+		return db.Instrument{}, err
+	}
+	return instrument, nil
+}
+
+// deleteInstrument moves an instrument to the trash, recording it in the
+// audit log
+func (s *InstrumentService) deleteInstrument(ctx context.Context, dbtx db.DBTX, msg *expensesv1.DeleteInstrumentRequest) error {
+	// Check if instrument exists
+	existing, err := s.repo.GetInstrument(ctx, dbtx, authz.WorkspaceFrom(ctx), msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Instrument not found", "id", msg.Id)
+			return errors.NotFound("instrument", msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to check if instrument exists", "id", msg.Id, "error", err)
+		return storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "instrument", existing.ID, msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return err
+	}
+
+	// Move the instrument to the trash
+	if err := s.repo.DeleteInstrument(ctx, dbtx, msg.Id, existing.Revision, s.clock.Now().UTC()); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to delete instrument", "id", msg.Id, "error", err)
+		return storageError(err)
+	}
+
+	// Record the mutation in the audit log
+	return s.auditor.record(ctx, dbtx, "instrument", existing.ID, audit.ActionDelete, toProtoInstrument(existing), nil)
+}
+
 // toProtoInstrument converts a db.Instrument to a expensesv1.Instrument
 func toProtoInstrument(instrument db.Instrument) *expensesv1.Instrument {
 	return &expensesv1.Instrument{
@@ -299,5 +593,7 @@ func toProtoInstrument(instrument db.Instrument) *expensesv1.Instrument {
 		Name:      instrument.Name,
 		CreatedAt: timestamppb.New(instrument.CreatedAt),
 		UpdatedAt: timestamppb.New(instrument.UpdatedAt),
+		Etag:      etag.Compute(instrument.Revision, instrument.UpdatedAt),
+		DeletedAt: timestampOrNil(instrument.DeletedAt),
 	}
 }
@@ -2,10 +2,16 @@ package services
 
 import (
 	"context"
+	"slices"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
 	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 // TestCreateInstrument tests the CreateInstrument RPC method
@@ -14,7 +20,7 @@ func TestCreateInstrument(t *testing.T) {
 	resetTestDB(t)
 
 	// Create a new InstrumentService with the test repositories
-	service := NewInstrumentService(instrumentRepo, testClock, testLogger)
+	service := NewInstrumentService(instrumentRepo, auditRepo, testPages, testClock, testLogger)
 
 	// Define test cases
 	tests := []struct {
@@ -30,14 +36,6 @@ func TestCreateInstrument(t *testing.T) {
 			},
 			expectError: false,
 		},
-		{
-			name: "Missing name",
-			request: &expensesv1.CreateInstrumentRequest{
-				Name: "",
-			},
-			expectError: true,
-			errorMsg:    "name is required",
-		},
 		{
 			name: "Duplicate name",
 			request: &expensesv1.CreateInstrumentRequest{
@@ -89,7 +87,7 @@ func TestGetInstrument(t *testing.T) {
 	resetTestDB(t)
 
 	// Create a new InstrumentService with the test repositories
-	service := NewInstrumentService(instrumentRepo, testClock, testLogger)
+	service := NewInstrumentService(instrumentRepo, auditRepo, testPages, testClock, testLogger)
 
 	// Create a test instrument (using the main DB connection for setup)
 	testInstrument := createTestInstrument(t, testDB, "Bank Account")
@@ -158,12 +156,16 @@ func TestListInstruments(t *testing.T) {
 	resetTestDB(t)
 
 	// Create a new InstrumentService with the test repositories
-	service := NewInstrumentService(instrumentRepo, testClock, testLogger)
+	service := NewInstrumentService(instrumentRepo, auditRepo, testPages, testClock, testLogger)
 
 	// Create test instruments (using the main DB connection for setup)
-	_ = createTestInstrument(t, testDB, "Cash")
+	cash := createTestInstrument(t, testDB, "Cash")
 	_ = createTestInstrument(t, testDB, "Bank Account")
-	_ = createTestInstrument(t, testDB, "Credit Card")
+	creditCard := createTestInstrument(t, testDB, "Credit Card")
+
+	// Page tokens continue after an instrument ordered by name
+	filter := pagination.Filter("instruments", "", "")
+	afterCash := pageTokenAfter(t, filter, cash.Name, cash.ID)
 
 	// Define test cases
 	tests := []struct {
@@ -188,31 +190,55 @@ func TestListInstruments(t *testing.T) {
 		{
 			name:      "List with pagination (page 2)",
 			pageSize:  2,
-			pageToken: "2", // Start from offset 2
-			expectedN: 1,   // Only 1 instrument left
+			pageToken: afterCash,
+			expectedN: 1, // Only 1 instrument left
 		},
 		{
 			name:      "Empty result",
 			pageSize:  2,
-			pageToken: "10", // Start from offset 10 (beyond available data)
+			pageToken: pageTokenAfter(t, filter, creditCard.Name, creditCard.ID),
 			expectedN: 0,
 		},
+		{
+			name:        "Raw offset token",
+			pageSize:    2,
+			pageToken:   "2",
+			expectError: true,
+		},
+		{
+			name:        "Token signed with another secret",
+			pageSize:    2,
+			pageToken:   pageTokenWith(t, "another-secret", time.Hour, filter, cash.Name, cash.ID),
+			expectError: true,
+		},
+		{
+			name:        "Token issued for another list",
+			pageSize:    2,
+			pageToken:   pageTokenAfter(t, pagination.Filter("users", "", ""), cash.Name, cash.ID),
+			expectError: true,
+		},
+		{
+			name:        "Expired token",
+			pageSize:    2,
+			pageToken:   pageTokenWith(t, "test-page-token-secret", -time.Minute, filter, cash.Name, cash.ID),
+			expectError: true,
+		},
 	}
 
 	// Run tests
 	ctx := context.Background()
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			var pagination *expensesv1.Pagination
+			var page *expensesv1.Pagination
 			if tc.pageSize > 0 || tc.pageToken != "" {
-				pagination = &expensesv1.Pagination{
+				page = &expensesv1.Pagination{
 					PageSize:  tc.pageSize,
 					PageToken: tc.pageToken,
 				}
 			}
 
 			req := connect.NewRequest(&expensesv1.ListInstrumentsRequest{
-				Pagination: pagination,
+				Pagination: page,
 			})
 
 			// Read operations can use the main DB connection
@@ -248,7 +274,7 @@ func TestUpdateInstrument(t *testing.T) {
 	resetTestDB(t)
 
 	// Create a new InstrumentService with the test repositories
-	service := NewInstrumentService(instrumentRepo, testClock, testLogger)
+	service := NewInstrumentService(instrumentRepo, auditRepo, testPages, testClock, testLogger)
 
 	// Create test instruments (using the main DB connection for setup)
 	testInstrument := createTestInstrument(t, testDB, "Original Name")
@@ -341,7 +367,7 @@ func TestDeleteInstrument(t *testing.T) {
 	resetTestDB(t)
 
 	// Create a new InstrumentService with the test repositories
-	service := NewInstrumentService(instrumentRepo, testClock, testLogger)
+	service := NewInstrumentService(instrumentRepo, auditRepo, testPages, testClock, testLogger)
 
 	// Create a test instrument (using the main DB connection for setup)
 	testInstrument := createTestInstrument(t, testDB, "Delete Test Instrument")
@@ -403,9 +429,9 @@ func TestDeleteInstrument(t *testing.T) {
 					t.Errorf("Expected success=true, got false")
 				}
 
-				// Verify the instrument was actually deleted
+				// Verify the instrument was moved to the trash
 				var count int
-				countErr := testDB.QueryRow("SELECT COUNT(*) FROM instruments WHERE id = ?", tc.instrumentID).Scan(&count)
+				countErr := testDB.QueryRow(testDB.Rebind("SELECT COUNT(*) FROM instruments WHERE id = ? AND deleted_at IS NULL"), tc.instrumentID).Scan(&count)
 				if countErr != nil {
 					t.Fatalf("Failed to query deleted instrument: %v", countErr)
 				}
@@ -417,3 +443,153 @@ func TestDeleteInstrument(t *testing.T) {
 		})
 	}
 }
+
+// TestBatchInstruments tests the BatchCreateInstruments, BatchUpdateInstruments
+// and BatchDeleteInstruments RPC methods
+func TestBatchInstruments(t *testing.T) {
+	// Create a new InstrumentService with the test repositories
+	service := NewInstrumentService(instrumentRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Define test cases, each against a single existing instrument
+	ctx := context.Background()
+	tests := []struct {
+		name          string
+		call          func(existing db.Instrument) (int, []*expensesv1.BatchError, error)
+		expectCode    connect.Code
+		expectCount   int
+		expectIndexes []int32
+		expectRows    int
+	}{
+		{
+			name: "Create all",
+			call: func(db.Instrument) (int, []*expensesv1.BatchError, error) {
+				resp, err := service.BatchCreateInstruments(ctx, connect.NewRequest(&expensesv1.BatchCreateInstrumentsRequest{
+					Requests: []*expensesv1.CreateInstrumentRequest{{Name: "Cash"}, {Name: "Bank Account"}},
+				}))
+				if err != nil {
+					return 0, nil, err
+				}
+				return len(resp.Msg.Instruments), resp.Msg.Errors, nil
+			},
+			expectCount: 2,
+			expectRows:  3,
+		},
+		{
+			name: "Create with a duplicate rolls back the batch",
+			call: func(existing db.Instrument) (int, []*expensesv1.BatchError, error) {
+				_, err := service.BatchCreateInstruments(ctx, connect.NewRequest(&expensesv1.BatchCreateInstrumentsRequest{
+					Requests: []*expensesv1.CreateInstrumentRequest{{Name: "Cash"}, {Name: existing.Name}},
+				}))
+				return 0, nil, err
+			},
+			expectCode: connect.CodeAlreadyExists,
+			expectRows: 1,
+		},
+		{
+			name: "Create partially",
+			call: func(existing db.Instrument) (int, []*expensesv1.BatchError, error) {
+				resp, err := service.BatchCreateInstruments(ctx, connect.NewRequest(&expensesv1.BatchCreateInstrumentsRequest{
+					Requests:     []*expensesv1.CreateInstrumentRequest{{Name: "Cash"}, {Name: existing.Name}, {}, {Name: "Bank Account"}},
+					AllowPartial: true,
+				}))
+				if err != nil {
+					return 0, nil, err
+				}
+				return len(resp.Msg.Instruments), resp.Msg.Errors, nil
+			},
+			expectCount:   2,
+			expectIndexes: []int32{1, 2},
+			expectRows:    3,
+		},
+		{
+			name: "Update partially",
+			call: func(existing db.Instrument) (int, []*expensesv1.BatchError, error) {
+				mask := &fieldmaskpb.FieldMask{Paths: []string{"name"}}
+				resp, err := service.BatchUpdateInstruments(ctx, connect.NewRequest(&expensesv1.BatchUpdateInstrumentsRequest{
+					Requests: []*expensesv1.UpdateInstrumentRequest{
+						{Id: "ins_nonexistent", Name: "Missing", UpdateMask: mask},
+						{Id: existing.ID, Name: "Renamed", UpdateMask: mask},
+					},
+					AllowPartial: true,
+				}))
+				if err != nil {
+					return 0, nil, err
+				}
+				return len(resp.Msg.Instruments), resp.Msg.Errors, nil
+			},
+			expectCount:   1,
+			expectIndexes: []int32{0},
+			expectRows:    1,
+		},
+		{
+			name: "Delete with a missing instrument rolls back the batch",
+			call: func(existing db.Instrument) (int, []*expensesv1.BatchError, error) {
+				_, err := service.BatchDeleteInstruments(ctx, connect.NewRequest(&expensesv1.BatchDeleteInstrumentsRequest{
+					Requests: []*expensesv1.DeleteInstrumentRequest{{Id: existing.ID}, {Id: "ins_nonexistent"}},
+				}))
+				return 0, nil, err
+			},
+			expectCode: connect.CodeNotFound,
+			expectRows: 1,
+		},
+		{
+			name: "Delete partially",
+			call: func(existing db.Instrument) (int, []*expensesv1.BatchError, error) {
+				resp, err := service.BatchDeleteInstruments(ctx, connect.NewRequest(&expensesv1.BatchDeleteInstrumentsRequest{
+					Requests:     []*expensesv1.DeleteInstrumentRequest{{Id: existing.ID}, {Id: "ins_nonexistent"}},
+					AllowPartial: true,
+				}))
+				if err != nil {
+					return 0, nil, err
+				}
+				return len(resp.Msg.Ids), resp.Msg.Errors, nil
+			},
+			expectCount:   1,
+			expectIndexes: []int32{1},
+			expectRows:    0,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset the test database and create the existing instrument
+			resetTestDB(t)
+			existing := createTestInstrument(t, testDB, "Existing")
+
+			count, failures, err := tc.call(existing)
+
+			if tc.expectCode != 0 {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Fatalf("Expected code %v, got %v", tc.expectCode, err)
+				}
+				if reason := errorReason(t, err); reason != errors.ReasonBatchFailed {
+					t.Errorf("Expected reason %s, got %s", errors.ReasonBatchFailed, reason)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if count != tc.expectCount {
+					t.Errorf("Expected %d successes, got %d", tc.expectCount, count)
+				}
+				indexes := make([]int32, len(failures))
+				for i, failure := range failures {
+					indexes[i] = failure.Index
+				}
+				if !slices.Equal(indexes, tc.expectIndexes) {
+					t.Errorf("Expected failures at %v, got %v", tc.expectIndexes, indexes)
+				}
+			}
+
+			// Verify the instruments left by the batch
+			var rows int
+			if err := testDB.QueryRow("SELECT COUNT(*) FROM instruments WHERE deleted_at IS NULL").Scan(&rows); err != nil {
+				t.Fatalf("Failed to count instruments: %v", err)
+			}
+			if rows != tc.expectRows {
+				t.Errorf("Expected %d instruments, got %d", tc.expectRows, rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/filter"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// parsePage validates the pagination parameters of a List request. The filter
+// hash must match the one the page token was issued for.
+func parsePage(ctx context.Context, logger *slog.Logger, pages *pagination.Codec, p *expensesv1.Pagination, filter string) (pagination.Page, error) {
+	page, err := pages.Parse(p.GetPageSize(), p.GetPageToken(), filter)
+	if err != nil {
+		log.ErrorContext(ctx, logger, "Invalid pagination parameters", "token", p.GetPageToken(), "error", err)
+		return pagination.Page{}, errors.InvalidField("pagination", "%v", err)
+	}
+	page.SkipTotal = p.GetSkipTotalCount()
+
+	log.InfoContext(ctx, logger, "Pagination parameters", "limit", page.Size, "after_id", page.After.ID, "skip_total", page.SkipTotal)
+	return page, nil
+}
+
+// paginate trims a result fetched with one row beyond the page size back to
+// the page, and issues the token for the next page if that extra row exists.
+// count returns the number of rows matching the list's filters; it is not
+// called when the request skipped the total count.
+func paginate[T any](ctx context.Context, logger *slog.Logger, pages *pagination.Codec, page pagination.Page, rows []T, cursor func(T) pagination.Cursor, count func() (int64, error)) ([]T, *expensesv1.PaginationResponse, error) {
+	nextPageToken := ""
+	if int64(len(rows)) > page.Size {
+		rows = rows[:page.Size]
+		token, err := pages.Encode(cursor(rows[len(rows)-1]), page.Filter)
+		if err != nil {
+			log.ErrorContext(ctx, logger, "Failed to issue page token", "error", err)
+			return nil, nil, errors.Internal(err)
+		}
+		nextPageToken = token
+	}
+
+	pageResponse := &expensesv1.PaginationResponse{NextPageToken: nextPageToken}
+	if page.SkipTotal {
+		pageResponse.TotalCountUnavailable = true
+		return rows, pageResponse, nil
+	}
+	total, err := count()
+	if err != nil {
+		log.ErrorContext(ctx, logger, "Failed to count list total", "error", err)
+		return nil, nil, storageError(err)
+	}
+	pageResponse.TotalCount = int32(total)
+	return rows, pageResponse, nil
+}
+
+// parseQuery compiles the filter and order_by of a List request against the
+// allowlist of the listed resource and positions it after the page cursor
+func parseQuery[T any](ctx context.Context, logger *slog.Logger, schema *filter.Schema[T], filterExpr, orderBy string, after pagination.Cursor) (*filter.Query[T], error) {
+	query, err := schema.Compile(filterExpr, orderBy)
+	if err != nil {
+		log.ErrorContext(ctx, logger, "Invalid filter or order_by", "filter", filterExpr, "order_by", orderBy, "error", err)
+		return nil, errors.InvalidField("filter", "%v", err)
+	}
+	if !after.IsZero() {
+		if err := query.After(after.Keys, after.ID); err != nil {
+			log.ErrorContext(ctx, logger, "Invalid page token", "error", err)
+			return nil, errors.InvalidField("pagination.page_token", "%v", pagination.ErrInvalidToken)
+		}
+	}
+	return query, nil
+}
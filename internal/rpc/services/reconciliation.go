@@ -0,0 +1,556 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/money"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// ReconciliationService implements the ReconciliationService interface defined in the proto
+type ReconciliationService struct {
+	expensesv1connect.UnimplementedReconciliationServiceHandler
+	repo            *repo.ReconciliationRepo
+	accountRepo     *repo.AccountRepo
+	categoryRepo    *repo.CategoryRepo
+	transactionRepo *repo.TransactionRepo
+	auditor         auditor
+	pages           *pagination.Codec
+	clock           clock.Clock
+	logger          *slog.Logger
+}
+
+// NewReconciliationService creates a new ReconciliationService
+func NewReconciliationService(
+	repo *repo.ReconciliationRepo,
+	accountRepo *repo.AccountRepo,
+	categoryRepo *repo.CategoryRepo,
+	transactionRepo *repo.TransactionRepo,
+	auditRepo *repo.AuditRepo,
+	pages *pagination.Codec,
+	clock clock.Clock,
+	logger *slog.Logger,
+) *ReconciliationService {
+	return &ReconciliationService{
+		repo:            repo,
+		accountRepo:     accountRepo,
+		categoryRepo:    categoryRepo,
+		transactionRepo: transactionRepo,
+		auditor:         newAuditor(auditRepo, clock, logger),
+		pages:           pages,
+		clock:           clock,
+		logger:          logger,
+	}
+}
+
+// ReconcileCash records a counted cash balance, compares it with the ledger
+// and optionally posts an adjusting transaction for the discrepancy
+func (s *ReconciliationService) ReconcileCash(ctx context.Context, req *connect.Request[expensesv1.ReconcileCashRequest]) (*connect.Response[expensesv1.ReconcileCashResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Reconciling cash", "account_id", req.Msg.AccountId)
+
+	// Validate input
+	if req.Msg.AccountId == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for ReconcileCash", "error", "account_id is required")
+		return nil, errors.Required("account_id")
+	}
+	if req.Msg.Counted == nil {
+		log.ErrorContext(ctx, s.logger, "Invalid input for ReconcileCash", "error", "counted is required")
+		return nil, errors.Required("counted")
+	}
+	now := s.clock.Now().UTC()
+	countedAt := now
+	if req.Msg.CountedAt != nil {
+		countedAt = req.Msg.CountedAt.AsTime().UTC()
+	}
+	if countedAt.After(now) {
+		log.ErrorContext(ctx, s.logger, "Invalid input for ReconcileCash", "error", "counted_at must not be in the future")
+		return nil, errors.InvalidField("counted_at", "counted_at must not be in the future")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Resolve the cash account and the counted amount in its currency
+	account, err := s.getCashAccount(ctx, tx, req.Msg.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	currency := cashAccountCurrency(account)
+	counted, err := s.countedAmount(ctx, req.Msg.Counted, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the adjustment references up front, even if no adjustment is needed
+	var adjustmentAccount db.Account
+	adjustmentCategoryID := nullableString(req.Msg.AdjustmentCategoryId)
+	adjust := nullableString(req.Msg.AdjustmentAccountId) != nil
+	if adjust {
+		adjustmentAccount, err = s.validateAdjustment(ctx, tx, *req.Msg.AdjustmentAccountId, adjustmentCategoryID, account)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Compare the count with the ledger at the same moment
+	ledgerBalance, err := s.repo.GetLedgerBalance(ctx, tx, account.AccountID, account.CurrencyID, countedAt)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to get ledger balance", "account_id", account.AccountID, "error", err)
+		return nil, storageError(err)
+	}
+	discrepancy := counted - ledgerBalance
+
+	// Post the adjusting transaction, dated at the count so later counts see it
+	var adjustment *expensesv1.Transaction
+	var adjustmentID *string
+	if adjust && discrepancy != 0 {
+		allocationTag := account.AccountName
+		if tag := nullableString(req.Msg.AllocationTag); tag != nil {
+			allocationTag = *tag
+		}
+		adjustment, err = s.postAdjustment(ctx, tx, account, adjustmentAccount, adjustmentCategoryID, allocationTag, countedAt, discrepancy)
+		if err != nil {
+			return nil, err
+		}
+		adjustmentID = &adjustment.Id
+	}
+
+	// Record the count
+	reconciliation, err := s.repo.CreateReconciliation(ctx, tx, db.CreateReconciliationParams{
+		WorkspaceID:             authz.WorkspaceFrom(ctx),
+		AccountID:               account.AccountID,
+		CountedAt:               countedAt,
+		CountedAmount:           counted,
+		LedgerBalance:           ledgerBalance,
+		Discrepancy:             discrepancy,
+		CurrencyID:              account.CurrencyID,
+		AdjustmentTransactionID: adjustmentID,
+		Notes:                   nullableString(&req.Msg.Notes),
+	})
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to create reconciliation", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the count, and the adjustment if one was posted, in the audit log
+	// within the transaction
+	if adjustment != nil {
+		if err := s.auditor.record(ctx, tx, "transaction", adjustment.Id, audit.ActionCreate, nil, adjustment); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.auditor.record(ctx, tx, "reconciliation", reconciliation.ID, audit.ActionCreate, nil, toProtoReconciliation(reconciliation, currency)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Cash reconciled successfully", "id", reconciliation.ID, "discrepancy", discrepancy, "adjusted", adjustment != nil)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.ReconcileCashResponse{
+		Reconciliation: toProtoReconciliation(reconciliation, currency),
+		Adjustment:     adjustment,
+	}), nil
+}
+
+// GetReconciliation retrieves a reconciliation by ID
+func (s *ReconciliationService) GetReconciliation(ctx context.Context, req *connect.Request[expensesv1.GetReconciliationRequest]) (*connect.Response[expensesv1.GetReconciliationResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Getting reconciliation", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for GetReconciliation", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Get reconciliation from database (read operations can use the main DB connection)
+	row, err := s.repo.GetReconciliation(ctx, s.repo.GetDB(), authz.WorkspaceFrom(ctx), req.Msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Reconciliation not found", "id", req.Msg.Id)
+			return nil, errors.NotFound("reconciliation", req.Msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get reconciliation", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Reconciliation retrieved successfully", "id", row.Reconciliation.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.GetReconciliationResponse{
+		Reconciliation: toProtoReconciliation(row.Reconciliation, reconciliationRowCurrency(row)),
+	}), nil
+}
+
+// ListReconciliations retrieves a paginated list of reconciliations, newest first
+func (s *ReconciliationService) ListReconciliations(ctx context.Context, req *connect.Request[expensesv1.ListReconciliationsRequest]) (*connect.Response[expensesv1.ListReconciliationsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing reconciliations", "account_id", req.Msg.GetAccountId(), "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("reconciliations", req.Msg.GetAccountId(), req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering within the caller's workspace, narrowed
+	// to the requested account
+	query, err := parseQuery(ctx, s.logger, repo.ReconciliationFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("reconciliations", authz.WorkspaceFrom(ctx)))
+	if accountID := nullableString(req.Msg.AccountId); accountID != nil {
+		if err := query.Equal("account_id", *accountID); err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to restrict reconciliations by account", "error", err)
+			return nil, errors.Internal(err)
+		}
+	}
+	if subject, ok := authz.SubjectFrom(ctx); ok {
+		query.Where(repo.ReconciliationsOfMember(subject.UserID))
+	}
+
+	// Get reconciliations from database (read operations can use the main DB connection)
+	rows, err := s.repo.ListReconciliations(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list reconciliations", "error", err)
+		return nil, storageError(err)
+	}
+
+	rows, pageResponse, err := paginate(ctx, s.logger, s.pages, page, rows, func(row db.GetReconciliationRow) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.Reconciliation.ID}
+	}, func() (int64, error) {
+		return s.repo.CountReconciliations(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to proto messages
+	protoReconciliations := make([]*expensesv1.Reconciliation, len(rows))
+	for i, row := range rows {
+		protoReconciliations[i] = toProtoReconciliation(row.Reconciliation, reconciliationRowCurrency(row))
+	}
+
+	log.InfoContext(ctx, s.logger, "Reconciliations retrieved successfully", "count", len(rows))
+
+	return connect.NewResponse(&expensesv1.ListReconciliationsResponse{
+		Reconciliations:    protoReconciliations,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// ListUnreconciledPeriods lists, per cash account, the counts that left an
+// unadjusted discrepancy and the activity since the last count
+func (s *ReconciliationService) ListUnreconciledPeriods(ctx context.Context, req *connect.Request[expensesv1.ListUnreconciledPeriodsRequest]) (*connect.Response[expensesv1.ListUnreconciledPeriodsResponse], error) {
+	asOf := s.clock.Now().UTC()
+	if req.Msg.AsOf != nil {
+		asOf = req.Msg.AsOf.AsTime().UTC()
+	}
+
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing unreconciled periods", "account_id", req.Msg.GetAccountId(), "as_of", asOf)
+
+	// Resolve the cash accounts to inspect (read operations can use the main DB connection)
+	dbtx := s.repo.GetDB()
+	var accounts []db.ListCashAccountsRow
+	if accountID := nullableString(req.Msg.AccountId); accountID != nil {
+		account, err := s.getCashAccount(ctx, dbtx, *accountID)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	} else {
+		var err error
+		accounts, err = s.repo.ListCashAccounts(ctx, dbtx, authz.WorkspaceFrom(ctx), nil)
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to list cash accounts", "error", err)
+			return nil, storageError(err)
+		}
+
+		// Inspect only the accounts the caller belongs to
+		if subject, ok := authz.SubjectFrom(ctx); ok {
+			accounts = slices.DeleteFunc(accounts, func(account db.ListCashAccountsRow) bool {
+				return !subject.Member(account.AccountID)
+			})
+		}
+	}
+
+	var periods []*expensesv1.UnreconciledPeriod
+	for _, account := range accounts {
+		accountPeriods, err := s.unreconciledPeriods(ctx, dbtx, account, asOf)
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to compute unreconciled periods", "account_id", account.AccountID, "error", err)
+			return nil, storageError(err)
+		}
+		periods = append(periods, accountPeriods...)
+	}
+
+	log.InfoContext(ctx, s.logger, "Unreconciled periods retrieved successfully", "accounts", len(accounts), "count", len(periods))
+
+	return connect.NewResponse(&expensesv1.ListUnreconciledPeriodsResponse{
+		Periods: periods,
+	}), nil
+}
+
+// unreconciledPeriods walks the counts of a cash account in order. Each count
+// closes the period since the previous one; a count with an unadjusted
+// discrepancy leaves its period unreconciled, as does any activity after the
+// last count.
+func (s *ReconciliationService) unreconciledPeriods(ctx context.Context, dbtx db.DBTX, account db.ListCashAccountsRow, asOf time.Time) ([]*expensesv1.UnreconciledPeriod, error) {
+	reconciliations, err := s.repo.ListAccountReconciliations(ctx, dbtx, account.AccountID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	currency := cashAccountCurrency(account)
+
+	var periods []*expensesv1.UnreconciledPeriod
+	var start time.Time // zero until the first count
+	for _, reconciliation := range reconciliations {
+		if reconciliation.Discrepancy != 0 && reconciliation.AdjustmentTransactionID == nil {
+			count, err := s.repo.CountEntriesBetween(ctx, dbtx, account.AccountID, start, reconciliation.CountedAt)
+			if err != nil {
+				return nil, err
+			}
+			id := reconciliation.ID
+			periods = append(periods, &expensesv1.UnreconciledPeriod{
+				AccountId:        account.AccountID,
+				AccountName:      account.AccountName,
+				Start:            periodStart(start),
+				End:              timestamppb.New(reconciliation.CountedAt),
+				Reason:           expensesv1.UnreconciledReason_UNRECONCILED_REASON_DISCREPANCY,
+				EntryCount:       count,
+				LedgerBalance:    currency.money(reconciliation.LedgerBalance),
+				ReconciliationId: &id,
+				Discrepancy:      currency.money(reconciliation.Discrepancy),
+			})
+		}
+		start = reconciliation.CountedAt
+	}
+
+	// Activity after the last count has not been counted yet
+	count, err := s.repo.CountEntriesBetween(ctx, dbtx, account.AccountID, start, asOf)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		balance, err := s.repo.GetLedgerBalance(ctx, dbtx, account.AccountID, account.CurrencyID, asOf)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, &expensesv1.UnreconciledPeriod{
+			AccountId:     account.AccountID,
+			AccountName:   account.AccountName,
+			Start:         periodStart(start),
+			End:           timestamppb.New(asOf),
+			Reason:        expensesv1.UnreconciledReason_UNRECONCILED_REASON_UNCOUNTED,
+			EntryCount:    count,
+			LedgerBalance: currency.money(balance),
+		})
+	}
+	return periods, nil
+}
+
+// getCashAccount retrieves a cash account, distinguishing unknown accounts
+// from accounts that cannot hold counted cash
+func (s *ReconciliationService) getCashAccount(ctx context.Context, dbtx db.DBTX, id string) (db.ListCashAccountsRow, error) {
+	accounts, err := s.repo.ListCashAccounts(ctx, dbtx, authz.WorkspaceFrom(ctx), &id)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to get cash account", "id", id, "error", err)
+		return db.ListCashAccountsRow{}, storageError(err)
+	}
+	if len(accounts) == 1 {
+		return accounts[0], nil
+	}
+
+	if _, err := s.accountRepo.GetAccount(ctx, dbtx, authz.WorkspaceFrom(ctx), id); err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Account not found", "id", id)
+			return db.ListCashAccountsRow{}, errors.NotFound("account", id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get account", "id", id, "error", err)
+		return db.ListCashAccountsRow{}, storageError(err)
+	}
+	log.ErrorContext(ctx, s.logger, "Account is not a cash account", "id", id)
+	return db.ListCashAccountsRow{}, errors.InvalidField("account_id", "account %s is not a cash account (an Asset account with a default currency)", id)
+}
+
+// countedAmount converts the counted Money into minor units of the cash
+// account's currency
+func (s *ReconciliationService) countedAmount(ctx context.Context, counted *expensesv1.Money, currency reportCurrency) (int64, error) {
+	invalid := func(msg string) error {
+		log.ErrorContext(ctx, s.logger, "Invalid input for ReconcileCash", "error", msg)
+		return errors.InvalidField("counted", "%s", msg)
+	}
+
+	if counted.Currency != "" && counted.Currency != currency.code {
+		return 0, invalid(fmt.Sprintf("counted currency %s does not match account currency %s", counted.Currency, currency.code))
+	}
+	amount := counted.Amount
+	if counted.Value != "" {
+		parsed, err := money.ParseDecimal(counted.Value, int(currency.minorUnits))
+		if err != nil {
+			return 0, invalid(fmt.Sprintf("counted: %v (%s has %d decimal places)", err, currency.code, currency.minorUnits))
+		}
+		if amount != 0 && amount != parsed {
+			return 0, invalid(fmt.Sprintf("counted amount %d does not match value %s", amount, counted.Value))
+		}
+		amount = parsed
+	}
+	if amount < 0 {
+		return 0, invalid("counted amount must not be negative")
+	}
+	return amount, nil
+}
+
+// validateAdjustment checks that adjustments go to an existing Equity account
+// in the cash account's currency, under an existing category
+func (s *ReconciliationService) validateAdjustment(ctx context.Context, dbtx db.DBTX, accountID string, categoryID *string, cash db.ListCashAccountsRow) (db.Account, error) {
+	account, err := s.accountRepo.GetAccount(ctx, dbtx, authz.WorkspaceFrom(ctx), accountID)
+	if err != nil {
+		return db.Account{}, referenceError(ctx, s.logger, "adjustment_account_id", accountID, err)
+	}
+	accountType, err := s.accountRepo.GetAccountType(ctx, dbtx, account.AccountTypeID)
+	if err != nil {
+		return db.Account{}, referenceError(ctx, s.logger, "account_type_id", account.AccountTypeID, err)
+	}
+	if accountType.Code != "E" {
+		log.ErrorContext(ctx, s.logger, "Adjustment account is not an Equity account", "id", accountID, "type", accountType.Code)
+		return db.Account{}, errors.InvalidField("adjustment_account_id", "adjustment account %s must be an Equity account", accountID)
+	}
+	if account.CurrencyID != nil && *account.CurrencyID != cash.CurrencyID {
+		log.ErrorContext(ctx, s.logger, "Adjustment account currency mismatch", "id", accountID)
+		return db.Account{}, errors.InvalidField("adjustment_account_id", "adjustment account %s does not hold %s", accountID, cash.CurrencyCode)
+	}
+	if categoryID != nil {
+		if _, err := s.categoryRepo.GetCategory(ctx, dbtx, authz.WorkspaceFrom(ctx), *categoryID); err != nil {
+			return db.Account{}, referenceError(ctx, s.logger, "adjustment_category_id", *categoryID, err)
+		}
+	}
+	return account, nil
+}
+
+// postAdjustment posts a balanced transaction that moves the cash account to
+// the counted balance, with the Equity account taking the other side
+func (s *ReconciliationService) postAdjustment(ctx context.Context, dbtx db.DBTX, cash db.ListCashAccountsRow, equity db.Account, categoryID *string, allocationTag string, date time.Time, discrepancy int64) (*expensesv1.Transaction, error) {
+	transaction, err := s.transactionRepo.CreateTransaction(ctx, dbtx, db.CreateTransactionParams{
+		WorkspaceID:   authz.WorkspaceFrom(ctx),
+		Date:          date,
+		Description:   fmt.Sprintf("Cash reconciliation of %s", cash.AccountName),
+		CategoryID:    categoryID,
+		AllocationTag: &allocationTag,
+	})
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to create adjusting transaction", "error", err)
+		return nil, storageError(err)
+	}
+
+	// A surplus debits cash; a shortfall credits it
+	cashEntry := db.CreateLedgerEntryParams{
+		TransactionID: transaction.ID,
+		AccountID:     cash.AccountID,
+		Memo:          "Counted cash adjustment",
+		CurrencyID:    &cash.CurrencyID,
+	}
+	equityEntry := db.CreateLedgerEntryParams{
+		TransactionID: transaction.ID,
+		AccountID:     equity.ID,
+		CategoryID:    categoryID,
+		Memo:          "Counted cash adjustment",
+		CurrencyID:    &cash.CurrencyID,
+	}
+	if discrepancy > 0 {
+		cashEntry.Debit, equityEntry.Credit = discrepancy, discrepancy
+	} else {
+		cashEntry.Credit, equityEntry.Debit = -discrepancy, -discrepancy
+	}
+	for _, entry := range []db.CreateLedgerEntryParams{cashEntry, equityEntry} {
+		if _, err := s.transactionRepo.CreateLedgerEntry(ctx, dbtx, entry); err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to create ledger entry", "transaction_id", transaction.ID, "error", err)
+			return nil, storageError(err)
+		}
+	}
+
+	// Read back the posted entries
+	rows, err := s.transactionRepo.ListLedgerEntries(ctx, dbtx, []string{transaction.ID})
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list ledger entries", "error", err)
+		return nil, storageError(err)
+	}
+	entries := make([]*expensesv1.LedgerEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = toProtoLedgerEntry(row)
+	}
+	return toProtoTransaction(transaction, entries), nil
+}
+
+// periodStart converts the start of a period, leaving it unset for periods
+// that begin with the account's first entry
+func periodStart(start time.Time) *timestamppb.Timestamp {
+	if start.IsZero() {
+		return nil
+	}
+	return timestamppb.New(start)
+}
+
+// cashAccountCurrency extracts the currency metadata of a cash account
+func cashAccountCurrency(account db.ListCashAccountsRow) reportCurrency {
+	return reportCurrency{code: account.CurrencyCode, minorUnits: account.CurrencyMinorUnits, symbol: account.CurrencySymbol}
+}
+
+// reconciliationRowCurrency extracts the currency metadata of a reconciliation row
+func reconciliationRowCurrency(row db.GetReconciliationRow) reportCurrency {
+	return reportCurrency{code: row.CurrencyCode, minorUnits: row.CurrencyMinorUnits, symbol: row.CurrencySymbol}
+}
+
+// toProtoReconciliation converts a db.Reconciliation to a expensesv1.Reconciliation
+func toProtoReconciliation(reconciliation db.Reconciliation, currency reportCurrency) *expensesv1.Reconciliation {
+	return &expensesv1.Reconciliation{
+		Id:                      reconciliation.ID,
+		AccountId:               reconciliation.AccountID,
+		CountedAt:               timestamppb.New(reconciliation.CountedAt),
+		Counted:                 currency.money(reconciliation.CountedAmount),
+		LedgerBalance:           currency.money(reconciliation.LedgerBalance),
+		Discrepancy:             currency.money(reconciliation.Discrepancy),
+		AdjustmentTransactionId: reconciliation.AdjustmentTransactionID,
+		Notes:                   stringValue(reconciliation.Notes),
+		CreatedAt:               timestamppb.New(reconciliation.CreatedAt),
+		UpdatedAt:               timestamppb.New(reconciliation.UpdatedAt),
+	}
+}
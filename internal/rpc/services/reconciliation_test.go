@@ -0,0 +1,442 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// newTestReconciliationService creates a ReconciliationService wired to the test repositories
+func newTestReconciliationService() *ReconciliationService {
+	return NewReconciliationService(reconciliationRepo, accountRepo, categoryRepo, transactionRepo, auditRepo, testPages, testClock, testLogger)
+}
+
+// setupReconciliationFixture creates the transaction fixture and leaves the
+// JPY cash account with a ledger balance of 8800 from 2025-04-10 onwards
+func setupReconciliationFixture(t *testing.T) transactionFixture {
+	t.Helper()
+
+	fx := setupTransactionFixture(t)
+	postTestTransaction(t, reportDate(1), debitLine(fx.cash.ID, 10000, "JPY"), creditLine(fx.earnings.ID, 10000, "JPY"))
+	postTestTransaction(t, reportDate(10), debitLine(fx.earnings.ID, 1200, "JPY"), creditLine(fx.cash.ID, 1200, "JPY"))
+	return fx
+}
+
+// postTestTransaction posts a transaction through the TransactionService and returns its ID
+func postTestTransaction(t *testing.T, date *timestamppb.Timestamp, lines ...*expensesv1.LedgerLine) string {
+	t.Helper()
+
+	resp, err := newTestTransactionService().CreateTransaction(context.Background(), connect.NewRequest(&expensesv1.CreateTransactionRequest{
+		Date:        date,
+		Description: "Test transaction",
+		Lines:       lines,
+	}))
+	if err != nil {
+		t.Fatalf("Failed to post transaction: %v", err)
+	}
+	return resp.Msg.Transaction.Id
+}
+
+// TestReconcileCash tests the ReconcileCash RPC method
+func TestReconcileCash(t *testing.T) {
+	// Create a new ReconciliationService with the test repositories
+	service := newTestReconciliationService()
+
+	foodCategory := "cat_food"
+	missing := "missing"
+	customTag := "Wallet envelope"
+
+	// Define test cases
+	tests := []struct {
+		name              string
+		request           func(fx transactionFixture) *expensesv1.ReconcileCashRequest
+		expectError       bool
+		expectCode        connect.Code
+		expectLedger      int64
+		expectDiscrepancy int64
+		expectTag         string // allocation tag of the adjustment, empty for none
+		expectCashDebit   int64
+		expectCashCredit  int64
+	}{
+		{
+			name: "Count matches the ledger",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{
+					AccountId:           fx.cash.ID,
+					CountedAt:           reportDate(12),
+					Counted:             &expensesv1.Money{Amount: 8800},
+					AdjustmentAccountId: &fx.earnings.ID,
+				}
+			},
+			expectLedger: 8800,
+		},
+		{
+			name: "Shortfall with adjustment tagged with the account name",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{
+					AccountId:            fx.cash.ID,
+					CountedAt:            reportDate(12),
+					Counted:              &expensesv1.Money{Value: "8500", Currency: "JPY"},
+					AdjustmentAccountId:  &fx.earnings.ID,
+					AdjustmentCategoryId: &foodCategory,
+				}
+			},
+			expectLedger:      8800,
+			expectDiscrepancy: -300,
+			expectTag:         "Cash",
+			expectCashCredit:  300,
+		},
+		{
+			name: "Surplus with a custom allocation tag",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{
+					AccountId:           fx.cash.ID,
+					Counted:             &expensesv1.Money{Amount: 9000},
+					AdjustmentAccountId: &fx.earnings.ID,
+					AllocationTag:       &customTag,
+				}
+			},
+			expectLedger:      8800,
+			expectDiscrepancy: 200,
+			expectTag:         customTag,
+			expectCashDebit:   200,
+		},
+		{
+			name: "Discrepancy without adjustment",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{
+					AccountId: fx.cash.ID,
+					CountedAt: reportDate(5),
+					Counted:   &expensesv1.Money{Amount: 9500},
+				}
+			},
+			expectLedger:      10000,
+			expectDiscrepancy: -500,
+		},
+		{
+			name: "Missing counted amount",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{AccountId: fx.cash.ID}
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Count in the future",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{
+					AccountId: fx.cash.ID,
+					CountedAt: timestamppb.New(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)),
+					Counted:   &expensesv1.Money{Amount: 8800},
+				}
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Unknown account",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{AccountId: missing, Counted: &expensesv1.Money{Amount: 1}}
+			},
+			expectError: true,
+			expectCode:  connect.CodeNotFound,
+		},
+		{
+			name: "Liability account is not a cash account",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{AccountId: fx.card.ID, Counted: &expensesv1.Money{Amount: 1}}
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Counted currency differs from the account",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{AccountId: fx.cash.ID, Counted: &expensesv1.Money{Amount: 1, Currency: "USD"}}
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Fractional yen",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{AccountId: fx.cash.ID, Counted: &expensesv1.Money{Value: "10.5"}}
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Negative count",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{AccountId: fx.cash.ID, Counted: &expensesv1.Money{Amount: -1}}
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Adjustment account must be Equity",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{AccountId: fx.cash.ID, Counted: &expensesv1.Money{Amount: 1}, AdjustmentAccountId: &fx.card.ID}
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Unknown adjustment category",
+			request: func(fx transactionFixture) *expensesv1.ReconcileCashRequest {
+				return &expensesv1.ReconcileCashRequest{AccountId: fx.cash.ID, Counted: &expensesv1.Money{Amount: 1}, AdjustmentAccountId: &fx.earnings.ID, AdjustmentCategoryId: &missing}
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fx := setupReconciliationFixture(t)
+
+			resp, err := service.ReconcileCash(context.Background(), connect.NewRequest(tc.request(fx)))
+
+			// Check error
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected error, got nil")
+				}
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			reconciliation := resp.Msg.Reconciliation
+			if reconciliation.LedgerBalance.Amount != tc.expectLedger {
+				t.Errorf("Expected ledger balance %d, got %d", tc.expectLedger, reconciliation.LedgerBalance.Amount)
+			}
+			if reconciliation.Discrepancy.Amount != tc.expectDiscrepancy {
+				t.Errorf("Expected discrepancy %d, got %d", tc.expectDiscrepancy, reconciliation.Discrepancy.Amount)
+			}
+
+			// Check the adjusting transaction
+			adjustment := resp.Msg.Adjustment
+			if tc.expectTag == "" {
+				if adjustment != nil || reconciliation.AdjustmentTransactionId != nil {
+					t.Fatalf("Expected no adjustment, got %v", adjustment)
+				}
+				return
+			}
+			if adjustment == nil || reconciliation.GetAdjustmentTransactionId() != adjustment.Id {
+				t.Fatalf("Expected an adjustment linked to the reconciliation, got %v", adjustment)
+			}
+			if adjustment.GetAllocationTag() != tc.expectTag {
+				t.Errorf("Expected allocation tag %q, got %q", tc.expectTag, adjustment.GetAllocationTag())
+			}
+			if !adjustment.Date.AsTime().Equal(reconciliation.CountedAt.AsTime()) {
+				t.Errorf("Expected the adjustment dated at the count, got %v", adjustment.Date.AsTime())
+			}
+			for _, entry := range adjustment.LedgerEntries {
+				if entry.AccountId != fx.cash.ID {
+					continue
+				}
+				if entry.Debit.Amount != tc.expectCashDebit || entry.Credit.Amount != tc.expectCashCredit {
+					t.Errorf("Expected cash debit %d and credit %d, got %d and %d", tc.expectCashDebit, tc.expectCashCredit, entry.Debit.Amount, entry.Credit.Amount)
+				}
+			}
+
+			// The ledger now agrees with the count
+			balance, err := reconciliationRepo.GetLedgerBalance(context.Background(), testDB, fx.cash.ID, "cur_jpy", reconciliation.CountedAt.AsTime())
+			if err != nil {
+				t.Fatalf("Failed to get ledger balance: %v", err)
+			}
+			if balance != reconciliation.Counted.Amount {
+				t.Errorf("Expected ledger balance %d after adjustment, got %d", reconciliation.Counted.Amount, balance)
+			}
+		})
+	}
+}
+
+// TestListUnreconciledPeriods tests the ListUnreconciledPeriods RPC method
+// as counts and transactions accumulate
+func TestListUnreconciledPeriods(t *testing.T) {
+	fx := setupReconciliationFixture(t)
+
+	// Create a new ReconciliationService with the test repositories
+	service := newTestReconciliationService()
+
+	ctx := context.Background()
+
+	// listPeriods returns the unreconciled periods of the cash account
+	listPeriods := func(t *testing.T) []*expensesv1.UnreconciledPeriod {
+		t.Helper()
+		resp, err := service.ListUnreconciledPeriods(ctx, connect.NewRequest(&expensesv1.ListUnreconciledPeriodsRequest{}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, period := range resp.Msg.Periods {
+			if period.AccountId != fx.cash.ID {
+				t.Errorf("Expected periods for Cash only, got %s", period.AccountName)
+			}
+		}
+		return resp.Msg.Periods
+	}
+	reconcile := func(t *testing.T, req *expensesv1.ReconcileCashRequest) *expensesv1.ReconcileCashResponse {
+		t.Helper()
+		resp, err := service.ReconcileCash(ctx, connect.NewRequest(req))
+		if err != nil {
+			t.Fatalf("Failed to reconcile: %v", err)
+		}
+		return resp.Msg
+	}
+
+	t.Run("Never counted", func(t *testing.T) {
+		periods := listPeriods(t)
+		if len(periods) != 1 {
+			t.Fatalf("Expected 1 period, got %v", periods)
+		}
+		period := periods[0]
+		if period.Reason != expensesv1.UnreconciledReason_UNRECONCILED_REASON_UNCOUNTED || period.Start != nil || period.EntryCount != 2 || period.LedgerBalance.Amount != 8800 {
+			t.Errorf("Expected an uncounted period of 2 entries from the start, got %v", period)
+		}
+	})
+
+	var shortfallID string
+	t.Run("Count with an unadjusted shortfall", func(t *testing.T) {
+		shortfallID = reconcile(t, &expensesv1.ReconcileCashRequest{
+			AccountId: fx.cash.ID,
+			CountedAt: reportDate(12),
+			Counted:   &expensesv1.Money{Amount: 8500},
+		}).Reconciliation.Id
+
+		periods := listPeriods(t)
+		if len(periods) != 1 {
+			t.Fatalf("Expected 1 period, got %v", periods)
+		}
+		period := periods[0]
+		if period.Reason != expensesv1.UnreconciledReason_UNRECONCILED_REASON_DISCREPANCY || period.GetReconciliationId() != shortfallID || period.Discrepancy.Amount != -300 {
+			t.Errorf("Expected the shortfall of -300, got %v", period)
+		}
+	})
+
+	t.Run("Activity after the count", func(t *testing.T) {
+		postTestTransaction(t, reportDate(20), debitLine(fx.earnings.ID, 800, "JPY"), creditLine(fx.cash.ID, 800, "JPY"))
+
+		periods := listPeriods(t)
+		if len(periods) != 2 {
+			t.Fatalf("Expected 2 periods, got %v", periods)
+		}
+		period := periods[1]
+		if period.Reason != expensesv1.UnreconciledReason_UNRECONCILED_REASON_UNCOUNTED || !period.Start.AsTime().Equal(reportDate(12).AsTime()) || period.EntryCount != 1 {
+			t.Errorf("Expected an uncounted period of 1 entry since the count, got %v", period)
+		}
+	})
+
+	var adjustmentID string
+	t.Run("Adjusted count closes the trailing period", func(t *testing.T) {
+		adjustment := reconcile(t, &expensesv1.ReconcileCashRequest{
+			AccountId:           fx.cash.ID,
+			CountedAt:           reportDate(21),
+			Counted:             &expensesv1.Money{Amount: 7900},
+			AdjustmentAccountId: &fx.earnings.ID,
+		}).Adjustment
+		if adjustment == nil {
+			t.Fatalf("Expected an adjustment")
+		}
+		adjustmentID = adjustment.Id
+
+		periods := listPeriods(t)
+		if len(periods) != 1 || periods[0].GetReconciliationId() != shortfallID {
+			t.Errorf("Expected only the earlier shortfall, got %v", periods)
+		}
+	})
+
+	t.Run("Deleting the adjustment reopens the count", func(t *testing.T) {
+		_, err := newTestTransactionService().DeleteTransaction(ctx, connect.NewRequest(&expensesv1.DeleteTransactionRequest{Id: adjustmentID}))
+		if err != nil {
+			t.Fatalf("Failed to delete adjustment: %v", err)
+		}
+
+		periods := listPeriods(t)
+		if len(periods) != 2 || periods[1].Reason != expensesv1.UnreconciledReason_UNRECONCILED_REASON_DISCREPANCY || periods[1].Discrepancy.Amount != -100 {
+			t.Errorf("Expected the adjusted count to reappear with -100, got %v", periods)
+		}
+	})
+
+	t.Run("Unknown account", func(t *testing.T) {
+		missing := "missing"
+		_, err := service.ListUnreconciledPeriods(ctx, connect.NewRequest(&expensesv1.ListUnreconciledPeriodsRequest{AccountId: &missing}))
+		if connect.CodeOf(err) != connect.CodeNotFound {
+			t.Errorf("Expected code %v, got %v", connect.CodeNotFound, connect.CodeOf(err))
+		}
+	})
+}
+
+// TestListReconciliations tests the GetReconciliation and ListReconciliations RPC methods
+func TestListReconciliations(t *testing.T) {
+	fx := setupReconciliationFixture(t)
+	jpy := "cur_jpy"
+	pettyCash := createTestAccount(t, testDB, "Petty Cash", "at_asset", &jpy)
+
+	// Create a new ReconciliationService with the test repositories
+	service := newTestReconciliationService()
+
+	ctx := context.Background()
+	for _, count := range []struct {
+		accountID string
+		day       int
+	}{{fx.cash.ID, 5}, {fx.cash.ID, 12}, {pettyCash.ID, 12}} {
+		_, err := service.ReconcileCash(ctx, connect.NewRequest(&expensesv1.ReconcileCashRequest{
+			AccountId: count.accountID,
+			CountedAt: reportDate(count.day),
+			Counted:   &expensesv1.Money{Value: "8800"},
+			Notes:     "Drawer count",
+		}))
+		if err != nil {
+			t.Fatalf("Failed to reconcile: %v", err)
+		}
+	}
+
+	resp, err := service.ListReconciliations(ctx, connect.NewRequest(&expensesv1.ListReconciliationsRequest{AccountId: &fx.cash.ID}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reconciliations := resp.Msg.Reconciliations
+	if len(reconciliations) != 2 || !reconciliations[0].CountedAt.AsTime().Equal(reportDate(12).AsTime()) {
+		t.Fatalf("Expected 2 reconciliations newest first, got %v", reconciliations)
+	}
+	if reconciliations[1].Discrepancy.Amount != -1200 || reconciliations[1].Discrepancy.Currency != "JPY" {
+		t.Errorf("Expected a discrepancy of -1200 JPY on 2025-04-05, got %v", reconciliations[1].Discrepancy)
+	}
+
+	// The account and filter narrow the list together, and the total follows them
+	resp, err = service.ListReconciliations(ctx, connect.NewRequest(&expensesv1.ListReconciliationsRequest{
+		AccountId: &fx.cash.ID,
+		Filter:    `discrepancy.amount < 0 AND counted_at < "2025-04-10"`,
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.Msg.Reconciliations) != 1 || resp.Msg.Reconciliations[0].Id != reconciliations[1].Id || resp.Msg.PaginationResponse.TotalCount != 1 {
+		t.Errorf("Expected only the 2025-04-05 count with a shortfall, got %v", resp.Msg.Reconciliations)
+	}
+
+	got, err := service.GetReconciliation(ctx, connect.NewRequest(&expensesv1.GetReconciliationRequest{Id: reconciliations[0].Id}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.Msg.Reconciliation.Notes != "Drawer count" || got.Msg.Reconciliation.Counted.Amount != 8800 {
+		t.Errorf("Expected the drawer count of 8800, got %v", got.Msg.Reconciliation)
+	}
+
+	_, err = service.GetReconciliation(ctx, connect.NewRequest(&expensesv1.GetReconciliationRequest{Id: "rec_missing"}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("Expected code %v, got %v", connect.CodeNotFound, connect.CodeOf(err))
+	}
+}
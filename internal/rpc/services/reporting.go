@@ -0,0 +1,422 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/money"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// uncategorizedName labels Equity entries that carry no category
+const uncategorizedName = "Uncategorized"
+
+// ReportingService implements the ReportingService interface defined in the proto
+type ReportingService struct {
+	expensesv1connect.UnimplementedReportingServiceHandler
+	repo   *repo.ReportingRepo
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewReportingService creates a new ReportingService
+func NewReportingService(repo *repo.ReportingRepo, clock clock.Clock, logger *slog.Logger) *ReportingService {
+	return &ReportingService{
+		repo:   repo,
+		clock:  clock,
+		logger: logger,
+	}
+}
+
+// reportCurrency carries the display metadata of a currency in a report
+type reportCurrency struct {
+	code       string
+	minorUnits int64
+	symbol     string
+}
+
+// money converts an amount in minor units of the currency to a expensesv1.Money
+func (c reportCurrency) money(amount int64) *expensesv1.Money {
+	return toProtoMoney(amount, c.code, c.minorUnits, c.symbol)
+}
+
+// GetTrialBalance lists the debit and credit totals of every account on a date
+// and proves that they balance per currency
+func (s *ReportingService) GetTrialBalance(ctx context.Context, req *connect.Request[expensesv1.GetTrialBalanceRequest]) (*connect.Response[expensesv1.GetTrialBalanceResponse], error) {
+	asOf := s.reportTime(req.Msg.AsOf)
+
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Getting trial balance", "as_of", asOf)
+
+	// Aggregate ledger entries (read operations can use the main DB connection)
+	rows, err := s.repo.ListLedgerBalances(ctx, s.repo.GetDB(), authz.WorkspaceFrom(ctx), reportMember(ctx), time.Time{}, asOf)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list ledger balances", "error", err)
+		return nil, storageError(err)
+	}
+	unbalancedRows, err := s.repo.ListUnbalancedTransactions(ctx, s.repo.GetDB(), authz.WorkspaceFrom(ctx), reportMember(ctx), asOf)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list unbalanced transactions", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Merge the category splits of each account, keeping the query's order
+	type lineKey struct{ accountID, currency string }
+	type lineTotal struct {
+		row           db.ListLedgerBalancesRow
+		debit, credit int64
+	}
+	var keys []lineKey
+	lines := make(map[lineKey]*lineTotal)
+	for _, row := range rows {
+		key := lineKey{row.AccountID, row.CurrencyCode}
+		line, ok := lines[key]
+		if !ok {
+			line = &lineTotal{row: row}
+			lines[key] = line
+			keys = append(keys, key)
+		}
+		if line.debit, err = money.Add(line.debit, row.TotalDebit); err != nil {
+			return nil, s.rangeError(ctx, err)
+		}
+		if line.credit, err = money.Add(line.credit, row.TotalCredit); err != nil {
+			return nil, s.rangeError(ctx, err)
+		}
+	}
+
+	totals := newCurrencyTotals(2) // debit, credit
+	resp := &expensesv1.GetTrialBalanceResponse{
+		AsOf:     timestamppb.New(asOf),
+		Balanced: true,
+	}
+	for _, key := range keys {
+		line := lines[key]
+		currency := ledgerBalanceCurrency(line.row)
+		if err := totals.add(currency, line.debit, line.credit); err != nil {
+			return nil, s.rangeError(ctx, err)
+		}
+		resp.Lines = append(resp.Lines, &expensesv1.TrialBalanceLine{
+			AccountId:       line.row.AccountID,
+			AccountName:     line.row.AccountName,
+			AccountTypeCode: line.row.AccountTypeCode,
+			Debit:           currency.money(line.debit),
+			Credit:          currency.money(line.credit),
+		})
+	}
+	for _, total := range totals.sorted() {
+		balanced := total.amounts[0] == total.amounts[1]
+		resp.Balanced = resp.Balanced && balanced
+		resp.Totals = append(resp.Totals, &expensesv1.TrialBalanceTotal{
+			Currency: total.currency.code,
+			Debit:    total.currency.money(total.amounts[0]),
+			Credit:   total.currency.money(total.amounts[1]),
+			Balanced: balanced,
+		})
+	}
+
+	// Flag every transaction that breaks the double-entry rule
+	for _, row := range unbalancedRows {
+		currency := reportCurrency{code: row.CurrencyCode, minorUnits: row.CurrencyMinorUnits, symbol: row.CurrencySymbol}
+		resp.Balanced = false
+		resp.UnbalancedTransactions = append(resp.UnbalancedTransactions, &expensesv1.UnbalancedTransaction{
+			TransactionId: row.TransactionID,
+			Date:          timestamppb.New(row.Date),
+			Description:   row.Description,
+			Debit:         currency.money(row.TotalDebit),
+			Credit:        currency.money(row.TotalCredit),
+		})
+	}
+	if !resp.Balanced {
+		log.WarnContext(ctx, s.logger, "Trial balance does not balance", "as_of", asOf, "unbalanced_transactions", len(resp.UnbalancedTransactions))
+	}
+
+	log.InfoContext(ctx, s.logger, "Trial balance computed successfully", "lines", len(resp.Lines), "balanced", resp.Balanced)
+
+	return connect.NewResponse(resp), nil
+}
+
+// GetBalanceSheet lists asset, liability and equity balances on a date, with
+// Equity balances split by category
+func (s *ReportingService) GetBalanceSheet(ctx context.Context, req *connect.Request[expensesv1.GetBalanceSheetRequest]) (*connect.Response[expensesv1.GetBalanceSheetResponse], error) {
+	asOf := s.reportTime(req.Msg.AsOf)
+
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Getting balance sheet", "as_of", asOf)
+
+	// Aggregate ledger entries (read operations can use the main DB connection)
+	rows, err := s.repo.ListLedgerBalances(ctx, s.repo.GetDB(), authz.WorkspaceFrom(ctx), reportMember(ctx), time.Time{}, asOf)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list ledger balances", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Asset and liability lines are per account; equity lines keep their
+	// category split
+	type lineKey struct{ accountID, categoryID, currency string }
+	var keys []lineKey
+	balances := make(map[lineKey]int64)
+	firstRows := make(map[lineKey]db.ListLedgerBalancesRow)
+	for _, row := range rows {
+		key := lineKey{accountID: row.AccountID, currency: row.CurrencyCode}
+		if row.AccountTypeCode == "E" {
+			key.categoryID = stringValue(row.CategoryID)
+		}
+		if _, ok := firstRows[key]; !ok {
+			firstRows[key] = row
+			keys = append(keys, key)
+		}
+		balance := row.TotalCredit - row.TotalDebit
+		if row.AccountTypeCode == "A" {
+			balance = -balance
+		}
+		if balances[key], err = money.Add(balances[key], balance); err != nil {
+			return nil, s.rangeError(ctx, err)
+		}
+	}
+
+	totals := newCurrencyTotals(3) // assets, liabilities, equity
+	resp := &expensesv1.GetBalanceSheetResponse{
+		AsOf: timestamppb.New(asOf),
+	}
+	for _, key := range keys {
+		row := firstRows[key]
+		currency := ledgerBalanceCurrency(row)
+		line := &expensesv1.BalanceSheetLine{
+			AccountId:   row.AccountID,
+			AccountName: row.AccountName,
+			Balance:     currency.money(balances[key]),
+		}
+		amounts := make([]int64, 3)
+		switch row.AccountTypeCode {
+		case "A":
+			amounts[0] = balances[key]
+			resp.Assets = append(resp.Assets, line)
+		case "L":
+			amounts[1] = balances[key]
+			resp.Liabilities = append(resp.Liabilities, line)
+		default:
+			line.CategoryId = row.CategoryID
+			line.CategoryName = categoryLabel(row.CategoryID, row.CategoryName)
+			amounts[2] = balances[key]
+			resp.Equity = append(resp.Equity, line)
+		}
+		if err := totals.add(currency, amounts...); err != nil {
+			return nil, s.rangeError(ctx, err)
+		}
+	}
+	for _, total := range totals.sorted() {
+		assets, liabilities, equity := total.amounts[0], total.amounts[1], total.amounts[2]
+		claims, err := money.Add(liabilities, equity)
+		if err != nil {
+			return nil, s.rangeError(ctx, err)
+		}
+		resp.Totals = append(resp.Totals, &expensesv1.BalanceSheetTotal{
+			Currency:    total.currency.code,
+			Assets:      total.currency.money(assets),
+			Liabilities: total.currency.money(liabilities),
+			Equity:      total.currency.money(equity),
+			Balanced:    assets == claims,
+		})
+	}
+
+	log.InfoContext(ctx, s.logger, "Balance sheet computed successfully", "assets", len(resp.Assets), "liabilities", len(resp.Liabilities), "equity", len(resp.Equity))
+
+	return connect.NewResponse(resp), nil
+}
+
+// GetIncomeStatement summarises income and expenses by category for a period,
+// computed from the category splits posted to Equity accounts
+func (s *ReportingService) GetIncomeStatement(ctx context.Context, req *connect.Request[expensesv1.GetIncomeStatementRequest]) (*connect.Response[expensesv1.GetIncomeStatementResponse], error) {
+	from := time.Time{}
+	if req.Msg.From != nil {
+		from = req.Msg.From.AsTime().UTC()
+	}
+	to := s.reportTime(req.Msg.To)
+
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Getting income statement", "from", from, "to", to)
+
+	// Validate input
+	if from.After(to) {
+		log.ErrorContext(ctx, s.logger, "Invalid input for GetIncomeStatement", "error", "from must not be after to")
+		return nil, errors.InvalidField("to", "from must not be after to")
+	}
+
+	// Aggregate ledger entries (read operations can use the main DB connection)
+	rows, err := s.repo.ListLedgerBalances(ctx, s.repo.GetDB(), authz.WorkspaceFrom(ctx), reportMember(ctx), from, to)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list ledger balances", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Net the Equity splits of each category across all Equity accounts
+	type lineKey struct{ categoryID, currency string }
+	var keys []lineKey
+	nets := make(map[lineKey]int64)
+	firstRows := make(map[lineKey]db.ListLedgerBalancesRow)
+	for _, row := range rows {
+		if row.AccountTypeCode != "E" {
+			continue
+		}
+		key := lineKey{stringValue(row.CategoryID), row.CurrencyCode}
+		if _, ok := firstRows[key]; !ok {
+			firstRows[key] = row
+			keys = append(keys, key)
+		}
+		if nets[key], err = money.Add(nets[key], row.TotalCredit-row.TotalDebit); err != nil {
+			return nil, s.rangeError(ctx, err)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := firstRows[keys[i]], firstRows[keys[j]]
+		if nameA, nameB := categoryLabel(a.CategoryID, a.CategoryName), categoryLabel(b.CategoryID, b.CategoryName); nameA != nameB {
+			return nameA < nameB
+		}
+		return keys[i].currency < keys[j].currency
+	})
+
+	totals := newCurrencyTotals(2) // income, expenses
+	resp := &expensesv1.GetIncomeStatementResponse{
+		From: timestamppb.New(from),
+		To:   timestamppb.New(to),
+	}
+	for _, key := range keys {
+		row := firstRows[key]
+		currency := ledgerBalanceCurrency(row)
+		net := nets[key]
+		line := &expensesv1.IncomeStatementLine{
+			CategoryId:   row.CategoryID,
+			CategoryName: categoryLabel(row.CategoryID, row.CategoryName),
+		}
+		switch {
+		case net > 0:
+			line.Amount = currency.money(net)
+			err = totals.add(currency, net, 0)
+			resp.Income = append(resp.Income, line)
+		case net < 0:
+			line.Amount = currency.money(-net)
+			err = totals.add(currency, 0, -net)
+			resp.Expenses = append(resp.Expenses, line)
+		}
+		if err != nil {
+			return nil, s.rangeError(ctx, err)
+		}
+	}
+	for _, total := range totals.sorted() {
+		income, expenses := total.amounts[0], total.amounts[1]
+		netIncome, err := money.Add(income, -expenses)
+		if err != nil {
+			return nil, s.rangeError(ctx, err)
+		}
+		resp.Totals = append(resp.Totals, &expensesv1.IncomeStatementTotal{
+			Currency:  total.currency.code,
+			Income:    total.currency.money(income),
+			Expenses:  total.currency.money(expenses),
+			NetIncome: total.currency.money(netIncome),
+		})
+	}
+
+	log.InfoContext(ctx, s.logger, "Income statement computed successfully", "income", len(resp.Income), "expenses", len(resp.Expenses))
+
+	return connect.NewResponse(resp), nil
+}
+
+// reportTime returns the requested time in UTC, defaulting to the current time
+func (s *ReportingService) reportTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return s.clock.Now().UTC()
+	}
+	return ts.AsTime().UTC()
+}
+
+// rangeError creates the OutOfRange error of a report whose sums do not fit in
+// an int64
+func (s *ReportingService) rangeError(ctx context.Context, err error) error {
+	log.ErrorContext(ctx, s.logger, "Report totals are out of range", "error", err)
+	return errors.Error(connect.CodeOutOfRange, errors.ReasonOutOfRange, err)
+}
+
+// reportMember returns the user whose transactions reports are narrowed to.
+// Only the transactions posted entirely on accounts the caller belongs to are
+// reported, so every transaction counts in full and debits still equal
+// credits. Requests without a subject, such as in-process calls, report on the
+// whole workspace.
+func reportMember(ctx context.Context) string {
+	if subject, ok := authz.SubjectFrom(ctx); ok {
+		return subject.UserID
+	}
+	return ""
+}
+
+// ledgerBalanceCurrency extracts the currency metadata of a ledger balance row
+func ledgerBalanceCurrency(row db.ListLedgerBalancesRow) reportCurrency {
+	return reportCurrency{code: row.CurrencyCode, minorUnits: row.CurrencyMinorUnits, symbol: row.CurrencySymbol}
+}
+
+// categoryLabel returns the display name of a category, labelling entries
+// without one as uncategorized
+func categoryLabel(categoryID *string, name string) string {
+	if categoryID == nil {
+		return uncategorizedName
+	}
+	return name
+}
+
+// currencyTotal accumulates a fixed number of amounts for one currency
+type currencyTotal struct {
+	currency reportCurrency
+	amounts  []int64
+}
+
+// currencyTotals accumulates report totals per currency
+type currencyTotals struct {
+	width  int
+	totals map[string]*currencyTotal
+}
+
+// newCurrencyTotals creates totals that track width amounts per currency
+func newCurrencyTotals(width int) *currencyTotals {
+	return &currencyTotals{width: width, totals: make(map[string]*currencyTotal)}
+}
+
+// add adds amounts to the totals of a currency, failing with
+// money.ErrOutOfRange when a total overflows
+func (t *currencyTotals) add(currency reportCurrency, amounts ...int64) error {
+	total, ok := t.totals[currency.code]
+	if !ok {
+		total = &currencyTotal{currency: currency, amounts: make([]int64, t.width)}
+		t.totals[currency.code] = total
+	}
+	for i, amount := range amounts {
+		sum, err := money.Add(total.amounts[i], amount)
+		if err != nil {
+			return err
+		}
+		total.amounts[i] = sum
+	}
+	return nil
+}
+
+// sorted returns the totals ordered by currency code
+func (t *currencyTotals) sorted() []*currencyTotal {
+	totals := make([]*currencyTotal, 0, len(t.totals))
+	for _, total := range t.totals {
+		totals = append(totals, total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].currency.code < totals[j].currency.code
+	})
+	return totals
+}
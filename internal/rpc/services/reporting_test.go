@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// reportDate returns midnight UTC on the given day of April 2025
+func reportDate(day int) *timestamppb.Timestamp {
+	return timestamppb.New(time.Date(2025, 4, day, 0, 0, 0, 0, time.UTC))
+}
+
+// setupReportingFixture posts a small set of transactions for the reporting
+// tests, plus one unbalanced transaction dated 2025-04-15 that bypasses the
+// TransactionService
+func setupReportingFixture(t *testing.T) transactionFixture {
+	t.Helper()
+
+	fx := setupTransactionFixture(t)
+	createTestCategory(t, testDB, "cat_salary", "Salary", nil)
+
+	ctx := context.Background()
+	service := newTestTransactionService()
+	foodCategory, salaryCategory := "cat_food", "cat_salary"
+	requests := []*expensesv1.CreateTransactionRequest{
+		{
+			Date:        reportDate(1),
+			Description: "Opening balance",
+			Lines: []*expensesv1.LedgerLine{
+				debitLine(fx.cash.ID, 100000, "JPY"),
+				creditLine(fx.earnings.ID, 100000, "JPY"),
+			},
+		},
+		{
+			Date:        reportDate(10),
+			Description: "Lunch",
+			Lines: []*expensesv1.LedgerLine{
+				{AccountId: fx.earnings.ID, CategoryId: &foodCategory, Debit: &expensesv1.Money{Amount: 1200, Currency: "JPY"}},
+				creditLine(fx.card.ID, 1200, "JPY"),
+			},
+		},
+		{
+			Date:        reportDate(20),
+			Description: "Consulting fee",
+			Lines: []*expensesv1.LedgerLine{
+				debitLine(fx.usd.ID, 5000, "USD"),
+				{AccountId: fx.earnings.ID, CategoryId: &salaryCategory, Credit: &expensesv1.Money{Amount: 5000, Currency: "USD"}},
+			},
+		},
+	}
+	for _, request := range requests {
+		if _, err := service.CreateTransaction(ctx, connect.NewRequest(request)); err != nil {
+			t.Fatalf("Failed to create transaction %q: %v", request.Description, err)
+		}
+	}
+
+	// Insert a one-sided transaction directly, as an import bug might
+	date := time.Date(2025, 4, 15, 0, 0, 0, 0, time.UTC)
+	if _, err := testDB.Exec(testDB.Rebind("INSERT INTO transactions (id, workspace_id, date, description) VALUES (?, ?, ?, ?)"), "txn_bad", repo.DefaultWorkspaceID, date, "Broken import"); err != nil {
+		t.Fatalf("Failed to insert unbalanced transaction: %v", err)
+	}
+	if _, err := testDB.Exec(testDB.Rebind("INSERT INTO ledger_entries (id, transaction_id, account_id, memo, debit, currency_id) VALUES (?, ?, ?, ?, ?, ?)"), "le_bad", "txn_bad", fx.cash.ID, "", 300, "cur_jpy"); err != nil {
+		t.Fatalf("Failed to insert unbalanced ledger entry: %v", err)
+	}
+	return fx
+}
+
+// TestGetTrialBalance tests the GetTrialBalance RPC method
+func TestGetTrialBalance(t *testing.T) {
+	fx := setupReportingFixture(t)
+
+	// Create a new ReportingService with the test repository
+	service := NewReportingService(reportingRepo, testClock, testLogger)
+
+	// Seed a member of the cash and earnings accounts only, who must not see
+	// the lunch and consulting fee transactions posted partly on other
+	// accounts
+	ctx := context.Background()
+	member := createTestUser(t, testDB, "Member", "member@example.com")
+	for _, accountID := range []string{fx.cash.ID, fx.earnings.ID} {
+		if _, err := accountRepo.AddAccountUser(ctx, testDB, accountID, member.ID); err != nil {
+			t.Fatalf("Failed to add account user: %v", err)
+		}
+	}
+	memberCtx := authz.WithSubject(ctx, authz.NewSubject(member.ID, repo.DefaultWorkspaceID, false, []string{fx.cash.ID, fx.earnings.ID}))
+
+	// Define test cases
+	tests := []struct {
+		name             string
+		ctx              context.Context
+		asOf             *timestamppb.Timestamp
+		expectBalanced   bool
+		expectLines      []string // account name and currency of each line
+		expectJPYDebit   int64
+		expectJPYCredit  int64
+		expectUnbalanced []string
+	}{
+		{
+			name:           "Before the broken import",
+			asOf:           reportDate(12),
+			expectBalanced: true,
+			expectLines: []string{
+				"Cash JPY",
+				"Credit Card JPY",
+				"Current Year Earnings JPY",
+			},
+			expectJPYDebit:  101200,
+			expectJPYCredit: 101200,
+		},
+		{
+			name:           "Defaults to now and flags the broken import",
+			expectBalanced: false,
+			expectLines: []string{
+				"Cash JPY",
+				"USD Wallet USD",
+				"Credit Card JPY",
+				"Current Year Earnings JPY",
+				"Current Year Earnings USD",
+			},
+			expectJPYDebit:   101500,
+			expectJPYCredit:  101200,
+			expectUnbalanced: []string{"txn_bad"},
+		},
+		{
+			name:           "Member of some accounts sees whole transactions only",
+			ctx:            memberCtx,
+			asOf:           reportDate(12),
+			expectBalanced: true,
+			expectLines: []string{
+				"Cash JPY",
+				"Current Year Earnings JPY",
+			},
+			expectJPYDebit:  100000,
+			expectJPYCredit: 100000,
+		},
+		{
+			name:           "Member of some accounts sees the broken import on their account",
+			ctx:            memberCtx,
+			expectBalanced: false,
+			expectLines: []string{
+				"Cash JPY",
+				"Current Year Earnings JPY",
+			},
+			expectJPYDebit:   100300,
+			expectJPYCredit:  100000,
+			expectUnbalanced: []string{"txn_bad"},
+		},
+	}
+
+	// Run test cases
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.ctx == nil {
+				tc.ctx = ctx
+			}
+			resp, err := service.GetTrialBalance(tc.ctx, connect.NewRequest(&expensesv1.GetTrialBalanceRequest{AsOf: tc.asOf}))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if resp.Msg.Balanced != tc.expectBalanced {
+				t.Errorf("Expected balanced %v, got %v", tc.expectBalanced, resp.Msg.Balanced)
+			}
+
+			var lines []string
+			for _, line := range resp.Msg.Lines {
+				lines = append(lines, line.AccountName+" "+line.Debit.Currency)
+			}
+			if len(lines) != len(tc.expectLines) {
+				t.Fatalf("Expected lines %v, got %v", tc.expectLines, lines)
+			}
+			for i := range lines {
+				if lines[i] != tc.expectLines[i] {
+					t.Errorf("Expected lines %v, got %v", tc.expectLines, lines)
+					break
+				}
+			}
+
+			for _, total := range resp.Msg.Totals {
+				if total.Currency != "JPY" {
+					if !total.Balanced {
+						t.Errorf("Expected %s to balance, got debit %d and credit %d", total.Currency, total.Debit.Amount, total.Credit.Amount)
+					}
+					continue
+				}
+				if total.Debit.Amount != tc.expectJPYDebit || total.Credit.Amount != tc.expectJPYCredit {
+					t.Errorf("Expected JPY totals %d/%d, got %d/%d", tc.expectJPYDebit, tc.expectJPYCredit, total.Debit.Amount, total.Credit.Amount)
+				}
+				if total.Balanced != (tc.expectJPYDebit == tc.expectJPYCredit) {
+					t.Errorf("Expected JPY balanced %v, got %v", tc.expectJPYDebit == tc.expectJPYCredit, total.Balanced)
+				}
+			}
+
+			if len(resp.Msg.UnbalancedTransactions) != len(tc.expectUnbalanced) {
+				t.Fatalf("Expected unbalanced transactions %v, got %v", tc.expectUnbalanced, resp.Msg.UnbalancedTransactions)
+			}
+			for i, txn := range resp.Msg.UnbalancedTransactions {
+				if txn.TransactionId != tc.expectUnbalanced[i] {
+					t.Errorf("Expected unbalanced transaction %s, got %s", tc.expectUnbalanced[i], txn.TransactionId)
+				}
+				if txn.Debit.Amount != 300 || txn.Credit.Amount != 0 || txn.Debit.Value != "300" {
+					t.Errorf("Expected a 300 debit and no credit, got %v and %v", txn.Debit, txn.Credit)
+				}
+			}
+		})
+	}
+}
+
+// TestGetBalanceSheet tests the GetBalanceSheet RPC method
+func TestGetBalanceSheet(t *testing.T) {
+	setupReportingFixture(t)
+
+	// Create a new ReportingService with the test repository
+	service := NewReportingService(reportingRepo, testClock, testLogger)
+
+	resp, err := service.GetBalanceSheet(context.Background(), connect.NewRequest(&expensesv1.GetBalanceSheetRequest{AsOf: reportDate(12)}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(resp.Msg.Assets) != 1 || resp.Msg.Assets[0].AccountName != "Cash" || resp.Msg.Assets[0].Balance.Amount != 100000 {
+		t.Errorf("Expected Cash asset of 100000, got %v", resp.Msg.Assets)
+	}
+	if len(resp.Msg.Liabilities) != 1 || resp.Msg.Liabilities[0].AccountName != "Credit Card" || resp.Msg.Liabilities[0].Balance.Amount != 1200 {
+		t.Errorf("Expected Credit Card liability of 1200, got %v", resp.Msg.Liabilities)
+	}
+
+	// Equity lines are split by category, uncategorized first
+	equity := resp.Msg.Equity
+	if len(equity) != 2 {
+		t.Fatalf("Expected 2 equity lines, got %v", equity)
+	}
+	if equity[0].CategoryId != nil || equity[0].CategoryName != uncategorizedName || equity[0].Balance.Amount != 100000 {
+		t.Errorf("Expected uncategorized equity of 100000, got %v", equity[0])
+	}
+	if equity[1].GetCategoryId() != "cat_food" || equity[1].Balance.Amount != -1200 {
+		t.Errorf("Expected Food equity of -1200, got %v", equity[1])
+	}
+
+	if len(resp.Msg.Totals) != 1 {
+		t.Fatalf("Expected totals for JPY only, got %v", resp.Msg.Totals)
+	}
+	total := resp.Msg.Totals[0]
+	if total.Assets.Amount != 100000 || total.Liabilities.Amount != 1200 || total.Equity.Amount != 98800 || !total.Balanced {
+		t.Errorf("Expected assets 100000 = 1200 + 98800, got %v", total)
+	}
+}
+
+// TestGetIncomeStatement tests the GetIncomeStatement RPC method
+func TestGetIncomeStatement(t *testing.T) {
+	setupReportingFixture(t)
+
+	// Create a new ReportingService with the test repository
+	service := NewReportingService(reportingRepo, testClock, testLogger)
+
+	// Define test cases
+	tests := []struct {
+		name           string
+		from           *timestamppb.Timestamp
+		to             *timestamppb.Timestamp
+		expectError    bool
+		expectCode     connect.Code
+		expectIncome   []string // category name and currency of each line
+		expectExpenses []string
+		expectTotals   map[string]int64 // net income per currency
+	}{
+		{
+			name:           "Whole month",
+			from:           reportDate(1),
+			to:             reportDate(30),
+			expectIncome:   []string{"Salary USD", "Uncategorized JPY"},
+			expectExpenses: []string{"Food JPY"},
+			expectTotals:   map[string]int64{"JPY": 98800, "USD": 5000},
+		},
+		{
+			name:           "Inclusive bounds",
+			from:           reportDate(10),
+			to:             reportDate(10),
+			expectExpenses: []string{"Food JPY"},
+			expectTotals:   map[string]int64{"JPY": -1200},
+		},
+		{
+			name:         "Empty period",
+			from:         reportDate(11),
+			to:           reportDate(19),
+			expectTotals: map[string]int64{},
+		},
+		{
+			name:        "From after to",
+			from:        reportDate(20),
+			to:          reportDate(10),
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.GetIncomeStatement(context.Background(), connect.NewRequest(&expensesv1.GetIncomeStatementRequest{From: tc.from, To: tc.to}))
+
+			// Check error
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected error, got nil")
+				}
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			checkLines := func(kind string, lines []*expensesv1.IncomeStatementLine, expected []string) {
+				var got []string
+				for _, line := range lines {
+					got = append(got, line.CategoryName+" "+line.Amount.Currency)
+				}
+				if len(got) != len(expected) {
+					t.Errorf("Expected %s %v, got %v", kind, expected, got)
+					return
+				}
+				for i := range got {
+					if got[i] != expected[i] {
+						t.Errorf("Expected %s %v, got %v", kind, expected, got)
+						return
+					}
+				}
+			}
+			checkLines("income", resp.Msg.Income, tc.expectIncome)
+			checkLines("expenses", resp.Msg.Expenses, tc.expectExpenses)
+
+			if len(resp.Msg.Totals) != len(tc.expectTotals) {
+				t.Fatalf("Expected totals for %v, got %v", tc.expectTotals, resp.Msg.Totals)
+			}
+			for _, total := range resp.Msg.Totals {
+				if total.NetIncome.Amount != tc.expectTotals[total.Currency] {
+					t.Errorf("Expected %s net income %d, got %d", total.Currency, tc.expectTotals[total.Currency], total.NetIncome.Amount)
+				}
+			}
+		})
+	}
+}
+
+// TestReportOutOfRange tests that reports whose sums do not fit in an int64
+// fail instead of wrapping around
+func TestReportOutOfRange(t *testing.T) {
+	fx := setupTransactionFixture(t)
+	createTestCategory(t, testDB, "cat_salary", "Salary", nil)
+
+	// Insert two transactions of 2^62 directly, since each line is above the
+	// maximum amount, which sum to 2^63 per account
+	date := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	for i, categoryID := range []string{"cat_food", "cat_salary"} {
+		transactionID := fmt.Sprintf("txn_large_%d", i)
+		if _, err := testDB.Exec(testDB.Rebind("INSERT INTO transactions (id, workspace_id, date, description) VALUES (?, ?, ?, ?)"), transactionID, repo.DefaultWorkspaceID, date, "Large"); err != nil {
+			t.Fatalf("Failed to insert transaction: %v", err)
+		}
+		entries := []struct {
+			accountID     string
+			debit, credit int64
+		}{
+			{fx.cash.ID, 1 << 62, 0},
+			{fx.earnings.ID, 0, 1 << 62},
+		}
+		for j, entry := range entries {
+			if _, err := testDB.Exec(testDB.Rebind("INSERT INTO ledger_entries (id, transaction_id, account_id, category_id, memo, debit, credit, currency_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+				fmt.Sprintf("le_large_%d_%d", i, j), transactionID, entry.accountID, categoryID, "", entry.debit, entry.credit, "cur_jpy"); err != nil {
+				t.Fatalf("Failed to insert ledger entry: %v", err)
+			}
+		}
+	}
+
+	// Create a new ReportingService with the test repository
+	service := NewReportingService(reportingRepo, testClock, testLogger)
+	ctx := context.Background()
+
+	// Define test cases
+	tests := []struct {
+		name   string
+		report func() error
+	}{
+		{
+			name: "Trial balance",
+			report: func() error {
+				_, err := service.GetTrialBalance(ctx, connect.NewRequest(&expensesv1.GetTrialBalanceRequest{AsOf: reportDate(30)}))
+				return err
+			},
+		},
+		{
+			name: "Balance sheet",
+			report: func() error {
+				_, err := service.GetBalanceSheet(ctx, connect.NewRequest(&expensesv1.GetBalanceSheetRequest{AsOf: reportDate(30)}))
+				return err
+			},
+		},
+		{
+			name: "Income statement",
+			report: func() error {
+				_, err := service.GetIncomeStatement(ctx, connect.NewRequest(&expensesv1.GetIncomeStatementRequest{From: reportDate(1), To: reportDate(30)}))
+				return err
+			},
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.report()
+			if connect.CodeOf(err) != connect.CodeOutOfRange {
+				t.Errorf("Expected code %v, got %v", connect.CodeOutOfRange, err)
+			}
+		})
+	}
+}
@@ -8,15 +8,29 @@ import (
 	"testing"
 	"time"
 
+	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/auth"
 	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/config"
+	"github.com/atreya2011/expense-manager/internal/money"
+	"github.com/atreya2011/expense-manager/internal/pagination"
 	"github.com/atreya2011/expense-manager/internal/repo"
 	db "github.com/atreya2011/expense-manager/internal/repo/gen"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 )
 
 const (
 	testDBPath = ":memory:"
+
+	// testDSNEnv names a PostgreSQL database to run the tests against instead
+	// of an in-memory SQLite database. The tests drop everything in its public
+	// schema, so it must be a scratch database.
+	testDSNEnv = "TEST_DATABASE_DSN"
+
+	// testPostgresSchema is the PostgreSQL schema, relative to this package
+	testPostgresSchema = "../../../db/postgres/schema.sql"
 )
 
 var (
@@ -24,12 +38,25 @@ var (
 	testDB *sqlx.DB
 
 	// Global repositories for tests
-	userRepo       *repo.UserRepo
-	instrumentRepo *repo.InstrumentRepo
+	userRepo           *repo.UserRepo
+	instrumentRepo     *repo.InstrumentRepo
+	institutionRepo    *repo.InstitutionRepo
+	currencyRepo       *repo.CurrencyRepo
+	accountRepo        *repo.AccountRepo
+	categoryRepo       *repo.CategoryRepo
+	transactionRepo    *repo.TransactionRepo
+	reportingRepo      *repo.ReportingRepo
+	reconciliationRepo *repo.ReconciliationRepo
+	auditRepo          *repo.AuditRepo
+	authRepo           *repo.AuthRepo
+	workspaceRepo      *repo.WorkspaceRepo
 
 	// Test clock for predictable timestamps
 	testClock clock.Clock
 
+	// Page token codec with a fixed secret
+	testPages *pagination.Codec
+
 	// Test logger for predictable logging
 	testLogger *slog.Logger
 )
@@ -55,9 +82,14 @@ func TestMain(m *testing.M) {
 
 // setupTestEnvironment initializes the test database and services
 func setupTestEnvironment() error {
-	// Create a new in-memory test database
+	// Create a new in-memory test database, or connect to the PostgreSQL one
+	// given in the environment
+	cfg := config.DatabaseConfig{Driver: repo.DriverSQLite, Path: testDBPath}
+	if dsn := os.Getenv(testDSNEnv); dsn != "" {
+		cfg = config.DatabaseConfig{Driver: repo.DriverPostgres, DSN: dsn}
+	}
 	var err error
-	testDB, err = repo.OpenDB(testDBPath)
+	testDB, err = repo.OpenDB(cfg)
 	if err != nil {
 		return err
 	}
@@ -70,10 +102,26 @@ func setupTestEnvironment() error {
 	// Initialize repositories
 	userRepo = repo.NewUserRepo(testDB)
 	instrumentRepo = repo.NewInstrumentRepo(testDB)
+	institutionRepo = repo.NewInstitutionRepo(testDB)
+	currencyRepo = repo.NewCurrencyRepo(testDB)
+	accountRepo = repo.NewAccountRepo(testDB)
+	categoryRepo = repo.NewCategoryRepo(testDB)
+	transactionRepo = repo.NewTransactionRepo(testDB)
+	reportingRepo = repo.NewReportingRepo(testDB)
+	reconciliationRepo = repo.NewReconciliationRepo(testDB)
+	auditRepo = repo.NewAuditRepo(testDB)
+	authRepo = repo.NewAuthRepo(testDB)
+	workspaceRepo = repo.NewWorkspaceRepo(testDB)
 
 	// Initialize test clock
 	testClock = clock.NewMockClock(time.Date(2025, 4, 26, 12, 0, 0, 0, time.UTC))
 
+	// Initialize page tokens
+	testPages, err = pagination.NewCodec([]byte("test-page-token-secret"), time.Hour, testClock)
+	if err != nil {
+		return err
+	}
+
 	// Initialize test logger that discards output
 	testLogger = slog.New(slog.DiscardHandler)
 
@@ -92,32 +140,255 @@ func teardownTestEnvironment() {
 
 // Create test schema
 func createTestSchema(db *sqlx.DB) error {
-	// Create users table
-	_, err := db.Exec(`
-		CREATE TABLE users (
+	if db.DriverName() == repo.DriverPostgres {
+		return createPostgresTestSchema(db)
+	}
+
+	statements := []string{
+		// Create account_types table
+		`CREATE TABLE account_types (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			code TEXT NOT NULL CHECK (code IN ('A', 'L', 'E')),
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (name),
+			UNIQUE (code)
+		)`,
+		// Create users table
+		`CREATE TABLE users (
 			id TEXT PRIMARY KEY,
 			name TEXT NOT NULL,
 			email TEXT NOT NULL,
 			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE (email)
-		)
-	`)
-	if err != nil {
-		return err
-	}
-
-	// Create instruments table
-	_, err = db.Exec(`
-		CREATE TABLE instruments (
+			revision INTEGER NOT NULL DEFAULT 1,
+			deleted_at TIMESTAMP,
+			is_admin BOOLEAN NOT NULL DEFAULT FALSE
+		)`,
+		`CREATE UNIQUE INDEX users_email ON users (email) WHERE deleted_at IS NULL`,
+		// Create workspaces table
+		`CREATE TABLE workspaces (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		// Create workspace_users table
+		`CREATE TABLE workspace_users (
+			workspace_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (workspace_id, user_id)
+		)`,
+		// Create instruments table
+		`CREATE TABLE instruments (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			revision INTEGER NOT NULL DEFAULT 1,
+			deleted_at TIMESTAMP
+		)`,
+		`CREATE UNIQUE INDEX instruments_workspace_id_name ON instruments (workspace_id, name) WHERE deleted_at IS NULL`,
+		// Create currencies table
+		`CREATE TABLE currencies (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			code TEXT NOT NULL,
+			name TEXT NOT NULL,
+			minor_units INTEGER NOT NULL DEFAULT 2,
+			symbol TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			revision INTEGER NOT NULL DEFAULT 1,
+			deleted_at TIMESTAMP
+		)`,
+		`CREATE UNIQUE INDEX currencies_workspace_id_code ON currencies (workspace_id, code) WHERE deleted_at IS NULL`,
+		// Create institutions table
+		`CREATE TABLE institutions (
 			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
 			name TEXT NOT NULL,
+			type TEXT NOT NULL CHECK (type IN ('BANK', 'CARD_ISSUER', 'BROKER', 'WALLET', 'OTHER')),
 			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE (name)
-		)
-	`)
-	return err
+			revision INTEGER NOT NULL DEFAULT 1,
+			deleted_at TIMESTAMP
+		)`,
+		`CREATE UNIQUE INDEX institutions_workspace_id_name ON institutions (workspace_id, name) WHERE deleted_at IS NULL`,
+		// Create accounts table
+		`CREATE TABLE accounts (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT,
+			account_type_id TEXT NOT NULL,
+			instrument_id TEXT,
+			institution_id TEXT,
+			currency_id TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			revision INTEGER NOT NULL DEFAULT 1,
+			UNIQUE (workspace_id, name)
+		)`,
+		// Create account_users table
+		`CREATE TABLE account_users (
+			account_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (account_id, user_id)
+		)`,
+		// Create categories table
+		`CREATE TABLE categories (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			parent_id TEXT,
+			name TEXT NOT NULL,
+			description TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			revision INTEGER NOT NULL DEFAULT 1,
+			UNIQUE (workspace_id, name)
+		)`,
+		// Create transactions table
+		`CREATE TABLE transactions (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			date TIMESTAMP NOT NULL,
+			description TEXT NOT NULL,
+			notes TEXT,
+			category_id TEXT,
+			instrument_id TEXT,
+			allocation_tag TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			revision INTEGER NOT NULL DEFAULT 1
+		)`,
+		// Create ledger_entries table
+		`CREATE TABLE ledger_entries (
+			id TEXT PRIMARY KEY,
+			transaction_id TEXT NOT NULL,
+			account_id TEXT NOT NULL,
+			category_id TEXT,
+			memo TEXT NOT NULL,
+			debit INTEGER NOT NULL DEFAULT 0,
+			credit INTEGER NOT NULL DEFAULT 0,
+			currency_id TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			CHECK (debit >= 0 AND credit >= 0 AND (debit = 0 OR credit = 0))
+		)`,
+		// Create reconciliations table
+		`CREATE TABLE reconciliations (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			account_id TEXT NOT NULL,
+			counted_at TIMESTAMP NOT NULL,
+			counted_amount INTEGER NOT NULL,
+			ledger_balance INTEGER NOT NULL,
+			discrepancy INTEGER NOT NULL,
+			currency_id TEXT NOT NULL,
+			adjustment_transaction_id TEXT,
+			notes TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		// Create idempotency keys table
+		`CREATE TABLE idempotency_keys (
+			idempotency_key TEXT PRIMARY KEY,
+			method TEXT NOT NULL,
+			request_hash TEXT NOT NULL,
+			response BLOB,
+			code INTEGER NOT NULL DEFAULT 0,
+			error_message TEXT NOT NULL DEFAULT '',
+			completed_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+		// Create audit events table
+		`CREATE TABLE audit_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			resource_type TEXT NOT NULL,
+			resource_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			before_state TEXT,
+			after_state TEXT,
+			actor TEXT NOT NULL DEFAULT '',
+			request_id TEXT NOT NULL DEFAULT '',
+			occurred_at TIMESTAMP NOT NULL,
+			workspace_id TEXT NOT NULL DEFAULT ''
+		)`,
+		// Create change log table
+		`CREATE TABLE changes (
+			seq INTEGER PRIMARY KEY AUTOINCREMENT,
+			workspace_id TEXT NOT NULL,
+			resource_type TEXT NOT NULL,
+			resource_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			account_ids TEXT NOT NULL DEFAULT '',
+			occurred_at TIMESTAMP NOT NULL
+		)`,
+		// Create API tokens table
+		`CREATE TABLE api_tokens (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			UNIQUE (token_hash),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		)`,
+		// Create sessions table
+		`CREATE TABLE sessions (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			token_hash TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP,
+			UNIQUE (token_hash),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		)`,
+	}
+
+	for _, statement := range append(statements, testSeedStatements...) {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createPostgresTestSchema recreates the public schema of a PostgreSQL test
+// database from db/postgres/schema.sql
+func createPostgresTestSchema(db *sqlx.DB) error {
+	schema, err := os.ReadFile(testPostgresSchema)
+	if err != nil {
+		return err
+	}
+	statements := []string{"DROP SCHEMA public CASCADE", "CREATE SCHEMA public", string(schema)}
+	for _, statement := range append(statements, testSeedStatements...) {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// testSeedStatements insert the rows every test relies on
+var testSeedStatements = []string{
+	// Seed account types, which are fixed master data
+	`INSERT INTO account_types (id, name, code) VALUES
+		('at_asset', 'Asset', 'A'),
+		('at_liability', 'Liability', 'L'),
+		('at_equity', 'Equity', 'E')`,
+	// Seed the default workspace of requests made without one
+	`INSERT INTO workspaces (id, name) VALUES ('wsp_default', 'Default')`,
 }
 
 // resetTestDB clears all data from the test database for a fresh test
@@ -125,16 +396,26 @@ func resetTestDB(t *testing.T) {
 	t.Helper()
 
 	// Delete all data from tables
-	tables := []string{"users", "instruments"}
+	tables := []string{
+		"api_tokens", "sessions", "reconciliations", "ledger_entries", "transactions", "categories", "account_users",
+		"accounts", "institutions", "currencies", "users", "instruments", "idempotency_keys",
+		"audit_events", "changes", "workspace_users",
+	}
 	for _, table := range tables {
 		_, err := testDB.Exec("DELETE FROM " + table)
 		if err != nil {
 			t.Fatalf("Failed to clear table %s: %v", table, err)
 		}
 	}
+
+	// Keep the default workspace, which the schema seeds
+	if _, err := testDB.Exec(testDB.Rebind("DELETE FROM workspaces WHERE id != ?"), repo.DefaultWorkspaceID); err != nil {
+		t.Fatalf("Failed to clear table workspaces: %v", err)
+	}
 }
 
-// createTestUser inserts a test user into the database using the provided DBTX
+// createTestUser inserts a test user of the default workspace into the
+// database using the provided DBTX
 func createTestUser(t *testing.T, dbtx db.DBTX, name, email string) db.User {
 	t.Helper()
 
@@ -152,10 +433,36 @@ func createTestUser(t *testing.T, dbtx db.DBTX, name, email string) db.User {
 	if err != nil {
 		t.Fatalf("Failed to create test user: %v", err)
 	}
+	if _, err := workspaceRepo.AddWorkspaceUser(ctx, dbtx, db.AddWorkspaceUserParams{WorkspaceID: repo.DefaultWorkspaceID, UserID: user.ID}); err != nil {
+		t.Fatalf("Failed to add test user to workspace: %v", err)
+	}
 
 	return user
 }
 
+// createTestAPIToken inserts an API token of a user using the provided DBTX,
+// returning it with its secret
+func createTestAPIToken(t *testing.T, dbtx db.DBTX, userID, name string, expiresAt *time.Time) (db.ApiToken, string) {
+	t.Helper()
+
+	token, err := auth.NewAPIToken()
+	if err != nil {
+		t.Fatalf("Failed to generate api token: %v", err)
+	}
+	apiToken, err := authRepo.CreateAPIToken(context.Background(), dbtx, db.CreateApiTokenParams{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: auth.HashToken(token),
+		CreatedAt: testClock.Now(),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test api token: %v", err)
+	}
+
+	return apiToken, token
+}
+
 // createTestInstrument inserts a test instrument into the database using the provided DBTX
 func createTestInstrument(t *testing.T, dbtx db.DBTX, name string) db.Instrument {
 	t.Helper()
@@ -166,7 +473,7 @@ func createTestInstrument(t *testing.T, dbtx db.DBTX, name string) db.Instrument
 
 	// Use the repository to create the instrument
 	var err error
-	instrument, err = instrumentRepo.CreateInstrument(ctx, dbtx, name)
+	instrument, err = instrumentRepo.CreateInstrument(ctx, dbtx, repo.DefaultWorkspaceID, name)
 	if err != nil {
 		t.Fatalf("Failed to create test instrument: %v", err)
 	}
@@ -174,6 +481,87 @@ func createTestInstrument(t *testing.T, dbtx db.DBTX, name string) db.Instrument
 	return instrument
 }
 
+// createTestCurrency inserts a test currency into the database using the provided DBTX.
+// Minor units follow ISO 4217 for known codes.
+func createTestCurrency(t *testing.T, dbtx db.DBTX, id, code, name string) {
+	t.Helper()
+
+	minorUnits := 2
+	if iso, ok := money.LookupISO(code); ok {
+		minorUnits = iso.MinorUnits
+	}
+	_, err := dbtx.ExecContext(context.Background(), testDB.Rebind("INSERT INTO currencies (id, workspace_id, code, name, minor_units) VALUES (?, ?, ?, ?, ?)"), id, repo.DefaultWorkspaceID, code, name, minorUnits)
+	if err != nil {
+		t.Fatalf("Failed to create test currency: %v", err)
+	}
+}
+
+// createTestInstitution inserts a test institution into the database using the provided DBTX
+func createTestInstitution(t *testing.T, dbtx db.DBTX, id, name, institutionType string) {
+	t.Helper()
+
+	_, err := dbtx.ExecContext(context.Background(), testDB.Rebind("INSERT INTO institutions (id, workspace_id, name, type) VALUES (?, ?, ?, ?)"), id, repo.DefaultWorkspaceID, name, institutionType)
+	if err != nil {
+		t.Fatalf("Failed to create test institution: %v", err)
+	}
+}
+
+// createTestAccount inserts a test account into the database using the provided DBTX
+func createTestAccount(t *testing.T, dbtx db.DBTX, name, accountTypeID string, currencyID *string) db.Account {
+	t.Helper()
+
+	ctx := context.Background()
+
+	// Use the repository to create the account
+	account, err := accountRepo.CreateAccount(ctx, dbtx, db.CreateAccountParams{
+		WorkspaceID:   repo.DefaultWorkspaceID,
+		Name:          name,
+		AccountTypeID: accountTypeID,
+		CurrencyID:    currencyID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test account: %v", err)
+	}
+
+	return account
+}
+
+// createTestCategory inserts a test category into the database using the provided DBTX
+func createTestCategory(t *testing.T, dbtx db.DBTX, id, name string, parentID *string) {
+	t.Helper()
+
+	_, err := dbtx.ExecContext(context.Background(), testDB.Rebind("INSERT INTO categories (id, workspace_id, name, parent_id) VALUES (?, ?, ?, ?)"), id, repo.DefaultWorkspaceID, name, parentID)
+	if err != nil {
+		t.Fatalf("Failed to create test category: %v", err)
+	}
+}
+
+// pageTokenAfter issues a page token that continues a list after the given row
+func pageTokenAfter(t *testing.T, filter, key, id string) string {
+	t.Helper()
+
+	token, err := testPages.Encode(pagination.Cursor{Keys: []string{key}, ID: id}, filter)
+	if err != nil {
+		t.Fatalf("Failed to issue page token: %v", err)
+	}
+	return token
+}
+
+// pageTokenWith issues a page token from a codec with its own secret and TTL
+func pageTokenWith(t *testing.T, secret string, ttl time.Duration, filter, key, id string) string {
+	t.Helper()
+
+	pages, err := pagination.NewCodec([]byte(secret), ttl, testClock)
+	if err != nil {
+		t.Fatalf("Failed to create page token codec: %v", err)
+	}
+	token, err := pages.Encode(pagination.Cursor{Keys: []string{key}, ID: id}, filter)
+	if err != nil {
+		t.Fatalf("Failed to issue page token: %v", err)
+	}
+	return token
+}
+
 // assertError checks if an error matches the expected condition
 func assertError(t *testing.T, err error, expectError bool, message string) {
 	t.Helper()
@@ -184,3 +572,24 @@ func assertError(t *testing.T, err error, expectError bool, message string) {
 		t.Errorf("Unexpected error: %v", err)
 	}
 }
+
+// errorReason returns the ErrorInfo reason attached to a connect error, or an
+// empty string if it has none
+func errorReason(t *testing.T, err error) string {
+	t.Helper()
+
+	connectErr, ok := err.(*connect.Error)
+	if !ok {
+		return ""
+	}
+	for _, detail := range connectErr.Details() {
+		value, err := detail.Value()
+		if err != nil {
+			t.Fatalf("Failed to decode error detail %s: %v", detail.Type(), err)
+		}
+		if info, ok := value.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
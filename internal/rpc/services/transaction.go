@@ -0,0 +1,671 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/etag"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/money"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// TransactionService implements the TransactionService interface defined in the proto
+type TransactionService struct {
+	expensesv1connect.UnimplementedTransactionServiceHandler
+	repo           *repo.TransactionRepo
+	accountRepo    *repo.AccountRepo
+	categoryRepo   *repo.CategoryRepo
+	instrumentRepo *repo.InstrumentRepo
+	currencyRepo   *repo.CurrencyRepo
+	auditor        auditor
+	pages          *pagination.Codec
+	clock          clock.Clock
+	logger         *slog.Logger
+}
+
+// NewTransactionService creates a new TransactionService
+func NewTransactionService(
+	repo *repo.TransactionRepo,
+	accountRepo *repo.AccountRepo,
+	categoryRepo *repo.CategoryRepo,
+	instrumentRepo *repo.InstrumentRepo,
+	currencyRepo *repo.CurrencyRepo,
+	auditRepo *repo.AuditRepo,
+	pages *pagination.Codec,
+	clock clock.Clock,
+	logger *slog.Logger,
+) *TransactionService {
+	return &TransactionService{
+		repo:           repo,
+		accountRepo:    accountRepo,
+		categoryRepo:   categoryRepo,
+		instrumentRepo: instrumentRepo,
+		currencyRepo:   currencyRepo,
+		auditor:        newAuditor(auditRepo, clock, logger),
+		pages:          pages,
+		clock:          clock,
+		logger:         logger,
+	}
+}
+
+// CreateTransaction posts a balanced transaction with its ledger lines
+func (s *TransactionService) CreateTransaction(ctx context.Context, req *connect.Request[expensesv1.CreateTransactionRequest]) (*connect.Response[expensesv1.CreateTransactionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Creating transaction", "description", req.Msg.Description, "lines", len(req.Msg.Lines))
+
+	// Validate input
+	if req.Msg.Description == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateTransaction", "error", "description is required")
+		return nil, errors.Required("description")
+	}
+	if len(req.Msg.Lines) < 2 {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateTransaction", "error", "at least two ledger lines are required")
+		return nil, errors.InvalidField("lines", "at least two ledger lines are required")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Validate header references and ledger lines within the transaction
+	params := db.CreateTransactionParams{
+		WorkspaceID:   authz.WorkspaceFrom(ctx),
+		Date:          s.transactionDate(req.Msg.Date),
+		Description:   req.Msg.Description,
+		Notes:         nullableString(&req.Msg.Notes),
+		CategoryID:    nullableString(req.Msg.CategoryId),
+		InstrumentID:  nullableString(req.Msg.InstrumentId),
+		AllocationTag: nullableString(req.Msg.AllocationTag),
+	}
+	if err := s.validateHeader(ctx, tx, params.CategoryID, params.InstrumentID); err != nil {
+		return nil, err
+	}
+	entries, err := s.resolveLines(ctx, tx, req.Msg.Lines)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBalanced(entries); err != nil {
+		log.ErrorContext(ctx, s.logger, "Unbalanced transaction", "error", err)
+		return nil, errors.InvalidField("lines", "%v", err)
+	}
+
+	// Create transaction header and ledger entries within the transaction
+	transaction, err := s.repo.CreateTransaction(ctx, tx, params)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to create transaction", "error", err)
+		return nil, storageError(err)
+	}
+	if err := s.writeLedgerEntries(ctx, tx, transaction.ID, entries); err != nil {
+		return nil, err
+	}
+
+	// Read back the posted entries before committing
+	protoTransactions, err := loadTransactions(ctx, s.logger, s.repo, tx, []db.Transaction{transaction})
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "transaction", transaction.ID, audit.ActionCreate, nil, protoTransactions[0]); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Transaction created successfully", "id", transaction.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.CreateTransactionResponse{
+		Transaction: protoTransactions[0],
+	}), nil
+}
+
+// GetTransaction retrieves a transaction and its ledger entries by ID
+func (s *TransactionService) GetTransaction(ctx context.Context, req *connect.Request[expensesv1.GetTransactionRequest]) (*connect.Response[expensesv1.GetTransactionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Getting transaction", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for GetTransaction", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Get transaction from database (read operations can use the main DB connection)
+	transaction, err := s.repo.GetTransaction(ctx, s.repo.GetDB(), authz.WorkspaceFrom(ctx), req.Msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Transaction not found", "id", req.Msg.Id)
+			return nil, errors.NotFound("transaction", req.Msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get transaction", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	protoTransactions, err := loadTransactions(ctx, s.logger, s.repo, s.repo.GetDB(), []db.Transaction{transaction})
+	if err != nil {
+		return nil, err
+	}
+
+	log.InfoContext(ctx, s.logger, "Transaction retrieved successfully", "id", transaction.ID)
+
+	// Prepare response, exposing the etag for conditional HTTP GETs
+	resp := connect.NewResponse(&expensesv1.GetTransactionResponse{
+		Transaction: protoTransactions[0],
+	})
+	resp.Header().Set(etag.Header, resp.Msg.Transaction.Etag)
+	return resp, nil
+}
+
+// ListTransactions retrieves a paginated list of transactions, newest first
+func (s *TransactionService) ListTransactions(ctx context.Context, req *connect.Request[expensesv1.ListTransactionsRequest]) (*connect.Response[expensesv1.ListTransactionsResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing transactions", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("transactions", req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering within the caller's workspace
+	query, err := parseQuery(ctx, s.logger, repo.TransactionFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+	query.Where(repo.InWorkspace("transactions", authz.WorkspaceFrom(ctx)))
+
+	// Narrow the list to the transactions posted only on accounts the caller
+	// belongs to
+	if subject, ok := authz.SubjectFrom(ctx); ok {
+		query.Where(repo.TransactionsOfMember(subject.UserID))
+	}
+
+	// Get transactions from database (read operations can use the main DB connection)
+	transactions, err := s.repo.ListTransactions(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list transactions", "error", err)
+		return nil, storageError(err)
+	}
+
+	transactions, pageResponse, err := paginate(ctx, s.logger, s.pages, page, transactions, func(row db.Transaction) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountTransactions(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	protoTransactions, err := loadTransactions(ctx, s.logger, s.repo, s.repo.GetDB(), transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	log.InfoContext(ctx, s.logger, "Transactions retrieved successfully", "count", len(transactions))
+
+	return connect.NewResponse(&expensesv1.ListTransactionsResponse{
+		Transactions:       protoTransactions,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// transactionUpdatePaths are the fields of a transaction UpdateTransaction may
+// change
+var transactionUpdatePaths = []string{"date", "description", "notes", "category_id", "instrument_id", "allocation_tag", "lines"}
+
+// UpdateTransaction updates the header fields of a transaction selected by the
+// update mask, and replaces all of its ledger lines when lines are masked
+func (s *TransactionService) UpdateTransaction(ctx context.Context, req *connect.Request[expensesv1.UpdateTransactionRequest]) (*connect.Response[expensesv1.UpdateTransactionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Updating transaction", "id", req.Msg.Id, "lines", len(req.Msg.Lines), "update_mask", req.Msg.GetUpdateMask().GetPaths())
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateTransaction", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+	mask, err := parseUpdateMask(ctx, s.logger, req.Msg.UpdateMask, transactionUpdatePaths)
+	if err != nil {
+		return nil, err
+	}
+	if mask.has("description") && req.Msg.Description == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateTransaction", "error", "description is required")
+		return nil, errors.Required("description")
+	}
+	if mask.has("lines") && len(req.Msg.Lines) < 2 {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateTransaction", "error", "at least two ledger lines are required")
+		return nil, errors.InvalidField("lines", "at least two ledger lines are required")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if transaction exists within the transaction
+	existing, err := s.repo.GetTransaction(ctx, tx, authz.WorkspaceFrom(ctx), req.Msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Transaction not found", "id", req.Msg.Id)
+			return nil, errors.NotFound("transaction", req.Msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to check if transaction exists", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "transaction", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Snapshot the transaction and its entries for the audit log before
+	// changing them
+	before, err := loadTransactions(ctx, s.logger, s.repo, tx, []db.Transaction{existing})
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the masked header references and re-check the balance of the
+	// new lines
+	changes := mask.pick(map[string]any{
+		"date":           s.transactionDate(req.Msg.Date),
+		"description":    req.Msg.Description,
+		"notes":          nullableString(&req.Msg.Notes),
+		"category_id":    nullableString(req.Msg.CategoryId),
+		"instrument_id":  nullableString(req.Msg.InstrumentId),
+		"allocation_tag": nullableString(req.Msg.AllocationTag),
+	})
+	var categoryID, instrumentID *string
+	if mask.has("category_id") {
+		categoryID = nullableString(req.Msg.CategoryId)
+	}
+	if mask.has("instrument_id") {
+		instrumentID = nullableString(req.Msg.InstrumentId)
+	}
+	if err := s.validateHeader(ctx, tx, categoryID, instrumentID); err != nil {
+		return nil, err
+	}
+	var entries []db.CreateLedgerEntryParams
+	if mask.has("lines") {
+		entries, err = s.resolveLines(ctx, tx, req.Msg.Lines)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBalanced(entries); err != nil {
+			log.ErrorContext(ctx, s.logger, "Unbalanced transaction", "id", req.Msg.Id, "error", err)
+			return nil, errors.InvalidField("lines", "%v", err)
+		}
+	}
+
+	// Update the masked header fields and replace all ledger entries if lines
+	// are masked within the transaction
+	transaction, err := s.repo.UpdateTransaction(ctx, tx, req.Msg.Id, existing.Revision, changes)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to update transaction", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if mask.has("lines") {
+		if err := s.repo.DeleteLedgerEntries(ctx, tx, transaction.ID); err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to delete ledger entries", "id", req.Msg.Id, "error", err)
+			return nil, storageError(err)
+		}
+		if err := s.writeLedgerEntries(ctx, tx, transaction.ID, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	// Read back the posted entries before committing
+	protoTransactions, err := loadTransactions(ctx, s.logger, s.repo, tx, []db.Transaction{transaction})
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "transaction", transaction.ID, audit.ActionUpdate, before[0], protoTransactions[0]); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Transaction updated successfully", "id", transaction.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.UpdateTransactionResponse{
+		Transaction: protoTransactions[0],
+	}), nil
+}
+
+// DeleteTransaction deletes a transaction and its ledger entries by ID
+func (s *TransactionService) DeleteTransaction(ctx context.Context, req *connect.Request[expensesv1.DeleteTransactionRequest]) (*connect.Response[expensesv1.DeleteTransactionResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Deleting transaction", "id", req.Msg.Id)
+
+	// Validate input
+	if req.Msg.Id == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for DeleteTransaction", "error", "id is required")
+		return nil, errors.Required("id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if transaction exists within the transaction
+	existing, err := s.repo.GetTransaction(ctx, tx, authz.WorkspaceFrom(ctx), req.Msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Transaction not found", "id", req.Msg.Id)
+			return nil, errors.NotFound("transaction", req.Msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to check if transaction exists", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "transaction", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Snapshot the transaction and its entries for the audit log before
+	// changing them
+	before, err := loadTransactions(ctx, s.logger, s.repo, tx, []db.Transaction{existing})
+	if err != nil {
+		return nil, err
+	}
+
+	// Delete transaction and its ledger entries within the transaction
+	err = s.repo.DeleteTransaction(ctx, tx, req.Msg.Id, existing.Revision)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to delete transaction", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "transaction", existing.ID, audit.ActionDelete, before[0], nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Transaction deleted successfully", "id", req.Msg.Id)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.DeleteTransactionResponse{
+		Success: true,
+	}), nil
+}
+
+// transactionDate returns the requested date in UTC, defaulting to the current time
+func (s *TransactionService) transactionDate(date *timestamppb.Timestamp) time.Time {
+	if date == nil {
+		return s.clock.Now().UTC()
+	}
+	return date.AsTime().UTC()
+}
+
+// validateHeader checks that the category and instrument of a transaction exist
+func (s *TransactionService) validateHeader(ctx context.Context, dbtx db.DBTX, categoryID, instrumentID *string) error {
+	if categoryID != nil {
+		if _, err := s.categoryRepo.GetCategory(ctx, dbtx, authz.WorkspaceFrom(ctx), *categoryID); err != nil {
+			return referenceError(ctx, s.logger, "category_id", *categoryID, err)
+		}
+	}
+	if instrumentID != nil {
+		if _, err := s.instrumentRepo.GetInstrument(ctx, dbtx, authz.WorkspaceFrom(ctx), *instrumentID); err != nil {
+			return referenceError(ctx, s.logger, "instrument_id", *instrumentID, err)
+		}
+	}
+	return nil
+}
+
+// resolveLines validates the ledger lines of a request and converts them into
+// ledger entry parameters with a resolved currency. The transaction ID is left
+// empty and filled in once the header has been written.
+func (s *TransactionService) resolveLines(ctx context.Context, dbtx db.DBTX, lines []*expensesv1.LedgerLine) ([]db.CreateLedgerEntryParams, error) {
+	entries := make([]db.CreateLedgerEntryParams, 0, len(lines))
+	for i, line := range lines {
+		if line.AccountId == "" {
+			return nil, s.lineError(ctx, i, "account_id is required")
+		}
+		if moneySet(line.Debit) == moneySet(line.Credit) {
+			return nil, s.lineError(ctx, i, "exactly one of debit or credit must be positive")
+		}
+		side := line.Debit
+		if !moneySet(line.Debit) {
+			side = line.Credit
+		}
+
+		// Every line must touch an existing account
+		account, err := s.accountRepo.GetAccount(ctx, dbtx, authz.WorkspaceFrom(ctx), line.AccountId)
+		if err != nil {
+			return nil, referenceError(ctx, s.logger, fmt.Sprintf("lines[%d].account_id", i), line.AccountId, err)
+		}
+		categoryID := nullableString(line.CategoryId)
+		if categoryID != nil {
+			if _, err := s.categoryRepo.GetCategory(ctx, dbtx, authz.WorkspaceFrom(ctx), *categoryID); err != nil {
+				return nil, referenceError(ctx, s.logger, fmt.Sprintf("lines[%d].category_id", i), *categoryID, err)
+			}
+		}
+
+		// Resolve the currency from the amount, falling back to the account's currency
+		var currency db.Currency
+		switch {
+		case side.Currency != "":
+			currency, err = s.currencyRepo.GetCurrencyByCode(ctx, dbtx, authz.WorkspaceFrom(ctx), side.Currency)
+			if err != nil {
+				return nil, referenceError(ctx, s.logger, fmt.Sprintf("lines[%d].currency", i), side.Currency, err)
+			}
+			if account.CurrencyID != nil && *account.CurrencyID != currency.ID {
+				return nil, s.lineError(ctx, i, fmt.Sprintf("currency %s does not match the currency of account %s", side.Currency, account.ID))
+			}
+		case account.CurrencyID != nil:
+			currency, err = s.currencyRepo.GetCurrency(ctx, dbtx, authz.WorkspaceFrom(ctx), *account.CurrencyID)
+			if err != nil {
+				return nil, referenceError(ctx, s.logger, fmt.Sprintf("lines[%d].currency", i), *account.CurrencyID, err)
+			}
+		default:
+			return nil, s.lineError(ctx, i, fmt.Sprintf("currency is required because account %s has no default currency", account.ID))
+		}
+
+		// The amount must be expressible in the currency's minor units
+		amount := side.Amount
+		if side.Value != "" {
+			parsed, err := money.ParseDecimal(side.Value, int(currency.MinorUnits))
+			if err != nil {
+				return nil, s.lineError(ctx, i, fmt.Sprintf("%v (%s has %d decimal places)", err, currency.Code, currency.MinorUnits))
+			}
+			if amount != 0 && amount != parsed {
+				return nil, s.lineError(ctx, i, fmt.Sprintf("amount %d does not match value %s", amount, side.Value))
+			}
+			amount = parsed
+		}
+		if amount < 0 {
+			return nil, s.lineError(ctx, i, "amounts must not be negative")
+		}
+		if amount > money.MaxAmount {
+			return nil, s.lineError(ctx, i, fmt.Sprintf("amounts must not exceed %d minor units", money.MaxAmount))
+		}
+		if amount == 0 {
+			return nil, s.lineError(ctx, i, "exactly one of debit or credit must be positive")
+		}
+
+		entry := db.CreateLedgerEntryParams{
+			AccountID:  account.ID,
+			CategoryID: categoryID,
+			Memo:       line.Memo,
+			CurrencyID: &currency.ID,
+		}
+		if side == line.Debit {
+			entry.Debit = amount
+		} else {
+			entry.Credit = amount
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// moneySet reports whether a Money carries an amount in either of its forms
+func moneySet(m *expensesv1.Money) bool {
+	return m.GetAmount() != 0 || m.GetValue() != ""
+}
+
+// checkBalanced verifies that debits equal credits for every currency
+func checkBalanced(entries []db.CreateLedgerEntryParams) error {
+	balances := make(map[string]int64)
+	for _, entry := range entries {
+		currencyID := stringValue(entry.CurrencyID)
+		balance, err := money.Add(balances[currencyID], entry.Debit, -entry.Credit)
+		if err != nil {
+			return fmt.Errorf("debits and credits for currency %s are out of range", currencyID)
+		}
+		balances[currencyID] = balance
+	}
+
+	currencyIDs := make([]string, 0, len(balances))
+	for currencyID, balance := range balances {
+		if balance != 0 {
+			currencyIDs = append(currencyIDs, currencyID)
+		}
+	}
+	if len(currencyIDs) == 0 {
+		return nil
+	}
+
+	// Report the first offending currency deterministically
+	sort.Strings(currencyIDs)
+	balance := balances[currencyIDs[0]]
+	return fmt.Errorf("debits and credits do not balance for currency %s (difference %d)", currencyIDs[0], balance)
+}
+
+// writeLedgerEntries inserts the resolved ledger entries for a transaction
+func (s *TransactionService) writeLedgerEntries(ctx context.Context, dbtx db.DBTX, transactionID string, entries []db.CreateLedgerEntryParams) error {
+	for _, entry := range entries {
+		entry.TransactionID = transactionID
+		if _, err := s.repo.CreateLedgerEntry(ctx, dbtx, entry); err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to create ledger entry", "transaction_id", transactionID, "error", err)
+			return storageError(err)
+		}
+	}
+	return nil
+}
+
+// loadTransactions fetches the ledger entries of the given transactions and
+// converts both into their proto representation
+func loadTransactions(ctx context.Context, logger *slog.Logger, transactionRepo *repo.TransactionRepo, dbtx db.DBTX, transactions []db.Transaction) ([]*expensesv1.Transaction, error) {
+	ids := make([]string, len(transactions))
+	for i, transaction := range transactions {
+		ids[i] = transaction.ID
+	}
+
+	rows, err := transactionRepo.ListLedgerEntries(ctx, dbtx, ids)
+	if err != nil {
+		log.ErrorContext(ctx, logger, "Failed to list ledger entries", "error", err)
+		return nil, storageError(err)
+	}
+	entriesByTransaction := make(map[string][]*expensesv1.LedgerEntry, len(transactions))
+	for _, row := range rows {
+		entriesByTransaction[row.LedgerEntry.TransactionID] = append(entriesByTransaction[row.LedgerEntry.TransactionID], toProtoLedgerEntry(row))
+	}
+
+	protoTransactions := make([]*expensesv1.Transaction, len(transactions))
+	for i, transaction := range transactions {
+		protoTransactions[i] = toProtoTransaction(transaction, entriesByTransaction[transaction.ID])
+	}
+	return protoTransactions, nil
+}
+
+// lineError reports an invalid ledger line
+func (s *TransactionService) lineError(ctx context.Context, index int, msg string) error {
+	log.ErrorContext(ctx, s.logger, "Invalid ledger line", "line", index, "error", msg)
+	return errors.Error(connect.CodeInvalidArgument, errors.ReasonInvalidField, fmt.Errorf("%w: lines[%d]: %s", errors.ErrInvalidInput, index, msg),
+		errors.BadRequest(errors.FieldViolation(fmt.Sprintf("lines[%d]", index), errors.ReasonInvalidField, msg)))
+}
+
+// toProtoTransaction converts a db.Transaction and its entries to a expensesv1.Transaction
+func toProtoTransaction(transaction db.Transaction, entries []*expensesv1.LedgerEntry) *expensesv1.Transaction {
+	return &expensesv1.Transaction{
+		Id:            transaction.ID,
+		Date:          timestamppb.New(transaction.Date),
+		Description:   transaction.Description,
+		Notes:         stringValue(transaction.Notes),
+		CategoryId:    transaction.CategoryID,
+		InstrumentId:  transaction.InstrumentID,
+		AllocationTag: transaction.AllocationTag,
+		CreatedAt:     timestamppb.New(transaction.CreatedAt),
+		UpdatedAt:     timestamppb.New(transaction.UpdatedAt),
+		Etag:          etag.Compute(transaction.Revision, transaction.UpdatedAt),
+		LedgerEntries: entries,
+	}
+}
+
+// toProtoLedgerEntry converts a ledger entry row and its currency to a expensesv1.LedgerEntry
+func toProtoLedgerEntry(row db.ListLedgerEntriesForTransactionsRow) *expensesv1.LedgerEntry {
+	entry := row.LedgerEntry
+	return &expensesv1.LedgerEntry{
+		Id:            entry.ID,
+		TransactionId: entry.TransactionID,
+		AccountId:     entry.AccountID,
+		CategoryId:    entry.CategoryID,
+		Memo:          entry.Memo,
+		Debit:         toProtoMoney(entry.Debit, row.CurrencyCode, row.CurrencyMinorUnits, row.CurrencySymbol),
+		Credit:        toProtoMoney(entry.Credit, row.CurrencyCode, row.CurrencyMinorUnits, row.CurrencySymbol),
+		CurrencyId:    stringValue(entry.CurrencyID),
+		CreatedAt:     timestamppb.New(entry.CreatedAt),
+		UpdatedAt:     timestamppb.New(entry.UpdatedAt),
+	}
+}
@@ -0,0 +1,804 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// newTestTransactionService creates a TransactionService wired to the test repositories
+func newTestTransactionService() *TransactionService {
+	return NewTransactionService(transactionRepo, accountRepo, categoryRepo, instrumentRepo, currencyRepo, auditRepo, testPages, testClock, testLogger)
+}
+
+// debitLine builds a ledger line debiting an account
+func debitLine(accountID string, amount int64, currency string) *expensesv1.LedgerLine {
+	return &expensesv1.LedgerLine{
+		AccountId: accountID,
+		Debit:     &expensesv1.Money{Amount: amount, Currency: currency},
+	}
+}
+
+// creditLine builds a ledger line crediting an account
+func creditLine(accountID string, amount int64, currency string) *expensesv1.LedgerLine {
+	return &expensesv1.LedgerLine{
+		AccountId: accountID,
+		Credit:    &expensesv1.Money{Amount: amount, Currency: currency},
+	}
+}
+
+// transactionFixture holds the accounts used by the transaction tests
+type transactionFixture struct {
+	cash     db.Account // JPY asset account
+	card     db.Account // JPY liability account
+	usd      db.Account // USD asset account
+	earnings db.Account // equity account without a default currency
+}
+
+// setupTransactionFixture resets the database and creates the accounts used by the transaction tests
+func setupTransactionFixture(t *testing.T) transactionFixture {
+	t.Helper()
+
+	resetTestDB(t)
+	createTestCurrency(t, testDB, "cur_jpy", "JPY", "Japanese Yen")
+	createTestCurrency(t, testDB, "cur_usd", "USD", "US Dollar")
+	createTestCategory(t, testDB, "cat_food", "Food", nil)
+
+	jpy, usd := "cur_jpy", "cur_usd"
+	return transactionFixture{
+		cash:     createTestAccount(t, testDB, "Cash", "at_asset", &jpy),
+		card:     createTestAccount(t, testDB, "Credit Card", "at_liability", &jpy),
+		usd:      createTestAccount(t, testDB, "USD Wallet", "at_asset", &usd),
+		earnings: createTestAccount(t, testDB, "Current Year Earnings", "at_equity", nil),
+	}
+}
+
+// TestCreateTransaction tests the CreateTransaction RPC method
+func TestCreateTransaction(t *testing.T) {
+	fx := setupTransactionFixture(t)
+
+	// Create a new TransactionService with the test repositories
+	service := newTestTransactionService()
+
+	foodCategory := "cat_food"
+	missing := "missing"
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		request     *expensesv1.CreateTransactionRequest
+		expectError bool
+		expectCode  connect.Code
+	}{
+		{
+			name: "Balanced transaction",
+			request: &expensesv1.CreateTransactionRequest{
+				Date:        timestamppb.New(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)),
+				Description: "Lunch",
+				CategoryId:  &foodCategory,
+				Lines: []*expensesv1.LedgerLine{
+					{AccountId: fx.earnings.ID, CategoryId: &foodCategory, Debit: &expensesv1.Money{Amount: 1200, Currency: "JPY"}},
+					creditLine(fx.cash.ID, 1200, ""),
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Balanced in each of two currencies",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Travel money",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.earnings.ID, 1000, "JPY"),
+					creditLine(fx.cash.ID, 1000, "JPY"),
+					debitLine(fx.earnings.ID, 25, "USD"),
+					creditLine(fx.usd.ID, 25, "USD"),
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Decimal values in dollars",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Coffee abroad",
+				Lines: []*expensesv1.LedgerLine{
+					{AccountId: fx.earnings.ID, Debit: &expensesv1.Money{Value: "4.50", Currency: "USD"}},
+					{AccountId: fx.usd.ID, Credit: &expensesv1.Money{Amount: 450, Value: "4.5"}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Fractional yen is not representable",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Fractional yen",
+				Lines: []*expensesv1.LedgerLine{
+					{AccountId: fx.earnings.ID, Debit: &expensesv1.Money{Value: "100.5", Currency: "JPY"}},
+					{AccountId: fx.cash.ID, Credit: &expensesv1.Money{Value: "100.5"}},
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Sub-cent dollars are not representable",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Sub-cent",
+				Lines: []*expensesv1.LedgerLine{
+					{AccountId: fx.earnings.ID, Debit: &expensesv1.Money{Value: "1.005", Currency: "USD"}},
+					{AccountId: fx.usd.ID, Credit: &expensesv1.Money{Value: "1.005"}},
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Amount and value disagree",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Disagreement",
+				Lines: []*expensesv1.LedgerLine{
+					{AccountId: fx.earnings.ID, Debit: &expensesv1.Money{Amount: 100, Value: "2.00", Currency: "USD"}},
+					creditLine(fx.usd.ID, 100, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Unbalanced transaction",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Unbalanced",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.earnings.ID, 1000, "JPY"),
+					creditLine(fx.cash.ID, 900, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Totals match but currencies do not",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Cross currency",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.earnings.ID, 100, "USD"),
+					creditLine(fx.cash.ID, 100, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Unknown account",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Unknown account",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine("acc_nonexistent", 100, "JPY"),
+					creditLine(fx.cash.ID, 100, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Unknown line category",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Unknown category",
+				Lines: []*expensesv1.LedgerLine{
+					{AccountId: fx.earnings.ID, CategoryId: &missing, Debit: &expensesv1.Money{Amount: 100, Currency: "JPY"}},
+					creditLine(fx.cash.ID, 100, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Line with both debit and credit",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Both sides",
+				Lines: []*expensesv1.LedgerLine{
+					{AccountId: fx.cash.ID, Debit: &expensesv1.Money{Amount: 100}, Credit: &expensesv1.Money{Amount: 100}},
+					creditLine(fx.card.ID, 100, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Negative amount",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Negative",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.cash.ID, -100, ""),
+					creditLine(fx.card.ID, -100, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Debits that wrap around int64",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Wrapping debits",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.cash.ID, 1<<62, ""),
+					debitLine(fx.cash.ID, 1<<62, ""),
+					debitLine(fx.cash.ID, 1<<62, ""),
+					debitLine(fx.cash.ID, 1<<62, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Decimal value above the maximum",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Too large",
+				Lines: []*expensesv1.LedgerLine{
+					{AccountId: fx.cash.ID, Debit: &expensesv1.Money{Value: "1000000000000001"}},
+					{AccountId: fx.card.ID, Credit: &expensesv1.Money{Value: "1000000000000001"}},
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Largest amount",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Largest",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.cash.ID, 1_000_000_000_000_000, ""),
+					creditLine(fx.card.ID, 1_000_000_000_000_000, ""),
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Currency does not match account",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Wrong currency",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.cash.ID, 100, "USD"),
+					creditLine(fx.usd.ID, 100, "USD"),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Missing currency on account without default",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "No currency",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.earnings.ID, 100, ""),
+					creditLine(fx.cash.ID, 100, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Single line",
+			request: &expensesv1.CreateTransactionRequest{
+				Description: "Single",
+				Lines:       []*expensesv1.LedgerLine{debitLine(fx.cash.ID, 100, "")},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Missing description",
+			request: &expensesv1.CreateTransactionRequest{
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.cash.ID, 100, ""),
+					creditLine(fx.card.ID, 100, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var before int
+			if err := testDB.QueryRow("SELECT COUNT(*) FROM ledger_entries").Scan(&before); err != nil {
+				t.Fatalf("Failed to count ledger entries: %v", err)
+			}
+
+			resp, err := service.CreateTransaction(ctx, connect.NewRequest(tc.request))
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.expectCode.String())
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+
+				// Nothing may be written when the request is rejected
+				var after int
+				if err := testDB.QueryRow("SELECT COUNT(*) FROM ledger_entries").Scan(&after); err != nil {
+					t.Fatalf("Failed to count ledger entries: %v", err)
+				}
+				if after != before {
+					t.Errorf("Expected no ledger entries to be written, got %d new", after-before)
+				}
+				return
+			}
+
+			// Verify response for successful cases
+			if resp == nil || resp.Msg == nil || resp.Msg.Transaction == nil {
+				t.Fatalf("Expected valid response, got nil")
+			}
+			transaction := resp.Msg.Transaction
+			if transaction.Id == "" {
+				t.Errorf("Expected transaction ID to be generated, got empty string")
+			}
+			if len(transaction.LedgerEntries) != len(tc.request.Lines) {
+				t.Fatalf("Expected %d ledger entries, got %d", len(tc.request.Lines), len(transaction.LedgerEntries))
+			}
+			for i, entry := range transaction.LedgerEntries {
+				if entry.AccountId != tc.request.Lines[i].AccountId {
+					t.Errorf("Entry %d: expected account %s, got %s", i, tc.request.Lines[i].AccountId, entry.AccountId)
+				}
+				if entry.CurrencyId == "" || entry.Debit.Currency == "" {
+					t.Errorf("Entry %d: expected a resolved currency", i)
+				}
+			}
+			if tc.request.Date == nil && !transaction.Date.AsTime().Equal(testClock.Now()) {
+				t.Errorf("Expected date to default to %v, got %v", testClock.Now(), transaction.Date.AsTime())
+			}
+		})
+	}
+}
+
+// TestCheckBalanced tests the per-currency balance check of ledger entries
+func TestCheckBalanced(t *testing.T) {
+	jpy, usd := "cur_jpy", "cur_usd"
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		entries     []db.CreateLedgerEntryParams
+		expectError string
+	}{
+		{
+			name: "Balanced",
+			entries: []db.CreateLedgerEntryParams{
+				{CurrencyID: &jpy, Debit: 100},
+				{CurrencyID: &jpy, Credit: 100},
+			},
+		},
+		{
+			name: "Unbalanced",
+			entries: []db.CreateLedgerEntryParams{
+				{CurrencyID: &jpy, Debit: 100},
+				{CurrencyID: &jpy, Credit: 90},
+				{CurrencyID: &usd, Debit: 5},
+				{CurrencyID: &usd, Credit: 5},
+			},
+			expectError: "debits and credits do not balance for currency cur_jpy (difference 10)",
+		},
+		{
+			name: "Debits that wrap around to zero",
+			entries: []db.CreateLedgerEntryParams{
+				{CurrencyID: &jpy, Debit: 1 << 62},
+				{CurrencyID: &jpy, Debit: 1 << 62},
+				{CurrencyID: &jpy, Debit: 1 << 62},
+				{CurrencyID: &jpy, Debit: 1 << 62},
+			},
+			expectError: "debits and credits for currency cur_jpy are out of range",
+		},
+		{
+			name: "Credits that wrap around",
+			entries: []db.CreateLedgerEntryParams{
+				{CurrencyID: &usd, Credit: math.MaxInt64},
+				{CurrencyID: &usd, Credit: 2},
+				{CurrencyID: &usd, Debit: 1},
+			},
+			expectError: "debits and credits for currency cur_usd are out of range",
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkBalanced(tc.entries)
+			if tc.expectError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectError {
+				t.Errorf("Expected error %q, got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+// TestGetTransaction tests the GetTransaction RPC method
+func TestGetTransaction(t *testing.T) {
+	fx := setupTransactionFixture(t)
+
+	// Create a new TransactionService with the test repositories
+	service := newTestTransactionService()
+
+	ctx := context.Background()
+	created, err := service.CreateTransaction(ctx, connect.NewRequest(&expensesv1.CreateTransactionRequest{
+		Description: "Card payment",
+		Lines: []*expensesv1.LedgerLine{
+			debitLine(fx.card.ID, 5000, ""),
+			creditLine(fx.cash.ID, 5000, ""),
+		},
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create test transaction: %v", err)
+	}
+
+	// Define test cases
+	tests := []struct {
+		name          string
+		transactionID string
+		expectError   bool
+		errorMsg      string
+	}{
+		{
+			name:          "Valid transaction retrieval",
+			transactionID: created.Msg.Transaction.Id,
+			expectError:   false,
+		},
+		{
+			name:          "Non-existent transaction",
+			transactionID: "txn_nonexistent",
+			expectError:   true,
+			errorMsg:      "not found",
+		},
+		{
+			name:          "Empty transaction ID",
+			transactionID: "",
+			expectError:   true,
+			errorMsg:      "id is required",
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.GetTransaction(ctx, connect.NewRequest(&expensesv1.GetTransactionRequest{
+				Id: tc.transactionID,
+			}))
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.errorMsg)
+
+			// Verify response for successful cases
+			if !tc.expectError {
+				if resp == nil || resp.Msg == nil || resp.Msg.Transaction == nil {
+					t.Fatalf("Expected valid response, got nil")
+				}
+				entries := resp.Msg.Transaction.LedgerEntries
+				if len(entries) != 2 {
+					t.Fatalf("Expected 2 ledger entries, got %d", len(entries))
+				}
+				if entries[0].Debit.Amount != 5000 || entries[0].Debit.Currency != "JPY" {
+					t.Errorf("Expected debit of 5000 JPY, got %v", entries[0].Debit)
+				}
+				if entries[0].Debit.Value != "5000" {
+					t.Errorf("Expected debit value 5000 for a currency without minor units, got %s", entries[0].Debit.Value)
+				}
+				if entries[1].Credit.Amount != 5000 {
+					t.Errorf("Expected credit of 5000, got %v", entries[1].Credit)
+				}
+			}
+		})
+	}
+}
+
+// TestListTransactions tests the ListTransactions RPC method
+func TestListTransactions(t *testing.T) {
+	fx := setupTransactionFixture(t)
+
+	// Create a new TransactionService with the test repositories
+	service := newTestTransactionService()
+
+	ctx := context.Background()
+	var created []*expensesv1.Transaction
+	for day := 1; day <= 3; day++ {
+		resp, err := service.CreateTransaction(ctx, connect.NewRequest(&expensesv1.CreateTransactionRequest{
+			Date:        timestamppb.New(time.Date(2025, 4, day, 0, 0, 0, 0, time.UTC)),
+			Description: "Daily spend",
+			Lines: []*expensesv1.LedgerLine{
+				debitLine(fx.earnings.ID, int64(day*100), "JPY"),
+				creditLine(fx.cash.ID, int64(day*100), ""),
+			},
+		}))
+		if err != nil {
+			t.Fatalf("Failed to create test transaction: %v", err)
+		}
+		created = append(created, resp.Msg.Transaction)
+	}
+
+	// Page tokens continue after a transaction ordered by date, newest first
+	day2 := created[1]
+	afterDay2 := pageTokenAfter(t, pagination.Filter("transactions", "", ""), day2.Date.AsTime().Format(time.RFC3339Nano), day2.Id)
+
+	// Define test cases
+	tests := []struct {
+		name      string
+		pageSize  int32
+		pageToken string
+		expectedN int
+	}{
+		{
+			name:      "List all transactions (default page size)",
+			expectedN: 3,
+		},
+		{
+			name:      "List with pagination (page 1)",
+			pageSize:  2,
+			expectedN: 2,
+		},
+		{
+			name:      "List with pagination (page 2)",
+			pageSize:  2,
+			pageToken: afterDay2,
+			expectedN: 1,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var page *expensesv1.Pagination
+			if tc.pageSize > 0 || tc.pageToken != "" {
+				page = &expensesv1.Pagination{
+					PageSize:  tc.pageSize,
+					PageToken: tc.pageToken,
+				}
+			}
+
+			resp, err := service.ListTransactions(ctx, connect.NewRequest(&expensesv1.ListTransactionsRequest{
+				Pagination: page,
+			}))
+			assertError(t, err, false, "")
+			if resp == nil || resp.Msg == nil {
+				t.Fatalf("Expected valid response, got nil")
+			}
+
+			if len(resp.Msg.Transactions) != tc.expectedN {
+				t.Fatalf("Expected %d transactions, got %d", tc.expectedN, len(resp.Msg.Transactions))
+			}
+			for _, transaction := range resp.Msg.Transactions {
+				if len(transaction.LedgerEntries) != 2 {
+					t.Errorf("Expected 2 ledger entries on %s, got %d", transaction.Id, len(transaction.LedgerEntries))
+				}
+			}
+		})
+	}
+
+	// Newest transactions come first
+	resp, err := service.ListTransactions(ctx, connect.NewRequest(&expensesv1.ListTransactionsRequest{}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := resp.Msg.Transactions[0].Date.AsTime().Day(); got != 3 {
+		t.Errorf("Expected newest transaction first, got day %d", got)
+	}
+}
+
+// TestUpdateTransaction tests the UpdateTransaction RPC method
+func TestUpdateTransaction(t *testing.T) {
+	fx := setupTransactionFixture(t)
+
+	// Create a new TransactionService with the test repositories
+	service := newTestTransactionService()
+
+	ctx := context.Background()
+	created, err := service.CreateTransaction(ctx, connect.NewRequest(&expensesv1.CreateTransactionRequest{
+		Description: "Groceries",
+		Lines: []*expensesv1.LedgerLine{
+			debitLine(fx.earnings.ID, 3000, "JPY"),
+			creditLine(fx.cash.ID, 3000, ""),
+		},
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create test transaction: %v", err)
+	}
+	transactionID := created.Msg.Transaction.Id
+
+	// Define test cases
+	tests := []struct {
+		name          string
+		request       *expensesv1.UpdateTransactionRequest
+		expectError   bool
+		expectCode    connect.Code
+		expectEntries int
+	}{
+		{
+			name: "Unbalanced replacement is rejected",
+			request: &expensesv1.UpdateTransactionRequest{
+				Id:          transactionID,
+				Description: "Groceries",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.earnings.ID, 3000, "JPY"),
+					creditLine(fx.cash.ID, 2000, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Valid replacement of all lines",
+			request: &expensesv1.UpdateTransactionRequest{
+				Id:          transactionID,
+				Description: "Groceries (split)",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.earnings.ID, 3500, "JPY"),
+					creditLine(fx.cash.ID, 1500, ""),
+					creditLine(fx.card.ID, 2000, ""),
+				},
+			},
+			expectError:   false,
+			expectEntries: 3,
+		},
+		{
+			name: "Masked header update keeps lines",
+			request: &expensesv1.UpdateTransactionRequest{
+				Id:          transactionID,
+				Description: "Groceries (masked)",
+				UpdateMask:  &fieldmaskpb.FieldMask{Paths: []string{"description"}},
+			},
+			expectError:   false,
+			expectEntries: 3,
+		},
+		{
+			name: "Masked lines are still validated",
+			request: &expensesv1.UpdateTransactionRequest{
+				Id:         transactionID,
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"lines"}},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Immutable created_at",
+			request: &expensesv1.UpdateTransactionRequest{
+				Id:          transactionID,
+				Description: "Groceries",
+				UpdateMask:  &fieldmaskpb.FieldMask{Paths: []string{"description", "created_at"}},
+			},
+			expectError: true,
+			expectCode:  connect.CodeInvalidArgument,
+		},
+		{
+			name: "Non-existent transaction",
+			request: &expensesv1.UpdateTransactionRequest{
+				Id:          "txn_nonexistent",
+				Description: "Nothing",
+				Lines: []*expensesv1.LedgerLine{
+					debitLine(fx.earnings.ID, 100, "JPY"),
+					creditLine(fx.cash.ID, 100, ""),
+				},
+			},
+			expectError: true,
+			expectCode:  connect.CodeNotFound,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.UpdateTransaction(ctx, connect.NewRequest(tc.request))
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.expectCode.String())
+			if tc.expectError {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Errorf("Expected code %v, got %v", tc.expectCode, connect.CodeOf(err))
+				}
+				return
+			}
+
+			if resp.Msg.Transaction.Description != tc.request.Description {
+				t.Errorf("Expected description=%s, got %s", tc.request.Description, resp.Msg.Transaction.Description)
+			}
+			if len(resp.Msg.Transaction.LedgerEntries) != tc.expectEntries {
+				t.Errorf("Expected %d ledger entries, got %d", tc.expectEntries, len(resp.Msg.Transaction.LedgerEntries))
+			}
+		})
+	}
+
+	// Only the replacement lines remain in the ledger
+	var count int
+	if err := testDB.QueryRow(testDB.Rebind("SELECT COUNT(*) FROM ledger_entries WHERE transaction_id = ?"), transactionID).Scan(&count); err != nil {
+		t.Fatalf("Failed to count ledger entries: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 ledger entries after update, got %d", count)
+	}
+}
+
+// TestDeleteTransaction tests the DeleteTransaction RPC method
+func TestDeleteTransaction(t *testing.T) {
+	fx := setupTransactionFixture(t)
+
+	// Create a new TransactionService with the test repositories
+	service := newTestTransactionService()
+
+	ctx := context.Background()
+	created, err := service.CreateTransaction(ctx, connect.NewRequest(&expensesv1.CreateTransactionRequest{
+		Description: "Mistake",
+		Lines: []*expensesv1.LedgerLine{
+			debitLine(fx.card.ID, 100, ""),
+			creditLine(fx.cash.ID, 100, ""),
+		},
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create test transaction: %v", err)
+	}
+
+	// Define test cases
+	tests := []struct {
+		name          string
+		transactionID string
+		expectError   bool
+		errorMsg      string
+	}{
+		{
+			name:          "Valid transaction deletion",
+			transactionID: created.Msg.Transaction.Id,
+			expectError:   false,
+		},
+		{
+			name:          "Non-existent transaction",
+			transactionID: "txn_nonexistent",
+			expectError:   true,
+			errorMsg:      "not found",
+		},
+		{
+			name:          "Empty transaction ID",
+			transactionID: "",
+			expectError:   true,
+			errorMsg:      "id is required",
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.DeleteTransaction(ctx, connect.NewRequest(&expensesv1.DeleteTransactionRequest{
+				Id: tc.transactionID,
+			}))
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.errorMsg)
+
+			// Verify response for successful cases
+			if !tc.expectError {
+				if resp == nil || resp.Msg == nil || !resp.Msg.Success {
+					t.Fatalf("Expected success=true")
+				}
+
+				// Verify the transaction and its ledger entries were actually deleted
+				var count int
+				if err := testDB.QueryRow(testDB.Rebind("SELECT COUNT(*) FROM ledger_entries WHERE transaction_id = ?"), tc.transactionID).Scan(&count); err != nil {
+					t.Fatalf("Failed to count ledger entries: %v", err)
+				}
+				if count != 0 {
+					t.Errorf("Ledger entries were not deleted, found %d records", count)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// TestRecreateDeleted tests that a resource moved to the trash frees its
+// unique name for a new one, and cannot be restored while the new one lives
+func TestRecreateDeleted(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create new services with the test repositories
+	users := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
+	instruments := NewInstrumentService(instrumentRepo, auditRepo, testPages, testClock, testLogger)
+	currencies := NewCurrencyService(currencyRepo, auditRepo, testPages, testClock, testLogger)
+	institutions := NewInstitutionService(institutionRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Define test cases
+	tests := []struct {
+		name     string
+		create   func(ctx context.Context) (string, error)
+		delete   func(ctx context.Context, id string) error
+		undelete func(ctx context.Context, id string) error
+	}{
+		{
+			name: "User",
+			create: func(ctx context.Context) (string, error) {
+				resp, err := users.CreateUser(ctx, connect.NewRequest(&expensesv1.CreateUserRequest{Name: "Recreated User", Email: "recreated@example.com"}))
+				if err != nil {
+					return "", err
+				}
+				return resp.Msg.User.Id, nil
+			},
+			delete: func(ctx context.Context, id string) error {
+				_, err := users.DeleteUser(ctx, connect.NewRequest(&expensesv1.DeleteUserRequest{Id: id}))
+				return err
+			},
+			undelete: func(ctx context.Context, id string) error {
+				_, err := users.UndeleteUser(ctx, connect.NewRequest(&expensesv1.UndeleteUserRequest{Id: id}))
+				return err
+			},
+		},
+		{
+			name: "Instrument",
+			create: func(ctx context.Context) (string, error) {
+				resp, err := instruments.CreateInstrument(ctx, connect.NewRequest(&expensesv1.CreateInstrumentRequest{Name: "Recreated Card"}))
+				if err != nil {
+					return "", err
+				}
+				return resp.Msg.Instrument.Id, nil
+			},
+			delete: func(ctx context.Context, id string) error {
+				_, err := instruments.DeleteInstrument(ctx, connect.NewRequest(&expensesv1.DeleteInstrumentRequest{Id: id}))
+				return err
+			},
+			undelete: func(ctx context.Context, id string) error {
+				_, err := instruments.UndeleteInstrument(ctx, connect.NewRequest(&expensesv1.UndeleteInstrumentRequest{Id: id}))
+				return err
+			},
+		},
+		{
+			name: "Currency",
+			create: func(ctx context.Context) (string, error) {
+				resp, err := currencies.CreateCurrency(ctx, connect.NewRequest(&expensesv1.CreateCurrencyRequest{Code: "CHF", Name: "Swiss Franc"}))
+				if err != nil {
+					return "", err
+				}
+				return resp.Msg.Currency.Id, nil
+			},
+			delete: func(ctx context.Context, id string) error {
+				_, err := currencies.DeleteCurrency(ctx, connect.NewRequest(&expensesv1.DeleteCurrencyRequest{Id: id}))
+				return err
+			},
+			undelete: func(ctx context.Context, id string) error {
+				_, err := currencies.UndeleteCurrency(ctx, connect.NewRequest(&expensesv1.UndeleteCurrencyRequest{Id: id}))
+				return err
+			},
+		},
+		{
+			name: "Institution",
+			create: func(ctx context.Context) (string, error) {
+				resp, err := institutions.CreateInstitution(ctx, connect.NewRequest(&expensesv1.CreateInstitutionRequest{Name: "Recreated Bank", Type: expensesv1.InstitutionType_INSTITUTION_TYPE_BANK}))
+				if err != nil {
+					return "", err
+				}
+				return resp.Msg.Institution.Id, nil
+			},
+			delete: func(ctx context.Context, id string) error {
+				_, err := institutions.DeleteInstitution(ctx, connect.NewRequest(&expensesv1.DeleteInstitutionRequest{Id: id}))
+				return err
+			},
+			undelete: func(ctx context.Context, id string) error {
+				_, err := institutions.UndeleteInstitution(ctx, connect.NewRequest(&expensesv1.UndeleteInstitutionRequest{Id: id}))
+				return err
+			},
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// Move the first one to the trash and create another under its name
+			original, err := tc.create(ctx)
+			if err != nil {
+				t.Fatalf("Failed to create: %v", err)
+			}
+			if err := tc.delete(ctx, original); err != nil {
+				t.Fatalf("Failed to delete: %v", err)
+			}
+			recreated, err := tc.create(ctx)
+			if err != nil {
+				t.Fatalf("Failed to recreate after deleting: %v", err)
+			}
+
+			// The original cannot come back while the new one holds its name
+			err = tc.undelete(ctx, original)
+			if connect.CodeOf(err) != connect.CodeAlreadyExists {
+				t.Fatalf("Expected code %v, got %v", connect.CodeAlreadyExists, err)
+			}
+
+			// It can once the new one is in the trash too
+			if err := tc.delete(ctx, recreated); err != nil {
+				t.Fatalf("Failed to delete: %v", err)
+			}
+			if err := tc.undelete(ctx, original); err != nil {
+				t.Errorf("Failed to undelete: %v", err)
+			}
+		})
+	}
+}
+
+// TestPurgeUsers tests that purging the trash keeps the users still holding
+// accounts or named as the actor of audit events
+func TestPurgeUsers(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create test users (using the main DB connection for setup): one holding
+	// an account, one that acted in the audit log and one nothing refers to
+	ctx := context.Background()
+	holder := createTestUser(t, testDB, "Account Holder", "holder@example.com")
+	actor := createTestUser(t, testDB, "Audit Actor", "actor@example.com")
+	unreferenced := createTestUser(t, testDB, "Unreferenced User", "unreferenced@example.com")
+	account := createTestAccount(t, testDB, "Shared Wallet", "at_asset", nil)
+	if _, err := accountRepo.AddAccountUser(ctx, testDB, account.ID, holder.ID); err != nil {
+		t.Fatalf("Failed to add account user: %v", err)
+	}
+	if _, err := auditRepo.CreateAuditEvent(ctx, testDB, db.CreateAuditEventParams{
+		WorkspaceID:  repo.DefaultWorkspaceID,
+		ResourceType: "account",
+		ResourceID:   account.ID,
+		Action:       audit.ActionUpdate,
+		Actor:        actor.ID,
+		OccurredAt:   testClock.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to create audit event: %v", err)
+	}
+
+	// Move them all to the trash and purge it
+	for _, user := range []db.User{holder, actor, unreferenced} {
+		if err := userRepo.DeleteUser(ctx, testDB, user.ID, user.Revision, testClock.Now()); err != nil {
+			t.Fatalf("Failed to delete user: %v", err)
+		}
+	}
+	purged, err := userRepo.PurgeUsers(ctx, testDB, testClock.Now())
+	if err != nil {
+		t.Fatalf("Failed to purge users: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 user purged, got %d", purged)
+	}
+
+	// Define test cases
+	tests := []struct {
+		name       string
+		userID     string
+		expectKept bool
+	}{
+		{
+			name:       "User holding an account",
+			userID:     holder.ID,
+			expectKept: true,
+		},
+		{
+			name:       "Actor of an audit event",
+			userID:     actor.ID,
+			expectKept: true,
+		},
+		{
+			name:   "Unreferenced user",
+			userID: unreferenced.ID,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := userRepo.GetDeletedUser(ctx, testDB, tc.userID)
+			if tc.expectKept && err != nil {
+				t.Errorf("Expected the user to stay in the trash, got %v", err)
+			}
+			if !tc.expectKept && !stderrors.Is(err, errors.ErrNotFound) {
+				t.Errorf("Expected the user to be purged, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+)
+
+// immutablePaths are the fields of every resource that no update may change
+var immutablePaths = []string{"id", "created_at", "updated_at"}
+
+// updateMask is the set of fields an Update request changes
+type updateMask map[string]bool
+
+// parseUpdateMask validates the update_mask of an Update request against the
+// mutable fields of the resource. An empty mask, or the single path "*",
+// selects every mutable field so requests without a mask replace the whole
+// resource as before.
+func parseUpdateMask(ctx context.Context, logger *slog.Logger, mask *fieldmaskpb.FieldMask, mutable []string, immutable ...string) (updateMask, error) {
+	paths := mask.GetPaths()
+	if len(paths) == 0 || (len(paths) == 1 && paths[0] == "*") {
+		paths = mutable
+	}
+
+	selected := make(updateMask, len(paths))
+	for _, path := range paths {
+		switch {
+		case slices.Contains(mutable, path):
+			selected[path] = true
+		case slices.Contains(immutablePaths, path) || slices.Contains(immutable, path):
+			log.ErrorContext(ctx, logger, "Invalid update_mask", "path", path, "error", "field is immutable")
+			return nil, errors.InvalidField("update_mask", "update_mask path %q is immutable", path)
+		default:
+			log.ErrorContext(ctx, logger, "Invalid update_mask", "path", path, "error", "unknown field")
+			return nil, errors.InvalidField("update_mask", "unknown update_mask path %q", path)
+		}
+	}
+	return selected, nil
+}
+
+// has reports whether the mask selects a field
+func (m updateMask) has(path string) bool {
+	return m[path]
+}
+
+// pick returns the values of the fields the mask selects, keyed by the column
+// each field is stored in
+func (m updateMask) pick(values map[string]any) map[string]any {
+	changes := make(map[string]any, len(values))
+	for path, value := range values {
+		if m.has(path) {
+			changes[path] = value
+		}
+	}
+	return changes
+}
@@ -9,9 +9,13 @@ import (
 	"connectrpc.com/connect"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/authz"
 	"github.com/atreya2011/expense-manager/internal/clock"
 	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/etag"
 	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/pagination"
 	"github.com/atreya2011/expense-manager/internal/repo"
 	db "github.com/atreya2011/expense-manager/internal/repo/gen"
 	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
@@ -21,17 +25,21 @@ import (
 // UserService implements the UserService interface defined in the proto
 type UserService struct {
 	expensesv1connect.UnimplementedUserServiceHandler
-	repo   *repo.UserRepo
-	clock  clock.Clock
-	logger *slog.Logger
+	repo    *repo.UserRepo
+	auditor auditor
+	pages   *pagination.Codec
+	clock   clock.Clock
+	logger  *slog.Logger
 }
 
 // NewUserService creates a new UserService
-func NewUserService(repo *repo.UserRepo, clock clock.Clock, logger *slog.Logger) *UserService {
+func NewUserService(repo *repo.UserRepo, auditRepo *repo.AuditRepo, pages *pagination.Codec, clock clock.Clock, logger *slog.Logger) *UserService {
 	return &UserService{
-		repo:   repo,
-		clock:  clock,
-		logger: logger,
+		repo:    repo,
+		auditor: newAuditor(auditRepo, clock, logger),
+		pages:   pages,
+		clock:   clock,
+		logger:  logger,
 	}
 }
 
@@ -40,17 +48,11 @@ func (s *UserService) CreateUser(ctx context.Context, req *connect.Request[expen
 	// Log method entry with context
 	log.InfoContext(ctx, s.logger, "Creating user", "name", req.Msg.Name, "email", req.Msg.Email)
 
-	// Validate input
-	if req.Msg.Name == "" || req.Msg.Email == "" {
-		log.ErrorContext(ctx, s.logger, "Invalid input for CreateUser", "error", "name and email are required")
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: name and email are required", errors.ErrInvalidInput))
-	}
-
 	// Begin transaction
 	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
 	if err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to begin transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
 	}
 	defer func() {
 		if err := tx.Rollback(); err != nil {
@@ -60,23 +62,15 @@ func (s *UserService) CreateUser(ctx context.Context, req *connect.Request[expen
 	}() // Rollback if any error occurs
 
 	// Create user in database within the transaction
-	user, err := s.repo.CreateUser(ctx, tx, db.CreateUserParams{
-		Name:  req.Msg.Name,
-		Email: req.Msg.Email,
-	})
+	user, err := s.createUser(ctx, tx, req.Msg)
 	if err != nil {
-		if stderrors.Is(err, errors.ErrDuplicate) {
-			log.ErrorContext(ctx, s.logger, "User already exists", "email", req.Msg.Email)
-			return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("%w: user with email %s already exists", errors.ErrDuplicate, req.Msg.Email))
-		}
-		log.ErrorContext(ctx, s.logger, "Failed to create user", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		return nil, err
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to commit transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
 	// Log success
@@ -93,64 +87,71 @@ func (s *UserService) GetUser(ctx context.Context, req *connect.Request[expenses
 	// Log method entry
 	log.InfoContext(ctx, s.logger, "Getting user", "id", req.Msg.Id)
 
-	// Validate input
-	if req.Msg.Id == "" {
-		log.ErrorContext(ctx, s.logger, "Invalid input for GetUser", "error", "id is required")
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: id is required", errors.ErrInvalidInput))
+	// Get user from database (read operations can use the main DB connection),
+	// among the users of the caller's workspace unless a server admin asks
+	var user db.User
+	var err error
+	if subject, ok := authz.SubjectFrom(ctx); ok && !subject.ServerAdmin {
+		user, err = s.repo.GetUserInWorkspace(ctx, s.repo.GetDB(), subject.WorkspaceID, req.Msg.Id)
+	} else {
+		user, err = s.repo.GetUser(ctx, s.repo.GetDB(), req.Msg.Id)
 	}
-
-	// Get user from database (read operations can use the main DB connection)
-	user, err := s.repo.GetUser(ctx, s.repo.GetDB(), req.Msg.Id)
 	if err != nil {
 		if stderrors.Is(err, errors.ErrNotFound) {
 			log.ErrorContext(ctx, s.logger, "User not found", "id", req.Msg.Id)
-			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("%w: user with id %s not found", errors.ErrNotFound, req.Msg.Id))
+			return nil, errors.NotFound("user", req.Msg.Id)
 		}
 		log.ErrorContext(ctx, s.logger, "Failed to get user", "id", req.Msg.Id, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		return nil, storageError(err)
 	}
 
 	log.InfoContext(ctx, s.logger, "User retrieved successfully", "id", user.ID)
 
-	// Prepare response
-	return connect.NewResponse(&expensesv1.GetUserResponse{
+	// Prepare response, exposing the etag for conditional HTTP GETs
+	resp := connect.NewResponse(&expensesv1.GetUserResponse{
 		User: toProtoUser(user),
-	}), nil
+	})
+	resp.Header().Set(etag.Header, resp.Msg.User.Etag)
+	return resp, nil
 }
 
 // ListUsers retrieves a paginated list of users
 func (s *UserService) ListUsers(ctx context.Context, req *connect.Request[expensesv1.ListUsersRequest]) (*connect.Response[expensesv1.ListUsersResponse], error) {
 	// Log method entry
-	log.InfoContext(ctx, s.logger, "Listing users")
+	log.InfoContext(ctx, s.logger, "Listing users", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
 
 	// Parse pagination parameters
-	limit := int32(50) // default limit
-	offset := int32(0) // default offset
-	if req.Msg.Pagination != nil {
-		if req.Msg.Pagination.PageSize > 0 {
-			limit = req.Msg.Pagination.PageSize
-		}
-		// Extract offset from page token if provided
-		if req.Msg.Pagination.PageToken != "" {
-			// In a real implementation, we would decode the page token to get the offset
-			// For simplicity, we'll just parse it as an offset
-			if _, err := fmt.Sscanf(req.Msg.Pagination.PageToken, "%d", &offset); err != nil {
-				log.ErrorContext(ctx, s.logger, "Invalid page token", "token", req.Msg.Pagination.PageToken, "error", err)
-				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: invalid page token", errors.ErrInvalidInput))
-			}
-		}
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("users", req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering
+	query, err := parseQuery(ctx, s.logger, repo.UserFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
 	}
 
-	log.InfoContext(ctx, s.logger, "Pagination parameters", "limit", limit, "offset", offset)
+	// Narrow the list to the users of the caller's workspace unless a server
+	// admin asks
+	if subject, ok := authz.SubjectFrom(ctx); ok && !subject.ServerAdmin {
+		query.Where(repo.UsersOfWorkspace(subject.WorkspaceID))
+	}
 
 	// Get users from database (read operations can use the main DB connection)
-	users, err := s.repo.ListUsers(ctx, s.repo.GetDB(), db.ListUsersParams{
-		Limit:  limit,
-		Offset: offset,
-	})
+	users, err := s.repo.ListUsers(ctx, s.repo.GetDB(), query, page.Size+1)
 	if err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to list users", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		return nil, storageError(err)
+	}
+
+	users, pageResponse, err := paginate(ctx, s.logger, s.pages, page, users, func(row db.User) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountUsers(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare response
@@ -159,43 +160,66 @@ func (s *UserService) ListUsers(ctx context.Context, req *connect.Request[expens
 		protoUsers[i] = toProtoUser(user)
 	}
 
-	// Prepare pagination response
-	nextPageToken := ""
-	if len(users) == int(limit) {
-		nextPageToken = fmt.Sprintf("%d", offset+limit)
-	}
-
 	log.InfoContext(ctx, s.logger, "Users retrieved successfully", "count", len(users))
 
 	return connect.NewResponse(&expensesv1.ListUsersResponse{
-		Users: protoUsers,
-		PaginationResponse: &expensesv1.PaginationResponse{
-			NextPageToken: nextPageToken,
-			TotalCount:    int32(len(users)), // In a real implementation, we would get the total count from the database
-		},
+		Users:              protoUsers,
+		PaginationResponse: pageResponse,
 	}), nil
 }
 
-// UpdateUser updates a user
+// userUpdatePaths are the fields of a user UpdateUser may change
+var userUpdatePaths = []string{"name", "email"}
+
+// UpdateUser updates the fields of a user selected by the update mask
 func (s *UserService) UpdateUser(ctx context.Context, req *connect.Request[expensesv1.UpdateUserRequest]) (*connect.Response[expensesv1.UpdateUserResponse], error) {
 	// Log method entry
-	log.InfoContext(ctx, s.logger, "Updating user", "id", req.Msg.Id, "name", req.Msg.Name, "email", req.Msg.Email)
+	log.InfoContext(ctx, s.logger, "Updating user", "id", req.Msg.Id, "name", req.Msg.Name, "email", req.Msg.Email, "update_mask", req.Msg.GetUpdateMask().GetPaths())
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
 
-	// Validate input
-	if req.Msg.Id == "" {
-		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateUser", "error", "id is required")
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: id is required", errors.ErrInvalidInput))
+	// Update user within the transaction
+	user, err := s.updateUser(ctx, tx, req.Msg)
+	if err != nil {
+		return nil, err
 	}
-	if req.Msg.Name == "" || req.Msg.Email == "" {
-		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateUser", "error", "name and email are required")
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: name and email are required", errors.ErrInvalidInput))
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
+	// Log success
+	log.InfoContext(ctx, s.logger, "User updated successfully", "id", user.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.UpdateUserResponse{
+		User: toProtoUser(user),
+	}), nil
+}
+
+// DeleteUser moves a user to the trash, keeping its account memberships
+func (s *UserService) DeleteUser(ctx context.Context, req *connect.Request[expensesv1.DeleteUserRequest]) (*connect.Response[expensesv1.DeleteUserResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Deleting user", "id", req.Msg.Id)
+
 	// Begin transaction
 	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
 	if err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to begin transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
 	}
 	defer func() {
 		if err := tx.Rollback(); err != nil {
@@ -204,63 +228,194 @@ func (s *UserService) UpdateUser(ctx context.Context, req *connect.Request[expen
 		}
 	}() // Rollback if any error occurs
 
-	// Check if user exists within the transaction
-	_, err = s.repo.GetUser(ctx, tx, req.Msg.Id)
+	// Move the user to the trash within the transaction
+	if err := s.deleteUser(ctx, tx, req.Msg); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "User deleted successfully", "id", req.Msg.Id)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.DeleteUserResponse{
+		Success: true,
+	}), nil
+}
+
+// ListDeletedUsers retrieves a paginated list of the users in the trash
+func (s *UserService) ListDeletedUsers(ctx context.Context, req *connect.Request[expensesv1.ListDeletedUsersRequest]) (*connect.Response[expensesv1.ListDeletedUsersResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing deleted users", "filter", req.Msg.Filter, "order_by", req.Msg.OrderBy)
+
+	// Parse pagination parameters
+	page, err := parsePage(ctx, s.logger, s.pages, req.Msg.Pagination, pagination.Filter("deleted_users", req.Msg.Filter, req.Msg.OrderBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the filter and ordering
+	query, err := parseQuery(ctx, s.logger, repo.UserFields, req.Msg.Filter, req.Msg.OrderBy, page.After)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get deleted users from database (read operations can use the main DB connection)
+	users, err := s.repo.ListDeletedUsers(ctx, s.repo.GetDB(), query, page.Size+1)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list deleted users", "error", err)
+		return nil, storageError(err)
+	}
+
+	users, pageResponse, err := paginate(ctx, s.logger, s.pages, page, users, func(row db.User) pagination.Cursor {
+		return pagination.Cursor{Keys: query.Keys(row), ID: row.ID}
+	}, func() (int64, error) {
+		return s.repo.CountDeletedUsers(ctx, s.repo.GetDB(), query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to proto messages
+	protoUsers := make([]*expensesv1.User, len(users))
+	for i, user := range users {
+		protoUsers[i] = toProtoUser(user)
+	}
+
+	log.InfoContext(ctx, s.logger, "Deleted users retrieved successfully", "count", len(users))
+
+	return connect.NewResponse(&expensesv1.ListDeletedUsersResponse{
+		Users:              protoUsers,
+		PaginationResponse: pageResponse,
+	}), nil
+}
+
+// UndeleteUser restores a user from the trash
+func (s *UserService) UndeleteUser(ctx context.Context, req *connect.Request[expensesv1.UndeleteUserRequest]) (*connect.Response[expensesv1.UndeleteUserResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Undeleting user", "id", req.Msg.Id)
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Check if the user is in the trash within the transaction
+	existing, err := s.repo.GetDeletedUser(ctx, tx, req.Msg.Id)
 	if err != nil {
 		if stderrors.Is(err, errors.ErrNotFound) {
-			log.ErrorContext(ctx, s.logger, "User not found", "id", req.Msg.Id)
-			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("%w: user with id %s not found", errors.ErrNotFound, req.Msg.Id))
+			log.ErrorContext(ctx, s.logger, "Deleted user not found", "id", req.Msg.Id)
+			return nil, errors.Error(connect.CodeNotFound, errors.ReasonNotFound, fmt.Errorf("%w: deleted user with id %s not found", errors.ErrNotFound, req.Msg.Id), errors.Resource("user", req.Msg.Id))
 		}
-		log.ErrorContext(ctx, s.logger, "Failed to check if user exists", "id", req.Msg.Id, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		log.ErrorContext(ctx, s.logger, "Failed to check if deleted user exists", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "user", existing.ID, req.Msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return nil, err
 	}
 
-	// Update user in database within the transaction
-	user, err := s.repo.UpdateUser(ctx, tx, db.UpdateUserParams{
-		ID:    req.Msg.Id,
-		Name:  req.Msg.Name,
-		Email: req.Msg.Email,
-	})
+	// Restore the user within the transaction
+	user, err := s.repo.UndeleteUser(ctx, tx, req.Msg.Id, existing.Revision)
 	if err != nil {
 		if stderrors.Is(err, errors.ErrDuplicate) {
-			log.ErrorContext(ctx, s.logger, "User with email already exists", "email", req.Msg.Email)
-			return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("%w: user with email %s already exists", errors.ErrDuplicate, req.Msg.Email))
+			log.ErrorContext(ctx, s.logger, "A live user already has the email of the deleted user", "id", req.Msg.Id, "email", existing.Email)
+			return nil, errors.AlreadyExists("user", existing.Email, "email", "user with email %s already exists", existing.Email)
 		}
-		log.ErrorContext(ctx, s.logger, "Failed to update user", "id", req.Msg.Id, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		log.ErrorContext(ctx, s.logger, "Failed to undelete user", "id", req.Msg.Id, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "user", user.ID, audit.ActionUndelete, toProtoUser(existing), toProtoUser(user)); err != nil {
+		return nil, err
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to commit transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
 	// Log success
-	log.InfoContext(ctx, s.logger, "User updated successfully", "id", user.ID)
+	log.InfoContext(ctx, s.logger, "User undeleted successfully", "id", user.ID)
 
 	// Prepare response
-	return connect.NewResponse(&expensesv1.UpdateUserResponse{
+	return connect.NewResponse(&expensesv1.UndeleteUserResponse{
 		User: toProtoUser(user),
 	}), nil
 }
 
-// DeleteUser deletes a user by ID
-func (s *UserService) DeleteUser(ctx context.Context, req *connect.Request[expensesv1.DeleteUserRequest]) (*connect.Response[expensesv1.DeleteUserResponse], error) {
+// BatchCreateUsers creates many users in one transaction
+func (s *UserService) BatchCreateUsers(ctx context.Context, req *connect.Request[expensesv1.BatchCreateUsersRequest]) (*connect.Response[expensesv1.BatchCreateUsersResponse], error) {
 	// Log method entry
-	log.InfoContext(ctx, s.logger, "Deleting user", "id", req.Msg.Id)
+	log.InfoContext(ctx, s.logger, "Batch creating users", "count", len(req.Msg.Requests), "allow_partial", req.Msg.AllowPartial)
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Create each user within the transaction
+	var users []*expensesv1.User
+	failures, err := runBatch(ctx, s.logger, tx, req.Msg.Requests, req.Msg.AllowPartial, func(msg *expensesv1.CreateUserRequest) error {
+		user, err := s.createUser(ctx, tx, msg)
+		if err != nil {
+			return err
+		}
+		users = append(users, toProtoUser(user))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Validate input
-	if req.Msg.Id == "" {
-		log.ErrorContext(ctx, s.logger, "Invalid input for DeleteUser", "error", "id is required")
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w: id is required", errors.ErrInvalidInput))
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
+	// Log success
+	log.InfoContext(ctx, s.logger, "Users batch created successfully", "created", len(users), "failed", len(failures))
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.BatchCreateUsersResponse{
+		Users:  users,
+		Errors: failures,
+	}), nil
+}
+
+// BatchUpdateUsers updates many users in one transaction
+func (s *UserService) BatchUpdateUsers(ctx context.Context, req *connect.Request[expensesv1.BatchUpdateUsersRequest]) (*connect.Response[expensesv1.BatchUpdateUsersResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Batch updating users", "count", len(req.Msg.Requests), "allow_partial", req.Msg.AllowPartial)
+
 	// Begin transaction
 	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
 	if err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to begin transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
 	}
 	defer func() {
 		if err := tx.Rollback(); err != nil {
@@ -269,39 +424,185 @@ func (s *UserService) DeleteUser(ctx context.Context, req *connect.Request[expen
 		}
 	}() // Rollback if any error occurs
 
-	// Check if user exists within the transaction
-	_, err = s.repo.GetUser(ctx, tx, req.Msg.Id)
-	if err != nil {
-		if stderrors.Is(err, errors.ErrNotFound) {
-			log.ErrorContext(ctx, s.logger, "User not found", "id", req.Msg.Id)
-			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("%w: user with id %s not found", errors.ErrNotFound, req.Msg.Id))
+	// Update each user within the transaction
+	var users []*expensesv1.User
+	failures, err := runBatch(ctx, s.logger, tx, req.Msg.Requests, req.Msg.AllowPartial, func(msg *expensesv1.UpdateUserRequest) error {
+		user, err := s.updateUser(ctx, tx, msg)
+		if err != nil {
+			return err
 		}
-		log.ErrorContext(ctx, s.logger, "Failed to check if user exists", "id", req.Msg.Id, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		users = append(users, toProtoUser(user))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Users batch updated successfully", "updated", len(users), "failed", len(failures))
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.BatchUpdateUsersResponse{
+		Users:  users,
+		Errors: failures,
+	}), nil
+}
+
+// BatchDeleteUsers moves many users to the trash in one transaction
+func (s *UserService) BatchDeleteUsers(ctx context.Context, req *connect.Request[expensesv1.BatchDeleteUsersRequest]) (*connect.Response[expensesv1.BatchDeleteUsersResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Batch deleting users", "count", len(req.Msg.Requests), "allow_partial", req.Msg.AllowPartial)
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
 	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
 
-	// Delete user from database within the transaction
-	err = s.repo.DeleteUser(ctx, tx, req.Msg.Id)
+	// Move each user to the trash within the transaction
+	var ids []string
+	failures, err := runBatch(ctx, s.logger, tx, req.Msg.Requests, req.Msg.AllowPartial, func(msg *expensesv1.DeleteUserRequest) error {
+		if err := s.deleteUser(ctx, tx, msg); err != nil {
+			return err
+		}
+		ids = append(ids, msg.Id)
+		return nil
+	})
 	if err != nil {
-		log.ErrorContext(ctx, s.logger, "Failed to delete user", "id", req.Msg.Id, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: %v", errors.ErrInternal, err))
+		return nil, err
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("%w: failed to commit transaction: %v", errors.ErrInternal, err))
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
 	// Log success
-	log.InfoContext(ctx, s.logger, "User deleted successfully", "id", req.Msg.Id)
+	log.InfoContext(ctx, s.logger, "Users batch deleted successfully", "deleted", len(ids), "failed", len(failures))
 
 	// Prepare response
-	return connect.NewResponse(&expensesv1.DeleteUserResponse{
-		Success: true,
+	return connect.NewResponse(&expensesv1.BatchDeleteUsersResponse{
+		Ids:    ids,
+		Errors: failures,
 	}), nil
 }
 
+// createUser creates a user, recording it in the audit log
+func (s *UserService) createUser(ctx context.Context, dbtx db.DBTX, msg *expensesv1.CreateUserRequest) (db.User, error) {
+	user, err := s.repo.CreateUser(ctx, dbtx, db.CreateUserParams{
+		Name:    msg.Name,
+		Email:   msg.Email,
+		IsAdmin: msg.Admin,
+	})
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "User already exists", "email", msg.Email)
+			return db.User{}, errors.AlreadyExists("user", msg.Email, "email", "user with email %s already exists", msg.Email)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to create user", "error", err)
+		return db.User{}, storageError(err)
+	}
+
+	// Record the mutation in the audit log
+	if err := s.auditor.record(ctx, dbtx, "user", user.ID, audit.ActionCreate, nil, toProtoUser(user)); err != nil {
+		return db.User{}, err
+	}
+	return user, nil
+}
+
+// updateUser updates the fields of a user selected by the update mask,
+// recording the change in the audit log
+func (s *UserService) updateUser(ctx context.Context, dbtx db.DBTX, msg *expensesv1.UpdateUserRequest) (db.User, error) {
+	// Validate the fields the update mask selects, which the validation
+	// interceptor cannot tell are required
+	mask, err := parseUpdateMask(ctx, s.logger, msg.UpdateMask, userUpdatePaths)
+	if err != nil {
+		return db.User{}, err
+	}
+	if mask.has("name") && msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateUser", "error", "name is required")
+		return db.User{}, errors.Required("name")
+	}
+	if mask.has("email") && msg.Email == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for UpdateUser", "error", "email is required")
+		return db.User{}, errors.Required("email")
+	}
+
+	// Check if user exists
+	existing, err := s.repo.GetUser(ctx, dbtx, msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "User not found", "id", msg.Id)
+			return db.User{}, errors.NotFound("user", msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to check if user exists", "id", msg.Id, "error", err)
+		return db.User{}, storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "user", existing.ID, msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return db.User{}, err
+	}
+
+	// Update the masked fields of the user
+	user, err := s.repo.UpdateUser(ctx, dbtx, msg.Id, existing.Revision, mask.pick(map[string]any{
+		"name":  msg.Name,
+		"email": msg.Email,
+	}))
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "User with email already exists", "email", msg.Email)
+			return db.User{}, errors.AlreadyExists("user", msg.Email, "email", "user with email %s already exists", msg.Email)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to update user", "id", msg.Id, "error", err)
+		return db.User{}, storageError(err)
+	}
+
+	// Record the mutation in the audit log
+	if err := s.auditor.record(ctx, dbtx, "user", user.ID, audit.ActionUpdate, toProtoUser(existing), toProtoUser(user)); err != nil {
+		return db.User{}, err
+	}
+	return user, nil
+}
+
+// deleteUser moves a user to the trash, recording it in the audit log
+func (s *UserService) deleteUser(ctx context.Context, dbtx db.DBTX, msg *expensesv1.DeleteUserRequest) error {
+	// Check if user exists
+	existing, err := s.repo.GetUser(ctx, dbtx, msg.Id)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "User not found", "id", msg.Id)
+			return errors.NotFound("user", msg.Id)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to check if user exists", "id", msg.Id, "error", err)
+		return storageError(err)
+	}
+	if err := checkETag(ctx, s.logger, "user", existing.ID, msg.Etag, existing.Revision, existing.UpdatedAt); err != nil {
+		return err
+	}
+
+	// Move the user to the trash
+	if err := s.repo.DeleteUser(ctx, dbtx, msg.Id, existing.Revision, s.clock.Now().UTC()); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to delete user", "id", msg.Id, "error", err)
+		return storageError(err)
+	}
+
+	// Record the mutation in the audit log
+	return s.auditor.record(ctx, dbtx, "user", existing.ID, audit.ActionDelete, toProtoUser(existing), nil)
+}
+
 // toProtoUser converts a db.User to a expensesv1.User
 func toProtoUser(user db.User) *expensesv1.User {
 	return &expensesv1.User{
@@ -310,5 +611,8 @@ func toProtoUser(user db.User) *expensesv1.User {
 		Email:     user.Email,
 		CreatedAt: timestamppb.New(user.CreatedAt),
 		UpdatedAt: timestamppb.New(user.UpdatedAt),
+		Etag:      etag.Compute(user.Revision, user.UpdatedAt),
+		DeletedAt: timestampOrNil(user.DeletedAt),
+		Admin:     user.IsAdmin,
 	}
 }
@@ -2,10 +2,20 @@ package services
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/pagination"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
 	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 // TestCreateUser tests the CreateUser RPC method
@@ -14,7 +24,7 @@ func TestCreateUser(t *testing.T) {
 	resetTestDB(t)
 
 	// Create a new UserService with the test repositories
-	service := NewUserService(userRepo, testClock, testLogger)
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
 
 	// Define test cases
 	tests := []struct {
@@ -31,24 +41,6 @@ func TestCreateUser(t *testing.T) {
 			},
 			expectError: false,
 		},
-		{
-			name: "Missing name",
-			request: &expensesv1.CreateUserRequest{
-				Name:  "",
-				Email: "no-name@example.com",
-			},
-			expectError: true,
-			errorMsg:    "name is required",
-		},
-		{
-			name: "Missing email",
-			request: &expensesv1.CreateUserRequest{
-				Name:  "No Email User",
-				Email: "",
-			},
-			expectError: true,
-			errorMsg:    "email is required",
-		},
 		{
 			name: "Duplicate email",
 			request: &expensesv1.CreateUserRequest{
@@ -106,7 +98,7 @@ func TestGetUser(t *testing.T) {
 	resetTestDB(t)
 
 	// Create a new UserService with the test repositories
-	service := NewUserService(userRepo, testClock, testLogger)
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
 
 	// Create a test user (using the main DB connection for setup)
 	testUser := createTestUser(t, testDB, "Get Test User", "get@example.com")
@@ -179,18 +171,23 @@ func TestListUsers(t *testing.T) {
 	resetTestDB(t)
 
 	// Create a new UserService with the test repositories
-	service := NewUserService(userRepo, testClock, testLogger)
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
 
 	// Create test users (using the main DB connection for setup)
 	_ = createTestUser(t, testDB, "List User 1", "list1@example.com")
-	_ = createTestUser(t, testDB, "List User 2", "list2@example.com")
-	_ = createTestUser(t, testDB, "List User 3", "list3@example.com")
+	user2 := createTestUser(t, testDB, "List User 2", "list2@example.com")
+	user3 := createTestUser(t, testDB, "List User 3", "list3@example.com")
+
+	// Page tokens continue after a user ordered by name
+	filter := pagination.Filter("users", "", "")
+	afterUser2 := pageTokenAfter(t, filter, user2.Name, user2.ID)
 
 	// Define test cases
 	tests := []struct {
 		name        string
 		pageSize    int32
 		pageToken   string
+		skipTotal   bool
 		expectedN   int
 		expectError bool
 	}{
@@ -209,31 +206,62 @@ func TestListUsers(t *testing.T) {
 		{
 			name:      "List with pagination (page 2)",
 			pageSize:  2,
-			pageToken: "2", // Start from offset 2
-			expectedN: 1,   // Only 1 user left
+			pageToken: afterUser2,
+			expectedN: 1, // Only 1 user left
 		},
 		{
 			name:      "Empty result",
 			pageSize:  2,
-			pageToken: "10", // Start from offset 10 (beyond available data)
+			pageToken: pageTokenAfter(t, filter, user3.Name, user3.ID),
 			expectedN: 0,
 		},
+		{
+			name:      "Skip total count",
+			pageSize:  2,
+			skipTotal: true,
+			expectedN: 2,
+		},
+		{
+			name:        "Raw offset token",
+			pageSize:    2,
+			pageToken:   "2",
+			expectError: true,
+		},
+		{
+			name:        "Token signed with another secret",
+			pageSize:    2,
+			pageToken:   pageTokenWith(t, "another-secret", time.Hour, filter, user2.Name, user2.ID),
+			expectError: true,
+		},
+		{
+			name:        "Token issued for another list",
+			pageSize:    2,
+			pageToken:   pageTokenAfter(t, pagination.Filter("instruments", "", ""), user2.Name, user2.ID),
+			expectError: true,
+		},
+		{
+			name:        "Expired token",
+			pageSize:    2,
+			pageToken:   pageTokenWith(t, "test-page-token-secret", -time.Minute, filter, user2.Name, user2.ID),
+			expectError: true,
+		},
 	}
 
 	// Run tests
 	ctx := context.Background()
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			var pagination *expensesv1.Pagination
+			var page *expensesv1.Pagination
 			if tc.pageSize > 0 || tc.pageToken != "" {
-				pagination = &expensesv1.Pagination{
-					PageSize:  tc.pageSize,
-					PageToken: tc.pageToken,
+				page = &expensesv1.Pagination{
+					PageSize:       tc.pageSize,
+					PageToken:      tc.pageToken,
+					SkipTotalCount: tc.skipTotal,
 				}
 			}
 
 			req := connect.NewRequest(&expensesv1.ListUsersRequest{
-				Pagination: pagination,
+				Pagination: page,
 			})
 
 			// Read operations can use the main DB connection
@@ -258,9 +286,171 @@ func TestListUsers(t *testing.T) {
 						t.Errorf("Expected next page token, got empty")
 					}
 				}
+
+				// The total counts every user regardless of the page, unless skipped
+				pageResponse := resp.Msg.PaginationResponse
+				if pageResponse.TotalCountUnavailable != tc.skipTotal {
+					t.Errorf("Expected total_count_unavailable=%v, got %v", tc.skipTotal, pageResponse.TotalCountUnavailable)
+				}
+				if !tc.skipTotal && pageResponse.TotalCount != 3 {
+					t.Errorf("Expected total count 3, got %d", pageResponse.TotalCount)
+				}
+			}
+		})
+	}
+}
+
+// TestListUsersFilter tests the filter and order_by parameters of the ListUsers RPC method
+func TestListUsersFilter(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new UserService with the test repositories
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Create test users (using the main DB connection for setup)
+	alice := createTestUser(t, testDB, "Alice", "alice@example.com")
+	bob := createTestUser(t, testDB, "Bob", "bob@work.example.com")
+	carol := createTestUser(t, testDB, "Carol", "carol@work.example.com")
+
+	// Define test cases
+	tests := []struct {
+		name          string
+		filter        string
+		orderBy       string
+		expectedIDs   []string
+		expectError   bool
+		expectInError string
+	}{
+		{
+			name:        "Case-insensitive wildcard match",
+			filter:      `email:"*@WORK.example.com"`,
+			expectedIDs: []string{bob.ID, carol.ID},
+		},
+		{
+			name:        "Wildcard equality",
+			filter:      `name = "A*"`,
+			expectedIDs: []string{alice.ID},
+		},
+		{
+			name:        "Negation and OR",
+			filter:      `-name = Alice AND (email:"bob*" OR email:"nobody*")`,
+			expectedIDs: []string{bob.ID},
+		},
+		{
+			name:        "Timestamp comparison",
+			filter:      `name:"*o*" AND created_at > "2000-01-01"`,
+			expectedIDs: []string{bob.ID, carol.ID},
+		},
+		{
+			name:        "Descending order",
+			orderBy:     "email desc",
+			expectedIDs: []string{carol.ID, bob.ID, alice.ID},
+		},
+		{
+			name:          "Unknown field",
+			filter:        `name = "Bob" AND password = "x"`,
+			expectError:   true,
+			expectInError: `unknown field "password" at position 18`,
+		},
+		{
+			name:          "Missing value",
+			filter:        `name =`,
+			expectError:   true,
+			expectInError: "at position 7",
+		},
+		{
+			name:          "Unbalanced parentheses",
+			filter:        `(name = "Bob"`,
+			expectError:   true,
+			expectInError: "expected ')' but found end of filter at position 14",
+		},
+		{
+			name:          "Invalid timestamp",
+			filter:        `created_at > "yesterday"`,
+			expectError:   true,
+			expectInError: "at position 14",
+		},
+		{
+			name:          "Unknown order_by field",
+			orderBy:       "name, id desc",
+			expectError:   true,
+			expectInError: `unknown field "id" at position 7`,
+		},
+		{
+			name:          "Invalid order_by direction",
+			orderBy:       "name sideways",
+			expectError:   true,
+			expectInError: "at position 6",
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.ListUsers(ctx, connect.NewRequest(&expensesv1.ListUsersRequest{
+				Filter:  tc.filter,
+				OrderBy: tc.orderBy,
+			}))
+
+			// Check errors
+			assertError(t, err, tc.expectError, "")
+			if tc.expectError {
+				if connect.CodeOf(err) != connect.CodeInvalidArgument {
+					t.Errorf("Expected code %v, got %v", connect.CodeInvalidArgument, connect.CodeOf(err))
+				}
+				if !strings.Contains(err.Error(), tc.expectInError) {
+					t.Errorf("Expected error to contain %q, got %v", tc.expectInError, err)
+				}
+				return
+			}
+
+			if len(resp.Msg.Users) != len(tc.expectedIDs) {
+				t.Fatalf("Expected %d users, got %d", len(tc.expectedIDs), len(resp.Msg.Users))
+			}
+			for i, user := range resp.Msg.Users {
+				if user.Id != tc.expectedIDs[i] {
+					t.Errorf("Expected user %d to be %s, got %s", i, tc.expectedIDs[i], user.Id)
+				}
+			}
+			if resp.Msg.PaginationResponse.TotalCount != int32(len(tc.expectedIDs)) {
+				t.Errorf("Expected total count %d, got %d", len(tc.expectedIDs), resp.Msg.PaginationResponse.TotalCount)
 			}
 		})
 	}
+
+	t.Run("Pages follow a custom order", func(t *testing.T) {
+		req := &expensesv1.ListUsersRequest{
+			Filter:     `email:"*example.com"`,
+			OrderBy:    "email desc",
+			Pagination: &expensesv1.Pagination{PageSize: 1},
+		}
+		var ids []string
+		for {
+			resp, err := service.ListUsers(ctx, connect.NewRequest(req))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, user := range resp.Msg.Users {
+				ids = append(ids, user.Id)
+			}
+			if resp.Msg.PaginationResponse.NextPageToken == "" {
+				break
+			}
+			req.Pagination.PageToken = resp.Msg.PaginationResponse.NextPageToken
+		}
+		expected := []string{carol.ID, bob.ID, alice.ID}
+		if strings.Join(ids, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected users %v across pages, got %v", expected, ids)
+		}
+
+		// A token cannot be replayed with a different order
+		req.OrderBy = "email"
+		if _, err := service.ListUsers(ctx, connect.NewRequest(req)); connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Errorf("Expected code %v for a token reused with another order, got %v", connect.CodeInvalidArgument, connect.CodeOf(err))
+		}
+	})
 }
 
 // TestUpdateUser tests the UpdateUser RPC method
@@ -269,7 +459,7 @@ func TestUpdateUser(t *testing.T) {
 	resetTestDB(t)
 
 	// Create a new UserService with the test repositories
-	service := NewUserService(userRepo, testClock, testLogger)
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
 
 	// Create test users (using the main DB connection for setup)
 	testUser := createTestUser(t, testDB, "Original Name", "original@example.com")
@@ -375,13 +565,480 @@ func TestUpdateUser(t *testing.T) {
 	}
 }
 
+// TestUpdateUserMask tests partial updates of UpdateUser with an update mask
+func TestUpdateUserMask(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new UserService with the test repositories
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Create a test user (using the main DB connection for setup)
+	testUser := createTestUser(t, testDB, "Original Name", "original@example.com")
+
+	// Define test cases, each applied on top of the previous ones
+	tests := []struct {
+		name          string
+		request       *expensesv1.UpdateUserRequest
+		expectError   bool
+		errorMsg      string
+		expectedName  string
+		expectedEmail string
+	}{
+		{
+			name: "Email only",
+			request: &expensesv1.UpdateUserRequest{
+				Id:         testUser.ID,
+				Email:      "masked@example.com",
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}},
+			},
+			expectedName:  "Original Name",
+			expectedEmail: "masked@example.com",
+		},
+		{
+			name: "Name only ignores unmasked fields",
+			request: &expensesv1.UpdateUserRequest{
+				Id:         testUser.ID,
+				Name:       "Masked Name",
+				Email:      "ignored@example.com",
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+			},
+			expectedName:  "Masked Name",
+			expectedEmail: "masked@example.com",
+		},
+		{
+			name: "Wildcard requires every field",
+			request: &expensesv1.UpdateUserRequest{
+				Id:         testUser.ID,
+				Name:       "Wildcard Name",
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"*"}},
+			},
+			expectError: true,
+			errorMsg:    "email is required",
+		},
+		{
+			name: "Masked field is still validated",
+			request: &expensesv1.UpdateUserRequest{
+				Id:         testUser.ID,
+				Name:       "Ignored Name",
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}},
+			},
+			expectError: true,
+			errorMsg:    "email is required",
+		},
+		{
+			name: "Unknown path",
+			request: &expensesv1.UpdateUserRequest{
+				Id:         testUser.ID,
+				Name:       "Updated Name",
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name", "password"}},
+			},
+			expectError: true,
+			errorMsg:    `unknown update_mask path "password"`,
+		},
+		{
+			name: "Immutable id",
+			request: &expensesv1.UpdateUserRequest{
+				Id:         testUser.ID,
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"id"}},
+			},
+			expectError: true,
+			errorMsg:    `update_mask path "id" is immutable`,
+		},
+		{
+			name: "Immutable created_at",
+			request: &expensesv1.UpdateUserRequest{
+				Id:         testUser.ID,
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"created_at"}},
+			},
+			expectError: true,
+			errorMsg:    `update_mask path "created_at" is immutable`,
+		},
+	}
+
+	// Run tests
+	ctx := context.Background()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.UpdateUser(ctx, connect.NewRequest(tc.request))
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.errorMsg)
+			if tc.expectError {
+				if connect.CodeOf(err) != connect.CodeInvalidArgument {
+					t.Errorf("Expected code %v, got %v", connect.CodeInvalidArgument, connect.CodeOf(err))
+				}
+				return
+			}
+
+			// Verify only the masked fields changed
+			if resp.Msg.User.Name != tc.expectedName {
+				t.Errorf("Expected name=%s, got %s", tc.expectedName, resp.Msg.User.Name)
+			}
+			if resp.Msg.User.Email != tc.expectedEmail {
+				t.Errorf("Expected email=%s, got %s", tc.expectedEmail, resp.Msg.User.Email)
+			}
+		})
+	}
+}
+
+// TestUserETag tests optimistic concurrency control of user updates and
+// deletions with etags
+func TestUserETag(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new UserService with the test repositories
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Create a test user (using the main DB connection for setup)
+	testUser := createTestUser(t, testDB, "ETag User", "etag@example.com")
+
+	// Read the user and its etag, as two clients would
+	ctx := context.Background()
+	got, err := service.GetUser(ctx, connect.NewRequest(&expensesv1.GetUserRequest{Id: testUser.ID}))
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	readETag := got.Msg.User.Etag
+	if readETag == "" {
+		t.Fatalf("Expected an etag on the user")
+	}
+	if header := got.Header().Get("ETag"); header != readETag {
+		t.Errorf("Expected ETag header=%s, got %s", readETag, header)
+	}
+
+	// The first client updates with the etag it read
+	updated, err := service.UpdateUser(ctx, connect.NewRequest(&expensesv1.UpdateUserRequest{
+		Id:         testUser.ID,
+		Name:       "First Writer",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+		Etag:       readETag,
+	}))
+	if err != nil {
+		t.Fatalf("Failed to update user with current etag: %v", err)
+	}
+	if updated.Msg.User.Etag == readETag {
+		t.Errorf("Expected the etag to change after an update")
+	}
+
+	// Define the writes of the second client, still holding the etag it read
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{
+			name: "Stale update",
+			call: func() error {
+				_, err := service.UpdateUser(ctx, connect.NewRequest(&expensesv1.UpdateUserRequest{
+					Id:         testUser.ID,
+					Name:       "Second Writer",
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+					Etag:       readETag,
+				}))
+				return err
+			},
+		},
+		{
+			name: "Stale delete",
+			call: func() error {
+				_, err := service.DeleteUser(ctx, connect.NewRequest(&expensesv1.DeleteUserRequest{
+					Id:   testUser.ID,
+					Etag: readETag,
+				}))
+				return err
+			},
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			assertError(t, err, true, "stale etag")
+			if connect.CodeOf(err) != connect.CodeAborted {
+				t.Errorf("Expected code %v, got %v", connect.CodeAborted, connect.CodeOf(err))
+			}
+		})
+	}
+
+	// The first write survived and a delete with the current etag succeeds
+	got, err = service.GetUser(ctx, connect.NewRequest(&expensesv1.GetUserRequest{Id: testUser.ID}))
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if got.Msg.User.Name != "First Writer" {
+		t.Errorf("Expected name=First Writer, got %s", got.Msg.User.Name)
+	}
+	if _, err := service.DeleteUser(ctx, connect.NewRequest(&expensesv1.DeleteUserRequest{
+		Id:   testUser.ID,
+		Etag: got.Msg.User.Etag,
+	})); err != nil {
+		t.Errorf("Failed to delete user with current etag: %v", err)
+	}
+}
+
+// TestUserConcurrentWrites tests that writes based on the same revision of a
+// user cannot both apply, even when they read it before either one commits
+func TestUserConcurrentWrites(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new UserService with the test repositories
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Create a test user (using the main DB connection for setup)
+	testUser := createTestUser(t, testDB, "Concurrent User", "concurrent@example.com")
+	ctx := context.Background()
+	got, err := service.GetUser(ctx, connect.NewRequest(&expensesv1.GetUserRequest{Id: testUser.ID}))
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+
+	// Update the user from several clients holding the same etag at once
+	const writers = 4
+	errs := make([]error, writers)
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = service.UpdateUser(ctx, connect.NewRequest(&expensesv1.UpdateUserRequest{
+				Id:         testUser.ID,
+				Name:       fmt.Sprintf("Writer %d", i),
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+				Etag:       got.Msg.User.Etag,
+			}))
+		}()
+	}
+	wg.Wait()
+
+	applied := 0
+	for _, err := range errs {
+		if err == nil {
+			applied++
+			continue
+		}
+		if connect.CodeOf(err) != connect.CodeAborted {
+			t.Errorf("Expected code %v, got %v", connect.CodeAborted, err)
+		}
+	}
+	if applied != 1 {
+		t.Errorf("Expected exactly 1 update to apply, got %d", applied)
+	}
+	current, err := userRepo.GetUser(ctx, testDB, testUser.ID)
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if current.Revision != testUser.Revision+1 {
+		t.Errorf("Expected revision=%d, got %d", testUser.Revision+1, current.Revision)
+	}
+
+	// Define the writes of a client that read the user before the update
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{
+			name: "Update at a stale revision",
+			call: func() error {
+				_, err := userRepo.UpdateUser(ctx, testDB, testUser.ID, testUser.Revision, map[string]any{"name": "Stale Writer"})
+				return err
+			},
+		},
+		{
+			name: "Delete at a stale revision",
+			call: func() error {
+				return userRepo.DeleteUser(ctx, testDB, testUser.ID, testUser.Revision, testClock.Now())
+			},
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			if !stderrors.Is(err, errors.ErrConflict) {
+				t.Fatalf("Expected a conflict, got %v", err)
+			}
+			if code := connect.CodeOf(storageError(err)); code != connect.CodeAborted {
+				t.Errorf("Expected code %v, got %v", connect.CodeAborted, code)
+			}
+		})
+	}
+}
+
+// TestListDeletedUsers tests that deleted users move from ListUsers to the
+// ListDeletedUsers RPC method
+func TestListDeletedUsers(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new UserService with the test repositories
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Create test users (using the main DB connection for setup) and move two
+	// of them to the trash
+	kept := createTestUser(t, testDB, "Kept User", "kept@example.com")
+	trashed1 := createTestUser(t, testDB, "Trashed User 1", "trashed1@example.com")
+	trashed2 := createTestUser(t, testDB, "Trashed User 2", "trashed2@example.com")
+	ctx := context.Background()
+	for _, user := range []string{trashed1.ID, trashed2.ID} {
+		if _, err := service.DeleteUser(ctx, connect.NewRequest(&expensesv1.DeleteUserRequest{Id: user})); err != nil {
+			t.Fatalf("Failed to delete user: %v", err)
+		}
+	}
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		deleted     bool
+		filter      string
+		expectedIDs []string
+	}{
+		{
+			name:        "List excludes deleted users",
+			expectedIDs: []string{kept.ID},
+		},
+		{
+			name:        "List deleted users",
+			deleted:     true,
+			expectedIDs: []string{trashed1.ID, trashed2.ID},
+		},
+		{
+			name:        "Filter deleted users",
+			deleted:     true,
+			filter:      `email = "trashed2@example.com"`,
+			expectedIDs: []string{trashed2.ID},
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Read operations can use the main DB connection
+			var users []*expensesv1.User
+			if tc.deleted {
+				resp, err := service.ListDeletedUsers(ctx, connect.NewRequest(&expensesv1.ListDeletedUsersRequest{Filter: tc.filter}))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				users = resp.Msg.Users
+			} else {
+				resp, err := service.ListUsers(ctx, connect.NewRequest(&expensesv1.ListUsersRequest{Filter: tc.filter}))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				users = resp.Msg.Users
+			}
+
+			if len(users) != len(tc.expectedIDs) {
+				t.Fatalf("Expected %d users, got %d", len(tc.expectedIDs), len(users))
+			}
+			for i, user := range users {
+				if user.Id != tc.expectedIDs[i] {
+					t.Errorf("Expected user %d to be %s, got %s", i, tc.expectedIDs[i], user.Id)
+				}
+				if (user.DeletedAt != nil) != tc.deleted {
+					t.Errorf("Expected deleted_at set=%v, got %v", tc.deleted, user.DeletedAt)
+				}
+			}
+		})
+	}
+}
+
+// TestUndeleteUser tests the UndeleteUser RPC method
+func TestUndeleteUser(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+
+	// Create a new UserService with the test repositories
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Create test users (using the main DB connection for setup) and move one
+	// of them to the trash, keeping the etag it had before
+	live := createTestUser(t, testDB, "Live User", "live@example.com")
+	trashed := createTestUser(t, testDB, "Trashed User", "trashed@example.com")
+	ctx := context.Background()
+	if _, err := service.DeleteUser(ctx, connect.NewRequest(&expensesv1.DeleteUserRequest{Id: trashed.ID})); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	staleETag := toProtoUser(trashed).Etag
+
+	// Define test cases, run in order
+	tests := []struct {
+		name        string
+		userID      string
+		etag        string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Empty user ID",
+			userID:      "",
+			expectError: true,
+			errorMsg:    "id is required",
+		},
+		{
+			name:        "User not in the trash",
+			userID:      live.ID,
+			expectError: true,
+			errorMsg:    "not found",
+		},
+		{
+			name:        "Etag from before the deletion",
+			userID:      trashed.ID,
+			etag:        staleETag,
+			expectError: true,
+			errorMsg:    "stale etag",
+		},
+		{
+			name:   "Valid undelete",
+			userID: trashed.ID,
+		},
+		{
+			name:        "Already restored",
+			userID:      trashed.ID,
+			expectError: true,
+			errorMsg:    "not found",
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := service.UndeleteUser(ctx, connect.NewRequest(&expensesv1.UndeleteUserRequest{
+				Id:   tc.userID,
+				Etag: tc.etag,
+			}))
+
+			// Check errors
+			assertError(t, err, tc.expectError, tc.errorMsg)
+
+			// Verify the user is back with its fields intact
+			if !tc.expectError {
+				if resp.Msg.User.DeletedAt != nil {
+					t.Errorf("Expected deleted_at to be cleared, got %v", resp.Msg.User.DeletedAt)
+				}
+				got, err := service.GetUser(ctx, connect.NewRequest(&expensesv1.GetUserRequest{Id: tc.userID}))
+				if err != nil {
+					t.Fatalf("Failed to get undeleted user: %v", err)
+				}
+				if got.Msg.User.Email != trashed.Email {
+					t.Errorf("Expected email=%s, got %s", trashed.Email, got.Msg.User.Email)
+				}
+			}
+		})
+	}
+}
+
 // TestDeleteUser tests the DeleteUser RPC method
 func TestDeleteUser(t *testing.T) {
 	// Reset the test database
 	resetTestDB(t)
 
 	// Create a new UserService with the test repositories
-	service := NewUserService(userRepo, testClock, testLogger)
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
 
 	// Create a test user (using the main DB connection for setup)
 	testUser := createTestUser(t, testDB, "Delete Test User", "delete@example.com")
@@ -443,9 +1100,9 @@ func TestDeleteUser(t *testing.T) {
 					t.Errorf("Expected success=true, got false")
 				}
 
-				// Verify the user was actually deleted
+				// Verify the user was moved to the trash
 				var count int
-				countErr := testDB.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", tc.userID).Scan(&count)
+				countErr := testDB.QueryRow(testDB.Rebind("SELECT COUNT(*) FROM users WHERE id = ? AND deleted_at IS NULL"), tc.userID).Scan(&count)
 				if countErr != nil {
 					t.Fatalf("Failed to query deleted user: %v", countErr)
 				}
@@ -457,3 +1114,118 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+// TestBatchUsers tests the per-request errors of the batch RPC methods of
+// users
+func TestBatchUsers(t *testing.T) {
+	// Create a new UserService with the test repositories
+	service := NewUserService(userRepo, auditRepo, testPages, testClock, testLogger)
+
+	// Define test cases, each against two existing users
+	ctx := context.Background()
+	tests := []struct {
+		name        string
+		call        func(alice, bob db.User) (int, []*expensesv1.BatchError, error)
+		expectCode  connect.Code
+		expectCount int
+		expectCodes []string
+		expectRows  int
+	}{
+		{
+			name: "Create partially",
+			call: func(alice, _ db.User) (int, []*expensesv1.BatchError, error) {
+				resp, err := service.BatchCreateUsers(ctx, connect.NewRequest(&expensesv1.BatchCreateUsersRequest{
+					Requests: []*expensesv1.CreateUserRequest{
+						{Name: "Carol", Email: "carol@example.com"},
+						{Name: "Invalid", Email: "not-an-email"},
+						{Name: "Copy", Email: alice.Email},
+					},
+					AllowPartial: true,
+				}))
+				if err != nil {
+					return 0, nil, err
+				}
+				return len(resp.Msg.Users), resp.Msg.Errors, nil
+			},
+			expectCount: 1,
+			expectCodes: []string{"invalid_argument", "already_exists"},
+			expectRows:  3,
+		},
+		{
+			name: "Update with a duplicate email rolls back the batch",
+			call: func(alice, bob db.User) (int, []*expensesv1.BatchError, error) {
+				_, err := service.BatchUpdateUsers(ctx, connect.NewRequest(&expensesv1.BatchUpdateUsersRequest{
+					Requests: []*expensesv1.UpdateUserRequest{
+						{Id: alice.ID, Email: "alice@elsewhere.example.com", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}}},
+						{Id: bob.ID, Email: "alice@elsewhere.example.com", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}}},
+					},
+				}))
+				return 0, nil, err
+			},
+			expectCode: connect.CodeAlreadyExists,
+			expectRows: 2,
+		},
+		{
+			name: "Delete all",
+			call: func(alice, bob db.User) (int, []*expensesv1.BatchError, error) {
+				resp, err := service.BatchDeleteUsers(ctx, connect.NewRequest(&expensesv1.BatchDeleteUsersRequest{
+					Requests: []*expensesv1.DeleteUserRequest{{Id: alice.ID}, {Id: bob.ID}},
+				}))
+				if err != nil {
+					return 0, nil, err
+				}
+				return len(resp.Msg.Ids), resp.Msg.Errors, nil
+			},
+			expectCount: 2,
+			expectRows:  0,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reset the test database and create the existing users
+			resetTestDB(t)
+			alice := createTestUser(t, testDB, "Alice", "alice@example.com")
+			bob := createTestUser(t, testDB, "Bob", "bob@example.com")
+
+			count, failures, err := tc.call(alice, bob)
+
+			if tc.expectCode != 0 {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Fatalf("Expected code %v, got %v", tc.expectCode, err)
+				}
+				if reason := errorReason(t, err); reason != errors.ReasonBatchFailed {
+					t.Errorf("Expected reason %s, got %s", errors.ReasonBatchFailed, reason)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if count != tc.expectCount {
+					t.Errorf("Expected %d successes, got %d", tc.expectCount, count)
+				}
+				codes := make([]string, len(failures))
+				for i, failure := range failures {
+					codes[i] = failure.Code
+					if len(failure.Details) == 0 {
+						t.Errorf("Expected the failure at %d to carry details", failure.Index)
+					}
+				}
+				if !slices.Equal(codes, tc.expectCodes) {
+					t.Errorf("Expected failures of %v, got %v", tc.expectCodes, codes)
+				}
+			}
+
+			// Verify the users left by the batch, which keep their emails when
+			// the batch is rolled back
+			var rows int
+			if err := testDB.QueryRow("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND email LIKE '%@example.com'").Scan(&rows); err != nil {
+				t.Fatalf("Failed to count users: %v", err)
+			}
+			if rows != tc.expectRows {
+				t.Errorf("Expected %d users, got %d", tc.expectRows, rows)
+			}
+		})
+	}
+}
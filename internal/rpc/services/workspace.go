@@ -0,0 +1,373 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/clock"
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	db "github.com/atreya2011/expense-manager/internal/repo/gen"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// WorkspaceService implements the WorkspaceService interface defined in the proto
+type WorkspaceService struct {
+	expensesv1connect.UnimplementedWorkspaceServiceHandler
+	repo     *repo.WorkspaceRepo
+	userRepo *repo.UserRepo
+	auditor  auditor
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+// NewWorkspaceService creates a new WorkspaceService
+func NewWorkspaceService(
+	repo *repo.WorkspaceRepo,
+	userRepo *repo.UserRepo,
+	auditRepo *repo.AuditRepo,
+	clock clock.Clock,
+	logger *slog.Logger,
+) *WorkspaceService {
+	return &WorkspaceService{
+		repo:     repo,
+		userRepo: userRepo,
+		auditor:  newAuditor(auditRepo, clock, logger),
+		clock:    clock,
+		logger:   logger,
+	}
+}
+
+// CreateWorkspace creates a new workspace with the authenticated user as its
+// admin
+func (s *WorkspaceService) CreateWorkspace(ctx context.Context, req *connect.Request[expensesv1.CreateWorkspaceRequest]) (*connect.Response[expensesv1.CreateWorkspaceResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Creating workspace", "name", req.Msg.Name)
+
+	// Validate input
+	identity, err := s.identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Msg.Name == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for CreateWorkspace", "error", "name is required")
+		return nil, errors.Required("name")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Create the workspace and make its creator its admin within the transaction
+	workspace, err := s.repo.CreateWorkspace(ctx, tx, req.Msg.Name)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to create workspace", "error", err)
+		return nil, storageError(err)
+	}
+	if _, err := s.repo.AddWorkspaceUser(ctx, tx, db.AddWorkspaceUserParams{
+		WorkspaceID: workspace.ID,
+		UserID:      identity.UserID,
+		IsAdmin:     true,
+	}); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to add workspace admin", "id", workspace.ID, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log of the new workspace within the
+	// transaction
+	if err := s.auditor.recordIn(ctx, tx, workspace.ID, "workspace", workspace.ID, audit.ActionCreate, nil, toProtoWorkspace(workspace, true)); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Workspace created successfully", "id", workspace.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.CreateWorkspaceResponse{
+		Workspace: toProtoWorkspace(workspace, true),
+	}), nil
+}
+
+// ListWorkspaces retrieves the workspaces of the authenticated user
+func (s *WorkspaceService) ListWorkspaces(ctx context.Context, req *connect.Request[expensesv1.ListWorkspacesRequest]) (*connect.Response[expensesv1.ListWorkspacesResponse], error) {
+	// Log method entry
+	log.InfoContext(ctx, s.logger, "Listing workspaces")
+
+	identity, err := s.identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get workspaces from database (read operations can use the main DB connection)
+	rows, err := s.repo.ListWorkspacesForUser(ctx, s.repo.GetDB(), identity.UserID)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list workspaces", "error", err)
+		return nil, storageError(err)
+	}
+
+	// Convert to proto messages
+	workspaces := make([]*expensesv1.Workspace, len(rows))
+	for i, row := range rows {
+		workspaces[i] = toProtoWorkspace(row.Workspace, row.IsAdmin)
+	}
+
+	log.InfoContext(ctx, s.logger, "Workspaces listed successfully", "count", len(workspaces))
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.ListWorkspacesResponse{
+		Workspaces: workspaces,
+	}), nil
+}
+
+// InviteUser adds the user with an email to the workspace of the request,
+// creating the user if no user has the email
+func (s *WorkspaceService) InviteUser(ctx context.Context, req *connect.Request[expensesv1.InviteUserRequest]) (*connect.Response[expensesv1.InviteUserResponse], error) {
+	// Log method entry
+	workspaceID := authz.WorkspaceFrom(ctx)
+	log.InfoContext(ctx, s.logger, "Inviting user", "workspace_id", workspaceID, "email", req.Msg.Email, "admin", req.Msg.Admin)
+
+	// Validate input
+	if req.Msg.Email == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for InviteUser", "error", "email is required")
+		return nil, errors.Required("email")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Look up the invited user, creating it if no user has the email
+	user, err := s.userRepo.GetUserByEmail(ctx, tx, req.Msg.Email)
+	switch {
+	case stderrors.Is(err, errors.ErrNotFound):
+		if req.Msg.Name == "" {
+			log.ErrorContext(ctx, s.logger, "Invalid input for InviteUser", "error", "name is required to create a user", "email", req.Msg.Email)
+			return nil, errors.Error(connect.CodeInvalidArgument, errors.ReasonRequiredField, fmt.Errorf("%w: no user has email %s, and name is required to create one", errors.ErrInvalidInput, req.Msg.Email),
+				errors.BadRequest(errors.FieldViolation("name", errors.ReasonRequiredField, "name is required to create a user")))
+		}
+		user, err = s.userRepo.CreateUser(ctx, tx, db.CreateUserParams{Name: req.Msg.Name, Email: req.Msg.Email})
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to create user", "email", req.Msg.Email, "error", err)
+			return nil, storageError(err)
+		}
+		if err := s.auditor.record(ctx, tx, "user", user.ID, audit.ActionCreate, nil, toProtoUser(user)); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		log.ErrorContext(ctx, s.logger, "Failed to get user", "email", req.Msg.Email, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Add the user to the workspace within the transaction
+	member, err := s.repo.AddWorkspaceUser(ctx, tx, db.AddWorkspaceUserParams{
+		WorkspaceID: workspaceID,
+		UserID:      user.ID,
+		IsAdmin:     req.Msg.Admin,
+	})
+	if err != nil {
+		if stderrors.Is(err, errors.ErrDuplicate) {
+			log.ErrorContext(ctx, s.logger, "User already belongs to workspace", "workspace_id", workspaceID, "user_id", user.ID)
+			return nil, errors.AlreadyExists("workspace_user", user.ID, "email", "user %s already belongs to this workspace", user.ID)
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to add workspace user", "workspace_id", workspaceID, "user_id", user.ID, "error", err)
+		return nil, storageError(err)
+	}
+	workspaceUser := toProtoWorkspaceUser(user, member.IsAdmin, member.CreatedAt)
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "workspace_user", user.ID, audit.ActionCreate, nil, workspaceUser); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "User invited successfully", "workspace_id", workspaceID, "user_id", user.ID)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.InviteUserResponse{
+		WorkspaceUser: workspaceUser,
+	}), nil
+}
+
+// RemoveWorkspaceUser removes a user from the workspace of the request and
+// from every account of it, keeping at least one admin
+func (s *WorkspaceService) RemoveWorkspaceUser(ctx context.Context, req *connect.Request[expensesv1.RemoveWorkspaceUserRequest]) (*connect.Response[expensesv1.RemoveWorkspaceUserResponse], error) {
+	// Log method entry
+	workspaceID := authz.WorkspaceFrom(ctx)
+	log.InfoContext(ctx, s.logger, "Removing workspace user", "workspace_id", workspaceID, "user_id", req.Msg.UserId)
+
+	// Validate input
+	if req.Msg.UserId == "" {
+		log.ErrorContext(ctx, s.logger, "Invalid input for RemoveWorkspaceUser", "error", "user_id is required")
+		return nil, errors.Required("user_id")
+	}
+
+	// Begin transaction
+	tx, err := s.repo.GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to begin transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Cannot return error from defer, just log it
+			log.ErrorContext(ctx, s.logger, "Failed to rollback transaction", "error", err)
+		}
+	}() // Rollback if any error occurs
+
+	// Get the membership being removed within the transaction
+	member, err := s.repo.GetWorkspaceUser(ctx, tx, workspaceID, req.Msg.UserId)
+	if err != nil {
+		if stderrors.Is(err, errors.ErrNotFound) {
+			log.ErrorContext(ctx, s.logger, "Workspace user not found", "workspace_id", workspaceID, "user_id", req.Msg.UserId)
+			return nil, errors.Error(connect.CodeNotFound, errors.ReasonNotFound, fmt.Errorf("%w: user %s does not belong to this workspace", errors.ErrNotFound, req.Msg.UserId),
+				errors.Resource("workspace_user", req.Msg.UserId))
+		}
+		log.ErrorContext(ctx, s.logger, "Failed to get workspace user", "workspace_id", workspaceID, "user_id", req.Msg.UserId, "error", err)
+		return nil, storageError(err)
+	}
+	user, err := s.userRepo.GetUser(ctx, tx, req.Msg.UserId)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to get user", "id", req.Msg.UserId, "error", err)
+		return nil, storageError(err)
+	}
+
+	// A workspace without admins could no longer be managed
+	if member.IsAdmin {
+		admins, err := s.repo.CountWorkspaceAdmins(ctx, tx, workspaceID)
+		if err != nil {
+			log.ErrorContext(ctx, s.logger, "Failed to count workspace admins", "workspace_id", workspaceID, "error", err)
+			return nil, storageError(err)
+		}
+		if admins <= 1 {
+			log.ErrorContext(ctx, s.logger, "Cannot remove the last workspace admin", "workspace_id", workspaceID, "user_id", req.Msg.UserId)
+			return nil, errors.Error(connect.CodeFailedPrecondition, errors.ReasonLastAdmin, fmt.Errorf("%w: user %s is the last admin of this workspace", errors.ErrInvalidInput, req.Msg.UserId),
+				errors.Resource("workspace_user", req.Msg.UserId))
+		}
+	}
+
+	// Remove the user from the workspace and its accounts within the transaction
+	if err := s.repo.RemoveWorkspaceUser(ctx, tx, workspaceID, req.Msg.UserId); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to remove workspace user", "workspace_id", workspaceID, "user_id", req.Msg.UserId, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Record the mutation in the audit log within the transaction
+	if err := s.auditor.record(ctx, tx, "workspace_user", user.ID, audit.ActionDelete, toProtoWorkspaceUser(user, member.IsAdmin, member.CreatedAt), nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to commit transaction", "error", err)
+		return nil, storageError(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	// Log success
+	log.InfoContext(ctx, s.logger, "Workspace user removed successfully", "workspace_id", workspaceID, "user_id", req.Msg.UserId)
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.RemoveWorkspaceUserResponse{
+		Success: true,
+	}), nil
+}
+
+// ListWorkspaceUsers retrieves the users of the workspace of the request
+func (s *WorkspaceService) ListWorkspaceUsers(ctx context.Context, req *connect.Request[expensesv1.ListWorkspaceUsersRequest]) (*connect.Response[expensesv1.ListWorkspaceUsersResponse], error) {
+	// Log method entry
+	workspaceID := authz.WorkspaceFrom(ctx)
+	log.InfoContext(ctx, s.logger, "Listing workspace users", "workspace_id", workspaceID)
+
+	// Get workspace users from database (read operations can use the main DB connection)
+	rows, err := s.repo.ListWorkspaceUsers(ctx, s.repo.GetDB(), workspaceID)
+	if err != nil {
+		log.ErrorContext(ctx, s.logger, "Failed to list workspace users", "workspace_id", workspaceID, "error", err)
+		return nil, storageError(err)
+	}
+
+	// Convert to proto messages
+	users := make([]*expensesv1.WorkspaceUser, len(rows))
+	for i, row := range rows {
+		users[i] = toProtoWorkspaceUser(row.User, row.IsAdmin, row.JoinedAt)
+	}
+
+	log.InfoContext(ctx, s.logger, "Workspace users listed successfully", "workspace_id", workspaceID, "count", len(users))
+
+	// Prepare response
+	return connect.NewResponse(&expensesv1.ListWorkspaceUsersResponse{
+		WorkspaceUsers: users,
+	}), nil
+}
+
+// identity returns the authenticated caller, failing with Unauthenticated if
+// the request carries no credential
+func (s *WorkspaceService) identity(ctx context.Context) (auth.Identity, error) {
+	identity, ok := auth.IdentityFrom(ctx)
+	if !ok {
+		log.ErrorContext(ctx, s.logger, "Request is not authenticated")
+		return auth.Identity{}, errors.Unauthenticated(fmt.Errorf("%w: an api token or session is required", errors.ErrUnauthenticated))
+	}
+	return identity, nil
+}
+
+// toProtoWorkspace converts a db.Workspace to a expensesv1.Workspace, with
+// whether the caller is an admin of it
+func toProtoWorkspace(workspace db.Workspace, admin bool) *expensesv1.Workspace {
+	return &expensesv1.Workspace{
+		Id:        workspace.ID,
+		Name:      workspace.Name,
+		CreatedAt: timestamppb.New(workspace.CreatedAt),
+		UpdatedAt: timestamppb.New(workspace.UpdatedAt),
+		Admin:     admin,
+	}
+}
+
+// toProtoWorkspaceUser converts a user of a workspace to a
+// expensesv1.WorkspaceUser
+func toProtoWorkspaceUser(user db.User, admin bool, joinedAt time.Time) *expensesv1.WorkspaceUser {
+	return &expensesv1.WorkspaceUser{
+		User:     toProtoUser(user),
+		Admin:    admin,
+		JoinedAt: timestamppb.New(joinedAt),
+	}
+}
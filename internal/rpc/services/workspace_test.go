@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/atreya2011/expense-manager/internal/audit"
+	"github.com/atreya2011/expense-manager/internal/auth"
+	"github.com/atreya2011/expense-manager/internal/authz"
+	"github.com/atreya2011/expense-manager/internal/repo"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+	"github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1/expensesv1connect"
+)
+
+// TestWorkspaces tests keeping the data of workspaces apart and managing
+// their users through the interceptors, in the order the server chains them
+func TestWorkspaces(t *testing.T) {
+	// Reset the test database
+	resetTestDB(t)
+	ctx := context.Background()
+
+	// Serve the WorkspaceService and InstrumentService behind the interceptors
+	interceptors := connect.WithInterceptors(
+		audit.NewInterceptor(),
+		auth.NewInterceptor(authRepo, testClock, testLogger),
+		authz.NewInterceptor(repo.NewAuthzRepo(testDB), auditRepo, testClock, testLogger),
+	)
+	mux := http.NewServeMux()
+	mux.Handle(expensesv1connect.NewWorkspaceServiceHandler(NewWorkspaceService(workspaceRepo, userRepo, auditRepo, testClock, testLogger), interceptors))
+	mux.Handle(expensesv1connect.NewInstrumentServiceHandler(NewInstrumentService(instrumentRepo, auditRepo, testPages, testClock, testLogger), interceptors))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	workspaces := expensesv1connect.NewWorkspaceServiceClient(server.Client(), server.URL)
+	instruments := expensesv1connect.NewInstrumentServiceClient(server.Client(), server.URL)
+
+	// Seed two users with an API token each, who create a workspace each
+	alice := createTestUser(t, testDB, "Alice", "alice@example.com")
+	bob := createTestUser(t, testDB, "Bob", "bob@example.com")
+	_, aliceToken := createTestAPIToken(t, testDB, alice.ID, "alice", nil)
+	_, bobToken := createTestAPIToken(t, testDB, bob.ID, "bob", nil)
+	withCaller := func(req connect.AnyRequest, token, workspaceID string) {
+		req.Header().Set("Authorization", "Bearer "+token)
+		if workspaceID != "" {
+			req.Header().Set(authz.WorkspaceHeader, workspaceID)
+		}
+	}
+	createWorkspace := func(token, name string) string {
+		req := connect.NewRequest(&expensesv1.CreateWorkspaceRequest{Name: name})
+		withCaller(req, token, "")
+		res, err := workspaces.CreateWorkspace(ctx, req)
+		if err != nil {
+			t.Fatalf("Failed to create workspace: %v", err)
+		}
+		if !res.Msg.Workspace.Admin {
+			t.Fatalf("Expected the creator to be an admin of %s", name)
+		}
+		return res.Msg.Workspace.Id
+	}
+	home := createWorkspace(aliceToken, "Home")
+	flat := createWorkspace(bobToken, "Flat")
+
+	// Both workspaces may hold an instrument of the same name
+	createInstrument := func(token, workspaceID string) string {
+		req := connect.NewRequest(&expensesv1.CreateInstrumentRequest{Name: "Cash"})
+		withCaller(req, token, workspaceID)
+		res, err := instruments.CreateInstrument(ctx, req)
+		if err != nil {
+			t.Fatalf("Failed to create instrument: %v", err)
+		}
+		return res.Msg.Instrument.Id
+	}
+	homeCash := createInstrument(aliceToken, home)
+	createInstrument(bobToken, flat)
+
+	// Define test cases
+	tests := []struct {
+		name       string
+		call       func() error
+		expectCode connect.Code
+	}{
+		{
+			name: "Reads its own workspace",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.GetInstrumentRequest{Id: homeCash})
+				withCaller(req, aliceToken, home)
+				_, err := instruments.GetInstrument(ctx, req)
+				return err
+			},
+		},
+		{
+			name: "Cannot read another workspace",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.GetInstrumentRequest{Id: homeCash})
+				withCaller(req, bobToken, flat)
+				_, err := instruments.GetInstrument(ctx, req)
+				return err
+			},
+			expectCode: connect.CodeNotFound,
+		},
+		{
+			name: "Cannot name a workspace of others",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.ListInstrumentsRequest{})
+				withCaller(req, aliceToken, flat)
+				_, err := instruments.ListInstruments(ctx, req)
+				return err
+			},
+			expectCode: connect.CodePermissionDenied,
+		},
+		{
+			name: "Admin invites a new user",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.InviteUserRequest{Email: "carol@example.com", Name: "Carol"})
+				withCaller(req, aliceToken, home)
+				_, err := workspaces.InviteUser(ctx, req)
+				return err
+			},
+		},
+		{
+			name: "Admin invites an existing user",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.InviteUserRequest{Email: "bob@example.com"})
+				withCaller(req, aliceToken, home)
+				_, err := workspaces.InviteUser(ctx, req)
+				return err
+			},
+		},
+		{
+			name: "Invite a user twice",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.InviteUserRequest{Email: "carol@example.com"})
+				withCaller(req, aliceToken, home)
+				_, err := workspaces.InviteUser(ctx, req)
+				return err
+			},
+			expectCode: connect.CodeAlreadyExists,
+		},
+		{
+			name: "Invite an unknown user without a name",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.InviteUserRequest{Email: "dave@example.com"})
+				withCaller(req, aliceToken, home)
+				_, err := workspaces.InviteUser(ctx, req)
+				return err
+			},
+			expectCode: connect.CodeInvalidArgument,
+		},
+		{
+			name: "Member cannot invite",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.InviteUserRequest{Email: "erin@example.com", Name: "Erin"})
+				withCaller(req, bobToken, home)
+				_, err := workspaces.InviteUser(ctx, req)
+				return err
+			},
+			expectCode: connect.CodePermissionDenied,
+		},
+		{
+			name: "Cannot remove the last admin",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.RemoveWorkspaceUserRequest{UserId: alice.ID})
+				withCaller(req, aliceToken, home)
+				_, err := workspaces.RemoveWorkspaceUser(ctx, req)
+				return err
+			},
+			expectCode: connect.CodeFailedPrecondition,
+		},
+		{
+			name: "Admin removes a user",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.RemoveWorkspaceUserRequest{UserId: bob.ID})
+				withCaller(req, aliceToken, home)
+				_, err := workspaces.RemoveWorkspaceUser(ctx, req)
+				return err
+			},
+		},
+		{
+			name: "Remove a user of another workspace",
+			call: func() error {
+				req := connect.NewRequest(&expensesv1.RemoveWorkspaceUserRequest{UserId: bob.ID})
+				withCaller(req, aliceToken, home)
+				_, err := workspaces.RemoveWorkspaceUser(ctx, req)
+				return err
+			},
+			expectCode: connect.CodeNotFound,
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+
+			if tc.expectCode != 0 {
+				if connect.CodeOf(err) != tc.expectCode {
+					t.Fatalf("Expected code %v, got %v", tc.expectCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	// Each workspace lists its own instruments and users
+	listReq := connect.NewRequest(&expensesv1.ListInstrumentsRequest{})
+	withCaller(listReq, bobToken, flat)
+	listed, err := instruments.ListInstruments(ctx, listReq)
+	if err != nil {
+		t.Fatalf("Failed to list instruments: %v", err)
+	}
+	if len(listed.Msg.Instruments) != 1 || listed.Msg.Instruments[0].Id == homeCash {
+		t.Errorf("Expected only the Flat instrument, got %v", listed.Msg.Instruments)
+	}
+	usersReq := connect.NewRequest(&expensesv1.ListWorkspaceUsersRequest{})
+	withCaller(usersReq, aliceToken, home)
+	users, err := workspaces.ListWorkspaceUsers(ctx, usersReq)
+	if err != nil {
+		t.Fatalf("Failed to list workspace users: %v", err)
+	}
+	var names []string
+	for _, user := range users.Msg.WorkspaceUsers {
+		names = append(names, user.User.Name)
+	}
+	if len(names) != 2 || names[0] != "Alice" || names[1] != "Carol" {
+		t.Errorf("Expected [Alice Carol], got %v", names)
+	}
+
+	// Without a header, a request is made in the workspace joined first
+	workspacesReq := connect.NewRequest(&expensesv1.ListWorkspacesRequest{})
+	withCaller(workspacesReq, aliceToken, "")
+	joined, err := workspaces.ListWorkspaces(ctx, workspacesReq)
+	if err != nil {
+		t.Fatalf("Failed to list workspaces: %v", err)
+	}
+	if len(joined.Msg.Workspaces) != 2 {
+		t.Errorf("Expected 2 workspaces, got %d", len(joined.Msg.Workspaces))
+	}
+	defaultReq := connect.NewRequest(&expensesv1.GetInstrumentRequest{Id: homeCash})
+	withCaller(defaultReq, aliceToken, "")
+	if _, err := instruments.GetInstrument(ctx, defaultReq); connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("Expected the default workspace not to hold %s, got %v", homeCash, err)
+	}
+}
@@ -0,0 +1,136 @@
+// Package validate implements a Connect interceptor enforcing the protovalidate
+// rules of request messages, such as required fields, email formats and
+// maximum lengths, before the handler runs. Requests breaking a rule fail with
+// InvalidArgument and a BadRequest naming each offending field.
+package validate
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"buf.build/go/protovalidate"
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	"github.com/atreya2011/expense-manager/internal/log"
+)
+
+// ruleRequired is the ID of the rule of required fields
+const ruleRequired = "required"
+
+// Interceptor validates every request message against the rules annotated
+// in its .proto file
+type Interceptor struct {
+	logger *slog.Logger
+}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// NewInterceptor creates a new Interceptor
+func NewInterceptor(logger *slog.Logger) *Interceptor {
+	return &Interceptor{
+		logger: logger,
+	}
+}
+
+// WrapUnary implements connect.Interceptor
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		if err := i.validate(ctx, req.Spec().Procedure, req.Any()); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor. Each message of a
+// stream is validated as it is received.
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		return next(ctx, &streamingHandlerConn{StreamingHandlerConn: conn, ctx: ctx, interceptor: i})
+	}
+}
+
+// validate checks msg against its rules
+func (i *Interceptor) validate(ctx context.Context, procedure string, msg any) error {
+	message, ok := msg.(proto.Message)
+	if !ok {
+		return nil
+	}
+	if err := Message(message); err != nil {
+		log.ErrorContext(ctx, i.logger, "Invalid request", "procedure", procedure, "error", err)
+		return err
+	}
+	return nil
+}
+
+// Message checks msg against its rules, returning the error the interceptor
+// fails a request breaking them with. Batch RPCs validate each of their
+// requests with it.
+func Message(msg proto.Message) error {
+	err := protovalidate.Validate(msg)
+	if err == nil {
+		return nil
+	}
+	var validationErr *protovalidate.ValidationError
+	if !stderrors.As(err, &validationErr) {
+		return errors.Internal(err)
+	}
+	return violationError(validationErr)
+}
+
+// violationError converts the violations of a request into an InvalidArgument
+// error with a field violation each. Requests only missing required fields get
+// the same error as errors.Required.
+func violationError(err *protovalidate.ValidationError) *connect.Error {
+	var (
+		required     []string
+		descriptions []string
+		violations   []*errdetails.BadRequest_FieldViolation
+	)
+	for _, violation := range err.Violations {
+		field := protovalidate.FieldPathString(violation.Proto.GetField())
+		reason, description := errors.ReasonInvalidField, violation.Proto.GetMessage()
+		switch {
+		case violation.Proto.GetRuleId() == ruleRequired:
+			required = append(required, field)
+			reason, description = errors.ReasonRequiredField, field+" is required"
+		case field != "":
+			description = field + ": " + description
+		}
+		descriptions = append(descriptions, description)
+		violations = append(violations, errors.FieldViolation(field, reason, description))
+	}
+	if len(required) == len(violations) {
+		return errors.Required(required...)
+	}
+	return errors.Error(connect.CodeInvalidArgument, errors.ReasonInvalidField,
+		fmt.Errorf("%w: %s", errors.ErrInvalidInput, strings.Join(descriptions, "; ")), errors.BadRequest(violations...))
+}
+
+// streamingHandlerConn validates the messages a stream receives
+type streamingHandlerConn struct {
+	connect.StreamingHandlerConn
+	ctx         context.Context
+	interceptor *Interceptor
+}
+
+func (c *streamingHandlerConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	return c.interceptor.validate(c.ctx, c.Spec().Procedure, msg)
+}
@@ -0,0 +1,195 @@
+package validate
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+
+	"github.com/atreya2011/expense-manager/internal/errors"
+	expensesv1 "github.com/atreya2011/expense-manager/internal/rpc/gen/expenses/v1"
+)
+
+// TestInterceptor tests the enforcement of the protovalidate rules of request
+// messages
+func TestInterceptor(t *testing.T) {
+	interceptor := NewInterceptor(slog.New(slog.DiscardHandler))
+
+	// Define test cases
+	tests := []struct {
+		name         string
+		request      connect.AnyRequest
+		expectReason string
+		expectFields []string
+		errorMsg     string
+	}{
+		{
+			name:    "Valid user",
+			request: connect.NewRequest(&expensesv1.CreateUserRequest{Name: "Test User", Email: "test@example.com"}),
+		},
+		{
+			name:         "Missing name and email",
+			request:      connect.NewRequest(&expensesv1.CreateUserRequest{}),
+			expectReason: errors.ReasonRequiredField,
+			expectFields: []string{"name", "email"},
+			errorMsg:     "name and email are required",
+		},
+		{
+			name:         "Invalid email",
+			request:      connect.NewRequest(&expensesv1.CreateUserRequest{Name: "Test User", Email: "not-an-email"}),
+			expectReason: errors.ReasonInvalidField,
+			expectFields: []string{"email"},
+			errorMsg:     "email: value must be a valid email address",
+		},
+		{
+			name:         "Missing name and invalid email",
+			request:      connect.NewRequest(&expensesv1.CreateUserRequest{Email: "not-an-email"}),
+			expectReason: errors.ReasonInvalidField,
+			expectFields: []string{"name", "email"},
+			errorMsg:     "name is required; email: value must be a valid email address",
+		},
+		{
+			name:    "Update without email",
+			request: connect.NewRequest(&expensesv1.UpdateUserRequest{Id: "usr_1", Name: "Renamed"}),
+		},
+		{
+			name:         "Update with invalid email",
+			request:      connect.NewRequest(&expensesv1.UpdateUserRequest{Id: "usr_1", Email: "not-an-email"}),
+			expectReason: errors.ReasonInvalidField,
+			expectFields: []string{"email"},
+		},
+		{
+			name:         "Missing id",
+			request:      connect.NewRequest(&expensesv1.DeleteInstrumentRequest{}),
+			expectReason: errors.ReasonRequiredField,
+			expectFields: []string{"id"},
+			errorMsg:     "id is required",
+		},
+		{
+			name:         "Name too long",
+			request:      connect.NewRequest(&expensesv1.CreateInstrumentRequest{Name: strings.Repeat("a", 201)}),
+			expectReason: errors.ReasonInvalidField,
+			expectFields: []string{"name"},
+			errorMsg:     "name: value length must be at most 200 characters",
+		},
+		{
+			name:    "Lowercase currency code",
+			request: connect.NewRequest(&expensesv1.CreateCurrencyRequest{Code: "eur"}),
+		},
+		{
+			name:         "Invalid currency code",
+			request:      connect.NewRequest(&expensesv1.CreateCurrencyRequest{Code: "EURO"}),
+			expectReason: errors.ReasonInvalidField,
+			expectFields: []string{"code"},
+		},
+		{
+			name: "Batch with an invalid request",
+			request: connect.NewRequest(&expensesv1.BatchCreateUsersRequest{Requests: []*expensesv1.CreateUserRequest{
+				{Name: "Test User", Email: "test@example.com"},
+				{Email: "not-an-email"},
+			}}),
+		},
+		{
+			name:         "Empty batch",
+			request:      connect.NewRequest(&expensesv1.BatchDeleteInstrumentsRequest{}),
+			expectReason: errors.ReasonInvalidField,
+			expectFields: []string{"requests"},
+		},
+		{
+			name: "Valid ledger lines",
+			request: connect.NewRequest(&expensesv1.CreateTransactionRequest{Lines: []*expensesv1.LedgerLine{
+				{AccountId: "acc_1", Debit: &expensesv1.Money{Amount: 100, Currency: "JPY"}},
+				{AccountId: "acc_2", Credit: &expensesv1.Money{Value: "100"}},
+			}}),
+		},
+		{
+			name: "Negative amount",
+			request: connect.NewRequest(&expensesv1.CreateTransactionRequest{Lines: []*expensesv1.LedgerLine{
+				{AccountId: "acc_1", Debit: &expensesv1.Money{Amount: 100}},
+				{AccountId: "acc_2", Credit: &expensesv1.Money{Amount: -100}},
+			}}),
+			expectReason: errors.ReasonInvalidField,
+			expectFields: []string{"lines[1].credit"},
+			errorMsg:     "lines[1].credit: amount must not be negative",
+		},
+		{
+			name: "Amount above the maximum",
+			request: connect.NewRequest(&expensesv1.CreateTransactionRequest{Lines: []*expensesv1.LedgerLine{
+				{AccountId: "acc_1", Debit: &expensesv1.Money{Amount: 1 << 62}},
+				{AccountId: "acc_2", Credit: &expensesv1.Money{Amount: 1000}},
+			}}),
+			expectReason: errors.ReasonInvalidField,
+			expectFields: []string{"lines[0].debit"},
+			errorMsg:     "lines[0].debit: amount must not exceed 1000000000000000 minor units",
+		},
+		{
+			name: "Negative value and invalid currency",
+			request: connect.NewRequest(&expensesv1.ReconcileCashRequest{
+				AccountId: "acc_1",
+				Counted:   &expensesv1.Money{Value: "-1.50", Currency: "yen!"},
+			}),
+			expectReason: errors.ReasonInvalidField,
+			expectFields: []string{"counted", "counted.currency"},
+		},
+	}
+
+	// Run tests
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			next := connect.UnaryFunc(func(context.Context, connect.AnyRequest) (connect.AnyResponse, error) {
+				called = true
+				return nil, nil
+			})
+			_, err := interceptor.WrapUnary(next)(context.Background(), tc.request)
+
+			if tc.expectReason == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if !called {
+					t.Errorf("Expected the handler to run")
+				}
+				return
+			}
+			if called {
+				t.Errorf("Expected the handler not to run")
+			}
+			if connect.CodeOf(err) != connect.CodeInvalidArgument {
+				t.Fatalf("Expected code %v, got %v", connect.CodeInvalidArgument, err)
+			}
+			if tc.errorMsg != "" && !strings.Contains(err.Error(), tc.errorMsg) {
+				t.Errorf("Expected error message to contain %q, got %q", tc.errorMsg, err.Error())
+			}
+
+			var (
+				reason string
+				fields []string
+			)
+			for _, detail := range err.(*connect.Error).Details() {
+				value, err := detail.Value()
+				if err != nil {
+					t.Fatalf("Failed to decode error detail %s: %v", detail.Type(), err)
+				}
+				switch msg := value.(type) {
+				case *errdetails.ErrorInfo:
+					reason = msg.Reason
+				case *errdetails.BadRequest:
+					for _, violation := range msg.FieldViolations {
+						fields = append(fields, violation.Field)
+					}
+				}
+			}
+			if reason != tc.expectReason {
+				t.Errorf("Expected reason %s, got %s", tc.expectReason, reason)
+			}
+			if !slices.Equal(fields, tc.expectFields) {
+				t.Errorf("Expected field violations of %q, got %q", tc.expectFields, fields)
+			}
+		})
+	}
+}